@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go-lang-final/internal/auth"
+	"go-lang-final/internal/config"
+	"go-lang-final/internal/store"
+	"go-lang-final/internal/tenant"
+)
+
+const usage = `usage: admin <command> [flags]
+
+commands:
+  apikey create -name NAME -roles viewer,operator -tenant TENANT
+  apikey rotate -id ID [-grace 24h]
+  apikey revoke -id ID
+  apikey list
+  tenant create -id ID -name NAME [-currencies USD,EUR] [-max-amount N] [-max-payments N]
+                [-daily-payments N] [-daily-amount N]
+  tenant update -id ID -name NAME [same flags as create]
+  tenant list
+`
+
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		fatalf("failed to load config: %v", err)
+	}
+	paymentStore, err := store.NewPaymentStore(cfg.DatabaseURL)
+	if err != nil {
+		fatalf("failed to connect to the database: %v", err)
+	}
+	keys := auth.NewAPIKeyStore(paymentStore.DB)
+	tenants := tenant.NewStore(paymentStore.DB)
+
+	ctx := context.Background()
+	switch os.Args[1] + " " + os.Args[2] {
+	case "apikey create":
+		runCreate(ctx, keys, os.Args[3:])
+	case "apikey rotate":
+		runRotate(ctx, keys, os.Args[3:])
+	case "apikey revoke":
+		runRevoke(ctx, keys, os.Args[3:])
+	case "apikey list":
+		runList(ctx, keys)
+	case "tenant create":
+		runTenantSave(ctx, tenants.CreateTenant, os.Args[3:])
+	case "tenant update":
+		runTenantSave(ctx, tenants.UpdateTenant, os.Args[3:])
+	case "tenant list":
+		runTenantList(ctx, tenants)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func runCreate(ctx context.Context, keys *auth.APIKeyStore, args []string) {
+	fs := flag.NewFlagSet("apikey create", flag.ExitOnError)
+	name := fs.String("name", "", "human readable key name")
+	roles := fs.String("roles", "", "comma separated roles granted to the key")
+	tenantID := fs.String("tenant", "", "tenant the key acts for; empty only for platform_admin keys")
+	fs.Parse(args)
+	if *name == "" || *roles == "" {
+		fatalf("-name and -roles are required")
+	}
+
+	key, raw, err := keys.CreateAPIKey(ctx, *name, strings.Split(*roles, ","), *tenantID)
+	if err != nil {
+		fatalf("failed to create api key: %v", err)
+	}
+	printKey(key, raw)
+}
+
+func runRotate(ctx context.Context, keys *auth.APIKeyStore, args []string) {
+	fs := flag.NewFlagSet("apikey rotate", flag.ExitOnError)
+	id := fs.Int64("id", 0, "id of the key to rotate")
+	grace := fs.Duration("grace", 24*time.Hour, "how long the old key stays valid")
+	fs.Parse(args)
+
+	key, raw, err := keys.RotateAPIKey(ctx, *id, *grace)
+	if err != nil {
+		fatalf("failed to rotate api key: %v", err)
+	}
+	printKey(key, raw)
+}
+
+func runRevoke(ctx context.Context, keys *auth.APIKeyStore, args []string) {
+	fs := flag.NewFlagSet("apikey revoke", flag.ExitOnError)
+	id := fs.Int64("id", 0, "id of the key to revoke")
+	fs.Parse(args)
+
+	if err := keys.RevokeAPIKey(ctx, *id); err != nil {
+		fatalf("failed to revoke api key: %v", err)
+	}
+	fmt.Printf("api key %d revoked\n", *id)
+}
+
+func runList(ctx context.Context, keys *auth.APIKeyStore) {
+	list, err := keys.ListAPIKeys(ctx)
+	if err != nil {
+		fatalf("failed to list api keys: %v", err)
+	}
+	printJSON(list)
+}
+
+func runTenantSave(ctx context.Context, save func(context.Context, tenant.Tenant) error, args []string) {
+	fs := flag.NewFlagSet("tenant", flag.ExitOnError)
+	id := fs.String("id", "", "tenant id")
+	name := fs.String("name", "", "display name")
+	currencies := fs.String("currencies", "", "comma separated allowed currencies, empty for any")
+	maxAmount := fs.Float64("max-amount", 0, "largest single payment amount, 0 for unlimited")
+	maxPayments := fs.Int64("max-payments", 0, "maximum number of stored payments, 0 for unlimited")
+	dailyPayments := fs.Int64("daily-payments", 0, "payments that may be created per UTC day, 0 for unlimited")
+	dailyAmount := fs.Float64("daily-amount", 0, "total amount that may be created per UTC day, 0 for unlimited")
+	fs.Parse(args)
+	if *id == "" || *name == "" {
+		fatalf("-id and -name are required")
+	}
+
+	t := tenant.Tenant{ID: *id, Name: *name}
+	if *currencies != "" {
+		t.AllowedCurrencies = strings.Split(*currencies, ",")
+	}
+	if *maxAmount > 0 {
+		t.MaxPaymentAmount = maxAmount
+	}
+	if *maxPayments > 0 {
+		t.MaxPayments = maxPayments
+	}
+	if *dailyPayments > 0 {
+		t.DailyPaymentLimit = dailyPayments
+	}
+	if *dailyAmount > 0 {
+		t.DailyAmountLimit = dailyAmount
+	}
+
+	if err := save(ctx, t); err != nil {
+		fatalf("failed to save tenant: %v", err)
+	}
+	fmt.Printf("tenant %s saved\n", t.ID)
+}
+
+func runTenantList(ctx context.Context, tenants *tenant.Store) {
+	list, err := tenants.ListTenants(ctx)
+	if err != nil {
+		fatalf("failed to list tenants: %v", err)
+	}
+	printJSON(list)
+}
+
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func printKey(key *auth.APIKey, raw string) {
+	fmt.Printf("id:     %d\nname:   %s\nroles:  %s\ntenant: %s\nkey:    %s\n\nStore this key now, it cannot be shown again.\n",
+		key.ID, key.Name, strings.Join(key.Roles, ","), key.TenantID, raw)
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"go-lang-final/internal/ctl"
+	"go-lang-final/pkg/paymentsclient"
+)
+
+const usage = `usage: paymentsctl <command> [flags]
+
+commands:
+  get -id ID
+  list [-currency CUR] [-amount N] [-page N] [-page-size N] [-all]
+  create -id ID -amount N -currency CUR [-idempotency-key KEY]
+  update -id ID [-amount N] [-currency CUR] [-yes]
+  refund -id ID [-amount N] [-reason TEXT] [-yes]
+  cancel -id ID [-reason TEXT] [-yes]
+  history -id ID
+  export [-format csv|jsonl] [-currency CUR] [-file PATH]
+  watch [-after EVENT_ID]
+
+every command accepts:
+  -profile NAME      connection profile from $PAYMENTSCTL_CONFIG or
+                     ~/.config/paymentsctl/config.yaml
+  -transport T       grpc (default) or rest, overriding the profile
+  -tenant ID         tenant to act for, for cross-tenant administrators
+  -o FORMAT          table (default), json or yaml
+
+update, refund and cancel ask for confirmation; -yes skips the question and
+is required when stdin is not a terminal.
+`
+
+// common holds the flags shared by every command.
+type common struct {
+	profile   string
+	transport string
+	tenant    string
+	output    string
+}
+
+func newFlagSet(name string) (*flag.FlagSet, *common) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	c := &common{}
+	fs.StringVar(&c.profile, "profile", os.Getenv("PAYMENTSCTL_PROFILE"), "connection profile")
+	fs.StringVar(&c.transport, "transport", "", "grpc or rest, overriding the profile")
+	fs.StringVar(&c.tenant, "tenant", "", "tenant to act for, overriding the profile")
+	fs.StringVar(&c.output, "o", ctl.Table, "output format: table, json or yaml")
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	return fs, c
+}
+
+// connect returns a client for the selected profile and a printer for the
+// selected output format.
+func (c *common) connect() (*paymentsclient.Client, *ctl.Printer, io.Closer) {
+	printer, err := ctl.NewPrinter(os.Stdout, c.output)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	cfg, err := ctl.LoadConfig(ctl.ConfigPath())
+	if err != nil {
+		fatalf("failed to load config: %v", err)
+	}
+	profile, err := cfg.Profile(c.profile)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	if c.transport != "" {
+		profile.Transport = c.transport
+	}
+	if c.tenant != "" {
+		profile.Tenant = c.tenant
+	}
+	client, conn, err := profile.Connect()
+	if err != nil {
+		fatalf("failed to connect: %v", err)
+	}
+	return client, printer, conn
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	args := os.Args[2:]
+	switch os.Args[1] {
+	case "get":
+		runGet(ctx, args)
+	case "list":
+		runList(ctx, args)
+	case "create":
+		runCreate(ctx, args)
+	case "update":
+		runUpdate(ctx, args)
+	case "refund":
+		runRefund(ctx, args)
+	case "cancel":
+		runCancel(ctx, args)
+	case "history":
+		runHistory(ctx, args)
+	case "export":
+		runExport(ctx, args)
+	case "watch":
+		runWatch(ctx, args)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func runGet(ctx context.Context, args []string) {
+	fs, c := newFlagSet("get")
+	id := fs.Int64("id", 0, "payment id")
+	fs.Parse(args)
+	requireID(*id)
+
+	client, printer, conn := c.connect()
+	defer conn.Close()
+	p, err := client.Get(ctx, *id)
+	if err != nil {
+		fatalf("failed to get payment: %v", err)
+	}
+	check(printer.Payment(*p))
+}
+
+func runList(ctx context.Context, args []string) {
+	fs, c := newFlagSet("list")
+	currency := fs.String("currency", "", "only payments in this currency")
+	amount := fs.Float64("amount", 0, "only payments of exactly this amount")
+	page := fs.Int("page", 1, "page to show")
+	pageSize := fs.Int("page-size", paymentsclient.DefaultPageSize, "payments per page")
+	all := fs.Bool("all", false, "show every page")
+	fs.Parse(args)
+
+	opts := paymentsclient.ListOptions{Currency: *currency, PageSize: *pageSize}
+	if flagSet(fs, "amount") {
+		opts.Amount = amount
+	}
+
+	client, printer, conn := c.connect()
+	defer conn.Close()
+	if !*all {
+		result, err := client.List(ctx, opts, *page)
+		if err != nil {
+			fatalf("failed to list payments: %v", err)
+		}
+		check(printer.Payments(result.Payments))
+		return
+	}
+
+	var payments []paymentsclient.Payment
+	it := client.Iterate(ctx, opts)
+	for it.Next() {
+		payments = append(payments, it.Payment())
+	}
+	if err := it.Err(); err != nil {
+		fatalf("failed to list payments: %v", err)
+	}
+	check(printer.Payments(payments))
+}
+
+func runCreate(ctx context.Context, args []string) {
+	fs, c := newFlagSet("create")
+	id := fs.Int64("id", 0, "payment id")
+	amount := fs.Float64("amount", 0, "amount")
+	currency := fs.String("currency", "", "ISO 4217 currency code")
+	key := fs.String("idempotency-key", "", "idempotency key, for creates retried by hand")
+	fs.Parse(args)
+	requireID(*id)
+	if *amount <= 0 || *currency == "" {
+		fatalf("-amount and -currency are required")
+	}
+
+	client, printer, conn := c.connect()
+	defer conn.Close()
+	if *key != "" {
+		ctx = paymentsclient.WithIdempotencyKey(ctx, *key)
+	}
+	p, err := client.Create(ctx, paymentsclient.Payment{ID: *id, Amount: *amount, Currency: *currency})
+	if err != nil {
+		fatalf("failed to create payment: %v", err)
+	}
+	check(printer.Payment(*p))
+}
+
+func runUpdate(ctx context.Context, args []string) {
+	fs, c := newFlagSet("update")
+	id := fs.Int64("id", 0, "payment id")
+	amount := fs.Float64("amount", 0, "new amount")
+	currency := fs.String("currency", "", "new currency")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	fs.Parse(args)
+	requireID(*id)
+	if !flagSet(fs, "amount") && *currency == "" {
+		fatalf("-amount or -currency is required")
+	}
+
+	client, printer, conn := c.connect()
+	defer conn.Close()
+	current, err := client.Get(ctx, *id)
+	if err != nil {
+		fatalf("failed to get payment: %v", err)
+	}
+	updated := *current
+	if flagSet(fs, "amount") {
+		updated.Amount = *amount
+	}
+	if *currency != "" {
+		updated.Currency = *currency
+	}
+
+	confirm(*yes, "Update payment %d from %.2f %s to %.2f %s?", *id, current.Amount, current.Currency, updated.Amount, updated.Currency)
+	p, err := client.Update(ctx, updated)
+	if err != nil {
+		fatalf("failed to update payment: %v", err)
+	}
+	check(printer.Payment(*p))
+}
+
+func runRefund(ctx context.Context, args []string) {
+	fs, c := newFlagSet("refund")
+	id := fs.Int64("id", 0, "payment id")
+	amount := fs.Float64("amount", 0, "amount to refund, by default everything not yet refunded")
+	reason := fs.String("reason", "", "reason recorded in the payment history")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	fs.Parse(args)
+	requireID(*id)
+
+	client, printer, conn := c.connect()
+	defer conn.Close()
+	current, err := client.Get(ctx, *id)
+	if err != nil {
+		fatalf("failed to get payment: %v", err)
+	}
+	refund := *amount
+	if !flagSet(fs, "amount") {
+		refund = current.Amount - current.RefundedAmount
+	}
+
+	confirm(*yes, "Refund %.2f %s of payment %d (%.2f of %.2f already refunded)?",
+		refund, current.Currency, *id, current.RefundedAmount, current.Amount)
+	p, err := client.Refund(ctx, *id, refund, *reason)
+	if err != nil {
+		fatalf("failed to refund payment: %v", err)
+	}
+	check(printer.Payment(*p))
+}
+
+func runCancel(ctx context.Context, args []string) {
+	fs, c := newFlagSet("cancel")
+	id := fs.Int64("id", 0, "payment id")
+	reason := fs.String("reason", "", "reason recorded in the payment history")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	fs.Parse(args)
+	requireID(*id)
+
+	client, printer, conn := c.connect()
+	defer conn.Close()
+	confirm(*yes, "Cancel payment %d?", *id)
+	p, err := client.Cancel(ctx, *id, *reason)
+	if err != nil {
+		fatalf("failed to cancel payment: %v", err)
+	}
+	check(printer.Payment(*p))
+}
+
+func runHistory(ctx context.Context, args []string) {
+	fs, c := newFlagSet("history")
+	id := fs.Int64("id", 0, "payment id")
+	fs.Parse(args)
+	requireID(*id)
+
+	client, printer, conn := c.connect()
+	defer conn.Close()
+	events, err := client.History(ctx, *id)
+	if err != nil {
+		fatalf("failed to get payment history: %v", err)
+	}
+	check(printer.Events(events))
+}
+
+func runExport(ctx context.Context, args []string) {
+	fs, c := newFlagSet("export")
+	format := fs.String("format", ctl.CSV, "csv or jsonl")
+	currency := fs.String("currency", "", "only payments in this currency")
+	file := fs.String("file", "", "write to this file instead of stdout")
+	fs.Parse(args)
+
+	out := os.Stdout
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			fatalf("failed to create %s: %v", *file, err)
+		}
+		defer f.Close()
+		out = f
+	}
+	exporter, err := ctl.NewExporter(out, *format)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	client, _, conn := c.connect()
+	defer conn.Close()
+	it := client.Iterate(ctx, paymentsclient.ListOptions{Currency: *currency, PageSize: 100})
+	n := 0
+	for it.Next() {
+		check(exporter.Write(it.Payment()))
+		n++
+	}
+	if err := it.Err(); err != nil {
+		fatalf("export stopped after %d payments: %v", n, err)
+	}
+	check(exporter.Close())
+	if *file != "" {
+		fmt.Fprintf(os.Stderr, "exported %d payments to %s\n", n, *file)
+	}
+}
+
+func runWatch(ctx context.Context, args []string) {
+	fs, c := newFlagSet("watch")
+	after := fs.Int64("after", 0, "start after this event id; 0 replays the whole feed")
+	fs.Parse(args)
+
+	client, printer, conn := c.connect()
+	defer conn.Close()
+	err := client.Watch(ctx, *after, printer.Event)
+	if err != nil && !errors.Is(ctx.Err(), context.Canceled) {
+		fatalf("watch stopped: %v", err)
+	}
+}
+
+func confirm(yes bool, format string, args ...interface{}) {
+	if err := ctl.NewConfirmer(yes).Confirm(format, args...); err != nil {
+		fatalf("%v", err)
+	}
+}
+
+func requireID(id int64) {
+	if id == 0 {
+		fatalf("-id is required")
+	}
+}
+
+// flagSet reports whether the named flag was given, so zero values can be
+// told apart from defaults.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func check(err error) {
+	if err != nil {
+		fatalf("%v", err)
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
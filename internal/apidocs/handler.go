@@ -0,0 +1,28 @@
+package apidocs
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var static embed.FS
+
+// Handler serves the OpenAPI document at /openapi.json and the docs page
+// under /docs/. The page has no external dependencies, so it works offline.
+// Both are public; mount them outside the authenticated API router.
+func Handler() http.Handler {
+	assets, _ := fs.Sub(static, "static")
+	spec, _ := json.Marshal(Spec())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(spec)
+	})
+	mux.Handle("/docs/", http.StripPrefix("/docs/", http.FileServer(http.FS(assets))))
+	mux.Handle("/docs", http.RedirectHandler("/docs/", http.StatusMovedPermanently))
+	return mux
+}
@@ -0,0 +1,998 @@
+// Package apidocs publishes the OpenAPI description of the REST API and an
+// embedded page for browsing it.
+package apidocs
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go-lang-final/internal/handlers"
+	"go-lang-final/internal/idempotency"
+	"go-lang-final/internal/models"
+	"go-lang-final/proto"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Spec returns the OpenAPI document of the REST API. The v1 operations are
+// generated from the google.api.http bindings in payment.proto; the v2
+// operations from the Go types their handlers encode.
+var Spec = sync.OnceValue(build)
+
+var pathParam = regexp.MustCompile(`\{([^}=]+)\}`)
+
+// idempotencyKey makes a create safe to retry: replays of the same request
+// succeed without creating another payment.
+var idempotencyKey = Parameter{Name: idempotency.Header, In: "header", Schema: &Schema{Type: "string"}}
+
+type builder struct {
+	doc *Document
+}
+
+func build() *Document {
+	b := &builder{doc: &Document{
+		OpenAPI: "3.1.0",
+		Info: Info{
+			Title:   "Payments API",
+			Version: "2.0",
+			Description: "The v1 API and its legacy aliases are deprecated and will be removed after " +
+				handlers.V1Sunset.Format("2006-01-02") + "; use v2.",
+		},
+		Paths: map[string]PathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{
+				"Error": {
+					Type:        "object",
+					Description: "A gRPC status; code is the canonical gRPC status code.",
+					Properties: map[string]*Schema{
+						"code":    {Type: "integer", Format: "int32"},
+						"message": {Type: "string"},
+						"details": {Type: "array", Items: &Schema{
+							Type:                 "object",
+							Properties:           map[string]*Schema{"@type": {Type: "string"}},
+							AdditionalProperties: &Schema{},
+						}},
+					},
+				},
+			},
+			SecuritySchemes: map[string]SecurityScheme{
+				"apiKey":    {Type: "apiKey", In: "header", Name: "X-API-Key"},
+				"bearer":    {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				"mutualTLS": {Type: "mutualTLS"},
+			},
+		},
+		Security: []map[string][]string{{"apiKey": {}}, {"bearer": {}}, {"mutualTLS": {}}},
+	}}
+	b.v1()
+	b.v2()
+	return b.doc
+}
+
+func (b *builder) v1() {
+	methods := proto.File_proto_payment_proto.Services().ByName("PaymentService").Methods()
+	for _, binding := range handlers.Bindings() {
+		m := methods.ByName(protoreflect.Name(binding.RPC))
+		op := &Operation{
+			OperationID: binding.RPC + "V1",
+			Summary:     binding.FullMethod,
+			Tags:        []string{"v1"},
+			Deprecated:  true,
+		}
+		if binding.Deprecated {
+			op.OperationID = binding.RPC + "Legacy"
+			op.Summary += " (legacy alias of " + binding.Successor + ")"
+		}
+
+		fields := m.Input().Fields()
+		bound := map[string]bool{}
+		for _, match := range pathParam.FindAllStringSubmatch(binding.Path, -1) {
+			bound[match[1]] = true
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: match[1], In: "path", Required: true,
+				Schema: b.field(fields.ByName(protoreflect.Name(match[1]))),
+			})
+		}
+		switch binding.Body {
+		case "":
+			for i := 0; i < fields.Len(); i++ {
+				if f := fields.Get(i); !bound[string(f.Name())] {
+					op.Parameters = append(op.Parameters, Parameter{Name: string(f.Name()), In: "query", Schema: b.field(f)})
+				}
+			}
+		case "*":
+			op.RequestBody = &RequestBody{Required: true, Content: jsonContent(b.message(m.Input()))}
+		default:
+			op.RequestBody = &RequestBody{Required: true, Content: jsonContent(b.field(fields.ByName(protoreflect.Name(binding.Body))))}
+		}
+
+		result := b.message(m.Output())
+		if binding.ResponseBody != "" {
+			result = b.field(m.Output().Fields().ByName(protoreflect.Name(binding.ResponseBody)))
+		}
+		code := "200"
+		if binding.RPC == "CreatePayment" {
+			code = "201"
+			op.Parameters = append(op.Parameters, idempotencyKey)
+		}
+		op.Responses = responses(code, Response{
+			Description: "OK",
+			Headers: map[string]Header{
+				"Deprecation": {Schema: &Schema{Type: "string"}},
+				"Sunset":      {Description: "Removal date of the v1 API.", Schema: &Schema{Type: "string"}},
+				"Link":        {Description: "The v2 successor of the route.", Schema: &Schema{Type: "string"}},
+			},
+			Content: jsonContent(result),
+		})
+		b.doc.add(binding.Verb, binding.Path, op)
+	}
+}
+
+func (b *builder) v2() {
+	payment := b.goType(reflect.TypeOf(models.Payment{}))
+	id := Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer", Format: "int64"}}
+
+	b.doc.add(http.MethodPost, "/v2/payments", &Operation{
+		OperationID: "CreatePayment",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{idempotencyKey},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(b.goType(reflect.TypeOf(handlers.CreateRequest{})))},
+		Responses: responses("201", Response{
+			Description: "Created",
+			Headers:     map[string]Header{"Location": {Schema: &Schema{Type: "string", Format: "uri-reference"}}},
+			Content:     jsonContent(payment),
+		}),
+	})
+	b.doc.add(http.MethodGet, "/v2/payments", &Operation{
+		OperationID: "ListPayments",
+		Tags:        []string{"v2"},
+		Parameters: []Parameter{
+			{Name: "currency", In: "query", Schema: &Schema{Type: "string"}},
+			{Name: "amount", In: "query", Schema: &Schema{Type: "number", Format: "double"}},
+			{Name: "metadata", In: "query", Style: "deepObject", Explode: true, Schema: &Schema{
+				Type:                 "object",
+				Description:          "metadata[key]=value matches payments with that metadata.",
+				AdditionalProperties: &Schema{Type: "string"},
+			}},
+			{Name: "query", In: "query", Schema: &Schema{Type: "string", Description: "Full-text search over descriptions; results are ranked."}},
+			{Name: "page", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+			{Name: "page_size", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+		},
+		Responses: responses("200", Response{
+			Description: "OK",
+			Content:     jsonContent(b.goType(reflect.TypeOf(handlers.ListEnvelope{}))),
+		}),
+	})
+	b.doc.add(http.MethodGet, "/v2/payments/{id}", &Operation{
+		OperationID: "GetPayment",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(payment)}),
+	})
+	b.doc.add(http.MethodPatch, "/v2/payments/{id}", &Operation{
+		OperationID: "UpdatePayment",
+		Summary:     "Applies a JSON Merge Patch; id, tenant_id, status and refunded_amount are read-only.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{
+			handlers.MergePatchContentType: {Schema: &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"amount":               {Type: "number", Format: "double"},
+					"currency":             {Type: "string"},
+					"metadata":             {Type: "object", Description: "Merged key by key; null removes a key.", AdditionalProperties: &Schema{Type: "string"}},
+					"description":          {Type: "string"},
+					"statement_descriptor": {Type: "string"},
+				},
+			}},
+		}},
+		Responses: responses("200", Response{Description: "OK", Content: jsonContent(payment)}),
+	})
+	b.doc.add(http.MethodDelete, "/v2/payments/{id}", &Operation{
+		OperationID: "DeletePayment",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		Responses:   responses("204", Response{Description: "Deleted"}),
+	})
+
+	events := jsonContent(b.goType(reflect.TypeOf(handlers.EventList{})))
+	b.doc.add(http.MethodPost, "/v2/payments/{id}/refund", &Operation{
+		OperationID: "RefundPayment",
+		Summary:     "Refunds part or all of the unrefunded amount.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(b.goType(reflect.TypeOf(handlers.RefundRequest{})))},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(payment)}),
+	})
+	b.doc.add(http.MethodPost, "/v2/payments/{id}/cancel", &Operation{
+		OperationID: "CancelPayment",
+		Summary:     "Cancels a payment that has not been refunded.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		RequestBody: &RequestBody{Content: jsonContent(b.goType(reflect.TypeOf(handlers.CancelRequest{})))},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(payment)}),
+	})
+	b.doc.add(http.MethodGet, "/v2/payments/{id}/events", &Operation{
+		OperationID: "ListPaymentEvents",
+		Summary:     "Returns the history of a payment, oldest first.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		Responses:   responses("200", Response{Description: "OK", Content: events}),
+	})
+	b.doc.add(http.MethodGet, "/v2/events", &Operation{
+		OperationID: "WatchPayments",
+		Summary:     "Returns events after the given event id; poll with the id of the last event seen.",
+		Tags:        []string{"v2"},
+		Parameters: []Parameter{
+			{Name: "after", In: "query", Schema: &Schema{Type: "integer", Format: "int64"}},
+			{Name: "limit", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+		},
+		Responses: responses("200", Response{Description: "OK", Content: events}),
+	})
+
+	method := b.goType(reflect.TypeOf(models.PaymentMethod{}))
+	b.doc.add(http.MethodPost, "/v2/payment_methods", &Operation{
+		OperationID: "CreatePaymentMethod",
+		Summary:     "Stores a card in the vault; pay with the returned token as payment_method.",
+		Tags:        []string{"v2"},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(b.goType(reflect.TypeOf(handlers.PaymentMethodRequest{})))},
+		Responses: responses("201", Response{
+			Description: "Created",
+			Headers:     map[string]Header{"Location": {Schema: &Schema{Type: "string", Format: "uri-reference"}}},
+			Content:     jsonContent(method),
+		}),
+	})
+	b.doc.add(http.MethodGet, "/v2/payment_methods/{token}", &Operation{
+		OperationID: "GetPaymentMethod",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{{Name: "token", In: "path", Required: true, Schema: &Schema{Type: "string"}}},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(method)}),
+	})
+
+	b.bankTransfers()
+	b.reconciliation()
+	b.settlements()
+	b.fx()
+	b.fees()
+	b.subscriptions()
+	b.invoices()
+	b.customers()
+	b.disputes()
+}
+
+func (b *builder) bankTransfers() {
+	location := map[string]Header{"Location": {Schema: &Schema{Type: "string", Format: "uri-reference"}}}
+	stringID := func(name string) Parameter {
+		return Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}}
+	}
+
+	account := b.goType(reflect.TypeOf(models.BankAccount{}))
+	b.doc.add(http.MethodPost, "/v2/bank_accounts", &Operation{
+		OperationID: "CreateBankAccount",
+		Summary:     "Stores a SEPA bank account; pay by bank transfer with the returned token as payment_method.",
+		Tags:        []string{"v2"},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(b.goType(reflect.TypeOf(handlers.BankAccountRequest{})))},
+		Responses:   responses("201", Response{Description: "Created", Headers: location, Content: jsonContent(account)}),
+	})
+	b.doc.add(http.MethodGet, "/v2/bank_accounts/{token}", &Operation{
+		OperationID: "GetBankAccount",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{stringID("token")},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(account)}),
+	})
+
+	transfer := b.goType(reflect.TypeOf(models.CreditTransfer{}))
+	b.doc.add(http.MethodPost, "/v2/payouts", &Operation{
+		OperationID: "CreatePayout",
+		Summary:     "Queues a credit transfer to a bank account for the next batch.",
+		Tags:        []string{"v2"},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(b.goType(reflect.TypeOf(handlers.PayoutRequest{})))},
+		Responses:   responses("201", Response{Description: "Created", Headers: location, Content: jsonContent(transfer)}),
+	})
+	b.doc.add(http.MethodGet, "/v2/credit_transfers/{id}", &Operation{
+		OperationID: "GetCreditTransfer",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{stringID("id")},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(transfer)}),
+	})
+
+	batch := b.goType(reflect.TypeOf(models.CreditTransferBatch{}))
+	b.doc.add(http.MethodPost, "/v2/credit_transfer_batches", &Operation{
+		OperationID: "CreateCreditTransferBatch",
+		Summary:     "Collects the pending credit transfers into a pain.001 file.",
+		Tags:        []string{"v2"},
+		RequestBody: &RequestBody{Content: jsonContent(b.goType(reflect.TypeOf(handlers.BatchRequest{})))},
+		Responses:   responses("201", Response{Description: "Created", Headers: location, Content: jsonContent(batch)}),
+	})
+	b.doc.add(http.MethodGet, "/v2/credit_transfer_batches/{id}", &Operation{
+		OperationID: "GetCreditTransferBatch",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{stringID("id")},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(batch)}),
+	})
+	b.doc.add(http.MethodGet, "/v2/credit_transfer_batches/{id}/pain001", &Operation{
+		OperationID: "GetCreditTransferBatchFile",
+		Summary:     "Returns the pain.001.001.03 file of a batch for upload to the bank.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{stringID("id")},
+		Responses: responses("200", Response{Description: "OK", Content: map[string]MediaType{
+			"application/xml": {Schema: &Schema{Type: "string"}},
+		}}),
+	})
+	b.doc.add(http.MethodPost, "/v2/credit_transfer_batches/status_reports", &Operation{
+		OperationID: "ImportPaymentStatusReport",
+		Summary:     "Applies a pain.002 status report from the bank to the batch it refers to.",
+		Tags:        []string{"v2"},
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{
+			"application/xml": {Schema: &Schema{Type: "string"}},
+		}},
+		Responses: responses("200", Response{Description: "OK", Content: jsonContent(b.goType(reflect.TypeOf(handlers.StatusReportResult{})))}),
+	})
+}
+
+func (b *builder) reconciliation() {
+	location := map[string]Header{"Location": {Schema: &Schema{Type: "string", Format: "uri-reference"}}}
+	runID := Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}
+
+	run := b.goType(reflect.TypeOf(models.ReconciliationRun{}))
+	b.doc.add(http.MethodPost, "/v2/reconciliation_runs", &Operation{
+		OperationID: "ImportBankStatement",
+		Summary:     "Matches the lines of a camt.053 or MT940 bank statement against payments and refunds.",
+		Tags:        []string{"v2"},
+		Parameters: []Parameter{
+			{Name: "tolerance", In: "query", Schema: &Schema{Type: "number", Format: "double"}},
+			{Name: "date_window_days", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+		},
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{
+			"application/xml": {Schema: &Schema{Type: "string"}},
+			"text/plain":      {Schema: &Schema{Type: "string"}},
+		}},
+		Responses: responses("201", Response{Description: "Created", Headers: location, Content: jsonContent(run)}),
+	})
+	b.doc.add(http.MethodGet, "/v2/reconciliation_runs", &Operation{
+		OperationID: "ListReconciliationRuns",
+		Tags:        []string{"v2"},
+		Parameters: []Parameter{
+			{Name: "page", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+			{Name: "page_size", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+		},
+		Responses: responses("200", Response{Description: "OK", Content: jsonContent(b.goType(reflect.TypeOf(handlers.RunListEnvelope{})))}),
+	})
+	b.doc.add(http.MethodGet, "/v2/reconciliation_runs/{id}", &Operation{
+		OperationID: "GetReconciliationRun",
+		Summary:     "Returns a run with its lines; status=exceptions lists the lines still to be resolved.",
+		Tags:        []string{"v2"},
+		Parameters: []Parameter{runID, {Name: "status", In: "query", Schema: &Schema{
+			Type: "string",
+			Enum: []string{"exceptions", "matched", "unmatched", "duplicated", "amount_mismatch"},
+		}}},
+		Responses: responses("200", Response{Description: "OK", Content: jsonContent(run)}),
+	})
+	b.doc.add(http.MethodPost, "/v2/reconciliation_runs/{id}/lines/{line}/resolve", &Operation{
+		OperationID: "ResolveReconciliationLine",
+		Summary:     "Accepts or writes off an exception of a run.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{runID, {Name: "line", In: "path", Required: true, Schema: &Schema{Type: "integer", Format: "int32"}}},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(b.goType(reflect.TypeOf(handlers.ResolveRequest{})))},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(b.goType(reflect.TypeOf(models.ReconciliationLine{})))}),
+	})
+}
+
+func (b *builder) settlements() {
+	location := map[string]Header{"Location": {Schema: &Schema{Type: "string", Format: "uri-reference"}}}
+	batchID := Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}
+	page := []Parameter{
+		{Name: "page", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+		{Name: "page_size", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+	}
+
+	batch := b.goType(reflect.TypeOf(models.SettlementBatch{}))
+	b.doc.add(http.MethodPost, "/v2/settlement_batches", &Operation{
+		OperationID: "ImportSettlementReport",
+		Summary:     "Checks a provider's settlement file against its payments and books the provider's fees.",
+		Tags:        []string{"v2"},
+		Parameters: []Parameter{
+			{Name: "provider", In: "query", Required: true, Schema: &Schema{Type: "string"}},
+			{Name: "reference", In: "query", Required: true, Schema: &Schema{Type: "string"}},
+			{Name: "settlement_date", In: "query", Schema: &Schema{Type: "string", Format: "date"}},
+		},
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{
+			"text/csv": {Schema: &Schema{Type: "string"}},
+		}},
+		Responses: responses("201", Response{Description: "Created", Headers: location, Content: jsonContent(batch)}),
+	})
+	b.doc.add(http.MethodGet, "/v2/settlement_batches", &Operation{
+		OperationID: "ListSettlementBatches",
+		Tags:        []string{"v2"},
+		Parameters:  append([]Parameter{{Name: "provider", In: "query", Schema: &Schema{Type: "string"}}}, page...),
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(b.goType(reflect.TypeOf(handlers.SettlementListEnvelope{})))}),
+	})
+	b.doc.add(http.MethodGet, "/v2/settlement_batches/{id}", &Operation{
+		OperationID: "GetSettlementBatch",
+		Summary:     "Returns a batch with its lines; status=discrepancies lists every line that is not matched.",
+		Tags:        []string{"v2"},
+		Parameters: []Parameter{batchID, {Name: "status", In: "query", Schema: &Schema{
+			Type: "string",
+			Enum: []string{"discrepancies", "matched", "unexpected", "missing", "duplicated", "amount_mismatch", "fee_deviation"},
+		}}},
+		Responses: responses("200", Response{Description: "OK", Content: jsonContent(batch)}),
+	})
+	b.doc.add(http.MethodGet, "/v2/settlement_batches/{id}/report", &Operation{
+		OperationID: "GetSettlementReport",
+		Summary:     "Returns the summary and discrepancies of a batch as CSV.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{batchID},
+		Responses: responses("200", Response{Description: "OK", Content: map[string]MediaType{
+			"text/csv": {Schema: &Schema{Type: "string"}},
+		}}),
+	})
+	b.doc.add(http.MethodGet, "/v2/ledger_postings", &Operation{
+		OperationID: "ListLedgerPostings",
+		Tags:        []string{"v2"},
+		Parameters: append([]Parameter{
+			{Name: "payment_id", In: "query", Schema: &Schema{Type: "integer", Format: "int64"}},
+			{Name: "account", In: "query", Schema: &Schema{Type: "string"}},
+		}, page...),
+		Responses: responses("200", Response{Description: "OK", Content: jsonContent(b.goType(reflect.TypeOf(handlers.LedgerListEnvelope{})))}),
+	})
+}
+
+func (b *builder) fx() {
+	page := []Parameter{
+		{Name: "page", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+		{Name: "page_size", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+	}
+
+	b.doc.add(http.MethodPost, "/v2/fx_rates", &Operation{
+		OperationID: "ImportFXRates",
+		Summary:     "Adds exchange rates, as JSON or as a CSV table with the header base,quote,rate,effective_at.",
+		Tags:        []string{"v2"},
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{
+			"application/json": {Schema: b.goType(reflect.TypeOf(handlers.FXRatesRequest{}))},
+			"text/csv":         {Schema: &Schema{Type: "string"}},
+		}},
+		Responses: responses("200", Response{Description: "OK", Content: jsonContent(b.goType(reflect.TypeOf(handlers.FXRatesResponse{})))}),
+	})
+	b.doc.add(http.MethodGet, "/v2/fx_rates", &Operation{
+		OperationID: "ListFXRates",
+		Tags:        []string{"v2"},
+		Parameters: append([]Parameter{
+			{Name: "base", In: "query", Schema: &Schema{Type: "string"}},
+			{Name: "quote", In: "query", Schema: &Schema{Type: "string"}},
+		}, page...),
+		Responses: responses("200", Response{Description: "OK", Content: jsonContent(b.goType(reflect.TypeOf(handlers.FXRateListEnvelope{})))}),
+	})
+	b.doc.add(http.MethodPost, "/v2/fx_quotes", &Operation{
+		OperationID: "CreateFXQuote",
+		Summary:     "Locks the current rate of a currency pair; payments created with the quote convert at it until it expires.",
+		Tags:        []string{"v2"},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(b.goType(reflect.TypeOf(handlers.FXQuoteRequest{})))},
+		Responses:   responses("201", Response{Description: "Created", Content: jsonContent(b.goType(reflect.TypeOf(models.FXQuote{})))}),
+	})
+	b.doc.add(http.MethodGet, "/v2/payments/{id}/conversion", &Operation{
+		OperationID: "GetPaymentConversion",
+		Summary:     "Returns the presentment and settlement amounts of a payment with the rate used.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer", Format: "int64"}}},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(b.goType(reflect.TypeOf(models.PaymentConversion{})))}),
+	})
+}
+
+func (b *builder) fees() {
+	page := []Parameter{
+		{Name: "page", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+		{Name: "page_size", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+	}
+	planID := Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}
+	plan := b.goType(reflect.TypeOf(models.PricingPlan{}))
+	fee := b.goType(reflect.TypeOf(models.PaymentFee{}))
+
+	b.doc.add(http.MethodPost, "/v2/pricing_plans", &Operation{
+		OperationID: "CreatePricingPlan",
+		Summary:     "Defines a pricing plan: a percentage plus fixed fee with caps, volume tiers and overrides by currency and method.",
+		Tags:        []string{"v2"},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(plan)},
+		Responses: responses("201", Response{
+			Description: "Created",
+			Headers:     map[string]Header{"Location": {Schema: &Schema{Type: "string", Format: "uri-reference"}}},
+			Content:     jsonContent(plan),
+		}),
+	})
+	b.doc.add(http.MethodGet, "/v2/pricing_plans", &Operation{
+		OperationID: "ListPricingPlans",
+		Tags:        []string{"v2"},
+		Parameters:  page,
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(b.goType(reflect.TypeOf(handlers.PricingPlanListEnvelope{})))}),
+	})
+	b.doc.add(http.MethodGet, "/v2/pricing_plans/{id}", &Operation{
+		OperationID: "GetPricingPlan",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{planID},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(plan)}),
+	})
+	b.doc.add(http.MethodPut, "/v2/pricing_plans/{id}", &Operation{
+		OperationID: "UpdatePricingPlan",
+		Summary:     "Replaces a plan's name and rules; fees already charged are kept.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{planID},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(plan)},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(plan)}),
+	})
+	b.doc.add(http.MethodGet, "/v2/pricing_plan", &Operation{
+		OperationID: "GetTenantPricingPlan",
+		Summary:     "Returns the pricing plan of the tenant.",
+		Tags:        []string{"v2"},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(plan)}),
+	})
+	assigned := responses("200", Response{Description: "OK", Content: jsonContent(plan)})
+	assigned["204"] = Response{Description: "Tenant taken off its plan"}
+	b.doc.add(http.MethodPut, "/v2/pricing_plan", &Operation{
+		OperationID: "AssignPricingPlan",
+		Summary:     "Puts the tenant on a pricing plan, or with an empty plan_id takes it off its plan.",
+		Tags:        []string{"v2"},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(b.goType(reflect.TypeOf(handlers.AssignPricingPlanRequest{})))},
+		Responses:   assigned,
+	})
+	b.doc.add(http.MethodPost, "/v2/fees/simulate", &Operation{
+		OperationID: "SimulateFee",
+		Summary:     "Prices a hypothetical payment by the tenant's plan without recording anything.",
+		Tags:        []string{"v2"},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(b.goType(reflect.TypeOf(handlers.SimulateFeeRequest{})))},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(fee)}),
+	})
+	b.doc.add(http.MethodGet, "/v2/payments/{id}/fee", &Operation{
+		OperationID: "GetPaymentFee",
+		Summary:     "Returns the fee a payment was charged when it was captured.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer", Format: "int64"}}},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(fee)}),
+	})
+	b.doc.add(http.MethodGet, "/v2/fee_statements/{month}", &Operation{
+		OperationID: "GetFeeStatement",
+		Summary:     "Sums the fees the tenant was charged in a month, formatted YYYY-MM, by currency.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{{Name: "month", In: "path", Required: true, Schema: &Schema{Type: "string"}}},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(b.goType(reflect.TypeOf(models.FeeStatement{})))}),
+	})
+}
+
+func (b *builder) subscriptions() {
+	page := []Parameter{
+		{Name: "page", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+		{Name: "page_size", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+	}
+	id := Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}
+	plan := b.goType(reflect.TypeOf(models.SubscriptionPlan{}))
+	sub := b.goType(reflect.TypeOf(models.Subscription{}))
+	created := func(content map[string]MediaType) map[string]Response {
+		return responses("201", Response{
+			Description: "Created",
+			Headers:     map[string]Header{"Location": {Schema: &Schema{Type: "string", Format: "uri-reference"}}},
+			Content:     content,
+		})
+	}
+
+	b.doc.add(http.MethodPost, "/v2/subscription_plans", &Operation{
+		OperationID: "CreateSubscriptionPlan",
+		Summary:     "Defines a plan billing an amount every interval_count days, weeks, months or years, after an optional free trial.",
+		Tags:        []string{"v2"},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(plan)},
+		Responses:   created(jsonContent(plan)),
+	})
+	b.doc.add(http.MethodGet, "/v2/subscription_plans", &Operation{
+		OperationID: "ListSubscriptionPlans",
+		Tags:        []string{"v2"},
+		Parameters:  page,
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(b.goType(reflect.TypeOf(handlers.SubscriptionPlanListEnvelope{})))}),
+	})
+	b.doc.add(http.MethodGet, "/v2/subscription_plans/{id}", &Operation{
+		OperationID: "GetSubscriptionPlan",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(plan)}),
+	})
+	b.doc.add(http.MethodPost, "/v2/subscriptions", &Operation{
+		OperationID: "CreateSubscription",
+		Summary:     "Subscribes a saved payment method or bank account to a plan; it is charged when the trial ends, or right away without one.",
+		Tags:        []string{"v2"},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(b.goType(reflect.TypeOf(handlers.CreateSubscriptionRequest{})))},
+		Responses:   created(jsonContent(sub)),
+	})
+	b.doc.add(http.MethodGet, "/v2/subscriptions", &Operation{
+		OperationID: "ListSubscriptions",
+		Tags:        []string{"v2"},
+		Parameters:  append(page, Parameter{Name: "status", In: "query", Schema: &Schema{Type: "string"}}),
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(b.goType(reflect.TypeOf(handlers.SubscriptionListEnvelope{})))}),
+	})
+	b.doc.add(http.MethodGet, "/v2/subscriptions/{id}", &Operation{
+		OperationID: "GetSubscription",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(sub)}),
+	})
+	b.doc.add(http.MethodPut, "/v2/subscriptions/{id}/plan", &Operation{
+		OperationID: "ChangeSubscriptionPlan",
+		Summary:     "Moves a subscription to a plan in the same currency; the prorated difference is settled with the next renewal.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(b.goType(reflect.TypeOf(handlers.ChangeSubscriptionPlanRequest{})))},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(sub)}),
+	})
+	b.doc.add(http.MethodPost, "/v2/subscriptions/{id}/cancel", &Operation{
+		OperationID: "CancelSubscription",
+		Summary:     "Cancels a subscription now, or with at_period_end once the paid period is over.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		RequestBody: &RequestBody{Content: jsonContent(b.goType(reflect.TypeOf(handlers.CancelSubscriptionRequest{})))},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(sub)}),
+	})
+	b.doc.add(http.MethodGet, "/v2/subscriptions/{id}/charges", &Operation{
+		OperationID: "ListSubscriptionCharges",
+		Summary:     "Lists the charges of a subscription's renewals, retries included.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(b.goType(reflect.TypeOf(handlers.SubscriptionChargeList{})))}),
+	})
+}
+
+func (b *builder) invoices() {
+	page := []Parameter{
+		{Name: "page", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+		{Name: "page_size", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+	}
+	id := Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}
+	inv := b.goType(reflect.TypeOf(models.Invoice{}))
+
+	b.doc.add(http.MethodPost, "/v2/invoices", &Operation{
+		OperationID: "CreateInvoice",
+		Summary:     "Drafts an invoice; line amounts, discounts, tax and totals are calculated from the lines.",
+		Tags:        []string{"v2"},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(inv)},
+		Responses: responses("201", Response{
+			Description: "Created",
+			Headers:     map[string]Header{"Location": {Schema: &Schema{Type: "string", Format: "uri-reference"}}},
+			Content:     jsonContent(inv),
+		}),
+	})
+	b.doc.add(http.MethodGet, "/v2/invoices", &Operation{
+		OperationID: "ListInvoices",
+		Tags:        []string{"v2"},
+		Parameters:  append(page, Parameter{Name: "status", In: "query", Schema: &Schema{Type: "string"}}),
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(b.goType(reflect.TypeOf(handlers.InvoiceListEnvelope{})))}),
+	})
+	b.doc.add(http.MethodGet, "/v2/invoices/{id}", &Operation{
+		OperationID: "GetInvoice",
+		Summary:     "Returns an invoice with the payments applied to it.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(inv)}),
+	})
+	b.doc.add(http.MethodPut, "/v2/invoices/{id}", &Operation{
+		OperationID: "UpdateInvoice",
+		Summary:     "Replaces the contents of a draft.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(inv)},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(inv)}),
+	})
+	b.doc.add(http.MethodPost, "/v2/invoices/{id}/finalize", &Operation{
+		OperationID: "FinalizeInvoice",
+		Summary:     "Gives a draft the tenant's next invoice number and opens it for payment; an invoice of zero is paid right away.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(inv)}),
+	})
+	b.doc.add(http.MethodPost, "/v2/invoices/{id}/void", &Operation{
+		OperationID: "VoidInvoice",
+		Summary:     "Voids a draft, or an open invoice nothing was paid on; a numbered invoice keeps its number.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(inv)}),
+	})
+	b.doc.add(http.MethodPost, "/v2/invoices/{id}/payments", &Operation{
+		OperationID: "PayInvoice",
+		Summary:     "Applies a payment in the invoice's currency, or part of it, to an open invoice.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(b.goType(reflect.TypeOf(handlers.PayInvoiceRequest{})))},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(inv)}),
+	})
+	b.doc.add(http.MethodGet, "/v2/invoices/{id}/document", &Operation{
+		OperationID: "RenderInvoice",
+		Summary:     "Renders an invoice as an HTML page, or as a PDF document with format=pdf.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id, {Name: "format", In: "query", Schema: &Schema{Type: "string", Enum: []string{"html", "pdf"}}}},
+		Responses: responses("200", Response{Description: "OK", Content: map[string]MediaType{
+			"text/html":       {Schema: &Schema{Type: "string"}},
+			"application/pdf": {Schema: &Schema{Type: "string", Format: "binary"}},
+		}}),
+	})
+}
+
+func (b *builder) customers() {
+	page := []Parameter{
+		{Name: "page", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+		{Name: "page_size", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+	}
+	id := Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}
+	customer := b.goType(reflect.TypeOf(models.Customer{}))
+	methods := b.goType(reflect.TypeOf(models.CustomerPaymentMethods{}))
+
+	b.doc.add(http.MethodPost, "/v2/customers", &Operation{
+		OperationID: "CreateCustomer",
+		Summary:     "Creates a customer; external_ref is unique per tenant.",
+		Tags:        []string{"v2"},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(customer)},
+		Responses: responses("201", Response{
+			Description: "Created",
+			Headers:     map[string]Header{"Location": {Schema: &Schema{Type: "string", Format: "uri-reference"}}},
+			Content:     jsonContent(customer),
+		}),
+	})
+	b.doc.add(http.MethodGet, "/v2/customers", &Operation{
+		OperationID: "ListCustomers",
+		Tags:        []string{"v2"},
+		Parameters: append(page,
+			Parameter{Name: "email", In: "query", Schema: &Schema{Type: "string"}},
+			Parameter{Name: "external_ref", In: "query", Schema: &Schema{Type: "string"}},
+		),
+		Responses: responses("200", Response{Description: "OK", Content: jsonContent(b.goType(reflect.TypeOf(handlers.CustomerListEnvelope{})))}),
+	})
+	b.doc.add(http.MethodGet, "/v2/customers/{id}", &Operation{
+		OperationID: "GetCustomer",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(customer)}),
+	})
+	b.doc.add(http.MethodPut, "/v2/customers/{id}", &Operation{
+		OperationID: "UpdateCustomer",
+		Summary:     "Replaces the email, name, external reference and metadata of a customer.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(customer)},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(customer)}),
+	})
+	b.doc.add(http.MethodDelete, "/v2/customers/{id}", &Operation{
+		OperationID: "DeleteCustomer",
+		Summary:     "Deletes a customer without payments, payment methods or subscriptions.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		Responses:   responses("204", Response{Description: "Deleted"}),
+	})
+	b.doc.add(http.MethodPost, "/v2/customers/{id}/erase", &Operation{
+		OperationID: "EraseCustomer",
+		Summary:     "Erases the personal data of a customer and its saved payment methods; its payments are kept.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(customer)}),
+	})
+	b.doc.add(http.MethodPost, "/v2/customers/{id}/payment_methods", &Operation{
+		OperationID: "AttachPaymentMethod",
+		Summary:     "Saves a card or bank account token for a customer.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(b.goType(reflect.TypeOf(handlers.AttachPaymentMethodRequest{})))},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(methods)}),
+	})
+	b.doc.add(http.MethodGet, "/v2/customers/{id}/payment_methods", &Operation{
+		OperationID: "ListCustomerPaymentMethods",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(methods)}),
+	})
+	b.doc.add(http.MethodGet, "/v2/customers/{id}/payments", &Operation{
+		OperationID: "ListCustomerPayments",
+		Summary:     "Lists the payments of a customer, newest first.",
+		Tags:        []string{"v2"},
+		Parameters:  append([]Parameter{id}, page...),
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(b.goType(reflect.TypeOf(handlers.ListEnvelope{})))}),
+	})
+	b.doc.add(http.MethodGet, "/v2/customers/{id}/lifetime_value", &Operation{
+		OperationID: "GetCustomerLifetimeValue",
+		Summary:     "Sums what a customer paid and was refunded, by currency.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(b.goType(reflect.TypeOf(models.CustomerLifetimeValue{})))}),
+	})
+}
+
+// responses adds the error responses every operation shares to the success
+// response.
+func responses(code string, ok Response) map[string]Response {
+	return map[string]Response{
+		code: ok,
+		"429": {
+			Description: "Rate limit or daily quota exceeded",
+			Headers: map[string]Header{
+				"Retry-After":         {Schema: &Schema{Type: "integer"}},
+				"RateLimit-Limit":     {Schema: &Schema{Type: "integer"}},
+				"RateLimit-Remaining": {Schema: &Schema{Type: "integer"}},
+				"RateLimit-Reset":     {Schema: &Schema{Type: "integer"}},
+			},
+			Content: jsonContent(ref("Error")),
+		},
+		"default": {Description: "Error", Content: jsonContent(ref("Error"))},
+	}
+}
+
+// message registers a proto message under components and references it.
+// Messages follow the proto JSON mapping used by the gateway.
+func (b *builder) message(md protoreflect.MessageDescriptor) *Schema {
+	name := "v1." + string(md.Name())
+	if _, ok := b.doc.Components.Schemas[name]; !ok {
+		s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		b.doc.Components.Schemas[name] = s
+		fields := md.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			s.Properties[string(fields.Get(i).Name())] = b.field(fields.Get(i))
+		}
+	}
+	return ref(name)
+}
+
+func (b *builder) field(fd protoreflect.FieldDescriptor) *Schema {
+	var s *Schema
+	switch fd.Kind() {
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		s = &Schema{Type: "string", Format: "int64"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		s = &Schema{Type: "integer", Format: "int32"}
+	case protoreflect.DoubleKind:
+		s = &Schema{Type: "number", Format: "double"}
+	case protoreflect.FloatKind:
+		s = &Schema{Type: "number", Format: "float"}
+	case protoreflect.BoolKind:
+		s = &Schema{Type: "boolean"}
+	case protoreflect.BytesKind:
+		s = &Schema{Type: "string", Format: "byte"}
+	case protoreflect.EnumKind:
+		s = &Schema{Type: "string"}
+		values := fd.Enum().Values()
+		for i := 0; i < values.Len(); i++ {
+			s.Enum = append(s.Enum, string(values.Get(i).Name()))
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		s = b.message(fd.Message())
+	default:
+		s = &Schema{Type: "string"}
+	}
+	if fd.IsList() {
+		return &Schema{Type: "array", Items: s}
+	}
+	return s
+}
+
+// goType registers a struct encoded with encoding/json under components and
+// references it. Fields without omitempty are required.
+func (b *builder) goType(t reflect.Type) *Schema {
+	if t == reflect.TypeOf(time.Time{}) {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		return &Schema{Type: "array", Items: b.goType(t.Elem())}
+	case reflect.Ptr:
+		return b.goType(t.Elem())
+	case reflect.Struct:
+	default:
+		return &Schema{}
+	}
+
+	name := t.Name()
+	if _, ok := b.doc.Components.Schemas[name]; !ok {
+		s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		b.doc.Components.Schemas[name] = s
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if tag == "-" || !f.IsExported() {
+				continue
+			}
+			if tag == "" {
+				tag = f.Name
+			}
+			s.Properties[tag] = b.goType(f.Type)
+			if !strings.Contains(opts, "omitempty") {
+				s.Required = append(s.Required, tag)
+			}
+		}
+	}
+	return ref(name)
+}
+
+func (b *builder) disputes() {
+	page := []Parameter{
+		{Name: "page", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+		{Name: "page_size", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+	}
+	id := Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}
+	location := map[string]Header{"Location": {Schema: &Schema{Type: "string", Format: "uri-reference"}}}
+	dispute := b.goType(reflect.TypeOf(models.Dispute{}))
+
+	b.doc.add(http.MethodPost, "/v2/disputes", &Operation{
+		OperationID: "CreateDispute",
+		Summary:     "Opens a dispute of a captured payment and holds the disputed amount back from the balance.",
+		Tags:        []string{"v2"},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(b.goType(reflect.TypeOf(handlers.CreateDisputeRequest{})))},
+		Responses:   responses("201", Response{Description: "Created", Headers: location, Content: jsonContent(dispute)}),
+	})
+	b.doc.add(http.MethodGet, "/v2/disputes", &Operation{
+		OperationID: "ListDisputes",
+		Summary:     "Lists disputes, those whose evidence is due soonest first.",
+		Tags:        []string{"v2"},
+		Parameters: append(page,
+			Parameter{Name: "status", In: "query", Schema: &Schema{Type: "string", Enum: []string{"needs_response", "under_review", "won", "lost"}}},
+			Parameter{Name: "payment_id", In: "query", Schema: &Schema{Type: "integer", Format: "int64"}},
+		),
+		Responses: responses("200", Response{Description: "OK", Content: jsonContent(b.goType(reflect.TypeOf(handlers.DisputeListEnvelope{})))}),
+	})
+	b.doc.add(http.MethodGet, "/v2/disputes/{id}", &Operation{
+		OperationID: "GetDispute",
+		Summary:     "Returns a dispute with its evidence.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(dispute)}),
+	})
+	b.doc.add(http.MethodPost, "/v2/disputes/{id}/evidence", &Operation{
+		OperationID: "UploadDisputeEvidence",
+		Summary:     "Adds a PDF, PNG, JPEG or plain text file, sent as the request body, to a dispute that needs a response.",
+		Tags:        []string{"v2"},
+		Parameters: []Parameter{id,
+			{Name: "type", In: "query", Required: true, Schema: &Schema{Type: "string"}},
+			{Name: "filename", In: "query", Required: true, Schema: &Schema{Type: "string"}},
+		},
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{
+			"application/pdf": {Schema: &Schema{Type: "string", Format: "binary"}},
+			"image/png":       {Schema: &Schema{Type: "string", Format: "binary"}},
+			"image/jpeg":      {Schema: &Schema{Type: "string", Format: "binary"}},
+			"text/plain":      {Schema: &Schema{Type: "string"}},
+		}},
+		Responses: responses("201", Response{Description: "Created", Headers: location, Content: jsonContent(b.goType(reflect.TypeOf(models.DisputeEvidence{})))}),
+	})
+	b.doc.add(http.MethodGet, "/v2/disputes/{id}/evidence/{evidence_id}", &Operation{
+		OperationID: "GetDisputeEvidence",
+		Summary:     "Downloads an evidence file.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id, {Name: "evidence_id", In: "path", Required: true, Schema: &Schema{Type: "string"}}},
+		Responses: responses("200", Response{Description: "OK", Content: map[string]MediaType{
+			"application/octet-stream": {Schema: &Schema{Type: "string", Format: "binary"}},
+		}}),
+	})
+	b.doc.add(http.MethodPost, "/v2/disputes/{id}/submit", &Operation{
+		OperationID: "SubmitDispute",
+		Summary:     "Contests a dispute with its evidence, sent to the payment provider for disputes it reported.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(dispute)}),
+	})
+	b.doc.add(http.MethodPost, "/v2/disputes/{id}/accept", &Operation{
+		OperationID: "AcceptDispute",
+		Summary:     "Gives up a dispute that needs a response; the disputed amount goes back to the cardholder.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(dispute)}),
+	})
+	b.doc.add(http.MethodPost, "/v2/disputes/{id}/resolve", &Operation{
+		OperationID: "ResolveDispute",
+		Summary:     "Records that the bank decided a dispute entered by hand as won, releasing the hold, or lost.",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{id},
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(b.goType(reflect.TypeOf(handlers.ResolveDisputeRequest{})))},
+		Responses:   responses("200", Response{Description: "OK", Content: jsonContent(dispute)}),
+	})
+}
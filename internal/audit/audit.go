@@ -0,0 +1,63 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go-lang-final/internal/logging"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	OutcomeAllowed = "allowed"
+	OutcomeDenied  = "denied"
+)
+
+// Event is a single security-relevant action. Events are append-only.
+type Event struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+	Principal string    `json:"principal"`
+	Action    string    `json:"action"`
+	Resource  string    `json:"resource"`
+	Outcome   string    `json:"outcome"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+type Recorder interface {
+	Record(ctx context.Context, event Event) error
+}
+
+// Store writes audit events to the audit_log table and mirrors them to the
+// request logger so they show up next to the access log line.
+type Store struct {
+	DB *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{DB: db}
+}
+
+func (s *Store) Record(ctx context.Context, event Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	if event.RequestID == "" {
+		event.RequestID = logging.RequestIDFromContext(ctx)
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"audit":     true,
+		"principal": event.Principal,
+		"action":    event.Action,
+		"resource":  event.Resource,
+		"outcome":   event.Outcome,
+		"reason":    event.Reason,
+	}).Info("audit event")
+
+	query := `INSERT INTO audit_log (time, request_id, principal, action, resource, outcome, reason) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := s.DB.ExecContext(ctx, query, event.Time, event.RequestID, event.Principal, event.Action, event.Resource, event.Outcome, event.Reason)
+	return err
+}
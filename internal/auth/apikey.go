@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const apiKeyPrefix = "pk"
+
+type APIKey struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Roles     []string   `json:"roles"`
+	TenantID  string     `json:"tenant_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeyStore keeps SHA-256 hashes of API keys in Postgres. The raw key is
+// only ever returned once, at creation or rotation time.
+type APIKeyStore struct {
+	DB *sql.DB
+}
+
+func NewAPIKeyStore(db *sql.DB) *APIKeyStore {
+	return &APIKeyStore{DB: db}
+}
+
+// generateAPIKey returns a key of the form pk_<prefix>_<secret>. The prefix
+// is stored in clear text so lookups do not need to scan every hash.
+func generateAPIKey() (raw string, prefix string, err error) {
+	p := make([]byte, 6)
+	if _, err := rand.Read(p); err != nil {
+		return "", "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	prefix = hex.EncodeToString(p)
+	raw = fmt.Sprintf("%s_%s_%s", apiKeyPrefix, prefix, base64.RawURLEncoding.EncodeToString(secret))
+	return raw, prefix, nil
+}
+
+func hashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func parseAPIKey(raw string) (prefix string, ok bool) {
+	parts := strings.SplitN(raw, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+func IsAPIKey(raw string) bool {
+	_, ok := parseAPIKey(raw)
+	return ok
+}
+
+// CreateAPIKey issues a key bound to a tenant. Cross-tenant keys for
+// platform administrators are created with an empty tenant.
+func (s *APIKeyStore) CreateAPIKey(ctx context.Context, name string, roles []string, tenantID string) (*APIKey, string, error) {
+	raw, prefix, err := generateAPIKey()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %v", err)
+	}
+
+	key := APIKey{Name: name, Prefix: prefix, Roles: roles, TenantID: tenantID}
+	query := `INSERT INTO api_keys (name, prefix, key_hash, roles, tenant_id) VALUES ($1, $2, $3, $4, NULLIF($5, '')) RETURNING id, created_at`
+	if err := s.DB.QueryRowContext(ctx, query, name, prefix, hashAPIKey(raw), pq.Array(roles), tenantID).Scan(&key.ID, &key.CreatedAt); err != nil {
+		return nil, "", err
+	}
+
+	return &key, raw, nil
+}
+
+// RotateAPIKey issues a new key with the same name and lets the old one
+// keep working for the grace period so clients can roll over.
+func (s *APIKeyStore) RotateAPIKey(ctx context.Context, id int64, grace time.Duration) (*APIKey, string, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback()
+
+	var (
+		name     string
+		roles    []string
+		tenantID string
+	)
+	query := `SELECT name, roles, COALESCE(tenant_id, '') FROM api_keys WHERE id = $1 AND revoked_at IS NULL FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, id).Scan(&name, pq.Array(&roles), &tenantID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", fmt.Errorf("api key not found")
+		}
+		return nil, "", err
+	}
+
+	expiresAt := time.Now().Add(grace)
+	if _, err := tx.ExecContext(ctx, `UPDATE api_keys SET expires_at = $2 WHERE id = $1`, id, expiresAt); err != nil {
+		return nil, "", err
+	}
+
+	raw, prefix, err := generateAPIKey()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %v", err)
+	}
+	key := APIKey{Name: name, Prefix: prefix, Roles: roles, TenantID: tenantID}
+	query = `INSERT INTO api_keys (name, prefix, key_hash, roles, tenant_id) VALUES ($1, $2, $3, $4, NULLIF($5, '')) RETURNING id, created_at`
+	if err := tx.QueryRowContext(ctx, query, name, prefix, hashAPIKey(raw), pq.Array(roles), tenantID).Scan(&key.ID, &key.CreatedAt); err != nil {
+		return nil, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", err
+	}
+	return &key, raw, nil
+}
+
+func (s *APIKeyStore) RevokeAPIKey(ctx context.Context, id int64) error {
+	res, err := s.DB.ExecContext(ctx, `UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("api key not found")
+	}
+	return nil
+}
+
+func (s *APIKeyStore) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	query := `SELECT id, name, prefix, roles, COALESCE(tenant_id, ''), created_at, expires_at, revoked_at FROM api_keys ORDER BY id`
+	rows, err := s.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		var key APIKey
+		if err := rows.Scan(&key.ID, &key.Name, &key.Prefix, pq.Array(&key.Roles), &key.TenantID, &key.CreatedAt, &key.ExpiresAt, &key.RevokedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// Authenticate resolves a raw API key to a principal.
+func (s *APIKeyStore) Authenticate(ctx context.Context, raw string) (*Principal, error) {
+	prefix, ok := parseAPIKey(raw)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	var (
+		id        int64
+		name      string
+		hash      string
+		roles     []string
+		tenantID  string
+		expiresAt *time.Time
+		revokedAt *time.Time
+	)
+	query := `SELECT id, name, key_hash, roles, COALESCE(tenant_id, ''), expires_at, revoked_at FROM api_keys WHERE prefix = $1`
+	err := s.DB.QueryRowContext(ctx, query, prefix).Scan(&id, &name, &hash, pq.Array(&roles), &tenantID, &expiresAt, &revokedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(hashAPIKey(raw))) != 1 {
+		return nil, ErrUnauthenticated
+	}
+	if revokedAt != nil || (expiresAt != nil && time.Now().After(*expiresAt)) {
+		return nil, ErrUnauthenticated
+	}
+
+	return &Principal{ID: strconv.FormatInt(id, 10), Name: name, Method: MethodAPIKey, Roles: roles, TenantID: tenantID}, nil
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"strings"
+)
+
+const (
+	AuthorizationHeader = "Authorization"
+	APIKeyHeader        = "X-API-Key"
+)
+
+// Authenticator resolves request credentials to a principal. API keys may
+// be sent in X-API-Key or as a bearer token; any other bearer token is
+// treated as a JWT. Without either, a verified mTLS client certificate is
+// mapped through Certs.
+type Authenticator struct {
+	APIKeys *APIKeyStore
+	JWT     *JWTVerifier
+	Certs   *CertMapper
+}
+
+func NewAuthenticator(apiKeys *APIKeyStore, verifier *JWTVerifier, certs *CertMapper) *Authenticator {
+	return &Authenticator{APIKeys: apiKeys, JWT: verifier, Certs: certs}
+}
+
+func (a *Authenticator) Authenticate(ctx context.Context, authorization, apiKey string, chains [][]*x509.Certificate) (*Principal, error) {
+	if apiKey != "" {
+		return a.authenticateAPIKey(ctx, apiKey)
+	}
+	if authorization == "" && len(chains) > 0 && a.Certs != nil {
+		return a.Certs.Principal(chains)
+	}
+
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, ErrUnauthenticated
+	}
+	token = strings.TrimSpace(token)
+
+	if IsAPIKey(token) {
+		return a.authenticateAPIKey(ctx, token)
+	}
+	if a.JWT == nil {
+		return nil, ErrUnauthenticated
+	}
+	return a.JWT.Verify(token)
+}
+
+func (a *Authenticator) authenticateAPIKey(ctx context.Context, raw string) (*Principal, error) {
+	if a.APIKeys == nil {
+		return nil, ErrUnauthenticated
+	}
+	return a.APIKeys.Authenticate(ctx, raw)
+}
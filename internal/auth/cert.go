@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"crypto/x509"
+
+	"go-lang-final/internal/config"
+)
+
+const MethodMTLS = "mtls"
+
+// CertMapper turns a verified client certificate into a principal using
+// the subjects listed in config. Unlisted certificates are rejected even
+// if they chain to the trusted CA.
+type CertMapper struct {
+	bySubject map[string]config.ClientCertificate
+}
+
+func NewCertMapper(entries []config.ClientCertificate) *CertMapper {
+	m := &CertMapper{bySubject: make(map[string]config.ClientCertificate, len(entries))}
+	for _, e := range entries {
+		m.bySubject[e.Subject] = e
+	}
+	return m
+}
+
+// Principal maps the leaf of the first verified chain. Chains must come
+// from a completed handshake, never from request headers.
+func (m *CertMapper) Principal(chains [][]*x509.Certificate) (*Principal, error) {
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return nil, ErrUnauthenticated
+	}
+	leaf := chains[0][0]
+
+	entry, ok := m.bySubject[leaf.Subject.String()]
+	if !ok {
+		entry, ok = m.bySubject[leaf.Subject.CommonName]
+	}
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	id := entry.ID
+	if id == "" {
+		id = leaf.Subject.CommonName
+	}
+	return &Principal{ID: id, Name: leaf.Subject.CommonName, Method: MethodMTLS, Roles: entry.Roles, TenantID: entry.TenantID}, nil
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"strings"
+
+	"go-lang-final/internal/logging"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func UnaryServerInterceptor(a *Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := a.authenticateIncoming(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func StreamServerInterceptor(a *Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticateIncoming(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (a *Authenticator) authenticateIncoming(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var chains [][]*x509.Certificate
+	if pr, ok := peer.FromContext(ctx); ok {
+		if info, ok := pr.AuthInfo.(credentials.TLSInfo); ok {
+			chains = info.State.VerifiedChains
+		}
+	}
+
+	p, err := a.Authenticate(ctx, firstValue(md, AuthorizationHeader), firstValue(md, APIKeyHeader), chains)
+	if err != nil {
+		if !errors.Is(err, ErrUnauthenticated) {
+			logging.FromContext(ctx).WithError(err).Error("authentication backend failed")
+			return ctx, status.Error(codes.Internal, "internal error")
+		}
+		return ctx, status.Error(codes.Unauthenticated, ErrUnauthenticated.Error())
+	}
+
+	ctx = WithPrincipal(ctx, p)
+	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).WithField("principal", p.ID))
+	return ctx, nil
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if vals := md.Get(strings.ToLower(key)); len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
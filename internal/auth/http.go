@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"crypto/x509"
+	"errors"
+	"net/http"
+
+	"go-lang-final/internal/logging"
+
+	"github.com/gorilla/mux"
+)
+
+// Middleware rejects requests without valid credentials with 401 and puts
+// the authenticated principal into the request context.
+func Middleware(a *Authenticator) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var chains [][]*x509.Certificate
+			if r.TLS != nil {
+				chains = r.TLS.VerifiedChains
+			}
+
+			p, err := a.Authenticate(r.Context(), r.Header.Get(AuthorizationHeader), r.Header.Get(APIKeyHeader), chains)
+			if err != nil {
+				writeAuthError(w, r, err)
+				return
+			}
+
+			ctx := WithPrincipal(r.Context(), p)
+			ctx = logging.WithLogger(ctx, logging.FromContext(ctx).WithField("principal", p.ID))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func writeAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	if !errors.Is(err, ErrUnauthenticated) {
+		logging.FromContext(r.Context()).WithError(err).Error("authentication backend failed")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("WWW-Authenticate", `Bearer realm="payments"`)
+	http.Error(w, ErrUnauthenticated.Error(), http.StatusUnauthorized)
+}
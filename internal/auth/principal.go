@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUnauthenticated is returned for every authentication failure so that
+// callers cannot distinguish an unknown key from a revoked or expired one.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Principal is the authenticated caller attached to a request context.
+type Principal struct {
+	ID       string
+	Name     string
+	Method   string
+	Roles    []string
+	TenantID string
+}
+
+type contextKey int
+
+const principalKey contextKey = 0
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey).(*Principal)
+	return p, ok && p != nil
+}
@@ -0,0 +1,188 @@
+// Package blob keeps files, such as dispute evidence, outside the database.
+// Stores are addressed by keys of slash-separated segments; the disk store
+// lays them out as directories under its root, and other backends can be
+// plugged in by implementing Store.
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Store types.
+const (
+	TypeDisk   = "disk"
+	TypeMemory = "memory"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Store is implemented by every place blobs can be kept in.
+type Store interface {
+	// Put stores the content of r under key, replacing what was there.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get opens the blob under key; the caller closes it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob under key. Deleting a missing blob is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+}
+
+// New returns a store of the given type. The disk store keeps its blobs
+// under dir, which is created if missing.
+func New(typ, dir string) (Store, error) {
+	switch typ {
+	case "", TypeDisk:
+		return NewDisk(dir)
+	case TypeMemory:
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", typ)
+	}
+}
+
+var segment = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+// CheckKey accepts keys of segments of letters, digits, dots, dashes and
+// underscores separated by slashes. No segment starts with a dot, so keys
+// cannot climb out of a disk store's root.
+func CheckKey(key string) error {
+	for _, s := range strings.Split(key, "/") {
+		if !segment.MatchString(s) {
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+	}
+	return nil
+}
+
+// Disk keeps blobs as files under a root directory.
+type Disk struct {
+	root string
+}
+
+func NewDisk(root string) (*Disk, error) {
+	if root == "" {
+		return nil, errors.New("disk blob store needs a directory")
+	}
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return nil, err
+	}
+	return &Disk{root: root}, nil
+}
+
+// Put writes the blob to a temporary file first and renames it into place,
+// so readers never see half a blob.
+func (d *Disk) Put(ctx context.Context, key string, r io.Reader) error {
+	if err := CheckKey(key); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	name := d.path(key)
+	if err := os.MkdirAll(filepath.Dir(name), 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(name), ".put-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+func (d *Disk) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := CheckKey(key); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(d.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (d *Disk) Delete(ctx context.Context, key string) error {
+	if err := CheckKey(key); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := os.Remove(d.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (d *Disk) path(key string) string {
+	return filepath.Join(d.root, filepath.FromSlash(key))
+}
+
+// Memory keeps blobs in memory, for development and tests. It is safe for
+// concurrent use.
+type Memory struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func NewMemory() *Memory {
+	return &Memory{blobs: make(map[string][]byte)}
+}
+
+func (m *Memory) Put(ctx context.Context, key string, r io.Reader) error {
+	if err := CheckKey(key); err != nil {
+		return err
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.blobs[key] = b
+	return nil
+}
+
+func (m *Memory) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.blobs[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.blobs, key)
+	return nil
+}
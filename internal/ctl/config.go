@@ -0,0 +1,213 @@
+// Package ctl implements the parts of paymentsctl that do not depend on flag
+// parsing: connection profiles, output rendering, exports and confirmation
+// prompts.
+package ctl
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go-lang-final/pkg/paymentsclient"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigEnv overrides the location of the config file.
+const ConfigEnv = "PAYMENTSCTL_CONFIG"
+
+const (
+	TransportGRPC = "grpc"
+	TransportREST = "rest"
+)
+
+// Config is the paymentsctl config file:
+//
+//	default: prod
+//	profiles:
+//	  prod:
+//	    grpc_addr: payments.example.com:443
+//	    rest_url: https://payments.example.com
+//	    api_key: $PAYMENTS_API_KEY
+//	    tls:
+//	      enabled: true
+type Config struct {
+	Default  string             `yaml:"default"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// Profile describes how to reach and authenticate to one deployment.
+// APIKey and Token are expanded from the environment, so secrets need not
+// be written to the file.
+type Profile struct {
+	// Transport is grpc (the default) or rest.
+	Transport string `yaml:"transport"`
+	GRPCAddr  string `yaml:"grpc_addr"`
+	RESTURL   string `yaml:"rest_url"`
+	APIKey    string `yaml:"api_key"`
+	Token     string `yaml:"token"`
+	// Tenant selects the tenant for cross-tenant administrators.
+	Tenant  string    `yaml:"tenant"`
+	Timeout string    `yaml:"timeout"`
+	TLS     TLSConfig `yaml:"tls"`
+}
+
+// TLSConfig enables TLS, optionally with a private CA and a client
+// certificate for mutual TLS.
+type TLSConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	CAFile     string `yaml:"ca_file"`
+	CertFile   string `yaml:"cert_file"`
+	KeyFile    string `yaml:"key_file"`
+	ServerName string `yaml:"server_name"`
+}
+
+// DefaultProfile is used when there is no config file.
+var DefaultProfile = Profile{
+	Transport: TransportGRPC,
+	GRPCAddr:  "localhost:50051",
+	RESTURL:   "http://localhost:8080",
+}
+
+// ConfigPath returns $PAYMENTSCTL_CONFIG, or config.yaml in the user's
+// config directory.
+func ConfigPath() string {
+	if path := os.Getenv(ConfigEnv); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "paymentsctl", "config.yaml")
+}
+
+// LoadConfig reads the config file at path. A missing file is not an error
+// and yields an empty config.
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(raw, cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Profile returns the named profile, or the default one when name is
+// empty. Unset fields fall back to DefaultProfile.
+func (c *Config) Profile(name string) (Profile, error) {
+	if name == "" {
+		name = c.Default
+	}
+	p := DefaultProfile
+	if name != "" {
+		named, ok := c.Profiles[name]
+		if !ok {
+			return Profile{}, fmt.Errorf("unknown profile %q", name)
+		}
+		p = named
+	}
+
+	if p.Transport == "" {
+		p.Transport = DefaultProfile.Transport
+	}
+	if p.GRPCAddr == "" {
+		p.GRPCAddr = DefaultProfile.GRPCAddr
+	}
+	if p.RESTURL == "" {
+		p.RESTURL = DefaultProfile.RESTURL
+	}
+	p.APIKey = os.ExpandEnv(p.APIKey)
+	p.Token = os.ExpandEnv(p.Token)
+	return p, nil
+}
+
+// Connect returns a client for the profile and a Closer releasing its
+// connection.
+func (p Profile) Connect() (*paymentsclient.Client, io.Closer, error) {
+	opts := []paymentsclient.Option{paymentsclient.WithTenant(p.Tenant)}
+	switch {
+	case p.Token != "":
+		opts = append(opts, paymentsclient.WithBearerToken(p.Token))
+	case p.APIKey != "":
+		opts = append(opts, paymentsclient.WithAPIKey(p.APIKey))
+	}
+	if p.Timeout != "" {
+		timeout, err := time.ParseDuration(p.Timeout)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid timeout %q: %w", p.Timeout, err)
+		}
+		opts = append(opts, paymentsclient.WithTimeout(timeout))
+	}
+
+	var tlsConfig *tls.Config
+	if p.TLS.Enabled {
+		var err error
+		if tlsConfig, err = p.TLS.config(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	switch p.Transport {
+	case TransportGRPC:
+		creds := insecure.NewCredentials()
+		if tlsConfig != nil {
+			creds = credentials.NewTLS(tlsConfig)
+		}
+		conn, err := grpc.NewClient(p.GRPCAddr, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, nil, err
+		}
+		return paymentsclient.NewGRPC(conn, opts...), conn, nil
+	case TransportREST:
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		return paymentsclient.NewHTTP(p.RESTURL, httpClient, opts...), noConn{}, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown transport %q, want grpc or rest", p.Transport)
+	}
+}
+
+// noConn is the Closer of REST clients, which share the HTTP client's pool.
+type noConn struct{}
+
+func (noConn) Close() error { return nil }
+
+func (c TLSConfig) config() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: c.ServerName}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.CAFile)
+		}
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
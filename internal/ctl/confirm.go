@@ -0,0 +1,57 @@
+package ctl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var (
+	ErrAborted        = errors.New("aborted")
+	ErrNotInteractive = errors.New("refusing to change payments without a terminal to confirm on; pass -yes")
+)
+
+// Confirmer asks before destructive commands. Yes skips the question, which
+// is required when there is no terminal to ask on.
+type Confirmer struct {
+	In          io.Reader
+	Out         io.Writer
+	Interactive bool
+	Yes         bool
+}
+
+// NewConfirmer asks on stdin and stderr, which keeps stdout clean for output.
+func NewConfirmer(yes bool) *Confirmer {
+	return &Confirmer{In: os.Stdin, Out: os.Stderr, Interactive: IsTerminal(os.Stdin), Yes: yes}
+}
+
+// Confirm returns nil once the question was answered with y or yes.
+func (c *Confirmer) Confirm(format string, args ...interface{}) error {
+	if c.Yes {
+		return nil
+	}
+	if !c.Interactive {
+		return ErrNotInteractive
+	}
+
+	fmt.Fprintf(c.Out, format+" [y/N] ", args...)
+	answer, err := bufio.NewReader(c.In).ReadString('\n')
+	if err != nil && answer == "" {
+		return ErrAborted
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	default:
+		return ErrAborted
+	}
+}
+
+// IsTerminal reports whether f is a character device such as a terminal.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,69 @@
+package ctl
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"go-lang-final/pkg/paymentsclient"
+)
+
+// Export formats.
+const (
+	CSV   = "csv"
+	JSONL = "jsonl"
+)
+
+// Exporter writes payments one at a time, so exports of any size stream
+// straight from the list iterator.
+type Exporter interface {
+	Write(paymentsclient.Payment) error
+	// Close flushes buffered output; it does not close the writer.
+	Close() error
+}
+
+func NewExporter(w io.Writer, format string) (Exporter, error) {
+	switch format {
+	case CSV:
+		e := &csvExporter{w: csv.NewWriter(w)}
+		return e, e.w.Write([]string{"id", "tenant_id", "amount", "currency", "status", "refunded_amount"})
+	case JSONL:
+		return &jsonlExporter{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q, want csv or jsonl", format)
+	}
+}
+
+type csvExporter struct {
+	w *csv.Writer
+}
+
+func (e *csvExporter) Write(p paymentsclient.Payment) error {
+	return e.w.Write([]string{
+		strconv.FormatInt(p.ID, 10),
+		p.TenantID,
+		amount(p.Amount),
+		p.Currency,
+		p.Status,
+		amount(p.RefundedAmount),
+	})
+}
+
+func (e *csvExporter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type jsonlExporter struct {
+	enc *json.Encoder
+}
+
+func (e *jsonlExporter) Write(p paymentsclient.Payment) error {
+	return e.enc.Encode(p)
+}
+
+func (e *jsonlExporter) Close() error {
+	return nil
+}
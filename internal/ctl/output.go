@@ -0,0 +1,131 @@
+package ctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"go-lang-final/pkg/paymentsclient"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats.
+const (
+	Table = "table"
+	JSON  = "json"
+	YAML  = "yaml"
+)
+
+// Printer renders payments and events in one output format.
+type Printer struct {
+	w      io.Writer
+	format string
+	// header is set once a streamed table printed its header.
+	header bool
+}
+
+func NewPrinter(w io.Writer, format string) (*Printer, error) {
+	switch format {
+	case Table, JSON, YAML:
+		return &Printer{w: w, format: format}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q, want table, json or yaml", format)
+	}
+}
+
+func (p *Printer) Payment(payment paymentsclient.Payment) error {
+	if p.format != Table {
+		return p.encode(payment)
+	}
+	return p.Payments([]paymentsclient.Payment{payment})
+}
+
+func (p *Printer) Payments(payments []paymentsclient.Payment) error {
+	if payments == nil {
+		payments = []paymentsclient.Payment{}
+	}
+	if p.format != Table {
+		return p.encode(payments)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTENANT\tAMOUNT\tCURRENCY\tSTATUS\tREFUNDED")
+	for _, payment := range payments {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", payment.ID, payment.TenantID, amount(payment.Amount),
+			payment.Currency, payment.Status, amount(payment.RefundedAmount))
+	}
+	return tw.Flush()
+}
+
+func (p *Printer) Events(events []paymentsclient.Event) error {
+	if events == nil {
+		events = []paymentsclient.Event{}
+	}
+	if p.format != Table {
+		return p.encode(events)
+	}
+	for _, e := range events {
+		if err := p.Event(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Event prints a single event, as watch does while following the feed.
+// Tables print their header before the first event and JSON is written one
+// object per line.
+func (p *Printer) Event(e paymentsclient.Event) error {
+	switch p.format {
+	case JSON:
+		return json.NewEncoder(p.w).Encode(e)
+	case YAML:
+		if p.header {
+			fmt.Fprintln(p.w, "---")
+		}
+		p.header = true
+		return p.encode(e)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 12, 0, 2, ' ', 0)
+	if !p.header {
+		fmt.Fprintln(tw, "EVENT\tPAYMENT\tTENANT\tTYPE\tAMOUNT\tCURRENCY\tSTATUS\tTIME\tREASON")
+		p.header = true
+	}
+	fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.ID, e.PaymentID, e.TenantID, e.Type, amount(e.Amount),
+		e.Currency, e.Status, e.CreatedAt.UTC().Format(time.RFC3339), e.Reason)
+	return tw.Flush()
+}
+
+// encode writes v as JSON or YAML. YAML goes through JSON so both formats
+// use the same field names.
+func (p *Printer) encode(v interface{}) error {
+	if p.format == JSON {
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var generic interface{}
+	if err := yaml.Unmarshal(raw, &generic); err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(p.w)
+	enc.SetIndent(2)
+	if err := enc.Encode(generic); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func amount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
@@ -0,0 +1,61 @@
+// Package customers checks the records of customers before they are
+// stored.
+package customers
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"go-lang-final/internal/metadata"
+	"go-lang-final/internal/models"
+)
+
+// Limits of a customer.
+const (
+	MaxEmailLength       = 254
+	MaxNameLength        = 500
+	MaxExternalRefLength = 255
+)
+
+var ErrInvalidCustomer = errors.New("invalid customer")
+
+// Normalize trims the fields of c and checks them against the limits. A
+// customer needs an email, a name or an external reference to be told
+// apart from the others.
+func Normalize(c *models.Customer) error {
+	c.Email = strings.TrimSpace(c.Email)
+	c.Name = strings.TrimSpace(c.Name)
+	c.ExternalRef = strings.TrimSpace(c.ExternalRef)
+	if err := CheckEmail(c.Email); err != nil {
+		return fmt.Errorf("%w: email %v", ErrInvalidCustomer, err)
+	}
+	switch {
+	case c.Email == "" && c.Name == "" && c.ExternalRef == "":
+		return fmt.Errorf("%w: email, name or external_ref is required", ErrInvalidCustomer)
+	case len(c.Name) > MaxNameLength:
+		return fmt.Errorf("%w: name is longer than %d bytes", ErrInvalidCustomer, MaxNameLength)
+	case len(c.ExternalRef) > MaxExternalRefLength:
+		return fmt.Errorf("%w: external_ref is longer than %d bytes", ErrInvalidCustomer, MaxExternalRefLength)
+	}
+	if err := metadata.Check(c.Metadata); err != nil {
+		return fmt.Errorf("%w: metadata %v", ErrInvalidCustomer, err)
+	}
+	return nil
+}
+
+// CheckEmail accepts an empty email or a bare address, without a display
+// name.
+func CheckEmail(email string) error {
+	if email == "" {
+		return nil
+	}
+	if len(email) > MaxEmailLength {
+		return fmt.Errorf("is longer than %d bytes", MaxEmailLength)
+	}
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return errors.New("must be an email address")
+	}
+	return nil
+}
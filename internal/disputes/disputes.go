@@ -0,0 +1,195 @@
+// Package disputes checks disputes and their evidence before they are
+// stored, works out which status changes are allowed and when the tenant
+// is reminded of an evidence deadline.
+package disputes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Dispute reasons, after the categories the card networks share.
+const (
+	ReasonFraudulent           = "fraudulent"
+	ReasonUnrecognized         = "unrecognized"
+	ReasonDuplicate            = "duplicate"
+	ReasonProductNotReceived   = "product_not_received"
+	ReasonProductUnacceptable  = "product_unacceptable"
+	ReasonSubscriptionCanceled = "subscription_canceled"
+	ReasonCreditNotProcessed   = "credit_not_processed"
+	ReasonGeneral              = "general"
+)
+
+// Evidence types.
+const (
+	EvidenceReceipt               = "receipt"
+	EvidenceCustomerCommunication = "customer_communication"
+	EvidenceShippingDocumentation = "shipping_documentation"
+	EvidenceServiceDocumentation  = "service_documentation"
+	EvidenceRefundPolicy          = "refund_policy"
+	EvidenceCancellationPolicy    = "cancellation_policy"
+	EvidenceOther                 = "other"
+)
+
+// Limits of evidence.
+const (
+	MaxEvidenceSize   = 5 << 20
+	MaxEvidenceFiles  = 20
+	MaxFilenameLength = 255
+)
+
+// DefaultResponseWindow is how long a dispute opened without a deadline
+// leaves to submit evidence.
+const DefaultResponseWindow = 7 * 24 * time.Hour
+
+// DefaultReminders are the lead times before an evidence deadline at which
+// the tenant is reminded, used unless others are configured.
+var DefaultReminders = []time.Duration{72 * time.Hour, 24 * time.Hour}
+
+var (
+	ErrInvalidDispute  = errors.New("invalid dispute")
+	ErrInvalidEvidence = errors.New("invalid dispute evidence")
+)
+
+var reasons = map[string]bool{
+	ReasonFraudulent: true, ReasonUnrecognized: true, ReasonDuplicate: true, ReasonProductNotReceived: true,
+	ReasonProductUnacceptable: true, ReasonSubscriptionCanceled: true, ReasonCreditNotProcessed: true, ReasonGeneral: true,
+}
+
+var evidenceTypes = map[string]bool{
+	EvidenceReceipt: true, EvidenceCustomerCommunication: true, EvidenceShippingDocumentation: true,
+	EvidenceServiceDocumentation: true, EvidenceRefundPolicy: true, EvidenceCancellationPolicy: true, EvidenceOther: true,
+}
+
+// contentTypes are the kinds of files card networks accept as evidence.
+var contentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/png":       true,
+	"image/jpeg":      true,
+	"text/plain":      true,
+}
+
+// ValidReason reports whether reason is a known dispute reason.
+func ValidReason(reason string) bool {
+	return reasons[reason]
+}
+
+// ValidEvidenceType reports whether typ is a known type of evidence.
+func ValidEvidenceType(typ string) bool {
+	return evidenceTypes[typ]
+}
+
+// ValidContentType reports whether evidence may be a file of the media
+// type contentType; parameters such as charset are ignored.
+func ValidContentType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return contentTypes[strings.ToLower(strings.TrimSpace(mediaType))]
+}
+
+// Validate checks a new dispute, giving it DefaultResponseWindow from now
+// when it has no evidence deadline.
+func Validate(d *models.Dispute, now time.Time) error {
+	if d.EvidenceDueBy.IsZero() {
+		d.EvidenceDueBy = now.Add(DefaultResponseWindow)
+	}
+	switch {
+	case !ValidReason(d.Reason):
+		return fmt.Errorf("%w: unknown reason %q", ErrInvalidDispute, d.Reason)
+	case d.Amount <= 0:
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidDispute)
+	case !d.EvidenceDueBy.After(now):
+		return fmt.Errorf("%w: evidence_due_by must be in the future", ErrInvalidDispute)
+	}
+	return nil
+}
+
+// CheckEvidence checks the type, name, media type and size of an evidence
+// file.
+func CheckEvidence(e models.DisputeEvidence) error {
+	switch {
+	case !ValidEvidenceType(e.Type):
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidEvidence, e.Type)
+	case e.Filename == "" || len(e.Filename) > MaxFilenameLength || path.Base(e.Filename) != e.Filename || strings.ContainsAny(e.Filename, `\/`):
+		return fmt.Errorf("%w: filename must be a file name of 1 to %d bytes", ErrInvalidEvidence, MaxFilenameLength)
+	case !ValidContentType(e.ContentType):
+		return fmt.Errorf("%w: content type %q is not accepted", ErrInvalidEvidence, e.ContentType)
+	case e.Size <= 0 || e.Size > MaxEvidenceSize:
+		return fmt.Errorf("%w: file must be 1 to %d bytes", ErrInvalidEvidence, MaxEvidenceSize)
+	}
+	return nil
+}
+
+// CanMove reports whether a dispute may go from status from to status to.
+// Evidence can only be added while a dispute needs a response, and closed
+// disputes stay closed.
+func CanMove(from, to string) bool {
+	switch from {
+	case models.DisputeNeedsResponse:
+		return to == models.DisputeUnderReview || to == models.DisputeWon || to == models.DisputeLost
+	case models.DisputeUnderReview:
+		return to == models.DisputeWon || to == models.DisputeLost
+	}
+	return false
+}
+
+// Closed reports whether status is the final status of a dispute.
+func Closed(status string) bool {
+	return status == models.DisputeWon || status == models.DisputeLost
+}
+
+// RemindersDue returns how many of the reminders at leads before dueBy
+// should have been sent by now. Once the deadline has passed none are.
+func RemindersDue(dueBy, now time.Time, leads []time.Duration) int {
+	if !now.Before(dueBy) {
+		return 0
+	}
+	due := 0
+	for _, lead := range leads {
+		if !now.Before(dueBy.Add(-lead)) {
+			due++
+		}
+	}
+	return due
+}
+
+// SortReminders orders lead times from the longest to the shortest, the
+// order their reminders are sent in.
+func SortReminders(leads []time.Duration) {
+	sort.Slice(leads, func(i, j int) bool { return leads[i] > leads[j] })
+}
+
+// Reminder is the notice that the evidence of a dispute is due in Left.
+type Reminder struct {
+	Dispute models.Dispute
+	Left    time.Duration
+}
+
+// Notifier delivers reminders of evidence deadlines to the tenant.
+type Notifier interface {
+	Remind(ctx context.Context, r Reminder) error
+}
+
+// LogNotifier writes reminders to the log of ctx as warnings. It is the
+// notifier used unless another is configured.
+type LogNotifier struct{}
+
+func (LogNotifier) Remind(ctx context.Context, r Reminder) error {
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"tenant_id":       r.Dispute.TenantID,
+		"dispute_id":      r.Dispute.ID,
+		"payment_id":      r.Dispute.PaymentID,
+		"evidence_due_by": r.Dispute.EvidenceDueBy,
+		"left":            r.Left.Round(time.Minute).String(),
+	}).Warn("dispute evidence due soon")
+	return nil
+}
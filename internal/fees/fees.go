@@ -0,0 +1,191 @@
+// Package fees prices payments by the pricing plan of the merchant that
+// takes them. A plan charges a percentage of the amount plus a fixed fee,
+// optionally kept between a minimum and a maximum, with volume tiers and
+// overrides by currency and payment method. Percentages are exact decimals
+// and fees round half away from zero to minor units, so a payment is
+// always priced the same.
+package fees
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/money"
+)
+
+// Payment methods a plan can price differently.
+const (
+	MethodCard         = "card"
+	MethodBankTransfer = "bank_transfer"
+)
+
+// PercentageScale is the number of decimals a percentage may have.
+const PercentageScale = 4
+
+var ErrInvalidPlan = errors.New("invalid pricing plan")
+
+var (
+	currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+	decimal      = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+)
+
+// Fee is what a payment is charged, in minor units of its currency, and the
+// percentage and fixed fee it was priced at.
+type Fee struct {
+	Amount     int64
+	Percentage string
+	Fixed      int64
+}
+
+// Method returns how a payment made with paymentMethod is priced.
+func Method(paymentMethod string) string {
+	if strings.HasPrefix(paymentMethod, models.BankAccountPrefix) {
+		return MethodBankTransfer
+	}
+	return MethodCard
+}
+
+// Validate checks a plan's pricing and overrides and normalizes the
+// notation of its percentages.
+func Validate(plan *models.PricingPlan) error {
+	if strings.TrimSpace(plan.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidPlan)
+	}
+	if err := validatePricing(&plan.Pricing); err != nil {
+		return fmt.Errorf("%w: pricing: %v", ErrInvalidPlan, err)
+	}
+	seen := make(map[string]bool, len(plan.Overrides))
+	for i := range plan.Overrides {
+		o := &plan.Overrides[i]
+		switch {
+		case o.Currency == "" && o.Method == "":
+			return fmt.Errorf("%w: overrides[%d]: currency or method is required", ErrInvalidPlan, i)
+		case o.Currency != "" && !currencyCode.MatchString(o.Currency):
+			return fmt.Errorf("%w: overrides[%d]: currency must be an ISO 4217 code", ErrInvalidPlan, i)
+		case o.Method != "" && o.Method != MethodCard && o.Method != MethodBankTransfer:
+			return fmt.Errorf("%w: overrides[%d]: method must be %s or %s", ErrInvalidPlan, i, MethodCard, MethodBankTransfer)
+		case seen[o.Currency+"/"+o.Method]:
+			return fmt.Errorf("%w: overrides[%d]: duplicate override", ErrInvalidPlan, i)
+		}
+		seen[o.Currency+"/"+o.Method] = true
+		if err := validatePricing(&o.Pricing); err != nil {
+			return fmt.Errorf("%w: overrides[%d]: %v", ErrInvalidPlan, i, err)
+		}
+	}
+	return nil
+}
+
+func validatePricing(p *models.Pricing) error {
+	var err error
+	if p.Percentage, err = NormalizePercentage(p.Percentage); err != nil {
+		return err
+	}
+	if p.Fixed < 0 || p.Min < 0 || p.Max < 0 {
+		return errors.New("fixed, min and max may not be negative")
+	}
+	if p.Max > 0 && p.Max < p.Min {
+		return errors.New("max is below min")
+	}
+	for i := range p.Tiers {
+		t := &p.Tiers[i]
+		if t.Volume < 0 || t.Fixed < 0 {
+			return fmt.Errorf("tiers[%d]: volume and fixed may not be negative", i)
+		}
+		if i > 0 && t.Volume <= p.Tiers[i-1].Volume {
+			return fmt.Errorf("tiers[%d]: volumes must ascend", i)
+		}
+		if t.Percentage, err = NormalizePercentage(t.Percentage); err != nil {
+			return fmt.Errorf("tiers[%d]: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// NormalizePercentage checks that s is a decimal percent between 0 and 100
+// of at most PercentageScale decimals and drops its trailing zeros. An
+// empty percentage stays empty and charges nothing.
+func NormalizePercentage(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	rat, err := parsePercentage(s)
+	if err != nil {
+		return "", err
+	}
+	s = strings.TrimRight(rat.FloatString(PercentageScale), "0")
+	return strings.TrimSuffix(s, "."), nil
+}
+
+func parsePercentage(s string) (*big.Rat, error) {
+	if !decimal.MatchString(s) {
+		return nil, fmt.Errorf("invalid percentage %q", s)
+	}
+	if _, frac, ok := strings.Cut(s, "."); ok && len(frac) > PercentageScale {
+		return nil, fmt.Errorf("percentage %q has more than %d decimals", s, PercentageScale)
+	}
+	rat, _ := new(big.Rat).SetString(s)
+	if rat.Cmp(big.NewRat(100, 1)) > 0 {
+		return nil, fmt.Errorf("percentage %q is above 100", s)
+	}
+	return rat, nil
+}
+
+// Calculate prices amount minor units in currency, paid by method, for a
+// tenant whose volume of the month so far in currency is volume minor
+// units. The plan is expected to be valid.
+func Calculate(plan models.PricingPlan, amount int64, currency, method string, volume int64) (Fee, error) {
+	p := pricingFor(plan, currency, method)
+	fee := Fee{Percentage: p.Percentage, Fixed: money.Minor(p.Fixed)}
+	for _, t := range p.Tiers {
+		if volume < money.Minor(t.Volume) {
+			break
+		}
+		fee.Percentage, fee.Fixed = t.Percentage, money.Minor(t.Fixed)
+	}
+
+	if fee.Percentage != "" {
+		pct, err := parsePercentage(fee.Percentage)
+		if err != nil {
+			return Fee{}, fmt.Errorf("%w: %v", ErrInvalidPlan, err)
+		}
+		// The percentage rounds to the smallest amount of currency, so a
+		// yen fee is whole yen.
+		step := money.Step(currency)
+		fee.Amount, _ = money.Round(new(big.Rat).Mul(new(big.Rat).SetInt64(amount), pct.Quo(pct, big.NewRat(100*step, 1))))
+		fee.Amount *= step
+	}
+	fee.Amount += fee.Fixed
+	if min := money.Minor(p.Min); fee.Amount < min {
+		fee.Amount = min
+	}
+	if max := money.Minor(p.Max); max > 0 && fee.Amount > max {
+		fee.Amount = max
+	}
+	return fee, nil
+}
+
+// pricingFor picks the most specific override matching the payment: one
+// for both its currency and method, then its currency, then its method.
+func pricingFor(plan models.PricingPlan, currency, method string) models.Pricing {
+	pricing, best := plan.Pricing, 0
+	for _, o := range plan.Overrides {
+		if o.Currency != "" && o.Currency != currency || o.Method != "" && o.Method != method {
+			continue
+		}
+		score := 0
+		if o.Currency != "" {
+			score += 2
+		}
+		if o.Method != "" {
+			score++
+		}
+		if score > best {
+			pricing, best = o.Pricing, score
+		}
+	}
+	return pricing
+}
@@ -0,0 +1,182 @@
+// Package fx converts amounts between currencies. Rates are exact decimals
+// kept as strings so the rate a conversion used can be stored and shown
+// without floating point drift; conversions round half away from zero to
+// minor units, so the same amount and rate always give the same result.
+package fx
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"go-lang-final/internal/money"
+)
+
+// Scale is the number of decimals a rate is stored with. Inverted rates are
+// rounded to it.
+const Scale = 10
+
+var (
+	ErrInvalidRate = errors.New("invalid exchange rate")
+	ErrNoRate      = errors.New("no exchange rate")
+	ErrInvalidFile = errors.New("invalid rate table")
+)
+
+var (
+	currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+	decimal      = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+)
+
+// Rate says that one unit of Base is worth Rate units of Quote from
+// EffectiveAt on, until a later rate of the pair takes effect.
+type Rate struct {
+	Base        string
+	Quote       string
+	Rate        string
+	EffectiveAt time.Time
+	// Source names where the rate came from, such as a file name or the
+	// principal that entered it.
+	Source string
+}
+
+// Validate checks the currencies and that the rate is a positive decimal
+// of at most Scale decimals, and normalizes the rate's notation.
+func (r *Rate) Validate() error {
+	if !currencyCode.MatchString(r.Base) || !currencyCode.MatchString(r.Quote) {
+		return fmt.Errorf("%w: currencies must be ISO 4217 codes", ErrInvalidRate)
+	}
+	if r.Base == r.Quote {
+		return fmt.Errorf("%w: %s to itself", ErrInvalidRate, r.Base)
+	}
+	if r.EffectiveAt.IsZero() {
+		return fmt.Errorf("%w: no effective time", ErrInvalidRate)
+	}
+	rat, err := Parse(r.Rate)
+	if err != nil {
+		return err
+	}
+	r.Rate = Format(rat)
+	return nil
+}
+
+// Parse reads a positive decimal rate of at most Scale decimals.
+func Parse(s string) (*big.Rat, error) {
+	if !decimal.MatchString(s) {
+		return nil, fmt.Errorf("%w %q", ErrInvalidRate, s)
+	}
+	if _, frac, ok := strings.Cut(s, "."); ok && len(frac) > Scale {
+		return nil, fmt.Errorf("%w %q: more than %d decimals", ErrInvalidRate, s, Scale)
+	}
+	rat, _ := new(big.Rat).SetString(s)
+	if rat.Sign() <= 0 {
+		return nil, fmt.Errorf("%w %q: must be positive", ErrInvalidRate, s)
+	}
+	return rat, nil
+}
+
+// Format writes a rate with at most Scale decimals and without trailing
+// zeros.
+func Format(rate *big.Rat) string {
+	s := rate.FloatString(Scale)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Invert returns the rate of the opposite direction, rounded to Scale
+// decimals.
+func Invert(rate string) (string, error) {
+	rat, err := Parse(rate)
+	if err != nil {
+		return "", err
+	}
+	inverted := Format(new(big.Rat).Inv(rat))
+	if inverted == "0" {
+		return "", fmt.Errorf("%w %q: inverse below %d decimals", ErrInvalidRate, rate, Scale)
+	}
+	return inverted, nil
+}
+
+// Convert converts amount minor units of currency at rate to minor units
+// of quote, rounding half away from zero. The amount is scaled by the ISO
+// 4217 exponents of both: 1000 JPY at 0.0062 are 620 cents of EUR, not 6.
+func Convert(amount int64, currency, quote, rate string) (int64, error) {
+	rat, err := Parse(rate)
+	if err != nil {
+		return 0, err
+	}
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), rat)
+	if shift := money.Exponent(quote) - money.Exponent(currency); shift > 0 {
+		product.Mul(product, pow10(shift))
+	} else if shift < 0 {
+		product.Quo(product, pow10(-shift))
+	}
+	converted, ok := money.Round(product)
+	if !ok {
+		return 0, fmt.Errorf("%w: %d at %s overflows", ErrInvalidRate, amount, rate)
+	}
+	return converted, nil
+}
+
+func pow10(n int) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil))
+}
+
+// ParseTable reads a rate table: CSV with the header base,quote,rate,
+// effective_at, where effective_at is an RFC 3339 timestamp. Rows may come
+// in any order; each is validated.
+func ParseTable(r io.Reader, source string) ([]Rate, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	want := []string{"base", "quote", "rate", "effective_at"}
+	if len(header) != len(want) {
+		return nil, fmt.Errorf("%w: header must be %s", ErrInvalidFile, strings.Join(want, ","))
+	}
+	for i, name := range header {
+		if strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))) != want[i] {
+			return nil, fmt.Errorf("%w: header must be %s", ErrInvalidFile, strings.Join(want, ","))
+		}
+	}
+
+	var rates []Rate
+	for row := 2; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		effectiveAt, err := time.Parse(time.RFC3339, strings.TrimSpace(record[3]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: effective_at must be an RFC 3339 timestamp", ErrInvalidFile, row)
+		}
+		rate := Rate{
+			Base:        strings.ToUpper(strings.TrimSpace(record[0])),
+			Quote:       strings.ToUpper(strings.TrimSpace(record[1])),
+			Rate:        strings.TrimSpace(record[2]),
+			EffectiveAt: effectiveAt.UTC(),
+			Source:      source,
+		}
+		if err := rate.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidFile, row, err)
+		}
+		rates = append(rates, rate)
+	}
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("%w: no rates", ErrInvalidFile)
+	}
+	return rates, nil
+}
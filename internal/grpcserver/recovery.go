@@ -0,0 +1,53 @@
+package grpcserver
+
+import (
+	"context"
+	"runtime/debug"
+
+	"go-lang-final/internal/metrics"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryRecoveryInterceptor turns a panic in the rest of the chain into an
+// Internal error. The panic value and stack are logged but never sent to
+// the client. logger and registry may be nil.
+func UnaryRecoveryInterceptor(logger *logrus.Logger, registry *metrics.Registry) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recovered(ctx, logger, registry, info.FullMethod, p)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func StreamRecoveryInterceptor(logger *logrus.Logger, registry *metrics.Registry) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recovered(ss.Context(), logger, registry, info.FullMethod, p)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func recovered(ctx context.Context, logger *logrus.Logger, registry *metrics.Registry, method string, p interface{}) error {
+	if registry != nil {
+		registry.Panic()
+	}
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	logger.WithContext(ctx).WithFields(logrus.Fields{
+		"grpc_method": method,
+		"panic":       p,
+		"stack":       string(debug.Stack()),
+	}).Error("recovered from panic in gRPC handler")
+	return status.Error(codes.Internal, "internal error")
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/sepa"
+	"go-lang-final/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// EnableCreditTransfers lets the service write pain.001 batches paying out
+// of debtor's account. Without it bank accounts, payouts and status reports
+// still work, but no batch can be created.
+func (s *PaymentService) EnableCreditTransfers(debtor sepa.Party) {
+	s.debtor = &debtor
+}
+
+func (s *PaymentService) CreateBankAccount(ctx context.Context, req *proto.CreateBankAccountRequest) (*proto.BankAccount, error) {
+	holder := strings.TrimSpace(req.GetHolderName())
+	if holder == "" || len(holder) > 70 {
+		return nil, status.Error(codes.InvalidArgument, "holder_name must be 1 to 70 characters")
+	}
+	iban, err := sepa.ValidateIBAN(req.GetIban())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to create bank account: %v", err)
+	}
+	var bic string
+	if req.GetBic() != "" {
+		if bic, err = sepa.ValidateBIC(req.GetBic()); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "failed to create bank account: %v", err)
+		}
+	}
+
+	account, err := s.store.CreateBankAccount(ctx, models.BankAccount{HolderName: holder, BIC: bic}, iban)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to create bank account")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to create bank account: %v", err)
+	}
+	return bankAccountProto(*account), nil
+}
+
+func (s *PaymentService) GetBankAccount(ctx context.Context, req *proto.GetBankAccountRequest) (*proto.BankAccount, error) {
+	account, err := s.store.GetBankAccount(ctx, req.GetToken())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "bank account not found: %v", err)
+	}
+	return bankAccountProto(*account), nil
+}
+
+func (s *PaymentService) CreatePayout(ctx context.Context, req *proto.CreatePayoutRequest) (*proto.CreditTransfer, error) {
+	if req.GetAmount() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "amount must be positive")
+	}
+	transfer, err := s.store.CreatePayout(ctx, models.CreditTransfer{
+		BankAccount: req.GetBankAccount(),
+		Amount:      req.GetAmount(),
+		Currency:    req.GetCurrency(),
+		Remittance:  sepa.Text(req.GetRemittance(), 140),
+	})
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to create payout")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to create payout: %v", err)
+	}
+	return creditTransferProto(*transfer), nil
+}
+
+func (s *PaymentService) GetCreditTransfer(ctx context.Context, req *proto.GetCreditTransferRequest) (*proto.CreditTransfer, error) {
+	transfer, err := s.store.GetCreditTransfer(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "credit transfer not found: %v", err)
+	}
+	return creditTransferProto(*transfer), nil
+}
+
+// CreateCreditTransferBatch writes the pending credit transfers into a
+// pain.001 file executed on req.execution_date, today if unset.
+func (s *PaymentService) CreateCreditTransferBatch(ctx context.Context, req *proto.CreateCreditTransferBatchRequest) (*proto.CreditTransferBatch, error) {
+	if s.debtor == nil {
+		return nil, status.Error(codes.Unimplemented, "credit transfer batches are not available")
+	}
+	now := time.Now().UTC()
+	today := now.Truncate(24 * time.Hour)
+	executionDate := today
+	if req.GetExecutionDate() != "" {
+		date, err := time.Parse("2006-01-02", req.GetExecutionDate())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "execution_date must be a YYYY-MM-DD date")
+		}
+		if date.Before(today) {
+			return nil, status.Error(codes.InvalidArgument, "execution_date must not be in the past")
+		}
+		executionDate = date
+	}
+
+	var document []byte
+	batch, err := s.store.CreateCreditTransferBatch(ctx, executionDate, func(id string, transfers []sepa.Transfer) ([]byte, error) {
+		var err error
+		document, err = sepa.Batch{
+			MessageID:     id,
+			CreatedAt:     now,
+			ExecutionDate: executionDate,
+			Debtor:        *s.debtor,
+			Transfers:     transfers,
+		}.Pain001()
+		return document, err
+	})
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to create credit transfer batch")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to create credit transfer batch: %v", err)
+	}
+	return batchProto(*batch, document), nil
+}
+
+func (s *PaymentService) GetCreditTransferBatch(ctx context.Context, req *proto.GetCreditTransferBatchRequest) (*proto.CreditTransferBatch, error) {
+	batch, document, err := s.store.GetCreditTransferBatch(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "credit transfer batch not found: %v", err)
+	}
+	return batchProto(*batch, document), nil
+}
+
+func (s *PaymentService) ImportPaymentStatusReport(ctx context.Context, req *proto.ImportPaymentStatusReportRequest) (*proto.ImportPaymentStatusReportResponse, error) {
+	report, err := sepa.ParsePain002(req.GetDocument())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to import status report: %v", err)
+	}
+	batch, updated, err := s.store.ApplyStatusReport(ctx, report)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to import status report")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to import status report: %v", err)
+	}
+	return &proto.ImportPaymentStatusReportResponse{
+		BatchId:     batch.ID,
+		GroupStatus: batch.BankStatus,
+		Updated:     int32(updated),
+	}, nil
+}
+
+func bankAccountProto(account models.BankAccount) *proto.BankAccount {
+	return &proto.BankAccount{
+		Token:      account.Token,
+		HolderName: account.HolderName,
+		Country:    account.Country,
+		Last4:      account.Last4,
+		Bic:        account.BIC,
+		TenantId:   account.TenantID,
+		CreatedAt:  timestamppb.New(account.CreatedAt),
+	}
+}
+
+func creditTransferProto(transfer models.CreditTransfer) *proto.CreditTransfer {
+	return &proto.CreditTransfer{
+		Id:          transfer.ID,
+		BankAccount: transfer.BankAccount,
+		PaymentId:   transfer.PaymentID,
+		Kind:        transfer.Kind,
+		Amount:      transfer.Amount,
+		Currency:    transfer.Currency,
+		Remittance:  transfer.Remittance,
+		Status:      transfer.Status,
+		Reason:      transfer.Reason,
+		BatchId:     transfer.BatchID,
+		TenantId:    transfer.TenantID,
+		CreatedAt:   timestamppb.New(transfer.CreatedAt),
+	}
+}
+
+func batchProto(batch models.CreditTransferBatch, document []byte) *proto.CreditTransferBatch {
+	return &proto.CreditTransferBatch{
+		Id:            batch.ID,
+		Transfers:     int32(batch.Transfers),
+		Total:         batch.Total,
+		ExecutionDate: batch.ExecutionDate,
+		BankStatus:    batch.BankStatus,
+		Document:      document,
+		TenantId:      batch.TenantID,
+		CreatedAt:     timestamppb.New(batch.CreatedAt),
+	}
+}
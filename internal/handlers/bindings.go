@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"go-lang-final/proto"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	protobuf "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Binding is one HTTP route of the REST API, as declared by a
+// google.api.http option in payment.proto.
+type Binding struct {
+	// RPC is the short method name, also used as the mux route name.
+	RPC        string
+	FullMethod string
+	Verb       string
+	Path       string
+	// Body and ResponseBody are the body and response_body of the rule.
+	Body         string
+	ResponseBody string
+	// Deprecated marks additional bindings kept for old clients; Successor
+	// is the primary path of the same RPC.
+	Deprecated bool
+	Successor  string
+}
+
+// Bindings returns the HTTP bindings of every PaymentService method, primary
+// binding first.
+func Bindings() []Binding {
+	var bindings []Binding
+	service := proto.File_proto_payment_proto.Services().ByName("PaymentService")
+	methods := service.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		m := methods.Get(i)
+		rule, ok := protobuf.GetExtension(m.Options(), annotations.E_Http).(*annotations.HttpRule)
+		if !ok || rule == nil {
+			continue
+		}
+		primary := newBinding(m, rule)
+		bindings = append(bindings, primary)
+		for _, extra := range rule.GetAdditionalBindings() {
+			b := newBinding(m, extra)
+			b.Deprecated = true
+			b.Successor = primary.Path
+			bindings = append(bindings, b)
+		}
+	}
+	return bindings
+}
+
+func newBinding(m protoreflect.MethodDescriptor, rule *annotations.HttpRule) Binding {
+	b := Binding{
+		RPC:          string(m.Name()),
+		FullMethod:   "/" + string(m.Parent().FullName()) + "/" + string(m.Name()),
+		Body:         rule.GetBody(),
+		ResponseBody: rule.GetResponseBody(),
+	}
+	switch p := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		b.Verb, b.Path = "GET", p.Get
+	case *annotations.HttpRule_Put:
+		b.Verb, b.Path = "PUT", p.Put
+	case *annotations.HttpRule_Post:
+		b.Verb, b.Path = "POST", p.Post
+	case *annotations.HttpRule_Delete:
+		b.Verb, b.Path = "DELETE", p.Delete
+	case *annotations.HttpRule_Patch:
+		b.Verb, b.Path = "PATCH", p.Patch
+	case *annotations.HttpRule_Custom:
+		b.Verb, b.Path = p.Custom.GetKind(), p.Custom.GetPath()
+	}
+	return b
+}
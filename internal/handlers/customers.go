@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *PaymentService) CreateCustomer(ctx context.Context, req *proto.Customer) (*proto.Customer, error) {
+	c, err := s.store.CreateCustomer(ctx, customerModel(req))
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to create customer")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to create customer: %v", err)
+	}
+	return customerProto(c), nil
+}
+
+func (s *PaymentService) GetCustomer(ctx context.Context, req *proto.GetCustomerRequest) (*proto.Customer, error) {
+	c, err := s.store.GetCustomer(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "customer not found: %v", err)
+	}
+	return customerProto(c), nil
+}
+
+func (s *PaymentService) ListCustomers(ctx context.Context, req *proto.ListCustomersRequest) (*proto.ListCustomersResponse, error) {
+	page, pageSize := pagination(req.GetPage(), req.GetPageSize())
+
+	list, err := s.store.ListCustomers(ctx, req.GetEmail(), req.GetExternalRef(), page, pageSize)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to list customers")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to list customers: %v", err)
+	}
+
+	res := &proto.ListCustomersResponse{}
+	for i := range list {
+		res.Customers = append(res.Customers, customerProto(&list[i]))
+	}
+	return res, nil
+}
+
+// UpdateCustomer replaces the email, name, external reference and metadata
+// of a customer.
+func (s *PaymentService) UpdateCustomer(ctx context.Context, req *proto.Customer) (*proto.Customer, error) {
+	c, err := s.store.UpdateCustomer(ctx, customerModel(req))
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to update customer")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to update customer: %v", err)
+	}
+	return customerProto(c), nil
+}
+
+// DeleteCustomer deletes a customer nothing refers to yet. Customers with
+// payments, payment methods or subscriptions can only be erased.
+func (s *PaymentService) DeleteCustomer(ctx context.Context, req *proto.DeleteCustomerRequest) (*proto.DeleteCustomerResponse, error) {
+	if err := s.store.DeleteCustomer(ctx, req.GetId()); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to delete customer")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to delete customer: %v", err)
+	}
+	return &proto.DeleteCustomerResponse{}, nil
+}
+
+// EraseCustomer removes the personal data of a customer for a GDPR erasure
+// request. Its payments stay, linked to the anonymous customer.
+func (s *PaymentService) EraseCustomer(ctx context.Context, req *proto.EraseCustomerRequest) (*proto.Customer, error) {
+	c, err := s.store.EraseCustomer(ctx, req.GetId(), time.Now().UTC())
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to erase customer")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to erase customer: %v", err)
+	}
+	return customerProto(c), nil
+}
+
+// AttachPaymentMethod saves a card or bank account token for a customer.
+func (s *PaymentService) AttachPaymentMethod(ctx context.Context, req *proto.AttachPaymentMethodRequest) (*proto.CustomerPaymentMethods, error) {
+	methods, err := s.store.AttachPaymentMethod(ctx, req.GetId(), req.GetToken())
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to attach payment method")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to attach payment method: %v", err)
+	}
+	return customerPaymentMethodsProto(methods), nil
+}
+
+func (s *PaymentService) ListCustomerPaymentMethods(ctx context.Context, req *proto.ListCustomerPaymentMethodsRequest) (*proto.CustomerPaymentMethods, error) {
+	methods, err := s.store.ListCustomerPaymentMethods(ctx, req.GetId())
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to list customer payment methods")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to list customer payment methods: %v", err)
+	}
+	return customerPaymentMethodsProto(methods), nil
+}
+
+// ListCustomerPayments lists the payments of a customer, newest first.
+func (s *PaymentService) ListCustomerPayments(ctx context.Context, req *proto.ListCustomerPaymentsRequest) (*proto.ListPaymentsResponse, error) {
+	page, pageSize := pagination(req.GetPage(), req.GetPageSize())
+
+	payments, err := s.store.ListCustomerPayments(ctx, req.GetId(), page, pageSize)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to list customer payments")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to list customer payments: %v", err)
+	}
+
+	res := &proto.ListPaymentsResponse{}
+	for _, payment := range payments {
+		res.Payments = append(res.Payments, paymentProto(payment))
+	}
+	return res, nil
+}
+
+func (s *PaymentService) GetCustomerLifetimeValue(ctx context.Context, req *proto.GetCustomerLifetimeValueRequest) (*proto.CustomerLifetimeValue, error) {
+	ltv, err := s.store.CustomerLifetimeValue(ctx, req.GetId())
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to get customer lifetime value")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to get customer lifetime value: %v", err)
+	}
+
+	res := &proto.CustomerLifetimeValue{CustomerId: ltv.CustomerID}
+	for _, v := range ltv.Values {
+		res.Values = append(res.Values, &proto.CustomerValue{
+			Currency:       v.Currency,
+			Payments:       int32(v.Payments),
+			Gross:          v.Gross,
+			Refunded:       v.Refunded,
+			Net:            v.Net,
+			FirstPaymentAt: optionalTimestamp(v.FirstPaymentAt),
+			LastPaymentAt:  optionalTimestamp(v.LastPaymentAt),
+		})
+	}
+	return res, nil
+}
+
+func customerModel(req *proto.Customer) models.Customer {
+	return models.Customer{
+		ID:          req.GetId(),
+		Email:       req.GetEmail(),
+		Name:        req.GetName(),
+		ExternalRef: req.GetExternalRef(),
+		Metadata:    req.GetMetadata(),
+	}
+}
+
+func customerProto(c *models.Customer) *proto.Customer {
+	return &proto.Customer{
+		Id:          c.ID,
+		Email:       c.Email,
+		Name:        c.Name,
+		ExternalRef: c.ExternalRef,
+		Metadata:    c.Metadata,
+		ErasedAt:    optionalTimestamp(c.ErasedAt),
+		CreatedAt:   timestamppb.New(c.CreatedAt),
+		UpdatedAt:   timestamppb.New(c.UpdatedAt),
+	}
+}
+
+func customerPaymentMethodsProto(methods *models.CustomerPaymentMethods) *proto.CustomerPaymentMethods {
+	res := &proto.CustomerPaymentMethods{}
+	for _, card := range methods.Cards {
+		res.Cards = append(res.Cards, paymentMethodProto(card))
+	}
+	for _, account := range methods.BankAccounts {
+		res.BankAccounts = append(res.BankAccounts, bankAccountProto(account))
+	}
+	return res
+}
@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"go-lang-final/internal/blob"
+	"go-lang-final/internal/disputes"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/provider"
+	"go-lang-final/internal/store"
+	"go-lang-final/internal/tenant"
+	"go-lang-final/proto"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// disputeBatch is how many disputes one CheckDisputeDeadlines pass looks
+// at.
+const disputeBatch = 100
+
+// ConfigureDisputes sets the blob store evidence is kept in, the lead
+// times before evidence deadlines at which tenants are reminded and who
+// delivers the reminders. Nil reminders use disputes.DefaultReminders and
+// a nil notifier logs them.
+func (s *PaymentService) ConfigureDisputes(evidence blob.Store, reminders []time.Duration, notifier disputes.Notifier) {
+	if reminders != nil {
+		reminders = slices.Clone(reminders)
+		disputes.SortReminders(reminders)
+	}
+	s.evidence, s.disputeReminders, s.notifier = evidence, reminders, notifier
+}
+
+// CreateDispute opens a dispute of a captured payment entered by hand,
+// such as one the bank reported by mail.
+func (s *PaymentService) CreateDispute(ctx context.Context, req *proto.CreateDisputeRequest) (*proto.Dispute, error) {
+	d := models.Dispute{PaymentID: req.GetPaymentId(), Reason: req.GetReason(), Amount: req.GetAmount()}
+	if req.GetEvidenceDueBy() != nil {
+		d.EvidenceDueBy = req.GetEvidenceDueBy().AsTime()
+	}
+	dispute, err := s.store.CreateDispute(ctx, d, time.Now().UTC())
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to create dispute")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to create dispute: %v", err)
+	}
+	return disputeProto(dispute), nil
+}
+
+func (s *PaymentService) GetDispute(ctx context.Context, req *proto.GetDisputeRequest) (*proto.Dispute, error) {
+	d, err := s.store.GetDispute(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "dispute not found: %v", err)
+	}
+	return disputeProto(d), nil
+}
+
+func (s *PaymentService) ListDisputes(ctx context.Context, req *proto.ListDisputesRequest) (*proto.ListDisputesResponse, error) {
+	page, pageSize := pagination(req.GetPage(), req.GetPageSize())
+
+	list, err := s.store.ListDisputes(ctx, req.GetStatus(), req.GetPaymentId(), page, pageSize)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to list disputes")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to list disputes: %v", err)
+	}
+
+	res := &proto.ListDisputesResponse{}
+	for i := range list {
+		res.Disputes = append(res.Disputes, disputeProto(&list[i]))
+	}
+	return res, nil
+}
+
+// UploadDisputeEvidence adds a file to a dispute that needs a response,
+// keeping its content in the evidence blob store.
+func (s *PaymentService) UploadDisputeEvidence(ctx context.Context, req *proto.UploadDisputeEvidenceRequest) (*proto.DisputeEvidence, error) {
+	if s.evidence == nil {
+		return nil, status.Error(codes.FailedPrecondition, "no dispute evidence store is configured")
+	}
+	doc := req.GetDocument()
+	sum := sha256.Sum256(doc)
+	e := models.DisputeEvidence{
+		Type:        req.GetType(),
+		Filename:    req.GetFilename(),
+		ContentType: req.GetContentType(),
+		Size:        int64(len(doc)),
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+	upload := func(key string) error {
+		return s.evidence.Put(ctx, key, bytes.NewReader(doc))
+	}
+
+	evidence, err := s.store.AddDisputeEvidence(ctx, req.GetDisputeId(), e, upload)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to upload dispute evidence")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to upload dispute evidence: %v", err)
+	}
+	return disputeEvidenceProto(evidence), nil
+}
+
+// GetDisputeEvidence returns an evidence file with its content.
+func (s *PaymentService) GetDisputeEvidence(ctx context.Context, req *proto.GetDisputeEvidenceRequest) (*proto.GetDisputeEvidenceResponse, error) {
+	if s.evidence == nil {
+		return nil, status.Error(codes.FailedPrecondition, "no dispute evidence store is configured")
+	}
+	e, err := s.store.GetDisputeEvidence(ctx, req.GetDisputeId(), req.GetId())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "dispute evidence not found: %v", err)
+	}
+	doc, err := s.readEvidence(ctx, *e)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to read dispute evidence")
+		return nil, status.Errorf(codes.Internal, "failed to read dispute evidence: %v", err)
+	}
+	return &proto.GetDisputeEvidenceResponse{Filename: e.Filename, ContentType: e.ContentType, Document: doc}, nil
+}
+
+// SubmitDispute contests a dispute with its evidence. Disputes a provider
+// reported have the evidence sent to the provider first.
+func (s *PaymentService) SubmitDispute(ctx context.Context, req *proto.SubmitDisputeRequest) (*proto.Dispute, error) {
+	d, err := s.store.SubmitDispute(ctx, req.GetId(), time.Now().UTC(), s.submitCall(ctx))
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to submit dispute")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to submit dispute: %v", err)
+	}
+	return disputeProto(d), nil
+}
+
+// AcceptDispute gives up a dispute that still needs a response, losing it.
+func (s *PaymentService) AcceptDispute(ctx context.Context, req *proto.AcceptDisputeRequest) (*proto.Dispute, error) {
+	current, err := s.store.GetDispute(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "dispute not found: %v", err)
+	}
+	if current.Status != models.DisputeNeedsResponse {
+		return nil, status.Error(codes.FailedPrecondition, "only disputes that need a response can be accepted")
+	}
+	d, err := s.store.CloseDispute(ctx, req.GetId(), models.DisputeLost, "accepted", time.Now().UTC())
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to accept dispute")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to accept dispute: %v", err)
+	}
+	return disputeProto(d), nil
+}
+
+// ResolveDispute records how the bank decided a dispute entered by hand.
+// Provider sync records the decisions of the disputes providers reported.
+func (s *PaymentService) ResolveDispute(ctx context.Context, req *proto.ResolveDisputeRequest) (*proto.Dispute, error) {
+	current, err := s.store.GetDispute(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "dispute not found: %v", err)
+	}
+	if current.ProviderRef != "" {
+		return nil, status.Errorf(codes.FailedPrecondition, "dispute is decided by payment provider %q", current.Provider)
+	}
+	d, err := s.store.CloseDispute(ctx, req.GetId(), req.GetStatus(), req.GetOutcome(), time.Now().UTC())
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to resolve dispute")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to resolve dispute: %v", err)
+	}
+	return disputeProto(d), nil
+}
+
+// submitCall sends the evidence of a provider's dispute to the provider.
+func (s *PaymentService) submitCall(ctx context.Context) store.DisputeCall {
+	return func(d models.Dispute) error {
+		if s.router == nil {
+			return fmt.Errorf("payment provider %q is not configured", d.Provider)
+		}
+		p, ok := s.router.Provider(d.Provider)
+		if !ok {
+			return fmt.Errorf("payment provider %q is not configured", d.Provider)
+		}
+		disputer, ok := p.(provider.Disputer)
+		if !ok {
+			return fmt.Errorf("payment provider %q does not take dispute evidence", d.Provider)
+		}
+		if s.evidence == nil {
+			return errors.New("no dispute evidence store is configured")
+		}
+
+		evidence := make([]provider.Evidence, 0, len(d.Evidence))
+		for _, e := range d.Evidence {
+			doc, err := s.readEvidence(ctx, e)
+			if err != nil {
+				return err
+			}
+			evidence = append(evidence, provider.Evidence{Type: e.Type, Filename: e.Filename, ContentType: e.ContentType, Content: doc})
+		}
+		_, err := disputer.SubmitEvidence(ctx, d.ProviderRef, evidence)
+		return err
+	}
+}
+
+func (s *PaymentService) readEvidence(ctx context.Context, e models.DisputeEvidence) ([]byte, error) {
+	r, err := s.evidence.Get(ctx, e.Key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// SyncDisputes records the disputes payment providers report: new ones are
+// opened against the payment of their transaction and status changes, such
+// as a bank's decision, are applied. ctx must carry a cross-tenant scope to
+// cover every tenant. It returns how many disputes changed.
+func (s *PaymentService) SyncDisputes(ctx context.Context) (int, error) {
+	if s.router == nil {
+		return 0, nil
+	}
+
+	changed := 0
+	now := time.Now().UTC()
+	for _, p := range s.router.Providers() {
+		disputer, ok := p.(provider.Disputer)
+		if !ok {
+			continue
+		}
+		reported, err := disputer.Disputes(ctx)
+		if err != nil {
+			return changed, err
+		}
+		for _, pd := range reported {
+			log := logging.FromContext(ctx).WithFields(logrus.Fields{
+				"provider":     p.Name(),
+				"provider_ref": pd.ID,
+			})
+			payment, err := s.store.PaymentByProviderRef(ctx, p.Name(), pd.Ref)
+			if err != nil {
+				log.WithError(err).Error("failed to find payment of provider dispute")
+				continue
+			}
+			_, ok, err := s.store.RecordProviderDispute(tenant.WithScope(ctx, tenant.Scope{TenantID: payment.TenantID}), models.Dispute{
+				PaymentID:     payment.ID,
+				Reason:        pd.Reason,
+				Amount:        pd.Amount,
+				Status:        string(pd.Status),
+				EvidenceDueBy: pd.EvidenceDueBy,
+				Provider:      p.Name(),
+				ProviderRef:   pd.ID,
+			}, now)
+			if err != nil {
+				log.WithError(err).Error("failed to record provider dispute")
+				continue
+			}
+			if ok {
+				changed++
+			}
+		}
+	}
+	return changed, nil
+}
+
+// CheckDisputeDeadlines loses the disputes entered by hand whose evidence
+// deadline passed without a response, and reminds tenants of deadlines
+// coming up. Each reminder is claimed first, so concurrent passes on other
+// replicas do not send it again. ctx must carry a cross-tenant scope to
+// cover every tenant. It returns how many disputes were lost or reminded
+// of.
+func (s *PaymentService) CheckDisputeDeadlines(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	leads := s.reminders()
+	due, err := s.store.DueDisputes(ctx, now, leads, disputeBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	handled := 0
+	for _, d := range due {
+		ok, err := s.checkDeadline(tenant.WithScope(ctx, tenant.Scope{TenantID: d.TenantID}), d, leads, now)
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).WithFields(logrus.Fields{
+				"tenant_id":  d.TenantID,
+				"dispute_id": d.ID,
+			}).Error("failed to check dispute deadline")
+			continue
+		}
+		if ok {
+			handled++
+		}
+	}
+	return handled, nil
+}
+
+func (s *PaymentService) checkDeadline(ctx context.Context, d models.Dispute, leads []time.Duration, now time.Time) (bool, error) {
+	if !now.Before(d.EvidenceDueBy) {
+		_, err := s.store.CloseDispute(ctx, d.ID, models.DisputeLost, "evidence deadline passed", now)
+		return err == nil, err
+	}
+
+	due := disputes.RemindersDue(d.EvidenceDueBy, now, leads)
+	if due <= d.RemindersSent {
+		return false, nil
+	}
+	claimed, err := s.store.ClaimDisputeReminder(ctx, d.ID, d.RemindersSent, due)
+	if err != nil || !claimed {
+		return false, err
+	}
+	notifier := s.notifier
+	if notifier == nil {
+		notifier = disputes.LogNotifier{}
+	}
+	return true, notifier.Remind(ctx, disputes.Reminder{Dispute: d, Left: d.EvidenceDueBy.Sub(now)})
+}
+
+func (s *PaymentService) reminders() []time.Duration {
+	if s.disputeReminders == nil {
+		return disputes.DefaultReminders
+	}
+	return s.disputeReminders
+}
+
+// RunDisputes calls SyncDisputes and CheckDisputeDeadlines every interval
+// until ctx is done.
+func (s *PaymentService) RunDisputes(ctx context.Context, interval time.Duration, logger *logrus.Logger) {
+	ctx = tenant.WithScope(ctx, tenant.Scope{All: true})
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.SyncDisputes(ctx); err != nil {
+			logger.WithError(err).Error("dispute sync failed")
+		}
+		if _, err := s.CheckDisputeDeadlines(ctx); err != nil {
+			logger.WithError(err).Error("dispute deadline check failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func disputeProto(d *models.Dispute) *proto.Dispute {
+	res := &proto.Dispute{
+		Id:            d.ID,
+		PaymentId:     d.PaymentID,
+		Reason:        d.Reason,
+		Amount:        d.Amount,
+		Currency:      d.Currency,
+		Status:        d.Status,
+		EvidenceDueBy: timestamppb.New(d.EvidenceDueBy),
+		Provider:      d.Provider,
+		ProviderRef:   d.ProviderRef,
+		Outcome:       d.Outcome,
+		SubmittedAt:   optionalTimestamp(d.SubmittedAt),
+		ClosedAt:      optionalTimestamp(d.ClosedAt),
+		CreatedAt:     timestamppb.New(d.CreatedAt),
+		UpdatedAt:     timestamppb.New(d.UpdatedAt),
+	}
+	for i := range d.Evidence {
+		res.Evidence = append(res.Evidence, disputeEvidenceProto(&d.Evidence[i]))
+	}
+	return res
+}
+
+func disputeEvidenceProto(e *models.DisputeEvidence) *proto.DisputeEvidence {
+	return &proto.DisputeEvidence{
+		Id:          e.ID,
+		DisputeId:   e.DisputeID,
+		Type:        e.Type,
+		Filename:    e.Filename,
+		ContentType: e.ContentType,
+		Size:        e.Size,
+		Sha256:      e.SHA256,
+		CreatedAt:   timestamppb.New(e.CreatedAt),
+	}
+}
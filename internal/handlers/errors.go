@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+
+	"go-lang-final/internal/customers"
+	"go-lang-final/internal/disputes"
+	"go-lang-final/internal/fees"
+	"go-lang-final/internal/fx"
+	"go-lang-final/internal/idempotency"
+	"go-lang-final/internal/invoices"
+	"go-lang-final/internal/provider"
+	"go-lang-final/internal/reconcile"
+	"go-lang-final/internal/routing"
+	"go-lang-final/internal/sepa"
+	"go-lang-final/internal/settlement"
+	"go-lang-final/internal/store"
+	"go-lang-final/internal/subscriptions"
+	"go-lang-final/internal/tenant"
+	"go-lang-final/internal/vault"
+
+	"google.golang.org/grpc/codes"
+)
+
+// grpcCode maps store and provider errors to a status code, using fallback for anything
+// unrecognised. The REST gateway derives HTTP statuses from these codes.
+func grpcCode(err error, fallback codes.Code) codes.Code {
+	switch {
+	case errors.Is(err, store.ErrPaymentNotFound), errors.Is(err, store.ErrPaymentMethodNotFound),
+		errors.Is(err, store.ErrBankAccountNotFound), errors.Is(err, store.ErrCreditTransferNotFound), errors.Is(err, store.ErrBatchNotFound),
+		errors.Is(err, store.ErrRunNotFound), errors.Is(err, store.ErrLineNotFound), errors.Is(err, store.ErrSettlementNotFound),
+		errors.Is(err, store.ErrQuoteNotFound), errors.Is(err, store.ErrConversionNotFound), errors.Is(err, store.ErrPricingPlanNotFound),
+		errors.Is(err, store.ErrFeeNotFound), errors.Is(err, store.ErrSubscriptionPlanNotFound), errors.Is(err, store.ErrSubscriptionNotFound),
+		errors.Is(err, store.ErrInvoiceNotFound), errors.Is(err, store.ErrCustomerNotFound), errors.Is(err, store.ErrDisputeNotFound),
+		errors.Is(err, store.ErrEvidenceNotFound), errors.Is(err, provider.ErrUnknownDispute):
+		return codes.NotFound
+	case errors.Is(err, store.ErrQuotaExceeded), errors.Is(err, store.ErrDailyQuotaExceeded):
+		return codes.ResourceExhausted
+	case errors.Is(err, store.ErrCurrencyNotAllowed), errors.Is(err, store.ErrAmountTooLarge),
+		errors.Is(err, store.ErrRefundTooLarge), errors.Is(err, vault.ErrInvalidNumber), errors.Is(err, vault.ErrUnsupportedBrand),
+		errors.Is(err, vault.ErrInvalidExpiry), errors.Is(err, vault.ErrExpired), errors.Is(err, store.ErrCurrencyNotSEPA),
+		errors.Is(err, sepa.ErrInvalidIBAN), errors.Is(err, sepa.ErrUnsupportedCountry), errors.Is(err, sepa.ErrInvalidBIC),
+		errors.Is(err, sepa.ErrInvalidBatch), errors.Is(err, sepa.ErrInvalidReport), errors.Is(err, reconcile.ErrInvalidStatement),
+		errors.Is(err, settlement.ErrInvalidReport), errors.Is(err, fx.ErrInvalidRate), errors.Is(err, fx.ErrInvalidFile),
+		errors.Is(err, store.ErrQuoteMismatch), errors.Is(err, fees.ErrInvalidPlan), errors.Is(err, subscriptions.ErrInvalidPlan),
+		errors.Is(err, store.ErrPlanCurrencyMismatch), errors.Is(err, invoices.ErrInvalidInvoice), errors.Is(err, store.ErrInvoiceCurrency),
+		errors.Is(err, store.ErrInvoicePaymentTooLarge), errors.Is(err, customers.ErrInvalidCustomer),
+		errors.Is(err, store.ErrInvalidPaymentDetails), errors.Is(err, disputes.ErrInvalidDispute), errors.Is(err, disputes.ErrInvalidEvidence),
+		errors.Is(err, store.ErrDisputeTooLarge), errors.Is(err, store.ErrAmountPrecision):
+		return codes.InvalidArgument
+	case errors.Is(err, store.ErrInvalidState), errors.Is(err, provider.ErrNotAllowed), errors.Is(err, store.ErrNoPendingTransfers),
+		errors.Is(err, store.ErrNotException), errors.Is(err, fx.ErrNoRate), errors.Is(err, store.ErrQuoteExpired),
+		errors.Is(err, store.ErrNoPricingPlan), errors.Is(err, store.ErrSubscriptionState), errors.Is(err, store.ErrRenewalPending),
+		errors.Is(err, store.ErrInvoiceState), errors.Is(err, store.ErrCustomerErased), errors.Is(err, store.ErrCustomerInUse),
+		errors.Is(err, store.ErrCustomerActive), errors.Is(err, store.ErrDisputeState), errors.Is(err, store.ErrNotDisputable),
+		errors.Is(err, store.ErrNoEvidence), errors.Is(err, store.ErrTooMuchEvidence), errors.Is(err, store.ErrAmountFixed),
+		errors.Is(err, store.ErrPaymentCaptured):
+		return codes.FailedPrecondition
+	case errors.As(err, new(*provider.DeclineError)):
+		return codes.FailedPrecondition
+	case errors.Is(err, provider.ErrTimeout), errors.Is(err, routing.ErrNoProvider):
+		return codes.Unavailable
+	case errors.Is(err, tenant.ErrNoTenant):
+		return codes.PermissionDenied
+	case errors.Is(err, routing.ErrNoVault):
+		return codes.Unimplemented
+	case errors.Is(err, idempotency.ErrKeyReused), errors.Is(err, store.ErrSettlementExists),
+		errors.Is(err, store.ErrCustomerExists):
+		return codes.AlreadyExists
+	default:
+		return fallback
+	}
+}
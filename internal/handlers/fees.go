@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"go-lang-final/internal/fees"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ConfigureFees turns charging captured payments the fee of their tenant's
+// pricing plan on or off.
+func (s *PaymentService) ConfigureFees(enabled bool) {
+	s.fees = enabled
+}
+
+func (s *PaymentService) CreatePricingPlan(ctx context.Context, req *proto.PricingPlan) (*proto.PricingPlan, error) {
+	plan, err := s.store.CreatePricingPlan(ctx, pricingPlanModel(req))
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to create pricing plan")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to create pricing plan: %v", err)
+	}
+	return pricingPlanProto(plan), nil
+}
+
+func (s *PaymentService) UpdatePricingPlan(ctx context.Context, req *proto.PricingPlan) (*proto.PricingPlan, error) {
+	plan, err := s.store.UpdatePricingPlan(ctx, pricingPlanModel(req))
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to update pricing plan")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to update pricing plan: %v", err)
+	}
+	return pricingPlanProto(plan), nil
+}
+
+func (s *PaymentService) GetPricingPlan(ctx context.Context, req *proto.GetPricingPlanRequest) (*proto.PricingPlan, error) {
+	plan, err := s.store.GetPricingPlan(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "pricing plan not found: %v", err)
+	}
+	return pricingPlanProto(plan), nil
+}
+
+func (s *PaymentService) ListPricingPlans(ctx context.Context, req *proto.ListPricingPlansRequest) (*proto.ListPricingPlansResponse, error) {
+	page, pageSize := pagination(req.GetPage(), req.GetPageSize())
+
+	plans, err := s.store.ListPricingPlans(ctx, page, pageSize)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to list pricing plans")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to list pricing plans: %v", err)
+	}
+
+	res := &proto.ListPricingPlansResponse{}
+	for i := range plans {
+		res.Plans = append(res.Plans, pricingPlanProto(&plans[i]))
+	}
+	return res, nil
+}
+
+// AssignPricingPlan puts the tenant in scope on a plan. Taking the tenant
+// off its plan returns an empty plan.
+func (s *PaymentService) AssignPricingPlan(ctx context.Context, req *proto.AssignPricingPlanRequest) (*proto.PricingPlan, error) {
+	plan, err := s.store.AssignPricingPlan(ctx, req.GetPlanId())
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to assign pricing plan")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to assign pricing plan: %v", err)
+	}
+	if plan == nil {
+		return &proto.PricingPlan{}, nil
+	}
+	return pricingPlanProto(plan), nil
+}
+
+func (s *PaymentService) GetTenantPricingPlan(ctx context.Context, req *proto.GetTenantPricingPlanRequest) (*proto.PricingPlan, error) {
+	plan, err := s.store.TenantPricingPlan(ctx)
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "pricing plan not found: %v", err)
+	}
+	return pricingPlanProto(plan), nil
+}
+
+func (s *PaymentService) SimulateFee(ctx context.Context, req *proto.SimulateFeeRequest) (*proto.PaymentFee, error) {
+	method := req.GetMethod()
+	if method == "" {
+		method = fees.MethodCard
+	}
+	fee, err := s.store.SimulateFee(ctx, req.GetAmount(), req.GetCurrency(), method, req.Volume)
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to simulate fee: %v", err)
+	}
+	return paymentFeeProto(*fee), nil
+}
+
+func (s *PaymentService) GetPaymentFee(ctx context.Context, req *proto.GetPaymentFeeRequest) (*proto.PaymentFee, error) {
+	fee, err := s.store.GetPaymentFee(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "payment fee not found: %v", err)
+	}
+	return paymentFeeProto(*fee), nil
+}
+
+// GetFeeStatement sums the tenant's fees of a month, the current one when
+// the request names none.
+func (s *PaymentService) GetFeeStatement(ctx context.Context, req *proto.GetFeeStatementRequest) (*proto.FeeStatement, error) {
+	month := time.Now().UTC()
+	if req.GetMonth() != "" {
+		var err error
+		if month, err = time.Parse(monthLayout, req.GetMonth()); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "month must be formatted YYYY-MM")
+		}
+	}
+
+	statement, err := s.store.FeeStatement(ctx, month)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to build fee statement")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to build fee statement: %v", err)
+	}
+
+	res := &proto.FeeStatement{TenantId: statement.TenantID, Month: statement.Month}
+	for _, t := range statement.Totals {
+		res.Totals = append(res.Totals, &proto.FeeStatementTotal{
+			Currency: t.Currency,
+			Payments: t.Payments,
+			Volume:   t.Volume,
+			Fees:     t.Fees,
+		})
+	}
+	return res, nil
+}
+
+// monthLayout formats the months of fee statements.
+const monthLayout = "2006-01"
+
+func pricingPlanModel(p *proto.PricingPlan) models.PricingPlan {
+	plan := models.PricingPlan{ID: p.GetId(), Name: p.GetName(), Pricing: pricingModel(p.GetPricing())}
+	if p.GetCreatedAt() != nil {
+		plan.CreatedAt, plan.UpdatedAt = p.GetCreatedAt().AsTime(), p.GetUpdatedAt().AsTime()
+	}
+	for _, o := range p.GetOverrides() {
+		plan.Overrides = append(plan.Overrides, models.PricingOverride{
+			Currency: o.GetCurrency(),
+			Method:   o.GetMethod(),
+			Pricing:  pricingModel(o.GetPricing()),
+		})
+	}
+	return plan
+}
+
+func pricingModel(p *proto.Pricing) models.Pricing {
+	pricing := models.Pricing{Percentage: p.GetPercentage(), Fixed: p.GetFixed(), Min: p.GetMin(), Max: p.GetMax()}
+	for _, t := range p.GetTiers() {
+		pricing.Tiers = append(pricing.Tiers, models.PricingTier{Volume: t.GetVolume(), Percentage: t.GetPercentage(), Fixed: t.GetFixed()})
+	}
+	return pricing
+}
+
+func pricingPlanProto(plan *models.PricingPlan) *proto.PricingPlan {
+	res := &proto.PricingPlan{
+		Id:        plan.ID,
+		Name:      plan.Name,
+		Pricing:   pricingProto(plan.Pricing),
+		CreatedAt: timestamppb.New(plan.CreatedAt),
+		UpdatedAt: timestamppb.New(plan.UpdatedAt),
+	}
+	for _, o := range plan.Overrides {
+		res.Overrides = append(res.Overrides, &proto.PricingOverride{
+			Currency: o.Currency,
+			Method:   o.Method,
+			Pricing:  pricingProto(o.Pricing),
+		})
+	}
+	return res
+}
+
+func pricingProto(p models.Pricing) *proto.Pricing {
+	res := &proto.Pricing{Percentage: p.Percentage, Fixed: p.Fixed, Min: p.Min, Max: p.Max}
+	for _, t := range p.Tiers {
+		res.Tiers = append(res.Tiers, &proto.PricingTier{Volume: t.Volume, Percentage: t.Percentage, Fixed: t.Fixed})
+	}
+	return res
+}
+
+func paymentFeeProto(f models.PaymentFee) *proto.PaymentFee {
+	res := &proto.PaymentFee{
+		PaymentId:     f.PaymentID,
+		PlanId:        f.PlanID,
+		Amount:        f.Amount,
+		Currency:      f.Currency,
+		PaymentAmount: f.PaymentAmount,
+		Method:        f.Method,
+		Percentage:    f.Percentage,
+		Fixed:         f.Fixed,
+		Volume:        f.Volume,
+	}
+	if f.CreatedAt != nil {
+		res.CreatedAt = timestamppb.New(*f.CreatedAt)
+	}
+	return res
+}
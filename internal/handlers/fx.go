@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"go-lang-final/internal/auth"
+	"go-lang-final/internal/fx"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// defaultQuoteTTL applies until ConfigureFX sets a quote TTL.
+const defaultQuoteTTL = 15 * time.Minute
+
+// ConfigureFX sets the currency payments are converted to when the request
+// names none, empty for no conversion, and how long quotes lock a rate.
+func (s *PaymentService) ConfigureFX(settlementCurrency string, quoteTTL time.Duration) {
+	s.settlementCurrency = settlementCurrency
+	s.quoteTTL = quoteTTL
+}
+
+// conversion returns what a create request asks to be converted to, or nil
+// when the payment stays in its own currency.
+func (s *PaymentService) conversion(req *proto.CreatePaymentRequest) *models.PaymentConversion {
+	if req.GetFxQuoteId() != "" {
+		return &models.PaymentConversion{SettlementCurrency: req.GetSettlementCurrency(), QuoteID: req.GetFxQuoteId()}
+	}
+	currency := req.GetSettlementCurrency()
+	if currency == "" {
+		currency = s.settlementCurrency
+	}
+	if currency == "" || currency == req.GetCurrency() {
+		return nil
+	}
+	return &models.PaymentConversion{SettlementCurrency: currency}
+}
+
+// ImportFXRates adds rates to the shared rate table, recording the calling
+// principal as their source.
+func (s *PaymentService) ImportFXRates(ctx context.Context, req *proto.ImportFXRatesRequest) (*proto.ImportFXRatesResponse, error) {
+	source := "api"
+	if p, ok := auth.PrincipalFromContext(ctx); ok {
+		source = "api:" + p.ID
+	}
+	rates := make([]fx.Rate, 0, len(req.GetRates()))
+	for _, r := range req.GetRates() {
+		rate := fx.Rate{Base: r.GetBase(), Quote: r.GetQuote(), Rate: r.GetRate(), Source: source}
+		if r.GetEffectiveAt() != nil {
+			rate.EffectiveAt = r.GetEffectiveAt().AsTime()
+		}
+		rates = append(rates, rate)
+	}
+
+	imported, err := s.store.SaveFXRates(ctx, rates)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to import fx rates")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to import fx rates: %v", err)
+	}
+	return &proto.ImportFXRatesResponse{Imported: int32(imported)}, nil
+}
+
+func (s *PaymentService) ListFXRates(ctx context.Context, req *proto.ListFXRatesRequest) (*proto.ListFXRatesResponse, error) {
+	page, pageSize := pagination(req.GetPage(), req.GetPageSize())
+
+	rates, err := s.store.ListFXRates(ctx, req.GetBase(), req.GetQuote(), page, pageSize)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to list fx rates")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to list fx rates: %v", err)
+	}
+
+	res := &proto.ListFXRatesResponse{}
+	for _, r := range rates {
+		res.Rates = append(res.Rates, &proto.FXRate{
+			Base:        r.Base,
+			Quote:       r.Quote,
+			Rate:        r.Rate,
+			EffectiveAt: timestamppb.New(r.EffectiveAt),
+			Source:      r.Source,
+		})
+	}
+	return res, nil
+}
+
+func (s *PaymentService) CreateFXQuote(ctx context.Context, req *proto.CreateFXQuoteRequest) (*proto.FXQuote, error) {
+	ttl := s.quoteTTL
+	if ttl <= 0 {
+		ttl = defaultQuoteTTL
+	}
+	quote, err := s.store.CreateFXQuote(ctx, req.GetCurrency(), req.GetSettlementCurrency(), req.GetAmount(), ttl)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to create fx quote")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to create fx quote: %v", err)
+	}
+	return &proto.FXQuote{
+		Id:                 quote.ID,
+		Currency:           quote.Currency,
+		SettlementCurrency: quote.SettlementCurrency,
+		Rate:               quote.Rate,
+		RateEffectiveAt:    timestamppb.New(quote.RateEffectiveAt),
+		Amount:             quote.Amount,
+		SettlementAmount:   quote.SettlementAmount,
+		ExpiresAt:          timestamppb.New(quote.ExpiresAt),
+		TenantId:           quote.TenantID,
+		CreatedAt:          timestamppb.New(quote.CreatedAt),
+	}, nil
+}
+
+func (s *PaymentService) GetPaymentConversion(ctx context.Context, req *proto.GetPaymentConversionRequest) (*proto.PaymentConversion, error) {
+	c, err := s.store.GetPaymentConversion(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "payment conversion not found: %v", err)
+	}
+	return paymentConversionProto(*c), nil
+}
+
+func paymentConversionProto(c models.PaymentConversion) *proto.PaymentConversion {
+	return &proto.PaymentConversion{
+		PaymentId:          c.PaymentID,
+		Amount:             c.Amount,
+		Currency:           c.Currency,
+		SettlementAmount:   c.SettlementAmount,
+		SettlementCurrency: c.SettlementCurrency,
+		Rate:               c.Rate,
+		RateEffectiveAt:    timestamppb.New(c.RateEffectiveAt),
+		QuoteId:            c.QuoteID,
+		CreatedAt:          timestamppb.New(c.CreatedAt),
+	}
+}
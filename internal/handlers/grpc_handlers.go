@@ -2,51 +2,175 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"go-lang-final/internal/blob"
+	"go-lang-final/internal/disputes"
+	"go-lang-final/internal/idempotency"
+	"go-lang-final/internal/invoices"
+	"go-lang-final/internal/logging"
 	"go-lang-final/internal/models"
+	"go-lang-final/internal/ratelimit"
+	"go-lang-final/internal/reconcile"
+	"go-lang-final/internal/routing"
+	"go-lang-final/internal/sepa"
 	"go-lang-final/internal/store"
+	"go-lang-final/internal/vault"
 	"go-lang-final/proto"
 )
 
-func RegisterGRPCHandlers(grpcServer *grpc.Server, store *store.PaymentStore) {
-	proto.RegisterPaymentServiceServer(grpcServer, &PaymentService{store: store})
+// defaultPageSize applies when a list request leaves pageSize unset.
+const defaultPageSize = 20
+
+// pagination fills in the first page and the default page size for unset
+// list request fields.
+func pagination(page, pageSize int32) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	return int(page), int(pageSize)
+}
+
+func RegisterGRPCHandlers(grpcServer *grpc.Server, svc *PaymentService) {
+	proto.RegisterPaymentServiceServer(grpcServer, svc)
 }
 
 type PaymentService struct {
 	proto.UnimplementedPaymentServiceServer
-	store *store.PaymentStore
+	store  *store.PaymentStore
+	router *routing.Router
+	vault  *vault.Vault
+	// debtor is the account credit transfer batches pay out of; nil until
+	// EnableCreditTransfers.
+	debtor *sepa.Party
+	// reconciliation holds the default matching options; nil means
+	// reconcile.DefaultOptions.
+	reconciliation *reconcile.Options
+	// settlements holds the settlement file formats by provider name.
+	settlements        map[string]SettlementFormat
+	settlementLookback int
+	// settlementCurrency is what payments are converted to by default,
+	// empty for none; quoteTTL is how long fx quotes lock their rate.
+	settlementCurrency string
+	quoteTTL           time.Duration
+	// fees turns on charging captured payments the fee of their tenant's
+	// pricing plan.
+	fees bool
+	// subscriptionLease is how long a renewal being charged is held, and
+	// dunning the waits before failed renewals are tried again.
+	subscriptionLease time.Duration
+	dunning           []time.Duration
+	// invoicePrefix starts invoice numbers, the default prefix when empty;
+	// issuer is who invoices are from.
+	invoicePrefix string
+	issuer        invoices.Issuer
+	// evidence is where dispute evidence is kept, nil until
+	// ConfigureDisputes; disputeReminders are the lead times of deadline
+	// reminders, longest first, and notifier delivers them.
+	evidence         blob.Store
+	disputeReminders []time.Duration
+	notifier         disputes.Notifier
 }
 
 func (s *PaymentService) CreatePayment(ctx context.Context, req *proto.CreatePaymentRequest) (*proto.CreatePaymentResponse, error) {
-	payment := models.Payment{
-		ID:       req.GetId(),
-		Amount:   req.GetAmount(),
-		Currency: req.GetCurrency(),
+	ctx, err := idempotency.FromIncoming(ctx)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	err := s.store.CreatePayment(payment)
+	payment := models.Payment{
+		ID:                  req.GetId(),
+		Amount:              req.GetAmount(),
+		Currency:            req.GetCurrency(),
+		Status:              models.StatusCreated,
+		PaymentMethod:       req.GetPaymentMethod(),
+		Customer:            req.GetCustomer(),
+		Conversion:          s.conversion(req),
+		Metadata:            req.GetMetadata(),
+		Description:         strings.TrimSpace(req.GetDescription()),
+		StatementDescriptor: req.GetStatementDescriptor(),
+	}
+	actionURL, err := s.createPayment(ctx, &payment, req.GetCardNumber())
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create payment: %v", err)
+		if errors.Is(err, store.ErrDailyQuotaExceeded) {
+			return nil, ratelimit.ResourceExhausted(err.Error(), ratelimit.UntilQuotaReset(time.Now()))
+		}
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to create payment: %v", err)
+	}
+
+	res := &proto.CreatePaymentResponse{Success: true, Status: payment.Status, ActionUrl: actionURL}
+	if c := payment.Conversion; c != nil && !c.CreatedAt.IsZero() {
+		res.Conversion = paymentConversionProto(*c)
+	}
+	if f := payment.Fee; f != nil && f.CreatedAt != nil {
+		res.Fee = paymentFeeProto(*f)
 	}
+	return res, nil
+}
 
-	return &proto.CreatePaymentResponse{Success: true}, nil
+// createPayment charges payment through its provider, if it has one, and
+// stores it, charged the fee of the tenant's plan when fees are on. It
+// fills in the payment and returns the URL of a challenge the customer has
+// to complete, if any. A provider transaction whose payment could not be
+// stored is released.
+func (s *PaymentService) createPayment(ctx context.Context, payment *models.Payment, cardNumber string) (string, error) {
+	if s.fees {
+		payment.Fee = &models.PaymentFee{}
+	}
+	var (
+		actionURL string
+		err       error
+	)
+	// Bank transfers arrive at the bank, not through a payment provider.
+	if s.router != nil && !strings.HasPrefix(payment.PaymentMethod, models.BankAccountPrefix) {
+		if actionURL, err = s.charge(ctx, payment, cardNumber); err != nil {
+			logging.FromContext(ctx).WithError(err).Error("payment provider refused payment")
+			return "", err
+		}
+	}
+
+	if err := s.store.CreatePayment(ctx, *payment); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to create payment")
+		// Only a fresh provider transaction can be released safely; with an
+		// idempotency key the client retries into the same one.
+		if payment.ProviderRef != "" && (store.Rejected(err) || idempotency.FromContext(ctx) == "") {
+			s.release(ctx, *payment)
+		}
+		return "", err
+	}
+	return actionURL, nil
 }
 
 func (s *PaymentService) GetPayment(ctx context.Context, req *proto.GetPaymentRequest) (*proto.GetPaymentResponse, error) {
-	payment, err := s.store.GetPayment(req.GetId())
+	payment, err := s.store.GetPayment(ctx, req.GetId())
 	if err != nil {
-		return nil, status.Errorf(codes.NotFound, "payment not found: %v", err)
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "payment not found: %v", err)
 	}
 
 	return &proto.GetPaymentResponse{
-		Id:       payment.ID,
-		Amount:   payment.Amount,
-		Currency: payment.Currency,
+		Id:                  payment.ID,
+		Amount:              payment.Amount,
+		Currency:            payment.Currency,
+		TenantId:            payment.TenantID,
+		Status:              payment.Status,
+		RefundedAmount:      payment.RefundedAmount,
+		Provider:            payment.Provider,
+		ProviderRef:         payment.ProviderRef,
+		ProviderStatus:      payment.ProviderStatus,
+		PaymentMethod:       payment.PaymentMethod,
+		Metadata:            payment.Metadata,
+		Description:         payment.Description,
+		StatementDescriptor: payment.StatementDescriptor,
 	}, nil
 }
 
@@ -57,36 +181,53 @@ func (s *PaymentService) UpdatePayment(ctx context.Context, req *proto.UpdatePay
 		Currency: req.GetCurrency(),
 	}
 
-	err := s.store.UpdatePayment(req.GetId(), payment)
+	details := store.PaymentDetails{
+		Metadata:            req.GetMetadata(),
+		Description:         req.Description,
+		StatementDescriptor: req.StatementDescriptor,
+	}
+
+	err := s.store.UpdatePayment(ctx, req.GetId(), payment, details)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to update payment: %v", err)
+		logging.FromContext(ctx).WithError(err).Error("failed to update payment")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to update payment: %v", err)
 	}
 
 	return &proto.UpdatePaymentResponse{Success: true}, nil
 }
 
 func (s *PaymentService) DeletePayment(ctx context.Context, req *proto.DeletePaymentRequest) (*proto.DeletePaymentResponse, error) {
-	err := s.store.DeletePayment(req.GetId())
+	err := s.store.DeletePayment(ctx, req.GetId())
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to delete payment: %v", err)
+		logging.FromContext(ctx).WithError(err).Error("failed to delete payment")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to delete payment: %v", err)
 	}
 
 	return &proto.DeletePaymentResponse{Success: true}, nil
 }
 
 func (s *PaymentService) ListPayments(ctx context.Context, req *proto.ListPaymentsRequest) (*proto.ListPaymentsResponse, error) {
-	payments, err := s.store.ListPayments(req.GetCurrency(), fmt.Sprintf("%.2f", req.GetAmount()), int(req.GetPage()), int(req.GetPageSize()))
+	var amount string
+	if req.Amount != nil {
+		amount = fmt.Sprintf("%.2f", req.GetAmount())
+	}
+	page, pageSize := pagination(req.GetPage(), req.GetPageSize())
+
+	filter := store.PaymentFilter{
+		Currency: req.GetCurrency(),
+		Amount:   amount,
+		Metadata: req.GetMetadata(),
+		Query:    strings.TrimSpace(req.GetQuery()),
+	}
+	payments, err := s.store.ListPayments(ctx, filter, page, pageSize)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to list payments: %v", err)
+		logging.FromContext(ctx).WithError(err).Error("failed to list payments")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to list payments: %v", err)
 	}
 
 	var paymentProtos []*proto.Payment
 	for _, payment := range payments {
-		paymentProtos = append(paymentProtos, &proto.Payment{
-			Id:       payment.ID,
-			Amount:   payment.Amount,
-			Currency: payment.Currency,
-		})
+		paymentProtos = append(paymentProtos, paymentProto(payment))
 	}
 
 	return &proto.ListPaymentsResponse{Payments: paymentProtos}, nil
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go-lang-final/internal/invoices"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Invoice document formats.
+const (
+	InvoiceFormatHTML = "html"
+	InvoiceFormatPDF  = "pdf"
+)
+
+// ConfigureInvoices sets the prefix of invoice numbers and who invoices are
+// from. An empty prefix uses invoices.DefaultNumberPrefix.
+func (s *PaymentService) ConfigureInvoices(prefix string, issuer invoices.Issuer) {
+	s.invoicePrefix, s.issuer = prefix, issuer
+}
+
+func (s *PaymentService) CreateInvoice(ctx context.Context, req *proto.Invoice) (*proto.Invoice, error) {
+	inv, err := s.store.CreateInvoice(ctx, invoiceModel(req))
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to create invoice")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to create invoice: %v", err)
+	}
+	return invoiceProto(inv), nil
+}
+
+func (s *PaymentService) GetInvoice(ctx context.Context, req *proto.GetInvoiceRequest) (*proto.Invoice, error) {
+	inv, err := s.store.GetInvoice(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "invoice not found: %v", err)
+	}
+	return invoiceProto(inv), nil
+}
+
+func (s *PaymentService) ListInvoices(ctx context.Context, req *proto.ListInvoicesRequest) (*proto.ListInvoicesResponse, error) {
+	page, pageSize := pagination(req.GetPage(), req.GetPageSize())
+
+	list, err := s.store.ListInvoices(ctx, req.GetStatus(), page, pageSize)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to list invoices")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to list invoices: %v", err)
+	}
+
+	res := &proto.ListInvoicesResponse{}
+	for i := range list {
+		res.Invoices = append(res.Invoices, invoiceProto(&list[i]))
+	}
+	return res, nil
+}
+
+// UpdateInvoice replaces the contents of a draft.
+func (s *PaymentService) UpdateInvoice(ctx context.Context, req *proto.Invoice) (*proto.Invoice, error) {
+	inv, err := s.store.UpdateInvoice(ctx, invoiceModel(req))
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to update invoice")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to update invoice: %v", err)
+	}
+	return invoiceProto(inv), nil
+}
+
+// FinalizeInvoice numbers a draft and opens it for payment.
+func (s *PaymentService) FinalizeInvoice(ctx context.Context, req *proto.FinalizeInvoiceRequest) (*proto.Invoice, error) {
+	prefix := s.invoicePrefix
+	if prefix == "" {
+		prefix = invoices.DefaultNumberPrefix
+	}
+	inv, err := s.store.FinalizeInvoice(ctx, req.GetId(), prefix, time.Now().UTC())
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to finalize invoice")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to finalize invoice: %v", err)
+	}
+	return invoiceProto(inv), nil
+}
+
+func (s *PaymentService) VoidInvoice(ctx context.Context, req *proto.VoidInvoiceRequest) (*proto.Invoice, error) {
+	inv, err := s.store.VoidInvoice(ctx, req.GetId(), time.Now().UTC())
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to void invoice")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to void invoice: %v", err)
+	}
+	return invoiceProto(inv), nil
+}
+
+// PayInvoice applies a payment, or part of it, to an open invoice.
+func (s *PaymentService) PayInvoice(ctx context.Context, req *proto.PayInvoiceRequest) (*proto.Invoice, error) {
+	inv, err := s.store.PayInvoice(ctx, req.GetId(), req.GetPaymentId(), req.GetAmount(), time.Now().UTC())
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to pay invoice")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to pay invoice: %v", err)
+	}
+	return invoiceProto(inv), nil
+}
+
+// RenderInvoice renders an invoice, drafts included, as an HTML page or a
+// PDF document.
+func (s *PaymentService) RenderInvoice(ctx context.Context, req *proto.RenderInvoiceRequest) (*proto.RenderInvoiceResponse, error) {
+	format := req.GetFormat()
+	if format == "" {
+		format = InvoiceFormatHTML
+	}
+	if format != InvoiceFormatHTML && format != InvoiceFormatPDF {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown invoice format %q", format)
+	}
+	inv, err := s.store.GetInvoice(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "invoice not found: %v", err)
+	}
+
+	if format == InvoiceFormatPDF {
+		return &proto.RenderInvoiceResponse{ContentType: "application/pdf", Document: invoices.RenderPDF(*inv, s.issuer)}, nil
+	}
+	doc, err := invoices.RenderHTML(*inv, s.issuer)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to render invoice")
+		return nil, status.Errorf(codes.Internal, "failed to render invoice: %v", err)
+	}
+	return &proto.RenderInvoiceResponse{ContentType: "text/html; charset=utf-8", Document: doc}, nil
+}
+
+func invoiceModel(req *proto.Invoice) models.Invoice {
+	inv := models.Invoice{
+		ID:              req.GetId(),
+		Currency:        req.GetCurrency(),
+		CustomerName:    strings.TrimSpace(req.GetCustomerName()),
+		CustomerEmail:   strings.TrimSpace(req.GetCustomerEmail()),
+		CustomerAddress: strings.TrimSpace(req.GetCustomerAddress()),
+		Memo:            strings.TrimSpace(req.GetMemo()),
+		DueDate:         req.GetDueDate(),
+	}
+	for _, l := range req.GetLines() {
+		inv.Lines = append(inv.Lines, models.InvoiceLine{
+			Description:  l.GetDescription(),
+			Quantity:     l.GetQuantity(),
+			UnitAmount:   l.GetUnitAmount(),
+			DiscountRate: l.GetDiscountRate(),
+			TaxRate:      l.GetTaxRate(),
+		})
+	}
+	return inv
+}
+
+func invoiceProto(inv *models.Invoice) *proto.Invoice {
+	res := &proto.Invoice{
+		Id:              inv.ID,
+		Number:          inv.Number,
+		Status:          inv.Status,
+		Currency:        inv.Currency,
+		CustomerName:    inv.CustomerName,
+		CustomerEmail:   inv.CustomerEmail,
+		CustomerAddress: inv.CustomerAddress,
+		Memo:            inv.Memo,
+		Subtotal:        inv.Subtotal,
+		Discount:        inv.Discount,
+		Tax:             inv.Tax,
+		Total:           inv.Total,
+		AmountPaid:      inv.AmountPaid,
+		AmountDue:       inv.AmountDue,
+		DueDate:         inv.DueDate,
+		IssuedAt:        optionalTimestamp(inv.IssuedAt),
+		PaidAt:          optionalTimestamp(inv.PaidAt),
+		VoidedAt:        optionalTimestamp(inv.VoidedAt),
+		CreatedAt:       timestamppb.New(inv.CreatedAt),
+		UpdatedAt:       timestamppb.New(inv.UpdatedAt),
+	}
+	for _, l := range inv.Lines {
+		res.Lines = append(res.Lines, &proto.InvoiceLine{
+			Description:  l.Description,
+			Quantity:     l.Quantity,
+			UnitAmount:   l.UnitAmount,
+			DiscountRate: l.DiscountRate,
+			TaxRate:      l.TaxRate,
+			Amount:       l.Amount,
+			Discount:     l.Discount,
+			Tax:          l.Tax,
+		})
+	}
+	for _, p := range inv.Payments {
+		res.Payments = append(res.Payments, &proto.InvoicePayment{
+			PaymentId: p.PaymentID,
+			Amount:    p.Amount,
+			CreatedAt: timestamppb.New(p.CreatedAt),
+		})
+	}
+	return res
+}
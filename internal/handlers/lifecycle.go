@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/proto"
+)
+
+// watchInterval is how often WatchPayments polls for new events, and
+// watchBatch how many it reads per poll.
+const (
+	watchInterval = time.Second
+	watchBatch    = 100
+)
+
+func (s *PaymentService) RefundPayment(ctx context.Context, req *proto.RefundPaymentRequest) (*proto.RefundPaymentResponse, error) {
+	payment, err := s.store.RefundPayment(ctx, req.GetId(), req.GetAmount(), req.GetReason(), s.refundCall(ctx, req.GetAmount()))
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to refund payment")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to refund payment: %v", err)
+	}
+
+	return &proto.RefundPaymentResponse{Payment: paymentProto(*payment)}, nil
+}
+
+func (s *PaymentService) CancelPayment(ctx context.Context, req *proto.CancelPaymentRequest) (*proto.CancelPaymentResponse, error) {
+	payment, err := s.store.CancelPayment(ctx, req.GetId(), req.GetReason(), s.voidCall(ctx))
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to cancel payment")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to cancel payment: %v", err)
+	}
+
+	return &proto.CancelPaymentResponse{Payment: paymentProto(*payment)}, nil
+}
+
+func (s *PaymentService) ListPaymentEvents(ctx context.Context, req *proto.ListPaymentEventsRequest) (*proto.ListPaymentEventsResponse, error) {
+	events, err := s.store.PaymentEvents(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to list payment events: %v", err)
+	}
+
+	res := &proto.ListPaymentEventsResponse{}
+	for _, event := range events {
+		res.Events = append(res.Events, eventProto(event))
+	}
+	return res, nil
+}
+
+// WatchPayments streams every event after req.after_event_id and then
+// follows new events until the client goes away.
+func (s *PaymentService) WatchPayments(req *proto.WatchPaymentsRequest, stream proto.PaymentService_WatchPaymentsServer) error {
+	ctx := stream.Context()
+	after := req.GetAfterEventId()
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		events, err := s.store.EventsAfter(ctx, after, watchBatch)
+		if err != nil {
+			return status.Errorf(grpcCode(err, codes.Internal), "failed to watch payments: %v", err)
+		}
+		for _, event := range events {
+			if err := stream.Send(eventProto(event)); err != nil {
+				return err
+			}
+			after = event.ID
+		}
+		if len(events) == watchBatch {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func paymentProto(payment models.Payment) *proto.Payment {
+	return &proto.Payment{
+		Id:                  payment.ID,
+		Amount:              payment.Amount,
+		Currency:            payment.Currency,
+		TenantId:            payment.TenantID,
+		Status:              payment.Status,
+		RefundedAmount:      payment.RefundedAmount,
+		Provider:            payment.Provider,
+		ProviderRef:         payment.ProviderRef,
+		ProviderStatus:      payment.ProviderStatus,
+		PaymentMethod:       payment.PaymentMethod,
+		Customer:            payment.Customer,
+		Metadata:            payment.Metadata,
+		Description:         payment.Description,
+		StatementDescriptor: payment.StatementDescriptor,
+	}
+}
+
+func eventProto(event models.PaymentEvent) *proto.PaymentEvent {
+	return &proto.PaymentEvent{
+		Id:        event.ID,
+		PaymentId: event.PaymentID,
+		TenantId:  event.TenantID,
+		Type:      event.Type,
+		Amount:    event.Amount,
+		Currency:  event.Currency,
+		Status:    event.Status,
+		Reason:    event.Reason,
+		CreatedAt: timestamppb.New(event.CreatedAt),
+	}
+}
@@ -0,0 +1,26 @@
+package handlers
+
+// MergePatchContentType is the media type of an RFC 7386 JSON Merge Patch.
+const MergePatchContentType = "application/merge-patch+json"
+
+// mergePatch applies an RFC 7386 merge patch to target, both decoded with
+// encoding/json. Null members of patch remove the member from target, objects
+// are merged recursively and any other value replaces the target outright.
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+	return t
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/vault"
+	"go-lang-final/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *PaymentService) CreatePaymentMethod(ctx context.Context, req *proto.CreatePaymentMethodRequest) (*proto.PaymentMethod, error) {
+	if s.vault == nil {
+		return nil, status.Error(codes.Unimplemented, "payment methods are not available")
+	}
+
+	method, err := s.vault.Tokenize(ctx, vault.Card{
+		Number:   req.GetNumber(),
+		ExpMonth: int(req.GetExpMonth()),
+		ExpYear:  int(req.GetExpYear()),
+	})
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to create payment method")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to create payment method: %v", err)
+	}
+	return paymentMethodProto(*method), nil
+}
+
+func (s *PaymentService) GetPaymentMethod(ctx context.Context, req *proto.GetPaymentMethodRequest) (*proto.PaymentMethod, error) {
+	if s.vault == nil {
+		return nil, status.Error(codes.Unimplemented, "payment methods are not available")
+	}
+
+	method, err := s.vault.PaymentMethod(ctx, req.GetToken())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "payment method not found: %v", err)
+	}
+	return paymentMethodProto(*method), nil
+}
+
+func paymentMethodProto(method models.PaymentMethod) *proto.PaymentMethod {
+	return &proto.PaymentMethod{
+		Token:     method.Token,
+		Brand:     method.Brand,
+		Last4:     method.Last4,
+		ExpMonth:  int32(method.ExpMonth),
+		ExpYear:   int32(method.ExpYear),
+		TenantId:  method.TenantID,
+		CreatedAt: timestamppb.New(method.CreatedAt),
+	}
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"go-lang-final/internal/rbac"
+	"go-lang-final/proto"
+)
+
+// RoutePermissions annotates every named REST route with the permission it
+// requires. The RBAC middleware denies routes that are missing here.
+var RoutePermissions = map[string]rbac.Permission{
+	"CreatePayment": rbac.PaymentsWrite,
+	"GetPayment":    rbac.PaymentsRead,
+	"UpdatePayment": rbac.PaymentsWrite,
+	"DeletePayment": rbac.PaymentsDelete,
+	"ListPayments":  rbac.PaymentsRead,
+
+	"RefundPayment":     rbac.RefundsCreate,
+	"CancelPayment":     rbac.PaymentsWrite,
+	"ListPaymentEvents": rbac.PaymentsRead,
+	"WatchPayments":     rbac.PaymentsRead,
+
+	"ImportFXRates":        rbac.FXRatesWrite,
+	"ListFXRates":          rbac.PaymentsRead,
+	"CreateFXQuote":        rbac.PaymentsWrite,
+	"GetPaymentConversion": rbac.PaymentsRead,
+
+	"CreatePricingPlan":    rbac.PricingPlansWrite,
+	"UpdatePricingPlan":    rbac.PricingPlansWrite,
+	"GetPricingPlan":       rbac.PricingPlansWrite,
+	"ListPricingPlans":     rbac.PricingPlansWrite,
+	"AssignPricingPlan":    rbac.PricingPlansWrite,
+	"GetTenantPricingPlan": rbac.PaymentsRead,
+	"SimulateFee":          rbac.PaymentsRead,
+	"GetPaymentFee":        rbac.PaymentsRead,
+	"GetFeeStatement":      rbac.LedgerRead,
+
+	"CreateSubscriptionPlan":  rbac.PaymentsWrite,
+	"GetSubscriptionPlan":     rbac.PaymentsRead,
+	"ListSubscriptionPlans":   rbac.PaymentsRead,
+	"CreateSubscription":      rbac.PaymentsWrite,
+	"GetSubscription":         rbac.PaymentsRead,
+	"ListSubscriptions":       rbac.PaymentsRead,
+	"ChangeSubscriptionPlan":  rbac.PaymentsWrite,
+	"CancelSubscription":      rbac.PaymentsWrite,
+	"ListSubscriptionCharges": rbac.PaymentsRead,
+	"CreateInvoice":           rbac.PaymentsWrite,
+	"ListInvoices":            rbac.PaymentsRead,
+	"GetInvoice":              rbac.PaymentsRead,
+	"UpdateInvoice":           rbac.PaymentsWrite,
+	"FinalizeInvoice":         rbac.PaymentsWrite,
+	"VoidInvoice":             rbac.PaymentsWrite,
+	"PayInvoice":              rbac.PaymentsWrite,
+	"RenderInvoice":           rbac.PaymentsRead,
+
+	"CreateCustomer":             rbac.PaymentsWrite,
+	"ListCustomers":              rbac.PaymentsRead,
+	"GetCustomer":                rbac.PaymentsRead,
+	"UpdateCustomer":             rbac.PaymentsWrite,
+	"DeleteCustomer":             rbac.PaymentsDelete,
+	"EraseCustomer":              rbac.CustomersErase,
+	"AttachPaymentMethod":        rbac.PaymentsWrite,
+	"ListCustomerPaymentMethods": rbac.PaymentsRead,
+	"ListCustomerPayments":       rbac.PaymentsRead,
+	"GetCustomerLifetimeValue":   rbac.PaymentsRead,
+
+	"CreateDispute":         rbac.DisputesWrite,
+	"ListDisputes":          rbac.PaymentsRead,
+	"GetDispute":            rbac.PaymentsRead,
+	"UploadDisputeEvidence": rbac.DisputesWrite,
+	"GetDisputeEvidence":    rbac.PaymentsRead,
+	"SubmitDispute":         rbac.DisputesWrite,
+	"AcceptDispute":         rbac.DisputesWrite,
+	"ResolveDispute":        rbac.DisputesWrite,
+
+	"CreatePaymentMethod": rbac.PaymentMethodsCreate,
+	"GetPaymentMethod":    rbac.PaymentsRead,
+
+	"CreateBankAccount":          rbac.PaymentMethodsCreate,
+	"GetBankAccount":             rbac.PaymentsRead,
+	"CreatePayout":               rbac.PayoutsWrite,
+	"GetCreditTransfer":          rbac.PaymentsRead,
+	"CreateCreditTransferBatch":  rbac.PayoutsWrite,
+	"GetCreditTransferBatch":     rbac.PaymentsRead,
+	"GetCreditTransferBatchFile": rbac.PayoutsWrite,
+	"ImportPaymentStatusReport":  rbac.PayoutsWrite,
+
+	"ImportBankStatement":       rbac.ReconciliationWrite,
+	"GetReconciliationRun":      rbac.PaymentsRead,
+	"ListReconciliationRuns":    rbac.PaymentsRead,
+	"ResolveReconciliationLine": rbac.ReconciliationWrite,
+
+	"ImportSettlementReport": rbac.ReconciliationWrite,
+	"GetSettlementBatch":     rbac.PaymentsRead,
+	"GetSettlementReport":    rbac.PaymentsRead,
+	"ListSettlementBatches":  rbac.PaymentsRead,
+	"ListLedgerPostings":     rbac.LedgerRead,
+}
+
+// MethodPermissions is the gRPC counterpart of RoutePermissions.
+var MethodPermissions = map[string]rbac.Permission{
+	proto.PaymentService_CreatePayment_FullMethodName: rbac.PaymentsWrite,
+	proto.PaymentService_GetPayment_FullMethodName:    rbac.PaymentsRead,
+	proto.PaymentService_UpdatePayment_FullMethodName: rbac.PaymentsWrite,
+	proto.PaymentService_DeletePayment_FullMethodName: rbac.PaymentsDelete,
+	proto.PaymentService_ListPayments_FullMethodName:  rbac.PaymentsRead,
+
+	proto.PaymentService_RefundPayment_FullMethodName:     rbac.RefundsCreate,
+	proto.PaymentService_CancelPayment_FullMethodName:     rbac.PaymentsWrite,
+	proto.PaymentService_ListPaymentEvents_FullMethodName: rbac.PaymentsRead,
+	proto.PaymentService_WatchPayments_FullMethodName:     rbac.PaymentsRead,
+
+	proto.PaymentService_ImportFXRates_FullMethodName:        rbac.FXRatesWrite,
+	proto.PaymentService_ListFXRates_FullMethodName:          rbac.PaymentsRead,
+	proto.PaymentService_CreateFXQuote_FullMethodName:        rbac.PaymentsWrite,
+	proto.PaymentService_GetPaymentConversion_FullMethodName: rbac.PaymentsRead,
+
+	proto.PaymentService_CreatePricingPlan_FullMethodName:    rbac.PricingPlansWrite,
+	proto.PaymentService_UpdatePricingPlan_FullMethodName:    rbac.PricingPlansWrite,
+	proto.PaymentService_GetPricingPlan_FullMethodName:       rbac.PricingPlansWrite,
+	proto.PaymentService_ListPricingPlans_FullMethodName:     rbac.PricingPlansWrite,
+	proto.PaymentService_AssignPricingPlan_FullMethodName:    rbac.PricingPlansWrite,
+	proto.PaymentService_GetTenantPricingPlan_FullMethodName: rbac.PaymentsRead,
+	proto.PaymentService_SimulateFee_FullMethodName:          rbac.PaymentsRead,
+	proto.PaymentService_GetPaymentFee_FullMethodName:        rbac.PaymentsRead,
+	proto.PaymentService_GetFeeStatement_FullMethodName:      rbac.LedgerRead,
+
+	proto.PaymentService_CreateSubscriptionPlan_FullMethodName:  rbac.PaymentsWrite,
+	proto.PaymentService_GetSubscriptionPlan_FullMethodName:     rbac.PaymentsRead,
+	proto.PaymentService_ListSubscriptionPlans_FullMethodName:   rbac.PaymentsRead,
+	proto.PaymentService_CreateSubscription_FullMethodName:      rbac.PaymentsWrite,
+	proto.PaymentService_GetSubscription_FullMethodName:         rbac.PaymentsRead,
+	proto.PaymentService_ListSubscriptions_FullMethodName:       rbac.PaymentsRead,
+	proto.PaymentService_ChangeSubscriptionPlan_FullMethodName:  rbac.PaymentsWrite,
+	proto.PaymentService_CancelSubscription_FullMethodName:      rbac.PaymentsWrite,
+	proto.PaymentService_ListSubscriptionCharges_FullMethodName: rbac.PaymentsRead,
+	proto.PaymentService_CreateInvoice_FullMethodName:           rbac.PaymentsWrite,
+	proto.PaymentService_GetInvoice_FullMethodName:              rbac.PaymentsRead,
+	proto.PaymentService_ListInvoices_FullMethodName:            rbac.PaymentsRead,
+	proto.PaymentService_UpdateInvoice_FullMethodName:           rbac.PaymentsWrite,
+	proto.PaymentService_FinalizeInvoice_FullMethodName:         rbac.PaymentsWrite,
+	proto.PaymentService_VoidInvoice_FullMethodName:             rbac.PaymentsWrite,
+	proto.PaymentService_PayInvoice_FullMethodName:              rbac.PaymentsWrite,
+	proto.PaymentService_RenderInvoice_FullMethodName:           rbac.PaymentsRead,
+
+	proto.PaymentService_CreateCustomer_FullMethodName:             rbac.PaymentsWrite,
+	proto.PaymentService_GetCustomer_FullMethodName:                rbac.PaymentsRead,
+	proto.PaymentService_ListCustomers_FullMethodName:              rbac.PaymentsRead,
+	proto.PaymentService_UpdateCustomer_FullMethodName:             rbac.PaymentsWrite,
+	proto.PaymentService_DeleteCustomer_FullMethodName:             rbac.PaymentsDelete,
+	proto.PaymentService_EraseCustomer_FullMethodName:              rbac.CustomersErase,
+	proto.PaymentService_AttachPaymentMethod_FullMethodName:        rbac.PaymentsWrite,
+	proto.PaymentService_ListCustomerPaymentMethods_FullMethodName: rbac.PaymentsRead,
+	proto.PaymentService_ListCustomerPayments_FullMethodName:       rbac.PaymentsRead,
+	proto.PaymentService_GetCustomerLifetimeValue_FullMethodName:   rbac.PaymentsRead,
+
+	proto.PaymentService_CreateDispute_FullMethodName:         rbac.DisputesWrite,
+	proto.PaymentService_GetDispute_FullMethodName:            rbac.PaymentsRead,
+	proto.PaymentService_ListDisputes_FullMethodName:          rbac.PaymentsRead,
+	proto.PaymentService_UploadDisputeEvidence_FullMethodName: rbac.DisputesWrite,
+	proto.PaymentService_GetDisputeEvidence_FullMethodName:    rbac.PaymentsRead,
+	proto.PaymentService_SubmitDispute_FullMethodName:         rbac.DisputesWrite,
+	proto.PaymentService_AcceptDispute_FullMethodName:         rbac.DisputesWrite,
+	proto.PaymentService_ResolveDispute_FullMethodName:        rbac.DisputesWrite,
+
+	proto.PaymentService_CreatePaymentMethod_FullMethodName: rbac.PaymentMethodsCreate,
+	proto.PaymentService_GetPaymentMethod_FullMethodName:    rbac.PaymentsRead,
+
+	proto.PaymentService_CreateBankAccount_FullMethodName:         rbac.PaymentMethodsCreate,
+	proto.PaymentService_GetBankAccount_FullMethodName:            rbac.PaymentsRead,
+	proto.PaymentService_CreatePayout_FullMethodName:              rbac.PayoutsWrite,
+	proto.PaymentService_GetCreditTransfer_FullMethodName:         rbac.PaymentsRead,
+	proto.PaymentService_CreateCreditTransferBatch_FullMethodName: rbac.PayoutsWrite,
+	proto.PaymentService_GetCreditTransferBatch_FullMethodName:    rbac.PaymentsRead,
+	proto.PaymentService_ImportPaymentStatusReport_FullMethodName: rbac.PayoutsWrite,
+
+	proto.PaymentService_ImportBankStatement_FullMethodName:       rbac.ReconciliationWrite,
+	proto.PaymentService_GetReconciliationRun_FullMethodName:      rbac.PaymentsRead,
+	proto.PaymentService_ListReconciliationRuns_FullMethodName:    rbac.PaymentsRead,
+	proto.PaymentService_ResolveReconciliationLine_FullMethodName: rbac.ReconciliationWrite,
+
+	proto.PaymentService_ImportSettlementReport_FullMethodName: rbac.ReconciliationWrite,
+	proto.PaymentService_GetSettlementBatch_FullMethodName:     rbac.PaymentsRead,
+	proto.PaymentService_ListSettlementBatches_FullMethodName:  rbac.PaymentsRead,
+	proto.PaymentService_ListLedgerPostings_FullMethodName:     rbac.LedgerRead,
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go-lang-final/internal/idempotency"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/provider"
+	"go-lang-final/internal/routing"
+	"go-lang-final/internal/store"
+	"go-lang-final/internal/tenant"
+	"go-lang-final/internal/vault"
+
+	"github.com/sirupsen/logrus"
+)
+
+// providerSyncBatch is how many pending payments one SyncProvider pass
+// looks at.
+const providerSyncBatch = 100
+
+// NewPaymentService returns the service charging payments through p. With
+// a nil provider payments are only recorded, as before providers existed.
+func NewPaymentService(store *store.PaymentStore, p provider.Provider) *PaymentService {
+	if p == nil {
+		return &PaymentService{store: store}
+	}
+	return NewRoutedPaymentService(store, routing.Single(p), nil)
+}
+
+// NewRoutedPaymentService returns the service charging each payment through
+// the provider r picks for it. Cards are tokenized with v; with a nil vault
+// payment methods are not available.
+func NewRoutedPaymentService(store *store.PaymentStore, r *routing.Router, v *vault.Vault) *PaymentService {
+	return &PaymentService{store: store, router: r, vault: v}
+}
+
+// charge authorizes and captures payment with the provider the router picks
+// and fills in its provider fields, status and routing decision. A payment
+// needing a challenge gets status requires_action and the challenge URL is
+// returned; SyncProvider captures it once the customer is done.
+func (s *PaymentService) charge(ctx context.Context, payment *models.Payment, cardNumber string) (string, error) {
+	key, err := providerKey(ctx, *payment)
+	if err != nil {
+		return "", err
+	}
+	scope, _ := tenant.FromContext(ctx)
+	p, txn, decision, err := s.router.Authorize(ctx, routing.Request{
+		TenantID:      scope.TenantID,
+		PaymentMethod: payment.PaymentMethod,
+		Authorization: provider.Authorization{
+			Key:        key,
+			Amount:     payment.Amount,
+			Currency:   payment.Currency,
+			CardNumber: cardNumber,
+		},
+	})
+	payment.Routing = decision
+	if err != nil {
+		logging.FromContext(ctx).WithFields(logrus.Fields{
+			"routing_rule":     decision.Rule,
+			"routing_attempts": decision.Attempts,
+		}).Info("payment not authorized by any provider")
+		return "", err
+	}
+	payment.Provider, payment.ProviderRef = p.Name(), txn.Ref
+	if txn.Status == provider.StatusAuthorized {
+		if txn, err = p.Capture(ctx, txn.Ref, payment.Amount); err != nil {
+			s.release(ctx, *payment)
+			return "", err
+		}
+	}
+
+	payment.ProviderStatus = string(txn.Status)
+	switch txn.Status {
+	case provider.StatusRequiresAction:
+		payment.Status = models.StatusRequiresAction
+	case provider.StatusCaptured, provider.StatusSettled:
+		payment.Status = models.StatusCreated
+	default:
+		// A replayed key whose earlier attempt was released.
+		return "", fmt.Errorf("%w: transaction %s is %s", provider.ErrNotAllowed, txn.Ref, txn.Status)
+	}
+	return txn.ActionURL, nil
+}
+
+// release gives back the funds of a charge whose payment was not stored: an
+// authorization or unsettled capture is voided, anything else refunded.
+func (s *PaymentService) release(ctx context.Context, payment models.Payment) {
+	ctx = context.WithoutCancel(ctx)
+	p, err := s.providerOf(payment)
+	if err == nil {
+		_, err = p.Void(ctx, payment.ProviderRef)
+		if errors.Is(err, provider.ErrNotAllowed) {
+			_, err = p.Refund(ctx, payment.ProviderRef, payment.Amount)
+		}
+	}
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).WithField("provider_ref", payment.ProviderRef).Error("failed to release provider transaction")
+	}
+}
+
+// providerKey derives the provider's idempotency key. A create replayed
+// with the same idempotency key and request reaches the same provider
+// transaction, so retries never charge twice; without an idempotency key
+// every attempt is a new transaction.
+func providerKey(ctx context.Context, payment models.Payment) (string, error) {
+	key := idempotency.FromContext(ctx)
+	if key == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(b), nil
+	}
+	scope, _ := tenant.FromContext(ctx)
+	tenantID, err := scope.Single()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%d\x00%s\x00%s", tenantID, key, payment.ID, strconv.FormatFloat(payment.Amount, 'f', -1, 64), payment.Currency)))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// providerOf returns the provider that processed payment.
+func (s *PaymentService) providerOf(payment models.Payment) (provider.Provider, error) {
+	p, ok := s.router.Provider(payment.Provider)
+	if !ok {
+		return nil, fmt.Errorf("payment provider %q is not configured", payment.Provider)
+	}
+	return p, nil
+}
+
+// refundCall refunds through the provider for payments it processed.
+func (s *PaymentService) refundCall(ctx context.Context, amount float64) store.ProviderCall {
+	if s.router == nil {
+		return nil
+	}
+	return func(current models.Payment) (string, error) {
+		if current.ProviderRef == "" {
+			return current.ProviderStatus, nil
+		}
+		p, err := s.providerOf(current)
+		if err != nil {
+			return "", err
+		}
+		txn, err := p.Refund(ctx, current.ProviderRef, amount)
+		if err != nil {
+			return "", err
+		}
+		return string(txn.Status), nil
+	}
+}
+
+// voidCall voids the provider transaction of a cancelled payment. Settled
+// transactions cannot be voided; such payments have to be refunded.
+func (s *PaymentService) voidCall(ctx context.Context) store.ProviderCall {
+	if s.router == nil {
+		return nil
+	}
+	return func(current models.Payment) (string, error) {
+		if current.ProviderRef == "" {
+			return current.ProviderStatus, nil
+		}
+		p, err := s.providerOf(current)
+		if err != nil {
+			return "", err
+		}
+		txn, err := p.Void(ctx, current.ProviderRef)
+		if err != nil {
+			return "", err
+		}
+		return string(txn.Status), nil
+	}
+}
+
+// SyncProvider fetches the provider status of payments whose transaction
+// can still change on its own and records what changed: a passed challenge
+// is captured and the payment goes through, a failed one cancels it, and
+// settlement is noted. ctx must carry a cross-tenant scope to cover every
+// tenant. It returns how many payments changed.
+func (s *PaymentService) SyncProvider(ctx context.Context) (int, error) {
+	if s.router == nil {
+		return 0, nil
+	}
+	payments, err := s.store.PendingProviderPayments(ctx, providerSyncBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, payment := range payments {
+		p, err := s.providerOf(payment)
+		if err != nil {
+			continue
+		}
+		ok, err := s.syncPayment(tenant.WithScope(ctx, tenant.Scope{TenantID: payment.TenantID}), p, payment)
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).WithFields(logrus.Fields{
+				"payment_id":   payment.ID,
+				"provider_ref": payment.ProviderRef,
+			}).Error("failed to sync payment with provider")
+			continue
+		}
+		if ok {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+func (s *PaymentService) syncPayment(ctx context.Context, p provider.Provider, payment models.Payment) (bool, error) {
+	txn, err := p.Status(ctx, payment.ProviderRef)
+	if err != nil {
+		return false, err
+	}
+
+	to, reason := payment.Status, ""
+	if payment.Status == models.StatusRequiresAction {
+		switch txn.Status {
+		case provider.StatusAuthorized:
+			if txn, err = p.Capture(ctx, payment.ProviderRef, payment.Amount); err != nil {
+				return false, err
+			}
+			to, reason = models.StatusCreated, "challenge completed"
+		case provider.StatusFailed, provider.StatusVoided:
+			to, reason = models.StatusCancelled, "challenge failed"
+		}
+	}
+	if to == payment.Status && string(txn.Status) == payment.ProviderStatus {
+		return false, nil
+	}
+
+	_, err = s.store.RecordProviderStatus(ctx, payment.ID, payment.Status, to, string(txn.Status), reason, s.fees)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RunProviderSync calls SyncProvider every interval until ctx is done.
+func (s *PaymentService) RunProviderSync(ctx context.Context, interval time.Duration, logger *logrus.Logger) {
+	ctx = tenant.WithScope(ctx, tenant.Scope{All: true})
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.SyncProvider(ctx); err != nil {
+			logger.WithError(err).Error("payment provider sync failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
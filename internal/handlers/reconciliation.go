@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+
+	"go-lang-final/internal/auth"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/money"
+	"go-lang-final/internal/reconcile"
+	"go-lang-final/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ConfigureReconciliation sets the matching options bank statements are
+// reconciled with when the import does not set its own.
+func (s *PaymentService) ConfigureReconciliation(opts reconcile.Options) {
+	s.reconciliation = &opts
+}
+
+// ImportBankStatement parses a camt.053 or MT940 statement and matches its
+// lines. A tolerance or date window left at zero falls back to the
+// configured default.
+func (s *PaymentService) ImportBankStatement(ctx context.Context, req *proto.ImportBankStatementRequest) (*proto.ReconciliationRun, error) {
+	opts := reconcile.DefaultOptions()
+	if s.reconciliation != nil {
+		opts = *s.reconciliation
+	}
+	if req.GetTolerance() < 0 || req.GetDateWindowDays() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "tolerance and date_window_days must not be negative")
+	}
+	if req.GetTolerance() > 0 {
+		opts.Tolerance = money.Minor(req.GetTolerance())
+	}
+	if req.GetDateWindowDays() > 0 {
+		opts.DateWindow = int(req.GetDateWindowDays())
+	}
+
+	statement, err := reconcile.Parse(req.GetDocument())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to import bank statement: %v", err)
+	}
+	run, err := s.store.CreateReconciliationRun(ctx, statement, opts)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to import bank statement")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to import bank statement: %v", err)
+	}
+	return reconciliationRunProto(*run), nil
+}
+
+func (s *PaymentService) GetReconciliationRun(ctx context.Context, req *proto.GetReconciliationRunRequest) (*proto.ReconciliationRun, error) {
+	run, err := s.store.GetReconciliationRun(ctx, req.GetId(), req.GetStatus())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "reconciliation run not found: %v", err)
+	}
+	return reconciliationRunProto(*run), nil
+}
+
+func (s *PaymentService) ListReconciliationRuns(ctx context.Context, req *proto.ListReconciliationRunsRequest) (*proto.ListReconciliationRunsResponse, error) {
+	page, pageSize := pagination(req.GetPage(), req.GetPageSize())
+
+	runs, err := s.store.ListReconciliationRuns(ctx, page, pageSize)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to list reconciliation runs")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to list reconciliation runs: %v", err)
+	}
+
+	res := &proto.ListReconciliationRunsResponse{}
+	for _, run := range runs {
+		res.Runs = append(res.Runs, reconciliationRunProto(run))
+	}
+	return res, nil
+}
+
+// ResolveReconciliationLine accepts or writes off an exception, recording
+// the calling principal as the one who resolved it.
+func (s *PaymentService) ResolveReconciliationLine(ctx context.Context, req *proto.ResolveReconciliationLineRequest) (*proto.ReconciliationLine, error) {
+	switch req.GetResolution() {
+	case models.ResolutionAccepted, models.ResolutionWrittenOff:
+	default:
+		return nil, status.Error(codes.InvalidArgument, "resolution must be accepted or written_off")
+	}
+	var resolvedBy string
+	if p, ok := auth.PrincipalFromContext(ctx); ok {
+		resolvedBy = p.ID
+	}
+
+	line, err := s.store.ResolveReconciliationLine(ctx, req.GetRunId(), int(req.GetLine()), req.GetResolution(), req.GetPaymentId(), req.GetNote(), resolvedBy)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to resolve reconciliation line")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to resolve reconciliation line: %v", err)
+	}
+	return reconciliationLineProto(*line), nil
+}
+
+func reconciliationRunProto(run models.ReconciliationRun) *proto.ReconciliationRun {
+	res := &proto.ReconciliationRun{
+		Id:               run.ID,
+		Format:           run.Format,
+		StatementId:      run.StatementID,
+		Account:          run.Account,
+		Tolerance:        run.Tolerance,
+		DateWindowDays:   int32(run.DateWindowDays),
+		LineCount:        int32(run.Lines),
+		Matched:          int32(run.Matched),
+		Unmatched:        int32(run.Unmatched),
+		Duplicated:       int32(run.Duplicated),
+		AmountMismatches: int32(run.AmountMismatches),
+		TenantId:         run.TenantID,
+		CreatedAt:        timestamppb.New(run.CreatedAt),
+	}
+	for _, line := range run.Items {
+		res.Lines = append(res.Lines, reconciliationLineProto(line))
+	}
+	return res
+}
+
+func reconciliationLineProto(line models.ReconciliationLine) *proto.ReconciliationLine {
+	res := &proto.ReconciliationLine{
+		Line:           int32(line.Line),
+		BankRef:        line.BankRef,
+		Reference:      line.Reference,
+		Description:    line.Description,
+		Direction:      line.Direction,
+		Amount:         line.Amount,
+		Currency:       line.Currency,
+		BookingDate:    line.BookingDate,
+		Status:         line.Status,
+		PaymentId:      line.PaymentID,
+		EventId:        line.EventID,
+		Difference:     line.Difference,
+		Resolution:     line.Resolution,
+		ResolutionNote: line.ResolutionNote,
+		ResolvedBy:     line.ResolvedBy,
+	}
+	if line.ResolvedAt != nil {
+		res.ResolvedAt = timestamppb.New(*line.ResolvedAt)
+	}
+	return res
+}
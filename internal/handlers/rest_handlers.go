@@ -1,138 +1,110 @@
 package handlers
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
-	"go-lang-final/internal/models"
+	"go-lang-final/internal/idempotency"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/ratelimit"
 	"go-lang-final/internal/store"
+	"go-lang-final/proto"
 
 	"github.com/gorilla/mux"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	protobuf "google.golang.org/protobuf/proto"
 )
 
-func RegisterRESTHandlers(r *mux.Router, store *store.PaymentStore, logger *logrus.Logger) {
-	handler := NewRestHandler(store)
-	r.HandleFunc("/create", handler.CreatePayment).Methods("POST")
-	r.HandleFunc("/get", handler.GetPayment).Methods("GET")
-	r.HandleFunc("/update", handler.UpdatePayment).Methods("PUT")
-	r.HandleFunc("/delete", handler.DeletePayment).Methods("DELETE")
-	r.HandleFunc("/list", handler.ListPayments).Methods("GET")
-}
-
-type RestHandler struct {
-	store *store.PaymentStore
-}
+// V1Sunset is the date after which the /v1 API and its legacy aliases may be
+// removed, announced in the Sunset header of every v1 response.
+var V1Sunset = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
 
-func NewRestHandler(store *store.PaymentStore) *RestHandler {
-	return &RestHandler{store: store}
+// RegisterRESTHandlers serves the REST API. The deprecated v1 API is generated
+// from payment.proto: an in-process gateway decodes each request and calls the
+// gRPC service implementation directly, so both transports share routes,
+// field names and error mapping. The v2 resource API is layered on the same
+// implementation. Every route is named after its RPC, which is what the
+// route-level middleware (RBAC, rate limits) keys on.
+func RegisterRESTHandlers(r *mux.Router, store *store.PaymentStore, logger *logrus.Logger) {
+	RegisterRESTService(r, NewPaymentService(store, nil), logger)
 }
 
-func (h *RestHandler) CreatePayment(w http.ResponseWriter, r *http.Request) {
-	var payment models.Payment
-	if err := json.NewDecoder(r.Body).Decode(&payment); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	if err := h.store.CreatePayment(payment); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+// RegisterRESTService is RegisterRESTHandlers for any implementation of the
+// gRPC service.
+func RegisterRESTService(r *mux.Router, svc proto.PaymentServiceServer, logger *logrus.Logger) {
+	r.Use(logging.Middleware(logger))
+
+	gateway := NewGateway(svc)
+	for _, b := range Bindings() {
+		successor := b.Path
+		if b.Deprecated {
+			successor = b.Successor
+		}
+		successor = strings.Replace(successor, "/v1/", "/v2/", 1)
+		r.Handle(b.Path, deprecated(successor, gateway)).Methods(b.Verb).Name(b.RPC)
 	}
-
-	w.WriteHeader(http.StatusCreated)
+	registerV2(r, svc, gateway)
 }
 
-func (h *RestHandler) GetPayment(w http.ResponseWriter, r *http.Request) {
-	idStr := r.URL.Query().Get("id")
-	id, err := strconv.ParseInt(idStr, 10, 64) // Convert to int64
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	payment, err := h.store.GetPayment(id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
-		return
-	}
-
-	if err := json.NewEncoder(w).Encode(payment); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+// NewGateway returns a gateway mux serving the PaymentService HTTP bindings
+// from svc, with requests validated as the gRPC interceptors validate them.
+func NewGateway(svc proto.PaymentServiceServer) *runtime.ServeMux {
+	gateway := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+		runtime.WithErrorHandler(errorHandler),
+		runtime.WithIncomingHeaderMatcher(incomingHeader),
+		runtime.WithForwardResponseOption(createdStatus),
+	)
+	// Registering against a server never fails; the error only exists for
+	// the endpoint-dialling variants.
+	_ = proto.RegisterPaymentServiceHandlerServer(context.Background(), gateway, validated{svc: svc})
+	return gateway
 }
 
-func (h *RestHandler) UpdatePayment(w http.ResponseWriter, r *http.Request) {
-	idStr := r.URL.Query().Get("id")
-	id, err := strconv.ParseInt(idStr, 10, 64) // Convert to int64
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+// incomingHeader forwards the Idempotency-Key header to the service as
+// metadata, on top of the gateway's defaults.
+func incomingHeader(key string) (string, bool) {
+	if http.CanonicalHeaderKey(key) == idempotency.Header {
+		return idempotency.Metadata, true
 	}
-
-	var payment models.Payment
-	if err := json.NewDecoder(r.Body).Decode(&payment); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	if err := h.store.UpdatePayment(id, payment); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.WriteHeader(http.StatusOK)
+	return runtime.DefaultHeaderMatcher(key)
 }
 
-func (h *RestHandler) DeletePayment(w http.ResponseWriter, r *http.Request) {
-	idStr := r.URL.Query().Get("id")
-	id, err := strconv.ParseInt(idStr, 10, 64) // Convert to int64
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	if err := h.store.DeletePayment(id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.WriteHeader(http.StatusOK)
+// deprecated marks responses from a v1 route and points at its v2
+// replacement.
+func deprecated(successor string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Sunset", V1Sunset.Format(http.TimeFormat))
+		w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		next.ServeHTTP(w, r)
+	})
 }
 
-func (h *RestHandler) ListPayments(w http.ResponseWriter, r *http.Request) {
-	// Handle query parameters for pagination and filters
-	currency := r.URL.Query().Get("currency")
-	amountStr := r.URL.Query().Get("amount")
-	amount, err := strconv.ParseFloat(amountStr, 64) // Convert to float64
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-	pageStr := r.URL.Query().Get("page")
-	page, err := strconv.Atoi(pageStr)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-	pageSizeStr := r.URL.Query().Get("pageSize")
-	pageSize, err := strconv.Atoi(pageSizeStr)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	payments, err := h.store.ListPayments(currency, fmt.Sprintf("%.2f", amount), page, pageSize)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+// errorHandler adds Retry-After for errors carrying RetryInfo, such as the
+// daily quota, before writing the standard gateway error response.
+func errorHandler(ctx context.Context, mux *runtime.ServeMux, m runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			w.Header().Set("Retry-After", strconv.Itoa(ratelimit.CeilSeconds(info.GetRetryDelay().AsDuration())))
+		}
 	}
+	runtime.DefaultHTTPErrorHandler(ctx, mux, m, w, r, err)
+}
 
-	if err := json.NewEncoder(w).Encode(payments); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+func createdStatus(ctx context.Context, w http.ResponseWriter, resp protobuf.Message) error {
+	if _, ok := resp.(*proto.CreatePaymentResponse); ok {
+		w.WriteHeader(http.StatusCreated)
 	}
+	return nil
 }
@@ -0,0 +1,459 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"go-lang-final/internal/idempotency"
+	"go-lang-final/internal/models"
+	"go-lang-final/proto"
+
+	"github.com/gorilla/mux"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// CreateRequest is the body of POST /v2/payments. The card number is passed
+// to the payment provider and never stored; a card stored with POST
+// /v2/payment_methods is charged by its token instead. A payment is
+// converted to settlement_currency at the current rate, or at the rate of
+// the quote fx_quote_id. customer is the id of the customer paying, if
+// known. metadata, description and statement_descriptor are the
+// integrator's own.
+type CreateRequest struct {
+	ID                  int64             `json:"id"`
+	Amount              float64           `json:"amount"`
+	Currency            string            `json:"currency"`
+	CardNumber          string            `json:"card_number,omitempty"`
+	PaymentMethod       string            `json:"payment_method,omitempty"`
+	SettlementCurrency  string            `json:"settlement_currency,omitempty"`
+	FXQuoteID           string            `json:"fx_quote_id,omitempty"`
+	Customer            string            `json:"customer,omitempty"`
+	Metadata            map[string]string `json:"metadata,omitempty"`
+	Description         string            `json:"description,omitempty"`
+	StatementDescriptor string            `json:"statement_descriptor,omitempty"`
+}
+
+// ListEnvelope is the body of every v2 list response.
+type ListEnvelope struct {
+	Data       []models.Payment `json:"data"`
+	Pagination Pagination       `json:"pagination"`
+	Links      Links            `json:"links"`
+}
+
+type Pagination struct {
+	Page     int `json:"page"`
+	PageSize int `json:"page_size"`
+}
+
+// Links holds the pagination links of a list response. Next is only set when
+// the current page is full, Prev only past the first page.
+type Links struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// restV2 serves the resource-oriented /v2 API on top of the gRPC service
+// implementation. Unlike /v1 it is not generated from google.api.http
+// bindings; see proto/payment.proto. Every route is named after the RPC it
+// calls, which is also its operationId in internal/apidocs. Errors share
+// the gateway's status mapping and body.
+type restV2 struct {
+	svc     proto.PaymentServiceServer
+	gateway *runtime.ServeMux
+	// feedSource pages the event feed; nil when the service cannot.
+	feedSource EventSource
+}
+
+func registerV2(r *mux.Router, svc proto.PaymentServiceServer, gateway *runtime.ServeMux) {
+	h := &restV2{svc: validated{svc: svc}, gateway: gateway}
+	h.feedSource, _ = svc.(EventSource)
+	r.HandleFunc("/v2/payments", h.create).Methods("POST").Name("CreatePayment")
+	r.HandleFunc("/v2/payments", h.list).Methods("GET").Name("ListPayments")
+	r.HandleFunc("/v2/payments/{id}", h.get).Methods("GET").Name("GetPayment")
+	r.HandleFunc("/v2/payments/{id}", h.patch).Methods("PATCH").Name("UpdatePayment")
+	r.HandleFunc("/v2/payments/{id}", h.delete).Methods("DELETE").Name("DeletePayment")
+	r.HandleFunc("/v2/payments/{id}/refund", h.refund).Methods("POST").Name("RefundPayment")
+	r.HandleFunc("/v2/payments/{id}/cancel", h.cancel).Methods("POST").Name("CancelPayment")
+	r.HandleFunc("/v2/payments/{id}/events", h.events).Methods("GET").Name("ListPaymentEvents")
+	r.HandleFunc("/v2/payments/{id}/conversion", h.getConversion).Methods("GET").Name("GetPaymentConversion")
+	r.HandleFunc("/v2/events", h.feed).Methods("GET").Name("WatchPayments")
+	r.HandleFunc("/v2/payment_methods", h.createPaymentMethod).Methods("POST").Name("CreatePaymentMethod")
+	r.HandleFunc("/v2/payment_methods/{token}", h.getPaymentMethod).Methods("GET").Name("GetPaymentMethod")
+	r.HandleFunc("/v2/bank_accounts", h.createBankAccount).Methods("POST").Name("CreateBankAccount")
+	r.HandleFunc("/v2/bank_accounts/{token}", h.getBankAccount).Methods("GET").Name("GetBankAccount")
+	r.HandleFunc("/v2/payouts", h.createPayout).Methods("POST").Name("CreatePayout")
+	r.HandleFunc("/v2/credit_transfers/{id}", h.getCreditTransfer).Methods("GET").Name("GetCreditTransfer")
+	r.HandleFunc("/v2/credit_transfer_batches", h.createBatch).Methods("POST").Name("CreateCreditTransferBatch")
+	r.HandleFunc("/v2/credit_transfer_batches/status_reports", h.importStatusReport).Methods("POST").Name("ImportPaymentStatusReport")
+	r.HandleFunc("/v2/credit_transfer_batches/{id}", h.getBatch).Methods("GET").Name("GetCreditTransferBatch")
+	r.HandleFunc("/v2/credit_transfer_batches/{id}/pain001", h.getBatchFile).Methods("GET").Name("GetCreditTransferBatchFile")
+	r.HandleFunc("/v2/reconciliation_runs", h.importStatement).Methods("POST").Name("ImportBankStatement")
+	r.HandleFunc("/v2/reconciliation_runs", h.listRuns).Methods("GET").Name("ListReconciliationRuns")
+	r.HandleFunc("/v2/reconciliation_runs/{id}", h.getRun).Methods("GET").Name("GetReconciliationRun")
+	r.HandleFunc("/v2/reconciliation_runs/{id}/lines/{line}/resolve", h.resolveLine).Methods("POST").Name("ResolveReconciliationLine")
+	r.HandleFunc("/v2/settlement_batches", h.importSettlement).Methods("POST").Name("ImportSettlementReport")
+	r.HandleFunc("/v2/settlement_batches", h.listSettlements).Methods("GET").Name("ListSettlementBatches")
+	r.HandleFunc("/v2/settlement_batches/{id}", h.getSettlement).Methods("GET").Name("GetSettlementBatch")
+	r.HandleFunc("/v2/settlement_batches/{id}/report", h.getSettlementReport).Methods("GET").Name("GetSettlementReport")
+	r.HandleFunc("/v2/ledger_postings", h.listLedger).Methods("GET").Name("ListLedgerPostings")
+	r.HandleFunc("/v2/fx_rates", h.importRates).Methods("POST").Name("ImportFXRates")
+	r.HandleFunc("/v2/fx_rates", h.listRates).Methods("GET").Name("ListFXRates")
+	r.HandleFunc("/v2/fx_quotes", h.createQuote).Methods("POST").Name("CreateFXQuote")
+	r.HandleFunc("/v2/payments/{id}/fee", h.getFee).Methods("GET").Name("GetPaymentFee")
+	r.HandleFunc("/v2/pricing_plans", h.createPlan).Methods("POST").Name("CreatePricingPlan")
+	r.HandleFunc("/v2/pricing_plans", h.listPlans).Methods("GET").Name("ListPricingPlans")
+	r.HandleFunc("/v2/pricing_plans/{id}", h.getPlan).Methods("GET").Name("GetPricingPlan")
+	r.HandleFunc("/v2/pricing_plans/{id}", h.updatePlan).Methods("PUT").Name("UpdatePricingPlan")
+	r.HandleFunc("/v2/pricing_plan", h.getTenantPlan).Methods("GET").Name("GetTenantPricingPlan")
+	r.HandleFunc("/v2/pricing_plan", h.assignPlan).Methods("PUT").Name("AssignPricingPlan")
+	r.HandleFunc("/v2/fees/simulate", h.simulateFee).Methods("POST").Name("SimulateFee")
+	r.HandleFunc("/v2/fee_statements/{month}", h.getFeeStatement).Methods("GET").Name("GetFeeStatement")
+	r.HandleFunc("/v2/subscription_plans", h.createSubscriptionPlan).Methods("POST").Name("CreateSubscriptionPlan")
+	r.HandleFunc("/v2/subscription_plans", h.listSubscriptionPlans).Methods("GET").Name("ListSubscriptionPlans")
+	r.HandleFunc("/v2/subscription_plans/{id}", h.getSubscriptionPlan).Methods("GET").Name("GetSubscriptionPlan")
+	r.HandleFunc("/v2/subscriptions", h.createSubscription).Methods("POST").Name("CreateSubscription")
+	r.HandleFunc("/v2/subscriptions", h.listSubscriptions).Methods("GET").Name("ListSubscriptions")
+	r.HandleFunc("/v2/subscriptions/{id}", h.getSubscription).Methods("GET").Name("GetSubscription")
+	r.HandleFunc("/v2/subscriptions/{id}/plan", h.changeSubscriptionPlan).Methods("PUT").Name("ChangeSubscriptionPlan")
+	r.HandleFunc("/v2/subscriptions/{id}/cancel", h.cancelSubscription).Methods("POST").Name("CancelSubscription")
+	r.HandleFunc("/v2/subscriptions/{id}/charges", h.listSubscriptionCharges).Methods("GET").Name("ListSubscriptionCharges")
+	r.HandleFunc("/v2/invoices", h.createInvoice).Methods("POST").Name("CreateInvoice")
+	r.HandleFunc("/v2/invoices", h.listInvoices).Methods("GET").Name("ListInvoices")
+	r.HandleFunc("/v2/invoices/{id}", h.getInvoice).Methods("GET").Name("GetInvoice")
+	r.HandleFunc("/v2/invoices/{id}", h.updateInvoice).Methods("PUT").Name("UpdateInvoice")
+	r.HandleFunc("/v2/invoices/{id}/finalize", h.finalizeInvoice).Methods("POST").Name("FinalizeInvoice")
+	r.HandleFunc("/v2/invoices/{id}/void", h.voidInvoice).Methods("POST").Name("VoidInvoice")
+	r.HandleFunc("/v2/invoices/{id}/payments", h.payInvoice).Methods("POST").Name("PayInvoice")
+	r.HandleFunc("/v2/invoices/{id}/document", h.getInvoiceDocument).Methods("GET").Name("RenderInvoice")
+	r.HandleFunc("/v2/customers", h.createCustomer).Methods("POST").Name("CreateCustomer")
+	r.HandleFunc("/v2/customers", h.listCustomers).Methods("GET").Name("ListCustomers")
+	r.HandleFunc("/v2/customers/{id}", h.getCustomer).Methods("GET").Name("GetCustomer")
+	r.HandleFunc("/v2/customers/{id}", h.updateCustomer).Methods("PUT").Name("UpdateCustomer")
+	r.HandleFunc("/v2/customers/{id}", h.deleteCustomer).Methods("DELETE").Name("DeleteCustomer")
+	r.HandleFunc("/v2/customers/{id}/erase", h.eraseCustomer).Methods("POST").Name("EraseCustomer")
+	r.HandleFunc("/v2/customers/{id}/payment_methods", h.attachPaymentMethod).Methods("POST").Name("AttachPaymentMethod")
+	r.HandleFunc("/v2/customers/{id}/payment_methods", h.listCustomerPaymentMethods).Methods("GET").Name("ListCustomerPaymentMethods")
+	r.HandleFunc("/v2/customers/{id}/payments", h.listCustomerPayments).Methods("GET").Name("ListCustomerPayments")
+	r.HandleFunc("/v2/customers/{id}/lifetime_value", h.getCustomerLifetimeValue).Methods("GET").Name("GetCustomerLifetimeValue")
+	r.HandleFunc("/v2/disputes", h.createDispute).Methods("POST").Name("CreateDispute")
+	r.HandleFunc("/v2/disputes", h.listDisputes).Methods("GET").Name("ListDisputes")
+	r.HandleFunc("/v2/disputes/{id}", h.getDispute).Methods("GET").Name("GetDispute")
+	r.HandleFunc("/v2/disputes/{id}/evidence", h.uploadDisputeEvidence).Methods("POST").Name("UploadDisputeEvidence")
+	r.HandleFunc("/v2/disputes/{id}/evidence/{evidence_id}", h.getDisputeEvidence).Methods("GET").Name("GetDisputeEvidence")
+	r.HandleFunc("/v2/disputes/{id}/submit", h.submitDispute).Methods("POST").Name("SubmitDispute")
+	r.HandleFunc("/v2/disputes/{id}/accept", h.acceptDispute).Methods("POST").Name("AcceptDispute")
+	r.HandleFunc("/v2/disputes/{id}/resolve", h.resolveDispute).Methods("POST").Name("ResolveDispute")
+}
+
+func (h *restV2) create(w http.ResponseWriter, r *http.Request) {
+	var payment CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&payment); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid payment: %v", err))
+		return
+	}
+
+	ctx := r.Context()
+	if key := r.Header.Get(idempotency.Header); key != "" {
+		md, _ := metadata.FromIncomingContext(ctx)
+		ctx = metadata.NewIncomingContext(ctx, metadata.Join(md, metadata.Pairs(idempotency.Metadata, key)))
+	}
+	res, err := h.svc.CreatePayment(ctx, &proto.CreatePaymentRequest{
+		Id:                  payment.ID,
+		Amount:              payment.Amount,
+		Currency:            payment.Currency,
+		CardNumber:          payment.CardNumber,
+		PaymentMethod:       payment.PaymentMethod,
+		SettlementCurrency:  payment.SettlementCurrency,
+		FxQuoteId:           payment.FXQuoteID,
+		Customer:            payment.Customer,
+		Metadata:            payment.Metadata,
+		Description:         payment.Description,
+		StatementDescriptor: payment.StatementDescriptor,
+	})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	created, err := h.fetch(r.Context(), payment.ID)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	created.ActionURL, created.Customer = res.GetActionUrl(), payment.Customer
+	if res.GetConversion() != nil {
+		c := paymentConversionModel(res.GetConversion())
+		created.Conversion = &c
+	}
+	if res.GetFee() != nil {
+		f := paymentFeeModel(res.GetFee())
+		created.Fee = &f
+	}
+	w.Header().Set("Location", paymentPath(created.ID))
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (h *restV2) get(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	payment, err := h.fetch(r.Context(), id)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, payment)
+}
+
+// patch applies a JSON Merge Patch to the payment. id, tenant_id and the
+// lifecycle and provider fields are read-only, and amount and currency
+// cannot be removed. Metadata keys are merged one by one, so a null key
+// removes just that key.
+func (h *restV2) patch(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != MergePatchContentType {
+		h.error(w, r, &runtime.HTTPStatusError{
+			HTTPStatus: http.StatusUnsupportedMediaType,
+			Err:        status.Errorf(codes.InvalidArgument, "PATCH requires Content-Type %s", MergePatchContentType),
+		})
+		return
+	}
+	var patch interface{}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid merge patch: %v", err))
+		return
+	}
+
+	current, err := h.fetch(r.Context(), id)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	var target interface{}
+	raw, _ := json.Marshal(current)
+	_ = json.Unmarshal(raw, &target)
+
+	merged, ok := mergePatch(target, patch).(map[string]interface{})
+	if !ok {
+		h.error(w, r, status.Error(codes.InvalidArgument, "merge patch must be a JSON object"))
+		return
+	}
+	for _, field := range []string{"amount", "currency"} {
+		if _, ok := merged[field]; !ok {
+			h.error(w, r, status.Errorf(codes.InvalidArgument, "%s cannot be removed", field))
+			return
+		}
+	}
+	var updated models.Payment
+	raw, _ = json.Marshal(merged)
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&updated); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid merge patch: %v", err))
+		return
+	}
+	readOnly := updated
+	readOnly.Amount, readOnly.Currency = current.Amount, current.Currency
+	readOnly.Metadata, readOnly.Description, readOnly.StatementDescriptor = current.Metadata, current.Description, current.StatementDescriptor
+	if !reflect.DeepEqual(readOnly, current) {
+		h.error(w, r, status.Error(codes.InvalidArgument, "only amount, currency, metadata, description and statement_descriptor can be changed"))
+		return
+	}
+
+	req := &proto.UpdatePaymentRequest{
+		Id:       id,
+		Amount:   updated.Amount,
+		Currency: updated.Currency,
+		Metadata: metadataChanges(current.Metadata, updated.Metadata),
+	}
+	if updated.Description != current.Description {
+		req.Description = &updated.Description
+	}
+	if updated.StatementDescriptor != current.StatementDescriptor {
+		req.StatementDescriptor = &updated.StatementDescriptor
+	}
+	_, err = h.svc.UpdatePayment(r.Context(), req)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	payment, err := h.fetch(r.Context(), id)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, payment)
+}
+
+func (h *restV2) delete(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	if _, err := h.svc.DeletePayment(r.Context(), &proto.DeletePaymentRequest{Id: id}); err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// list accepts the optional filters currency, amount, metadata[key] and
+// the full-text search query plus page and page_size, and answers with a
+// ListEnvelope.
+func (h *restV2) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := &proto.ListPaymentsRequest{Currency: query.Get("currency"), Query: query.Get("query")}
+	for name, values := range query {
+		if key, ok := strings.CutPrefix(name, "metadata["); ok && strings.HasSuffix(key, "]") {
+			if req.Metadata == nil {
+				req.Metadata = map[string]string{}
+			}
+			req.Metadata[strings.TrimSuffix(key, "]")] = values[0]
+		}
+	}
+	if s := query.Get("amount"); s != "" {
+		amount, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid amount %q", s))
+			return
+		}
+		req.Amount = &amount
+	}
+	page, err := queryInt(query, "page", 1)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	pageSize, err := queryInt(query, "page_size", defaultPageSize)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	req.Page, req.PageSize = int32(page), int32(pageSize)
+
+	res, err := h.svc.ListPayments(r.Context(), req)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	envelope := ListEnvelope{
+		Data:       make([]models.Payment, 0, len(res.GetPayments())),
+		Pagination: Pagination{Page: page, PageSize: pageSize},
+		Links:      Links{Self: pageLink(r.URL, page)},
+	}
+	for _, p := range res.GetPayments() {
+		envelope.Data = append(envelope.Data, paymentModel(p))
+	}
+	if len(envelope.Data) == pageSize {
+		envelope.Links.Next = pageLink(r.URL, page+1)
+	}
+	if page > 1 {
+		envelope.Links.Prev = pageLink(r.URL, page-1)
+	}
+	writeJSON(w, http.StatusOK, envelope)
+}
+
+func (h *restV2) fetch(ctx context.Context, id int64) (models.Payment, error) {
+	res, err := h.svc.GetPayment(ctx, &proto.GetPaymentRequest{Id: id})
+	if err != nil {
+		return models.Payment{}, err
+	}
+	return models.Payment{
+		ID:                  res.GetId(),
+		Amount:              res.GetAmount(),
+		Currency:            res.GetCurrency(),
+		TenantID:            res.GetTenantId(),
+		Status:              res.GetStatus(),
+		RefundedAmount:      res.GetRefundedAmount(),
+		Provider:            res.GetProvider(),
+		ProviderRef:         res.GetProviderRef(),
+		ProviderStatus:      res.GetProviderStatus(),
+		PaymentMethod:       res.GetPaymentMethod(),
+		Metadata:            res.GetMetadata(),
+		Description:         res.GetDescription(),
+		StatementDescriptor: res.GetStatementDescriptor(),
+	}, nil
+}
+
+func (h *restV2) error(w http.ResponseWriter, r *http.Request, err error) {
+	ctx := runtime.NewServerMetadataContext(r.Context(), runtime.ServerMetadata{})
+	_, marshaler := runtime.MarshalerForRequest(h.gateway, r)
+	errorHandler(ctx, h.gateway, marshaler, w, r, err)
+}
+
+func pathID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid payment id %q", mux.Vars(r)["id"])
+	}
+	return id, nil
+}
+
+func queryInt(query url.Values, name string, fallback int) (int, error) {
+	s := query.Get(name)
+	if s == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, status.Errorf(codes.InvalidArgument, "%s must be a positive integer", name)
+	}
+	return n, nil
+}
+
+func paymentPath(id int64) string {
+	return fmt.Sprintf("/v2/payments/%d", id)
+}
+
+// metadataChanges returns what UpdatePayment has to merge into current to
+// get updated: the keys added or changed, and the keys removed with an
+// empty value.
+func metadataChanges(current, updated map[string]string) map[string]string {
+	changes := map[string]string{}
+	for k := range current {
+		if _, ok := updated[k]; !ok {
+			changes[k] = ""
+		}
+	}
+	for k, v := range updated {
+		if current[k] != v {
+			changes[k] = v
+		}
+	}
+	return changes
+}
+
+func pageLink(u *url.URL, page int) string {
+	query := u.Query()
+	query.Set("page", strconv.Itoa(page))
+	return u.Path + "?" + query.Encode()
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/validation"
+	"go-lang-final/proto"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BankAccountRequest is the body of POST /v2/bank_accounts. The IBAN is
+// checked against its country's format and check digits; responses only
+// ever show its last four characters.
+type BankAccountRequest struct {
+	HolderName string `json:"holder_name"`
+	IBAN       string `json:"iban"`
+	BIC        string `json:"bic,omitempty"`
+}
+
+// PayoutRequest is the body of POST /v2/payouts. Payouts are in EUR.
+type PayoutRequest struct {
+	BankAccount string  `json:"bank_account"`
+	Amount      float64 `json:"amount"`
+	Currency    string  `json:"currency"`
+	Remittance  string  `json:"remittance,omitempty"`
+}
+
+// BatchRequest is the body of POST /v2/credit_transfer_batches. An empty
+// execution date means today.
+type BatchRequest struct {
+	ExecutionDate string `json:"execution_date,omitempty"`
+}
+
+// StatusReportResult is the response to an imported pain.002 report.
+type StatusReportResult struct {
+	BatchID     string `json:"batch_id"`
+	GroupStatus string `json:"group_status,omitempty"`
+	Updated     int    `json:"updated"`
+}
+
+func (h *restV2) createBankAccount(w http.ResponseWriter, r *http.Request) {
+	var req BankAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid bank account: %v", err))
+		return
+	}
+
+	res, err := h.svc.CreateBankAccount(r.Context(), &proto.CreateBankAccountRequest{
+		HolderName: req.HolderName,
+		Iban:       req.IBAN,
+		Bic:        req.BIC,
+	})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/v2/bank_accounts/"+res.GetToken())
+	writeJSON(w, http.StatusCreated, bankAccountModel(res))
+}
+
+func (h *restV2) getBankAccount(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetBankAccount(r.Context(), &proto.GetBankAccountRequest{Token: mux.Vars(r)["token"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, bankAccountModel(res))
+}
+
+func (h *restV2) createPayout(w http.ResponseWriter, r *http.Request) {
+	var req PayoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid payout: %v", err))
+		return
+	}
+
+	res, err := h.svc.CreatePayout(r.Context(), &proto.CreatePayoutRequest{
+		BankAccount: req.BankAccount,
+		Amount:      req.Amount,
+		Currency:    req.Currency,
+		Remittance:  req.Remittance,
+	})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/v2/credit_transfers/"+res.GetId())
+	writeJSON(w, http.StatusCreated, creditTransferModel(res))
+}
+
+func (h *restV2) getCreditTransfer(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetCreditTransfer(r.Context(), &proto.GetCreditTransferRequest{Id: mux.Vars(r)["id"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, creditTransferModel(res))
+}
+
+func (h *restV2) createBatch(w http.ResponseWriter, r *http.Request) {
+	var req BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid batch: %v", err))
+		return
+	}
+
+	res, err := h.svc.CreateCreditTransferBatch(r.Context(), &proto.CreateCreditTransferBatchRequest{ExecutionDate: req.ExecutionDate})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/v2/credit_transfer_batches/"+res.GetId())
+	writeJSON(w, http.StatusCreated, batchModel(res))
+}
+
+func (h *restV2) getBatch(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetCreditTransferBatch(r.Context(), &proto.GetCreditTransferBatchRequest{Id: mux.Vars(r)["id"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, batchModel(res))
+}
+
+// getBatchFile serves the pain.001 file of a batch for upload to the bank.
+func (h *restV2) getBatchFile(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetCreditTransferBatch(r.Context(), &proto.GetCreditTransferBatchRequest{Id: mux.Vars(r)["id"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+res.GetId()+`.xml"`)
+	_, _ = w.Write(res.GetDocument())
+}
+
+// importStatusReport takes a pain.002 document as the raw request body.
+func (h *restV2) importStatusReport(w http.ResponseWriter, r *http.Request) {
+	document, err := io.ReadAll(http.MaxBytesReader(w, r.Body, validation.MaxStatusReportSize))
+	if err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid status report: %v", err))
+		return
+	}
+
+	res, err := h.svc.ImportPaymentStatusReport(r.Context(), &proto.ImportPaymentStatusReportRequest{Document: document})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, StatusReportResult{
+		BatchID:     res.GetBatchId(),
+		GroupStatus: res.GetGroupStatus(),
+		Updated:     int(res.GetUpdated()),
+	})
+}
+
+func bankAccountModel(a *proto.BankAccount) models.BankAccount {
+	return models.BankAccount{
+		Token:      a.GetToken(),
+		HolderName: a.GetHolderName(),
+		Country:    a.GetCountry(),
+		Last4:      a.GetLast4(),
+		BIC:        a.GetBic(),
+		TenantID:   a.GetTenantId(),
+		CreatedAt:  a.GetCreatedAt().AsTime(),
+	}
+}
+
+func creditTransferModel(t *proto.CreditTransfer) models.CreditTransfer {
+	return models.CreditTransfer{
+		ID:          t.GetId(),
+		BankAccount: t.GetBankAccount(),
+		PaymentID:   t.GetPaymentId(),
+		Kind:        t.GetKind(),
+		Amount:      t.GetAmount(),
+		Currency:    t.GetCurrency(),
+		Remittance:  t.GetRemittance(),
+		Status:      t.GetStatus(),
+		Reason:      t.GetReason(),
+		BatchID:     t.GetBatchId(),
+		TenantID:    t.GetTenantId(),
+		CreatedAt:   t.GetCreatedAt().AsTime(),
+	}
+}
+
+func batchModel(b *proto.CreditTransferBatch) models.CreditTransferBatch {
+	return models.CreditTransferBatch{
+		ID:            b.GetId(),
+		Transfers:     int(b.GetTransfers()),
+		Total:         b.GetTotal(),
+		ExecutionDate: b.GetExecutionDate(),
+		BankStatus:    b.GetBankStatus(),
+		TenantID:      b.GetTenantId(),
+		CreatedAt:     b.GetCreatedAt().AsTime(),
+	}
+}
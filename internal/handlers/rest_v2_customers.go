@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go-lang-final/internal/models"
+	"go-lang-final/proto"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CustomerListEnvelope is the paginated response of GET /v2/customers.
+type CustomerListEnvelope struct {
+	Data       []models.Customer `json:"data"`
+	Pagination Pagination        `json:"pagination"`
+	Links      Links             `json:"links"`
+}
+
+// AttachPaymentMethodRequest is the body of POST
+// /v2/customers/{id}/payment_methods. Token is a card or bank account
+// token.
+type AttachPaymentMethodRequest struct {
+	Token string `json:"token"`
+}
+
+func (h *restV2) createCustomer(w http.ResponseWriter, r *http.Request) {
+	var c models.Customer
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid customer: %v", err))
+		return
+	}
+
+	res, err := h.svc.CreateCustomer(r.Context(), customerRequest(c))
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/v2/customers/"+res.GetId())
+	writeJSON(w, http.StatusCreated, customerResponse(res))
+}
+
+func (h *restV2) getCustomer(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetCustomer(r.Context(), &proto.GetCustomerRequest{Id: mux.Vars(r)["id"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, customerResponse(res))
+}
+
+// listCustomers accepts the optional filters email and external_ref plus
+// page and page_size.
+func (h *restV2) listCustomers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := queryInt(query, "page", 1)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	pageSize, err := queryInt(query, "page_size", defaultPageSize)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	res, err := h.svc.ListCustomers(r.Context(), &proto.ListCustomersRequest{
+		Page:        int32(page),
+		PageSize:    int32(pageSize),
+		Email:       query.Get("email"),
+		ExternalRef: query.Get("external_ref"),
+	})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	envelope := CustomerListEnvelope{
+		Data:       make([]models.Customer, 0, len(res.GetCustomers())),
+		Pagination: Pagination{Page: page, PageSize: pageSize},
+		Links:      Links{Self: pageLink(r.URL, page)},
+	}
+	for _, c := range res.GetCustomers() {
+		envelope.Data = append(envelope.Data, customerResponse(c))
+	}
+	if len(envelope.Data) == pageSize {
+		envelope.Links.Next = pageLink(r.URL, page+1)
+	}
+	if page > 1 {
+		envelope.Links.Prev = pageLink(r.URL, page-1)
+	}
+	writeJSON(w, http.StatusOK, envelope)
+}
+
+func (h *restV2) updateCustomer(w http.ResponseWriter, r *http.Request) {
+	var c models.Customer
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid customer: %v", err))
+		return
+	}
+	c.ID = mux.Vars(r)["id"]
+
+	res, err := h.svc.UpdateCustomer(r.Context(), customerRequest(c))
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, customerResponse(res))
+}
+
+func (h *restV2) deleteCustomer(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.svc.DeleteCustomer(r.Context(), &proto.DeleteCustomerRequest{Id: mux.Vars(r)["id"]}); err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *restV2) eraseCustomer(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.EraseCustomer(r.Context(), &proto.EraseCustomerRequest{Id: mux.Vars(r)["id"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, customerResponse(res))
+}
+
+func (h *restV2) attachPaymentMethod(w http.ResponseWriter, r *http.Request) {
+	var req AttachPaymentMethodRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid payment method: %v", err))
+		return
+	}
+
+	res, err := h.svc.AttachPaymentMethod(r.Context(), &proto.AttachPaymentMethodRequest{Id: mux.Vars(r)["id"], Token: req.Token})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, customerPaymentMethodsModel(res))
+}
+
+func (h *restV2) listCustomerPaymentMethods(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.ListCustomerPaymentMethods(r.Context(), &proto.ListCustomerPaymentMethodsRequest{Id: mux.Vars(r)["id"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, customerPaymentMethodsModel(res))
+}
+
+// listCustomerPayments answers with the payments of a customer, newest
+// first, in a ListEnvelope.
+func (h *restV2) listCustomerPayments(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := queryInt(query, "page", 1)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	pageSize, err := queryInt(query, "page_size", defaultPageSize)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	res, err := h.svc.ListCustomerPayments(r.Context(), &proto.ListCustomerPaymentsRequest{
+		Id:       mux.Vars(r)["id"],
+		Page:     int32(page),
+		PageSize: int32(pageSize),
+	})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	envelope := ListEnvelope{
+		Data:       make([]models.Payment, 0, len(res.GetPayments())),
+		Pagination: Pagination{Page: page, PageSize: pageSize},
+		Links:      Links{Self: pageLink(r.URL, page)},
+	}
+	for _, p := range res.GetPayments() {
+		envelope.Data = append(envelope.Data, paymentModel(p))
+	}
+	if len(envelope.Data) == pageSize {
+		envelope.Links.Next = pageLink(r.URL, page+1)
+	}
+	if page > 1 {
+		envelope.Links.Prev = pageLink(r.URL, page-1)
+	}
+	writeJSON(w, http.StatusOK, envelope)
+}
+
+func (h *restV2) getCustomerLifetimeValue(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetCustomerLifetimeValue(r.Context(), &proto.GetCustomerLifetimeValueRequest{Id: mux.Vars(r)["id"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	ltv := models.CustomerLifetimeValue{
+		CustomerID: res.GetCustomerId(),
+		Values:     make([]models.CustomerValue, 0, len(res.GetValues())),
+	}
+	for _, v := range res.GetValues() {
+		ltv.Values = append(ltv.Values, models.CustomerValue{
+			Currency:       v.GetCurrency(),
+			Payments:       int(v.GetPayments()),
+			Gross:          v.GetGross(),
+			Refunded:       v.GetRefunded(),
+			Net:            v.GetNet(),
+			FirstPaymentAt: optionalTime(v.GetFirstPaymentAt()),
+			LastPaymentAt:  optionalTime(v.GetLastPaymentAt()),
+		})
+	}
+	writeJSON(w, http.StatusOK, ltv)
+}
+
+func customerRequest(c models.Customer) *proto.Customer {
+	return &proto.Customer{
+		Id:          c.ID,
+		Email:       c.Email,
+		Name:        c.Name,
+		ExternalRef: c.ExternalRef,
+		Metadata:    c.Metadata,
+	}
+}
+
+func customerResponse(res *proto.Customer) models.Customer {
+	return models.Customer{
+		ID:          res.GetId(),
+		Email:       res.GetEmail(),
+		Name:        res.GetName(),
+		ExternalRef: res.GetExternalRef(),
+		Metadata:    res.GetMetadata(),
+		ErasedAt:    optionalTime(res.GetErasedAt()),
+		CreatedAt:   res.GetCreatedAt().AsTime(),
+		UpdatedAt:   res.GetUpdatedAt().AsTime(),
+	}
+}
+
+func customerPaymentMethodsModel(res *proto.CustomerPaymentMethods) models.CustomerPaymentMethods {
+	methods := models.CustomerPaymentMethods{
+		Cards:        make([]models.PaymentMethod, 0, len(res.GetCards())),
+		BankAccounts: make([]models.BankAccount, 0, len(res.GetBankAccounts())),
+	}
+	for _, card := range res.GetCards() {
+		methods.Cards = append(methods.Cards, paymentMethodModel(card))
+	}
+	for _, account := range res.GetBankAccounts() {
+		methods.BankAccounts = append(methods.BankAccounts, bankAccountModel(account))
+	}
+	return methods
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"go-lang-final/internal/disputes"
+	"go-lang-final/internal/models"
+	"go-lang-final/proto"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// CreateDisputeRequest is the body of POST /v2/disputes. evidence_due_by
+// defaults to seven days from now.
+type CreateDisputeRequest struct {
+	PaymentID     int64      `json:"payment_id"`
+	Reason        string     `json:"reason"`
+	Amount        float64    `json:"amount"`
+	EvidenceDueBy *time.Time `json:"evidence_due_by,omitempty"`
+}
+
+// ResolveDisputeRequest is the body of POST /v2/disputes/{id}/resolve.
+type ResolveDisputeRequest struct {
+	Status  string `json:"status"`
+	Outcome string `json:"outcome,omitempty"`
+}
+
+// DisputeListEnvelope is the paginated response of GET /v2/disputes.
+type DisputeListEnvelope struct {
+	Data       []models.Dispute `json:"data"`
+	Pagination Pagination       `json:"pagination"`
+	Links      Links            `json:"links"`
+}
+
+func (h *restV2) createDispute(w http.ResponseWriter, r *http.Request) {
+	var body CreateDisputeRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid dispute: %v", err))
+		return
+	}
+	req := &proto.CreateDisputeRequest{PaymentId: body.PaymentID, Reason: body.Reason, Amount: body.Amount}
+	if body.EvidenceDueBy != nil {
+		req.EvidenceDueBy = timestamppb.New(*body.EvidenceDueBy)
+	}
+
+	res, err := h.svc.CreateDispute(r.Context(), req)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/v2/disputes/"+res.GetId())
+	writeJSON(w, http.StatusCreated, disputeResponse(res))
+}
+
+func (h *restV2) getDispute(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetDispute(r.Context(), &proto.GetDisputeRequest{Id: mux.Vars(r)["id"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, disputeResponse(res))
+}
+
+func (h *restV2) listDisputes(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := queryInt(query, "page", 1)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	pageSize, err := queryInt(query, "page_size", defaultPageSize)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	paymentID, err := queryInt(query, "payment_id", 0)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	res, err := h.svc.ListDisputes(r.Context(), &proto.ListDisputesRequest{
+		Page:      int32(page),
+		PageSize:  int32(pageSize),
+		Status:    query.Get("status"),
+		PaymentId: int64(paymentID),
+	})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	envelope := DisputeListEnvelope{
+		Data:       make([]models.Dispute, 0, len(res.GetDisputes())),
+		Pagination: Pagination{Page: page, PageSize: pageSize},
+		Links:      Links{Self: pageLink(r.URL, page)},
+	}
+	for _, d := range res.GetDisputes() {
+		envelope.Data = append(envelope.Data, disputeResponse(d))
+	}
+	if len(envelope.Data) == pageSize {
+		envelope.Links.Next = pageLink(r.URL, page+1)
+	}
+	if page > 1 {
+		envelope.Links.Prev = pageLink(r.URL, page-1)
+	}
+	writeJSON(w, http.StatusOK, envelope)
+}
+
+// uploadDisputeEvidence takes the file as the raw request body, typed by
+// its Content-Type header; the type and filename query parameters
+// describe it.
+func (h *restV2) uploadDisputeEvidence(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := &proto.UploadDisputeEvidenceRequest{
+		DisputeId:   mux.Vars(r)["id"],
+		Type:        query.Get("type"),
+		Filename:    query.Get("filename"),
+		ContentType: r.Header.Get("Content-Type"),
+	}
+	var err error
+	if req.Document, err = io.ReadAll(http.MaxBytesReader(w, r.Body, disputes.MaxEvidenceSize)); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid dispute evidence: %v", err))
+		return
+	}
+
+	res, err := h.svc.UploadDisputeEvidence(r.Context(), req)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/v2/disputes/"+res.GetDisputeId()+"/evidence/"+res.GetId())
+	writeJSON(w, http.StatusCreated, disputeEvidenceResponse(res))
+}
+
+// getDisputeEvidence serves an evidence file as an attachment.
+func (h *restV2) getDisputeEvidence(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	res, err := h.svc.GetDisputeEvidence(r.Context(), &proto.GetDisputeEvidenceRequest{DisputeId: vars["id"], Id: vars["evidence_id"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", res.GetContentType())
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": res.GetFilename()}))
+	_, _ = w.Write(res.GetDocument())
+}
+
+func (h *restV2) submitDispute(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.SubmitDispute(r.Context(), &proto.SubmitDisputeRequest{Id: mux.Vars(r)["id"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, disputeResponse(res))
+}
+
+func (h *restV2) acceptDispute(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.AcceptDispute(r.Context(), &proto.AcceptDisputeRequest{Id: mux.Vars(r)["id"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, disputeResponse(res))
+}
+
+func (h *restV2) resolveDispute(w http.ResponseWriter, r *http.Request) {
+	var body ResolveDisputeRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid dispute resolution: %v", err))
+		return
+	}
+
+	res, err := h.svc.ResolveDispute(r.Context(), &proto.ResolveDisputeRequest{Id: mux.Vars(r)["id"], Status: body.Status, Outcome: body.Outcome})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, disputeResponse(res))
+}
+
+func disputeResponse(res *proto.Dispute) models.Dispute {
+	d := models.Dispute{
+		ID:            res.GetId(),
+		PaymentID:     res.GetPaymentId(),
+		Reason:        res.GetReason(),
+		Amount:        res.GetAmount(),
+		Currency:      res.GetCurrency(),
+		Status:        res.GetStatus(),
+		EvidenceDueBy: res.GetEvidenceDueBy().AsTime(),
+		Provider:      res.GetProvider(),
+		ProviderRef:   res.GetProviderRef(),
+		Outcome:       res.GetOutcome(),
+		SubmittedAt:   optionalTime(res.GetSubmittedAt()),
+		ClosedAt:      optionalTime(res.GetClosedAt()),
+		CreatedAt:     res.GetCreatedAt().AsTime(),
+		UpdatedAt:     res.GetUpdatedAt().AsTime(),
+	}
+	for _, e := range res.GetEvidence() {
+		d.Evidence = append(d.Evidence, disputeEvidenceResponse(e))
+	}
+	return d
+}
+
+func disputeEvidenceResponse(res *proto.DisputeEvidence) models.DisputeEvidence {
+	return models.DisputeEvidence{
+		ID:          res.GetId(),
+		DisputeID:   res.GetDisputeId(),
+		Type:        res.GetType(),
+		Filename:    res.GetFilename(),
+		ContentType: res.GetContentType(),
+		Size:        res.GetSize(),
+		SHA256:      res.GetSha256(),
+		CreatedAt:   res.GetCreatedAt().AsTime(),
+	}
+}
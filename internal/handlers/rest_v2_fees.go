@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go-lang-final/internal/models"
+	"go-lang-final/proto"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PricingPlanListEnvelope is the paginated response of GET
+// /v2/pricing_plans.
+type PricingPlanListEnvelope struct {
+	Data       []models.PricingPlan `json:"data"`
+	Pagination Pagination           `json:"pagination"`
+	Links      Links                `json:"links"`
+}
+
+// AssignPricingPlanRequest is the body of PUT /v2/pricing_plan. An empty
+// plan_id takes the tenant off its plan.
+type AssignPricingPlanRequest struct {
+	PlanID string `json:"plan_id"`
+}
+
+// SimulateFeeRequest is the body of POST /v2/fees/simulate. method defaults
+// to card and volume to the tenant's volume of the month so far.
+type SimulateFeeRequest struct {
+	Amount   float64  `json:"amount"`
+	Currency string   `json:"currency"`
+	Method   string   `json:"method,omitempty"`
+	Volume   *float64 `json:"volume,omitempty"`
+}
+
+func (h *restV2) createPlan(w http.ResponseWriter, r *http.Request) {
+	var plan models.PricingPlan
+	if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid pricing plan: %v", err))
+		return
+	}
+	plan.ID = ""
+
+	res, err := h.svc.CreatePricingPlan(r.Context(), pricingPlanProto(&plan))
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/v2/pricing_plans/"+res.GetId())
+	writeJSON(w, http.StatusCreated, pricingPlanModel(res))
+}
+
+func (h *restV2) updatePlan(w http.ResponseWriter, r *http.Request) {
+	var plan models.PricingPlan
+	if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid pricing plan: %v", err))
+		return
+	}
+	plan.ID = mux.Vars(r)["id"]
+
+	res, err := h.svc.UpdatePricingPlan(r.Context(), pricingPlanProto(&plan))
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pricingPlanModel(res))
+}
+
+func (h *restV2) getPlan(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetPricingPlan(r.Context(), &proto.GetPricingPlanRequest{Id: mux.Vars(r)["id"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pricingPlanModel(res))
+}
+
+func (h *restV2) listPlans(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := queryInt(query, "page", 1)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	pageSize, err := queryInt(query, "page_size", defaultPageSize)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	res, err := h.svc.ListPricingPlans(r.Context(), &proto.ListPricingPlansRequest{Page: int32(page), PageSize: int32(pageSize)})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	envelope := PricingPlanListEnvelope{
+		Data:       make([]models.PricingPlan, 0, len(res.GetPlans())),
+		Pagination: Pagination{Page: page, PageSize: pageSize},
+		Links:      Links{Self: pageLink(r.URL, page)},
+	}
+	for _, plan := range res.GetPlans() {
+		envelope.Data = append(envelope.Data, pricingPlanModel(plan))
+	}
+	if len(envelope.Data) == pageSize {
+		envelope.Links.Next = pageLink(r.URL, page+1)
+	}
+	if page > 1 {
+		envelope.Links.Prev = pageLink(r.URL, page-1)
+	}
+	writeJSON(w, http.StatusOK, envelope)
+}
+
+func (h *restV2) getTenantPlan(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetTenantPricingPlan(r.Context(), &proto.GetTenantPricingPlanRequest{})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pricingPlanModel(res))
+}
+
+func (h *restV2) assignPlan(w http.ResponseWriter, r *http.Request) {
+	var req AssignPricingPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid pricing plan: %v", err))
+		return
+	}
+
+	res, err := h.svc.AssignPricingPlan(r.Context(), &proto.AssignPricingPlanRequest{PlanId: req.PlanID})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	if res.GetId() == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, pricingPlanModel(res))
+}
+
+func (h *restV2) simulateFee(w http.ResponseWriter, r *http.Request) {
+	var req SimulateFeeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid fee simulation: %v", err))
+		return
+	}
+
+	res, err := h.svc.SimulateFee(r.Context(), &proto.SimulateFeeRequest{
+		Amount:   req.Amount,
+		Currency: req.Currency,
+		Method:   req.Method,
+		Volume:   req.Volume,
+	})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, paymentFeeModel(res))
+}
+
+func (h *restV2) getFee(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	res, err := h.svc.GetPaymentFee(r.Context(), &proto.GetPaymentFeeRequest{Id: id})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, paymentFeeModel(res))
+}
+
+func (h *restV2) getFeeStatement(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetFeeStatement(r.Context(), &proto.GetFeeStatementRequest{Month: mux.Vars(r)["month"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	statement := models.FeeStatement{
+		TenantID: res.GetTenantId(),
+		Month:    res.GetMonth(),
+		Totals:   make([]models.FeeStatementTotal, 0, len(res.GetTotals())),
+	}
+	for _, t := range res.GetTotals() {
+		statement.Totals = append(statement.Totals, models.FeeStatementTotal{
+			Currency: t.GetCurrency(),
+			Payments: t.GetPayments(),
+			Volume:   t.GetVolume(),
+			Fees:     t.GetFees(),
+		})
+	}
+	writeJSON(w, http.StatusOK, statement)
+}
+
+func paymentFeeModel(f *proto.PaymentFee) models.PaymentFee {
+	fee := models.PaymentFee{
+		PaymentID:     f.GetPaymentId(),
+		PlanID:        f.GetPlanId(),
+		Amount:        f.GetAmount(),
+		Currency:      f.GetCurrency(),
+		PaymentAmount: f.GetPaymentAmount(),
+		Method:        f.GetMethod(),
+		Percentage:    f.GetPercentage(),
+		Fixed:         f.GetFixed(),
+		Volume:        f.GetVolume(),
+	}
+	if f.GetCreatedAt() != nil {
+		createdAt := f.GetCreatedAt().AsTime()
+		fee.CreatedAt = &createdAt
+	}
+	return fee
+}
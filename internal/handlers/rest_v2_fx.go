@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+
+	"go-lang-final/internal/fx"
+	"go-lang-final/internal/models"
+	"go-lang-final/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// maxRateTableSize bounds the rate tables POST /v2/fx_rates accepts.
+const maxRateTableSize = 10 << 20
+
+// FXRatesRequest is the JSON body of POST /v2/fx_rates. The endpoint also
+// takes a CSV rate table with the header base,quote,rate,effective_at.
+type FXRatesRequest struct {
+	Rates []models.FXRate `json:"rates"`
+}
+
+// FXRatesResponse counts the rates POST /v2/fx_rates added.
+type FXRatesResponse struct {
+	Imported int `json:"imported"`
+}
+
+// FXRateListEnvelope is the paginated response of GET /v2/fx_rates.
+type FXRateListEnvelope struct {
+	Data       []models.FXRate `json:"data"`
+	Pagination Pagination      `json:"pagination"`
+	Links      Links           `json:"links"`
+}
+
+// FXQuoteRequest is the body of POST /v2/fx_quotes; amount is optional.
+type FXQuoteRequest struct {
+	Currency           string  `json:"currency"`
+	SettlementCurrency string  `json:"settlement_currency"`
+	Amount             float64 `json:"amount,omitempty"`
+}
+
+func (h *restV2) importRates(w http.ResponseWriter, r *http.Request) {
+	body := http.MaxBytesReader(w, r.Body, maxRateTableSize)
+	var rates []models.FXRate
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+		table, err := fx.ParseTable(body, "")
+		if err != nil {
+			h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid rate table: %v", err))
+			return
+		}
+		for _, rate := range table {
+			rates = append(rates, models.FXRate{Base: rate.Base, Quote: rate.Quote, Rate: rate.Rate, EffectiveAt: rate.EffectiveAt})
+		}
+	} else {
+		var req FXRatesRequest
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid fx rates: %v", err))
+			return
+		}
+		rates = req.Rates
+	}
+
+	req := &proto.ImportFXRatesRequest{}
+	for _, rate := range rates {
+		req.Rates = append(req.Rates, &proto.FXRate{
+			Base:        rate.Base,
+			Quote:       rate.Quote,
+			Rate:        rate.Rate,
+			EffectiveAt: timestamppb.New(rate.EffectiveAt),
+		})
+	}
+	res, err := h.svc.ImportFXRates(r.Context(), req)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, FXRatesResponse{Imported: int(res.GetImported())})
+}
+
+func (h *restV2) listRates(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := queryInt(query, "page", 1)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	pageSize, err := queryInt(query, "page_size", defaultPageSize)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	res, err := h.svc.ListFXRates(r.Context(), &proto.ListFXRatesRequest{
+		Base:     query.Get("base"),
+		Quote:    query.Get("quote"),
+		Page:     int32(page),
+		PageSize: int32(pageSize),
+	})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	envelope := FXRateListEnvelope{
+		Data:       make([]models.FXRate, 0, len(res.GetRates())),
+		Pagination: Pagination{Page: page, PageSize: pageSize},
+		Links:      Links{Self: pageLink(r.URL, page)},
+	}
+	for _, rate := range res.GetRates() {
+		envelope.Data = append(envelope.Data, models.FXRate{
+			Base:        rate.GetBase(),
+			Quote:       rate.GetQuote(),
+			Rate:        rate.GetRate(),
+			EffectiveAt: rate.GetEffectiveAt().AsTime(),
+			Source:      rate.GetSource(),
+		})
+	}
+	if len(envelope.Data) == pageSize {
+		envelope.Links.Next = pageLink(r.URL, page+1)
+	}
+	if page > 1 {
+		envelope.Links.Prev = pageLink(r.URL, page-1)
+	}
+	writeJSON(w, http.StatusOK, envelope)
+}
+
+func (h *restV2) createQuote(w http.ResponseWriter, r *http.Request) {
+	var req FXQuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid fx quote: %v", err))
+		return
+	}
+
+	res, err := h.svc.CreateFXQuote(r.Context(), &proto.CreateFXQuoteRequest{
+		Currency:           req.Currency,
+		SettlementCurrency: req.SettlementCurrency,
+		Amount:             req.Amount,
+	})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, models.FXQuote{
+		ID:                 res.GetId(),
+		Currency:           res.GetCurrency(),
+		SettlementCurrency: res.GetSettlementCurrency(),
+		Rate:               res.GetRate(),
+		RateEffectiveAt:    res.GetRateEffectiveAt().AsTime(),
+		Amount:             res.GetAmount(),
+		SettlementAmount:   res.GetSettlementAmount(),
+		ExpiresAt:          res.GetExpiresAt().AsTime(),
+		TenantID:           res.GetTenantId(),
+		CreatedAt:          res.GetCreatedAt().AsTime(),
+	})
+}
+
+func (h *restV2) getConversion(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	res, err := h.svc.GetPaymentConversion(r.Context(), &proto.GetPaymentConversionRequest{Id: id})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, paymentConversionModel(res))
+}
+
+func paymentConversionModel(c *proto.PaymentConversion) models.PaymentConversion {
+	return models.PaymentConversion{
+		PaymentID:          c.GetPaymentId(),
+		Amount:             c.GetAmount(),
+		Currency:           c.GetCurrency(),
+		SettlementAmount:   c.GetSettlementAmount(),
+		SettlementCurrency: c.GetSettlementCurrency(),
+		Rate:               c.GetRate(),
+		RateEffectiveAt:    c.GetRateEffectiveAt().AsTime(),
+		QuoteID:            c.GetQuoteId(),
+		CreatedAt:          c.GetCreatedAt().AsTime(),
+	}
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go-lang-final/internal/models"
+	"go-lang-final/proto"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// InvoiceListEnvelope is the paginated response of GET /v2/invoices.
+type InvoiceListEnvelope struct {
+	Data       []models.Invoice `json:"data"`
+	Pagination Pagination       `json:"pagination"`
+	Links      Links            `json:"links"`
+}
+
+// PayInvoiceRequest is the body of POST /v2/invoices/{id}/payments. A
+// zero amount applies as much as the invoice and the payment have left.
+type PayInvoiceRequest struct {
+	PaymentID int64   `json:"payment_id"`
+	Amount    float64 `json:"amount,omitempty"`
+}
+
+func (h *restV2) createInvoice(w http.ResponseWriter, r *http.Request) {
+	var inv models.Invoice
+	if err := json.NewDecoder(r.Body).Decode(&inv); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid invoice: %v", err))
+		return
+	}
+
+	res, err := h.svc.CreateInvoice(r.Context(), invoiceRequest(inv))
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/v2/invoices/"+res.GetId())
+	writeJSON(w, http.StatusCreated, invoiceResponse(res))
+}
+
+func (h *restV2) getInvoice(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetInvoice(r.Context(), &proto.GetInvoiceRequest{Id: mux.Vars(r)["id"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, invoiceResponse(res))
+}
+
+func (h *restV2) listInvoices(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := queryInt(query, "page", 1)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	pageSize, err := queryInt(query, "page_size", defaultPageSize)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	res, err := h.svc.ListInvoices(r.Context(), &proto.ListInvoicesRequest{
+		Page:     int32(page),
+		PageSize: int32(pageSize),
+		Status:   query.Get("status"),
+	})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	envelope := InvoiceListEnvelope{
+		Data:       make([]models.Invoice, 0, len(res.GetInvoices())),
+		Pagination: Pagination{Page: page, PageSize: pageSize},
+		Links:      Links{Self: pageLink(r.URL, page)},
+	}
+	for _, inv := range res.GetInvoices() {
+		envelope.Data = append(envelope.Data, invoiceResponse(inv))
+	}
+	if len(envelope.Data) == pageSize {
+		envelope.Links.Next = pageLink(r.URL, page+1)
+	}
+	if page > 1 {
+		envelope.Links.Prev = pageLink(r.URL, page-1)
+	}
+	writeJSON(w, http.StatusOK, envelope)
+}
+
+func (h *restV2) updateInvoice(w http.ResponseWriter, r *http.Request) {
+	var inv models.Invoice
+	if err := json.NewDecoder(r.Body).Decode(&inv); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid invoice: %v", err))
+		return
+	}
+	inv.ID = mux.Vars(r)["id"]
+
+	res, err := h.svc.UpdateInvoice(r.Context(), invoiceRequest(inv))
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, invoiceResponse(res))
+}
+
+func (h *restV2) finalizeInvoice(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.FinalizeInvoice(r.Context(), &proto.FinalizeInvoiceRequest{Id: mux.Vars(r)["id"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, invoiceResponse(res))
+}
+
+func (h *restV2) voidInvoice(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.VoidInvoice(r.Context(), &proto.VoidInvoiceRequest{Id: mux.Vars(r)["id"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, invoiceResponse(res))
+}
+
+func (h *restV2) payInvoice(w http.ResponseWriter, r *http.Request) {
+	var req PayInvoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid invoice payment: %v", err))
+		return
+	}
+
+	res, err := h.svc.PayInvoice(r.Context(), &proto.PayInvoiceRequest{Id: mux.Vars(r)["id"], PaymentId: req.PaymentID, Amount: req.Amount})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, invoiceResponse(res))
+}
+
+// getInvoiceDocument serves an invoice as HTML, or as PDF with
+// ?format=pdf.
+func (h *restV2) getInvoiceDocument(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	res, err := h.svc.RenderInvoice(r.Context(), &proto.RenderInvoiceRequest{Id: mux.Vars(r)["id"], Format: format})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", res.GetContentType())
+	if format == InvoiceFormatPDF {
+		w.Header().Set("Content-Disposition", `attachment; filename="`+mux.Vars(r)["id"]+`.pdf"`)
+	}
+	_, _ = w.Write(res.GetDocument())
+}
+
+func invoiceRequest(inv models.Invoice) *proto.Invoice {
+	req := &proto.Invoice{
+		Id:              inv.ID,
+		Currency:        inv.Currency,
+		CustomerName:    inv.CustomerName,
+		CustomerEmail:   inv.CustomerEmail,
+		CustomerAddress: inv.CustomerAddress,
+		Memo:            inv.Memo,
+		DueDate:         inv.DueDate,
+	}
+	for _, l := range inv.Lines {
+		req.Lines = append(req.Lines, &proto.InvoiceLine{
+			Description:  l.Description,
+			Quantity:     l.Quantity,
+			UnitAmount:   l.UnitAmount,
+			DiscountRate: l.DiscountRate,
+			TaxRate:      l.TaxRate,
+		})
+	}
+	return req
+}
+
+func invoiceResponse(res *proto.Invoice) models.Invoice {
+	inv := models.Invoice{
+		ID:              res.GetId(),
+		Number:          res.GetNumber(),
+		Status:          res.GetStatus(),
+		Currency:        res.GetCurrency(),
+		CustomerName:    res.GetCustomerName(),
+		CustomerEmail:   res.GetCustomerEmail(),
+		CustomerAddress: res.GetCustomerAddress(),
+		Memo:            res.GetMemo(),
+		Lines:           make([]models.InvoiceLine, 0, len(res.GetLines())),
+		Subtotal:        res.GetSubtotal(),
+		Discount:        res.GetDiscount(),
+		Tax:             res.GetTax(),
+		Total:           res.GetTotal(),
+		AmountPaid:      res.GetAmountPaid(),
+		AmountDue:       res.GetAmountDue(),
+		DueDate:         res.GetDueDate(),
+		IssuedAt:        optionalTime(res.GetIssuedAt()),
+		PaidAt:          optionalTime(res.GetPaidAt()),
+		VoidedAt:        optionalTime(res.GetVoidedAt()),
+		CreatedAt:       res.GetCreatedAt().AsTime(),
+		UpdatedAt:       res.GetUpdatedAt().AsTime(),
+	}
+	for _, l := range res.GetLines() {
+		inv.Lines = append(inv.Lines, models.InvoiceLine{
+			Description:  l.GetDescription(),
+			Quantity:     l.GetQuantity(),
+			UnitAmount:   l.GetUnitAmount(),
+			DiscountRate: l.GetDiscountRate(),
+			TaxRate:      l.GetTaxRate(),
+			Amount:       l.GetAmount(),
+			Discount:     l.GetDiscount(),
+			Tax:          l.GetTax(),
+		})
+	}
+	for _, p := range res.GetPayments() {
+		inv.Payments = append(inv.Payments, models.InvoicePayment{
+			PaymentID: p.GetPaymentId(),
+			Amount:    p.GetAmount(),
+			CreatedAt: p.GetCreatedAt().AsTime(),
+		})
+	}
+	return inv
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"go-lang-final/internal/models"
+	"go-lang-final/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxFeedLimit caps the limit parameter of GET /v2/events.
+const maxFeedLimit = 1000
+
+// RefundRequest is the body of POST /v2/payments/{id}/refund.
+type RefundRequest struct {
+	Amount float64 `json:"amount"`
+	Reason string  `json:"reason,omitempty"`
+}
+
+// CancelRequest is the body of POST /v2/payments/{id}/cancel.
+type CancelRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// EventList is the body of the v2 event endpoints.
+type EventList struct {
+	Data []models.PaymentEvent `json:"data"`
+}
+
+// EventSource is implemented by services that can page through the event
+// feed. WatchPayments is a stream, so REST clients poll GET /v2/events
+// instead.
+type EventSource interface {
+	EventsAfter(ctx context.Context, after int64, limit int) ([]*proto.PaymentEvent, error)
+}
+
+func (s *PaymentService) EventsAfter(ctx context.Context, after int64, limit int) ([]*proto.PaymentEvent, error) {
+	events, err := s.store.EventsAfter(ctx, after, limit)
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to list events: %v", err)
+	}
+
+	res := make([]*proto.PaymentEvent, 0, len(events))
+	for _, event := range events {
+		res = append(res, eventProto(event))
+	}
+	return res, nil
+}
+
+func (h *restV2) refund(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	var req RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid refund: %v", err))
+		return
+	}
+
+	res, err := h.svc.RefundPayment(r.Context(), &proto.RefundPaymentRequest{Id: id, Amount: req.Amount, Reason: req.Reason})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, paymentModel(res.GetPayment()))
+}
+
+func (h *restV2) cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	var req CancelRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid cancellation: %v", err))
+			return
+		}
+	}
+
+	res, err := h.svc.CancelPayment(r.Context(), &proto.CancelPaymentRequest{Id: id, Reason: req.Reason})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, paymentModel(res.GetPayment()))
+}
+
+func (h *restV2) events(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	res, err := h.svc.ListPaymentEvents(r.Context(), &proto.ListPaymentEventsRequest{Id: id})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, eventList(res.GetEvents()))
+}
+
+// feed returns up to limit events after the event id in after. Clients poll
+// it with the id of the last event they have seen.
+func (h *restV2) feed(w http.ResponseWriter, r *http.Request) {
+	source := h.feedSource
+	if source == nil {
+		h.error(w, r, status.Error(codes.Unimplemented, "event feed is not available"))
+		return
+	}
+	query := r.URL.Query()
+	var after int64
+	if s := query.Get("after"); s != "" {
+		var err error
+		if after, err = strconv.ParseInt(s, 10, 64); err != nil || after < 0 {
+			h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid after %q", s))
+			return
+		}
+	}
+	limit, err := queryInt(query, "limit", watchBatch)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	if limit > maxFeedLimit {
+		limit = maxFeedLimit
+	}
+
+	events, err := source.EventsAfter(r.Context(), after, limit)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, eventList(events))
+}
+
+func paymentModel(p *proto.Payment) models.Payment {
+	return models.Payment{
+		ID:                  p.GetId(),
+		Amount:              p.GetAmount(),
+		Currency:            p.GetCurrency(),
+		TenantID:            p.GetTenantId(),
+		Status:              p.GetStatus(),
+		RefundedAmount:      p.GetRefundedAmount(),
+		Provider:            p.GetProvider(),
+		ProviderRef:         p.GetProviderRef(),
+		ProviderStatus:      p.GetProviderStatus(),
+		PaymentMethod:       p.GetPaymentMethod(),
+		Customer:            p.GetCustomer(),
+		Metadata:            p.GetMetadata(),
+		Description:         p.GetDescription(),
+		StatementDescriptor: p.GetStatementDescriptor(),
+	}
+}
+
+func eventList(events []*proto.PaymentEvent) EventList {
+	list := EventList{Data: make([]models.PaymentEvent, 0, len(events))}
+	for _, e := range events {
+		list.Data = append(list.Data, models.PaymentEvent{
+			ID:        e.GetId(),
+			PaymentID: e.GetPaymentId(),
+			TenantID:  e.GetTenantId(),
+			Type:      e.GetType(),
+			Amount:    e.GetAmount(),
+			Currency:  e.GetCurrency(),
+			Status:    e.GetStatus(),
+			Reason:    e.GetReason(),
+			CreatedAt: e.GetCreatedAt().AsTime(),
+		})
+	}
+	return list
+}
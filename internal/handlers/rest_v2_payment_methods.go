@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go-lang-final/internal/models"
+	"go-lang-final/proto"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PaymentMethodRequest is the body of POST /v2/payment_methods. The card is
+// stored in the vault; responses only ever show its brand, last four digits
+// and expiry.
+type PaymentMethodRequest struct {
+	Number   string `json:"number"`
+	ExpMonth int32  `json:"exp_month"`
+	ExpYear  int32  `json:"exp_year"`
+}
+
+func (h *restV2) createPaymentMethod(w http.ResponseWriter, r *http.Request) {
+	var req PaymentMethodRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid payment method: %v", err))
+		return
+	}
+
+	res, err := h.svc.CreatePaymentMethod(r.Context(), &proto.CreatePaymentMethodRequest{
+		Number:   req.Number,
+		ExpMonth: req.ExpMonth,
+		ExpYear:  req.ExpYear,
+	})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/v2/payment_methods/"+res.GetToken())
+	writeJSON(w, http.StatusCreated, paymentMethodModel(res))
+}
+
+func (h *restV2) getPaymentMethod(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetPaymentMethod(r.Context(), &proto.GetPaymentMethodRequest{Token: mux.Vars(r)["token"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, paymentMethodModel(res))
+}
+
+func paymentMethodModel(m *proto.PaymentMethod) models.PaymentMethod {
+	return models.PaymentMethod{
+		Token:     m.GetToken(),
+		Brand:     m.GetBrand(),
+		Last4:     m.GetLast4(),
+		ExpMonth:  int(m.GetExpMonth()),
+		ExpYear:   int(m.GetExpYear()),
+		TenantID:  m.GetTenantId(),
+		CreatedAt: m.GetCreatedAt().AsTime(),
+	}
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/validation"
+	"go-lang-final/proto"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ResolveRequest is the body of POST
+// /v2/reconciliation_runs/{id}/lines/{line}/resolve. PaymentID names the
+// payment an accepted line settles when matching missed it.
+type ResolveRequest struct {
+	Resolution string `json:"resolution"`
+	PaymentID  int64  `json:"payment_id,omitempty"`
+	Note       string `json:"note,omitempty"`
+}
+
+// RunListEnvelope is the paginated response of GET /v2/reconciliation_runs.
+// Runs are listed without their lines.
+type RunListEnvelope struct {
+	Data       []models.ReconciliationRun `json:"data"`
+	Pagination Pagination                 `json:"pagination"`
+	Links      Links                      `json:"links"`
+}
+
+// importStatement takes a camt.053 or MT940 statement as the raw request
+// body; the query parameters tolerance and date_window_days override the
+// configured matching options.
+func (h *restV2) importStatement(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := &proto.ImportBankStatementRequest{}
+	if s := query.Get("tolerance"); s != "" {
+		tolerance, err := strconv.ParseFloat(s, 64)
+		if err != nil || tolerance < 0 {
+			h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid tolerance %q", s))
+			return
+		}
+		req.Tolerance = tolerance
+	}
+	window, err := queryInt(query, "date_window_days", 0)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	if window > validation.MaxDateWindow {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "date_window_days must be at most %d", validation.MaxDateWindow))
+		return
+	}
+	req.DateWindowDays = int32(window)
+
+	if req.Document, err = io.ReadAll(http.MaxBytesReader(w, r.Body, validation.MaxStatementSize)); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid bank statement: %v", err))
+		return
+	}
+
+	res, err := h.svc.ImportBankStatement(r.Context(), req)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/v2/reconciliation_runs/"+res.GetId())
+	writeJSON(w, http.StatusCreated, reconciliationRunModel(res))
+}
+
+// getRun returns a run with its lines; the status query parameter narrows
+// them down to one line status or, with exceptions, to those still to be
+// resolved.
+func (h *restV2) getRun(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetReconciliationRun(r.Context(), &proto.GetReconciliationRunRequest{
+		Id:     mux.Vars(r)["id"],
+		Status: r.URL.Query().Get("status"),
+	})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reconciliationRunModel(res))
+}
+
+func (h *restV2) listRuns(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := queryInt(query, "page", 1)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	pageSize, err := queryInt(query, "page_size", defaultPageSize)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	res, err := h.svc.ListReconciliationRuns(r.Context(), &proto.ListReconciliationRunsRequest{Page: int32(page), PageSize: int32(pageSize)})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	envelope := RunListEnvelope{
+		Data:       make([]models.ReconciliationRun, 0, len(res.GetRuns())),
+		Pagination: Pagination{Page: page, PageSize: pageSize},
+		Links:      Links{Self: pageLink(r.URL, page)},
+	}
+	for _, run := range res.GetRuns() {
+		envelope.Data = append(envelope.Data, reconciliationRunModel(run))
+	}
+	if len(envelope.Data) == pageSize {
+		envelope.Links.Next = pageLink(r.URL, page+1)
+	}
+	if page > 1 {
+		envelope.Links.Prev = pageLink(r.URL, page-1)
+	}
+	writeJSON(w, http.StatusOK, envelope)
+}
+
+func (h *restV2) resolveLine(w http.ResponseWriter, r *http.Request) {
+	line, err := strconv.Atoi(mux.Vars(r)["line"])
+	if err != nil || line < 1 {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid line %q", mux.Vars(r)["line"]))
+		return
+	}
+	var req ResolveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid resolution: %v", err))
+		return
+	}
+
+	res, err := h.svc.ResolveReconciliationLine(r.Context(), &proto.ResolveReconciliationLineRequest{
+		RunId:      mux.Vars(r)["id"],
+		Line:       int32(line),
+		Resolution: req.Resolution,
+		PaymentId:  req.PaymentID,
+		Note:       req.Note,
+	})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reconciliationLineModel(res))
+}
+
+func reconciliationRunModel(run *proto.ReconciliationRun) models.ReconciliationRun {
+	res := models.ReconciliationRun{
+		ID:               run.GetId(),
+		Format:           run.GetFormat(),
+		StatementID:      run.GetStatementId(),
+		Account:          run.GetAccount(),
+		Tolerance:        run.GetTolerance(),
+		DateWindowDays:   int(run.GetDateWindowDays()),
+		Lines:            int(run.GetLineCount()),
+		Matched:          int(run.GetMatched()),
+		Unmatched:        int(run.GetUnmatched()),
+		Duplicated:       int(run.GetDuplicated()),
+		AmountMismatches: int(run.GetAmountMismatches()),
+		TenantID:         run.GetTenantId(),
+		CreatedAt:        run.GetCreatedAt().AsTime(),
+	}
+	for _, line := range run.GetLines() {
+		res.Items = append(res.Items, reconciliationLineModel(line))
+	}
+	return res
+}
+
+func reconciliationLineModel(line *proto.ReconciliationLine) models.ReconciliationLine {
+	res := models.ReconciliationLine{
+		Line:           int(line.GetLine()),
+		BankRef:        line.GetBankRef(),
+		Reference:      line.GetReference(),
+		Description:    line.GetDescription(),
+		Direction:      line.GetDirection(),
+		Amount:         line.GetAmount(),
+		Currency:       line.GetCurrency(),
+		BookingDate:    line.GetBookingDate(),
+		Status:         line.GetStatus(),
+		PaymentID:      line.GetPaymentId(),
+		EventID:        line.GetEventId(),
+		Difference:     line.GetDifference(),
+		Resolution:     line.GetResolution(),
+		ResolutionNote: line.GetResolutionNote(),
+		ResolvedBy:     line.GetResolvedBy(),
+	}
+	if line.GetResolvedAt() != nil {
+		resolvedAt := line.GetResolvedAt().AsTime()
+		res.ResolvedAt = &resolvedAt
+	}
+	return res
+}
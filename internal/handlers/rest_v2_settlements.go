@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"io"
+	"net/http"
+	"strconv"
+
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/validation"
+	"go-lang-final/proto"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SettlementListEnvelope is the paginated response of GET
+// /v2/settlement_batches. Batches are listed without their lines.
+type SettlementListEnvelope struct {
+	Data       []models.SettlementBatch `json:"data"`
+	Pagination Pagination               `json:"pagination"`
+	Links      Links                    `json:"links"`
+}
+
+// LedgerListEnvelope is the paginated response of GET /v2/ledger_postings.
+type LedgerListEnvelope struct {
+	Data       []models.LedgerPosting `json:"data"`
+	Pagination Pagination             `json:"pagination"`
+	Links      Links                  `json:"links"`
+}
+
+// importSettlement takes a settlement file as the raw request body; the
+// query parameters provider and reference are required, settlement_date is
+// optional.
+func (h *restV2) importSettlement(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := &proto.ImportSettlementReportRequest{
+		Provider:       query.Get("provider"),
+		Reference:      query.Get("reference"),
+		SettlementDate: query.Get("settlement_date"),
+	}
+	if req.Provider == "" || req.Reference == "" {
+		h.error(w, r, status.Error(codes.InvalidArgument, "provider and reference are required"))
+		return
+	}
+	var err error
+	if req.Document, err = io.ReadAll(http.MaxBytesReader(w, r.Body, validation.MaxSettlementSize)); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid settlement report: %v", err))
+		return
+	}
+
+	res, err := h.svc.ImportSettlementReport(r.Context(), req)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/v2/settlement_batches/"+res.GetId())
+	writeJSON(w, http.StatusCreated, settlementBatchModel(res))
+}
+
+// getSettlement returns a batch with its lines; the status query parameter
+// narrows them down to one status or, with discrepancies, to every line
+// that is not matched.
+func (h *restV2) getSettlement(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetSettlementBatch(r.Context(), &proto.GetSettlementBatchRequest{
+		Id:     mux.Vars(r)["id"],
+		Status: r.URL.Query().Get("status"),
+	})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, settlementBatchModel(res))
+}
+
+// getSettlementReport serves the summary of a batch followed by its
+// discrepancies as CSV, for finance teams to work through in a spreadsheet.
+func (h *restV2) getSettlementReport(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetSettlementBatch(r.Context(), &proto.GetSettlementBatchRequest{Id: mux.Vars(r)["id"], Status: "discrepancies"})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	amount := func(f float64) string { return strconv.FormatFloat(f, 'f', 2, 64) }
+	count := func(n int32) string { return strconv.Itoa(int(n)) }
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+res.GetId()+`.csv"`)
+	out := csv.NewWriter(w)
+	_ = out.WriteAll([][]string{
+		{"provider", "reference", "settlement_date", "currency", "lines", "matched", "unexpected", "missing", "duplicated",
+			"amount_mismatches", "fee_deviations", "gross", "fees", "expected_fees", "net"},
+		{res.GetProvider(), res.GetReference(), res.GetSettlementDate(), res.GetCurrency(), count(res.GetLineCount()),
+			count(res.GetMatched()), count(res.GetUnexpected()), count(res.GetMissing()), count(res.GetDuplicated()),
+			count(res.GetAmountMismatches()), count(res.GetFeeDeviations()), amount(res.GetGross()), amount(res.GetFees()),
+			amount(res.GetExpectedFees()), amount(res.GetNet())},
+		{},
+		{"line", "status", "type", "reference", "payment_id", "gross", "fee", "expected_fee", "net"},
+	})
+	for _, l := range res.GetLines() {
+		var paymentID string
+		if l.GetPaymentId() != 0 {
+			paymentID = strconv.FormatInt(l.GetPaymentId(), 10)
+		}
+		_ = out.Write([]string{count(l.GetLine()), l.GetStatus(), l.GetType(), l.GetReference(), paymentID,
+			amount(l.GetGross()), amount(l.GetFee()), amount(l.GetExpectedFee()), amount(l.GetNet())})
+	}
+	out.Flush()
+}
+
+func (h *restV2) listSettlements(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := queryInt(query, "page", 1)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	pageSize, err := queryInt(query, "page_size", defaultPageSize)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	res, err := h.svc.ListSettlementBatches(r.Context(), &proto.ListSettlementBatchesRequest{
+		Provider: query.Get("provider"),
+		Page:     int32(page),
+		PageSize: int32(pageSize),
+	})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	envelope := SettlementListEnvelope{
+		Data:       make([]models.SettlementBatch, 0, len(res.GetBatches())),
+		Pagination: Pagination{Page: page, PageSize: pageSize},
+		Links:      Links{Self: pageLink(r.URL, page)},
+	}
+	for _, batch := range res.GetBatches() {
+		envelope.Data = append(envelope.Data, settlementBatchModel(batch))
+	}
+	if len(envelope.Data) == pageSize {
+		envelope.Links.Next = pageLink(r.URL, page+1)
+	}
+	if page > 1 {
+		envelope.Links.Prev = pageLink(r.URL, page-1)
+	}
+	writeJSON(w, http.StatusOK, envelope)
+}
+
+// listLedger accepts the optional filters payment_id and account plus page
+// and page_size.
+func (h *restV2) listLedger(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := &proto.ListLedgerPostingsRequest{Account: query.Get("account")}
+	if s := query.Get("payment_id"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || id < 1 {
+			h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid payment_id %q", s))
+			return
+		}
+		req.PaymentId = id
+	}
+	page, err := queryInt(query, "page", 1)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	pageSize, err := queryInt(query, "page_size", defaultPageSize)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	req.Page, req.PageSize = int32(page), int32(pageSize)
+
+	res, err := h.svc.ListLedgerPostings(r.Context(), req)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	envelope := LedgerListEnvelope{
+		Data:       make([]models.LedgerPosting, 0, len(res.GetPostings())),
+		Pagination: Pagination{Page: page, PageSize: pageSize},
+		Links:      Links{Self: pageLink(r.URL, page)},
+	}
+	for _, p := range res.GetPostings() {
+		envelope.Data = append(envelope.Data, models.LedgerPosting{
+			ID:          p.GetId(),
+			EntryID:     p.GetEntryId(),
+			Account:     p.GetAccount(),
+			Amount:      p.GetAmount(),
+			Currency:    p.GetCurrency(),
+			PaymentID:   p.GetPaymentId(),
+			Source:      p.GetSource(),
+			SourceID:    p.GetSourceId(),
+			Description: p.GetDescription(),
+			TenantID:    p.GetTenantId(),
+			CreatedAt:   p.GetCreatedAt().AsTime(),
+		})
+	}
+	if len(envelope.Data) == pageSize {
+		envelope.Links.Next = pageLink(r.URL, page+1)
+	}
+	if page > 1 {
+		envelope.Links.Prev = pageLink(r.URL, page-1)
+	}
+	writeJSON(w, http.StatusOK, envelope)
+}
+
+func settlementBatchModel(b *proto.SettlementBatch) models.SettlementBatch {
+	res := models.SettlementBatch{
+		ID:               b.GetId(),
+		Provider:         b.GetProvider(),
+		Reference:        b.GetReference(),
+		SettlementDate:   b.GetSettlementDate(),
+		Currency:         b.GetCurrency(),
+		Lines:            int(b.GetLineCount()),
+		Matched:          int(b.GetMatched()),
+		Unexpected:       int(b.GetUnexpected()),
+		Missing:          int(b.GetMissing()),
+		Duplicated:       int(b.GetDuplicated()),
+		AmountMismatches: int(b.GetAmountMismatches()),
+		FeeDeviations:    int(b.GetFeeDeviations()),
+		Gross:            b.GetGross(),
+		Fees:             b.GetFees(),
+		Net:              b.GetNet(),
+		ExpectedFees:     b.GetExpectedFees(),
+		TenantID:         b.GetTenantId(),
+		CreatedAt:        b.GetCreatedAt().AsTime(),
+	}
+	for _, l := range b.GetLines() {
+		res.Items = append(res.Items, models.SettlementLine{
+			Line:        int(l.GetLine()),
+			Type:        l.GetType(),
+			Reference:   l.GetReference(),
+			PaymentID:   l.GetPaymentId(),
+			Currency:    l.GetCurrency(),
+			Gross:       l.GetGross(),
+			Fee:         l.GetFee(),
+			Net:         l.GetNet(),
+			ExpectedFee: l.GetExpectedFee(),
+			Status:      l.GetStatus(),
+		})
+	}
+	return res
+}
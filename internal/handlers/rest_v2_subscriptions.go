@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go-lang-final/internal/models"
+	"go-lang-final/proto"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// SubscriptionPlanListEnvelope is the paginated response of GET
+// /v2/subscription_plans.
+type SubscriptionPlanListEnvelope struct {
+	Data       []models.SubscriptionPlan `json:"data"`
+	Pagination Pagination                `json:"pagination"`
+	Links      Links                     `json:"links"`
+}
+
+// SubscriptionListEnvelope is the paginated response of GET
+// /v2/subscriptions.
+type SubscriptionListEnvelope struct {
+	Data       []models.Subscription `json:"data"`
+	Pagination Pagination            `json:"pagination"`
+	Links      Links                 `json:"links"`
+}
+
+// SubscriptionChargeList is the response of GET
+// /v2/subscriptions/{id}/charges.
+type SubscriptionChargeList struct {
+	Data []models.SubscriptionCharge `json:"data"`
+}
+
+// CreateSubscriptionRequest is the body of POST /v2/subscriptions.
+type CreateSubscriptionRequest struct {
+	PlanID        string `json:"plan_id"`
+	PaymentMethod string `json:"payment_method,omitempty"`
+	Customer      string `json:"customer,omitempty"`
+}
+
+// ChangeSubscriptionPlanRequest is the body of PUT
+// /v2/subscriptions/{id}/plan.
+type ChangeSubscriptionPlanRequest struct {
+	PlanID string `json:"plan_id"`
+}
+
+// CancelSubscriptionRequest is the optional body of POST
+// /v2/subscriptions/{id}/cancel.
+type CancelSubscriptionRequest struct {
+	AtPeriodEnd bool `json:"at_period_end,omitempty"`
+}
+
+func (h *restV2) createSubscriptionPlan(w http.ResponseWriter, r *http.Request) {
+	var plan models.SubscriptionPlan
+	if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid subscription plan: %v", err))
+		return
+	}
+
+	res, err := h.svc.CreateSubscriptionPlan(r.Context(), &proto.SubscriptionPlan{
+		Name:          plan.Name,
+		Interval:      plan.Interval,
+		IntervalCount: int32(plan.IntervalCount),
+		Amount:        plan.Amount,
+		Currency:      plan.Currency,
+		TrialDays:     int32(plan.TrialDays),
+	})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/v2/subscription_plans/"+res.GetId())
+	writeJSON(w, http.StatusCreated, subscriptionPlanModel(res))
+}
+
+func (h *restV2) getSubscriptionPlan(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetSubscriptionPlan(r.Context(), &proto.GetSubscriptionPlanRequest{Id: mux.Vars(r)["id"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, subscriptionPlanModel(res))
+}
+
+func (h *restV2) listSubscriptionPlans(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := queryInt(query, "page", 1)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	pageSize, err := queryInt(query, "page_size", defaultPageSize)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	res, err := h.svc.ListSubscriptionPlans(r.Context(), &proto.ListSubscriptionPlansRequest{Page: int32(page), PageSize: int32(pageSize)})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	envelope := SubscriptionPlanListEnvelope{
+		Data:       make([]models.SubscriptionPlan, 0, len(res.GetPlans())),
+		Pagination: Pagination{Page: page, PageSize: pageSize},
+		Links:      Links{Self: pageLink(r.URL, page)},
+	}
+	for _, plan := range res.GetPlans() {
+		envelope.Data = append(envelope.Data, subscriptionPlanModel(plan))
+	}
+	if len(envelope.Data) == pageSize {
+		envelope.Links.Next = pageLink(r.URL, page+1)
+	}
+	if page > 1 {
+		envelope.Links.Prev = pageLink(r.URL, page-1)
+	}
+	writeJSON(w, http.StatusOK, envelope)
+}
+
+func (h *restV2) createSubscription(w http.ResponseWriter, r *http.Request) {
+	var req CreateSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid subscription: %v", err))
+		return
+	}
+
+	res, err := h.svc.CreateSubscription(r.Context(), &proto.CreateSubscriptionRequest{PlanId: req.PlanID, PaymentMethod: req.PaymentMethod, Customer: req.Customer})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/v2/subscriptions/"+res.GetId())
+	writeJSON(w, http.StatusCreated, subscriptionModel(res))
+}
+
+func (h *restV2) getSubscription(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetSubscription(r.Context(), &proto.GetSubscriptionRequest{Id: mux.Vars(r)["id"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, subscriptionModel(res))
+}
+
+func (h *restV2) listSubscriptions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := queryInt(query, "page", 1)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	pageSize, err := queryInt(query, "page_size", defaultPageSize)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	res, err := h.svc.ListSubscriptions(r.Context(), &proto.ListSubscriptionsRequest{
+		Page:     int32(page),
+		PageSize: int32(pageSize),
+		Status:   query.Get("status"),
+	})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	envelope := SubscriptionListEnvelope{
+		Data:       make([]models.Subscription, 0, len(res.GetSubscriptions())),
+		Pagination: Pagination{Page: page, PageSize: pageSize},
+		Links:      Links{Self: pageLink(r.URL, page)},
+	}
+	for _, sub := range res.GetSubscriptions() {
+		envelope.Data = append(envelope.Data, subscriptionModel(sub))
+	}
+	if len(envelope.Data) == pageSize {
+		envelope.Links.Next = pageLink(r.URL, page+1)
+	}
+	if page > 1 {
+		envelope.Links.Prev = pageLink(r.URL, page-1)
+	}
+	writeJSON(w, http.StatusOK, envelope)
+}
+
+func (h *restV2) changeSubscriptionPlan(w http.ResponseWriter, r *http.Request) {
+	var req ChangeSubscriptionPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid plan change: %v", err))
+		return
+	}
+
+	res, err := h.svc.ChangeSubscriptionPlan(r.Context(), &proto.ChangeSubscriptionPlanRequest{Id: mux.Vars(r)["id"], PlanId: req.PlanID})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, subscriptionModel(res))
+}
+
+func (h *restV2) cancelSubscription(w http.ResponseWriter, r *http.Request) {
+	var req CancelSubscriptionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.error(w, r, status.Errorf(codes.InvalidArgument, "invalid cancellation: %v", err))
+			return
+		}
+	}
+
+	res, err := h.svc.CancelSubscription(r.Context(), &proto.CancelSubscriptionRequest{Id: mux.Vars(r)["id"], AtPeriodEnd: req.AtPeriodEnd})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, subscriptionModel(res))
+}
+
+func (h *restV2) listSubscriptionCharges(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.ListSubscriptionCharges(r.Context(), &proto.ListSubscriptionChargesRequest{Id: mux.Vars(r)["id"]})
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+
+	list := SubscriptionChargeList{Data: make([]models.SubscriptionCharge, 0, len(res.GetCharges()))}
+	for _, c := range res.GetCharges() {
+		list.Data = append(list.Data, models.SubscriptionCharge{
+			ID:             c.GetId(),
+			SubscriptionID: c.GetSubscriptionId(),
+			Period:         int(c.GetPeriod()),
+			Attempt:        int(c.GetAttempt()),
+			PaymentID:      c.GetPaymentId(),
+			Amount:         c.GetAmount(),
+			Currency:       c.GetCurrency(),
+			Status:         c.GetStatus(),
+			Reason:         c.GetReason(),
+			CreatedAt:      c.GetCreatedAt().AsTime(),
+			UpdatedAt:      c.GetUpdatedAt().AsTime(),
+		})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func subscriptionPlanModel(p *proto.SubscriptionPlan) models.SubscriptionPlan {
+	return models.SubscriptionPlan{
+		ID:            p.GetId(),
+		Name:          p.GetName(),
+		Interval:      p.GetInterval(),
+		IntervalCount: int(p.GetIntervalCount()),
+		Amount:        p.GetAmount(),
+		Currency:      p.GetCurrency(),
+		TrialDays:     int(p.GetTrialDays()),
+		CreatedAt:     p.GetCreatedAt().AsTime(),
+	}
+}
+
+func subscriptionModel(s *proto.Subscription) models.Subscription {
+	return models.Subscription{
+		ID:                 s.GetId(),
+		PlanID:             s.GetPlanId(),
+		PaymentMethod:      s.GetPaymentMethod(),
+		Customer:           s.GetCustomer(),
+		Status:             s.GetStatus(),
+		CurrentPeriodStart: s.GetCurrentPeriodStart().AsTime(),
+		CurrentPeriodEnd:   s.GetCurrentPeriodEnd().AsTime(),
+		TrialEnd:           optionalTime(s.GetTrialEnd()),
+		NextChargeAt:       optionalTime(s.GetNextChargeAt()),
+		Attempts:           int(s.GetAttempts()),
+		Balance:            s.GetBalance(),
+		CancelAtPeriodEnd:  s.GetCancelAtPeriodEnd(),
+		CanceledAt:         optionalTime(s.GetCanceledAt()),
+		CreatedAt:          s.GetCreatedAt().AsTime(),
+		UpdatedAt:          s.GetUpdatedAt().AsTime(),
+	}
+}
+
+func optionalTime(t *timestamppb.Timestamp) *time.Time {
+	if t == nil {
+		return nil
+	}
+	at := t.AsTime()
+	return &at
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/settlement"
+	"go-lang-final/internal/store"
+	"go-lang-final/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// SettlementFormat is how the settlement files of one provider are read and
+// checked. Tolerance is the fee deviation, in minor units, still accepted.
+type SettlementFormat struct {
+	Parser    settlement.Parser
+	Rate      settlement.Rate
+	Tolerance int64
+}
+
+// ConfigureSettlements sets the settlement file formats by provider name.
+// Payments the provider settled up to lookbackDays before a file's
+// settlement date are expected in it. Files of providers without a format
+// are refused.
+func (s *PaymentService) ConfigureSettlements(formats map[string]SettlementFormat, lookbackDays int) {
+	s.settlements = formats
+	s.settlementLookback = lookbackDays
+}
+
+func (s *PaymentService) ImportSettlementReport(ctx context.Context, req *proto.ImportSettlementReportRequest) (*proto.SettlementBatch, error) {
+	format, ok := s.settlements[req.GetProvider()]
+	if !ok {
+		return nil, status.Errorf(codes.FailedPrecondition, "no settlement format configured for provider %q", req.GetProvider())
+	}
+	if req.GetReference() == "" {
+		return nil, status.Error(codes.InvalidArgument, "reference must not be empty")
+	}
+	lines, err := format.Parser.Parse(req.GetDocument())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to import settlement report: %v", err)
+	}
+
+	date := req.GetSettlementDate()
+	if date == "" {
+		var latest time.Time
+		for _, l := range lines {
+			if l.Date.After(latest) {
+				latest = l.Date
+			}
+		}
+		if latest.IsZero() {
+			latest = time.Now().UTC()
+		}
+		date = latest.Format("2006-01-02")
+	}
+
+	batch, err := s.store.ImportSettlement(ctx, models.SettlementBatch{
+		Provider:       req.GetProvider(),
+		Reference:      req.GetReference(),
+		SettlementDate: date,
+	}, lines, store.SettlementCheck{Rate: format.Rate, Tolerance: format.Tolerance, LookbackDays: s.settlementLookback})
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to import settlement report")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to import settlement report: %v", err)
+	}
+	return settlementBatchProto(*batch), nil
+}
+
+func (s *PaymentService) GetSettlementBatch(ctx context.Context, req *proto.GetSettlementBatchRequest) (*proto.SettlementBatch, error) {
+	batch, err := s.store.GetSettlementBatch(ctx, req.GetId(), req.GetStatus())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "settlement batch not found: %v", err)
+	}
+	return settlementBatchProto(*batch), nil
+}
+
+func (s *PaymentService) ListSettlementBatches(ctx context.Context, req *proto.ListSettlementBatchesRequest) (*proto.ListSettlementBatchesResponse, error) {
+	page, pageSize := pagination(req.GetPage(), req.GetPageSize())
+	batches, err := s.store.ListSettlementBatches(ctx, req.GetProvider(), page, pageSize)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to list settlement batches")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to list settlement batches: %v", err)
+	}
+
+	res := &proto.ListSettlementBatchesResponse{}
+	for _, batch := range batches {
+		res.Batches = append(res.Batches, settlementBatchProto(batch))
+	}
+	return res, nil
+}
+
+func (s *PaymentService) ListLedgerPostings(ctx context.Context, req *proto.ListLedgerPostingsRequest) (*proto.ListLedgerPostingsResponse, error) {
+	page, pageSize := pagination(req.GetPage(), req.GetPageSize())
+	postings, err := s.store.ListLedgerPostings(ctx, req.GetPaymentId(), req.GetAccount(), page, pageSize)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to list ledger postings")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to list ledger postings: %v", err)
+	}
+
+	res := &proto.ListLedgerPostingsResponse{}
+	for _, p := range postings {
+		res.Postings = append(res.Postings, &proto.LedgerPosting{
+			Id:          p.ID,
+			EntryId:     p.EntryID,
+			Account:     p.Account,
+			Amount:      p.Amount,
+			Currency:    p.Currency,
+			PaymentId:   p.PaymentID,
+			Source:      p.Source,
+			SourceId:    p.SourceID,
+			Description: p.Description,
+			TenantId:    p.TenantID,
+			CreatedAt:   timestamppb.New(p.CreatedAt),
+		})
+	}
+	return res, nil
+}
+
+func settlementBatchProto(batch models.SettlementBatch) *proto.SettlementBatch {
+	res := &proto.SettlementBatch{
+		Id:               batch.ID,
+		Provider:         batch.Provider,
+		Reference:        batch.Reference,
+		SettlementDate:   batch.SettlementDate,
+		Currency:         batch.Currency,
+		LineCount:        int32(batch.Lines),
+		Matched:          int32(batch.Matched),
+		Unexpected:       int32(batch.Unexpected),
+		Missing:          int32(batch.Missing),
+		Duplicated:       int32(batch.Duplicated),
+		AmountMismatches: int32(batch.AmountMismatches),
+		FeeDeviations:    int32(batch.FeeDeviations),
+		Gross:            batch.Gross,
+		Fees:             batch.Fees,
+		Net:              batch.Net,
+		ExpectedFees:     batch.ExpectedFees,
+		TenantId:         batch.TenantID,
+		CreatedAt:        timestamppb.New(batch.CreatedAt),
+	}
+	for _, l := range batch.Items {
+		res.Lines = append(res.Lines, &proto.SettlementLine{
+			Line:        int32(l.Line),
+			Type:        l.Type,
+			Reference:   l.Reference,
+			PaymentId:   l.PaymentID,
+			Currency:    l.Currency,
+			Gross:       l.Gross,
+			Fee:         l.Fee,
+			Net:         l.Net,
+			ExpectedFee: l.ExpectedFee,
+			Status:      l.Status,
+		})
+	}
+	return res
+}
@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go-lang-final/internal/idempotency"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/store"
+	"go-lang-final/internal/subscriptions"
+	"go-lang-final/internal/tenant"
+	"go-lang-final/proto"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// subscriptionBatch is how many due subscriptions one ChargeSubscriptions
+// pass looks at.
+const subscriptionBatch = 100
+
+// defaultSubscriptionLease applies until ConfigureSubscriptions sets one.
+const defaultSubscriptionLease = 10 * time.Minute
+
+// ConfigureSubscriptions sets how long a renewal being charged is held
+// before another pass may pick it up, and the waits before failed renewals
+// are retried. Nil retries use subscriptions.DefaultRetries.
+func (s *PaymentService) ConfigureSubscriptions(lease time.Duration, retries []time.Duration) {
+	s.subscriptionLease, s.dunning = lease, retries
+}
+
+// CreateSubscriptionPlan stores a plan of the tenant; interval_count
+// defaults to 1.
+func (s *PaymentService) CreateSubscriptionPlan(ctx context.Context, req *proto.SubscriptionPlan) (*proto.SubscriptionPlan, error) {
+	count := int(req.GetIntervalCount())
+	if count == 0 {
+		count = 1
+	}
+	plan, err := s.store.CreateSubscriptionPlan(ctx, models.SubscriptionPlan{
+		Name:          strings.TrimSpace(req.GetName()),
+		Interval:      req.GetInterval(),
+		IntervalCount: count,
+		Amount:        req.GetAmount(),
+		Currency:      req.GetCurrency(),
+		TrialDays:     int(req.GetTrialDays()),
+	})
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to create subscription plan")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to create subscription plan: %v", err)
+	}
+	return subscriptionPlanProto(plan), nil
+}
+
+func (s *PaymentService) GetSubscriptionPlan(ctx context.Context, req *proto.GetSubscriptionPlanRequest) (*proto.SubscriptionPlan, error) {
+	plan, err := s.store.GetSubscriptionPlan(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "subscription plan not found: %v", err)
+	}
+	return subscriptionPlanProto(plan), nil
+}
+
+func (s *PaymentService) ListSubscriptionPlans(ctx context.Context, req *proto.ListSubscriptionPlansRequest) (*proto.ListSubscriptionPlansResponse, error) {
+	page, pageSize := pagination(req.GetPage(), req.GetPageSize())
+
+	plans, err := s.store.ListSubscriptionPlans(ctx, page, pageSize)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to list subscription plans")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to list subscription plans: %v", err)
+	}
+
+	res := &proto.ListSubscriptionPlansResponse{}
+	for i := range plans {
+		res.Plans = append(res.Plans, subscriptionPlanProto(&plans[i]))
+	}
+	return res, nil
+}
+
+func (s *PaymentService) CreateSubscription(ctx context.Context, req *proto.CreateSubscriptionRequest) (*proto.Subscription, error) {
+	sub, err := s.store.CreateSubscription(ctx, models.Subscription{PlanID: req.GetPlanId(), PaymentMethod: req.GetPaymentMethod(), Customer: req.GetCustomer()}, time.Now().UTC())
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to create subscription")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to create subscription: %v", err)
+	}
+	return subscriptionProto(sub), nil
+}
+
+func (s *PaymentService) GetSubscription(ctx context.Context, req *proto.GetSubscriptionRequest) (*proto.Subscription, error) {
+	sub, err := s.store.GetSubscription(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "subscription not found: %v", err)
+	}
+	return subscriptionProto(sub), nil
+}
+
+func (s *PaymentService) ListSubscriptions(ctx context.Context, req *proto.ListSubscriptionsRequest) (*proto.ListSubscriptionsResponse, error) {
+	page, pageSize := pagination(req.GetPage(), req.GetPageSize())
+
+	subs, err := s.store.ListSubscriptions(ctx, req.GetStatus(), page, pageSize)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to list subscriptions")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to list subscriptions: %v", err)
+	}
+
+	res := &proto.ListSubscriptionsResponse{}
+	for i := range subs {
+		res.Subscriptions = append(res.Subscriptions, subscriptionProto(&subs[i]))
+	}
+	return res, nil
+}
+
+func (s *PaymentService) ChangeSubscriptionPlan(ctx context.Context, req *proto.ChangeSubscriptionPlanRequest) (*proto.Subscription, error) {
+	sub, err := s.store.ChangeSubscriptionPlan(ctx, req.GetId(), req.GetPlanId(), time.Now().UTC())
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to change subscription plan")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to change subscription plan: %v", err)
+	}
+	return subscriptionProto(sub), nil
+}
+
+func (s *PaymentService) CancelSubscription(ctx context.Context, req *proto.CancelSubscriptionRequest) (*proto.Subscription, error) {
+	sub, err := s.store.CancelSubscription(ctx, req.GetId(), req.GetAtPeriodEnd(), time.Now().UTC())
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("failed to cancel subscription")
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to cancel subscription: %v", err)
+	}
+	return subscriptionProto(sub), nil
+}
+
+func (s *PaymentService) ListSubscriptionCharges(ctx context.Context, req *proto.ListSubscriptionChargesRequest) (*proto.ListSubscriptionChargesResponse, error) {
+	charges, err := s.store.ListSubscriptionCharges(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(grpcCode(err, codes.Internal), "failed to list subscription charges: %v", err)
+	}
+
+	res := &proto.ListSubscriptionChargesResponse{}
+	for _, c := range charges {
+		res.Charges = append(res.Charges, &proto.SubscriptionCharge{
+			Id:             c.ID,
+			SubscriptionId: c.SubscriptionID,
+			Period:         int32(c.Period),
+			Attempt:        int32(c.Attempt),
+			PaymentId:      c.PaymentID,
+			Amount:         c.Amount,
+			Currency:       c.Currency,
+			Status:         c.Status,
+			Reason:         c.Reason,
+			CreatedAt:      timestamppb.New(c.CreatedAt),
+			UpdatedAt:      timestamppb.New(c.UpdatedAt),
+		})
+	}
+	return res, nil
+}
+
+// ChargeSubscriptions charges the renewals that are due. Each is claimed
+// first, so concurrent passes on other replicas skip it, and its payment is
+// created under an idempotency key of the charge: a pass that dies midway
+// leaves the renewal to be claimed again once the lease runs out, and the
+// retry reaches the same payment and provider transaction instead of
+// charging twice. ctx must carry a cross-tenant scope to cover every
+// tenant. It returns how many renewals were settled.
+func (s *PaymentService) ChargeSubscriptions(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	due, err := s.store.DueSubscriptions(ctx, now, subscriptionBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for _, sub := range due {
+		ok, err := s.chargeSubscription(tenant.WithScope(ctx, tenant.Scope{TenantID: sub.TenantID}), sub.ID, now)
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).WithFields(logrus.Fields{
+				"tenant_id":       sub.TenantID,
+				"subscription_id": sub.ID,
+			}).Error("failed to charge subscription")
+			continue
+		}
+		if ok {
+			settled++
+		}
+	}
+	return settled, nil
+}
+
+// chargeSubscription collects the renewal of a due subscription and
+// records how it went. A charge whose payment waits for a challenge stays
+// pending; provider sync captures or cancels the payment and a later pass
+// records the outcome. Errors that may pass, like an unreachable provider
+// or database, leave the charge to be tried again after the lease.
+func (s *PaymentService) chargeSubscription(ctx context.Context, id string, now time.Time) (bool, error) {
+	lease := s.subscriptionLease
+	if lease == 0 {
+		lease = defaultSubscriptionLease
+	}
+	charge, err := s.store.ClaimSubscriptionCharge(ctx, id, now, lease)
+	if err != nil || charge == nil {
+		return false, err
+	}
+	if charge.Status != models.ChargePending {
+		return true, nil
+	}
+
+	paymentStatus := charge.PaymentStatus
+	if paymentStatus == "" {
+		payment := models.Payment{
+			ID:            charge.PaymentID,
+			Amount:        charge.Amount,
+			Currency:      charge.Currency,
+			Status:        models.StatusCreated,
+			PaymentMethod: charge.PaymentMethod,
+			Customer:      charge.Customer,
+		}
+		keyed := idempotency.WithKey(ctx, store.SubscriptionChargeKey(charge.ID))
+		_, err := s.createPayment(keyed, &payment, "")
+		if store.Conflict(err) {
+			// A client took the payment id of the charge; the charge gets
+			// a new one, and the provider transaction a new key with it.
+			if payment.ID, err = s.store.ReallocateSubscriptionPayment(ctx, charge.ID); err != nil || payment.ID == 0 {
+				return false, err
+			}
+			_, err = s.createPayment(keyed, &payment, "")
+		}
+		if err != nil {
+			if !declined(err) {
+				return false, err
+			}
+			return true, s.store.FailSubscriptionCharge(ctx, charge.ID, err.Error(), s.retries(), now)
+		}
+		paymentStatus = payment.Status
+	}
+
+	switch paymentStatus {
+	case models.StatusRequiresAction:
+		return false, nil
+	case models.StatusCancelled:
+		return true, s.store.FailSubscriptionCharge(ctx, charge.ID, "payment cancelled", s.retries(), now)
+	default:
+		return true, s.store.CompleteSubscriptionCharge(ctx, charge.ID)
+	}
+}
+
+func (s *PaymentService) retries() []time.Duration {
+	if s.dunning == nil {
+		return subscriptions.DefaultRetries
+	}
+	return s.dunning
+}
+
+// declined reports whether creating a payment failed for good, as opposed
+// to failing for a reason that may pass on its own. A storage conflict is
+// never a decline: the payment was not refused, its row was not written.
+func declined(err error) bool {
+	if store.Conflict(err) {
+		return false
+	}
+	if store.Rejected(err) {
+		return true
+	}
+	switch grpcCode(err, codes.Internal) {
+	case codes.Internal, codes.Unavailable:
+		return false
+	default:
+		return true
+	}
+}
+
+// RunSubscriptions calls ChargeSubscriptions every interval until ctx is
+// done.
+func (s *PaymentService) RunSubscriptions(ctx context.Context, interval time.Duration, logger *logrus.Logger) {
+	ctx = tenant.WithScope(ctx, tenant.Scope{All: true})
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.ChargeSubscriptions(ctx); err != nil {
+			logger.WithError(err).Error("subscription charging failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func subscriptionPlanProto(plan *models.SubscriptionPlan) *proto.SubscriptionPlan {
+	return &proto.SubscriptionPlan{
+		Id:            plan.ID,
+		Name:          plan.Name,
+		Interval:      plan.Interval,
+		IntervalCount: int32(plan.IntervalCount),
+		Amount:        plan.Amount,
+		Currency:      plan.Currency,
+		TrialDays:     int32(plan.TrialDays),
+		CreatedAt:     timestamppb.New(plan.CreatedAt),
+	}
+}
+
+func subscriptionProto(sub *models.Subscription) *proto.Subscription {
+	return &proto.Subscription{
+		Id:                 sub.ID,
+		PlanId:             sub.PlanID,
+		PaymentMethod:      sub.PaymentMethod,
+		Customer:           sub.Customer,
+		Status:             sub.Status,
+		CurrentPeriodStart: timestamppb.New(sub.CurrentPeriodStart),
+		CurrentPeriodEnd:   timestamppb.New(sub.CurrentPeriodEnd),
+		TrialEnd:           optionalTimestamp(sub.TrialEnd),
+		NextChargeAt:       optionalTimestamp(sub.NextChargeAt),
+		Attempts:           int32(sub.Attempts),
+		Balance:            sub.Balance,
+		CancelAtPeriodEnd:  sub.CancelAtPeriodEnd,
+		CanceledAt:         optionalTimestamp(sub.CanceledAt),
+		CreatedAt:          timestamppb.New(sub.CreatedAt),
+		UpdatedAt:          timestamppb.New(sub.UpdatedAt),
+	}
+}
+
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
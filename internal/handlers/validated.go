@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"context"
+
+	"go-lang-final/internal/validation"
+	"go-lang-final/proto"
+)
+
+// validated checks every request against the validation rules before
+// passing it on to svc. gRPC gets this from the validation interceptors;
+// REST calls the service in process, through the gateway and the /v2
+// handlers, so it goes through validated instead. An RPC added to the
+// service without a method here answers Unimplemented over REST.
+type validated struct {
+	proto.UnimplementedPaymentServiceServer
+	svc proto.PaymentServiceServer
+}
+
+func check[Req, Res any](ctx context.Context, req Req, call func(context.Context, Req) (Res, error)) (Res, error) {
+	if err := validation.Error(req); err != nil {
+		var zero Res
+		return zero, err
+	}
+	return call(ctx, req)
+}
+
+func (v validated) CreatePayment(ctx context.Context, req *proto.CreatePaymentRequest) (*proto.CreatePaymentResponse, error) {
+	return check(ctx, req, v.svc.CreatePayment)
+}
+
+func (v validated) GetPayment(ctx context.Context, req *proto.GetPaymentRequest) (*proto.GetPaymentResponse, error) {
+	return check(ctx, req, v.svc.GetPayment)
+}
+
+func (v validated) UpdatePayment(ctx context.Context, req *proto.UpdatePaymentRequest) (*proto.UpdatePaymentResponse, error) {
+	return check(ctx, req, v.svc.UpdatePayment)
+}
+
+func (v validated) DeletePayment(ctx context.Context, req *proto.DeletePaymentRequest) (*proto.DeletePaymentResponse, error) {
+	return check(ctx, req, v.svc.DeletePayment)
+}
+
+func (v validated) ListPayments(ctx context.Context, req *proto.ListPaymentsRequest) (*proto.ListPaymentsResponse, error) {
+	return check(ctx, req, v.svc.ListPayments)
+}
+
+func (v validated) RefundPayment(ctx context.Context, req *proto.RefundPaymentRequest) (*proto.RefundPaymentResponse, error) {
+	return check(ctx, req, v.svc.RefundPayment)
+}
+
+func (v validated) CancelPayment(ctx context.Context, req *proto.CancelPaymentRequest) (*proto.CancelPaymentResponse, error) {
+	return check(ctx, req, v.svc.CancelPayment)
+}
+
+func (v validated) ListPaymentEvents(ctx context.Context, req *proto.ListPaymentEventsRequest) (*proto.ListPaymentEventsResponse, error) {
+	return check(ctx, req, v.svc.ListPaymentEvents)
+}
+
+func (v validated) WatchPayments(req *proto.WatchPaymentsRequest, stream proto.PaymentService_WatchPaymentsServer) error {
+	if err := validation.Error(req); err != nil {
+		return err
+	}
+	return v.svc.WatchPayments(req, stream)
+}
+
+func (v validated) CreatePaymentMethod(ctx context.Context, req *proto.CreatePaymentMethodRequest) (*proto.PaymentMethod, error) {
+	return check(ctx, req, v.svc.CreatePaymentMethod)
+}
+
+func (v validated) GetPaymentMethod(ctx context.Context, req *proto.GetPaymentMethodRequest) (*proto.PaymentMethod, error) {
+	return check(ctx, req, v.svc.GetPaymentMethod)
+}
+
+func (v validated) CreateBankAccount(ctx context.Context, req *proto.CreateBankAccountRequest) (*proto.BankAccount, error) {
+	return check(ctx, req, v.svc.CreateBankAccount)
+}
+
+func (v validated) GetBankAccount(ctx context.Context, req *proto.GetBankAccountRequest) (*proto.BankAccount, error) {
+	return check(ctx, req, v.svc.GetBankAccount)
+}
+
+func (v validated) CreatePayout(ctx context.Context, req *proto.CreatePayoutRequest) (*proto.CreditTransfer, error) {
+	return check(ctx, req, v.svc.CreatePayout)
+}
+
+func (v validated) GetCreditTransfer(ctx context.Context, req *proto.GetCreditTransferRequest) (*proto.CreditTransfer, error) {
+	return check(ctx, req, v.svc.GetCreditTransfer)
+}
+
+func (v validated) CreateCreditTransferBatch(ctx context.Context, req *proto.CreateCreditTransferBatchRequest) (*proto.CreditTransferBatch, error) {
+	return check(ctx, req, v.svc.CreateCreditTransferBatch)
+}
+
+func (v validated) GetCreditTransferBatch(ctx context.Context, req *proto.GetCreditTransferBatchRequest) (*proto.CreditTransferBatch, error) {
+	return check(ctx, req, v.svc.GetCreditTransferBatch)
+}
+
+func (v validated) ImportPaymentStatusReport(ctx context.Context, req *proto.ImportPaymentStatusReportRequest) (*proto.ImportPaymentStatusReportResponse, error) {
+	return check(ctx, req, v.svc.ImportPaymentStatusReport)
+}
+
+func (v validated) ImportBankStatement(ctx context.Context, req *proto.ImportBankStatementRequest) (*proto.ReconciliationRun, error) {
+	return check(ctx, req, v.svc.ImportBankStatement)
+}
+
+func (v validated) GetReconciliationRun(ctx context.Context, req *proto.GetReconciliationRunRequest) (*proto.ReconciliationRun, error) {
+	return check(ctx, req, v.svc.GetReconciliationRun)
+}
+
+func (v validated) ListReconciliationRuns(ctx context.Context, req *proto.ListReconciliationRunsRequest) (*proto.ListReconciliationRunsResponse, error) {
+	return check(ctx, req, v.svc.ListReconciliationRuns)
+}
+
+func (v validated) ResolveReconciliationLine(ctx context.Context, req *proto.ResolveReconciliationLineRequest) (*proto.ReconciliationLine, error) {
+	return check(ctx, req, v.svc.ResolveReconciliationLine)
+}
+
+func (v validated) ImportSettlementReport(ctx context.Context, req *proto.ImportSettlementReportRequest) (*proto.SettlementBatch, error) {
+	return check(ctx, req, v.svc.ImportSettlementReport)
+}
+
+func (v validated) GetSettlementBatch(ctx context.Context, req *proto.GetSettlementBatchRequest) (*proto.SettlementBatch, error) {
+	return check(ctx, req, v.svc.GetSettlementBatch)
+}
+
+func (v validated) ListSettlementBatches(ctx context.Context, req *proto.ListSettlementBatchesRequest) (*proto.ListSettlementBatchesResponse, error) {
+	return check(ctx, req, v.svc.ListSettlementBatches)
+}
+
+func (v validated) ListLedgerPostings(ctx context.Context, req *proto.ListLedgerPostingsRequest) (*proto.ListLedgerPostingsResponse, error) {
+	return check(ctx, req, v.svc.ListLedgerPostings)
+}
+
+func (v validated) ImportFXRates(ctx context.Context, req *proto.ImportFXRatesRequest) (*proto.ImportFXRatesResponse, error) {
+	return check(ctx, req, v.svc.ImportFXRates)
+}
+
+func (v validated) ListFXRates(ctx context.Context, req *proto.ListFXRatesRequest) (*proto.ListFXRatesResponse, error) {
+	return check(ctx, req, v.svc.ListFXRates)
+}
+
+func (v validated) CreateFXQuote(ctx context.Context, req *proto.CreateFXQuoteRequest) (*proto.FXQuote, error) {
+	return check(ctx, req, v.svc.CreateFXQuote)
+}
+
+func (v validated) GetPaymentConversion(ctx context.Context, req *proto.GetPaymentConversionRequest) (*proto.PaymentConversion, error) {
+	return check(ctx, req, v.svc.GetPaymentConversion)
+}
+
+func (v validated) CreatePricingPlan(ctx context.Context, req *proto.PricingPlan) (*proto.PricingPlan, error) {
+	return check(ctx, req, v.svc.CreatePricingPlan)
+}
+
+func (v validated) UpdatePricingPlan(ctx context.Context, req *proto.PricingPlan) (*proto.PricingPlan, error) {
+	return check(ctx, req, v.svc.UpdatePricingPlan)
+}
+
+func (v validated) GetPricingPlan(ctx context.Context, req *proto.GetPricingPlanRequest) (*proto.PricingPlan, error) {
+	return check(ctx, req, v.svc.GetPricingPlan)
+}
+
+func (v validated) ListPricingPlans(ctx context.Context, req *proto.ListPricingPlansRequest) (*proto.ListPricingPlansResponse, error) {
+	return check(ctx, req, v.svc.ListPricingPlans)
+}
+
+func (v validated) AssignPricingPlan(ctx context.Context, req *proto.AssignPricingPlanRequest) (*proto.PricingPlan, error) {
+	return check(ctx, req, v.svc.AssignPricingPlan)
+}
+
+func (v validated) GetTenantPricingPlan(ctx context.Context, req *proto.GetTenantPricingPlanRequest) (*proto.PricingPlan, error) {
+	return check(ctx, req, v.svc.GetTenantPricingPlan)
+}
+
+func (v validated) SimulateFee(ctx context.Context, req *proto.SimulateFeeRequest) (*proto.PaymentFee, error) {
+	return check(ctx, req, v.svc.SimulateFee)
+}
+
+func (v validated) GetPaymentFee(ctx context.Context, req *proto.GetPaymentFeeRequest) (*proto.PaymentFee, error) {
+	return check(ctx, req, v.svc.GetPaymentFee)
+}
+
+func (v validated) GetFeeStatement(ctx context.Context, req *proto.GetFeeStatementRequest) (*proto.FeeStatement, error) {
+	return check(ctx, req, v.svc.GetFeeStatement)
+}
+
+func (v validated) CreateSubscriptionPlan(ctx context.Context, req *proto.SubscriptionPlan) (*proto.SubscriptionPlan, error) {
+	return check(ctx, req, v.svc.CreateSubscriptionPlan)
+}
+
+func (v validated) GetSubscriptionPlan(ctx context.Context, req *proto.GetSubscriptionPlanRequest) (*proto.SubscriptionPlan, error) {
+	return check(ctx, req, v.svc.GetSubscriptionPlan)
+}
+
+func (v validated) ListSubscriptionPlans(ctx context.Context, req *proto.ListSubscriptionPlansRequest) (*proto.ListSubscriptionPlansResponse, error) {
+	return check(ctx, req, v.svc.ListSubscriptionPlans)
+}
+
+func (v validated) CreateSubscription(ctx context.Context, req *proto.CreateSubscriptionRequest) (*proto.Subscription, error) {
+	return check(ctx, req, v.svc.CreateSubscription)
+}
+
+func (v validated) GetSubscription(ctx context.Context, req *proto.GetSubscriptionRequest) (*proto.Subscription, error) {
+	return check(ctx, req, v.svc.GetSubscription)
+}
+
+func (v validated) ListSubscriptions(ctx context.Context, req *proto.ListSubscriptionsRequest) (*proto.ListSubscriptionsResponse, error) {
+	return check(ctx, req, v.svc.ListSubscriptions)
+}
+
+func (v validated) ChangeSubscriptionPlan(ctx context.Context, req *proto.ChangeSubscriptionPlanRequest) (*proto.Subscription, error) {
+	return check(ctx, req, v.svc.ChangeSubscriptionPlan)
+}
+
+func (v validated) CancelSubscription(ctx context.Context, req *proto.CancelSubscriptionRequest) (*proto.Subscription, error) {
+	return check(ctx, req, v.svc.CancelSubscription)
+}
+
+func (v validated) ListSubscriptionCharges(ctx context.Context, req *proto.ListSubscriptionChargesRequest) (*proto.ListSubscriptionChargesResponse, error) {
+	return check(ctx, req, v.svc.ListSubscriptionCharges)
+}
+
+func (v validated) CreateInvoice(ctx context.Context, req *proto.Invoice) (*proto.Invoice, error) {
+	return check(ctx, req, v.svc.CreateInvoice)
+}
+
+func (v validated) GetInvoice(ctx context.Context, req *proto.GetInvoiceRequest) (*proto.Invoice, error) {
+	return check(ctx, req, v.svc.GetInvoice)
+}
+
+func (v validated) ListInvoices(ctx context.Context, req *proto.ListInvoicesRequest) (*proto.ListInvoicesResponse, error) {
+	return check(ctx, req, v.svc.ListInvoices)
+}
+
+func (v validated) UpdateInvoice(ctx context.Context, req *proto.Invoice) (*proto.Invoice, error) {
+	return check(ctx, req, v.svc.UpdateInvoice)
+}
+
+func (v validated) FinalizeInvoice(ctx context.Context, req *proto.FinalizeInvoiceRequest) (*proto.Invoice, error) {
+	return check(ctx, req, v.svc.FinalizeInvoice)
+}
+
+func (v validated) VoidInvoice(ctx context.Context, req *proto.VoidInvoiceRequest) (*proto.Invoice, error) {
+	return check(ctx, req, v.svc.VoidInvoice)
+}
+
+func (v validated) PayInvoice(ctx context.Context, req *proto.PayInvoiceRequest) (*proto.Invoice, error) {
+	return check(ctx, req, v.svc.PayInvoice)
+}
+
+func (v validated) RenderInvoice(ctx context.Context, req *proto.RenderInvoiceRequest) (*proto.RenderInvoiceResponse, error) {
+	return check(ctx, req, v.svc.RenderInvoice)
+}
+
+func (v validated) CreateCustomer(ctx context.Context, req *proto.Customer) (*proto.Customer, error) {
+	return check(ctx, req, v.svc.CreateCustomer)
+}
+
+func (v validated) GetCustomer(ctx context.Context, req *proto.GetCustomerRequest) (*proto.Customer, error) {
+	return check(ctx, req, v.svc.GetCustomer)
+}
+
+func (v validated) ListCustomers(ctx context.Context, req *proto.ListCustomersRequest) (*proto.ListCustomersResponse, error) {
+	return check(ctx, req, v.svc.ListCustomers)
+}
+
+func (v validated) UpdateCustomer(ctx context.Context, req *proto.Customer) (*proto.Customer, error) {
+	return check(ctx, req, v.svc.UpdateCustomer)
+}
+
+func (v validated) DeleteCustomer(ctx context.Context, req *proto.DeleteCustomerRequest) (*proto.DeleteCustomerResponse, error) {
+	return check(ctx, req, v.svc.DeleteCustomer)
+}
+
+func (v validated) EraseCustomer(ctx context.Context, req *proto.EraseCustomerRequest) (*proto.Customer, error) {
+	return check(ctx, req, v.svc.EraseCustomer)
+}
+
+func (v validated) AttachPaymentMethod(ctx context.Context, req *proto.AttachPaymentMethodRequest) (*proto.CustomerPaymentMethods, error) {
+	return check(ctx, req, v.svc.AttachPaymentMethod)
+}
+
+func (v validated) ListCustomerPaymentMethods(ctx context.Context, req *proto.ListCustomerPaymentMethodsRequest) (*proto.CustomerPaymentMethods, error) {
+	return check(ctx, req, v.svc.ListCustomerPaymentMethods)
+}
+
+func (v validated) ListCustomerPayments(ctx context.Context, req *proto.ListCustomerPaymentsRequest) (*proto.ListPaymentsResponse, error) {
+	return check(ctx, req, v.svc.ListCustomerPayments)
+}
+
+func (v validated) GetCustomerLifetimeValue(ctx context.Context, req *proto.GetCustomerLifetimeValueRequest) (*proto.CustomerLifetimeValue, error) {
+	return check(ctx, req, v.svc.GetCustomerLifetimeValue)
+}
+
+func (v validated) CreateDispute(ctx context.Context, req *proto.CreateDisputeRequest) (*proto.Dispute, error) {
+	return check(ctx, req, v.svc.CreateDispute)
+}
+
+func (v validated) GetDispute(ctx context.Context, req *proto.GetDisputeRequest) (*proto.Dispute, error) {
+	return check(ctx, req, v.svc.GetDispute)
+}
+
+func (v validated) ListDisputes(ctx context.Context, req *proto.ListDisputesRequest) (*proto.ListDisputesResponse, error) {
+	return check(ctx, req, v.svc.ListDisputes)
+}
+
+func (v validated) UploadDisputeEvidence(ctx context.Context, req *proto.UploadDisputeEvidenceRequest) (*proto.DisputeEvidence, error) {
+	return check(ctx, req, v.svc.UploadDisputeEvidence)
+}
+
+func (v validated) GetDisputeEvidence(ctx context.Context, req *proto.GetDisputeEvidenceRequest) (*proto.GetDisputeEvidenceResponse, error) {
+	return check(ctx, req, v.svc.GetDisputeEvidence)
+}
+
+func (v validated) SubmitDispute(ctx context.Context, req *proto.SubmitDisputeRequest) (*proto.Dispute, error) {
+	return check(ctx, req, v.svc.SubmitDispute)
+}
+
+func (v validated) AcceptDispute(ctx context.Context, req *proto.AcceptDisputeRequest) (*proto.Dispute, error) {
+	return check(ctx, req, v.svc.AcceptDispute)
+}
+
+func (v validated) ResolveDispute(ctx context.Context, req *proto.ResolveDisputeRequest) (*proto.Dispute, error) {
+	return check(ctx, req, v.svc.ResolveDispute)
+}
@@ -0,0 +1,51 @@
+// Package idempotency carries client-supplied idempotency keys from the
+// transport to the store, which uses them to make payment creation safe to
+// retry.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	Header   = "Idempotency-Key"
+	Metadata = "idempotency-key"
+
+	// MaxKeyLength bounds keys; clients typically send UUIDs.
+	MaxKeyLength = 255
+)
+
+// ErrKeyReused is returned when a key is replayed with a different request.
+var ErrKeyReused = errors.New("idempotency key already used for a different request")
+
+type contextKey int
+
+const keyKey contextKey = 0
+
+func WithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyKey, key)
+}
+
+// FromContext returns the idempotency key of the request, or "" if the
+// client did not send one.
+func FromContext(ctx context.Context) string {
+	key, _ := ctx.Value(keyKey).(string)
+	return key
+}
+
+// FromIncoming moves the key from incoming gRPC metadata into the context.
+func FromIncoming(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	vals := md.Get(Metadata)
+	if len(vals) == 0 || vals[0] == "" {
+		return ctx, nil
+	}
+	if len(vals[0]) > MaxKeyLength {
+		return ctx, fmt.Errorf("idempotency key longer than %d bytes", MaxKeyLength)
+	}
+	return WithKey(ctx, vals[0]), nil
+}
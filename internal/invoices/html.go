@@ -0,0 +1,116 @@
+package invoices
+
+import (
+	"bytes"
+	"html/template"
+	"strconv"
+
+	"go-lang-final/internal/models"
+)
+
+var page = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"money": formatAmount,
+	"title": title,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{title .Invoice}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; font-size: 14px; color: #222; margin: 40px; }
+h1 { font-size: 24px; margin: 0 0 24px; }
+.parties { display: flex; justify-content: space-between; margin-bottom: 24px; }
+.address { white-space: pre-line; }
+table { width: 100%; border-collapse: collapse; margin-top: 24px; }
+th, td { padding: 6px 8px; border-bottom: 1px solid #ddd; text-align: left; }
+.num { text-align: right; }
+.totals td { border: none; }
+.status { text-transform: uppercase; color: #888; }
+</style>
+</head>
+<body>
+<h1>{{title .Invoice}}{{if ne .Invoice.Status "open"}} <span class="status">{{.Invoice.Status}}</span>{{end}}</h1>
+<div class="parties">
+<div>
+{{- with .Issuer}}
+<strong>{{.Name}}</strong>
+<div class="address">{{.Address}}</div>
+{{- if .TaxID}}<div>Tax ID: {{.TaxID}}</div>{{end}}
+{{- end}}
+</div>
+<div>
+<div>Bill to:</div>
+<strong>{{.Invoice.CustomerName}}</strong>
+<div class="address">{{.Invoice.CustomerAddress}}</div>
+{{- if .Invoice.CustomerEmail}}<div>{{.Invoice.CustomerEmail}}</div>{{end}}
+</div>
+</div>
+<table>
+{{- if .Invoice.IssuedAt}}
+<tr><td>Issued</td><td>{{.Invoice.IssuedAt.Format "2006-01-02"}}</td></tr>
+{{- end}}
+{{- if .Invoice.DueDate}}
+<tr><td>Due</td><td>{{.Invoice.DueDate}}</td></tr>
+{{- end}}
+<tr><td>Currency</td><td>{{.Invoice.Currency}}</td></tr>
+</table>
+<table>
+<thead>
+<tr><th>Description</th><th class="num">Quantity</th><th class="num">Unit price</th><th class="num">Discount</th><th class="num">Tax</th><th class="num">Amount</th></tr>
+</thead>
+<tbody>
+{{- range .Invoice.Lines}}
+<tr>
+<td>{{.Description}}</td>
+<td class="num">{{.Quantity}}</td>
+<td class="num">{{money .UnitAmount}}</td>
+<td class="num">{{if .DiscountRate}}{{.DiscountRate}}%{{end}}</td>
+<td class="num">{{if .TaxRate}}{{.TaxRate}}%{{end}}</td>
+<td class="num">{{money .Amount}}</td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+<table class="totals">
+<tr><td class="num">Subtotal</td><td class="num">{{money .Invoice.Subtotal}}</td></tr>
+{{- if .Invoice.Discount}}
+<tr><td class="num">Discount</td><td class="num">-{{money .Invoice.Discount}}</td></tr>
+{{- end}}
+<tr><td class="num">Tax</td><td class="num">{{money .Invoice.Tax}}</td></tr>
+<tr><td class="num"><strong>Total</strong></td><td class="num"><strong>{{money .Invoice.Total}} {{.Invoice.Currency}}</strong></td></tr>
+{{- if .Invoice.AmountPaid}}
+<tr><td class="num">Paid</td><td class="num">{{money .Invoice.AmountPaid}}</td></tr>
+<tr><td class="num"><strong>Amount due</strong></td><td class="num"><strong>{{money .Invoice.AmountDue}} {{.Invoice.Currency}}</strong></td></tr>
+{{- end}}
+</table>
+{{- if .Invoice.Memo}}
+<p class="address">{{.Invoice.Memo}}</p>
+{{- end}}
+</body>
+</html>
+`))
+
+// RenderHTML renders inv from issuer as a standalone HTML page.
+func RenderHTML(inv models.Invoice, issuer Issuer) ([]byte, error) {
+	var buf bytes.Buffer
+	err := page.Execute(&buf, struct {
+		Invoice models.Invoice
+		Issuer  Issuer
+	}{inv, issuer})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// title names an invoice by its number, or as a draft before it has one.
+func title(inv models.Invoice) string {
+	if inv.Number == "" {
+		return "Draft invoice"
+	}
+	return "Invoice " + inv.Number
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
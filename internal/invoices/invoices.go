@@ -0,0 +1,116 @@
+// Package invoices works out the amounts of invoices and renders them as
+// HTML and PDF documents. Amounts are computed in minor units from exact
+// decimal rates, rounding half away from zero line by line, so an invoice
+// always adds up the same.
+package invoices
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"go-lang-final/internal/fees"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/money"
+)
+
+// Limits of an invoice.
+const (
+	MaxLines              = 100
+	MaxDescriptionLength  = 500
+	MaxQuantity           = 1_000_000
+	MaxUnitAmount         = 1_000_000
+	DefaultDueDays        = 30
+	DefaultNumberPrefix   = "INV-"
+	MaxNumberPrefixLength = 16
+)
+
+// DateLayout formats the due dates of invoices.
+const DateLayout = "2006-01-02"
+
+var ErrInvalidInvoice = errors.New("invalid invoice")
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Issuer is who invoices are from, printed at the top of every document.
+type Issuer struct {
+	Name    string
+	Address string
+	TaxID   string
+}
+
+// Number formats the nth invoice number of a tenant.
+func Number(prefix string, n int64) string {
+	return fmt.Sprintf("%s%06d", prefix, n)
+}
+
+// Calculate checks the lines of inv, normalizes the notation of their
+// rates and sets the amounts of the lines and the invoice's totals. What
+// was paid is kept and the amount due follows from it.
+func Calculate(inv *models.Invoice) error {
+	if !currencyCode.MatchString(inv.Currency) {
+		return fmt.Errorf("%w: currency must be an ISO 4217 code", ErrInvalidInvoice)
+	}
+	if inv.DueDate != "" {
+		if _, err := time.Parse(DateLayout, inv.DueDate); err != nil {
+			return fmt.Errorf("%w: due_date must be formatted YYYY-MM-DD", ErrInvalidInvoice)
+		}
+	}
+	if len(inv.Lines) == 0 || len(inv.Lines) > MaxLines {
+		return fmt.Errorf("%w: an invoice has 1 to %d lines", ErrInvalidInvoice, MaxLines)
+	}
+
+	var subtotal, discount, tax int64
+	for i := range inv.Lines {
+		l := &inv.Lines[i]
+		if err := calculateLine(l); err != nil {
+			return fmt.Errorf("%w: lines[%d]: %v", ErrInvalidInvoice, i, err)
+		}
+		subtotal += money.Minor(l.Amount)
+		discount += money.Minor(l.Discount)
+		tax += money.Minor(l.Tax)
+	}
+	total := subtotal - discount + tax
+	inv.Subtotal, inv.Discount, inv.Tax, inv.Total = money.Major(subtotal), money.Major(discount), money.Major(tax), money.Major(total)
+	inv.AmountDue = money.Major(total - money.Minor(inv.AmountPaid))
+	return nil
+}
+
+func calculateLine(l *models.InvoiceLine) error {
+	var err error
+	l.Description = strings.TrimSpace(l.Description)
+	switch {
+	case l.Description == "" || len(l.Description) > MaxDescriptionLength:
+		return fmt.Errorf("description must be 1 to %d bytes", MaxDescriptionLength)
+	case l.Quantity < 1 || l.Quantity > MaxQuantity:
+		return fmt.Errorf("quantity must be between 1 and %d", MaxQuantity)
+	case !(l.UnitAmount >= 0 && l.UnitAmount <= MaxUnitAmount):
+		return fmt.Errorf("unit_amount must be between 0 and %d", MaxUnitAmount)
+	}
+	if l.DiscountRate, err = fees.NormalizePercentage(l.DiscountRate); err != nil {
+		return fmt.Errorf("discount_rate: %v", err)
+	}
+	if l.TaxRate, err = fees.NormalizePercentage(l.TaxRate); err != nil {
+		return fmt.Errorf("tax_rate: %v", err)
+	}
+
+	amount := l.Quantity * money.Minor(l.UnitAmount)
+	discount := percent(amount, l.DiscountRate)
+	tax := percent(amount-discount, l.TaxRate)
+	l.Amount, l.Discount, l.Tax = money.Major(amount), money.Major(discount), money.Major(tax)
+	return nil
+}
+
+// percent returns rate percent of amount; rate is a normalized percentage.
+func percent(amount int64, rate string) int64 {
+	if rate == "" {
+		return 0
+	}
+	r, _ := new(big.Rat).SetString(rate)
+	r.Mul(r, new(big.Rat).SetInt64(amount))
+	n, _ := money.Round(r.Quo(r, big.NewRat(100, 1)))
+	return n
+}
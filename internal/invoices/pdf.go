@@ -0,0 +1,296 @@
+package invoices
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go-lang-final/internal/models"
+)
+
+// A4 in points, and the layout of the page.
+const (
+	pageWidth   = 595.28
+	pageHeight  = 841.89
+	margin      = 50.0
+	lineHeight  = 14.0
+	fontSize    = 10.0
+	right       = pageWidth - margin
+	descWrap    = 45
+	courierEm   = 0.6
+	pdfFontBody = "F1"
+	pdfFontBold = "F2"
+	pdfFontMono = "F3"
+)
+
+// RenderPDF renders inv from issuer as a PDF document. It only uses the
+// standard fonts every PDF reader has, so nothing is embedded and text
+// outside Windows-1252 is replaced by question marks.
+func RenderPDF(inv models.Invoice, issuer Issuer) []byte {
+	d := newDocument()
+
+	heading := title(inv)
+	if inv.Status != models.InvoiceOpen {
+		heading += " (" + inv.Status + ")"
+	}
+	d.text(margin, pdfFontBold, 18, heading)
+	d.skip(2)
+
+	// Issuer on the left, customer on the right.
+	top := d.y
+	d.text(margin, pdfFontBold, fontSize, issuer.Name)
+	d.skip(1)
+	for _, line := range lines(issuer.Address) {
+		d.text(margin, pdfFontBody, fontSize, line)
+		d.skip(1)
+	}
+	if issuer.TaxID != "" {
+		d.text(margin, pdfFontBody, fontSize, "Tax ID: "+issuer.TaxID)
+		d.skip(1)
+	}
+	left := d.y
+	d.y = top
+	d.text(330, pdfFontBody, fontSize, "Bill to:")
+	d.skip(1)
+	d.text(330, pdfFontBold, fontSize, inv.CustomerName)
+	d.skip(1)
+	for _, line := range lines(inv.CustomerAddress) {
+		d.text(330, pdfFontBody, fontSize, line)
+		d.skip(1)
+	}
+	if inv.CustomerEmail != "" {
+		d.text(330, pdfFontBody, fontSize, inv.CustomerEmail)
+		d.skip(1)
+	}
+	if left < d.y {
+		d.y = left
+	}
+	d.skip(1)
+
+	if inv.IssuedAt != nil {
+		d.text(margin, pdfFontBody, fontSize, "Issued: "+inv.IssuedAt.Format(DateLayout))
+		d.skip(1)
+	}
+	if inv.DueDate != "" {
+		d.text(margin, pdfFontBody, fontSize, "Due: "+inv.DueDate)
+		d.skip(1)
+	}
+	d.text(margin, pdfFontBody, fontSize, "Currency: "+inv.Currency)
+	d.skip(2)
+
+	header := func() {
+		d.text(margin, pdfFontBold, fontSize, "Description")
+		d.rightText(330, pdfFontBold, "Qty")
+		d.rightText(405, pdfFontBold, "Unit price")
+		d.rightText(455, pdfFontBold, "Disc.")
+		d.rightText(495, pdfFontBold, "Tax")
+		d.rightText(right, pdfFontBold, "Amount")
+		d.rule()
+		d.skip(1)
+	}
+	header()
+	for _, l := range inv.Lines {
+		desc := wrap(l.Description, descWrap)
+		if d.room() < len(desc) {
+			d.newPage()
+			header()
+		}
+		d.rightText(330, pdfFontMono, strconv.FormatInt(l.Quantity, 10))
+		d.rightText(405, pdfFontMono, formatAmount(l.UnitAmount))
+		if l.DiscountRate != "" {
+			d.rightText(455, pdfFontMono, l.DiscountRate+"%")
+		}
+		if l.TaxRate != "" {
+			d.rightText(495, pdfFontMono, l.TaxRate+"%")
+		}
+		d.rightText(right, pdfFontMono, formatAmount(l.Amount))
+		for _, line := range desc {
+			d.text(margin, pdfFontBody, fontSize, line)
+			d.skip(1)
+		}
+	}
+	d.rule()
+	d.skip(1)
+
+	totals := [][2]string{{"Subtotal", formatAmount(inv.Subtotal)}}
+	if inv.Discount != 0 {
+		totals = append(totals, [2]string{"Discount", "-" + formatAmount(inv.Discount)})
+	}
+	totals = append(totals, [2]string{"Tax", formatAmount(inv.Tax)}, [2]string{"Total " + inv.Currency, formatAmount(inv.Total)})
+	if inv.AmountPaid != 0 {
+		totals = append(totals, [2]string{"Paid", formatAmount(inv.AmountPaid)}, [2]string{"Amount due " + inv.Currency, formatAmount(inv.AmountDue)})
+	}
+	if d.room() < len(totals)+1 {
+		d.newPage()
+	}
+	for _, t := range totals {
+		d.rightText(455, pdfFontBold, t[0])
+		d.rightText(right, pdfFontMono, t[1])
+		d.skip(1)
+	}
+
+	if inv.Memo != "" {
+		d.skip(1)
+		for _, line := range lines(inv.Memo) {
+			for _, part := range wrap(line, 90) {
+				if d.room() < 1 {
+					d.newPage()
+				}
+				d.text(margin, pdfFontBody, fontSize, part)
+				d.skip(1)
+			}
+		}
+	}
+	return d.bytes()
+}
+
+// document collects the content streams of the pages of a PDF file; y is
+// the baseline the next line of the current page is written at.
+type document struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func newDocument() *document {
+	d := &document{}
+	d.newPage()
+	return d
+}
+
+func (d *document) newPage() {
+	d.pages = append(d.pages, new(bytes.Buffer))
+	d.y = pageHeight - margin - lineHeight
+}
+
+func (d *document) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// room is how many more lines fit on the current page.
+func (d *document) room() int {
+	return int((d.y - margin) / lineHeight)
+}
+
+func (d *document) skip(n int) {
+	d.y -= float64(n) * lineHeight
+	if d.y < margin {
+		d.newPage()
+	}
+}
+
+func (d *document) text(x float64, font string, size float64, s string) {
+	if s == "" {
+		return
+	}
+	fmt.Fprintf(d.page(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, d.y, escape(s))
+}
+
+// rightText writes s ending at x. Widths are only known for the monospaced
+// Courier; other fonts are approximated by Helvetica's average width.
+func (d *document) rightText(x float64, font, s string) {
+	em := 0.55
+	if font == pdfFontMono {
+		em = courierEm
+	}
+	d.text(x-float64(utf8.RuneCountInString(s))*em*fontSize, font, fontSize, s)
+}
+
+// rule draws a line under the current line across the page.
+func (d *document) rule() {
+	y := d.y - 4
+	fmt.Fprintf(d.page(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", margin, y, right, y)
+}
+
+// bytes assembles the PDF file: the catalog, the page tree and the fonts,
+// then a page object and content stream per page, and the cross-reference
+// table locating every object.
+func (d *document) bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	const firstPage = 6
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	for i, content := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /%s 3 0 R /%s 4 0 R /%s 5 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, pdfFontBody, pdfFontBold, pdfFontMono, firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// escape encodes s in Windows-1252 as a PDF literal string.
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			b.WriteByte(byte(r))
+		case r == '€':
+			b.WriteByte(0x80)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+func lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
+
+// wrap breaks s into lines of at most width characters at spaces, and
+// words longer than a line wherever they reach its end.
+func wrap(s string, width int) []string {
+	var out []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		for utf8.RuneCountInString(word) > width {
+			if line != "" {
+				out, line = append(out, line), ""
+			}
+			runes := []rune(word)
+			out, word = append(out, string(runes[:width])), string(runes[width:])
+		}
+		switch {
+		case line == "":
+			line = word
+		case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+			line += " " + word
+		default:
+			out, line = append(out, line), word
+		}
+	}
+	if line != "" || len(out) == 0 {
+		out = append(out, line)
+	}
+	return out
+}
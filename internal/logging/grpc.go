@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// UnaryServerInterceptor is the gRPC counterpart of Middleware. The request
+// ID is read from and echoed back in the x-request-id metadata key.
+func UnaryServerInterceptor(logger *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx, entry := prepareContext(ctx, logger)
+
+		resp, err := handler(ctx, req)
+
+		size := 0
+		if msg, ok := resp.(proto.Message); ok && err == nil {
+			size = proto.Size(msg)
+		}
+		logCall(ctx, entry, info.FullMethod, err, start, size)
+		return resp, err
+	}
+}
+
+func StreamServerInterceptor(logger *logrus.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, entry := prepareContext(ss.Context(), logger)
+
+		wrapped := &loggedStream{ServerStream: ss, ctx: ctx}
+		err := handler(srv, wrapped)
+
+		logCall(ctx, entry, info.FullMethod, err, start, wrapped.bytes)
+		return err
+	}
+}
+
+type loggedStream struct {
+	grpc.ServerStream
+	ctx   context.Context
+	bytes int
+}
+
+func (s *loggedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *loggedStream) SendMsg(m interface{}) error {
+	if msg, ok := m.(proto.Message); ok {
+		s.bytes += proto.Size(msg)
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+func prepareContext(ctx context.Context, logger *logrus.Logger) (context.Context, *logrus.Entry) {
+	incoming := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if vals := md.Get(RequestIDMetadata); len(vals) > 0 {
+			incoming = vals[0]
+		}
+	}
+	id := requestID(incoming)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadata, id))
+
+	entry := logger.WithField("request_id", id)
+	return WithLogger(WithRequestID(ctx, id), entry), entry
+}
+
+func logCall(ctx context.Context, entry *logrus.Entry, method string, err error, start time.Time, size int) {
+	peerAddr := ""
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		peerAddr = p.Addr.String()
+	}
+
+	entry.WithFields(logrus.Fields{
+		"transport":  "grpc",
+		"method":     method,
+		"code":       status.Code(err).String(),
+		"latency_ms": time.Since(start).Milliseconds(),
+		"bytes":      size,
+		"peer":       peerAddr,
+	}).Info("request completed")
+}
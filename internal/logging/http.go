@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Middleware assigns or propagates X-Request-ID, stores a request-scoped
+// logger in the context and writes one access log line per request.
+func Middleware(logger *logrus.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			id := requestID(r.Header.Get(RequestIDHeader))
+			w.Header().Set(RequestIDHeader, id)
+
+			entry := logger.WithField("request_id", id)
+			ctx := WithLogger(WithRequestID(r.Context(), id), entry)
+
+			rec := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+
+			entry.WithFields(logrus.Fields{
+				"transport":  "http",
+				"method":     r.Method,
+				"route":      routeTemplate(r),
+				"status":     rec.status,
+				"latency_ms": time.Since(start).Milliseconds(),
+				"bytes":      rec.bytes,
+				"peer":       r.RemoteAddr,
+			}).Info("request completed")
+		})
+	}
+}
+
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return r.URL.Path
+}
@@ -0,0 +1,27 @@
+package logging
+
+import (
+	"sync/atomic"
+
+	"go-lang-final/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// configured is the logger New built last, which FromContext falls back to.
+var configured atomic.Pointer[logrus.Logger]
+
+// New builds the service logger from config, including the redaction hook.
+// It is also the logger of contexts that carry none.
+func New(cfg config.LoggingConfig) *logrus.Logger {
+	logger := logrus.New()
+	if level, err := logrus.ParseLevel(cfg.Level); err == nil {
+		logger.SetLevel(level)
+	}
+	if cfg.Format == "json" {
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	}
+	logger.AddHook(NewRedactionHook(cfg.RedactFields))
+	configured.Store(logger)
+	return logger
+}
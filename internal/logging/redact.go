@@ -0,0 +1,36 @@
+package logging
+
+import (
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const redactedValue = "[REDACTED]"
+
+// RedactionHook masks the values of configured fields on every entry
+// before it reaches the formatter.
+type RedactionHook struct {
+	fields map[string]struct{}
+}
+
+func NewRedactionHook(fields []string) *RedactionHook {
+	h := &RedactionHook{fields: make(map[string]struct{}, len(fields))}
+	for _, f := range fields {
+		h.fields[strings.ToLower(f)] = struct{}{}
+	}
+	return h
+}
+
+func (h *RedactionHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *RedactionHook) Fire(entry *logrus.Entry) error {
+	for k := range entry.Data {
+		if _, ok := h.fields[strings.ToLower(k)]; ok {
+			entry.Data[k] = redactedValue
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Package metadata checks what integrators attach to payments and
+// customers: key/value metadata, descriptions and statement descriptors.
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of metadata.
+const (
+	MaxKeys        = 50
+	MaxKeyLength   = 40
+	MaxValueLength = 500
+)
+
+// MaxDescriptionLength bounds the descriptions of payments.
+const MaxDescriptionLength = 1000
+
+// Statement descriptors follow the card networks: 5 to 22 latin
+// characters, not all of them digits.
+const (
+	MinDescriptorLength = 5
+	MaxDescriptorLength = 22
+)
+
+// Check checks the number of keys of m and the length of its keys and
+// values.
+func Check(m map[string]string) error {
+	if len(m) > MaxKeys {
+		return fmt.Errorf("has more than %d keys", MaxKeys)
+	}
+	for k, v := range m {
+		switch {
+		case k == "" || utf8.RuneCountInString(k) > MaxKeyLength:
+			return fmt.Errorf("keys must be 1 to %d characters", MaxKeyLength)
+		case utf8.RuneCountInString(v) > MaxValueLength:
+			return fmt.Errorf("value of %q is longer than %d characters", k, MaxValueLength)
+		}
+	}
+	return nil
+}
+
+// CheckDescription accepts an empty description or one of up to
+// MaxDescriptionLength characters.
+func CheckDescription(description string) error {
+	if utf8.RuneCountInString(description) > MaxDescriptionLength {
+		return fmt.Errorf("is longer than %d characters", MaxDescriptionLength)
+	}
+	return nil
+}
+
+// CheckDescriptor accepts an empty statement descriptor or one the card
+// networks print: ASCII letters, digits, spaces and punctuation other than
+// < > \ ' " and *.
+func CheckDescriptor(descriptor string) error {
+	if descriptor == "" {
+		return nil
+	}
+	if len(descriptor) < MinDescriptorLength || len(descriptor) > MaxDescriptorLength {
+		return fmt.Errorf("must be %d to %d characters", MinDescriptorLength, MaxDescriptorLength)
+	}
+	letters := false
+	for _, r := range descriptor {
+		switch {
+		case r < ' ' || r > '~' || strings.ContainsRune(`<>\'"*`, r):
+			return errors.New(`must be ASCII without < > \ ' " or *`)
+		case r < '0' || r > '9':
+			letters = true
+		}
+	}
+	if !letters {
+		return errors.New("must not be all digits")
+	}
+	return nil
+}
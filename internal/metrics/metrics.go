@@ -0,0 +1,179 @@
+// Package metrics records per-method gRPC server metrics and serves them in
+// the Prometheus text exposition format.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Buckets are the upper bounds, in seconds, of the handling time histogram.
+var Buckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type callKey struct {
+	method string
+	code   string
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative; the last is +Inf
+	sum    float64
+	count  uint64
+}
+
+// Registry is safe for concurrent use.
+type Registry struct {
+	mu        sync.Mutex
+	handled   map[callKey]uint64
+	durations map[string]*histogram
+	inFlight  map[string]int64
+	panics    uint64
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		handled:   make(map[callKey]uint64),
+		durations: make(map[string]*histogram),
+		inFlight:  make(map[string]int64),
+	}
+}
+
+func (r *Registry) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer r.observe(info.FullMethod, r.begin(info.FullMethod), &err)
+		return handler(ctx, req)
+	}
+}
+
+func (r *Registry) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer r.observe(info.FullMethod, r.begin(info.FullMethod), &err)
+		return handler(srv, ss)
+	}
+}
+
+// Panic counts a recovered handler panic.
+func (r *Registry) Panic() {
+	r.mu.Lock()
+	r.panics++
+	r.mu.Unlock()
+}
+
+// Handled returns how many calls to method finished with the given status
+// code name, such as "OK" or "InvalidArgument".
+func (r *Registry) Handled(method, code string) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.handled[callKey{method, code}]
+}
+
+// begin marks a call to method as in flight and returns its start time.
+func (r *Registry) begin(method string) time.Time {
+	r.mu.Lock()
+	r.inFlight[method]++
+	r.mu.Unlock()
+	return time.Now()
+}
+
+// observe is deferred around a call to record its outcome. A panic is
+// recorded as Internal and passed on to the recovery interceptor.
+func (r *Registry) observe(method string, begin time.Time, err *error) {
+	code := status.Code(*err)
+	p := recover()
+	if p != nil {
+		code = codes.Internal
+	}
+
+	r.mu.Lock()
+	r.inFlight[method]--
+	r.handled[callKey{method, code.String()}]++
+	h, ok := r.durations[method]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(Buckets)+1)}
+		r.durations[method] = h
+	}
+	elapsed := time.Since(begin).Seconds()
+	h.counts[sort.SearchFloat64s(Buckets, elapsed)]++
+	h.sum += elapsed
+	h.count++
+	r.mu.Unlock()
+
+	if p != nil {
+		panic(p)
+	}
+}
+
+// Handler serves the metrics for scraping.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.write(w)
+	})
+}
+
+func (r *Registry) write(w http.ResponseWriter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP grpc_server_handled_total Completed gRPC calls by method and status code.")
+	fmt.Fprintln(w, "# TYPE grpc_server_handled_total counter")
+	keys := make([]callKey, 0, len(r.handled))
+	for k := range r.handled {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].code < keys[j].code
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "grpc_server_handled_total{grpc_method=%q,grpc_code=%q} %d\n", k.method, k.code, r.handled[k])
+	}
+
+	fmt.Fprintln(w, "# HELP grpc_server_handling_seconds Time to handle gRPC calls.")
+	fmt.Fprintln(w, "# TYPE grpc_server_handling_seconds histogram")
+	for _, method := range sortedKeys(r.durations) {
+		h := r.durations[method]
+		var cumulative uint64
+		for i, bound := range Buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "grpc_server_handling_seconds_bucket{grpc_method=%q,le=%q} %d\n", method, formatFloat(bound), cumulative)
+		}
+		fmt.Fprintf(w, "grpc_server_handling_seconds_bucket{grpc_method=%q,le=\"+Inf\"} %d\n", method, h.count)
+		fmt.Fprintf(w, "grpc_server_handling_seconds_sum{grpc_method=%q} %s\n", method, formatFloat(h.sum))
+		fmt.Fprintf(w, "grpc_server_handling_seconds_count{grpc_method=%q} %d\n", method, h.count)
+	}
+
+	fmt.Fprintln(w, "# HELP grpc_server_in_flight gRPC calls being handled.")
+	fmt.Fprintln(w, "# TYPE grpc_server_in_flight gauge")
+	for _, method := range sortedKeys(r.inFlight) {
+		fmt.Fprintf(w, "grpc_server_in_flight{grpc_method=%q} %d\n", method, r.inFlight[method])
+	}
+
+	fmt.Fprintln(w, "# HELP grpc_server_panics_total Handler panics recovered.")
+	fmt.Fprintln(w, "# TYPE grpc_server_panics_total counter")
+	fmt.Fprintf(w, "grpc_server_panics_total %d\n", r.panics)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
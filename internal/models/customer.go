@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Customer is who payments, saved payment methods and subscriptions belong
+// to. ExternalRef is the tenant's own id for the customer. Once ErasedAt is
+// set the customer's personal data is gone and every other field but the
+// id is blank.
+type Customer struct {
+	ID          string            `json:"id"`
+	TenantID    string            `json:"tenant_id,omitempty"`
+	Email       string            `json:"email,omitempty"`
+	Name        string            `json:"name,omitempty"`
+	ExternalRef string            `json:"external_ref,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	ErasedAt    *time.Time        `json:"erased_at,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// CustomerValue sums the payments of a customer in one currency. Net is
+// what was paid less what was refunded; payments cancelled or waiting for a
+// challenge are not counted.
+type CustomerValue struct {
+	Currency       string     `json:"currency"`
+	Payments       int        `json:"payments"`
+	Gross          float64    `json:"gross"`
+	Refunded       float64    `json:"refunded"`
+	Net            float64    `json:"net"`
+	FirstPaymentAt *time.Time `json:"first_payment_at,omitempty"`
+	LastPaymentAt  *time.Time `json:"last_payment_at,omitempty"`
+}
+
+// CustomerLifetimeValue is what a customer paid, by currency.
+type CustomerLifetimeValue struct {
+	CustomerID string          `json:"customer_id"`
+	Values     []CustomerValue `json:"values"`
+}
+
+// CustomerPaymentMethods are the saved cards and bank accounts of a
+// customer.
+type CustomerPaymentMethods struct {
+	Cards        []PaymentMethod `json:"cards"`
+	BankAccounts []BankAccount   `json:"bank_accounts"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// Dispute statuses. A new dispute needs a response until evidence is
+// submitted, which puts it under review, or until it is accepted or its
+// evidence deadline passes, which loses it. The cardholder's bank decides
+// disputes under review.
+const (
+	DisputeNeedsResponse = "needs_response"
+	DisputeUnderReview   = "under_review"
+	DisputeWon           = "won"
+	DisputeLost          = "lost"
+)
+
+// Dispute is a cardholder contesting Amount of a payment with their bank.
+// Evidence has to be submitted by EvidenceDueBy. Disputes reported by a
+// payment provider carry its name and the provider's id of the dispute in
+// ProviderRef; Outcome explains how a closed dispute was decided.
+type Dispute struct {
+	ID            string            `json:"id"`
+	TenantID      string            `json:"tenant_id,omitempty"`
+	PaymentID     int64             `json:"payment_id"`
+	Reason        string            `json:"reason"`
+	Amount        float64           `json:"amount"`
+	Currency      string            `json:"currency"`
+	Status        string            `json:"status"`
+	EvidenceDueBy time.Time         `json:"evidence_due_by"`
+	Provider      string            `json:"provider,omitempty"`
+	ProviderRef   string            `json:"provider_ref,omitempty"`
+	Outcome       string            `json:"outcome,omitempty"`
+	Evidence      []DisputeEvidence `json:"evidence,omitempty"`
+	SubmittedAt   *time.Time        `json:"submitted_at,omitempty"`
+	ClosedAt      *time.Time        `json:"closed_at,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	// RemindersSent counts the reminders of the evidence deadline sent so
+	// far.
+	RemindersSent int `json:"-"`
+}
+
+// DisputeEvidence is a file backing the tenant's side of a dispute. The
+// file itself is in the blob store under Key; SHA256 is the hex digest of
+// its content.
+type DisputeEvidence struct {
+	ID          string    `json:"id"`
+	DisputeID   string    `json:"dispute_id"`
+	Type        string    `json:"type"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+	Key         string    `json:"-"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// PricingPlan sets the fees of the tenants on it. Pricing applies to a
+// payment unless one of the Overrides matches it.
+type PricingPlan struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Pricing   Pricing           `json:"pricing"`
+	Overrides []PricingOverride `json:"overrides,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Pricing charges Percentage, an exact decimal percent of the amount, plus
+// Fixed, kept between Min and Max when they are set. The last of the Tiers
+// whose Volume the tenant has reached this month replaces Percentage and
+// Fixed.
+type Pricing struct {
+	Percentage string        `json:"percentage,omitempty"`
+	Fixed      float64       `json:"fixed,omitempty"`
+	Min        float64       `json:"min,omitempty"`
+	Max        float64       `json:"max,omitempty"`
+	Tiers      []PricingTier `json:"tiers,omitempty"`
+}
+
+// PricingTier applies from a month-to-date volume in the payment's
+// currency on.
+type PricingTier struct {
+	Volume     float64 `json:"volume"`
+	Percentage string  `json:"percentage,omitempty"`
+	Fixed      float64 `json:"fixed,omitempty"`
+}
+
+// PricingOverride prices the payments in Currency, by Method, or both.
+type PricingOverride struct {
+	Currency string  `json:"currency,omitempty"`
+	Method   string  `json:"method,omitempty"`
+	Pricing  Pricing `json:"pricing"`
+}
+
+// PaymentFee is the fee a payment was charged when it was captured, in the
+// payment's currency, with the pricing it came from. Volume is the tenant's
+// volume of the month before the payment. A simulated fee has no PaymentID
+// and CreatedAt.
+type PaymentFee struct {
+	PaymentID     int64      `json:"payment_id,omitempty"`
+	PlanID        string     `json:"plan_id"`
+	Amount        float64    `json:"amount"`
+	Currency      string     `json:"currency"`
+	PaymentAmount float64    `json:"payment_amount"`
+	Method        string     `json:"method"`
+	Percentage    string     `json:"percentage,omitempty"`
+	Fixed         float64    `json:"fixed,omitempty"`
+	Volume        float64    `json:"volume"`
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+}
+
+// FeeStatement sums the fees a tenant was charged in a month, by currency.
+type FeeStatement struct {
+	TenantID string              `json:"tenant_id"`
+	Month    string              `json:"month"`
+	Totals   []FeeStatementTotal `json:"totals"`
+}
+
+// FeeStatementTotal counts the payments charged a fee in one currency,
+// their volume and the fees.
+type FeeStatementTotal struct {
+	Currency string  `json:"currency"`
+	Payments int64   `json:"payments"`
+	Volume   float64 `json:"volume"`
+	Fees     float64 `json:"fees"`
+}
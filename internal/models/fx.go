@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// FXRate is the worth of one unit of Base in Quote from EffectiveAt on.
+// Rate is an exact decimal.
+type FXRate struct {
+	Base        string    `json:"base"`
+	Quote       string    `json:"quote"`
+	Rate        string    `json:"rate"`
+	EffectiveAt time.Time `json:"effective_at"`
+	Source      string    `json:"source,omitempty"`
+}
+
+// FXQuote locks the rate from Currency to SettlementCurrency until
+// ExpiresAt. Amount and SettlementAmount are only set when the quote was
+// asked for an amount.
+type FXQuote struct {
+	ID                 string    `json:"id"`
+	Currency           string    `json:"currency"`
+	SettlementCurrency string    `json:"settlement_currency"`
+	Rate               string    `json:"rate"`
+	RateEffectiveAt    time.Time `json:"rate_effective_at"`
+	Amount             float64   `json:"amount,omitempty"`
+	SettlementAmount   float64   `json:"settlement_amount,omitempty"`
+	ExpiresAt          time.Time `json:"expires_at"`
+	TenantID           string    `json:"tenant_id,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// PaymentConversion is the conversion of a payment from the currency the
+// customer paid in, the presentment currency, to the currency the merchant
+// settles in, with the snapshot of the rate used. QuoteID is set when the
+// rate came from a quote.
+type PaymentConversion struct {
+	PaymentID          int64     `json:"payment_id"`
+	Amount             float64   `json:"amount"`
+	Currency           string    `json:"currency"`
+	SettlementAmount   float64   `json:"settlement_amount"`
+	SettlementCurrency string    `json:"settlement_currency"`
+	Rate               string    `json:"rate"`
+	RateEffectiveAt    time.Time `json:"rate_effective_at"`
+	QuoteID            string    `json:"quote_id,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+}
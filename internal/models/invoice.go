@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Invoice statuses. A draft can still be edited and has no number;
+// finalizing numbers it and opens it for payment. It is paid once its
+// payments cover the total. Void invoices keep their number, so the
+// tenant's sequence stays gapless.
+const (
+	InvoiceDraft = "draft"
+	InvoiceOpen  = "open"
+	InvoicePaid  = "paid"
+	InvoiceVoid  = "void"
+)
+
+// Invoice bills a customer for its Lines in Currency. The amounts of the
+// lines and the totals are worked out from the quantities and rates when
+// the invoice is saved: Total is Subtotal less Discount plus Tax, and
+// AmountDue what is left of it after AmountPaid.
+type Invoice struct {
+	ID              string        `json:"id"`
+	TenantID        string        `json:"tenant_id,omitempty"`
+	Number          string        `json:"number,omitempty"`
+	Status          string        `json:"status"`
+	Currency        string        `json:"currency"`
+	CustomerName    string        `json:"customer_name,omitempty"`
+	CustomerEmail   string        `json:"customer_email,omitempty"`
+	CustomerAddress string        `json:"customer_address,omitempty"`
+	Memo            string        `json:"memo,omitempty"`
+	Lines           []InvoiceLine `json:"lines"`
+	Subtotal        float64       `json:"subtotal"`
+	Discount        float64       `json:"discount"`
+	Tax             float64       `json:"tax"`
+	Total           float64       `json:"total"`
+	AmountPaid      float64       `json:"amount_paid"`
+	AmountDue       float64       `json:"amount_due"`
+	// DueDate is formatted YYYY-MM-DD. Finalizing an invoice without one
+	// sets it to the default payment term after the issue date.
+	DueDate   string           `json:"due_date,omitempty"`
+	IssuedAt  *time.Time       `json:"issued_at,omitempty"`
+	PaidAt    *time.Time       `json:"paid_at,omitempty"`
+	VoidedAt  *time.Time       `json:"voided_at,omitempty"`
+	Payments  []InvoicePayment `json:"payments,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// InvoiceLine charges Quantity times UnitAmount. DiscountRate and TaxRate
+// are exact decimal percents; the discount comes off Amount and the tax is
+// levied on what is left.
+type InvoiceLine struct {
+	Description  string  `json:"description"`
+	Quantity     int64   `json:"quantity"`
+	UnitAmount   float64 `json:"unit_amount"`
+	DiscountRate string  `json:"discount_rate,omitempty"`
+	TaxRate      string  `json:"tax_rate,omitempty"`
+	Amount       float64 `json:"amount"`
+	Discount     float64 `json:"discount,omitempty"`
+	Tax          float64 `json:"tax,omitempty"`
+}
+
+// InvoicePayment is the part of a payment applied to an invoice. A payment
+// may settle several invoices and an invoice be settled by several
+// payments.
+type InvoicePayment struct {
+	PaymentID int64     `json:"payment_id"`
+	Amount    float64   `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+}
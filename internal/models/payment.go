@@ -1,7 +1,159 @@
 package models
 
+import "time"
+
+// Payment statuses. A payment waits in RequiresAction while the customer
+// completes a challenge with the provider. Refunds move a payment to
+// PartiallyRefunded or Refunded; only a payment without refunds can be
+// cancelled.
+const (
+	StatusRequiresAction    = "requires_action"
+	StatusCreated           = "created"
+	StatusPartiallyRefunded = "partially_refunded"
+	StatusRefunded          = "refunded"
+	StatusCancelled         = "cancelled"
+)
+
 type Payment struct {
-	ID       int64   `json:"id"`
-	Amount   float64 `json:"amount"`
-	Currency string  `json:"currency"`
+	ID             int64   `json:"id"`
+	Amount         float64 `json:"amount"`
+	Currency       string  `json:"currency"`
+	TenantID       string  `json:"tenant_id,omitempty"`
+	Status         string  `json:"status,omitempty"`
+	RefundedAmount float64 `json:"refunded_amount,omitempty"`
+	// Provider, ProviderRef and ProviderStatus identify the transaction at
+	// the payment provider. They are empty for payments recorded without one.
+	Provider       string `json:"provider,omitempty"`
+	ProviderRef    string `json:"provider_ref,omitempty"`
+	ProviderStatus string `json:"provider_status,omitempty"`
+	// PaymentMethod is the token of the vaulted card charged, or of the
+	// bank account a bank transfer came from. The card itself only ever
+	// lives in the vault.
+	PaymentMethod string `json:"payment_method,omitempty"`
+	// Customer is the id of the customer who paid, if known.
+	Customer string `json:"customer,omitempty"`
+	// Metadata, Description and StatementDescriptor are the integrator's:
+	// order ids and the like, a text to search payments by, and what the
+	// customer's statement shows.
+	Metadata            map[string]string `json:"metadata,omitempty"`
+	Description         string            `json:"description,omitempty"`
+	StatementDescriptor string            `json:"statement_descriptor,omitempty"`
+	// ActionURL is where the customer completes a challenge. It is only set
+	// on the response to a create that left the payment in RequiresAction.
+	ActionURL string `json:"action_url,omitempty"`
+	// Routing is how the provider was chosen. It is stored alongside the
+	// payment for analysis when the payment is created and not read back.
+	Routing *RoutingDecision `json:"-"`
+	// Conversion asks for the payment to be converted to a settlement
+	// currency when it is created, and then holds the conversion. Like
+	// ActionURL it is only set on the response to a create.
+	Conversion *PaymentConversion `json:"conversion,omitempty"`
+	// Fee asks for a captured payment to be charged the fee of the tenant's
+	// pricing plan when it is created, and then holds the fee. It is only
+	// set on the response to a create.
+	Fee *PaymentFee `json:"fee,omitempty"`
+}
+
+// RoutingDecision records which routing rule matched a payment and every
+// provider tried for it, in order.
+type RoutingDecision struct {
+	Rule string `json:"rule"`
+	// Country is the card's BIN country, if known.
+	Country  string           `json:"country,omitempty"`
+	Attempts []RoutingAttempt `json:"attempts"`
+}
+
+// RoutingAttempt is the outcome of one provider: "authorized",
+// "requires_action", "circuit_open", "timeout", "error" or "declined:<code>".
+type RoutingAttempt struct {
+	Provider string `json:"provider"`
+	Outcome  string `json:"outcome"`
+}
+
+// PaymentEvent is one entry of a payment's history.
+type PaymentEvent struct {
+	ID        int64     `json:"id"`
+	PaymentID int64     `json:"payment_id"`
+	TenantID  string    `json:"tenant_id"`
+	Type      string    `json:"type"`
+	Amount    float64   `json:"amount"`
+	Currency  string    `json:"currency"`
+	Status    string    `json:"status"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PaymentMethod describes a card stored in the vault. Only what is safe to
+// show is kept outside the vault: the brand, the last four digits and the
+// expiry.
+type PaymentMethod struct {
+	Token     string    `json:"token"`
+	Brand     string    `json:"brand"`
+	Last4     string    `json:"last4"`
+	ExpMonth  int       `json:"exp_month"`
+	ExpYear   int       `json:"exp_year"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BankAccountPrefix starts every bank account token, telling bank accounts
+// apart from vaulted cards wherever a payment method is expected.
+const BankAccountPrefix = "ba_"
+
+// BankAccount describes a SEPA bank account. Like cards, accounts are shown
+// by the last four characters of the IBAN only.
+type BankAccount struct {
+	Token      string    `json:"token"`
+	HolderName string    `json:"holder_name"`
+	Country    string    `json:"country"`
+	Last4      string    `json:"last4"`
+	BIC        string    `json:"bic,omitempty"`
+	TenantID   string    `json:"tenant_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Credit transfer kinds and statuses. A transfer is pending until a batch
+// puts it in a pain.001 file, submitted once the file is created, and then
+// follows the bank's status reports: accepted, and finally settled or
+// rejected.
+const (
+	TransferRefund = "refund"
+	TransferPayout = "payout"
+
+	TransferPending   = "pending"
+	TransferSubmitted = "submitted"
+	TransferAccepted  = "accepted"
+	TransferSettled   = "settled"
+	TransferRejected  = "rejected"
+)
+
+// CreditTransfer is a SEPA credit transfer to a bank account: a payout, or
+// the refund of a payment made by bank transfer. Reason is the bank's ISO
+// 20022 reason code of a rejection.
+type CreditTransfer struct {
+	ID          string    `json:"id"`
+	BankAccount string    `json:"bank_account"`
+	PaymentID   int64     `json:"payment_id,omitempty"`
+	Kind        string    `json:"kind"`
+	Amount      float64   `json:"amount"`
+	Currency    string    `json:"currency"`
+	Remittance  string    `json:"remittance,omitempty"`
+	Status      string    `json:"status"`
+	Reason      string    `json:"reason,omitempty"`
+	BatchID     string    `json:"batch_id,omitempty"`
+	TenantID    string    `json:"tenant_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreditTransferBatch is a pain.001 file of credit transfers. BankStatus is
+// the group status of the last pain.002 report on it, empty until one
+// arrives.
+type CreditTransferBatch struct {
+	ID            string    `json:"id"`
+	Transfers     int       `json:"transfers"`
+	Total         float64   `json:"total"`
+	ExecutionDate string    `json:"execution_date"`
+	BankStatus    string    `json:"bank_status,omitempty"`
+	TenantID      string    `json:"tenant_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
 }
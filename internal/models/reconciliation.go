@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// ReconciliationRun is one bank statement matched against payments and
+// refunds. The counts are the line statuses at import time; resolving
+// exceptions later does not change them.
+type ReconciliationRun struct {
+	ID               string               `json:"id"`
+	Format           string               `json:"format"`
+	StatementID      string               `json:"statement_id"`
+	Account          string               `json:"account,omitempty"`
+	Tolerance        float64              `json:"tolerance"`
+	DateWindowDays   int                  `json:"date_window_days"`
+	Lines            int                  `json:"lines"`
+	Matched          int                  `json:"matched"`
+	Unmatched        int                  `json:"unmatched"`
+	Duplicated       int                  `json:"duplicated"`
+	AmountMismatches int                  `json:"amount_mismatches"`
+	TenantID         string               `json:"tenant_id,omitempty"`
+	CreatedAt        time.Time            `json:"created_at"`
+	Items            []ReconciliationLine `json:"items,omitempty"`
+}
+
+// Resolutions of reconciliation exceptions: accepted lines are fine as
+// they are or settle the payment named when resolving, written off lines
+// are booked as differences.
+const (
+	ResolutionAccepted   = "accepted"
+	ResolutionWrittenOff = "written_off"
+)
+
+// ReconciliationLine is one statement line of a run. Status is matched,
+// unmatched, duplicated or amount_mismatch; Difference is the line amount
+// minus that of the matched payment or refund. Lines other than matched
+// ones stay exceptions until they have a Resolution.
+type ReconciliationLine struct {
+	Line           int        `json:"line"`
+	BankRef        string     `json:"bank_ref,omitempty"`
+	Reference      string     `json:"reference,omitempty"`
+	Description    string     `json:"description,omitempty"`
+	Direction      string     `json:"direction"`
+	Amount         float64    `json:"amount"`
+	Currency       string     `json:"currency"`
+	BookingDate    string     `json:"booking_date"`
+	Status         string     `json:"status"`
+	PaymentID      int64      `json:"payment_id,omitempty"`
+	EventID        int64      `json:"event_id,omitempty"`
+	Difference     float64    `json:"difference"`
+	Resolution     string     `json:"resolution,omitempty"`
+	ResolutionNote string     `json:"resolution_note,omitempty"`
+	ResolvedBy     string     `json:"resolved_by,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// SettlementBatch is one settlement file of a payment provider checked
+// against its payments. Lines counts the file's transactions; missing
+// payments come on top. Gross, Fees and Net total the file, ExpectedFees is
+// what the contracted rate would have charged.
+type SettlementBatch struct {
+	ID               string           `json:"id"`
+	Provider         string           `json:"provider"`
+	Reference        string           `json:"reference"`
+	SettlementDate   string           `json:"settlement_date"`
+	Currency         string           `json:"currency"`
+	Lines            int              `json:"lines"`
+	Matched          int              `json:"matched"`
+	Unexpected       int              `json:"unexpected"`
+	Missing          int              `json:"missing"`
+	Duplicated       int              `json:"duplicated"`
+	AmountMismatches int              `json:"amount_mismatches"`
+	FeeDeviations    int              `json:"fee_deviations"`
+	Gross            float64          `json:"gross"`
+	Fees             float64          `json:"fees"`
+	Net              float64          `json:"net"`
+	ExpectedFees     float64          `json:"expected_fees"`
+	TenantID         string           `json:"tenant_id,omitempty"`
+	CreatedAt        time.Time        `json:"created_at"`
+	Items            []SettlementLine `json:"items,omitempty"`
+}
+
+// SettlementLine is a transaction of a settlement file, or a payment the
+// file is missing. Type is charge or refund; Gross is negative for refunds
+// and, on missing lines, the payment's amount.
+type SettlementLine struct {
+	Line        int     `json:"line"`
+	Type        string  `json:"type"`
+	Reference   string  `json:"reference,omitempty"`
+	PaymentID   int64   `json:"payment_id,omitempty"`
+	Currency    string  `json:"currency"`
+	Gross       float64 `json:"gross"`
+	Fee         float64 `json:"fee"`
+	Net         float64 `json:"net"`
+	ExpectedFee float64 `json:"expected_fee"`
+	Status      string  `json:"status"`
+}
+
+// LedgerPosting is one side of a balanced ledger entry. Amount is positive
+// for debits and negative for credits; the postings sharing an EntryID sum
+// to zero. Source and SourceID name what produced the entry.
+type LedgerPosting struct {
+	ID          int64     `json:"id"`
+	EntryID     string    `json:"entry_id"`
+	Account     string    `json:"account"`
+	Amount      float64   `json:"amount"`
+	Currency    string    `json:"currency"`
+	PaymentID   int64     `json:"payment_id,omitempty"`
+	Source      string    `json:"source"`
+	SourceID    string    `json:"source_id,omitempty"`
+	Description string    `json:"description,omitempty"`
+	TenantID    string    `json:"tenant_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,90 @@
+package models
+
+import "time"
+
+// Subscription statuses. A trialing subscription is charged for the first
+// time when its trial ends. A failed renewal makes an active subscription
+// past due while it is retried; when the retries are used up, or the
+// customer cancels, it is canceled for good.
+const (
+	SubscriptionTrialing = "trialing"
+	SubscriptionActive   = "active"
+	SubscriptionPastDue  = "past_due"
+	SubscriptionCanceled = "canceled"
+)
+
+// Subscription charge statuses.
+const (
+	ChargePending   = "pending"
+	ChargeSucceeded = "succeeded"
+	ChargeFailed    = "failed"
+)
+
+// SubscriptionPlan bills Amount in Currency every IntervalCount intervals,
+// after a free trial of TrialDays.
+type SubscriptionPlan struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Interval      string    `json:"interval"`
+	IntervalCount int       `json:"interval_count"`
+	Amount        float64   `json:"amount"`
+	Currency      string    `json:"currency"`
+	TrialDays     int       `json:"trial_days,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Subscription charges PaymentMethod for its plan every period, on behalf
+// of Customer if it has one. The period from CurrentPeriodStart to
+// CurrentPeriodEnd is paid for, or is the trial; the next one is charged at
+// NextChargeAt. Balance is what plan changes left to settle, owed when
+// positive and credited when negative, and is added to the next renewal.
+type Subscription struct {
+	ID                 string     `json:"id"`
+	TenantID           string     `json:"tenant_id,omitempty"`
+	PlanID             string     `json:"plan_id"`
+	PaymentMethod      string     `json:"payment_method,omitempty"`
+	Customer           string     `json:"customer,omitempty"`
+	Status             string     `json:"status"`
+	CurrentPeriodStart time.Time  `json:"current_period_start"`
+	CurrentPeriodEnd   time.Time  `json:"current_period_end"`
+	TrialEnd           *time.Time `json:"trial_end,omitempty"`
+	NextChargeAt       *time.Time `json:"next_charge_at,omitempty"`
+	// Attempts counts the failed charges of the renewal being retried.
+	Attempts          int        `json:"attempts,omitempty"`
+	Balance           float64    `json:"balance,omitempty"`
+	CancelAtPeriodEnd bool       `json:"cancel_at_period_end,omitempty"`
+	CanceledAt        *time.Time `json:"canceled_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	// Renewals counts the periods paid for. Anchor and Periods place the
+	// period ends: the end of the current period is Periods intervals of the
+	// plan after Anchor. A plan change moves Anchor to the end of the
+	// current period and starts Periods over.
+	Renewals int       `json:"-"`
+	Anchor   time.Time `json:"-"`
+	Periods  int       `json:"-"`
+}
+
+// SubscriptionCharge is one attempt to collect a renewal. Period numbers
+// the renewals of the subscription, Attempt the tries of one renewal. A
+// renewal the subscription's credit covered has no payment.
+type SubscriptionCharge struct {
+	ID             string    `json:"id"`
+	SubscriptionID string    `json:"subscription_id"`
+	Period         int       `json:"period"`
+	Attempt        int       `json:"attempt"`
+	PaymentID      int64     `json:"payment_id,omitempty"`
+	Amount         float64   `json:"amount"`
+	Currency       string    `json:"currency"`
+	Status         string    `json:"status"`
+	Reason         string    `json:"reason,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	// PaymentMethod is charged for a pending charge, and PaymentStatus is
+	// the status of its payment once it was created.
+	PaymentMethod string `json:"-"`
+	PaymentStatus string `json:"-"`
+	// Customer is the customer of the subscription, whom the payment of a
+	// pending charge belongs to.
+	Customer string `json:"-"`
+}
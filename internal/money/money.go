@@ -0,0 +1,90 @@
+// Package money converts amounts between the float major units the API and
+// the database use and the integer minor units arithmetic is done in, and
+// rounds exact results to minor units. Minor and Major take amounts to
+// have two decimals, as payments do; ToMinor and ToMajor follow the ISO
+// 4217 exponent of a currency. Payments are kept in hundredths, so an
+// amount has at most Places decimals and then converts exactly both ways.
+package money
+
+import (
+	"math"
+	"math/big"
+)
+
+// exponents holds the ISO 4217 currencies whose minor unit is not a
+// hundredth.
+var exponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// Exponent returns the number of decimals of currency: 0 for JPY, 3 for
+// KWD and 2 for most others.
+func Exponent(currency string) int {
+	if e, ok := exponents[currency]; ok {
+		return e
+	}
+	return 2
+}
+
+// Places returns the number of decimals an amount in currency may have:
+// its exponent, but no more than the two payments are stored with.
+func Places(currency string) int {
+	return min(Exponent(currency), 2)
+}
+
+// Exact reports whether amount, in major units of currency, has no more
+// than Places(currency) decimals.
+func Exact(amount float64, currency string) bool {
+	scaled := amount * math.Pow10(Places(currency))
+	return math.Abs(scaled-math.Round(scaled)) < 1e-6
+}
+
+// Step returns the hundredths in the smallest amount of currency an amount
+// may have: 100 for JPY and 1 for most others.
+func Step(currency string) int64 {
+	return int64(math.Pow10(2 - Places(currency)))
+}
+
+// ToMinor returns amount, in major units of currency, in its minor units,
+// rounded to the nearest.
+func ToMinor(amount float64, currency string) int64 {
+	return int64(math.Round(amount * math.Pow10(Exponent(currency))))
+}
+
+// ToMajor returns amount, in minor units of currency, in major units.
+func ToMajor(amount int64, currency string) float64 {
+	return float64(amount) / math.Pow10(Exponent(currency))
+}
+
+// Minor returns amount, in major units, in minor units, rounded to the
+// nearest.
+func Minor(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// Major returns amount, in minor units, in major units.
+func Major(amount int64) float64 {
+	return float64(amount) / 100
+}
+
+// Round rounds r half away from zero. ok is false when the result does not
+// fit in an int64.
+func Round(r *big.Rat) (n int64, ok bool) {
+	num, den := r.Num(), r.Denom()
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	// |rem| / den >= 1/2 rounds away from zero.
+	if rem.Abs(rem).Lsh(rem, 1).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	if !quo.IsInt64() {
+		return 0, false
+	}
+	return quo.Int64(), true
+}
@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DisputeStatus is the provider's view of a dispute. The values match the
+// statuses of stored disputes.
+type DisputeStatus string
+
+const (
+	DisputeNeedsResponse DisputeStatus = "needs_response"
+	DisputeUnderReview   DisputeStatus = "under_review"
+	DisputeWon           DisputeStatus = "won"
+	DisputeLost          DisputeStatus = "lost"
+)
+
+// ErrUnknownDispute is returned for a dispute id the provider does not
+// know.
+var ErrUnknownDispute = errors.New("unknown provider dispute")
+
+// Dispute is a chargeback the cardholder's bank opened against the
+// transaction Ref. ID is the provider's id of the dispute.
+type Dispute struct {
+	ID            string
+	Ref           string
+	Reason        string
+	Amount        float64
+	EvidenceDueBy time.Time
+	Status        DisputeStatus
+}
+
+// Evidence is a file sent to the provider to contest a dispute.
+type Evidence struct {
+	Type        string
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// Disputer is implemented by providers that report disputes. Providers
+// that do not leave disputes to be entered by hand.
+type Disputer interface {
+	// Disputes returns the provider's disputes, open ones and those closed
+	// recently, so each status change is seen at least once.
+	Disputes(ctx context.Context) ([]Dispute, error)
+	// SubmitEvidence contests a dispute with evidence, which puts it
+	// under review.
+	SubmitEvidence(ctx context.Context, id string, evidence []Evidence) (Dispute, error)
+}
@@ -0,0 +1,112 @@
+// Package provider abstracts the payment service provider (PSP) that
+// actually moves the money. The payment lifecycle authorizes and captures
+// through a Provider when a payment is created, voids it on cancellation and
+// refunds through it; the provider's reference is stored on the payment.
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Status is the provider's view of a transaction.
+type Status string
+
+const (
+	// StatusRequiresAction means the customer must complete a challenge,
+	// such as 3-D Secure, before the authorization succeeds.
+	StatusRequiresAction Status = "requires_action"
+	StatusAuthorized     Status = "authorized"
+	// StatusCaptured means the funds were captured but have not settled
+	// yet. A captured transaction can still be voided.
+	StatusCaptured Status = "captured"
+	StatusSettled  Status = "settled"
+	StatusVoided   Status = "voided"
+	// StatusFailed means the customer failed the challenge.
+	StatusFailed Status = "failed"
+)
+
+var (
+	// ErrTimeout is returned when the provider did not answer in time. The
+	// outcome is unknown; retrying with the same idempotency key is safe.
+	ErrTimeout = errors.New("payment provider timed out")
+	// ErrUnknownTransaction is returned for a reference the provider does
+	// not know.
+	ErrUnknownTransaction = errors.New("unknown provider transaction")
+	// ErrNotAllowed is returned when the transaction's status does not allow
+	// the operation, such as voiding a settled transaction.
+	ErrNotAllowed = errors.New("provider transaction does not allow this operation")
+)
+
+// DeclineError reports that the provider declined an authorization.
+type DeclineError struct {
+	// Code is the provider's reason, such as "insufficient_funds".
+	Code string
+}
+
+func (e *DeclineError) Error() string {
+	return fmt.Sprintf("payment declined: %s", e.Code)
+}
+
+// softDeclines are decline codes that say nothing about the card itself, so
+// another provider may well approve the same payment.
+var softDeclines = map[string]bool{
+	"do_not_honor":       true,
+	"try_again_later":    true,
+	"processing_error":   true,
+	"issuer_unavailable": true,
+}
+
+// Soft reports whether the decline is worth retrying on another provider.
+// Nothing was authorized, so the retry cannot charge twice.
+func (e *DeclineError) Soft() bool {
+	return softDeclines[e.Code]
+}
+
+// Authorization is a request to reserve funds on a card.
+type Authorization struct {
+	// Key makes the authorization idempotent: the provider answers a repeated
+	// key with the original transaction instead of charging again.
+	Key        string
+	Amount     float64
+	Currency   string
+	CardNumber string
+	// ExpMonth and ExpYear are set for vaulted cards.
+	ExpMonth int
+	ExpYear  int
+}
+
+// Transaction is the provider's answer to every operation.
+type Transaction struct {
+	Ref    string
+	Status Status
+	// ActionURL is where the customer completes the challenge when Status is
+	// StatusRequiresAction.
+	ActionURL string
+}
+
+// Provider is implemented by every payment service provider integration.
+// Amounts are in major units of the authorized currency.
+type Provider interface {
+	// Name identifies the provider on stored payments.
+	Name() string
+	Authorize(ctx context.Context, auth Authorization) (Transaction, error)
+	Capture(ctx context.Context, ref string, amount float64) (Transaction, error)
+	// Void cancels an authorization, or a capture that has not settled.
+	Void(ctx context.Context, ref string) (Transaction, error)
+	Refund(ctx context.Context, ref string, amount float64) (Transaction, error)
+	// Status fetches the current state of a transaction, which changes
+	// asynchronously when a challenge is completed or funds settle.
+	Status(ctx context.Context, ref string) (Transaction, error)
+}
+
+// New returns a provider of the given type, such as "simulator".
+func New(typ string, opts SimulatorOptions) (Provider, error) {
+	switch typ {
+	case "", SimulatorName:
+		return NewSimulator(opts), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", typ)
+	}
+}
@@ -0,0 +1,346 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go-lang-final/internal/money"
+	"go-lang-final/internal/vault"
+)
+
+// SimulatorName is the name payments processed by the Simulator are stored
+// under.
+const SimulatorName = "simulator"
+
+// DefaultSettlementDelay is how long a delayed-settlement capture stays
+// unsettled unless SimulatorOptions says otherwise.
+const DefaultSettlementDelay = time.Minute
+
+// DefaultDisputeWindow is how long the disputes the Simulator opens leave
+// to submit evidence unless SimulatorOptions says otherwise.
+const DefaultDisputeWindow = 7 * 24 * time.Hour
+
+// Test card numbers understood by the Simulator. Any other number that
+// passes the Luhn check is approved and settles immediately; an empty
+// number behaves like CardApproved.
+const (
+	CardApproved          = "4242424242424242"
+	CardDeclined          = "4000000000000002"
+	CardInsufficientFunds = "4000000000009995"
+	CardTimeout           = "4000000000000119"
+	CardRequires3DS       = "4000000000003220"
+	CardDelayedSettlement = "4000000000000077"
+	// CardDisputed is captured normally, then the cardholder disputes the
+	// whole capture as fraudulent.
+	CardDisputed = "4000000000000259"
+)
+
+// Magic amounts: the Simulator looks at the cents of the authorized amount,
+// so 10.91 is declined whatever the card.
+const (
+	CentsDecline           = 91
+	CentsTimeout           = 92
+	CentsRequires3DS       = 93
+	CentsDelayedSettlement = 94
+	CentsDispute           = 95
+)
+
+// SimulatorOptions configures a Simulator. The zero value is usable.
+type SimulatorOptions struct {
+	// Name overrides SimulatorName, so several simulators can stand in for
+	// different providers.
+	Name            string
+	SettlementDelay time.Duration
+	DisputeWindow   time.Duration
+	// Now replaces the clock, so tests can settle captures without waiting.
+	Now func() time.Time
+}
+
+// Simulator is a deterministic in-memory Provider for development and for
+// running the integration suite offline. Magic card numbers and amounts
+// trigger declines, timeouts, 3-D Secure challenges, delayed settlement and
+// disputes; references are numbered in the order transactions are created.
+// Disputes can also be opened and decided by hand, playing the
+// cardholder's bank.
+type Simulator struct {
+	opts SimulatorOptions
+
+	mu         sync.Mutex
+	seq        int
+	txns       map[string]*simTxn
+	keys       map[string]string
+	disputeSeq int
+	disputes   []*Dispute
+}
+
+type simTxn struct {
+	status   Status
+	amount   float64
+	captured float64
+	refunded float64
+	delayed  bool
+	settleAt time.Time
+	// disputed makes the capture open a dispute.
+	disputed bool
+}
+
+func NewSimulator(opts SimulatorOptions) *Simulator {
+	if opts.SettlementDelay <= 0 {
+		opts.SettlementDelay = DefaultSettlementDelay
+	}
+	if opts.DisputeWindow <= 0 {
+		opts.DisputeWindow = DefaultDisputeWindow
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	if opts.Name == "" {
+		opts.Name = SimulatorName
+	}
+	return &Simulator{opts: opts, txns: make(map[string]*simTxn), keys: make(map[string]string)}
+}
+
+func (s *Simulator) Name() string {
+	return s.opts.Name
+}
+
+func (s *Simulator) Authorize(ctx context.Context, auth Authorization) (Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return Transaction{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ref, ok := s.keys[auth.Key]; ok && auth.Key != "" {
+		return s.transaction(ref), nil
+	}
+
+	card := auth.CardNumber
+	if card == "" {
+		card = CardApproved
+	}
+	cents := money.Minor(auth.Amount) % 100
+	switch {
+	case !vault.Luhn(card):
+		return Transaction{}, &DeclineError{Code: "invalid_number"}
+	case card == CardDeclined:
+		return Transaction{}, &DeclineError{Code: "card_declined"}
+	case card == CardInsufficientFunds:
+		return Transaction{}, &DeclineError{Code: "insufficient_funds"}
+	case cents == CentsDecline:
+		return Transaction{}, &DeclineError{Code: "do_not_honor"}
+	case card == CardTimeout, cents == CentsTimeout:
+		return Transaction{}, ErrTimeout
+	}
+
+	txn := &simTxn{
+		status:   StatusAuthorized,
+		amount:   auth.Amount,
+		delayed:  card == CardDelayedSettlement || cents == CentsDelayedSettlement,
+		disputed: card == CardDisputed || cents == CentsDispute,
+	}
+	if card == CardRequires3DS || cents == CentsRequires3DS {
+		txn.status = StatusRequiresAction
+	}
+	s.seq++
+	ref := fmt.Sprintf("sim_%06d", s.seq)
+	s.txns[ref] = txn
+	if auth.Key != "" {
+		s.keys[auth.Key] = ref
+	}
+	return s.transaction(ref), nil
+}
+
+func (s *Simulator) Capture(ctx context.Context, ref string, amount float64) (Transaction, error) {
+	return s.update(ctx, ref, func(txn *simTxn) error {
+		if txn.status != StatusAuthorized || amount <= 0 || amount > txn.amount {
+			return ErrNotAllowed
+		}
+		txn.captured = amount
+		txn.status = StatusSettled
+		if txn.delayed {
+			txn.status = StatusCaptured
+			txn.settleAt = s.opts.Now().Add(s.opts.SettlementDelay)
+		}
+		if txn.disputed {
+			s.openDispute(ref, "fraudulent", amount)
+		}
+		return nil
+	})
+}
+
+func (s *Simulator) Void(ctx context.Context, ref string) (Transaction, error) {
+	return s.update(ctx, ref, func(txn *simTxn) error {
+		switch txn.status {
+		case StatusRequiresAction, StatusAuthorized, StatusCaptured:
+			txn.status = StatusVoided
+			return nil
+		default:
+			return ErrNotAllowed
+		}
+	})
+}
+
+// Refund refunds part of a capture. The transaction keeps its status; the
+// refunded amounts may not exceed the captured amount.
+func (s *Simulator) Refund(ctx context.Context, ref string, amount float64) (Transaction, error) {
+	return s.update(ctx, ref, func(txn *simTxn) error {
+		if txn.status != StatusCaptured && txn.status != StatusSettled {
+			return ErrNotAllowed
+		}
+		if amount <= 0 || money.Minor(txn.refunded+amount) > money.Minor(txn.captured) {
+			return ErrNotAllowed
+		}
+		txn.refunded += amount
+		return nil
+	})
+}
+
+func (s *Simulator) Status(ctx context.Context, ref string) (Transaction, error) {
+	return s.update(ctx, ref, func(*simTxn) error { return nil })
+}
+
+// CompleteChallenge plays the customer's side of a 3-D Secure challenge: the
+// transaction becomes authorized if passed, failed otherwise.
+func (s *Simulator) CompleteChallenge(ref string, passed bool) error {
+	_, err := s.update(context.Background(), ref, func(txn *simTxn) error {
+		if txn.status != StatusRequiresAction {
+			return ErrNotAllowed
+		}
+		txn.status = StatusFailed
+		if passed {
+			txn.status = StatusAuthorized
+		}
+		return nil
+	})
+	return err
+}
+
+// update applies fn to the transaction after settling it if its time has
+// come.
+func (s *Simulator) update(ctx context.Context, ref string, fn func(*simTxn) error) (Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return Transaction{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	txn, ok := s.txns[ref]
+	if !ok {
+		return Transaction{}, ErrUnknownTransaction
+	}
+	if txn.status == StatusCaptured && !s.opts.Now().Before(txn.settleAt) {
+		txn.status = StatusSettled
+	}
+	if err := fn(txn); err != nil {
+		return Transaction{}, err
+	}
+	return s.transaction(ref), nil
+}
+
+func (s *Simulator) transaction(ref string) Transaction {
+	txn := Transaction{Ref: ref, Status: s.txns[ref].status}
+	if txn.Status == StatusRequiresAction {
+		txn.ActionURL = "https://simulator.invalid/3ds/" + ref
+	}
+	return txn
+}
+
+// OpenDispute plays the cardholder's bank disputing amount of the captured
+// transaction ref for reason.
+func (s *Simulator) OpenDispute(ref, reason string, amount float64) (Dispute, error) {
+	var d Dispute
+	_, err := s.update(context.Background(), ref, func(txn *simTxn) error {
+		if txn.status != StatusCaptured && txn.status != StatusSettled {
+			return ErrNotAllowed
+		}
+		if amount <= 0 || money.Minor(amount) > money.Minor(txn.captured-txn.refunded) {
+			return ErrNotAllowed
+		}
+		d = s.openDispute(ref, reason, amount)
+		return nil
+	})
+	return d, err
+}
+
+// ResolveDispute plays the cardholder's bank deciding a dispute: the
+// merchant wins it or loses it.
+func (s *Simulator) ResolveDispute(id string, won bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.dispute(id)
+	if d == nil {
+		return ErrUnknownDispute
+	}
+	if d.Status != DisputeNeedsResponse && d.Status != DisputeUnderReview {
+		return ErrNotAllowed
+	}
+	d.Status = DisputeLost
+	if won {
+		d.Status = DisputeWon
+	}
+	return nil
+}
+
+// Disputes returns every dispute the Simulator opened, oldest first.
+func (s *Simulator) Disputes(ctx context.Context) ([]Dispute, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	disputes := make([]Dispute, 0, len(s.disputes))
+	for _, d := range s.disputes {
+		disputes = append(disputes, *d)
+	}
+	return disputes, nil
+}
+
+// SubmitEvidence puts a dispute needing a response under review. The
+// evidence is not looked at, but there has to be some.
+func (s *Simulator) SubmitEvidence(ctx context.Context, id string, evidence []Evidence) (Dispute, error) {
+	if err := ctx.Err(); err != nil {
+		return Dispute{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.dispute(id)
+	if d == nil {
+		return Dispute{}, ErrUnknownDispute
+	}
+	if d.Status != DisputeNeedsResponse || len(evidence) == 0 {
+		return Dispute{}, ErrNotAllowed
+	}
+	d.Status = DisputeUnderReview
+	return *d, nil
+}
+
+// openDispute records a new dispute; s.mu must be held.
+func (s *Simulator) openDispute(ref, reason string, amount float64) Dispute {
+	s.disputeSeq++
+	d := &Dispute{
+		ID:            fmt.Sprintf("simdp_%06d", s.disputeSeq),
+		Ref:           ref,
+		Reason:        reason,
+		Amount:        amount,
+		EvidenceDueBy: s.opts.Now().Add(s.opts.DisputeWindow).UTC(),
+		Status:        DisputeNeedsResponse,
+	}
+	s.disputes = append(s.disputes, d)
+	return *d
+}
+
+// dispute finds a dispute by id; s.mu must be held.
+func (s *Simulator) dispute(id string) *Dispute {
+	for _, d := range s.disputes {
+		if d.ID == id {
+			return d
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Rule is a token bucket: Burst tokens at most, refilled at Rate per second.
+type Rule struct {
+	Rate  float64
+	Burst int
+}
+
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next token, zero when allowed.
+	RetryAfter time.Duration
+}
+
+// Store holds bucket state. Take consumes one token for key if available.
+type Store interface {
+	Take(ctx context.Context, key string, rule Rule, now time.Time) (Decision, error)
+}
+
+// refill advances a bucket to now and tries to take one token from it.
+func refill(tokens float64, last, now time.Time, rule Rule) (float64, Decision) {
+	elapsed := now.Sub(last).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	tokens = math.Min(float64(rule.Burst), tokens+elapsed*rule.Rate)
+
+	d := Decision{Limit: rule.Burst}
+	if tokens >= 1 {
+		tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = seconds((1 - tokens) / rule.Rate)
+	}
+	d.Remaining = int(math.Floor(tokens))
+	d.Reset = seconds((float64(rule.Burst) - tokens) / rule.Rate)
+	return tokens, d
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// MemoryStore keeps buckets in process. Idle buckets are swept once they
+// would have refilled completely, since they carry no state by then.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	takes   int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, rule Rule, now time.Time) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Burst), last: now}
+		s.buckets[key] = b
+	}
+
+	var d Decision
+	b.tokens, d = refill(b.tokens, b.last, now, rule)
+	b.last = now
+
+	s.takes++
+	if s.takes%1024 == 0 {
+		s.sweep(now)
+	}
+	return d, nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.last) > time.Hour {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-lang-final/internal/auth"
+	"go-lang-final/internal/config"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/tenant"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	KeyAPIKey = "api_key"
+	KeyTenant = "tenant"
+	KeyIP     = "ip"
+)
+
+// Limiter applies the configured rules on both transports. It must run
+// after authentication so buckets can be keyed by principal or tenant.
+type Limiter struct {
+	store Store
+	cfg   config.RateLimitConfig
+	now   func() time.Time
+}
+
+func New(store Store, cfg config.RateLimitConfig) *Limiter {
+	return &Limiter{store: store, cfg: cfg, now: time.Now}
+}
+
+func (l *Limiter) rule(name string, rules map[string]config.RateLimitRule) config.RateLimitRule {
+	if r, ok := rules[name]; ok {
+		return r
+	}
+	return l.cfg.Default
+}
+
+// bucketKey derives the bucket for a request. Callers without the
+// requested identity fall back to the next coarser one, ending at the IP.
+func bucketKey(ctx context.Context, kind, ip string) string {
+	switch kind {
+	case KeyTenant:
+		if scope, ok := tenant.FromContext(ctx); ok && scope.TenantID != "" {
+			return "tenant:" + scope.TenantID
+		}
+		fallthrough
+	case KeyAPIKey:
+		if p, ok := auth.PrincipalFromContext(ctx); ok {
+			return "principal:" + p.ID
+		}
+	}
+	return "ip:" + ip
+}
+
+func (l *Limiter) take(ctx context.Context, name, ip string, r config.RateLimitRule) (Decision, error) {
+	key := name + "|" + bucketKey(ctx, r.Key, ip)
+	return l.store.Take(ctx, key, Rule{Rate: r.Rate, Burst: r.Burst}, l.now())
+}
+
+func (l *Limiter) Middleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name := ""
+			if route := mux.CurrentRoute(r); route != nil {
+				name = route.GetName()
+			}
+			ip, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				ip = r.RemoteAddr
+			}
+
+			d, err := l.take(r.Context(), name, ip, l.rule(name, l.cfg.Routes))
+			if err != nil {
+				// Fail open: a broken limiter backend must not take the API down.
+				logging.FromContext(r.Context()).WithError(err).Error("rate limiter failed")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(CeilSeconds(d.Reset)))
+			if !d.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(CeilSeconds(d.RetryAfter)))
+				http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := l.limitCall(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := l.limitCall(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func (l *Limiter) limitCall(ctx context.Context, method string) error {
+	ip := ""
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip, _, _ = net.SplitHostPort(p.Addr.String())
+	}
+
+	d, err := l.take(ctx, method, ip, l.rule(method, l.cfg.Methods))
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("rate limiter failed")
+		return nil
+	}
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs(
+		"ratelimit-limit", strconv.Itoa(d.Limit),
+		"ratelimit-remaining", strconv.Itoa(d.Remaining),
+		"ratelimit-reset", strconv.Itoa(CeilSeconds(d.Reset)),
+	))
+	if d.Allowed {
+		return nil
+	}
+	return ResourceExhausted("rate limit exceeded", d.RetryAfter)
+}
+
+// ResourceExhausted builds a gRPC error carrying RetryInfo, so clients
+// know when to try again.
+func ResourceExhausted(msg string, retryAfter time.Duration) error {
+	st := status.New(codes.ResourceExhausted, msg)
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// CeilSeconds rounds d up to whole seconds, as Retry-After and the
+// RateLimit headers count them: a wait of 300ms is 1, not 0.
+func CeilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// PostgresStore shares buckets between replicas through the
+// rate_limit_buckets table. Each take locks a single row.
+type PostgresStore struct {
+	DB *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{DB: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, rule Rule, now time.Time) (Decision, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return Decision{}, err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO rate_limit_buckets (key, tokens, updated_at) VALUES ($1, $2, $3) ON CONFLICT (key) DO NOTHING`
+	if _, err := tx.ExecContext(ctx, query, key, float64(rule.Burst), now); err != nil {
+		return Decision{}, err
+	}
+
+	var (
+		tokens float64
+		last   time.Time
+	)
+	query = `SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, key).Scan(&tokens, &last); err != nil {
+		return Decision{}, err
+	}
+
+	tokens, d := refill(tokens, last, now, rule)
+	query = `UPDATE rate_limit_buckets SET tokens = $2, updated_at = GREATEST(updated_at, $3) WHERE key = $1`
+	if _, err := tx.ExecContext(ctx, query, key, tokens, now); err != nil {
+		return Decision{}, err
+	}
+
+	return d, tx.Commit()
+}
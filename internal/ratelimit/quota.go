@@ -0,0 +1,11 @@
+package ratelimit
+
+import "time"
+
+// UntilQuotaReset returns the time left until daily quotas roll over at
+// midnight UTC.
+func UntilQuotaReset(now time.Time) time.Duration {
+	now = now.UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	return midnight.Sub(now)
+}
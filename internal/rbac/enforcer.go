@@ -0,0 +1,115 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go-lang-final/internal/audit"
+	"go-lang-final/internal/auth"
+	"go-lang-final/internal/logging"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+// Enforcer checks every REST route and gRPC method against the permission
+// it is annotated with. Anything without an annotation is denied.
+type Enforcer struct {
+	policy   *Policy
+	routes   map[string]Permission
+	methods  map[string]Permission
+	recorder audit.Recorder
+}
+
+// NewEnforcer takes the permission annotations keyed by mux route name and
+// by gRPC full method name.
+func NewEnforcer(policy *Policy, routes, methods map[string]Permission, recorder audit.Recorder) *Enforcer {
+	return &Enforcer{policy: policy, routes: routes, methods: methods, recorder: recorder}
+}
+
+func (e *Enforcer) authorize(ctx context.Context, action string, perm Permission, annotated bool) error {
+	p, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return auth.ErrUnauthenticated
+	}
+
+	reason := ""
+	switch {
+	case !annotated:
+		reason = "no permission annotation"
+	case !e.policy.Allowed(p.Roles, perm):
+		reason = "missing permission " + string(perm)
+	default:
+		return nil
+	}
+
+	if e.recorder != nil {
+		err := e.recorder.Record(ctx, audit.Event{
+			Principal: p.ID,
+			Action:    action,
+			Resource:  string(perm),
+			Outcome:   audit.OutcomeDenied,
+			Reason:    reason,
+		})
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).Error("failed to record audit event")
+		}
+	}
+	return ErrForbidden
+}
+
+func (e *Enforcer) Middleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name := ""
+			if route := mux.CurrentRoute(r); route != nil {
+				name = route.GetName()
+			}
+			perm, annotated := e.routes[name]
+
+			if err := e.authorize(r.Context(), r.Method+" "+name, perm, annotated); err != nil {
+				if errors.Is(err, auth.ErrUnauthenticated) {
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (e *Enforcer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := e.authorizeMethod(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (e *Enforcer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := e.authorizeMethod(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func (e *Enforcer) authorizeMethod(ctx context.Context, method string) error {
+	perm, annotated := e.methods[method]
+	if err := e.authorize(ctx, method, perm, annotated); err != nil {
+		if errors.Is(err, auth.ErrUnauthenticated) {
+			return status.Error(codes.Unauthenticated, err.Error())
+		}
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return nil
+}
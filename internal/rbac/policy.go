@@ -0,0 +1,115 @@
+package rbac
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type Permission string
+
+const (
+	PaymentsRead   Permission = "payments:read"
+	PaymentsWrite  Permission = "payments:write"
+	PaymentsDelete Permission = "payments:delete"
+	RefundsCreate  Permission = "refunds:create"
+	AuditRead      Permission = "audit:read"
+	// PaymentMethodsCreate lets a principal store cards in the vault. No
+	// permission reveals a stored card; only payment providers see it.
+	PaymentMethodsCreate Permission = "payment_methods:create"
+	// PayoutsWrite lets a principal send money out by credit transfer and
+	// exchange batch files and status reports with the bank.
+	PayoutsWrite Permission = "payouts:write"
+	// ReconciliationWrite lets a principal import bank statements and
+	// provider settlement files and resolve the exceptions they leave.
+	ReconciliationWrite Permission = "reconciliation:write"
+	// LedgerRead lets a principal read the ledger postings.
+	LedgerRead Permission = "ledger:read"
+	// FXRatesWrite lets a principal add exchange rates, which every tenant
+	// converts at.
+	FXRatesWrite Permission = "fx_rates:write"
+	// PricingPlansWrite lets a principal define pricing plans and put
+	// tenants on them.
+	PricingPlansWrite Permission = "pricing_plans:write"
+	// CustomersErase lets a principal erase the personal data of a
+	// customer for good.
+	CustomersErase Permission = "customers:erase"
+	// DisputesWrite lets a principal open disputes, contest them with
+	// evidence and record their outcome, which moves the disputed money in
+	// the ledger.
+	DisputesWrite Permission = "disputes:write"
+	// TenantsCross lets a principal read across tenants and act on any
+	// tenant named in X-Tenant-ID.
+	TenantsCross Permission = "tenants:cross"
+)
+
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+	RoleAuditor  = "auditor"
+
+	RolePlatformAdmin = "platform_admin"
+)
+
+// Policy maps role names to the permissions they grant.
+type Policy struct {
+	Roles map[string][]Permission `json:"roles"`
+}
+
+func DefaultPolicy() *Policy {
+	return &Policy{Roles: map[string][]Permission{
+		RoleViewer:   {PaymentsRead},
+		RoleOperator: {PaymentsRead, PaymentsWrite, RefundsCreate, PaymentMethodsCreate},
+		RoleAdmin:    {PaymentsRead, PaymentsWrite, PaymentsDelete, RefundsCreate, PaymentMethodsCreate, PayoutsWrite, ReconciliationWrite, LedgerRead, AuditRead, CustomersErase, DisputesWrite},
+		RoleAuditor:  {PaymentsRead, LedgerRead, AuditRead},
+
+		RolePlatformAdmin: {PaymentsRead, PaymentsWrite, PaymentsDelete, RefundsCreate, PaymentMethodsCreate, PayoutsWrite, ReconciliationWrite, LedgerRead, AuditRead, CustomersErase, DisputesWrite, FXRatesWrite, PricingPlansWrite, TenantsCross},
+	}}
+}
+
+func LoadPolicyFile(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rbac policy: %v", err)
+	}
+
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse rbac policy: %v", err)
+	}
+	return &p, nil
+}
+
+func LoadPolicyDB(ctx context.Context, db *sql.DB) (*Policy, error) {
+	rows, err := db.QueryContext(ctx, `SELECT role, permission FROM role_permissions ORDER BY role, permission`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	p := &Policy{Roles: make(map[string][]Permission)}
+	for rows.Next() {
+		var role, perm string
+		if err := rows.Scan(&role, &perm); err != nil {
+			return nil, err
+		}
+		p.Roles[role] = append(p.Roles[role], Permission(perm))
+	}
+
+	return p, rows.Err()
+}
+
+// Allowed reports whether any of the roles grants the permission.
+func (p *Policy) Allowed(roles []string, perm Permission) bool {
+	for _, role := range roles {
+		for _, granted := range p.Roles[role] {
+			if granted == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
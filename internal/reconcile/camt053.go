@@ -0,0 +1,213 @@
+package reconcile
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ParseCAMT053 reads the booked entries of a camt.053 bank-to-customer
+// statement of any 001.xx version. An entry booking several transactions
+// together is split into one Entry per transaction when each carries its
+// own amount, so batch-booked credits can match payments one by one.
+// Pending and informational entries are left out.
+func ParseCAMT053(data []byte) (*Statement, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+	}
+	if !strings.HasPrefix(doc.XMLName.Space, "urn:iso:std:iso:20022:tech:xsd:camt.053.") {
+		return nil, fmt.Errorf("%w: unexpected namespace %q", ErrInvalidStatement, doc.XMLName.Space)
+	}
+	if len(doc.Statements) == 0 {
+		return nil, fmt.Errorf("%w: no Stmt", ErrInvalidStatement)
+	}
+
+	st := &Statement{
+		Format:  FormatCAMT053,
+		ID:      strings.TrimSpace(doc.Statements[0].ID),
+		Account: doc.Statements[0].Account.id(),
+	}
+	for _, stmt := range doc.Statements {
+		for _, ntry := range stmt.Entries {
+			if s := ntry.status(); s != "" && s != "BOOK" {
+				continue
+			}
+			entries, err := ntry.entries()
+			if err != nil {
+				return nil, err
+			}
+			st.Entries = append(st.Entries, entries...)
+		}
+	}
+	return st, nil
+}
+
+type camtDocument struct {
+	XMLName    xml.Name        `xml:"Document"`
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	ID      string      `xml:"Id"`
+	Account camtAccount `xml:"Acct"`
+	Entries []camtEntry `xml:"Ntry"`
+}
+
+type camtAccount struct {
+	IBAN  string `xml:"Id>IBAN"`
+	Other string `xml:"Id>Othr>Id"`
+}
+
+func (a camtAccount) id() string {
+	if a.IBAN != "" {
+		return strings.TrimSpace(a.IBAN)
+	}
+	return strings.TrimSpace(a.Other)
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+func (d camtDate) time() (time.Time, error) {
+	if d.Date != "" {
+		return time.Parse("2006-01-02", strings.TrimSpace(d.Date))
+	}
+	if d.DateTime != "" {
+		s := strings.TrimSpace(d.DateTime)
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t, nil
+		}
+		return time.Parse("2006-01-02T15:04:05", s)
+	}
+	return time.Time{}, fmt.Errorf("%w: entry without booking date", ErrInvalidStatement)
+}
+
+type camtEntry struct {
+	Amount      camtAmount   `xml:"Amt"`
+	Indicator   string       `xml:"CdtDbtInd"`
+	Status      camtStatus   `xml:"Sts"`
+	BookingDate camtDate     `xml:"BookgDt"`
+	BankRef     string       `xml:"AcctSvcrRef"`
+	Info        string       `xml:"AddtlNtryInf"`
+	Details     []camtTxDtls `xml:"NtryDtls>TxDtls"`
+}
+
+// camtStatus is plain text up to camt.053.001.08 and a code after.
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+type camtTxDtls struct {
+	EndToEndID string     `xml:"Refs>EndToEndId"`
+	BankRef    string     `xml:"Refs>AcctSvcrRef"`
+	InstrID    string     `xml:"Refs>InstrId"`
+	Amount     camtAmount `xml:"Amt"`
+	TxAmount   camtAmount `xml:"AmtDtls>TxAmt>Amt"`
+	Remittance []string   `xml:"RmtInf>Ustrd"`
+	Reference  string     `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+	Info       string     `xml:"AddtlTxInf"`
+}
+
+func (t camtTxDtls) amount() camtAmount {
+	if strings.TrimSpace(t.Amount.Value) != "" {
+		return t.Amount
+	}
+	return t.TxAmount
+}
+
+func (t camtTxDtls) references() []string {
+	var refs []string
+	for _, ref := range []string{t.EndToEndID, t.InstrID, t.Reference} {
+		if ref = strings.TrimSpace(ref); ref != "" && ref != "NOTPROVIDED" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+func (t camtTxDtls) text() []string {
+	text := append([]string{}, t.Remittance...)
+	if t.Info != "" {
+		text = append(text, t.Info)
+	}
+	return text
+}
+
+func (e camtEntry) status() string {
+	if e.Status.Code != "" {
+		return strings.TrimSpace(e.Status.Code)
+	}
+	return strings.TrimSpace(e.Status.Text)
+}
+
+func (e camtEntry) entries() ([]Entry, error) {
+	credit := strings.TrimSpace(e.Indicator) == "CRDT"
+	if !credit && strings.TrimSpace(e.Indicator) != "DBIT" {
+		return nil, fmt.Errorf("%w: CdtDbtInd %q", ErrInvalidStatement, e.Indicator)
+	}
+	booked, err := e.BookingDate.time()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+	}
+	base := Entry{
+		BankRef:     strings.TrimSpace(e.BankRef),
+		Credit:      credit,
+		Currency:    strings.TrimSpace(e.Amount.Currency),
+		BookingDate: booked,
+	}
+
+	split := len(e.Details) > 1
+	for _, tx := range e.Details {
+		split = split && strings.TrimSpace(tx.amount().Value) != ""
+	}
+	if !split {
+		entry := base
+		if entry.Amount, err = parseAmount(e.Amount.Value, '.'); err != nil {
+			return nil, err
+		}
+		var text []string
+		for _, tx := range e.Details {
+			entry.References = append(entry.References, tx.references()...)
+			text = append(text, tx.text()...)
+		}
+		if e.Info != "" {
+			text = append(text, e.Info)
+		}
+		entry.Text = joinText(text)
+		return []Entry{entry}, nil
+	}
+
+	entries := make([]Entry, 0, len(e.Details))
+	for _, tx := range e.Details {
+		entry := base
+		amount := tx.amount()
+		if entry.Amount, err = parseAmount(amount.Value, '.'); err != nil {
+			return nil, err
+		}
+		if amount.Currency != "" {
+			entry.Currency = strings.TrimSpace(amount.Currency)
+		}
+		if ref := strings.TrimSpace(tx.BankRef); ref != "" {
+			entry.BankRef = ref
+		}
+		entry.References = tx.references()
+		entry.Text = joinText(tx.text())
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func joinText(parts []string) string {
+	return strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+}
@@ -0,0 +1,215 @@
+package reconcile
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Statuses of a matched statement line.
+const (
+	// Matched lines settle exactly one payment or refund.
+	Matched = "matched"
+	// Unmatched lines found nothing, or more than one equally good
+	// candidate by amount alone.
+	Unmatched = "unmatched"
+	// Duplicated lines repeat a line of the same statement, or settle a
+	// payment or refund another line already settled.
+	Duplicated = "duplicated"
+	// AmountMismatch lines refer to a payment or refund whose amount or
+	// currency differs beyond the tolerance.
+	AmountMismatch = "amount_mismatch"
+)
+
+// Candidate kinds. Credits settle payments, debits settle refunds.
+const (
+	KindPayment = "payment"
+	KindRefund  = "refund"
+)
+
+// PaymentReference is the reference a payer quotes to have a bank transfer
+// matched to payment id.
+func PaymentReference(id int64) string {
+	return fmt.Sprintf("PAY-%d", id)
+}
+
+// Candidate is a payment or refund a statement line may settle. Amount is
+// in minor units.
+type Candidate struct {
+	EventID    int64
+	PaymentID  int64
+	Kind       string
+	References []string
+	Amount     int64
+	Currency   string
+	Date       time.Time
+	// Reconciled marks candidates a line of an earlier run settled.
+	Reconciled bool
+}
+
+// Options tune matching. Tolerance is the largest amount difference, in
+// minor units, still counted as a match; DateWindow is how many days a
+// line's booking date may lie from its candidate's date.
+type Options struct {
+	Tolerance  int64
+	DateWindow int
+}
+
+// DefaultOptions match exact amounts booked within three days.
+func DefaultOptions() Options {
+	return Options{DateWindow: 3}
+}
+
+// Result is the outcome for one statement line. Candidate is nil for
+// unmatched lines; Difference is the line amount minus the candidate's.
+type Result struct {
+	Status     string
+	Candidate  *Candidate
+	Difference int64
+}
+
+// Match pairs each entry with the candidate it settles. A candidate named
+// by one of the entry's references wins; among several, the one closest in
+// amount. Without a reference, an entry matches the single unsettled
+// candidate of its currency within the tolerance and date window, and stays
+// unmatched when there are several. Results are in entry order.
+func Match(entries []Entry, candidates []Candidate, opts Options) []Result {
+	results := make([]Result, len(entries))
+	claimed := make(map[int64]bool)
+	seen := make(map[string]int)
+
+	for i, e := range entries {
+		if key := lineKey(e); key != "" {
+			if first, ok := seen[key]; ok {
+				results[i] = Result{Status: Duplicated, Candidate: results[first].Candidate}
+				continue
+			}
+			seen[key] = i
+		}
+
+		kind := KindRefund
+		if e.Credit {
+			kind = KindPayment
+		}
+		var referenced, byAmount []*Candidate
+		for j := range candidates {
+			c := &candidates[j]
+			if c.Kind != kind || abs(days(e.BookingDate, c.Date)) > int64(opts.DateWindow) {
+				continue
+			}
+			if refers(e, c) {
+				referenced = append(referenced, c)
+			} else if c.Currency == e.Currency && abs(e.Amount-c.Amount) <= opts.Tolerance && !c.Reconciled && !claimed[c.EventID] {
+				byAmount = append(byAmount, c)
+			}
+		}
+
+		switch {
+		case len(referenced) > 0:
+			c := closest(e, referenced, claimed)
+			diff := e.Amount - c.Amount
+			switch {
+			case c.Reconciled || claimed[c.EventID]:
+				results[i] = Result{Status: Duplicated, Candidate: c, Difference: diff}
+			case c.Currency != e.Currency || abs(diff) > opts.Tolerance:
+				results[i] = Result{Status: AmountMismatch, Candidate: c, Difference: diff}
+			default:
+				claimed[c.EventID] = true
+				results[i] = Result{Status: Matched, Candidate: c, Difference: diff}
+			}
+		case len(byAmount) == 1:
+			c := byAmount[0]
+			claimed[c.EventID] = true
+			results[i] = Result{Status: Matched, Candidate: c, Difference: e.Amount - c.Amount}
+		default:
+			results[i] = Result{Status: Unmatched}
+		}
+	}
+	return results
+}
+
+// lineKey identifies a line by the bank's reference, so a line reported
+// twice is caught. Lines without one cannot be told apart from genuine
+// repeats and get no key.
+func lineKey(e Entry) string {
+	if e.BankRef == "" || e.BankRef == "NONREF" {
+		return ""
+	}
+	return fmt.Sprintf("%s/%t/%d/%s", e.BankRef, e.Credit, e.Amount, e.Currency)
+}
+
+// refers reports whether the entry names the candidate, either as one of
+// its references or as a whole word of its text.
+func refers(e Entry, c *Candidate) bool {
+	for _, ref := range c.References {
+		if ref == "" {
+			continue
+		}
+		for _, r := range e.References {
+			if strings.EqualFold(r, ref) {
+				return true
+			}
+		}
+		if containsWord(e.Text, ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// closest picks the referenced candidate nearest in amount, preferring
+// unsettled ones.
+func closest(e Entry, cs []*Candidate, claimed map[int64]bool) *Candidate {
+	best := cs[0]
+	better := func(c *Candidate) bool {
+		settled, bestSettled := c.Reconciled || claimed[c.EventID], best.Reconciled || claimed[best.EventID]
+		if settled != bestSettled {
+			return !settled
+		}
+		return abs(e.Amount-c.Amount) < abs(e.Amount-best.Amount)
+	}
+	for _, c := range cs[1:] {
+		if better(c) {
+			best = c
+		}
+	}
+	return best
+}
+
+// containsWord reports whether word occurs in text, ignoring case, without
+// letters or digits directly around it: PAY-7 is in "ref PAY-7" but not in
+// "PAY-70".
+func containsWord(text, word string) bool {
+	text, word = strings.ToUpper(text), strings.ToUpper(word)
+	for from := 0; ; {
+		i := strings.Index(text[from:], word)
+		if i < 0 {
+			return false
+		}
+		start, end := from+i, from+i+len(word)
+		if (start == 0 || !isAlnum(text[start-1])) && (end == len(text) || !isAlnum(text[end])) {
+			return true
+		}
+		from = start + 1
+	}
+}
+
+func isAlnum(b byte) bool {
+	return b >= '0' && b <= '9' || b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z'
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// days returns the number of calendar days, in UTC, from b to a.
+func days(a, b time.Time) int64 {
+	day := func(t time.Time) time.Time {
+		t = t.UTC()
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return int64(day(a).Sub(day(b)) / (24 * time.Hour))
+}
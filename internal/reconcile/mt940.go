@@ -0,0 +1,154 @@
+package reconcile
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	mt940Tag = regexp.MustCompile(`^:([0-9]{2}[A-Z]?):(.*)$`)
+	// mt940Line is the first line of a :61: field: value date, optional
+	// entry date, debit/credit mark (R for reversals), optional funds code,
+	// amount, transaction type, customer reference and bank reference.
+	mt940Line = regexp.MustCompile(`^([0-9]{6})([0-9]{4})?(RC|RD|C|D)([A-Z])?([0-9]+,[0-9]{0,2})([A-Z][A-Z0-9]{3})(.*?)(?://(.*))?$`)
+	// mt940Balance is an opening or closing balance, which carries the
+	// statement currency.
+	mt940Balance = regexp.MustCompile(`^[CD][0-9]{6}([A-Z]{3})`)
+	// mt940Subfield separates the structured subfields of a :86: field.
+	mt940Subfield = regexp.MustCompile(`\?[0-9]{2}`)
+	// mt940EndToEnd is the SEPA end-to-end reference inside a :86: field.
+	mt940EndToEnd = regexp.MustCompile(`EREF\+\s*([^\s?]+)`)
+)
+
+type mt940Field struct {
+	tag, value string
+}
+
+// ParseMT940 reads the statement lines of one or more MT940 messages. The
+// currency of each line is that of the message's opening balance.
+func ParseMT940(data []byte) (*Statement, error) {
+	fields := splitMT940(string(data))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: no MT940 fields", ErrInvalidStatement)
+	}
+
+	st := &Statement{Format: FormatMT940}
+	var (
+		currency string
+		current  *Entry
+	)
+	for _, f := range fields {
+		switch f.tag {
+		case "20":
+			if st.ID == "" {
+				st.ID = strings.TrimSpace(f.value)
+			}
+		case "25":
+			if st.Account == "" {
+				st.Account = strings.TrimSpace(f.value)
+			}
+		case "60F", "60M":
+			m := mt940Balance.FindStringSubmatch(f.value)
+			if m == nil {
+				return nil, fmt.Errorf("%w: invalid opening balance %q", ErrInvalidStatement, f.value)
+			}
+			currency = m[1]
+		case "61":
+			if currency == "" {
+				return nil, fmt.Errorf("%w: :61: before an opening balance", ErrInvalidStatement)
+			}
+			entry, err := parseMT940Line(f.value, currency)
+			if err != nil {
+				return nil, err
+			}
+			st.Entries = append(st.Entries, entry)
+			current = &st.Entries[len(st.Entries)-1]
+		case "86":
+			if current == nil {
+				continue
+			}
+			text := mt940Subfield.ReplaceAllString(strings.ReplaceAll(f.value, "\n", ""), " ")
+			if m := mt940EndToEnd.FindStringSubmatch(text); m != nil && m[1] != "NOTPROVIDED" {
+				current.References = append(current.References, m[1])
+			}
+			current.Text = joinText([]string{current.Text, text})
+			current = nil
+		case "62F", "62M":
+			current = nil
+		}
+	}
+	if st.ID == "" {
+		return nil, fmt.Errorf("%w: missing :20: reference", ErrInvalidStatement)
+	}
+	return st, nil
+}
+
+// splitMT940 returns the fields of the messages in data, joining
+// continuation lines to their field and dropping the SWIFT block wrappers
+// and message separators.
+func splitMT940(data string) []mt940Field {
+	var fields []mt940Field
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := mt940Tag.FindStringSubmatch(line); m != nil {
+			fields = append(fields, mt940Field{tag: m[1], value: m[2]})
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "-" || trimmed == "-}" || strings.HasPrefix(trimmed, "{") {
+			continue
+		}
+		if len(fields) > 0 {
+			fields[len(fields)-1].value += "\n" + line
+		}
+	}
+	return fields
+}
+
+func parseMT940Line(value, currency string) (Entry, error) {
+	first, rest, _ := strings.Cut(value, "\n")
+	m := mt940Line.FindStringSubmatch(strings.TrimSpace(first))
+	if m == nil {
+		return Entry{}, fmt.Errorf("%w: invalid statement line %q", ErrInvalidStatement, first)
+	}
+
+	valueDate, err := time.Parse("060102", m[1])
+	if err != nil {
+		return Entry{}, fmt.Errorf("%w: invalid value date %q", ErrInvalidStatement, m[1])
+	}
+	booked := valueDate
+	if m[2] != "" {
+		// The entry date has no year; it can fall into the year before or
+		// after the value date around new year.
+		entryDate, err := time.Parse("0102", m[2])
+		if err != nil {
+			return Entry{}, fmt.Errorf("%w: invalid entry date %q", ErrInvalidStatement, m[2])
+		}
+		booked = time.Date(valueDate.Year(), entryDate.Month(), entryDate.Day(), 0, 0, 0, 0, time.UTC)
+		switch {
+		case booked.Sub(valueDate) > 180*24*time.Hour:
+			booked = booked.AddDate(-1, 0, 0)
+		case valueDate.Sub(booked) > 180*24*time.Hour:
+			booked = booked.AddDate(1, 0, 0)
+		}
+	}
+	amount, err := parseAmount(m[5], ',')
+	if err != nil {
+		return Entry{}, err
+	}
+
+	entry := Entry{
+		BankRef:     strings.TrimSpace(m[8]),
+		Credit:      m[3] == "C" || m[3] == "RD",
+		Amount:      amount,
+		Currency:    currency,
+		BookingDate: booked,
+		Text:        joinText([]string{rest}),
+	}
+	if ref := strings.TrimSpace(m[7]); ref != "" && ref != "NONREF" {
+		entry.References = append(entry.References, ref)
+	}
+	return entry, nil
+}
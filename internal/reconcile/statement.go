@@ -0,0 +1,82 @@
+// Package reconcile reads bank statements and matches their lines to the
+// payments and refunds they settle. Statements come as ISO 20022 CAMT.053
+// documents or SWIFT MT940 messages; both are reduced to the same Entry.
+package reconcile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Statement formats.
+const (
+	FormatCAMT053 = "camt.053"
+	FormatMT940   = "mt940"
+)
+
+var ErrInvalidStatement = errors.New("invalid bank statement")
+
+// Statement is the booked entries of one bank statement file. A file with
+// several statements, as CAMT.053 and MT940 both allow, yields one Statement
+// with the ID and account of the first.
+type Statement struct {
+	Format  string
+	ID      string
+	Account string
+	Entries []Entry
+}
+
+// Entry is one booked statement line. Amount is in minor units and always
+// positive; Credit tells money received from money paid out.
+type Entry struct {
+	// BankRef is the bank's own reference of the line, which repeats when
+	// a line is reported twice.
+	BankRef string
+	// References are the structured references the line carries, such as
+	// end-to-end ids and customer references.
+	References  []string
+	Text        string
+	Credit      bool
+	Amount      int64
+	Currency    string
+	BookingDate time.Time
+}
+
+// Parse reads a CAMT.053 or MT940 statement, telling them apart by content.
+func Parse(data []byte) (*Statement, error) {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("<")) {
+		return ParseCAMT053(data)
+	}
+	return ParseMT940(data)
+}
+
+// parseAmount converts a decimal amount with the given decimal separator to
+// minor units without going through floating point.
+func parseAmount(s string, separator byte) (int64, error) {
+	s = strings.TrimSpace(s)
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, separator); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	if len(frac) > 2 {
+		return 0, fmt.Errorf("%w: amount %q has more than two decimals", ErrInvalidStatement, s)
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || units < 0 {
+		return 0, fmt.Errorf("%w: invalid amount %q", ErrInvalidStatement, s)
+	}
+	cents, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil || cents < 0 {
+		return 0, fmt.Errorf("%w: invalid amount %q", ErrInvalidStatement, s)
+	}
+	return units*100 + cents, nil
+}
@@ -0,0 +1,158 @@
+package routing
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerOptions tunes the circuit breaker kept for every provider. Zero
+// fields take the defaults below.
+type BreakerOptions struct {
+	// Window is how many of the latest calls the error rate is taken over.
+	Window int
+	// MinCalls is how many calls the window must hold before the breaker
+	// may open.
+	MinCalls int
+	// ErrorRate opens the breaker once reached, between 0 and 1.
+	ErrorRate float64
+	// Cooldown is how long an open breaker rejects calls before letting a
+	// single probe through.
+	Cooldown time.Duration
+}
+
+const (
+	DefaultWindow    = 20
+	DefaultMinCalls  = 10
+	DefaultErrorRate = 0.5
+	DefaultCooldown  = 30 * time.Second
+)
+
+func (o BreakerOptions) withDefaults() BreakerOptions {
+	if o.Window <= 0 {
+		o.Window = DefaultWindow
+	}
+	if o.MinCalls <= 0 {
+		o.MinCalls = DefaultMinCalls
+	}
+	if o.MinCalls > o.Window {
+		o.MinCalls = o.Window
+	}
+	if o.ErrorRate <= 0 || o.ErrorRate > 1 {
+		o.ErrorRate = DefaultErrorRate
+	}
+	if o.Cooldown <= 0 {
+		o.Cooldown = DefaultCooldown
+	}
+	return o
+}
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState string
+
+const (
+	StateClosed   BreakerState = "closed"
+	StateOpen     BreakerState = "open"
+	StateHalfOpen BreakerState = "half_open"
+)
+
+// breaker opens when too many of a provider's recent calls failed, so
+// payments stop being sent to a provider that is down. After the cooldown one
+// probe is let through: its success closes the breaker, its failure opens it
+// again. Declines are answers, not failures, and count as successes.
+type breaker struct {
+	opts BreakerOptions
+	now  func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	results  []bool // ring buffer of the latest calls, true for a failure
+	next     int
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newBreaker(opts BreakerOptions, now func() time.Time) *breaker {
+	return &breaker{opts: opts, now: now, state: StateClosed, results: make([]bool, 0, opts.Window)}
+}
+
+// allow reports whether a call may go to the provider. A true answer from a
+// half-open breaker is the probe and must be followed by record.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.opts.Cooldown {
+			return false
+		}
+		b.state = StateHalfOpen
+		b.probing = true
+		return true
+	case StateHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record counts the outcome of a call let through by allow.
+func (b *breaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen {
+		b.probing = false
+		if failed {
+			b.open()
+		} else {
+			b.reset()
+		}
+		return
+	}
+	if b.state == StateOpen {
+		return
+	}
+
+	if len(b.results) < b.opts.Window {
+		b.results = append(b.results, failed)
+	} else {
+		if b.results[b.next] {
+			b.failures--
+		}
+		b.results[b.next] = failed
+		b.next = (b.next + 1) % b.opts.Window
+	}
+	if failed {
+		b.failures++
+	}
+	if len(b.results) >= b.opts.MinCalls && float64(b.failures)/float64(len(b.results)) >= b.opts.ErrorRate {
+		b.open()
+	}
+}
+
+func (b *breaker) open() {
+	b.state = StateOpen
+	b.openedAt = b.now()
+}
+
+func (b *breaker) reset() {
+	b.state = StateClosed
+	b.results = b.results[:0]
+	b.next = 0
+	b.failures = 0
+}
+
+func (b *breaker) current() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.opts.Cooldown {
+		return StateHalfOpen
+	}
+	return b.state
+}
@@ -0,0 +1,72 @@
+package routing
+
+import (
+	"fmt"
+	"time"
+
+	"go-lang-final/internal/config"
+	"go-lang-final/internal/provider"
+)
+
+// FromConfig builds the providers listed in cfg and a router over them that
+// opens payment method tokens with cards, which may be nil. A config without
+// providers gets a single provider of type cfg.Name.
+func FromConfig(cfg config.ProviderConfig, cards CardSource) (*Router, error) {
+	instances := cfg.Providers
+	if len(instances) == 0 {
+		instances = []config.ProviderInstance{{Type: cfg.Name}}
+	}
+
+	var providers []provider.Provider
+	for _, instance := range instances {
+		delay := instance.SettlementDelay
+		if delay == "" {
+			delay = cfg.SettlementDelay
+		}
+		opts := provider.SimulatorOptions{Name: instance.Name}
+		if delay != "" {
+			d, err := time.ParseDuration(delay)
+			if err != nil {
+				return nil, fmt.Errorf("invalid settlement delay for provider %q: %v", instance.Name, err)
+			}
+			opts.SettlementDelay = d
+		}
+		p, err := provider.New(instance.Type, opts)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+
+	opts := Options{
+		Cards:        cards,
+		BINCountries: cfg.Routing.BINCountries,
+		Breaker: BreakerOptions{
+			Window:    cfg.Routing.Breaker.Window,
+			MinCalls:  cfg.Routing.Breaker.MinCalls,
+			ErrorRate: cfg.Routing.Breaker.ErrorRate,
+		},
+	}
+	if cfg.Routing.Breaker.Cooldown != "" {
+		d, err := time.ParseDuration(cfg.Routing.Breaker.Cooldown)
+		if err != nil {
+			return nil, fmt.Errorf("invalid circuit breaker cooldown: %v", err)
+		}
+		opts.Breaker.Cooldown = d
+	}
+	for _, rule := range cfg.Routing.Rules {
+		r := Rule{
+			Name:       rule.Name,
+			Currencies: rule.Currencies,
+			MinAmount:  rule.MinAmount,
+			MaxAmount:  rule.MaxAmount,
+			Tenants:    rule.Tenants,
+			Countries:  rule.Countries,
+		}
+		for _, target := range rule.Targets {
+			r.Targets = append(r.Targets, Target{Provider: target.Provider, Weight: target.Weight})
+		}
+		opts.Rules = append(opts.Rules, r)
+	}
+	return New(providers, opts)
+}
@@ -0,0 +1,320 @@
+// Package routing picks the payment provider for each payment. Rules match
+// on currency, amount band, tenant and the card's BIN country and split
+// payments between providers by weight, for instance to compare costs. Every
+// provider has a circuit breaker, and a soft decline is retried on the next
+// provider of the rule.
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/provider"
+	"go-lang-final/internal/vault"
+)
+
+// ErrNoProvider is returned when every provider a payment could go to has
+// its circuit breaker open.
+var ErrNoProvider = errors.New("no payment provider available")
+
+// ErrNoVault is returned for a payment method token when the router has no
+// CardSource.
+var ErrNoVault = errors.New("payment methods are not available")
+
+// CardSource opens vaulted cards. It is implemented by *vault.Detokenizer,
+// which the router is the only holder of.
+type CardSource interface {
+	Card(ctx context.Context, token, purpose string) (vault.Card, error)
+}
+
+// Target is a provider a rule sends payments to. Weights split payments
+// between the targets of a rule; a target with weight 0 only takes payments
+// failed over from the others.
+type Target struct {
+	Provider string
+	Weight   int
+}
+
+// Rule sends the payments it matches to its targets. Empty criteria match
+// every payment.
+type Rule struct {
+	Name       string
+	Currencies []string
+	// MinAmount and MaxAmount bound the amount band, MinAmount included and
+	// MaxAmount excluded. A zero MaxAmount has no upper bound.
+	MinAmount float64
+	MaxAmount float64
+	Tenants   []string
+	// Countries are ISO 3166 codes matched against the card's BIN country.
+	Countries []string
+	Targets   []Target
+}
+
+type Options struct {
+	// Rules are tried in order and the first match wins. A payment no rule
+	// matches may go to every provider, in the order they were given.
+	Rules []Rule
+	// BINCountries maps card number prefixes to ISO 3166 country codes; the
+	// longest matching prefix wins.
+	BINCountries map[string]string
+	Breaker      BreakerOptions
+	// Cards opens the payment method tokens of requests.
+	Cards CardSource
+	// Now and Rand replace the clock and the weighted split's randomness
+	// in tests.
+	Now  func() time.Time
+	Rand func() float64
+}
+
+// Request is a payment to route. A request with a PaymentMethod token gets
+// the card from the vault just before it is authorized.
+type Request struct {
+	TenantID      string
+	PaymentMethod string
+	provider.Authorization
+}
+
+// Router is safe for concurrent use.
+type Router struct {
+	providers map[string]provider.Provider
+	names     []string
+	rules     []Rule
+	bins      map[string]string
+	breakers  map[string]*breaker
+	rand      func() float64
+	cards     CardSource
+}
+
+// New returns a router over providers, which must have distinct names.
+func New(providers []provider.Provider, opts Options) (*Router, error) {
+	if len(providers) == 0 {
+		return nil, errors.New("routing needs at least one payment provider")
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	if opts.Rand == nil {
+		opts.Rand = rand.Float64
+	}
+	breakerOpts := opts.Breaker.withDefaults()
+
+	r := &Router{
+		providers: make(map[string]provider.Provider),
+		rules:     opts.Rules,
+		bins:      opts.BINCountries,
+		breakers:  make(map[string]*breaker),
+		rand:      opts.Rand,
+		cards:     opts.Cards,
+	}
+	for _, p := range providers {
+		name := p.Name()
+		if _, ok := r.providers[name]; ok {
+			return nil, fmt.Errorf("duplicate payment provider %q", name)
+		}
+		r.providers[name] = p
+		r.names = append(r.names, name)
+		r.breakers[name] = newBreaker(breakerOpts, opts.Now)
+	}
+	for _, rule := range opts.Rules {
+		if len(rule.Targets) == 0 {
+			return nil, fmt.Errorf("routing rule %q has no targets", rule.Name)
+		}
+		for _, target := range rule.Targets {
+			if _, ok := r.providers[target.Provider]; !ok {
+				return nil, fmt.Errorf("routing rule %q targets unknown provider %q", rule.Name, target.Provider)
+			}
+			if target.Weight < 0 {
+				return nil, fmt.Errorf("routing rule %q has a negative weight for %q", rule.Name, target.Provider)
+			}
+		}
+	}
+	return r, nil
+}
+
+// Single routes every payment to p.
+func Single(p provider.Provider) *Router {
+	r, _ := New([]provider.Provider{p}, Options{})
+	return r
+}
+
+// Provider returns the provider with the given name, to operate on payments
+// it processed.
+func (r *Router) Provider(name string) (provider.Provider, bool) {
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+// Providers returns the providers in the order they were given.
+func (r *Router) Providers() []provider.Provider {
+	providers := make([]provider.Provider, 0, len(r.names))
+	for _, name := range r.names {
+		providers = append(providers, r.providers[name])
+	}
+	return providers
+}
+
+// State returns the state of the named provider's circuit breaker.
+func (r *Router) State(name string) BreakerState {
+	b, ok := r.breakers[name]
+	if !ok {
+		return ""
+	}
+	return b.current()
+}
+
+// Authorize authorizes req with the first provider that answers it. A
+// provider whose breaker is open is skipped and a soft decline moves on to
+// the next candidate; nothing was authorized in either case, so this never
+// charges twice. Any other failure, a timeout included, leaves the outcome
+// unknown and is returned as is. The decision records every attempt, also
+// when an error is returned.
+func (r *Router) Authorize(ctx context.Context, req Request) (provider.Provider, provider.Transaction, *models.RoutingDecision, error) {
+	if req.PaymentMethod != "" {
+		if r.cards == nil {
+			return nil, provider.Transaction{}, &models.RoutingDecision{}, ErrNoVault
+		}
+		card, err := r.cards.Card(ctx, req.PaymentMethod, "authorize payment")
+		if err != nil {
+			return nil, provider.Transaction{}, &models.RoutingDecision{}, err
+		}
+		req.CardNumber, req.ExpMonth, req.ExpYear = card.Number, card.ExpMonth, card.ExpYear
+	}
+	decision, candidates := r.plan(req)
+
+	err := ErrNoProvider
+	for _, name := range candidates {
+		p, b := r.providers[name], r.breakers[name]
+		if !b.allow() {
+			decision.Attempts = append(decision.Attempts, models.RoutingAttempt{Provider: name, Outcome: "circuit_open"})
+			continue
+		}
+
+		var txn provider.Transaction
+		txn, err = p.Authorize(ctx, req.Authorization)
+		var decline *provider.DeclineError
+		switch {
+		case err == nil:
+			b.record(false)
+			decision.Attempts = append(decision.Attempts, models.RoutingAttempt{Provider: name, Outcome: string(txn.Status)})
+			return p, txn, decision, nil
+		case errors.As(err, &decline):
+			b.record(false)
+			decision.Attempts = append(decision.Attempts, models.RoutingAttempt{Provider: name, Outcome: "declined:" + decline.Code})
+			if !decline.Soft() {
+				return nil, provider.Transaction{}, decision, err
+			}
+		default:
+			// A cancelled request says nothing about the provider's health.
+			b.record(ctx.Err() == nil)
+			outcome := "error"
+			if errors.Is(err, provider.ErrTimeout) {
+				outcome = "timeout"
+			}
+			decision.Attempts = append(decision.Attempts, models.RoutingAttempt{Provider: name, Outcome: outcome})
+			return nil, provider.Transaction{}, decision, err
+		}
+	}
+	return nil, provider.Transaction{}, decision, err
+}
+
+// plan returns the decision for req so far and the providers to try, the
+// weighted pick first and the others by decreasing weight.
+func (r *Router) plan(req Request) (*models.RoutingDecision, []string) {
+	decision := &models.RoutingDecision{Country: r.country(req.CardNumber)}
+
+	var targets []Target
+	for _, rule := range r.rules {
+		if rule.matches(req, decision.Country) {
+			decision.Rule, targets = rule.Name, rule.Targets
+			break
+		}
+	}
+	if targets == nil {
+		for _, name := range r.names {
+			targets = append(targets, Target{Provider: name, Weight: 1})
+		}
+	}
+
+	primary := r.pick(targets, req.Key)
+	var rest []Target
+	for i, target := range targets {
+		if i != primary {
+			rest = append(rest, target)
+		}
+	}
+	sort.SliceStable(rest, func(i, j int) bool { return rest[i].Weight > rest[j].Weight })
+
+	candidates := []string{targets[primary].Provider}
+	for _, target := range rest {
+		candidates = append(candidates, target.Provider)
+	}
+	return decision, candidates
+}
+
+// pick returns the index of the target chosen by weight. A payment with an
+// idempotency key always picks the same target, so a retry reaches the
+// provider that may already hold its authorization.
+func (r *Router) pick(targets []Target, key string) int {
+	total := 0
+	for _, target := range targets {
+		total += target.Weight
+	}
+	if total == 0 {
+		return 0
+	}
+
+	var n int
+	if key != "" {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		n = int(h.Sum64() % uint64(total))
+	} else {
+		n = int(r.rand() * float64(total))
+	}
+	for i, target := range targets {
+		if n < target.Weight {
+			return i
+		}
+		n -= target.Weight
+	}
+	return len(targets) - 1
+}
+
+// country returns the BIN country of card, or "" if no prefix is known.
+func (r *Router) country(card string) string {
+	for n := len(card); n > 0; n-- {
+		if country, ok := r.bins[card[:n]]; ok {
+			return country
+		}
+	}
+	return ""
+}
+
+func (rule Rule) matches(req Request, country string) bool {
+	if rule.MinAmount > 0 && req.Amount < rule.MinAmount {
+		return false
+	}
+	if rule.MaxAmount > 0 && req.Amount >= rule.MaxAmount {
+		return false
+	}
+	return matchAny(rule.Currencies, req.Currency) && matchAny(rule.Tenants, req.TenantID) && matchAny(rule.Countries, country)
+}
+
+func matchAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+// Package sepa validates bank account identifiers and reads and writes the
+// ISO 20022 files of SEPA credit transfers: pain.001 customer credit transfer
+// initiations sent to the bank and the pain.002 status reports it returns.
+package sepa
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidIBAN        = errors.New("invalid IBAN")
+	ErrUnsupportedCountry = errors.New("IBAN country not supported")
+	ErrInvalidBIC         = errors.New("invalid BIC")
+)
+
+// bic is the BIC pattern of the ISO 20022 schemas: bank code, ISO 3166
+// country code, location and an optional branch code.
+var bic = regexp.MustCompile(`^[A-Z]{6}[A-Z2-9][A-NP-Z0-9]([A-Z0-9]{3})?$`)
+
+// bbanFormats holds the BBAN structure of the SEPA countries as published in
+// the SWIFT IBAN registry: a sequence of lengths followed by n for digits, a
+// for upper case letters or c for letters and digits. The IBAN adds the
+// country code and two check digits to the BBAN.
+var bbanFormats = map[string]string{
+	"AD": "4n4n12c",
+	"AT": "5n11n",
+	"BE": "3n7n2n",
+	"BG": "4a4n2n8c",
+	"CH": "5n12c",
+	"CY": "3n5n16c",
+	"CZ": "4n6n10n",
+	"DE": "8n10n",
+	"DK": "4n9n1n",
+	"EE": "2n2n11n1n",
+	"ES": "4n4n1n1n10n",
+	"FI": "3n11n",
+	"FR": "5n5n11c2n",
+	"GB": "4a6n8n",
+	"GI": "4a15c",
+	"GR": "3n4n16c",
+	"HR": "7n10n",
+	"HU": "3n4n1n15n1n",
+	"IE": "4a6n8n",
+	"IS": "4n2n6n10n",
+	"IT": "1a5n5n12c",
+	"LI": "5n12c",
+	"LT": "5n11n",
+	"LU": "3n13c",
+	"LV": "4a13c",
+	"MC": "5n5n11c2n",
+	"MT": "4a5n18c",
+	"NL": "4a10n",
+	"NO": "4n6n1n",
+	"PL": "8n16n",
+	"PT": "4n4n11n2n",
+	"RO": "4a16c",
+	"SE": "3n16n1n",
+	"SI": "5n8n2n",
+	"SK": "4n6n10n",
+	"SM": "1a5n5n12c",
+	"VA": "3n15n",
+}
+
+// NormalizeIBAN removes the spaces of the printed form and upper-cases iban.
+func NormalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+}
+
+// ValidateIBAN checks the length and BBAN structure of iban for its country
+// and its ISO 7064 mod 97-10 check digits. It returns the normalized IBAN.
+func ValidateIBAN(iban string) (string, error) {
+	iban = NormalizeIBAN(iban)
+	if len(iban) < 5 {
+		return "", ErrInvalidIBAN
+	}
+	format, ok := bbanFormats[iban[:2]]
+	if !ok {
+		return "", ErrUnsupportedCountry
+	}
+	if !isDigits(iban[2:4]) || !matchBBAN(iban[4:], format) || mod97(iban[4:]+iban[:4]) != 1 {
+		return "", ErrInvalidIBAN
+	}
+	return iban, nil
+}
+
+// IBANLength returns the IBAN length of a supported country, or 0.
+func IBANLength(country string) int {
+	format, ok := bbanFormats[country]
+	if !ok {
+		return 0
+	}
+	n := 4
+	for _, part := range splitFormat(format) {
+		n += part.length
+	}
+	return n
+}
+
+// Country returns the country code of a valid IBAN.
+func Country(iban string) string {
+	if len(iban) < 2 {
+		return ""
+	}
+	return iban[:2]
+}
+
+// ValidateBIC checks the structure of a BIC, also called SWIFT code, and
+// returns it upper-cased.
+func ValidateBIC(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !bic.MatchString(code) {
+		return "", ErrInvalidBIC
+	}
+	return code, nil
+}
+
+type formatPart struct {
+	length int
+	class  byte
+}
+
+func splitFormat(format string) []formatPart {
+	var parts []formatPart
+	start := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] >= '0' && format[i] <= '9' {
+			continue
+		}
+		n, _ := strconv.Atoi(format[start:i])
+		parts = append(parts, formatPart{length: n, class: format[i]})
+		start = i + 1
+	}
+	return parts
+}
+
+func matchBBAN(bban, format string) bool {
+	for _, part := range splitFormat(format) {
+		if len(bban) < part.length {
+			return false
+		}
+		for i := 0; i < part.length; i++ {
+			c := bban[i]
+			digit, letter := c >= '0' && c <= '9', c >= 'A' && c <= 'Z'
+			switch {
+			case part.class == 'n' && !digit,
+				part.class == 'a' && !letter,
+				part.class == 'c' && !digit && !letter:
+				return false
+			}
+		}
+		bban = bban[part.length:]
+	}
+	return bban == ""
+}
+
+// mod97 returns s modulo 97, reading letters as the numbers 10 to 35.
+func mod97(s string) int {
+	r := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+			r = (r*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			r = (r*100 + int(c-'A') + 10) % 97
+		default:
+			return -1
+		}
+	}
+	return r
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,334 @@
+package sepa
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Pain001Namespace is the namespace of the customer credit transfer
+// initiations this package writes, the version of the SEPA rulebook.
+const Pain001Namespace = "urn:iso:std:iso:20022:tech:xsd:pain.001.001.03"
+
+// MaxAmount is the largest amount, in cents, of one SEPA credit transfer.
+const MaxAmount = 99999999999
+
+// notProvided replaces the BIC of a debtor agent that was not configured,
+// as the rulebook allows for IBAN-only transfers.
+const notProvided = "NOTPROVIDED"
+
+var ErrInvalidBatch = errors.New("invalid SEPA credit transfer batch")
+
+// sepaText is the character set of the SEPA rulebook, which every text
+// element of a pain.001 has to keep to.
+var sepaText = regexp.MustCompile(`^[A-Za-z0-9/\-?:().,'+ ]*$`)
+
+// Party is the holder of an account: the debtor paying out or a creditor
+// receiving a transfer. BIC is optional.
+type Party struct {
+	Name string
+	IBAN string
+	BIC  string
+}
+
+// Transfer is one credit transfer of a batch. Amount is in euro cents.
+type Transfer struct {
+	// EndToEndID travels with the transfer to the creditor's bank and is
+	// how status reports refer to it.
+	EndToEndID string
+	Amount     int64
+	Creditor   Party
+	Remittance string
+}
+
+// Batch is a pain.001 message with a single payment information block: the
+// transfers are booked together from the debtor account on ExecutionDate.
+type Batch struct {
+	MessageID     string
+	CreatedAt     time.Time
+	ExecutionDate time.Time
+	Debtor        Party
+	Transfers     []Transfer
+}
+
+// Total returns the sum of the batch's transfers in cents.
+func (b Batch) Total() int64 {
+	var total int64
+	for _, t := range b.Transfers {
+		total += t.Amount
+	}
+	return total
+}
+
+// Validate checks the batch against the facets of the pain.001.001.03 XSD
+// and the SEPA rulebook: identifier and text lengths, the SEPA character
+// set, amount bounds, account identifiers and at least one transfer. Every
+// violation is listed in the returned error.
+func (b Batch) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	checkID := func(field, id string) {
+		switch {
+		case id == "" || len(id) > 35:
+			add("%s must be 1 to 35 characters", field)
+		case !sepaText.MatchString(id):
+			add("%s has characters outside the SEPA character set", field)
+		case strings.HasPrefix(id, "/") || strings.HasSuffix(id, "/") || strings.Contains(id, "//"):
+			add("%s must not start or end with / or contain //", field)
+		}
+	}
+	checkText := func(field, text string, max int, required bool) {
+		switch {
+		case text == "" && required:
+			add("%s is required", field)
+		case len(text) > max:
+			add("%s must be at most %d characters", field, max)
+		case !sepaText.MatchString(text):
+			add("%s has characters outside the SEPA character set", field)
+		}
+	}
+	checkParty := func(field string, p Party) {
+		checkText(field+" name", p.Name, 70, true)
+		if _, err := ValidateIBAN(p.IBAN); err != nil {
+			add("%s IBAN: %v", field, err)
+		}
+		if p.BIC != "" {
+			if _, err := ValidateBIC(p.BIC); err != nil {
+				add("%s BIC: %v", field, err)
+			}
+		}
+	}
+
+	checkID("message id", b.MessageID)
+	if b.CreatedAt.IsZero() {
+		add("creation time is required")
+	}
+	if b.ExecutionDate.IsZero() {
+		add("execution date is required")
+	}
+	checkParty("debtor", b.Debtor)
+	if len(b.Transfers) == 0 {
+		add("a batch needs at least one transfer")
+	}
+	seen := make(map[string]bool, len(b.Transfers))
+	for i, t := range b.Transfers {
+		field := fmt.Sprintf("transfer %d", i+1)
+		checkID(field+" end-to-end id", t.EndToEndID)
+		if seen[t.EndToEndID] {
+			add("%s end-to-end id %s is not unique", field, t.EndToEndID)
+		}
+		seen[t.EndToEndID] = true
+		if t.Amount < 1 || t.Amount > MaxAmount {
+			add("%s amount must be between 0.01 and 999999999.99", field)
+		}
+		checkParty(field+" creditor", t.Creditor)
+		checkText(field+" remittance", t.Remittance, 140, false)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidBatch, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Pain001 validates the batch and encodes it as a pain.001.001.03 document.
+func (b Batch) Pain001() ([]byte, error) {
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+
+	count, total := fmt.Sprint(len(b.Transfers)), formatAmount(b.Total())
+	info := paymentInformation{
+		ID:            b.MessageID,
+		Method:        "TRF",
+		BatchBooking:  true,
+		Transactions:  count,
+		ControlSum:    total,
+		ServiceLevel:  "SEPA",
+		ExecutionDate: b.ExecutionDate.Format("2006-01-02"),
+		Debtor:        partyIdentification{Name: b.Debtor.Name},
+		DebtorAccount: account{IBAN: NormalizeIBAN(b.Debtor.IBAN)},
+		DebtorAgent:   agentOf(b.Debtor.BIC),
+		ChargeBearer:  "SLEV",
+	}
+	if info.DebtorAgent == nil {
+		info.DebtorAgent = &agent{Other: notProvided}
+	}
+	for _, t := range b.Transfers {
+		tx := creditTransfer{
+			EndToEndID:      t.EndToEndID,
+			Amount:          instructedAmount{Currency: "EUR", Value: formatAmount(t.Amount)},
+			CreditorAgent:   agentOf(t.Creditor.BIC),
+			Creditor:        partyIdentification{Name: t.Creditor.Name},
+			CreditorAccount: account{IBAN: NormalizeIBAN(t.Creditor.IBAN)},
+		}
+		if t.Remittance != "" {
+			tx.Remittance = &remittance{Unstructured: t.Remittance}
+		}
+		info.Transfers = append(info.Transfers, tx)
+	}
+
+	doc := pain001Document{
+		Namespace: Pain001Namespace,
+		Initiation: initiation{
+			Header: groupHeader{
+				MessageID:    b.MessageID,
+				CreatedAt:    b.CreatedAt.UTC().Format("2006-01-02T15:04:05"),
+				Transactions: count,
+				ControlSum:   total,
+				Initiator:    partyIdentification{Name: b.Debtor.Name},
+			},
+			Payments: []paymentInformation{info},
+		},
+	}
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
+
+// RedactCreditors blanks the creditor name, IBAN and BIC of the transfers
+// with the given end-to-end ids in a document written by Pain001, for
+// keeping a file already handed to the bank without the personal data of
+// an erased account holder. Amounts, ids and remittance text stay.
+func RedactCreditors(document []byte, endToEndIDs []string) ([]byte, error) {
+	var doc pain001Document
+	if err := xml.Unmarshal(document, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBatch, err)
+	}
+	redact := make(map[string]bool, len(endToEndIDs))
+	for _, id := range endToEndIDs {
+		redact[id] = true
+	}
+	for i := range doc.Initiation.Payments {
+		transfers := doc.Initiation.Payments[i].Transfers
+		for j := range transfers {
+			if redact[transfers[j].EndToEndID] {
+				transfers[j].CreditorAgent = nil
+				transfers[j].Creditor = partyIdentification{}
+				transfers[j].CreditorAccount = account{}
+			}
+		}
+	}
+
+	// The namespace is written by the attribute, not the element name.
+	doc.XMLName = xml.Name{}
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
+
+// Text fits s into a SEPA text element of at most max characters: letters
+// with diacritics lose them, and other characters outside the SEPA set
+// become spaces.
+func Text(s string, max int) string {
+	var b strings.Builder
+	for _, r := range s {
+		if t, ok := transliterations[r]; ok {
+			b.WriteString(t)
+			continue
+		}
+		if r < 0x80 && sepaText.MatchString(string(r)) {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	text := strings.Join(strings.Fields(b.String()), " ")
+	if len(text) > max {
+		text = strings.TrimSpace(text[:max])
+	}
+	return text
+}
+
+var transliterations = map[rune]string{
+	'Ä': "Ae", 'Ö': "Oe", 'Ü': "Ue", 'ä': "ae", 'ö': "oe", 'ü': "ue", 'ß': "ss",
+	'À': "A", 'Á': "A", 'Â': "A", 'Ã': "A", 'Å': "A", 'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'å': "a",
+	'Ç': "C", 'ç': "c", 'È': "E", 'É': "E", 'Ê': "E", 'Ë': "E", 'è': "e", 'é': "e", 'ê': "e", 'ë': "e",
+	'Ì': "I", 'Í': "I", 'Î': "I", 'Ï': "I", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i",
+	'Ñ': "N", 'ñ': "n", 'Ò': "O", 'Ó': "O", 'Ô': "O", 'Õ': "O", 'Ø': "O", 'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ø': "o",
+	'Ù': "U", 'Ú': "U", 'Û': "U", 'ù': "u", 'ú': "u", 'û': "u", 'Ý': "Y", 'ý': "y", 'ÿ': "y",
+}
+
+func formatAmount(cents int64) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
+
+func agentOf(bic string) *agent {
+	if bic == "" {
+		return nil
+	}
+	return &agent{BIC: bic}
+}
+
+type pain001Document struct {
+	XMLName    xml.Name   `xml:"Document"`
+	Namespace  string     `xml:"xmlns,attr"`
+	Initiation initiation `xml:"CstmrCdtTrfInitn"`
+}
+
+type initiation struct {
+	Header   groupHeader          `xml:"GrpHdr"`
+	Payments []paymentInformation `xml:"PmtInf"`
+}
+
+type groupHeader struct {
+	MessageID    string              `xml:"MsgId"`
+	CreatedAt    string              `xml:"CreDtTm"`
+	Transactions string              `xml:"NbOfTxs"`
+	ControlSum   string              `xml:"CtrlSum"`
+	Initiator    partyIdentification `xml:"InitgPty"`
+}
+
+type paymentInformation struct {
+	ID            string              `xml:"PmtInfId"`
+	Method        string              `xml:"PmtMtd"`
+	BatchBooking  bool                `xml:"BtchBookg"`
+	Transactions  string              `xml:"NbOfTxs"`
+	ControlSum    string              `xml:"CtrlSum"`
+	ServiceLevel  string              `xml:"PmtTpInf>SvcLvl>Cd"`
+	ExecutionDate string              `xml:"ReqdExctnDt"`
+	Debtor        partyIdentification `xml:"Dbtr"`
+	DebtorAccount account             `xml:"DbtrAcct"`
+	DebtorAgent   *agent              `xml:"DbtrAgt"`
+	ChargeBearer  string              `xml:"ChrgBr"`
+	Transfers     []creditTransfer    `xml:"CdtTrfTxInf"`
+}
+
+type creditTransfer struct {
+	EndToEndID      string              `xml:"PmtId>EndToEndId"`
+	Amount          instructedAmount    `xml:"Amt>InstdAmt"`
+	CreditorAgent   *agent              `xml:"CdtrAgt,omitempty"`
+	Creditor        partyIdentification `xml:"Cdtr"`
+	CreditorAccount account             `xml:"CdtrAcct"`
+	Remittance      *remittance         `xml:"RmtInf,omitempty"`
+}
+
+type partyIdentification struct {
+	Name string `xml:"Nm"`
+}
+
+type account struct {
+	IBAN string `xml:"Id>IBAN"`
+}
+
+type agent struct {
+	BIC   string `xml:"FinInstnId>BIC,omitempty"`
+	Other string `xml:"FinInstnId>Othr>Id,omitempty"`
+}
+
+type instructedAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type remittance struct {
+	Unstructured string `xml:"Ustrd"`
+}
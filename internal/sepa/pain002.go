@@ -0,0 +1,133 @@
+package sepa
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Transaction statuses of a pain.002 report, from the ISO 20022 external
+// code list. Accepted* codes are interim steps; only settlement (ACSC) and
+// rejection (RJCT) are final.
+const (
+	StatusAcceptedTechnical  = "ACTC"
+	StatusAccepted           = "ACCP"
+	StatusAcceptedProcessing = "ACSP"
+	StatusAcceptedSettlement = "ACSC"
+	StatusPending            = "PDNG"
+	StatusRejected           = "RJCT"
+	// StatusPartiallyAccepted only appears as a group status.
+	StatusPartiallyAccepted = "PART"
+)
+
+var ErrInvalidReport = errors.New("invalid pain.002 status report")
+
+// StatusReport is a pain.002 customer payment status report on one pain.001
+// message. A group status of ACCP, ACSC or RJCT without transaction
+// statuses applies to every transfer of the message.
+type StatusReport struct {
+	MessageID         string
+	OriginalMessageID string
+	GroupStatus       string
+	GroupReason       string
+	Transactions      []TransactionStatus
+}
+
+// TransactionStatus is the bank's status of one transfer. Reason is the
+// ISO 20022 status reason code, such as AC01 for an incorrect account
+// number, and is usually only set on rejections.
+type TransactionStatus struct {
+	EndToEndID string
+	Status     string
+	Reason     string
+}
+
+// ParsePain002 reads a pain.002 status report of any 001.001.xx version.
+func ParsePain002(data []byte) (*StatusReport, error) {
+	var doc pain002Document
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReport, err)
+	}
+	if !strings.HasPrefix(doc.XMLName.Space, "urn:iso:std:iso:20022:tech:xsd:pain.002.") {
+		return nil, fmt.Errorf("%w: unexpected namespace %q", ErrInvalidReport, doc.XMLName.Space)
+	}
+
+	r := doc.Report
+	report := &StatusReport{
+		MessageID:         strings.TrimSpace(r.Header.MessageID),
+		OriginalMessageID: strings.TrimSpace(r.Group.OriginalMessageID),
+		GroupStatus:       strings.TrimSpace(r.Group.Status),
+		GroupReason:       r.Group.Reason.code(),
+	}
+	if report.OriginalMessageID == "" {
+		return nil, fmt.Errorf("%w: missing OrgnlMsgId", ErrInvalidReport)
+	}
+	for _, payment := range r.Payments {
+		for _, tx := range payment.Transactions {
+			status := strings.TrimSpace(tx.Status)
+			if status == "" {
+				status = strings.TrimSpace(payment.Status)
+			}
+			reason := tx.Reason.code()
+			if reason == "" {
+				reason = payment.Reason.code()
+			}
+			report.Transactions = append(report.Transactions, TransactionStatus{
+				EndToEndID: strings.TrimSpace(tx.OriginalEndToEndID),
+				Status:     status,
+				Reason:     reason,
+			})
+		}
+	}
+	if len(report.Transactions) == 0 && report.GroupStatus == "" {
+		return nil, fmt.Errorf("%w: no group or transaction status", ErrInvalidReport)
+	}
+	return report, nil
+}
+
+type pain002Document struct {
+	XMLName xml.Name     `xml:"Document"`
+	Report  statusReport `xml:"CstmrPmtStsRpt"`
+}
+
+type statusReport struct {
+	Header struct {
+		MessageID string `xml:"MsgId"`
+	} `xml:"GrpHdr"`
+	Group struct {
+		OriginalMessageID string        `xml:"OrgnlMsgId"`
+		Status            string        `xml:"GrpSts"`
+		Reason            statusReasons `xml:"StsRsnInf"`
+	} `xml:"OrgnlGrpInfAndSts"`
+	Payments []struct {
+		Status       string        `xml:"PmtInfSts"`
+		Reason       statusReasons `xml:"StsRsnInf"`
+		Transactions []struct {
+			OriginalEndToEndID string        `xml:"OrgnlEndToEndId"`
+			Status             string        `xml:"TxSts"`
+			Reason             statusReasons `xml:"StsRsnInf"`
+		} `xml:"TxInfAndSts"`
+	} `xml:"OrgnlPmtInfAndSts"`
+}
+
+type statusReasons []struct {
+	Code        string `xml:"Rsn>Cd"`
+	Proprietary string `xml:"Rsn>Prtry"`
+}
+
+// code returns the first reason code, preferring the ISO code over a
+// proprietary one.
+func (r statusReasons) code() string {
+	for _, reason := range r {
+		if code := strings.TrimSpace(reason.Code); code != "" {
+			return code
+		}
+		if code := strings.TrimSpace(reason.Proprietary); code != "" {
+			return code
+		}
+	}
+	return ""
+}
@@ -0,0 +1,121 @@
+package settlement
+
+// Statuses of a checked settlement line.
+const (
+	// Matched lines agree with their payment and the contracted fee.
+	Matched = "matched"
+	// Unexpected lines name no payment of the provider.
+	Unexpected = "unexpected"
+	// Missing lines stand for payments the provider reported settled that
+	// the file does not list.
+	Missing = "missing"
+	// Duplicated lines settle a charge an earlier line or batch already
+	// settled, or repeat a refund line of the same file.
+	Duplicated = "duplicated"
+	// AmountMismatch lines differ from the payment's amount, or refund more
+	// than was refunded, or are in another currency.
+	AmountMismatch = "amount_mismatch"
+	// FeeDeviation lines charge a fee other than the contracted one.
+	FeeDeviation = "fee_deviation"
+)
+
+// Payment is a payment of the provider a settlement file may list. Amounts
+// are in minor units.
+type Payment struct {
+	ID       int64
+	Ref      string
+	Amount   int64
+	Refunded int64
+	Currency string
+	// Expected marks payments the provider reported settled within the
+	// period the file covers; a file without them misses them.
+	Expected bool
+	// Settled marks payments whose charge an earlier batch listed.
+	Settled bool
+}
+
+// Result is the outcome for one line. Payment is nil for unexpected lines;
+// ExpectedFee is the contracted fee for charges and the line's own fee
+// otherwise.
+type Result struct {
+	Status      string
+	Payment     *Payment
+	ExpectedFee int64
+}
+
+// Check links each line to its payment, by payment id when the line has
+// one and by provider reference otherwise, and flags the discrepancies.
+// Fees of charges must be within tolerance minor units of the rate;
+// refunds are not checked against it. Expected payments no charge line
+// settled, in this file or earlier, come back as missing.
+func Check(lines []Line, payments []Payment, rate Rate, tolerance int64) ([]Result, []Payment) {
+	byID := make(map[int64]*Payment, len(payments))
+	byRef := make(map[string]*Payment, len(payments))
+	for i := range payments {
+		p := &payments[i]
+		byID[p.ID] = p
+		if p.Ref != "" {
+			byRef[p.Ref] = p
+		}
+	}
+
+	results := make([]Result, len(lines))
+	charged := make(map[int64]bool)
+	type refund struct {
+		payment int64
+		gross   int64
+	}
+	refunded := make(map[refund]bool)
+	for i, l := range lines {
+		r := &results[i]
+		r.ExpectedFee = l.Fee
+		if l.Type == TypeCharge {
+			r.ExpectedFee = rate.Fee(l.Gross)
+		}
+
+		if l.PaymentID != 0 {
+			r.Payment = byID[l.PaymentID]
+		} else {
+			r.Payment = byRef[l.Reference]
+		}
+		p := r.Payment
+		switch {
+		case p == nil:
+			r.Status = Unexpected
+		case l.Type == TypeCharge && (p.Settled || charged[p.ID]):
+			r.Status = Duplicated
+		case l.Type == TypeRefund && refunded[refund{p.ID, l.Gross}]:
+			r.Status = Duplicated
+		case l.Currency != p.Currency,
+			l.Type == TypeCharge && l.Gross != p.Amount,
+			l.Type == TypeRefund && -l.Gross > p.Refunded:
+			r.Status = AmountMismatch
+		case abs(l.Fee-r.ExpectedFee) > tolerance:
+			r.Status = FeeDeviation
+		default:
+			r.Status = Matched
+		}
+		if p != nil {
+			if l.Type == TypeCharge {
+				charged[p.ID] = true
+			} else {
+				refunded[refund{p.ID, l.Gross}] = true
+			}
+		}
+	}
+
+	var missing []Payment
+	for _, p := range payments {
+		if p.Expected && !p.Settled && !charged[p.ID] {
+			missing = append(missing, p)
+		}
+	}
+	return results, missing
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
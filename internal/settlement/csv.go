@@ -0,0 +1,197 @@
+package settlement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Fields a CSV column can be mapped to.
+const (
+	FieldReference = "reference"
+	FieldPaymentID = "payment_id"
+	FieldType      = "type"
+	FieldGross     = "gross"
+	FieldFee       = "fee"
+	FieldNet       = "net"
+	FieldCurrency  = "currency"
+	FieldDate      = "date"
+)
+
+var csvFields = []string{FieldReference, FieldPaymentID, FieldType, FieldGross, FieldFee, FieldNet, FieldCurrency, FieldDate}
+
+// defaultTypes covers the type names providers commonly use.
+var defaultTypes = map[string]string{
+	"charge":  TypeCharge,
+	"payment": TypeCharge,
+	"sale":    TypeCharge,
+	"capture": TypeCharge,
+	"refund":  TypeRefund,
+}
+
+// csvParser reads files with a header row. Fields a file has no column for
+// keep their zero value: without a type column a negative gross makes a
+// refund, without a net column the net is computed.
+type csvParser struct {
+	comma      rune
+	dateFormat string
+	columns    map[string]string
+	types      map[string]string
+}
+
+func newCSVParser(opts Options) (Parser, error) {
+	p := &csvParser{comma: ',', dateFormat: "2006-01-02", columns: map[string]string{}, types: defaultTypes}
+	if opts.Delimiter != "" {
+		r, size := utf8.DecodeRuneInString(opts.Delimiter)
+		if size != len(opts.Delimiter) || r == '"' || r == '\n' || r == '\r' {
+			return nil, fmt.Errorf("invalid CSV delimiter %q", opts.Delimiter)
+		}
+		p.comma = r
+	}
+	if opts.DateFormat != "" {
+		p.dateFormat = opts.DateFormat
+	}
+	for _, field := range csvFields {
+		p.columns[field] = field
+	}
+	for field, column := range opts.Columns {
+		if _, ok := p.columns[field]; !ok {
+			return nil, fmt.Errorf("unknown settlement field %q", field)
+		}
+		p.columns[field] = column
+	}
+	if len(opts.Types) > 0 {
+		p.types = map[string]string{}
+		for name, typ := range opts.Types {
+			if typ != TypeCharge && typ != TypeRefund {
+				return nil, fmt.Errorf("settlement type %q must map to charge or refund", name)
+			}
+			p.types[strings.ToLower(name)] = typ
+		}
+	}
+	return p, nil
+}
+
+func (p *csvParser) Parse(data []byte) ([]Line, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	r.Comma = p.comma
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: empty file", ErrInvalidReport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReport, err)
+	}
+
+	index := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for field, column := range p.columns {
+			if strings.EqualFold(column, name) {
+				index[field] = i
+			}
+		}
+	}
+	for _, field := range []string{FieldGross, FieldFee, FieldCurrency} {
+		if _, ok := index[field]; !ok {
+			return nil, fmt.Errorf("%w: no %q column for %s", ErrInvalidReport, p.columns[field], field)
+		}
+	}
+	_, hasRef := index[FieldReference]
+	_, hasID := index[FieldPaymentID]
+	if !hasRef && !hasID {
+		return nil, fmt.Errorf("%w: no column for reference or payment_id", ErrInvalidReport)
+	}
+
+	var lines []Line
+	for row := 2; ; row++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidReport, err)
+		}
+		value := func(field string) string {
+			if i, ok := index[field]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		line, skip, err := p.line(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w (row %d)", err, row)
+		}
+		if skip {
+			continue
+		}
+		line.Number = len(lines) + 1
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// line converts one record; skip is set for transaction types that are
+// neither charges nor refunds, such as payouts or balance adjustments.
+func (p *csvParser) line(value func(string) string) (line Line, skip bool, err error) {
+	if line.Gross, err = parseAmount(value(FieldGross)); err != nil {
+		return line, false, err
+	}
+	if line.Fee, err = parseAmount(value(FieldFee)); err != nil {
+		return line, false, err
+	}
+
+	line.Type = TypeCharge
+	if typ := value(FieldType); typ != "" {
+		var ok bool
+		if line.Type, ok = p.types[strings.ToLower(typ)]; !ok {
+			return line, true, nil
+		}
+	} else if line.Gross < 0 {
+		line.Type = TypeRefund
+	}
+	// Files differ in how they sign refunds; they always reduce the payout.
+	if line.Type == TypeRefund && line.Gross > 0 || line.Type == TypeCharge && line.Gross < 0 {
+		line.Gross = -line.Gross
+	}
+	if line.Fee < 0 {
+		line.Fee = -line.Fee
+	}
+	line.Net = line.Gross - line.Fee
+	if s := value(FieldNet); s != "" {
+		net, err := parseAmount(s)
+		if err != nil {
+			return line, false, err
+		}
+		if net != line.Net {
+			return line, false, fmt.Errorf("%w: net %s is not gross %s minus fee %s", ErrInvalidReport, s, value(FieldGross), value(FieldFee))
+		}
+	}
+
+	line.Reference = value(FieldReference)
+	if s := value(FieldPaymentID); s != "" {
+		if line.PaymentID, err = strconv.ParseInt(s, 10, 64); err != nil || line.PaymentID < 1 {
+			return line, false, fmt.Errorf("%w: invalid payment id %q", ErrInvalidReport, s)
+		}
+	}
+	if line.Reference == "" && line.PaymentID == 0 {
+		return line, false, fmt.Errorf("%w: line without reference", ErrInvalidReport)
+	}
+	line.Currency = strings.ToUpper(value(FieldCurrency))
+	if len(line.Currency) != 3 {
+		return line, false, fmt.Errorf("%w: invalid currency %q", ErrInvalidReport, value(FieldCurrency))
+	}
+	if s := value(FieldDate); s != "" {
+		if line.Date, err = time.Parse(p.dateFormat, s); err != nil {
+			return line, false, fmt.Errorf("%w: invalid date %q", ErrInvalidReport, s)
+		}
+	}
+	return line, false, nil
+}
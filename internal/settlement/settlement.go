@@ -0,0 +1,149 @@
+// Package settlement reads the settlement files payment providers send
+// with their payouts and checks them against the payments they list. Each
+// provider's file layout is described by a Parser; CSV files with
+// configurable columns are supported out of the box and other formats can
+// be registered.
+package settlement
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Line types. Charges settle captured payments, refunds pay money back.
+const (
+	TypeCharge = "charge"
+	TypeRefund = "refund"
+)
+
+var (
+	ErrInvalidReport = errors.New("invalid settlement report")
+	ErrUnknownFormat = errors.New("unknown settlement file format")
+)
+
+// Line is one transaction of a settlement file. Amounts are in minor units:
+// Gross is positive for charges and negative for refunds, Fee is what the
+// provider kept and Net = Gross - Fee is what it paid out.
+type Line struct {
+	// Number is the line's position among the file's transactions,
+	// starting at 1.
+	Number    int
+	Type      string
+	Reference string
+	// PaymentID is set when the file carries our payment id, which takes
+	// precedence over Reference.
+	PaymentID int64
+	Gross     int64
+	Fee       int64
+	Net       int64
+	Currency  string
+	Date      time.Time
+}
+
+// Parser reads the transactions of one provider's settlement file.
+type Parser interface {
+	Parse(data []byte) ([]Line, error)
+}
+
+// Options describe a file layout to a parser factory. Columns maps the
+// fields reference, payment_id, type, gross, fee, net, currency and date to
+// the file's own column names; Types maps the file's transaction types to
+// charge or refund, and lines of types it leaves out are skipped.
+type Options struct {
+	Delimiter  string
+	DateFormat string
+	Columns    map[string]string
+	Types      map[string]string
+}
+
+// Factory builds a parser for a layout.
+type Factory func(opts Options) (Parser, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{"csv": newCSVParser}
+)
+
+// Register makes a file format available to NewParser.
+func Register(format string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[format] = f
+}
+
+// Formats lists the registered file formats.
+func Formats() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	formats := make([]string, 0, len(factories))
+	for format := range factories {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// NewParser returns a parser of the given format, csv when empty.
+func NewParser(format string, opts Options) (Parser, error) {
+	if format == "" {
+		format = "csv"
+	}
+	mu.RLock()
+	f, ok := factories[format]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+	return f(opts)
+}
+
+// Rate is the fee contracted with a provider for a charge: Percent of the
+// gross amount, rounded half away from zero to minor units, plus Fixed
+// minor units.
+type Rate struct {
+	Percent float64
+	Fixed   int64
+}
+
+// Fee returns the contracted fee on a charge of gross minor units.
+func (r Rate) Fee(gross int64) int64 {
+	return int64(math.Round(float64(gross)*r.Percent/100)) + r.Fixed
+}
+
+// parseAmount converts a decimal amount with an optional sign to minor
+// units without going through floating point.
+func parseAmount(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("%w: empty amount", ErrInvalidReport)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	if len(frac) > 2 {
+		return 0, fmt.Errorf("%w: amount %q has more than two decimals", ErrInvalidReport, s)
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+	units, err := strconv.ParseUint(whole, 10, 62)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid amount %q", ErrInvalidReport, s)
+	}
+	cents, err := strconv.ParseUint(frac, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid amount %q", ErrInvalidReport, s)
+	}
+	amount := int64(units)*100 + int64(cents)
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
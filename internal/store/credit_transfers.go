@@ -0,0 +1,403 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/money"
+	"go-lang-final/internal/sepa"
+	"go-lang-final/internal/tenant"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrBankAccountNotFound    = errors.New("bank account not found")
+	ErrCurrencyNotSEPA        = errors.New("bank transfers are only available in EUR")
+	ErrCreditTransferNotFound = errors.New("credit transfer not found")
+	ErrBatchNotFound          = errors.New("credit transfer batch not found")
+	ErrNoPendingTransfers     = errors.New("no pending credit transfers")
+)
+
+// Prefixes of the ids of credit transfers and their batches, which become
+// the end-to-end and message ids of pain.001 files. The SEPA character set
+// has no underscore, so unlike tokens they are joined with a hyphen.
+const (
+	creditTransferPrefix = "ct-"
+	batchPrefix          = "ctb-"
+)
+
+// maxBatchTransfers caps the transfers of one pain.001 file; the rest wait
+// for the next batch.
+const maxBatchTransfers = 5000
+
+// A BatchFile writes the pain.001 file of a batch with message id id. It
+// runs while the transfers are locked, and an error leaves them pending.
+type BatchFile func(id string, transfers []sepa.Transfer) ([]byte, error)
+
+// CreateBankAccount stores a validated bank account under a new token.
+func (s *PaymentStore) CreateBankAccount(ctx context.Context, account models.BankAccount, iban string) (*models.BankAccount, error) {
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if account.Token, err = newID(models.BankAccountPrefix); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO bank_accounts (tenant_id, token, holder_name, iban, bic) VALUES ($1, $2, $3, $4, $5) RETURNING tenant_id, created_at`
+		return tx.QueryRowContext(ctx, query, tenantID, account.Token, account.HolderName, iban, account.BIC).Scan(&account.TenantID, &account.CreatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	account.Country, account.Last4 = sepa.Country(iban), last4(iban)
+	logging.FromContext(ctx).WithField("bank_account", account.Token).Debug("bank account created")
+	return &account, nil
+}
+
+// GetBankAccount returns what is safe to show of a bank account.
+func (s *PaymentStore) GetBankAccount(ctx context.Context, token string) (*models.BankAccount, error) {
+	var (
+		account models.BankAccount
+		iban    string
+	)
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT token, holder_name, iban, bic, tenant_id, created_at FROM bank_accounts WHERE tenant_id = $1 AND token = $2`
+		err = tx.QueryRowContext(ctx, query, tenantID, token).Scan(&account.Token, &account.HolderName, &iban, &account.BIC, &account.TenantID, &account.CreatedAt)
+		if err == sql.ErrNoRows {
+			return ErrBankAccountNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	account.Country, account.Last4 = sepa.Country(iban), last4(iban)
+	return &account, nil
+}
+
+// checkBankAccount makes sure a payment by bank transfer comes from an
+// account of its own tenant and is in euros.
+func checkBankAccount(ctx context.Context, tx *sql.Tx, tenantID string, payment models.Payment) error {
+	if payment.Currency != "EUR" {
+		return ErrCurrencyNotSEPA
+	}
+	var found int
+	err := tx.QueryRowContext(ctx, `SELECT 1 FROM bank_accounts WHERE tenant_id = $1 AND token = $2`, tenantID, payment.PaymentMethod).Scan(&found)
+	if err == sql.ErrNoRows {
+		return ErrBankAccountNotFound
+	}
+	return err
+}
+
+// CreatePayout queues a payout to a bank account for the next batch.
+func (s *PaymentStore) CreatePayout(ctx context.Context, transfer models.CreditTransfer) (*models.CreditTransfer, error) {
+	if transfer.Currency != "EUR" {
+		return nil, ErrCurrencyNotSEPA
+	}
+	transfer.Kind = models.TransferPayout
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		var found int
+		err = tx.QueryRowContext(ctx, `SELECT 1 FROM bank_accounts WHERE tenant_id = $1 AND token = $2`, tenantID, transfer.BankAccount).Scan(&found)
+		if err == sql.ErrNoRows {
+			return ErrBankAccountNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return queueTransfer(ctx, tx, tenantID, &transfer)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"credit_transfer": transfer.ID,
+		"amount":          transfer.Amount,
+	}).Debug("payout queued")
+	return &transfer, nil
+}
+
+// queueTransfer inserts a pending credit transfer and fills in its id,
+// status, tenant and creation time.
+func queueTransfer(ctx context.Context, tx *sql.Tx, tenantID string, transfer *models.CreditTransfer) error {
+	id, err := newID(creditTransferPrefix)
+	if err != nil {
+		return err
+	}
+	var paymentID sql.NullInt64
+	if transfer.PaymentID != 0 {
+		paymentID = sql.NullInt64{Int64: transfer.PaymentID, Valid: true}
+	}
+
+	query := `INSERT INTO credit_transfers (tenant_id, id, bank_account, payment_id, kind, amount, currency, remittance) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, status, tenant_id, created_at`
+	row := tx.QueryRowContext(ctx, query, tenantID, id, transfer.BankAccount, paymentID, transfer.Kind, transfer.Amount, transfer.Currency, transfer.Remittance)
+	return row.Scan(&transfer.ID, &transfer.Status, &transfer.TenantID, &transfer.CreatedAt)
+}
+
+// queueRefund sends the refund of a payment made by bank transfer back to
+// the account it came from.
+func queueRefund(ctx context.Context, tx *sql.Tx, tenantID string, payment models.Payment, amount float64, reason string) error {
+	remittance := fmt.Sprintf("Refund of payment %d", payment.ID)
+	if reason != "" {
+		remittance += " " + reason
+	}
+	return queueTransfer(ctx, tx, tenantID, &models.CreditTransfer{
+		BankAccount: payment.PaymentMethod,
+		PaymentID:   payment.ID,
+		Kind:        models.TransferRefund,
+		Amount:      amount,
+		Currency:    payment.Currency,
+		Remittance:  sepa.Text(remittance, 140),
+	})
+}
+
+func (s *PaymentStore) GetCreditTransfer(ctx context.Context, id string) (*models.CreditTransfer, error) {
+	var transfer models.CreditTransfer
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		var paymentID sql.NullInt64
+		var batchID sql.NullString
+		query := `SELECT id, bank_account, payment_id, kind, amount, currency, remittance, status, reason, batch_id, tenant_id, created_at FROM credit_transfers WHERE tenant_id = $1 AND id = $2`
+		err = tx.QueryRowContext(ctx, query, tenantID, id).Scan(&transfer.ID, &transfer.BankAccount, &paymentID, &transfer.Kind, &transfer.Amount, &transfer.Currency, &transfer.Remittance, &transfer.Status, &transfer.Reason, &batchID, &transfer.TenantID, &transfer.CreatedAt)
+		if err == sql.ErrNoRows {
+			return ErrCreditTransferNotFound
+		}
+		transfer.PaymentID, transfer.BatchID = paymentID.Int64, batchID.String
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// CreateCreditTransferBatch puts the pending credit transfers of the tenant
+// in scope, oldest first, into a batch executed on executionDate and marks
+// them submitted. file writes the pain.001 document stored with the batch.
+// Transfers locked by a concurrent batch are skipped, so no transfer ends
+// up in two files.
+func (s *PaymentStore) CreateCreditTransferBatch(ctx context.Context, executionDate time.Time, file BatchFile) (*models.CreditTransferBatch, error) {
+	var batch models.CreditTransferBatch
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT t.id, t.amount, t.remittance, a.holder_name, a.iban, a.bic FROM credit_transfers t JOIN bank_accounts a ON a.tenant_id = t.tenant_id AND a.token = t.bank_account
+			WHERE t.tenant_id = $1 AND t.status = 'pending' ORDER BY t.created_at, t.id LIMIT $2 FOR UPDATE OF t SKIP LOCKED`
+		rows, err := tx.QueryContext(ctx, query, tenantID, maxBatchTransfers)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		var (
+			transfers []sepa.Transfer
+			ids       []string
+			total     int64
+		)
+		for rows.Next() {
+			var (
+				t      sepa.Transfer
+				amount float64
+			)
+			if err := rows.Scan(&t.EndToEndID, &amount, &t.Remittance, &t.Creditor.Name, &t.Creditor.IBAN, &t.Creditor.BIC); err != nil {
+				return err
+			}
+			t.Amount = money.Minor(amount)
+			t.Creditor.Name = sepa.Text(t.Creditor.Name, 70)
+			transfers = append(transfers, t)
+			ids = append(ids, t.EndToEndID)
+			total += t.Amount
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(transfers) == 0 {
+			return ErrNoPendingTransfers
+		}
+
+		id, err := newID(batchPrefix)
+		if err != nil {
+			return err
+		}
+		document, err := file(id, transfers)
+		if err != nil {
+			return err
+		}
+		query = `INSERT INTO credit_transfer_batches (tenant_id, id, execution_date, transfers, total, document) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, transfers, total, execution_date, bank_status, tenant_id, created_at`
+		row := tx.QueryRowContext(ctx, query, tenantID, id, executionDate.Format("2006-01-02"), len(transfers), money.Major(total), document)
+		if err := scanBatch(row, &batch); err != nil {
+			return err
+		}
+		query = `UPDATE credit_transfers SET status = 'submitted', batch_id = $2 WHERE tenant_id = $1 AND id = ANY($3)`
+		_, err = tx.ExecContext(ctx, query, tenantID, id, pq.Array(ids))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"batch_id":  batch.ID,
+		"transfers": batch.Transfers,
+	}).Info("credit transfer batch created")
+	return &batch, nil
+}
+
+// GetCreditTransferBatch returns a batch and its pain.001 document.
+func (s *PaymentStore) GetCreditTransferBatch(ctx context.Context, id string) (*models.CreditTransferBatch, []byte, error) {
+	var (
+		batch    models.CreditTransferBatch
+		document []byte
+	)
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT id, transfers, total, execution_date, bank_status, tenant_id, created_at, document FROM credit_transfer_batches WHERE tenant_id = $1 AND id = $2`
+		var executionDate time.Time
+		err = tx.QueryRowContext(ctx, query, tenantID, id).Scan(&batch.ID, &batch.Transfers, &batch.Total, &executionDate, &batch.BankStatus, &batch.TenantID, &batch.CreatedAt, &document)
+		if err == sql.ErrNoRows {
+			return ErrBatchNotFound
+		}
+		batch.ExecutionDate = executionDate.Format("2006-01-02")
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &batch, document, nil
+}
+
+// ApplyStatusReport records a pain.002 report on one of the tenant's
+// batches and returns the batch and how many transfers changed. A group
+// status without transaction statuses applies to every transfer of the
+// batch. Settled and rejected transfers are final and pending statuses
+// change nothing, so reports can be imported in any order and more than
+// once.
+func (s *PaymentStore) ApplyStatusReport(ctx context.Context, report *sepa.StatusReport) (*models.CreditTransferBatch, int, error) {
+	var (
+		batch   models.CreditTransferBatch
+		updated int
+	)
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `UPDATE credit_transfer_batches SET bank_status = CASE WHEN $3 = '' THEN bank_status ELSE $3 END WHERE tenant_id = $1 AND id = $2 RETURNING id, transfers, total, execution_date, bank_status, tenant_id, created_at`
+		err = scanBatch(tx.QueryRowContext(ctx, query, tenantID, report.OriginalMessageID, report.GroupStatus), &batch)
+		if err == sql.ErrNoRows {
+			return ErrBatchNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		statuses := report.Transactions
+		if len(statuses) == 0 {
+			statuses = []sepa.TransactionStatus{{Status: report.GroupStatus, Reason: report.GroupReason}}
+		}
+		for _, ts := range statuses {
+			status, ok := transferStatus(ts.Status)
+			if !ok {
+				continue
+			}
+			reason := ""
+			if status == models.TransferRejected {
+				reason = ts.Reason
+			}
+			query := `UPDATE credit_transfers SET status = $4, reason = $5 WHERE tenant_id = $1 AND batch_id = $2 AND ($3 = '' OR id = $3) AND status NOT IN ('settled', 'rejected') AND status <> $4`
+			res, err := tx.ExecContext(ctx, query, tenantID, batch.ID, ts.EndToEndID, status, reason)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			updated += int(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"batch_id":     batch.ID,
+		"group_status": report.GroupStatus,
+		"updated":      updated,
+	}).Info("payment status report imported")
+	return &batch, updated, nil
+}
+
+// transferStatus maps a pain.002 status code to a credit transfer status.
+// Pending and partial acceptance say nothing about a single transfer.
+func transferStatus(code string) (string, bool) {
+	switch code {
+	case sepa.StatusAcceptedTechnical, sepa.StatusAccepted, sepa.StatusAcceptedProcessing:
+		return models.TransferAccepted, true
+	case sepa.StatusAcceptedSettlement:
+		return models.TransferSettled, true
+	case sepa.StatusRejected:
+		return models.TransferRejected, true
+	default:
+		return "", false
+	}
+}
+
+func scanBatch(row *sql.Row, batch *models.CreditTransferBatch) error {
+	var executionDate time.Time
+	if err := row.Scan(&batch.ID, &batch.Transfers, &batch.Total, &executionDate, &batch.BankStatus, &batch.TenantID, &batch.CreatedAt); err != nil {
+		return err
+	}
+	batch.ExecutionDate = executionDate.Format("2006-01-02")
+	return nil
+}
+
+func newID(prefix string) (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
+
+func last4(iban string) string {
+	if len(iban) < 4 {
+		return iban
+	}
+	return iban[len(iban)-4:]
+}
@@ -0,0 +1,582 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go-lang-final/internal/customers"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/money"
+	"go-lang-final/internal/sepa"
+	"go-lang-final/internal/tenant"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrCustomerNotFound = errors.New("customer not found")
+	ErrCustomerErased   = errors.New("customer was erased")
+	ErrCustomerExists   = errors.New("a customer with this external_ref exists")
+	ErrCustomerInUse    = errors.New("customer has payments, payment methods or subscriptions")
+	// ErrCustomerActive refuses to erase a customer who still has live
+	// subscriptions or credit transfers waiting to reach its bank accounts.
+	ErrCustomerActive = errors.New("customer has live subscriptions or open credit transfers")
+)
+
+const customerPrefix = "cus_"
+
+const customerColumns = `id, tenant_id, email, name, external_ref, metadata, erased_at, created_at, updated_at`
+
+// CreateCustomer stores a customer of the tenant in scope.
+func (s *PaymentStore) CreateCustomer(ctx context.Context, c models.Customer) (*models.Customer, error) {
+	if err := customers.Normalize(&c); err != nil {
+		return nil, err
+	}
+	metadata, err := marshalMetadata(c.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if c.ID, err = newID(customerPrefix); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO customers (tenant_id, id, email, name, external_ref, metadata) VALUES ($1, $2, $3, $4, $5, $6) RETURNING ` + customerColumns
+		row := tx.QueryRowContext(ctx, query, tenantID, c.ID, c.Email, c.Name, c.ExternalRef, metadata)
+		return customerConflict(scanCustomer(row, &c))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithField("customer_id", c.ID).Info("customer created")
+	return &c, nil
+}
+
+// GetCustomer returns a customer of the tenant in scope, erased ones
+// included.
+func (s *PaymentStore) GetCustomer(ctx context.Context, id string) (*models.Customer, error) {
+	var c models.Customer
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		query := `SELECT ` + customerColumns + ` FROM customers WHERE tenant_id = $1 AND id = $2`
+		err = scanCustomer(tx.QueryRowContext(ctx, query, tenantID, id), &c)
+		if err == sql.ErrNoRows {
+			return ErrCustomerNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ListCustomers pages through the tenant's customers, newest first. An
+// email or external reference, when not empty, has to match exactly.
+func (s *PaymentStore) ListCustomers(ctx context.Context, email, externalRef string, page, pageSize int) ([]models.Customer, error) {
+	var list []models.Customer
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT ` + customerColumns + ` FROM customers WHERE tenant_id = $1 AND ($2 = '' OR email = $2) AND ($3 = '' OR external_ref = $3)
+			ORDER BY created_at DESC, id LIMIT $4 OFFSET $5`
+		rows, err := tx.QueryContext(ctx, query, tenantID, email, externalRef, pageSize, (page-1)*pageSize)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var c models.Customer
+			if err := scanCustomer(rows, &c); err != nil {
+				return err
+			}
+			list = append(list, c)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// UpdateCustomer replaces the email, name, external reference and metadata
+// of a customer. Erased customers cannot be updated.
+func (s *PaymentStore) UpdateCustomer(ctx context.Context, c models.Customer) (*models.Customer, error) {
+	if err := customers.Normalize(&c); err != nil {
+		return nil, err
+	}
+	metadata, err := marshalMetadata(c.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if _, err := lockCustomer(ctx, tx, tenantID, c.ID); err != nil {
+			return err
+		}
+
+		query := `UPDATE customers SET email = $3, name = $4, external_ref = $5, metadata = $6, updated_at = now()
+			WHERE tenant_id = $1 AND id = $2 RETURNING ` + customerColumns
+		row := tx.QueryRowContext(ctx, query, tenantID, c.ID, c.Email, c.Name, c.ExternalRef, metadata)
+		return customerConflict(scanCustomer(row, &c))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithField("customer_id", c.ID).Info("customer updated")
+	return &c, nil
+}
+
+// DeleteCustomer deletes a customer nothing belongs to. Customers with
+// payments, payment methods or subscriptions are erased instead, which
+// keeps the financial records.
+func (s *PaymentStore) DeleteCustomer(ctx context.Context, id string) error {
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		var used bool
+		query := `SELECT EXISTS (SELECT 1 FROM payments WHERE tenant_id = $1 AND customer_id = $2)
+			OR EXISTS (SELECT 1 FROM payment_methods WHERE tenant_id = $1 AND customer_id = $2)
+			OR EXISTS (SELECT 1 FROM bank_accounts WHERE tenant_id = $1 AND customer_id = $2)
+			OR EXISTS (SELECT 1 FROM subscriptions WHERE tenant_id = $1 AND customer_id = $2)`
+		if err := tx.QueryRowContext(ctx, query, tenantID, id).Scan(&used); err != nil {
+			return err
+		}
+		if used {
+			return ErrCustomerInUse
+		}
+
+		res, err := tx.ExecContext(ctx, `DELETE FROM customers WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrCustomerNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).WithField("customer_id", id).Info("customer deleted")
+	return nil
+}
+
+// EraseCustomer removes the personal data of a customer at now: its email,
+// name, external reference and metadata are blanked, its vaulted cards are
+// deleted and the holder and IBAN of its bank accounts cleared. Its
+// payments lose their description, statement descriptor and metadata, the
+// free text integrators may have put personal data in. Invoices billed to
+// its email or paid by its payments lose their customer name, email and
+// address, and the pain.001 files of credit transfers to its bank accounts
+// the creditor name, IBAN and BIC. Payments and subscriptions keep
+// pointing at the anonymous customer, so amounts, statuses, refunds and
+// fees stay on record. Customers with live subscriptions or
+// credit transfers still to be paid out to them cannot be erased. Erasing
+// an erased customer changes nothing.
+func (s *PaymentStore) EraseCustomer(ctx context.Context, id string, now time.Time) (*models.Customer, error) {
+	var c *models.Customer
+	var erased bool
+	var cards, accounts, payments, invoices, batches int64
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		c, err = lockCustomer(ctx, tx, tenantID, id)
+		if err == ErrCustomerErased {
+			erased = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var active bool
+		query := `SELECT EXISTS (SELECT 1 FROM subscriptions WHERE tenant_id = $1 AND customer_id = $2 AND status <> 'canceled')
+			OR EXISTS (SELECT 1 FROM credit_transfers t JOIN bank_accounts a ON a.tenant_id = t.tenant_id AND a.token = t.bank_account
+				WHERE t.tenant_id = $1 AND a.customer_id = $2 AND t.status IN ('pending', 'submitted', 'accepted'))`
+		if err := tx.QueryRowContext(ctx, query, tenantID, id).Scan(&active); err != nil {
+			return err
+		}
+		if active {
+			return ErrCustomerActive
+		}
+
+		// Payments keep the tokens of the cards they were made with, but not
+		// the cards themselves.
+		res, err := tx.ExecContext(ctx, `DELETE FROM payment_methods WHERE tenant_id = $1 AND customer_id = $2`, tenantID, id)
+		if err != nil {
+			return err
+		}
+		if cards, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		res, err = tx.ExecContext(ctx, `UPDATE bank_accounts SET holder_name = '', iban = '', bic = '' WHERE tenant_id = $1 AND customer_id = $2`, tenantID, id)
+		if err != nil {
+			return err
+		}
+		if accounts, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		res, err = tx.ExecContext(ctx, `UPDATE payments SET description = '', statement_descriptor = '', metadata = '{}' WHERE tenant_id = $1 AND customer_id = $2`, tenantID, id)
+		if err != nil {
+			return err
+		}
+		if payments, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		query = `UPDATE invoices SET customer_name = '', customer_email = '', customer_address = '', updated_at = now()
+			WHERE tenant_id = $1 AND (($3 <> '' AND lower(customer_email) = lower($3))
+				OR id IN (SELECT i.invoice_id FROM invoice_payments i JOIN payments p ON p.tenant_id = i.tenant_id AND p.id = i.payment_id
+					WHERE i.tenant_id = $1 AND p.customer_id = $2))`
+		res, err = tx.ExecContext(ctx, query, tenantID, id, c.Email)
+		if err != nil {
+			return err
+		}
+		if invoices, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		if batches, err = redactCreditTransferBatches(ctx, tx, tenantID, id); err != nil {
+			return err
+		}
+
+		query = `UPDATE customers SET email = '', name = '', external_ref = '', metadata = '{}', erased_at = $3, updated_at = now()
+			WHERE tenant_id = $1 AND id = $2 RETURNING ` + customerColumns
+		return scanCustomer(tx.QueryRowContext(ctx, query, tenantID, id, now), c)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if erased {
+		return c, nil
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"customer_id":   id,
+		"cards":         cards,
+		"bank_accounts": accounts,
+		"payments":      payments,
+		"invoices":      invoices,
+		"batches":       batches,
+	}).Info("customer erased")
+	return c, nil
+}
+
+// AttachPaymentMethod makes a vaulted card or a bank account of the tenant
+// belong to a customer, and returns the customer's payment methods. A
+// payment method belongs to one customer at a time.
+func (s *PaymentStore) AttachPaymentMethod(ctx context.Context, id, token string) (*models.CustomerPaymentMethods, error) {
+	var methods *models.CustomerPaymentMethods
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if _, err := lockCustomer(ctx, tx, tenantID, id); err != nil {
+			return err
+		}
+
+		query, missing := `UPDATE payment_methods SET customer_id = $3 WHERE tenant_id = $1 AND token = $2`, ErrPaymentMethodNotFound
+		if strings.HasPrefix(token, models.BankAccountPrefix) {
+			query, missing = `UPDATE bank_accounts SET customer_id = $3 WHERE tenant_id = $1 AND token = $2`, ErrBankAccountNotFound
+		}
+		res, err := tx.ExecContext(ctx, query, tenantID, token, id)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return missing
+		}
+		methods, err = customerPaymentMethods(ctx, tx, tenantID, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"customer_id":    id,
+		"payment_method": token,
+	}).Info("payment method attached to customer")
+	return methods, nil
+}
+
+// ListCustomerPaymentMethods returns what is safe to show of the cards and
+// bank accounts of a customer, oldest first.
+func (s *PaymentStore) ListCustomerPaymentMethods(ctx context.Context, id string) (*models.CustomerPaymentMethods, error) {
+	var methods *models.CustomerPaymentMethods
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if err := checkCustomerExists(ctx, tx, tenantID, id); err != nil {
+			return err
+		}
+		methods, err = customerPaymentMethods(ctx, tx, tenantID, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return methods, nil
+}
+
+// ListCustomerPayments pages through the payments of a customer, the most
+// recently created first.
+func (s *PaymentStore) ListCustomerPayments(ctx context.Context, id string, page, pageSize int) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if err := checkCustomerExists(ctx, tx, tenantID, id); err != nil {
+			return err
+		}
+
+		query := `SELECT ` + paymentColumns + ` FROM payments p
+			LEFT JOIN LATERAL (SELECT min(e.created_at) AS created_at FROM payment_events e WHERE e.tenant_id = p.tenant_id AND e.payment_id = p.id) e ON true
+			WHERE p.tenant_id = $1 AND p.customer_id = $2
+			ORDER BY e.created_at DESC NULLS LAST, p.id DESC LIMIT $3 OFFSET $4`
+		rows, err := tx.QueryContext(ctx, query, tenantID, id, pageSize, (page-1)*pageSize)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			payment := models.Payment{Customer: id}
+			if err := scanPayment(rows, &payment); err != nil {
+				return err
+			}
+			payments = append(payments, payment)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// CustomerLifetimeValue sums the payments of a customer that went through,
+// by currency, along with when the first and the last of them were made.
+func (s *PaymentStore) CustomerLifetimeValue(ctx context.Context, id string) (*models.CustomerLifetimeValue, error) {
+	value := models.CustomerLifetimeValue{CustomerID: id, Values: []models.CustomerValue{}}
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if err := checkCustomerExists(ctx, tx, tenantID, id); err != nil {
+			return err
+		}
+
+		query := `SELECT p.currency, count(*), sum(p.amount), sum(p.refunded_amount), min(e.created_at), max(e.created_at)
+			FROM payments p
+			LEFT JOIN LATERAL (SELECT min(e.created_at) AS created_at FROM payment_events e WHERE e.tenant_id = p.tenant_id AND e.payment_id = p.id) e ON true
+			WHERE p.tenant_id = $1 AND p.customer_id = $2 AND p.status IN ('created', 'partially_refunded', 'refunded')
+			GROUP BY p.currency ORDER BY p.currency`
+		rows, err := tx.QueryContext(ctx, query, tenantID, id)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var v models.CustomerValue
+			var first, last sql.NullTime
+			if err := rows.Scan(&v.Currency, &v.Payments, &v.Gross, &v.Refunded, &first, &last); err != nil {
+				return err
+			}
+			v.Net = money.Major(money.Minor(v.Gross) - money.Minor(v.Refunded))
+			v.FirstPaymentAt, v.LastPaymentAt = nullTime(first), nullTime(last)
+			value.Values = append(value.Values, v)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// checkCustomer makes sure a payment or subscription is for a customer of
+// its own tenant who was not erased.
+func checkCustomer(ctx context.Context, tx *sql.Tx, tenantID, id string) error {
+	var erased bool
+	err := tx.QueryRowContext(ctx, `SELECT erased_at IS NOT NULL FROM customers WHERE tenant_id = $1 AND id = $2`, tenantID, id).Scan(&erased)
+	switch {
+	case err == sql.ErrNoRows:
+		return ErrCustomerNotFound
+	case err != nil:
+		return err
+	case erased:
+		return ErrCustomerErased
+	}
+	return nil
+}
+
+func checkCustomerExists(ctx context.Context, tx *sql.Tx, tenantID, id string) error {
+	if err := checkCustomer(ctx, tx, tenantID, id); err != ErrCustomerErased {
+		return err
+	}
+	return nil
+}
+
+// lockCustomer locks a customer who was not erased. An erased customer is
+// returned along with ErrCustomerErased.
+func lockCustomer(ctx context.Context, tx *sql.Tx, tenantID, id string) (*models.Customer, error) {
+	var c models.Customer
+	query := `SELECT ` + customerColumns + ` FROM customers WHERE tenant_id = $1 AND id = $2 FOR UPDATE`
+	err := scanCustomer(tx.QueryRowContext(ctx, query, tenantID, id), &c)
+	if err == sql.ErrNoRows {
+		return nil, ErrCustomerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.ErasedAt != nil {
+		return &c, ErrCustomerErased
+	}
+	return &c, nil
+}
+
+// redactCreditTransferBatches removes the creditor details of the transfers
+// to a customer's bank accounts from the pain.001 files they were sent in,
+// and returns how many files changed.
+func redactCreditTransferBatches(ctx context.Context, tx *sql.Tx, tenantID, id string) (int64, error) {
+	query := `SELECT b.id, b.document, ARRAY(SELECT t.id FROM credit_transfers t JOIN bank_accounts a ON a.tenant_id = t.tenant_id AND a.token = t.bank_account
+			WHERE t.tenant_id = b.tenant_id AND t.batch_id = b.id AND a.customer_id = $2 ORDER BY t.id)
+		FROM credit_transfer_batches b WHERE b.tenant_id = $1 AND b.id IN (SELECT t.batch_id FROM credit_transfers t JOIN bank_accounts a ON a.tenant_id = t.tenant_id AND a.token = t.bank_account
+			WHERE t.tenant_id = $1 AND a.customer_id = $2)
+		ORDER BY b.id FOR UPDATE OF b`
+	rows, err := tx.QueryContext(ctx, query, tenantID, id)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	documents := map[string][]byte{}
+	var ids []string
+	for rows.Next() {
+		var (
+			batchID   string
+			document  []byte
+			transfers []string
+		)
+		if err := rows.Scan(&batchID, &document, pq.Array(&transfers)); err != nil {
+			return 0, err
+		}
+		if documents[batchID], err = sepa.RedactCreditors(document, transfers); err != nil {
+			return 0, err
+		}
+		ids = append(ids, batchID)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	for _, batchID := range ids {
+		query = `UPDATE credit_transfer_batches SET document = $3 WHERE tenant_id = $1 AND id = $2`
+		if _, err := tx.ExecContext(ctx, query, tenantID, batchID, documents[batchID]); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(ids)), nil
+}
+
+func customerPaymentMethods(ctx context.Context, tx *sql.Tx, tenantID, id string) (*models.CustomerPaymentMethods, error) {
+	methods := &models.CustomerPaymentMethods{Cards: []models.PaymentMethod{}, BankAccounts: []models.BankAccount{}}
+
+	query := `SELECT token, brand, last4, exp_month, exp_year, tenant_id, created_at FROM payment_methods WHERE tenant_id = $1 AND customer_id = $2 ORDER BY created_at, token`
+	rows, err := tx.QueryContext(ctx, query, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m models.PaymentMethod
+		if err := rows.Scan(&m.Token, &m.Brand, &m.Last4, &m.ExpMonth, &m.ExpYear, &m.TenantID, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		methods.Cards = append(methods.Cards, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `SELECT token, holder_name, iban, bic, tenant_id, created_at FROM bank_accounts WHERE tenant_id = $1 AND customer_id = $2 ORDER BY created_at, token`
+	rows, err = tx.QueryContext(ctx, query, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a models.BankAccount
+		var iban string
+		if err := rows.Scan(&a.Token, &a.HolderName, &iban, &a.BIC, &a.TenantID, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		a.Country, a.Last4 = sepa.Country(iban), last4(iban)
+		methods.BankAccounts = append(methods.BankAccounts, a)
+	}
+	return methods, rows.Err()
+}
+
+// customerConflict reports a taken external reference as ErrCustomerExists.
+func customerConflict(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "customers_external_ref_idx" {
+		return ErrCustomerExists
+	}
+	return err
+}
+
+func scanCustomer(row scanner, c *models.Customer) error {
+	var metadata []byte
+	var erasedAt sql.NullTime
+	if err := row.Scan(&c.ID, &c.TenantID, &c.Email, &c.Name, &c.ExternalRef, &metadata, &erasedAt, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return err
+	}
+	m, err := unmarshalMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	c.Metadata, c.ErasedAt = m, nullTime(erasedAt)
+	return nil
+}
@@ -0,0 +1,559 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-lang-final/internal/disputes"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/money"
+	"go-lang-final/internal/tenant"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrDisputeNotFound  = errors.New("dispute not found")
+	ErrEvidenceNotFound = errors.New("dispute evidence not found")
+	// ErrDisputeState is returned for an operation the status of the
+	// dispute does not allow, such as adding evidence to a closed dispute.
+	ErrDisputeState = errors.New("dispute does not allow this operation")
+	// ErrNotDisputable is returned for a payment that was never captured.
+	ErrNotDisputable   = errors.New("only captured payments can be disputed")
+	ErrDisputeTooLarge = errors.New("dispute amount exceeds what was paid and is not refunded or disputed")
+	ErrNoEvidence      = errors.New("dispute has no evidence")
+	ErrTooMuchEvidence = errors.New("dispute has the most evidence files allowed")
+)
+
+// Ledger accounts of disputes.
+const (
+	// AccountDisputeHolds is what open disputes hold back from the tenant's
+	// balance.
+	AccountDisputeHolds = "dispute_holds"
+	// AccountChargebacks is what lost disputes returned to cardholders.
+	AccountChargebacks = "chargebacks"
+)
+
+const (
+	disputePrefix  = "dp_"
+	evidencePrefix = "dpe_"
+)
+
+const disputeColumns = `id, tenant_id, payment_id, reason, amount, currency, status, evidence_due_by, provider, provider_ref, outcome,
+	reminders_sent, submitted_at, closed_at, created_at, updated_at`
+
+const evidenceColumns = `id, dispute_id, type, filename, content_type, size, sha256, blob_key, created_at`
+
+// EvidenceUpload stores the file of new evidence in the blob store under
+// key. It runs before the evidence is committed, so a failed upload leaves
+// no record behind.
+type EvidenceUpload func(key string) error
+
+// DisputeCall makes the provider side of contesting a dispute with its
+// evidence. Nil, or a dispute entered by hand, skips it.
+type DisputeCall func(d models.Dispute) error
+
+// CreateDispute opens a dispute of a payment of the tenant in scope and
+// holds the disputed amount back from the tenant's balance.
+func (s *PaymentStore) CreateDispute(ctx context.Context, d models.Dispute, now time.Time) (*models.Dispute, error) {
+	if err := disputes.Validate(&d, now); err != nil {
+		return nil, err
+	}
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		return openDispute(ctx, tx, tenantID, &d)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"dispute_id": d.ID,
+		"payment_id": d.PaymentID,
+		"amount":     d.Amount,
+	}).Info("dispute opened")
+	return &d, nil
+}
+
+// GetDispute returns a dispute of the tenant in scope with its evidence.
+func (s *PaymentStore) GetDispute(ctx context.Context, id string) (*models.Dispute, error) {
+	var d models.Dispute
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		query := `SELECT ` + disputeColumns + ` FROM disputes WHERE tenant_id = $1 AND id = $2`
+		err = scanDispute(tx.QueryRowContext(ctx, query, tenantID, id), &d)
+		if err == sql.ErrNoRows {
+			return ErrDisputeNotFound
+		}
+		if err != nil {
+			return err
+		}
+		d.Evidence, err = loadEvidence(ctx, tx, tenantID, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ListDisputes pages through the tenant's disputes, those due soonest
+// first. A status or payment id, when set, has to match.
+func (s *PaymentStore) ListDisputes(ctx context.Context, status string, paymentID int64, page, pageSize int) ([]models.Dispute, error) {
+	var list []models.Dispute
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT ` + disputeColumns + ` FROM disputes WHERE tenant_id = $1 AND ($2 = '' OR status = $2) AND ($3 = 0 OR payment_id = $3)
+			ORDER BY evidence_due_by, id LIMIT $4 OFFSET $5`
+		rows, err := tx.QueryContext(ctx, query, tenantID, status, paymentID, pageSize, (page-1)*pageSize)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var d models.Dispute
+			if err := scanDispute(rows, &d); err != nil {
+				return err
+			}
+			list = append(list, d)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// AddDisputeEvidence records a file contesting a dispute that still needs
+// a response, storing its content with upload.
+func (s *PaymentStore) AddDisputeEvidence(ctx context.Context, disputeID string, e models.DisputeEvidence, upload EvidenceUpload) (*models.DisputeEvidence, error) {
+	if err := disputes.CheckEvidence(e); err != nil {
+		return nil, err
+	}
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		d, err := lockDispute(ctx, tx, tenantID, disputeID)
+		if err != nil {
+			return err
+		}
+		if d.Status != models.DisputeNeedsResponse {
+			return ErrDisputeState
+		}
+		var files int
+		query := `SELECT count(*) FROM dispute_evidence WHERE tenant_id = $1 AND dispute_id = $2`
+		if err := tx.QueryRowContext(ctx, query, tenantID, disputeID).Scan(&files); err != nil {
+			return err
+		}
+		if files >= disputes.MaxEvidenceFiles {
+			return ErrTooMuchEvidence
+		}
+
+		if e.ID, err = newID(evidencePrefix); err != nil {
+			return err
+		}
+		e.DisputeID, e.Key = disputeID, disputeID+"/"+e.ID
+		query = `INSERT INTO dispute_evidence (tenant_id, id, dispute_id, type, filename, content_type, size, sha256, blob_key)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING created_at`
+		err = tx.QueryRowContext(ctx, query, tenantID, e.ID, e.DisputeID, e.Type, e.Filename, e.ContentType, e.Size, e.SHA256, e.Key).Scan(&e.CreatedAt)
+		if err != nil {
+			return err
+		}
+		return upload(e.Key)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"dispute_id":  disputeID,
+		"evidence_id": e.ID,
+		"size":        e.Size,
+	}).Info("dispute evidence added")
+	return &e, nil
+}
+
+// GetDisputeEvidence returns one evidence file of a dispute.
+func (s *PaymentStore) GetDisputeEvidence(ctx context.Context, disputeID, id string) (*models.DisputeEvidence, error) {
+	var e models.DisputeEvidence
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		query := `SELECT ` + evidenceColumns + ` FROM dispute_evidence WHERE tenant_id = $1 AND dispute_id = $2 AND id = $3`
+		err = scanEvidence(tx.QueryRowContext(ctx, query, tenantID, disputeID, id), &e)
+		if err == sql.ErrNoRows {
+			return ErrEvidenceNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// SubmitDispute contests a dispute with the evidence added so far, putting
+// it under review. call sends the evidence to the dispute's provider.
+func (s *PaymentStore) SubmitDispute(ctx context.Context, id string, now time.Time, call DisputeCall) (*models.Dispute, error) {
+	var d models.Dispute
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if d, err = lockDispute(ctx, tx, tenantID, id); err != nil {
+			return err
+		}
+		if !disputes.CanMove(d.Status, models.DisputeUnderReview) {
+			return ErrDisputeState
+		}
+		if d.Evidence, err = loadEvidence(ctx, tx, tenantID, id); err != nil {
+			return err
+		}
+		if len(d.Evidence) == 0 {
+			return ErrNoEvidence
+		}
+		if call != nil && d.ProviderRef != "" {
+			if err := call(d); err != nil {
+				return err
+			}
+		}
+
+		evidence := d.Evidence
+		query := `UPDATE disputes SET status = 'under_review', submitted_at = $3, updated_at = now() WHERE tenant_id = $1 AND id = $2 RETURNING ` + disputeColumns
+		if err := scanDispute(tx.QueryRowContext(ctx, query, tenantID, id, now), &d); err != nil {
+			return err
+		}
+		d.Evidence = evidence
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithField("dispute_id", id).Info("dispute submitted")
+	return &d, nil
+}
+
+// CloseDispute records that a dispute was won or lost, explained by
+// outcome. A won dispute releases the held amount to the tenant; a lost
+// one returns it to the cardholder.
+func (s *PaymentStore) CloseDispute(ctx context.Context, id, status, outcome string, now time.Time) (*models.Dispute, error) {
+	var d models.Dispute
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if d, err = lockDispute(ctx, tx, tenantID, id); err != nil {
+			return err
+		}
+		return closeDispute(ctx, tx, tenantID, &d, status, outcome, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"dispute_id": id,
+		"status":     status,
+	}).Info("dispute closed")
+	return &d, nil
+}
+
+// PaymentByProviderRef finds the payment a provider processed as
+// transaction ref, in any tenant the scope covers.
+func (s *PaymentStore) PaymentByProviderRef(ctx context.Context, provider, ref string) (*models.Payment, error) {
+	var payment models.Payment
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		query := `SELECT ` + paymentColumns + ` FROM payments WHERE ($1 = '' OR tenant_id = $1) AND provider = $2 AND provider_ref = $3`
+		err := scanPayment(tx.QueryRowContext(ctx, query, scope.TenantID, provider, ref), &payment)
+		if err == sql.ErrNoRows {
+			return ErrPaymentNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// RecordProviderDispute brings the tenant's copy of a dispute a provider
+// reported up to date: a new one is opened, and a status the provider
+// moved it to is recorded. It reports whether anything changed.
+func (s *PaymentStore) RecordProviderDispute(ctx context.Context, reported models.Dispute, now time.Time) (*models.Dispute, bool, error) {
+	var (
+		d       models.Dispute
+		changed bool
+	)
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT ` + disputeColumns + ` FROM disputes WHERE tenant_id = $1 AND provider = $2 AND provider_ref = $3 FOR UPDATE`
+		err = scanDispute(tx.QueryRowContext(ctx, query, tenantID, reported.Provider, reported.ProviderRef), &d)
+		switch {
+		case err == sql.ErrNoRows:
+			d = reported
+			if !disputes.ValidReason(d.Reason) {
+				d.Reason = disputes.ReasonGeneral
+			}
+			if err := openDispute(ctx, tx, tenantID, &d); err != nil {
+				return err
+			}
+			changed = true
+		case err != nil:
+			return err
+		}
+
+		switch {
+		case reported.Status == d.Status:
+			return nil
+		case reported.Status == models.DisputeUnderReview && disputes.CanMove(d.Status, reported.Status):
+			query := `UPDATE disputes SET status = 'under_review', submitted_at = COALESCE(submitted_at, $3), updated_at = now()
+				WHERE tenant_id = $1 AND id = $2 RETURNING ` + disputeColumns
+			changed = true
+			return scanDispute(tx.QueryRowContext(ctx, query, tenantID, d.ID, now), &d)
+		case disputes.Closed(reported.Status) && disputes.CanMove(d.Status, reported.Status):
+			changed = true
+			return closeDispute(ctx, tx, tenantID, &d, reported.Status, "decided by "+reported.Provider, now)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if changed {
+		logging.FromContext(ctx).WithFields(logrus.Fields{
+			"dispute_id":   d.ID,
+			"provider_ref": d.ProviderRef,
+			"status":       d.Status,
+		}).Info("provider dispute recorded")
+	}
+	return &d, changed, nil
+}
+
+// DueDisputes returns disputes needing a response, of every tenant the
+// scope covers, that were entered by hand and whose deadline passed, or
+// whose next reminder, at one of leads before the deadline, may be due:
+// those due within the longest lead that have not had every reminder.
+// Providers close their own disputes once the deadline passes.
+func (s *PaymentStore) DueDisputes(ctx context.Context, now time.Time, leads []time.Duration, limit int) ([]models.Dispute, error) {
+	var longest time.Duration
+	for _, lead := range leads {
+		longest = max(longest, lead)
+	}
+
+	var list []models.Dispute
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		query := `SELECT ` + disputeColumns + ` FROM disputes WHERE ($1 = '' OR tenant_id = $1) AND status = 'needs_response'
+			AND ((evidence_due_by <= $2 AND provider_ref = '') OR (evidence_due_by > $2 AND evidence_due_by <= $3 AND reminders_sent < $4))
+			ORDER BY evidence_due_by LIMIT $5`
+		rows, err := tx.QueryContext(ctx, query, scope.TenantID, now, now.Add(longest), len(leads), limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var d models.Dispute
+			if err := scanDispute(rows, &d); err != nil {
+				return err
+			}
+			list = append(list, d)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ClaimDisputeReminder raises the count of reminders sent for a dispute
+// from sent to due. It reports false when another replica got there first
+// or the dispute no longer needs a response, so each reminder goes out
+// once.
+func (s *PaymentStore) ClaimDisputeReminder(ctx context.Context, id string, sent, due int) (bool, error) {
+	var claimed bool
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		query := `UPDATE disputes SET reminders_sent = $4, updated_at = now()
+			WHERE tenant_id = $1 AND id = $2 AND status = 'needs_response' AND reminders_sent = $3`
+		res, err := tx.ExecContext(ctx, query, tenantID, id, sent, due)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		claimed = n == 1
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return claimed, nil
+}
+
+// openDispute inserts a dispute of a captured payment, in the payment's
+// currency, and holds its amount back from the tenant's balance. The
+// payment is locked so concurrent disputes cannot add up to more than was
+// paid.
+func openDispute(ctx context.Context, tx *sql.Tx, tenantID string, d *models.Dispute) error {
+	var payment models.Payment
+	query := `SELECT amount, currency, status, refunded_amount FROM payments WHERE tenant_id = $1 AND id = $2 FOR UPDATE`
+	err := tx.QueryRowContext(ctx, query, tenantID, d.PaymentID).Scan(&payment.Amount, &payment.Currency, &payment.Status, &payment.RefundedAmount)
+	if err == sql.ErrNoRows {
+		return ErrPaymentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if payment.Status == models.StatusCancelled || payment.Status == models.StatusRequiresAction {
+		return ErrNotDisputable
+	}
+
+	var disputed float64
+	query = `SELECT COALESCE(sum(amount), 0) FROM disputes WHERE tenant_id = $1 AND payment_id = $2 AND status <> 'won'`
+	if err := tx.QueryRowContext(ctx, query, tenantID, d.PaymentID).Scan(&disputed); err != nil {
+		return err
+	}
+	if money.Minor(d.Amount) > money.Minor(payment.Amount)-money.Minor(payment.RefundedAmount)-money.Minor(disputed) {
+		return ErrDisputeTooLarge
+	}
+
+	if d.ID, err = newID(disputePrefix); err != nil {
+		return err
+	}
+	query = `INSERT INTO disputes (tenant_id, id, payment_id, reason, amount, currency, status, evidence_due_by, provider, provider_ref)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING ` + disputeColumns
+	status := d.Status
+	if status != models.DisputeUnderReview {
+		status = models.DisputeNeedsResponse
+	}
+	row := tx.QueryRowContext(ctx, query, tenantID, d.ID, d.PaymentID, d.Reason, d.Amount, payment.Currency, status, d.EvidenceDueBy,
+		d.Provider, d.ProviderRef)
+	if err := disputeConflict(scanDispute(row, d)); err != nil {
+		return err
+	}
+
+	return post(ctx, tx, tenantID, ledgerEntry{
+		Source:      "dispute",
+		SourceID:    d.ID,
+		PaymentID:   d.PaymentID,
+		Currency:    d.Currency,
+		Description: fmt.Sprintf("hold for dispute of payment %d", d.PaymentID),
+		Postings: []ledgerPosting{
+			{Account: AccountMerchantBalance, Amount: money.Minor(d.Amount)},
+			{Account: AccountDisputeHolds, Amount: -money.Minor(d.Amount)},
+		},
+	})
+}
+
+// closeDispute moves a locked dispute to won or lost and releases or
+// reverses its hold.
+func closeDispute(ctx context.Context, tx *sql.Tx, tenantID string, d *models.Dispute, status, outcome string, now time.Time) error {
+	if !disputes.Closed(status) || !disputes.CanMove(d.Status, status) {
+		return ErrDisputeState
+	}
+	query := `UPDATE disputes SET status = $3, outcome = $4, closed_at = $5, updated_at = now() WHERE tenant_id = $1 AND id = $2 RETURNING ` + disputeColumns
+	if err := scanDispute(tx.QueryRowContext(ctx, query, tenantID, d.ID, status, outcome, now), d); err != nil {
+		return err
+	}
+
+	entry := ledgerEntry{
+		Source:      "dispute",
+		SourceID:    d.ID,
+		PaymentID:   d.PaymentID,
+		Currency:    d.Currency,
+		Description: fmt.Sprintf("release of won dispute of payment %d", d.PaymentID),
+		Postings: []ledgerPosting{
+			{Account: AccountDisputeHolds, Amount: money.Minor(d.Amount)},
+			{Account: AccountMerchantBalance, Amount: -money.Minor(d.Amount)},
+		},
+	}
+	if status == models.DisputeLost {
+		entry.Description = fmt.Sprintf("reversal of lost dispute of payment %d", d.PaymentID)
+		entry.Postings[1].Account = AccountChargebacks
+	}
+	return post(ctx, tx, tenantID, entry)
+}
+
+func lockDispute(ctx context.Context, tx *sql.Tx, tenantID, id string) (models.Dispute, error) {
+	var d models.Dispute
+	query := `SELECT ` + disputeColumns + ` FROM disputes WHERE tenant_id = $1 AND id = $2 FOR UPDATE`
+	err := scanDispute(tx.QueryRowContext(ctx, query, tenantID, id), &d)
+	if err == sql.ErrNoRows {
+		return d, ErrDisputeNotFound
+	}
+	return d, err
+}
+
+func loadEvidence(ctx context.Context, tx *sql.Tx, tenantID, disputeID string) ([]models.DisputeEvidence, error) {
+	query := `SELECT ` + evidenceColumns + ` FROM dispute_evidence WHERE tenant_id = $1 AND dispute_id = $2 ORDER BY created_at, id`
+	rows, err := tx.QueryContext(ctx, query, tenantID, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var evidence []models.DisputeEvidence
+	for rows.Next() {
+		var e models.DisputeEvidence
+		if err := scanEvidence(rows, &e); err != nil {
+			return nil, err
+		}
+		evidence = append(evidence, e)
+	}
+	return evidence, rows.Err()
+}
+
+func scanDispute(row scanner, d *models.Dispute) error {
+	var submittedAt, closedAt sql.NullTime
+	err := row.Scan(&d.ID, &d.TenantID, &d.PaymentID, &d.Reason, &d.Amount, &d.Currency, &d.Status, &d.EvidenceDueBy, &d.Provider, &d.ProviderRef,
+		&d.Outcome, &d.RemindersSent, &submittedAt, &closedAt, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	d.SubmittedAt, d.ClosedAt = nullTime(submittedAt), nullTime(closedAt)
+	return nil
+}
+
+func scanEvidence(row scanner, e *models.DisputeEvidence) error {
+	return row.Scan(&e.ID, &e.DisputeID, &e.Type, &e.Filename, &e.ContentType, &e.Size, &e.SHA256, &e.Key, &e.CreatedAt)
+}
+
+// disputeConflict turns the unique violation of a provider's dispute
+// recorded twice into ErrDisputeState.
+func disputeConflict(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrDisputeState
+	}
+	return err
+}
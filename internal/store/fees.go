@@ -0,0 +1,398 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-lang-final/internal/fees"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/money"
+	"go-lang-final/internal/tenant"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrPricingPlanNotFound = errors.New("pricing plan not found")
+	ErrNoPricingPlan       = errors.New("tenant has no pricing plan")
+	ErrFeeNotFound         = errors.New("payment has no fee")
+)
+
+// Ledger accounts of the fees tenants are charged.
+const (
+	// AccountMerchantBalance is what we owe the tenant.
+	AccountMerchantBalance = "merchant_balance"
+	// AccountFeeRevenue is the income from the fees tenants pay.
+	AccountFeeRevenue = "fee_revenue"
+)
+
+// pricingPlanPrefix starts the ids of pricing plans.
+const pricingPlanPrefix = "pp_"
+
+// CreatePricingPlan validates and stores a new pricing plan.
+func (s *PaymentStore) CreatePricingPlan(ctx context.Context, plan models.PricingPlan) (*models.PricingPlan, error) {
+	if err := fees.Validate(&plan); err != nil {
+		return nil, err
+	}
+	pricing, overrides, err := marshalPlan(plan)
+	if err != nil {
+		return nil, err
+	}
+	if plan.ID, err = newID(pricingPlanPrefix); err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO pricing_plans (id, name, pricing, overrides) VALUES ($1, $2, $3, $4) RETURNING created_at, updated_at`
+	if err := s.DB.QueryRowContext(ctx, query, plan.ID, plan.Name, pricing, overrides).Scan(&plan.CreatedAt, &plan.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"plan_id": plan.ID,
+		"name":    plan.Name,
+	}).Info("pricing plan created")
+	return &plan, nil
+}
+
+// UpdatePricingPlan replaces the name and rules of a plan. Fees already
+// charged keep the pricing they were charged at.
+func (s *PaymentStore) UpdatePricingPlan(ctx context.Context, plan models.PricingPlan) (*models.PricingPlan, error) {
+	if err := fees.Validate(&plan); err != nil {
+		return nil, err
+	}
+	pricing, overrides, err := marshalPlan(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE pricing_plans SET name = $2, pricing = $3, overrides = $4, updated_at = now() WHERE id = $1 RETURNING created_at, updated_at`
+	err = s.DB.QueryRowContext(ctx, query, plan.ID, plan.Name, pricing, overrides).Scan(&plan.CreatedAt, &plan.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrPricingPlanNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithField("plan_id", plan.ID).Info("pricing plan updated")
+	return &plan, nil
+}
+
+func (s *PaymentStore) GetPricingPlan(ctx context.Context, id string) (*models.PricingPlan, error) {
+	query := `SELECT id, name, pricing, overrides, created_at, updated_at FROM pricing_plans WHERE id = $1`
+	plan, err := scanPlan(s.DB.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrPricingPlanNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (s *PaymentStore) ListPricingPlans(ctx context.Context, page, pageSize int) ([]models.PricingPlan, error) {
+	query := `SELECT id, name, pricing, overrides, created_at, updated_at FROM pricing_plans ORDER BY id LIMIT $1 OFFSET $2`
+	rows, err := s.DB.QueryContext(ctx, query, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plans []models.PricingPlan
+	for rows.Next() {
+		plan, err := scanPlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, *plan)
+	}
+	return plans, rows.Err()
+}
+
+// AssignPricingPlan puts the tenant in scope on a plan, or with an empty
+// planID takes it off its plan. It returns the plan, nil when none.
+func (s *PaymentStore) AssignPricingPlan(ctx context.Context, planID string) (*models.PricingPlan, error) {
+	var plan *models.PricingPlan
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if planID != "" {
+			query := `SELECT id, name, pricing, overrides, created_at, updated_at FROM pricing_plans WHERE id = $1`
+			plan, err = scanPlan(tx.QueryRowContext(ctx, query, planID))
+			if err == sql.ErrNoRows {
+				return ErrPricingPlanNotFound
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, `UPDATE tenants SET pricing_plan_id = $2 WHERE id = $1`, tenantID, sql.NullString{String: planID, Valid: planID != ""})
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return tenant.ErrNoTenant
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithField("plan_id", planID).Info("pricing plan assigned")
+	return plan, nil
+}
+
+// TenantPricingPlan returns the plan of the tenant in scope.
+func (s *PaymentStore) TenantPricingPlan(ctx context.Context) (*models.PricingPlan, error) {
+	var plan *models.PricingPlan
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		plan, err = tenantPlan(ctx, tx, tenantID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func tenantPlan(ctx context.Context, tx *sql.Tx, tenantID string) (*models.PricingPlan, error) {
+	query := `SELECT p.id, p.name, p.pricing, p.overrides, p.created_at, p.updated_at
+		FROM tenants t JOIN pricing_plans p ON p.id = t.pricing_plan_id WHERE t.id = $1`
+	plan, err := scanPlan(tx.QueryRowContext(ctx, query, tenantID))
+	if err == sql.ErrNoRows {
+		return nil, ErrNoPricingPlan
+	}
+	return plan, err
+}
+
+func marshalPlan(plan models.PricingPlan) ([]byte, []byte, error) {
+	pricing, err := json.Marshal(plan.Pricing)
+	if err != nil {
+		return nil, nil, err
+	}
+	if plan.Overrides == nil {
+		plan.Overrides = []models.PricingOverride{}
+	}
+	overrides, err := json.Marshal(plan.Overrides)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pricing, overrides, nil
+}
+
+func scanPlan(row scanner) (*models.PricingPlan, error) {
+	var (
+		plan               models.PricingPlan
+		pricing, overrides []byte
+	)
+	if err := row.Scan(&plan.ID, &plan.Name, &pricing, &overrides, &plan.CreatedAt, &plan.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(pricing, &plan.Pricing); err != nil {
+		return nil, fmt.Errorf("pricing plan %s: %v", plan.ID, err)
+	}
+	if err := json.Unmarshal(overrides, &plan.Overrides); err != nil {
+		return nil, fmt.Errorf("pricing plan %s: %v", plan.ID, err)
+	}
+	if len(plan.Overrides) == 0 {
+		plan.Overrides = nil
+	}
+	return &plan, nil
+}
+
+// monthStart returns the start of the UTC month of t.
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// monthVolume returns the tenant's volume of payments charged a fee in
+// currency since the start of the month, in minor units.
+func monthVolume(ctx context.Context, tx *sql.Tx, tenantID, currency string, now time.Time) (int64, error) {
+	var volume float64
+	query := `SELECT COALESCE(sum(payment_amount), 0) FROM payment_fees WHERE tenant_id = $1 AND currency = $2 AND created_at >= $3`
+	if err := tx.QueryRowContext(ctx, query, tenantID, currency, monthStart(now)).Scan(&volume); err != nil {
+		return 0, err
+	}
+	return money.Minor(volume), nil
+}
+
+// SimulateFee prices a payment by the plan of the tenant in scope without
+// recording anything. A nil volume stands for the tenant's volume of the
+// month so far.
+func (s *PaymentStore) SimulateFee(ctx context.Context, amount float64, currency, method string, volume *float64) (*models.PaymentFee, error) {
+	var fee *models.PaymentFee
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		plan, err := tenantPlan(ctx, tx, tenantID)
+		if err != nil {
+			return err
+		}
+		var mtd int64
+		if volume != nil {
+			mtd = money.Minor(*volume)
+		} else if mtd, err = monthVolume(ctx, tx, tenantID, currency, time.Now()); err != nil {
+			return err
+		}
+		fee, err = price(*plan, money.Minor(amount), currency, method, mtd)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fee, nil
+}
+
+func price(plan models.PricingPlan, amount int64, currency, method string, volume int64) (*models.PaymentFee, error) {
+	f, err := fees.Calculate(plan, amount, currency, method, volume)
+	if err != nil {
+		return nil, err
+	}
+	return &models.PaymentFee{
+		PlanID:        plan.ID,
+		Amount:        money.Major(f.Amount),
+		Currency:      currency,
+		PaymentAmount: money.Major(amount),
+		Method:        method,
+		Percentage:    f.Percentage,
+		Fixed:         money.Major(f.Fixed),
+		Volume:        money.Major(volume),
+	}, nil
+}
+
+// chargeFee charges a captured payment the fee of the tenant's plan, once:
+// a payment already charged keeps its fee. The fee moves from what we owe
+// the tenant to fee revenue. A tenant without a plan is not charged and
+// fee is left as it is.
+func chargeFee(ctx context.Context, tx *sql.Tx, tenantID string, payment models.Payment, fee *models.PaymentFee) error {
+	plan, err := tenantPlan(ctx, tx, tenantID)
+	if err == ErrNoPricingPlan {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	volume, err := monthVolume(ctx, tx, tenantID, payment.Currency, time.Now())
+	if err != nil {
+		return err
+	}
+	priced, err := price(*plan, money.Minor(payment.Amount), payment.Currency, fees.Method(payment.PaymentMethod), volume)
+	if err != nil {
+		return err
+	}
+	priced.PaymentID = payment.ID
+
+	query := `INSERT INTO payment_fees (tenant_id, payment_id, plan_id, amount, currency, payment_amount, method, percentage, fixed, volume)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (tenant_id, payment_id) DO NOTHING RETURNING created_at`
+	var createdAt time.Time
+	err = tx.QueryRowContext(ctx, query, tenantID, priced.PaymentID, priced.PlanID, priced.Amount, priced.Currency, priced.PaymentAmount,
+		priced.Method, sql.NullString{String: priced.Percentage, Valid: priced.Percentage != ""}, priced.Fixed, priced.Volume).Scan(&createdAt)
+	if err == sql.ErrNoRows {
+		return loadFee(ctx, tx, tenantID, payment.ID, fee)
+	}
+	if err != nil {
+		return err
+	}
+	priced.CreatedAt = &createdAt
+	*fee = *priced
+
+	if fee.Amount == 0 {
+		return nil
+	}
+	return post(ctx, tx, tenantID, ledgerEntry{
+		Source:      "fee",
+		SourceID:    plan.ID,
+		PaymentID:   payment.ID,
+		Currency:    payment.Currency,
+		Description: fmt.Sprintf("fee on payment %d", payment.ID),
+		Postings: []ledgerPosting{
+			{Account: AccountMerchantBalance, Amount: money.Minor(fee.Amount)},
+			{Account: AccountFeeRevenue, Amount: -money.Minor(fee.Amount)},
+		},
+	})
+}
+
+// GetPaymentFee returns the fee a payment was charged.
+func (s *PaymentStore) GetPaymentFee(ctx context.Context, paymentID int64) (*models.PaymentFee, error) {
+	var fee models.PaymentFee
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		return loadFee(ctx, tx, tenantID, paymentID, &fee)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &fee, nil
+}
+
+func loadFee(ctx context.Context, tx *sql.Tx, tenantID string, paymentID int64, fee *models.PaymentFee) error {
+	var (
+		percentage sql.NullString
+		createdAt  time.Time
+	)
+	query := `SELECT payment_id, plan_id, amount, currency, payment_amount, method, percentage, fixed, volume, created_at
+		FROM payment_fees WHERE tenant_id = $1 AND payment_id = $2`
+	err := tx.QueryRowContext(ctx, query, tenantID, paymentID).Scan(&fee.PaymentID, &fee.PlanID, &fee.Amount, &fee.Currency,
+		&fee.PaymentAmount, &fee.Method, &percentage, &fee.Fixed, &fee.Volume, &createdAt)
+	if err == sql.ErrNoRows {
+		return ErrFeeNotFound
+	}
+	if err != nil {
+		return err
+	}
+	fee.CreatedAt = &createdAt
+	fee.Percentage, err = fees.NormalizePercentage(percentage.String)
+	return err
+}
+
+// FeeStatement sums the fees charged to the tenant in scope in the UTC
+// month starting at month, by currency.
+func (s *PaymentStore) FeeStatement(ctx context.Context, month time.Time) (*models.FeeStatement, error) {
+	month = monthStart(month)
+	statement := models.FeeStatement{Month: month.Format("2006-01"), Totals: []models.FeeStatementTotal{}}
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		statement.TenantID = tenantID
+
+		query := `SELECT currency, count(*), sum(payment_amount), sum(amount) FROM payment_fees
+			WHERE tenant_id = $1 AND created_at >= $2 AND created_at < $3 GROUP BY currency ORDER BY currency`
+		rows, err := tx.QueryContext(ctx, query, tenantID, month, month.AddDate(0, 1, 0))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var t models.FeeStatementTotal
+			if err := rows.Scan(&t.Currency, &t.Payments, &t.Volume, &t.Fees); err != nil {
+				return err
+			}
+			statement.Totals = append(statement.Totals, t)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &statement, nil
+}
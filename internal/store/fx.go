@@ -0,0 +1,259 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-lang-final/internal/fx"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/money"
+	"go-lang-final/internal/tenant"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrQuoteNotFound      = errors.New("fx quote not found")
+	ErrQuoteExpired       = errors.New("fx quote expired")
+	ErrQuoteMismatch      = errors.New("fx quote is for other currencies")
+	ErrConversionNotFound = errors.New("payment has no conversion")
+)
+
+// quotePrefix starts the ids of fx quotes.
+const quotePrefix = "fxq_"
+
+// SaveFXRates adds rates to the shared rate table. A rate of a pair that
+// already has one at the same effective time is skipped, so loading a rate
+// table again changes nothing; the count of rates added is returned.
+func (s *PaymentStore) SaveFXRates(ctx context.Context, rates []fx.Rate) (int, error) {
+	for i := range rates {
+		if err := rates[i].Validate(); err != nil {
+			return 0, err
+		}
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	added := 0
+	query := `INSERT INTO fx_rates (base, quote, rate, effective_at, source) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (base, quote, effective_at) DO NOTHING`
+	for _, r := range rates {
+		res, err := tx.ExecContext(ctx, query, r.Base, r.Quote, r.Rate, r.EffectiveAt, r.Source)
+		if err != nil {
+			return 0, err
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			added += int(n)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"rates": len(rates),
+		"added": added,
+	}).Info("fx rates saved")
+	return added, nil
+}
+
+// ListFXRates returns the rates, optionally of one base or quote currency,
+// by pair with the latest first.
+func (s *PaymentStore) ListFXRates(ctx context.Context, base, quote string, page, pageSize int) ([]models.FXRate, error) {
+	query := `SELECT base, quote, rate, effective_at, source FROM fx_rates
+		WHERE ($1 = '' OR base = $1) AND ($2 = '' OR quote = $2)
+		ORDER BY base, quote, effective_at DESC LIMIT $3 OFFSET $4`
+	rows, err := s.DB.QueryContext(ctx, query, base, quote, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []models.FXRate
+	for rows.Next() {
+		var r models.FXRate
+		if err := rows.Scan(&r.Base, &r.Quote, &r.Rate, &r.EffectiveAt, &r.Source); err != nil {
+			return nil, err
+		}
+		if r.Rate, err = normalizeRate(r.Rate); err != nil {
+			return nil, err
+		}
+		rates = append(rates, r)
+	}
+	return rates, rows.Err()
+}
+
+// rateAt returns the rate from one currency to another in effect at at.
+// The latest rate of either direction wins; a rate of the opposite
+// direction is inverted.
+func rateAt(ctx context.Context, tx *sql.Tx, from, to string, at time.Time) (string, time.Time, error) {
+	var (
+		base, rate  string
+		effectiveAt time.Time
+	)
+	query := `SELECT base, rate, effective_at FROM fx_rates
+		WHERE (base = $1 AND quote = $2 OR base = $2 AND quote = $1) AND effective_at <= $3
+		ORDER BY effective_at DESC, base = $1 DESC LIMIT 1`
+	err := tx.QueryRowContext(ctx, query, from, to, at).Scan(&base, &rate, &effectiveAt)
+	if err == sql.ErrNoRows {
+		return "", time.Time{}, fmt.Errorf("%w from %s to %s", fx.ErrNoRate, from, to)
+	}
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if rate, err = normalizeRate(rate); err != nil {
+		return "", time.Time{}, err
+	}
+	if base != from {
+		if rate, err = fx.Invert(rate); err != nil {
+			return "", time.Time{}, err
+		}
+	}
+	return rate, effectiveAt, nil
+}
+
+// normalizeRate drops the trailing zeros of a NUMERIC rate.
+func normalizeRate(s string) (string, error) {
+	rat, err := fx.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return fx.Format(rat), nil
+}
+
+// CreateFXQuote locks the current rate from currency to settlementCurrency
+// for ttl. With a positive amount the quote also carries the converted
+// amount.
+func (s *PaymentStore) CreateFXQuote(ctx context.Context, currency, settlementCurrency string, amount float64, ttl time.Duration) (*models.FXQuote, error) {
+	quote := models.FXQuote{Currency: currency, SettlementCurrency: settlementCurrency, Amount: amount}
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if quote.ID, err = newID(quotePrefix); err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		if quote.Rate, quote.RateEffectiveAt, err = rateAt(ctx, tx, currency, settlementCurrency, now); err != nil {
+			return err
+		}
+		var amounts [2]sql.NullFloat64
+		if amount > 0 {
+			converted, err := fx.Convert(money.ToMinor(amount, currency), currency, settlementCurrency, quote.Rate)
+			if err != nil {
+				return err
+			}
+			quote.SettlementAmount = money.ToMajor(converted, settlementCurrency)
+			amounts = [2]sql.NullFloat64{{Float64: amount, Valid: true}, {Float64: quote.SettlementAmount, Valid: true}}
+		}
+		quote.ExpiresAt = now.Add(ttl)
+
+		query := `INSERT INTO fx_quotes (tenant_id, id, currency, settlement_currency, rate, rate_effective_at, amount, settlement_amount, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING tenant_id, created_at`
+		return tx.QueryRowContext(ctx, query, tenantID, quote.ID, currency, settlementCurrency, quote.Rate, quote.RateEffectiveAt,
+			amounts[0], amounts[1], quote.ExpiresAt).Scan(&quote.TenantID, &quote.CreatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"quote_id":            quote.ID,
+		"currency":            quote.Currency,
+		"settlement_currency": quote.SettlementCurrency,
+		"rate":                quote.Rate,
+		"expires_at":          quote.ExpiresAt,
+	}).Info("fx quote created")
+	return &quote, nil
+}
+
+// convert fills in the conversion a payment asked for: at the rate of its
+// quote, which must be unexpired and for the payment's currency, or else
+// at the rate in effect now.
+func convert(ctx context.Context, tx *sql.Tx, tenantID string, payment models.Payment) error {
+	c := payment.Conversion
+	c.PaymentID, c.Amount, c.Currency = payment.ID, payment.Amount, payment.Currency
+	now := time.Now().UTC()
+
+	if c.QuoteID != "" {
+		var (
+			currency, settlementCurrency string
+			expiresAt                    time.Time
+		)
+		query := `SELECT currency, settlement_currency, rate, rate_effective_at, expires_at FROM fx_quotes WHERE tenant_id = $1 AND id = $2`
+		err := tx.QueryRowContext(ctx, query, tenantID, c.QuoteID).Scan(&currency, &settlementCurrency, &c.Rate, &c.RateEffectiveAt, &expiresAt)
+		if err == sql.ErrNoRows {
+			return ErrQuoteNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !now.Before(expiresAt) {
+			return ErrQuoteExpired
+		}
+		if currency != payment.Currency || c.SettlementCurrency != "" && c.SettlementCurrency != settlementCurrency {
+			return fmt.Errorf("%w: %s to %s", ErrQuoteMismatch, currency, settlementCurrency)
+		}
+		c.SettlementCurrency = settlementCurrency
+		if c.Rate, err = normalizeRate(c.Rate); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if c.Rate, c.RateEffectiveAt, err = rateAt(ctx, tx, payment.Currency, c.SettlementCurrency, now); err != nil {
+			return err
+		}
+	}
+
+	converted, err := fx.Convert(money.ToMinor(payment.Amount, payment.Currency), payment.Currency, c.SettlementCurrency, c.Rate)
+	if err != nil {
+		return err
+	}
+	c.SettlementAmount = money.ToMajor(converted, c.SettlementCurrency)
+
+	query := `INSERT INTO payment_conversions (tenant_id, payment_id, amount, currency, settlement_amount, settlement_currency, rate, rate_effective_at, quote_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING created_at`
+	return tx.QueryRowContext(ctx, query, tenantID, c.PaymentID, c.Amount, c.Currency, c.SettlementAmount, c.SettlementCurrency,
+		c.Rate, c.RateEffectiveAt, c.QuoteID).Scan(&c.CreatedAt)
+}
+
+// GetPaymentConversion returns how a payment was converted to its
+// settlement currency.
+func (s *PaymentStore) GetPaymentConversion(ctx context.Context, paymentID int64) (*models.PaymentConversion, error) {
+	var c models.PaymentConversion
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		return loadConversion(ctx, tx, tenantID, paymentID, &c)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func loadConversion(ctx context.Context, tx *sql.Tx, tenantID string, paymentID int64, c *models.PaymentConversion) error {
+	query := `SELECT payment_id, amount, currency, settlement_amount, settlement_currency, rate, rate_effective_at, quote_id, created_at
+		FROM payment_conversions WHERE tenant_id = $1 AND payment_id = $2`
+	err := tx.QueryRowContext(ctx, query, tenantID, paymentID).Scan(&c.PaymentID, &c.Amount, &c.Currency, &c.SettlementAmount,
+		&c.SettlementCurrency, &c.Rate, &c.RateEffectiveAt, &c.QuoteID, &c.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrConversionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	c.Rate, err = normalizeRate(c.Rate)
+	return err
+}
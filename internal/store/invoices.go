@@ -0,0 +1,381 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"go-lang-final/internal/invoices"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/money"
+	"go-lang-final/internal/tenant"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrInvoiceNotFound        = errors.New("invoice not found")
+	ErrInvoiceState           = errors.New("invoice status does not allow this operation")
+	ErrInvoiceCurrency        = errors.New("payment currency differs from the invoice's")
+	ErrInvoicePaymentTooLarge = errors.New("amount exceeds what is left of the invoice or the payment")
+)
+
+const invoicePrefix = "in_"
+
+const invoiceColumns = `id, tenant_id, number, status, currency, customer_name, customer_email, customer_address, memo, lines,
+	subtotal, discount, tax, total, amount_paid, due_date, issued_at, paid_at, voided_at, created_at, updated_at`
+
+// CreateInvoice works out the amounts of inv and stores it as a draft of
+// the tenant in scope.
+func (s *PaymentStore) CreateInvoice(ctx context.Context, inv models.Invoice) (*models.Invoice, error) {
+	inv.AmountPaid = 0
+	if err := invoices.Calculate(&inv); err != nil {
+		return nil, err
+	}
+	lines, err := json.Marshal(inv.Lines)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if inv.ID, err = newID(invoicePrefix); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO invoices (tenant_id, id, status, currency, customer_name, customer_email, customer_address, memo, lines, subtotal, discount, tax, total, due_date)
+			VALUES ($1, $2, 'draft', $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, '')::date) RETURNING ` + invoiceColumns
+		row := tx.QueryRowContext(ctx, query, tenantID, inv.ID, inv.Currency, inv.CustomerName, inv.CustomerEmail, inv.CustomerAddress, inv.Memo, lines,
+			inv.Subtotal, inv.Discount, inv.Tax, inv.Total, inv.DueDate)
+		return scanInvoice(row, &inv)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"invoice_id": inv.ID,
+		"total":      inv.Total,
+		"currency":   inv.Currency,
+	}).Info("invoice created")
+	return &inv, nil
+}
+
+// UpdateInvoice replaces the customer, lines, memo and due date of a
+// draft and works out its amounts again.
+func (s *PaymentStore) UpdateInvoice(ctx context.Context, inv models.Invoice) (*models.Invoice, error) {
+	inv.AmountPaid = 0
+	if err := invoices.Calculate(&inv); err != nil {
+		return nil, err
+	}
+	lines, err := json.Marshal(inv.Lines)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		current, err := lockInvoice(ctx, tx, tenantID, inv.ID)
+		if err != nil {
+			return err
+		}
+		if current.Status != models.InvoiceDraft {
+			return ErrInvoiceState
+		}
+
+		query := `UPDATE invoices SET currency = $3, customer_name = $4, customer_email = $5, customer_address = $6, memo = $7, lines = $8,
+			subtotal = $9, discount = $10, tax = $11, total = $12, due_date = NULLIF($13, '')::date, updated_at = now()
+			WHERE tenant_id = $1 AND id = $2 RETURNING ` + invoiceColumns
+		row := tx.QueryRowContext(ctx, query, tenantID, inv.ID, inv.Currency, inv.CustomerName, inv.CustomerEmail, inv.CustomerAddress, inv.Memo, lines,
+			inv.Subtotal, inv.Discount, inv.Tax, inv.Total, inv.DueDate)
+		return scanInvoice(row, &inv)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithField("invoice_id", inv.ID).Info("invoice updated")
+	return &inv, nil
+}
+
+// GetInvoice returns an invoice with the payments applied to it.
+func (s *PaymentStore) GetInvoice(ctx context.Context, id string) (*models.Invoice, error) {
+	var inv models.Invoice
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		query := `SELECT ` + invoiceColumns + ` FROM invoices WHERE tenant_id = $1 AND id = $2`
+		err = scanInvoice(tx.QueryRowContext(ctx, query, tenantID, id), &inv)
+		if err == sql.ErrNoRows {
+			return ErrInvoiceNotFound
+		}
+		if err != nil {
+			return err
+		}
+		inv.Payments, err = invoicePayments(ctx, tx, tenantID, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// ListInvoices pages through the tenant's invoices, newest first, only
+// those with status unless it is empty. Their payments are left out.
+func (s *PaymentStore) ListInvoices(ctx context.Context, status string, page, pageSize int) ([]models.Invoice, error) {
+	var list []models.Invoice
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT ` + invoiceColumns + ` FROM invoices WHERE tenant_id = $1 AND ($2 = '' OR status = $2) ORDER BY created_at DESC, id LIMIT $3 OFFSET $4`
+		rows, err := tx.QueryContext(ctx, query, tenantID, status, pageSize, (page-1)*pageSize)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var inv models.Invoice
+			if err := scanInvoice(rows, &inv); err != nil {
+				return err
+			}
+			list = append(list, inv)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// FinalizeInvoice issues a draft at now: it takes the tenant's next number,
+// formatted with prefix, and opens the invoice for payment. The due date
+// defaults to invoices.DefaultDueDays after the issue date, and an invoice
+// of nothing is paid at once.
+func (s *PaymentStore) FinalizeInvoice(ctx context.Context, id, prefix string, now time.Time) (*models.Invoice, error) {
+	var inv *models.Invoice
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if inv, err = lockInvoice(ctx, tx, tenantID, id); err != nil {
+			return err
+		}
+		if inv.Status != models.InvoiceDraft {
+			return ErrInvoiceState
+		}
+
+		// The sequence row stays locked until the transaction ends, so
+		// concurrent finalizations take consecutive numbers and a rollback
+		// returns the number.
+		var n int64
+		query := `INSERT INTO invoice_sequences (tenant_id, last_number) VALUES ($1, 1)
+			ON CONFLICT (tenant_id) DO UPDATE SET last_number = invoice_sequences.last_number + 1 RETURNING last_number`
+		if err := tx.QueryRowContext(ctx, query, tenantID).Scan(&n); err != nil {
+			return err
+		}
+
+		dueDate := inv.DueDate
+		if dueDate == "" {
+			dueDate = now.AddDate(0, 0, invoices.DefaultDueDays).Format(invoices.DateLayout)
+		}
+		status, paidAt := models.InvoiceOpen, sql.NullTime{}
+		if money.Minor(inv.Total) == 0 {
+			status, paidAt = models.InvoicePaid, sql.NullTime{Time: now, Valid: true}
+		}
+		query = `UPDATE invoices SET number = $3, status = $4, due_date = $5, issued_at = $6, paid_at = $7, updated_at = now()
+			WHERE tenant_id = $1 AND id = $2 RETURNING ` + invoiceColumns
+		return scanInvoice(tx.QueryRowContext(ctx, query, tenantID, id, invoices.Number(prefix, n), status, dueDate, now, paidAt), inv)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"invoice_id": id,
+		"number":     inv.Number,
+		"total":      inv.Total,
+	}).Info("invoice finalized")
+	return inv, nil
+}
+
+// VoidInvoice voids a draft, or an open invoice nothing was paid on yet.
+// A voided invoice keeps its number.
+func (s *PaymentStore) VoidInvoice(ctx context.Context, id string, now time.Time) (*models.Invoice, error) {
+	var inv *models.Invoice
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if inv, err = lockInvoice(ctx, tx, tenantID, id); err != nil {
+			return err
+		}
+		if inv.Status != models.InvoiceDraft && (inv.Status != models.InvoiceOpen || money.Minor(inv.AmountPaid) != 0) {
+			return ErrInvoiceState
+		}
+
+		query := `UPDATE invoices SET status = 'void', voided_at = $3, updated_at = now() WHERE tenant_id = $1 AND id = $2 RETURNING ` + invoiceColumns
+		return scanInvoice(tx.QueryRowContext(ctx, query, tenantID, id, now), inv)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"invoice_id": id,
+		"number":     inv.Number,
+	}).Info("invoice voided")
+	return inv, nil
+}
+
+// PayInvoice applies amount of a payment to an open invoice; a zero amount
+// applies as much as the invoice and the payment have left. A payment can
+// settle invoices in its currency up to its unrefunded amount, and the
+// invoice is paid once its payments cover the total.
+func (s *PaymentStore) PayInvoice(ctx context.Context, id string, paymentID int64, amount float64, now time.Time) (*models.Invoice, error) {
+	var inv *models.Invoice
+	var applied int64
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if inv, err = lockInvoice(ctx, tx, tenantID, id); err != nil {
+			return err
+		}
+		if inv.Status != models.InvoiceOpen {
+			return ErrInvoiceState
+		}
+
+		var payment models.Payment
+		query := `SELECT amount, currency, status, refunded_amount FROM payments WHERE tenant_id = $1 AND id = $2 FOR UPDATE`
+		err = tx.QueryRowContext(ctx, query, tenantID, paymentID).Scan(&payment.Amount, &payment.Currency, &payment.Status, &payment.RefundedAmount)
+		if err == sql.ErrNoRows {
+			return ErrPaymentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if payment.Status != models.StatusCreated && payment.Status != models.StatusPartiallyRefunded {
+			return ErrInvalidState
+		}
+		if payment.Currency != inv.Currency {
+			return ErrInvoiceCurrency
+		}
+
+		var used float64
+		query = `SELECT COALESCE(SUM(amount), 0) FROM invoice_payments WHERE tenant_id = $1 AND payment_id = $2`
+		if err := tx.QueryRowContext(ctx, query, tenantID, paymentID).Scan(&used); err != nil {
+			return err
+		}
+		left := money.Minor(payment.Amount) - money.Minor(payment.RefundedAmount) - money.Minor(used)
+		due := money.Minor(inv.Total) - money.Minor(inv.AmountPaid)
+		applied = money.Minor(amount)
+		if applied == 0 {
+			applied = min(left, due)
+		}
+		if applied <= 0 || applied > left || applied > due {
+			return ErrInvoicePaymentTooLarge
+		}
+
+		query = `INSERT INTO invoice_payments (tenant_id, invoice_id, payment_id, amount) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (tenant_id, invoice_id, payment_id) DO UPDATE SET amount = invoice_payments.amount + EXCLUDED.amount`
+		if _, err := tx.ExecContext(ctx, query, tenantID, id, paymentID, money.Major(applied)); err != nil {
+			return err
+		}
+
+		status, paidAt := models.InvoiceOpen, sql.NullTime{}
+		if applied == due {
+			status, paidAt = models.InvoicePaid, sql.NullTime{Time: now, Valid: true}
+		}
+		query = `UPDATE invoices SET amount_paid = amount_paid + $3, status = $4, paid_at = $5, updated_at = now() WHERE tenant_id = $1 AND id = $2 RETURNING ` + invoiceColumns
+		if err := scanInvoice(tx.QueryRowContext(ctx, query, tenantID, id, money.Major(applied), status, paidAt), inv); err != nil {
+			return err
+		}
+		inv.Payments, err = invoicePayments(ctx, tx, tenantID, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"invoice_id": id,
+		"payment_id": paymentID,
+		"amount":     money.Major(applied),
+		"status":     inv.Status,
+	}).Info("payment applied to invoice")
+	return inv, nil
+}
+
+func lockInvoice(ctx context.Context, tx *sql.Tx, tenantID, id string) (*models.Invoice, error) {
+	var inv models.Invoice
+	query := `SELECT ` + invoiceColumns + ` FROM invoices WHERE tenant_id = $1 AND id = $2 FOR UPDATE`
+	err := scanInvoice(tx.QueryRowContext(ctx, query, tenantID, id), &inv)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func invoicePayments(ctx context.Context, tx *sql.Tx, tenantID, id string) ([]models.InvoicePayment, error) {
+	query := `SELECT payment_id, amount, created_at FROM invoice_payments WHERE tenant_id = $1 AND invoice_id = $2 ORDER BY created_at, payment_id`
+	rows, err := tx.QueryContext(ctx, query, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []models.InvoicePayment
+	for rows.Next() {
+		var p models.InvoicePayment
+		if err := rows.Scan(&p.PaymentID, &p.Amount, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
+func scanInvoice(row scanner, inv *models.Invoice) error {
+	var number sql.NullString
+	var lines []byte
+	var dueDate, issuedAt, paidAt, voidedAt sql.NullTime
+	err := row.Scan(&inv.ID, &inv.TenantID, &number, &inv.Status, &inv.Currency, &inv.CustomerName, &inv.CustomerEmail, &inv.CustomerAddress, &inv.Memo, &lines,
+		&inv.Subtotal, &inv.Discount, &inv.Tax, &inv.Total, &inv.AmountPaid, &dueDate, &issuedAt, &paidAt, &voidedAt, &inv.CreatedAt, &inv.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	inv.Lines = nil
+	if err := json.Unmarshal(lines, &inv.Lines); err != nil {
+		return err
+	}
+	inv.Number = number.String
+	inv.DueDate = ""
+	if dueDate.Valid {
+		inv.DueDate = dueDate.Time.Format(invoices.DateLayout)
+	}
+	inv.IssuedAt, inv.PaidAt, inv.VoidedAt = nullTime(issuedAt), nullTime(paidAt), nullTime(voidedAt)
+	inv.AmountDue = money.Major(money.Minor(inv.Total) - money.Minor(inv.AmountPaid))
+	return nil
+}
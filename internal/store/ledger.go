@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/money"
+	"go-lang-final/internal/tenant"
+)
+
+var ErrUnbalancedEntry = errors.New("ledger entry does not balance")
+
+// Ledger accounts. Accounts kept per provider end in the provider's name.
+const (
+	// AccountProviderFees is the expense of the fees providers keep.
+	AccountProviderFees = "provider_fees:"
+	// AccountProviderBalance is what providers owe us until they pay out.
+	AccountProviderBalance = "provider_balance:"
+)
+
+// entryPrefix starts the ids of ledger entries.
+const entryPrefix = "le_"
+
+// ledgerEntry is a set of postings in one currency that sum to zero.
+// Amounts are in minor units, debits positive.
+type ledgerEntry struct {
+	Source      string
+	SourceID    string
+	PaymentID   int64
+	Currency    string
+	Description string
+	Postings    []ledgerPosting
+}
+
+type ledgerPosting struct {
+	Account string
+	Amount  int64
+}
+
+// post writes a balanced entry to the ledger in tx.
+func post(ctx context.Context, tx *sql.Tx, tenantID string, e ledgerEntry) error {
+	var sum int64
+	for _, p := range e.Postings {
+		sum += p.Amount
+	}
+	if sum != 0 || len(e.Postings) < 2 {
+		return ErrUnbalancedEntry
+	}
+	entryID, err := newID(entryPrefix)
+	if err != nil {
+		return err
+	}
+
+	paymentID := sql.NullInt64{Int64: e.PaymentID, Valid: e.PaymentID != 0}
+	query := `INSERT INTO ledger_postings (tenant_id, entry_id, account, amount, currency, payment_id, source, source_id, description)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	for _, p := range e.Postings {
+		if _, err := tx.ExecContext(ctx, query, tenantID, entryID, p.Account, money.Major(p.Amount), e.Currency, paymentID,
+			e.Source, e.SourceID, e.Description); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListLedgerPostings returns the tenant's postings, oldest first, optionally
+// only those of one payment or one account.
+func (s *PaymentStore) ListLedgerPostings(ctx context.Context, paymentID int64, account string, page, pageSize int) ([]models.LedgerPosting, error) {
+	var postings []models.LedgerPosting
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT id, entry_id, account, amount, currency, payment_id, source, source_id, description, tenant_id, created_at
+			FROM ledger_postings WHERE tenant_id = $1 AND ($2 = 0 OR payment_id = $2) AND ($3 = '' OR account = $3)
+			ORDER BY id LIMIT $4 OFFSET $5`
+		rows, err := tx.QueryContext(ctx, query, tenantID, paymentID, account, pageSize, (page-1)*pageSize)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var (
+				p         models.LedgerPosting
+				paymentID sql.NullInt64
+			)
+			if err := rows.Scan(&p.ID, &p.EntryID, &p.Account, &p.Amount, &p.Currency, &paymentID, &p.Source, &p.SourceID,
+				&p.Description, &p.TenantID, &p.CreatedAt); err != nil {
+				return err
+			}
+			p.PaymentID = paymentID.Int64
+			postings = append(postings, p)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return postings, nil
+}
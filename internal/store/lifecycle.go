@@ -0,0 +1,195 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/money"
+	"go-lang-final/internal/tenant"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// A ProviderCall makes the provider side of a refund or cancellation and
+// returns the transaction's new provider status. It runs while the payment
+// row is locked, after the store has checked the change is allowed, so
+// concurrent requests cannot both reach the provider; an error aborts the
+// change. A nil ProviderCall leaves the provider status as it is.
+type ProviderCall func(current models.Payment) (string, error)
+
+// RefundPayment refunds amount of a payment, moving it to partially_refunded
+// or refunded. Amounts are compared in cents so repeated partial refunds add
+// up exactly to the payment amount. Refunds of payments made by bank
+// transfer are queued as credit transfers back to the paying account.
+func (s *PaymentStore) RefundPayment(ctx context.Context, id int64, amount float64, reason string, call ProviderCall) (*models.Payment, error) {
+	var payment models.Payment
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		current, err := lockPayment(ctx, tx, tenantID, id)
+		if err != nil {
+			return err
+		}
+		if current.Status != models.StatusCreated && current.Status != models.StatusPartiallyRefunded {
+			return ErrInvalidState
+		}
+
+		refund, refunded, total := money.Minor(amount), money.Minor(current.RefundedAmount), money.Minor(current.Amount)
+		if refund <= 0 || refunded+refund > total {
+			return ErrRefundTooLarge
+		}
+		status := models.StatusPartiallyRefunded
+		if refunded+refund == total {
+			status = models.StatusRefunded
+		}
+		providerStatus, err := callProvider(call, current)
+		if err != nil {
+			return err
+		}
+
+		if err := setEventReason(ctx, tx, reason); err != nil {
+			return err
+		}
+		query := `UPDATE payments SET refunded_amount = $3, status = $4, provider_status = $5 WHERE tenant_id = $1 AND id = $2 RETURNING ` + paymentColumns
+		row := tx.QueryRowContext(ctx, query, tenantID, id, money.Major(refunded+refund), status, providerStatus)
+		if err := scanPayment(row, &payment); err != nil {
+			return err
+		}
+		if strings.HasPrefix(payment.PaymentMethod, models.BankAccountPrefix) {
+			return queueRefund(ctx, tx, tenantID, payment, money.Major(refund), reason)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"payment_id": id,
+		"amount":     amount,
+		"status":     payment.Status,
+	}).Debug("payment refunded")
+	return &payment, nil
+}
+
+// CancelPayment cancels a payment that has not been refunded, including one
+// still waiting for a challenge.
+func (s *PaymentStore) CancelPayment(ctx context.Context, id int64, reason string, call ProviderCall) (*models.Payment, error) {
+	var payment models.Payment
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		current, err := lockPayment(ctx, tx, tenantID, id)
+		if err != nil {
+			return err
+		}
+		if current.Status != models.StatusCreated && current.Status != models.StatusRequiresAction {
+			return ErrInvalidState
+		}
+		providerStatus, err := callProvider(call, current)
+		if err != nil {
+			return err
+		}
+
+		if err := setEventReason(ctx, tx, reason); err != nil {
+			return err
+		}
+		query := `UPDATE payments SET status = $3, provider_status = $4 WHERE tenant_id = $1 AND id = $2 RETURNING ` + paymentColumns
+		row := tx.QueryRowContext(ctx, query, tenantID, id, models.StatusCancelled, providerStatus)
+		return scanPayment(row, &payment)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithField("payment_id", id).Debug("payment cancelled")
+	return &payment, nil
+}
+
+// PaymentEvents returns the history of a payment, oldest first. The events
+// outlive the payment, so a deleted payment still has a history.
+func (s *PaymentStore) PaymentEvents(ctx context.Context, id int64) ([]models.PaymentEvent, error) {
+	var events []models.PaymentEvent
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT id, payment_id, tenant_id, type, amount, currency, status, reason, created_at FROM payment_events WHERE tenant_id = $1 AND payment_id = $2 ORDER BY id`
+		events, err = scanEvents(tx.QueryContext(ctx, query, tenantID, id))
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return ErrPaymentNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// EventsAfter returns up to limit events with an id above after, for the
+// tenant in scope or every tenant for a cross-tenant scope.
+func (s *PaymentStore) EventsAfter(ctx context.Context, after int64, limit int) ([]models.PaymentEvent, error) {
+	var events []models.PaymentEvent
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		query := `SELECT id, payment_id, tenant_id, type, amount, currency, status, reason, created_at FROM payment_events WHERE ($1 = '' OR tenant_id = $1) AND id > $2 ORDER BY id LIMIT $3`
+		var err error
+		events, err = scanEvents(tx.QueryContext(ctx, query, scope.TenantID, after, limit))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func lockPayment(ctx context.Context, tx *sql.Tx, tenantID string, id int64) (models.Payment, error) {
+	var payment models.Payment
+	query := `SELECT amount, status, refunded_amount, provider, provider_ref, provider_status FROM payments WHERE tenant_id = $1 AND id = $2 FOR UPDATE`
+	err := tx.QueryRowContext(ctx, query, tenantID, id).Scan(&payment.Amount, &payment.Status, &payment.RefundedAmount, &payment.Provider, &payment.ProviderRef, &payment.ProviderStatus)
+	if err == sql.ErrNoRows {
+		return payment, ErrPaymentNotFound
+	}
+	return payment, err
+}
+
+func callProvider(call ProviderCall, current models.Payment) (string, error) {
+	if call == nil {
+		return current.ProviderStatus, nil
+	}
+	return call(current)
+}
+
+// setEventReason passes reason to the trigger that records payment events.
+func setEventReason(ctx context.Context, tx *sql.Tx, reason string) error {
+	_, err := tx.ExecContext(ctx, `SELECT set_config('app.event_reason', $1, true)`, reason)
+	return err
+}
+
+func scanEvents(rows *sql.Rows, err error) ([]models.PaymentEvent, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.PaymentEvent
+	for rows.Next() {
+		var e models.PaymentEvent
+		if err := rows.Scan(&e.ID, &e.PaymentID, &e.TenantID, &e.Type, &e.Amount, &e.Currency, &e.Status, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
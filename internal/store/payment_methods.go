@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/tenant"
+)
+
+var ErrPaymentMethodNotFound = errors.New("payment method not found")
+
+// SealedCard is a card encrypted by the vault: the card data under a data
+// key, and the data key wrapped by the vault key KeyID.
+type SealedCard struct {
+	KeyID      string
+	WrappedKey []byte
+	Ciphertext []byte
+}
+
+// CreatePaymentMethod stores a tokenized card. Only the sealed card holds
+// the card number.
+func (s *PaymentStore) CreatePaymentMethod(ctx context.Context, method models.PaymentMethod, sealed SealedCard) (*models.PaymentMethod, error) {
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `INSERT INTO payment_methods (tenant_id, token, brand, last4, exp_month, exp_year, key_id, wrapped_key, ciphertext) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING tenant_id, created_at`
+		row := tx.QueryRowContext(ctx, query, tenantID, method.Token, method.Brand, method.Last4, method.ExpMonth, method.ExpYear, sealed.KeyID, sealed.WrappedKey, sealed.Ciphertext)
+		return row.Scan(&method.TenantID, &method.CreatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithField("payment_method", method.Token).Debug("payment method created")
+	return &method, nil
+}
+
+// GetPaymentMethod returns what is safe to show of a vaulted card.
+func (s *PaymentStore) GetPaymentMethod(ctx context.Context, token string) (*models.PaymentMethod, error) {
+	var method models.PaymentMethod
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT token, brand, last4, exp_month, exp_year, tenant_id, created_at FROM payment_methods WHERE tenant_id = $1 AND token = $2`
+		err = tx.QueryRowContext(ctx, query, tenantID, token).Scan(&method.Token, &method.Brand, &method.Last4, &method.ExpMonth, &method.ExpYear, &method.TenantID, &method.CreatedAt)
+		if err == sql.ErrNoRows {
+			return ErrPaymentMethodNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &method, nil
+}
+
+// SealedPaymentMethod returns the encrypted card behind token. Only the
+// vault can open it.
+func (s *PaymentStore) SealedPaymentMethod(ctx context.Context, token string) (*SealedCard, error) {
+	var sealed SealedCard
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT key_id, wrapped_key, ciphertext FROM payment_methods WHERE tenant_id = $1 AND token = $2`
+		err = tx.QueryRowContext(ctx, query, tenantID, token).Scan(&sealed.KeyID, &sealed.WrappedKey, &sealed.Ciphertext)
+		if err == sql.ErrNoRows {
+			return ErrPaymentMethodNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sealed, nil
+}
+
+// checkPaymentMethod makes sure a payment references a card of its own
+// tenant.
+func checkPaymentMethod(ctx context.Context, tx *sql.Tx, tenantID, token string) error {
+	var found int
+	err := tx.QueryRowContext(ctx, `SELECT 1 FROM payment_methods WHERE tenant_id = $1 AND token = $2`, tenantID, token).Scan(&found)
+	if err == sql.ErrNoRows {
+		return ErrPaymentMethodNotFound
+	}
+	return err
+}
@@ -1,11 +1,98 @@
 package store
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"go-lang-final/internal/idempotency"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/metadata"
 	"go-lang-final/internal/models"
+	"go-lang-final/internal/money"
+	"go-lang-final/internal/tenant"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrPaymentNotFound    = errors.New("payment not found")
+	ErrQuotaExceeded      = errors.New("tenant payment quota exceeded")
+	ErrCurrencyNotAllowed = errors.New("currency not allowed for tenant")
+	ErrAmountTooLarge     = errors.New("amount exceeds tenant limit")
+	ErrDailyQuotaExceeded = errors.New("tenant daily creation quota exceeded")
+	ErrInvalidState       = errors.New("payment status does not allow this operation")
+	ErrRefundTooLarge     = errors.New("refund exceeds the unrefunded amount")
+	// ErrAmountPrecision reports an amount with more decimals than its
+	// currency has, or than payments are stored with.
+	ErrAmountPrecision = errors.New("amount has more decimals than its currency allows")
+	// ErrAmountFixed reports a change to the amount or currency of a
+	// payment a provider holds, or that was refunded or cancelled.
+	ErrAmountFixed = errors.New("amount and currency of this payment cannot change")
+	// ErrPaymentCaptured reports deleting a payment a provider holds; it
+	// has to be cancelled first.
+	ErrPaymentCaptured = errors.New("payment is held by a provider and must be cancelled first")
+	// ErrInvalidPaymentDetails reports metadata, a description or a
+	// statement descriptor over the limits of package metadata.
+	ErrInvalidPaymentDetails = errors.New("invalid payment details")
 )
 
+// paymentColumns are the columns of payments scanPayment reads.
+const paymentColumns = `tenant_id, id, amount, currency, status, refunded_amount, provider, provider_ref, provider_status, payment_method, metadata, description, statement_descriptor`
+
+// PaymentFilter narrows ListPayments; empty fields match every payment. A
+// payment matches Metadata when it has each of its keys with the same
+// value. Query searches the descriptions, and the best matches come first.
+type PaymentFilter struct {
+	Currency string
+	Amount   string
+	Metadata map[string]string
+	Query    string
+}
+
+// PaymentDetails changes what integrators attach to a payment. Metadata is
+// merged into the payment's, a key with an empty value being removed. A nil
+// Description or StatementDescriptor is left as it is.
+type PaymentDetails struct {
+	Metadata            map[string]string
+	Description         *string
+	StatementDescriptor *string
+}
+
+func (d PaymentDetails) empty() bool {
+	return len(d.Metadata) == 0 && d.Description == nil && d.StatementDescriptor == nil
+}
+
+// Rejected reports whether CreatePayment refused the payment, as opposed to
+// failing for a reason that leaves unknown whether it was stored.
+func Rejected(err error) bool {
+	var pqErr *pq.Error
+	switch {
+	case errors.Is(err, ErrQuotaExceeded), errors.Is(err, ErrCurrencyNotAllowed), errors.Is(err, ErrAmountTooLarge),
+		errors.Is(err, ErrDailyQuotaExceeded), errors.Is(err, idempotency.ErrKeyReused), errors.Is(err, tenant.ErrNoTenant),
+		errors.Is(err, ErrPaymentMethodNotFound), errors.Is(err, ErrBankAccountNotFound), errors.Is(err, ErrCurrencyNotSEPA),
+		errors.Is(err, ErrCustomerNotFound), errors.Is(err, ErrCustomerErased), errors.Is(err, ErrInvalidPaymentDetails):
+		return true
+	case errors.As(err, &pqErr):
+		return pqErr.Code.Class() == "23" // integrity constraint violation
+	default:
+		return false
+	}
+}
+
+// Conflict reports whether a write failed on a unique constraint, such as
+// a payment id that is taken. It says nothing about the payment itself.
+func Conflict(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
 type PaymentStore struct {
 	DB *sql.DB
 }
@@ -23,59 +110,500 @@ func NewPaymentStore(dsn string) (*PaymentStore, error) {
 	return &PaymentStore{DB: db}, nil
 }
 
-func (s *PaymentStore) CreatePayment(payment models.Payment) error {
-	query := `INSERT INTO payments (id, amount, currency) VALUES ($1, $2, $3)`
-	_, err := s.DB.Exec(query, payment.ID, payment.Amount, payment.Currency)
+// inTenant runs fn in a transaction scoped to the tenant in ctx. Besides
+// the explicit tenant_id filters in every query, the scope is published to
+// Postgres so the row-level security policies on payments apply as well.
+func (s *PaymentStore) inTenant(ctx context.Context, fn func(tx *sql.Tx, scope tenant.Scope) error) error {
+	scope, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrNoTenant
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	crossTenant := "off"
+	if scope.All {
+		crossTenant = "on"
+	}
+	query := `SELECT set_config('app.tenant_id', $1, true), set_config('app.cross_tenant', $2, true)`
+	if _, err := tx.ExecContext(ctx, query, scope.TenantID, crossTenant); err != nil {
+		return err
+	}
+
+	if err := fn(tx, scope); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkTenantLimits enforces the per-tenant configuration. The tenant row
+// is locked so concurrent creates cannot both slip under the quota.
+func checkTenantLimits(ctx context.Context, tx *sql.Tx, tenantID string, payment models.Payment, creating bool) error {
+	var t tenant.Tenant
+	query := `SELECT allowed_currencies, max_payment_amount, max_payments, daily_payment_limit, daily_amount_limit FROM tenants WHERE id = $1 FOR UPDATE`
+	err := tx.QueryRowContext(ctx, query, tenantID).Scan(pq.Array(&t.AllowedCurrencies), &t.MaxPaymentAmount, &t.MaxPayments, &t.DailyPaymentLimit, &t.DailyAmountLimit)
+	if err == sql.ErrNoRows {
+		return tenant.ErrNoTenant
+	}
+	if err != nil {
+		return err
+	}
+
+	if !t.AllowsCurrency(payment.Currency) {
+		return ErrCurrencyNotAllowed
+	}
+	if t.MaxPaymentAmount != nil && payment.Amount > *t.MaxPaymentAmount {
+		return ErrAmountTooLarge
+	}
+	if creating && t.MaxPayments != nil {
+		var count int64
+		if err := tx.QueryRowContext(ctx, `SELECT count(*) FROM payments WHERE tenant_id = $1`, tenantID).Scan(&count); err != nil {
+			return err
+		}
+		if count >= *t.MaxPayments {
+			return ErrQuotaExceeded
+		}
+	}
+	if creating {
+		return recordDailyUsage(ctx, tx, &t, tenantID, payment.Amount)
+	}
+	return nil
+}
+
+// recordDailyUsage counts the payment against today's creation volume. The
+// counter lives in the same transaction as the insert, so a rejected or
+// failed create does not consume quota.
+func recordDailyUsage(ctx context.Context, tx *sql.Tx, t *tenant.Tenant, tenantID string, amount float64) error {
+	var (
+		count int64
+		total float64
+	)
+	query := `INSERT INTO daily_quotas (tenant_id, day, payments, amount) VALUES ($1, (now() AT TIME ZONE 'UTC')::date, 1, $2)
+		ON CONFLICT (tenant_id, day) DO UPDATE SET payments = daily_quotas.payments + 1, amount = daily_quotas.amount + EXCLUDED.amount
+		RETURNING payments, amount`
+	if err := tx.QueryRowContext(ctx, query, tenantID, amount).Scan(&count, &total); err != nil {
+		return err
+	}
+
+	if t.DailyPaymentLimit != nil && count > *t.DailyPaymentLimit {
+		return ErrDailyQuotaExceeded
+	}
+	if t.DailyAmountLimit != nil && total > *t.DailyAmountLimit {
+		return ErrDailyQuotaExceeded
+	}
+	return nil
+}
+
+// CreatePayment inserts a payment, with status created unless the payment
+// says otherwise. When the context carries an idempotency key, a replay of
+// the same request succeeds without creating anything and a replay with a
+// different request fails with idempotency.ErrKeyReused. A payment with a
+// Conversion is converted to its settlement currency, and the conversion is
+// filled in. A captured payment with a Fee is charged the fee of the
+// tenant's pricing plan, if it has one, and the fee is filled in.
+func (s *PaymentStore) CreatePayment(ctx context.Context, payment models.Payment) error {
+	if !money.Exact(payment.Amount, payment.Currency) {
+		return ErrAmountPrecision
+	}
+	if err := checkDetails(&payment); err != nil {
+		return err
+	}
+	key := idempotency.FromContext(ctx)
+	replayed := false
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if key != "" {
+			if replayed, err = replayedCreate(ctx, tx, tenantID, key, payment); err != nil {
+				return err
+			}
+			if replayed {
+				// The replay reports the conversion and fee of the original.
+				if payment.Conversion != nil {
+					if err := loadConversion(ctx, tx, tenantID, payment.ID, payment.Conversion); err != ErrConversionNotFound {
+						return err
+					}
+				}
+				if payment.Fee != nil {
+					if err := loadFee(ctx, tx, tenantID, payment.ID, payment.Fee); err != ErrFeeNotFound {
+						return err
+					}
+				}
+				return nil
+			}
+		}
+		if err := checkTenantLimits(ctx, tx, tenantID, payment, true); err != nil {
+			return err
+		}
+
+		switch {
+		case strings.HasPrefix(payment.PaymentMethod, models.BankAccountPrefix):
+			if err := checkBankAccount(ctx, tx, tenantID, payment); err != nil {
+				return err
+			}
+		case payment.PaymentMethod != "":
+			if err := checkPaymentMethod(ctx, tx, tenantID, payment.PaymentMethod); err != nil {
+				return err
+			}
+		}
+		if payment.Customer != "" {
+			if err := checkCustomer(ctx, tx, tenantID, payment.Customer); err != nil {
+				return err
+			}
+		}
+
+		status := payment.Status
+		if status == "" {
+			status = models.StatusCreated
+		}
+		query := `INSERT INTO payments (tenant_id, id, amount, currency, status, provider, provider_ref, provider_status, payment_method) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+		if _, err = tx.ExecContext(ctx, query, tenantID, payment.ID, payment.Amount, payment.Currency, status, payment.Provider, payment.ProviderRef, payment.ProviderStatus, payment.PaymentMethod); err != nil {
+			return err
+		}
+		if payment.Customer != "" || len(payment.Metadata) > 0 || payment.Description != "" || payment.StatementDescriptor != "" {
+			metadata, err := marshalMetadata(payment.Metadata)
+			if err != nil {
+				return err
+			}
+			query = `UPDATE payments SET customer_id = NULLIF($3, ''), metadata = $4, description = $5, statement_descriptor = $6 WHERE tenant_id = $1 AND id = $2`
+			if _, err := tx.ExecContext(ctx, query, tenantID, payment.ID, payment.Customer, metadata, payment.Description, payment.StatementDescriptor); err != nil {
+				return err
+			}
+		}
+		if payment.Routing != nil {
+			if err := recordRouting(ctx, tx, tenantID, payment); err != nil {
+				return err
+			}
+		}
+		if payment.Conversion != nil {
+			if err := convert(ctx, tx, tenantID, payment); err != nil {
+				return err
+			}
+		}
+		if payment.Fee != nil && status == models.StatusCreated {
+			if err := chargeFee(ctx, tx, tenantID, payment, payment.Fee); err != nil {
+				return err
+			}
+		}
+		if key != "" {
+			query = `INSERT INTO idempotency_keys (tenant_id, key, request_hash, payment_id) VALUES ($1, $2, $3, $4)`
+			_, err = tx.ExecContext(ctx, query, tenantID, key, requestHash(payment), payment.ID)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"payment_id": payment.ID,
+		"amount":     payment.Amount,
+		"currency":   payment.Currency,
+		"replayed":   replayed,
+	}).Debug("payment created")
+	return nil
+}
+
+// recordRouting stores how the payment's provider was chosen.
+func recordRouting(ctx context.Context, tx *sql.Tx, tenantID string, payment models.Payment) error {
+	attempts, err := json.Marshal(payment.Routing.Attempts)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO payment_routing_decisions (tenant_id, payment_id, rule, country, provider, attempts) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err = tx.ExecContext(ctx, query, tenantID, payment.ID, payment.Routing.Rule, payment.Routing.Country, payment.Provider, attempts)
 	return err
 }
 
-func (s *PaymentStore) GetPayment(id int64) (*models.Payment, error) {
-	query := `SELECT id, amount, currency FROM payments WHERE id = $1`
-	row := s.DB.QueryRow(query, id)
+// replayedCreate reports whether key already created this exact payment.
+func replayedCreate(ctx context.Context, tx *sql.Tx, tenantID, key string, payment models.Payment) (bool, error) {
+	var hash string
+	err := tx.QueryRowContext(ctx, `SELECT request_hash FROM idempotency_keys WHERE tenant_id = $1 AND key = $2`, tenantID, key).Scan(&hash)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return false, nil
+	case err != nil:
+		return false, err
+	case hash != requestHash(payment):
+		return false, idempotency.ErrKeyReused
+	default:
+		return true, nil
+	}
+}
+
+// requestHash identifies the request that created payment by every field
+// of it that is stored, so a replay that differs in any of them is refused.
+func requestHash(payment models.Payment) string {
+	// encoding/json sorts the metadata keys, so equal requests encode alike.
+	request, _ := json.Marshal([]interface{}{
+		payment.ID, strconv.FormatFloat(payment.Amount, 'f', -1, 64), payment.Currency, payment.PaymentMethod, payment.Customer,
+		payment.Metadata, payment.Description, payment.StatementDescriptor,
+	})
+	sum := sha256.Sum256(request)
+	return hex.EncodeToString(sum[:])
+}
 
+func (s *PaymentStore) GetPayment(ctx context.Context, id int64) (*models.Payment, error) {
 	var payment models.Payment
-	if err := row.Scan(&payment.ID, &payment.Amount, &payment.Currency); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("payment not found")
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT ` + paymentColumns + ` FROM payments WHERE tenant_id = $1 AND id = $2`
+		if err := scanPayment(tx.QueryRowContext(ctx, query, tenantID, id), &payment); err != nil {
+			if err == sql.ErrNoRows {
+				return ErrPaymentNotFound
+			}
+			return err
 		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return &payment, nil
 }
 
-func (s *PaymentStore) UpdatePayment(id int64, payment models.Payment) error {
-	query := `UPDATE payments SET amount = $2, currency = $3 WHERE id = $1`
-	_, err := s.DB.Exec(query, id, payment.Amount, payment.Currency)
-	return err
+// UpdatePayment changes the amount and currency of a payment, and its
+// details unless they are empty. The amount and currency only change while
+// the payment is created, unrefunded and not held by a provider; otherwise
+// they must be passed unchanged, and ErrAmountFixed is returned if not.
+func (s *PaymentStore) UpdatePayment(ctx context.Context, id int64, payment models.Payment, details PaymentDetails) error {
+	if !money.Exact(payment.Amount, payment.Currency) {
+		return ErrAmountPrecision
+	}
+	if err := checkDetailsUpdate(details); err != nil {
+		return err
+	}
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if err := checkTenantLimits(ctx, tx, tenantID, payment, false); err != nil {
+			return err
+		}
+
+		query := `UPDATE payments SET amount = $3, currency = $4 WHERE tenant_id = $1 AND id = $2
+			AND ((amount = $3 AND currency = $4) OR (status = 'created' AND refunded_amount = 0 AND provider_ref = ''))`
+		res, err := tx.ExecContext(ctx, query, tenantID, id, payment.Amount, payment.Currency)
+		if err != nil {
+			return err
+		}
+		if err := expectGuardedRow(ctx, tx, tenantID, id, res, ErrAmountFixed); err != nil || details.empty() {
+			return err
+		}
+		return updateDetails(ctx, tx, tenantID, id, details)
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"payment_id": id,
+		"amount":     payment.Amount,
+		"currency":   payment.Currency,
+	}).Debug("payment updated")
+	return nil
 }
 
-func (s *PaymentStore) DeletePayment(id int64) error {
-	query := `DELETE FROM payments WHERE id = $1`
-	_, err := s.DB.Exec(query, id)
-	return err
+// DeletePayment deletes a payment. A payment a provider holds is only
+// deleted once cancelled; before that ErrPaymentCaptured is returned.
+func (s *PaymentStore) DeletePayment(ctx context.Context, id int64) error {
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `DELETE FROM payments WHERE tenant_id = $1 AND id = $2 AND (provider_ref = '' OR status = 'cancelled')`
+		res, err := tx.ExecContext(ctx, query, tenantID, id)
+		if err != nil {
+			return err
+		}
+		return expectGuardedRow(ctx, tx, tenantID, id, res, ErrPaymentCaptured)
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).WithField("payment_id", id).Debug("payment deleted")
+	return nil
 }
 
-func (s *PaymentStore) ListPayments(currency string, amount string, page int, pageSize int) ([]models.Payment, error) {
-	query := `SELECT id, amount, currency FROM payments WHERE currency = $1 AND amount = $2 LIMIT $3 OFFSET $4`
-	rows, err := s.DB.Query(query, currency, amount, pageSize, (page-1)*pageSize)
+// ListPayments lists payments of the tenant in scope, or of every tenant
+// for a cross-tenant scope, that match filter.
+func (s *PaymentStore) ListPayments(ctx context.Context, filter PaymentFilter, page int, pageSize int) ([]models.Payment, error) {
+	metadata, err := marshalMetadata(filter.Metadata)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
 	var payments []models.Payment
-	for rows.Next() {
-		var payment models.Payment
-		if err := rows.Scan(&payment.ID, &payment.Amount, &payment.Currency); err != nil {
-			return nil, err
+	err = s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		query := `SELECT ` + paymentColumns + ` FROM payments
+			WHERE ($1 = '' OR tenant_id = $1) AND ($2 = '' OR currency = $2) AND ($3 = '' OR amount = NULLIF($3, '')::numeric)
+				AND ($4::jsonb = '{}' OR metadata @> $4::jsonb) AND ($5 = '' OR description_search @@ websearch_to_tsquery('simple', $5))
+			ORDER BY CASE WHEN $5 = '' THEN 0 ELSE ts_rank(description_search, websearch_to_tsquery('simple', $5)) END DESC, id
+			LIMIT $6 OFFSET $7`
+		rows, err := tx.QueryContext(ctx, query, scope.TenantID, filter.Currency, filter.Amount, metadata, filter.Query, pageSize, (page-1)*pageSize)
+		if err != nil {
+			return err
 		}
-		payments = append(payments, payment)
-	}
+		defer rows.Close()
 
-	if err := rows.Err(); err != nil {
+		for rows.Next() {
+			var payment models.Payment
+			if err := scanPayment(rows, &payment); err != nil {
+				return err
+			}
+			payments = append(payments, payment)
+		}
+		return rows.Err()
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return payments, nil
 }
+
+func expectOneRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrPaymentNotFound
+	}
+	return nil
+}
+
+// expectGuardedRow is expectOneRow for statements whose WHERE clause also
+// checks the state of the payment: when no row matched, it returns refused
+// if the payment exists.
+func expectGuardedRow(ctx context.Context, tx *sql.Tx, tenantID string, id int64, res sql.Result, refused error) error {
+	if err := expectOneRow(res); err != ErrPaymentNotFound {
+		return err
+	}
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM payments WHERE tenant_id = $1 AND id = $2)`
+	if err := tx.QueryRowContext(ctx, query, tenantID, id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return refused
+	}
+	return ErrPaymentNotFound
+}
+
+// checkDetails checks the metadata, description and statement descriptor
+// of a new payment, dropping metadata keys without a value.
+func checkDetails(payment *models.Payment) error {
+	for k, v := range payment.Metadata {
+		if v == "" {
+			delete(payment.Metadata, k)
+		}
+	}
+	if err := metadata.Check(payment.Metadata); err != nil {
+		return fmt.Errorf("%w: metadata %v", ErrInvalidPaymentDetails, err)
+	}
+	if err := metadata.CheckDescription(payment.Description); err != nil {
+		return fmt.Errorf("%w: description %v", ErrInvalidPaymentDetails, err)
+	}
+	if err := metadata.CheckDescriptor(payment.StatementDescriptor); err != nil {
+		return fmt.Errorf("%w: statement_descriptor %v", ErrInvalidPaymentDetails, err)
+	}
+	return nil
+}
+
+func checkDetailsUpdate(details PaymentDetails) error {
+	if err := metadata.Check(details.Metadata); err != nil {
+		return fmt.Errorf("%w: metadata %v", ErrInvalidPaymentDetails, err)
+	}
+	if details.Description != nil {
+		if err := metadata.CheckDescription(*details.Description); err != nil {
+			return fmt.Errorf("%w: description %v", ErrInvalidPaymentDetails, err)
+		}
+	}
+	if details.StatementDescriptor != nil {
+		if err := metadata.CheckDescriptor(*details.StatementDescriptor); err != nil {
+			return fmt.Errorf("%w: statement_descriptor %v", ErrInvalidPaymentDetails, err)
+		}
+	}
+	return nil
+}
+
+// updateDetails merges details into a payment. The merged metadata has to
+// stay within the limits too.
+func updateDetails(ctx context.Context, tx *sql.Tx, tenantID string, id int64, details PaymentDetails) error {
+	set, removed := map[string]string{}, []string{}
+	for k, v := range details.Metadata {
+		if v == "" {
+			removed = append(removed, k)
+		} else {
+			set[k] = v
+		}
+	}
+	patch, err := json.Marshal(set)
+	if err != nil {
+		return err
+	}
+
+	var merged []byte
+	query := `UPDATE payments SET metadata = (metadata || $3::jsonb) - $4::text[], description = COALESCE($5, description),
+		statement_descriptor = COALESCE($6, statement_descriptor) WHERE tenant_id = $1 AND id = $2 RETURNING metadata`
+	err = tx.QueryRowContext(ctx, query, tenantID, id, patch, pq.Array(removed), details.Description, details.StatementDescriptor).Scan(&merged)
+	if err != nil {
+		return err
+	}
+	m, err := unmarshalMetadata(merged)
+	if err != nil {
+		return err
+	}
+	if err := metadata.Check(m); err != nil {
+		return fmt.Errorf("%w: metadata %v", ErrInvalidPaymentDetails, err)
+	}
+	return nil
+}
+
+func scanPayment(row scanner, payment *models.Payment) error {
+	var metadata []byte
+	if err := row.Scan(&payment.TenantID, &payment.ID, &payment.Amount, &payment.Currency, &payment.Status, &payment.RefundedAmount, &payment.Provider, &payment.ProviderRef, &payment.ProviderStatus, &payment.PaymentMethod, &metadata, &payment.Description, &payment.StatementDescriptor); err != nil {
+		return err
+	}
+	m, err := unmarshalMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	payment.Metadata = m
+	return nil
+}
+
+func marshalMetadata(m map[string]string) ([]byte, error) {
+	if m == nil {
+		m = map[string]string{}
+	}
+	return json.Marshal(m)
+}
+
+// unmarshalMetadata decodes a metadata column, leaving empty metadata nil.
+func unmarshalMetadata(b []byte) (map[string]string, error) {
+	var m map[string]string
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	if len(m) == 0 {
+		return nil, nil
+	}
+	return m, nil
+}
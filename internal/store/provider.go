@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/tenant"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PendingProviderPayments returns up to limit payments whose provider
+// transaction can still change on its own: those waiting for a challenge and
+// those captured but not settled. A cross-tenant scope sees every tenant.
+func (s *PaymentStore) PendingProviderPayments(ctx context.Context, limit int) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		query := `SELECT ` + paymentColumns + ` FROM payments WHERE ($1 = '' OR tenant_id = $1) AND provider_ref <> '' AND (status = 'requires_action' OR provider_status IN ('authorized', 'captured')) ORDER BY id LIMIT $2`
+		rows, err := tx.QueryContext(ctx, query, scope.TenantID, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var payment models.Payment
+			if err := scanPayment(rows, &payment); err != nil {
+				return err
+			}
+			payments = append(payments, payment)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+// RecordProviderStatus stores a change the provider made to the payment's
+// transaction, moving the payment from status from to status to. A payment
+// that left status from in the meantime, say because it was cancelled, is
+// not touched and ErrInvalidState is returned. With chargeFees a payment
+// captured by the change is charged the fee of the tenant's pricing plan.
+func (s *PaymentStore) RecordProviderStatus(ctx context.Context, id int64, from, to, providerStatus, reason string, chargeFees bool) (*models.Payment, error) {
+	var payment models.Payment
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		if err := setEventReason(ctx, tx, reason); err != nil {
+			return err
+		}
+		query := `UPDATE payments SET status = $4, provider_status = $5 WHERE tenant_id = $1 AND id = $2 AND status = $3 RETURNING ` + paymentColumns
+		row := tx.QueryRowContext(ctx, query, tenantID, id, from, to, providerStatus)
+		err = scanPayment(row, &payment)
+		if err == sql.ErrNoRows {
+			return ErrInvalidState
+		}
+		if err != nil {
+			return err
+		}
+		if chargeFees && from != models.StatusCreated && to == models.StatusCreated {
+			return chargeFee(ctx, tx, tenantID, payment, &models.PaymentFee{})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"payment_id":      id,
+		"status":          payment.Status,
+		"provider_status": payment.ProviderStatus,
+	}).Debug("payment provider status recorded")
+	return &payment, nil
+}
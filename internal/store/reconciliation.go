@@ -0,0 +1,340 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/money"
+	"go-lang-final/internal/reconcile"
+	"go-lang-final/internal/tenant"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrRunNotFound  = errors.New("reconciliation run not found")
+	ErrLineNotFound = errors.New("reconciliation line not found")
+	ErrNotException = errors.New("only unresolved exceptions can be resolved")
+)
+
+// runPrefix starts the ids of reconciliation runs.
+const runPrefix = "rec_"
+
+const reconciliationLineColumns = `line, bank_ref, reference, description, direction, amount, currency, booking_date, status, payment_id, event_id, difference, resolution, resolution_note, resolved_by, resolved_at`
+
+// CreateReconciliationRun matches the statement's lines against the
+// payments and refunds of the tenant in scope and stores the outcome.
+// Candidates are the created and refunded events of payments around the
+// statement's booking dates; events a line of an earlier run settled count
+// as reconciled, so importing a statement twice flags its lines duplicated.
+func (s *PaymentStore) CreateReconciliationRun(ctx context.Context, statement *reconcile.Statement, opts reconcile.Options) (*models.ReconciliationRun, error) {
+	run := models.ReconciliationRun{
+		Format:         statement.Format,
+		StatementID:    statement.ID,
+		Account:        statement.Account,
+		Tolerance:      money.Major(opts.Tolerance),
+		DateWindowDays: opts.DateWindow,
+		Lines:          len(statement.Entries),
+	}
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if run.ID, err = newID(runPrefix); err != nil {
+			return err
+		}
+
+		var candidates []reconcile.Candidate
+		if len(statement.Entries) > 0 {
+			if candidates, err = settlementCandidates(ctx, tx, tenantID, statement.Entries, opts.DateWindow); err != nil {
+				return err
+			}
+		}
+		results := reconcile.Match(statement.Entries, candidates, opts)
+		for _, r := range results {
+			switch r.Status {
+			case reconcile.Matched:
+				run.Matched++
+			case reconcile.Unmatched:
+				run.Unmatched++
+			case reconcile.Duplicated:
+				run.Duplicated++
+			case reconcile.AmountMismatch:
+				run.AmountMismatches++
+			}
+		}
+
+		query := `INSERT INTO reconciliation_runs (tenant_id, id, format, statement_id, account, tolerance, date_window_days, lines, matched, unmatched, duplicated, amount_mismatches)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING tenant_id, created_at`
+		err = tx.QueryRowContext(ctx, query, tenantID, run.ID, run.Format, run.StatementID, run.Account, run.Tolerance, run.DateWindowDays,
+			run.Lines, run.Matched, run.Unmatched, run.Duplicated, run.AmountMismatches).Scan(&run.TenantID, &run.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		query = `INSERT INTO reconciliation_lines (tenant_id, run_id, line, bank_ref, reference, description, direction, amount, currency, booking_date, status, payment_id, event_id, difference)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+		for i, e := range statement.Entries {
+			line := reconciliationLine(i+1, e, results[i])
+			var paymentID, eventID sql.NullInt64
+			if c := results[i].Candidate; c != nil {
+				paymentID = sql.NullInt64{Int64: c.PaymentID, Valid: true}
+				eventID = sql.NullInt64{Int64: c.EventID, Valid: true}
+			}
+			if _, err := tx.ExecContext(ctx, query, tenantID, run.ID, line.Line, line.BankRef, line.Reference, line.Description, line.Direction,
+				line.Amount, line.Currency, line.BookingDate, line.Status, paymentID, eventID, line.Difference); err != nil {
+				return err
+			}
+			run.Items = append(run.Items, line)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"run_id":     run.ID,
+		"lines":      run.Lines,
+		"matched":    run.Matched,
+		"exceptions": run.Lines - run.Matched,
+	}).Info("bank statement reconciled")
+	return &run, nil
+}
+
+// settlementCandidates loads the payment and refund events that lines
+// booked between the earliest and latest entry could settle.
+func settlementCandidates(ctx context.Context, tx *sql.Tx, tenantID string, entries []reconcile.Entry, window int) ([]reconcile.Candidate, error) {
+	day := func(t time.Time) time.Time {
+		t = t.UTC()
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	from, to := day(entries[0].BookingDate), day(entries[0].BookingDate)
+	for _, e := range entries[1:] {
+		if d := day(e.BookingDate); d.Before(from) {
+			from = d
+		} else if d.After(to) {
+			to = d
+		}
+	}
+	// A day of slack on both ends covers time zones; Match compares dates.
+	from, to = from.AddDate(0, 0, -window-1), to.AddDate(0, 0, window+2)
+
+	query := `SELECT e.id, e.payment_id, e.type, e.amount, e.currency, e.created_at, COALESCE(p.provider_ref, ''),
+			ARRAY(SELECT t.id FROM credit_transfers t WHERE t.tenant_id = e.tenant_id AND t.payment_id = e.payment_id AND t.kind = 'refund'),
+			EXISTS (SELECT 1 FROM reconciliation_lines l WHERE l.tenant_id = e.tenant_id AND l.event_id = e.id AND (l.status = 'matched' OR l.resolution = 'accepted'))
+		FROM payment_events e LEFT JOIN payments p ON p.tenant_id = e.tenant_id AND p.id = e.payment_id
+		WHERE e.tenant_id = $1 AND e.type IN ('created', 'refunded') AND e.created_at >= $2 AND e.created_at < $3
+		ORDER BY e.id`
+	rows, err := tx.QueryContext(ctx, query, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []reconcile.Candidate
+	for rows.Next() {
+		var (
+			c           reconcile.Candidate
+			eventType   string
+			amount      float64
+			providerRef string
+			transfers   []string
+		)
+		if err := rows.Scan(&c.EventID, &c.PaymentID, &eventType, &amount, &c.Currency, &c.Date, &providerRef, pq.Array(&transfers), &c.Reconciled); err != nil {
+			return nil, err
+		}
+		c.Amount = money.Minor(amount)
+		c.References = []string{reconcile.PaymentReference(c.PaymentID)}
+		if providerRef != "" {
+			c.References = append(c.References, providerRef)
+		}
+		c.Kind = reconcile.KindPayment
+		if eventType == "refunded" {
+			c.Kind = reconcile.KindRefund
+			c.References = append(c.References, transfers...)
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+func reconciliationLine(n int, e reconcile.Entry, r reconcile.Result) models.ReconciliationLine {
+	line := models.ReconciliationLine{
+		Line:        n,
+		BankRef:     e.BankRef,
+		Reference:   strings.Join(e.References, " "),
+		Description: e.Text,
+		Direction:   "debit",
+		Amount:      money.Major(e.Amount),
+		Currency:    e.Currency,
+		BookingDate: e.BookingDate.Format("2006-01-02"),
+		Status:      r.Status,
+		Difference:  money.Major(r.Difference),
+	}
+	if e.Credit {
+		line.Direction = "credit"
+	}
+	if r.Candidate != nil {
+		line.PaymentID, line.EventID = r.Candidate.PaymentID, r.Candidate.EventID
+	}
+	return line
+}
+
+// GetReconciliationRun returns a run with its lines: all of them when
+// lines is empty, those with that status, or with "exceptions" the lines
+// that are neither matched nor resolved.
+func (s *PaymentStore) GetReconciliationRun(ctx context.Context, id, lines string) (*models.ReconciliationRun, error) {
+	var run models.ReconciliationRun
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT ` + reconciliationRunColumns + ` FROM reconciliation_runs WHERE tenant_id = $1 AND id = $2`
+		err = scanRun(tx.QueryRowContext(ctx, query, tenantID, id), &run)
+		if err == sql.ErrNoRows {
+			return ErrRunNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		query = `SELECT ` + reconciliationLineColumns + ` FROM reconciliation_lines WHERE tenant_id = $1 AND run_id = $2
+			AND ($3 = '' OR ($3 = 'exceptions' AND status <> 'matched' AND resolution = '') OR status = $3) ORDER BY line`
+		rows, err := tx.QueryContext(ctx, query, tenantID, id, lines)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var line models.ReconciliationLine
+			if err := scanLine(rows, &line); err != nil {
+				return err
+			}
+			run.Items = append(run.Items, line)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ListReconciliationRuns returns the tenant's runs, newest first.
+func (s *PaymentStore) ListReconciliationRuns(ctx context.Context, page, pageSize int) ([]models.ReconciliationRun, error) {
+	var runs []models.ReconciliationRun
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT ` + reconciliationRunColumns + ` FROM reconciliation_runs WHERE tenant_id = $1 ORDER BY created_at DESC, id LIMIT $2 OFFSET $3`
+		rows, err := tx.QueryContext(ctx, query, tenantID, pageSize, (page-1)*pageSize)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var run models.ReconciliationRun
+			if err := scanRun(rows, &run); err != nil {
+				return err
+			}
+			runs = append(runs, run)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// ResolveReconciliationLine records how an exception was dealt with.
+// Accepting a line may name the payment it settles when matching missed
+// it; paymentID is zero otherwise.
+func (s *PaymentStore) ResolveReconciliationLine(ctx context.Context, runID string, n int, resolution string, paymentID int64, note, resolvedBy string) (*models.ReconciliationLine, error) {
+	var line models.ReconciliationLine
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		var status, current string
+		query := `SELECT status, resolution FROM reconciliation_lines WHERE tenant_id = $1 AND run_id = $2 AND line = $3 FOR UPDATE`
+		err = tx.QueryRowContext(ctx, query, tenantID, runID, n).Scan(&status, &current)
+		if err == sql.ErrNoRows {
+			return ErrLineNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if status == reconcile.Matched || current != "" {
+			return ErrNotException
+		}
+		if paymentID != 0 {
+			var found int
+			err := tx.QueryRowContext(ctx, `SELECT 1 FROM payments WHERE tenant_id = $1 AND id = $2`, tenantID, paymentID).Scan(&found)
+			if err == sql.ErrNoRows {
+				return ErrPaymentNotFound
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		query = `UPDATE reconciliation_lines SET resolution = $4, payment_id = COALESCE($5, payment_id), resolution_note = $6, resolved_by = $7, resolved_at = now()
+			WHERE tenant_id = $1 AND run_id = $2 AND line = $3 RETURNING ` + reconciliationLineColumns
+		return scanLine(tx.QueryRowContext(ctx, query, tenantID, runID, n, resolution, sql.NullInt64{Int64: paymentID, Valid: paymentID != 0}, note, resolvedBy), &line)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"run_id":     runID,
+		"line":       n,
+		"resolution": resolution,
+	}).Info("reconciliation exception resolved")
+	return &line, nil
+}
+
+const reconciliationRunColumns = `id, format, statement_id, account, tolerance, date_window_days, lines, matched, unmatched, duplicated, amount_mismatches, tenant_id, created_at`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRun(row scanner, run *models.ReconciliationRun) error {
+	return row.Scan(&run.ID, &run.Format, &run.StatementID, &run.Account, &run.Tolerance, &run.DateWindowDays,
+		&run.Lines, &run.Matched, &run.Unmatched, &run.Duplicated, &run.AmountMismatches, &run.TenantID, &run.CreatedAt)
+}
+
+func scanLine(row scanner, line *models.ReconciliationLine) error {
+	var (
+		paymentID, eventID sql.NullInt64
+		bookingDate        time.Time
+		resolvedAt         sql.NullTime
+	)
+	err := row.Scan(&line.Line, &line.BankRef, &line.Reference, &line.Description, &line.Direction, &line.Amount, &line.Currency, &bookingDate,
+		&line.Status, &paymentID, &eventID, &line.Difference, &line.Resolution, &line.ResolutionNote, &line.ResolvedBy, &resolvedAt)
+	if err != nil {
+		return err
+	}
+	line.PaymentID, line.EventID = paymentID.Int64, eventID.Int64
+	line.BookingDate = bookingDate.Format("2006-01-02")
+	if resolvedAt.Valid {
+		line.ResolvedAt = &resolvedAt.Time
+	}
+	return nil
+}
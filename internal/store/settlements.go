@@ -0,0 +1,319 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/money"
+	"go-lang-final/internal/settlement"
+	"go-lang-final/internal/tenant"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrSettlementNotFound = errors.New("settlement batch not found")
+	ErrSettlementExists   = errors.New("settlement file already imported")
+)
+
+// settlementPrefix starts the ids of settlement batches.
+const settlementPrefix = "stb_"
+
+// SettlementCheck is how a provider's settlement files are checked: the
+// contracted fee, the tolerance on it in minor units, and how many days
+// before the settlement date payments the provider settled are expected in
+// the file.
+type SettlementCheck struct {
+	Rate         settlement.Rate
+	Tolerance    int64
+	LookbackDays int
+}
+
+// ImportSettlement links the lines of a provider's settlement file to the
+// provider's payments, records the fees as ledger postings and stores the
+// batch with its discrepancies. batch names the provider, the file's
+// reference and its settlement date; a file must be in one currency and can
+// only be imported once.
+func (s *PaymentStore) ImportSettlement(ctx context.Context, batch models.SettlementBatch, lines []settlement.Line, check SettlementCheck) (*models.SettlementBatch, error) {
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%w: no transactions", settlement.ErrInvalidReport)
+	}
+	batch.Currency = lines[0].Currency
+	refs, ids := make([]string, 0, len(lines)), make([]int64, 0, len(lines))
+	var gross, fees, expectedFees int64
+	for _, l := range lines {
+		if l.Currency != batch.Currency {
+			return nil, fmt.Errorf("%w: lines in %s and %s", settlement.ErrInvalidReport, batch.Currency, l.Currency)
+		}
+		if l.PaymentID != 0 {
+			ids = append(ids, l.PaymentID)
+		} else {
+			refs = append(refs, l.Reference)
+		}
+		gross += l.Gross
+		fees += l.Fee
+	}
+	date, err := time.Parse("2006-01-02", batch.SettlementDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid settlement date %q", settlement.ErrInvalidReport, batch.SettlementDate)
+	}
+
+	err = s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if batch.ID, err = newID(settlementPrefix); err != nil {
+			return err
+		}
+
+		payments, err := settlementPayments(ctx, tx, tenantID, batch, refs, ids, date.AddDate(0, 0, -check.LookbackDays), date.AddDate(0, 0, 1))
+		if err != nil {
+			return err
+		}
+		results, missing := settlement.Check(lines, payments, check.Rate, check.Tolerance)
+
+		batch.Lines = len(lines)
+		batch.Missing = len(missing)
+		for _, r := range results {
+			expectedFees += r.ExpectedFee
+			switch r.Status {
+			case settlement.Matched:
+				batch.Matched++
+			case settlement.Unexpected:
+				batch.Unexpected++
+			case settlement.Duplicated:
+				batch.Duplicated++
+			case settlement.AmountMismatch:
+				batch.AmountMismatches++
+			case settlement.FeeDeviation:
+				batch.FeeDeviations++
+			}
+		}
+		batch.Gross, batch.Fees = money.Major(gross), money.Major(fees)
+		batch.Net, batch.ExpectedFees = money.Major(gross-fees), money.Major(expectedFees)
+
+		query := `INSERT INTO settlement_batches (tenant_id, id, provider, reference, settlement_date, currency, lines, matched, unexpected, missing,
+				duplicated, amount_mismatches, fee_deviations, gross, fees, net, expected_fees)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+			ON CONFLICT (tenant_id, provider, reference) DO NOTHING RETURNING tenant_id, created_at`
+		err = tx.QueryRowContext(ctx, query, tenantID, batch.ID, batch.Provider, batch.Reference, batch.SettlementDate, batch.Currency,
+			batch.Lines, batch.Matched, batch.Unexpected, batch.Missing, batch.Duplicated, batch.AmountMismatches, batch.FeeDeviations,
+			batch.Gross, batch.Fees, batch.Net, batch.ExpectedFees).Scan(&batch.TenantID, &batch.CreatedAt)
+		if err == sql.ErrNoRows {
+			return ErrSettlementExists
+		}
+		if err != nil {
+			return err
+		}
+
+		for i, l := range lines {
+			item := models.SettlementLine{
+				Line:        l.Number,
+				Type:        l.Type,
+				Reference:   l.Reference,
+				Currency:    l.Currency,
+				Gross:       money.Major(l.Gross),
+				Fee:         money.Major(l.Fee),
+				Net:         money.Major(l.Net),
+				ExpectedFee: money.Major(results[i].ExpectedFee),
+				Status:      results[i].Status,
+			}
+			if p := results[i].Payment; p != nil {
+				item.PaymentID = p.ID
+				if item.Reference == "" {
+					item.Reference = p.Ref
+				}
+			}
+			batch.Items = append(batch.Items, item)
+
+			if l.Fee == 0 {
+				continue
+			}
+			err := post(ctx, tx, tenantID, ledgerEntry{
+				Source:      "settlement",
+				SourceID:    batch.ID,
+				PaymentID:   item.PaymentID,
+				Currency:    l.Currency,
+				Description: fmt.Sprintf("%s fee on %s %s", batch.Provider, l.Type, item.Reference),
+				Postings: []ledgerPosting{
+					{Account: AccountProviderFees + batch.Provider, Amount: l.Fee},
+					{Account: AccountProviderBalance + batch.Provider, Amount: -l.Fee},
+				},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		for _, p := range missing {
+			batch.Items = append(batch.Items, models.SettlementLine{
+				Line:        len(batch.Items) + 1,
+				Type:        settlement.TypeCharge,
+				Reference:   p.Ref,
+				PaymentID:   p.ID,
+				Currency:    p.Currency,
+				Gross:       money.Major(p.Amount),
+				ExpectedFee: money.Major(check.Rate.Fee(p.Amount)),
+				Status:      settlement.Missing,
+			})
+		}
+
+		query = `INSERT INTO settlement_lines (tenant_id, batch_id, line, type, reference, payment_id, currency, gross, fee, net, expected_fee, status)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+		for _, item := range batch.Items {
+			paymentID := sql.NullInt64{Int64: item.PaymentID, Valid: item.PaymentID != 0}
+			if _, err := tx.ExecContext(ctx, query, tenantID, batch.ID, item.Line, item.Type, item.Reference, paymentID, item.Currency,
+				item.Gross, item.Fee, item.Net, item.ExpectedFee, item.Status); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"batch_id":      batch.ID,
+		"provider":      batch.Provider,
+		"lines":         batch.Lines,
+		"matched":       batch.Matched,
+		"discrepancies": len(batch.Items) - batch.Matched,
+	}).Info("settlement file imported")
+	return &batch, nil
+}
+
+// settlementPayments loads the provider's payments the file names plus
+// those it settled between from and to in the batch's currency, which the
+// file should list.
+func settlementPayments(ctx context.Context, tx *sql.Tx, tenantID string, batch models.SettlementBatch, refs []string, ids []int64, from, to time.Time) ([]settlement.Payment, error) {
+	query := `SELECT p.id, p.provider_ref, p.amount, p.refunded_amount, p.currency,
+			p.currency = $5 AND EXISTS (SELECT 1 FROM payment_events e WHERE e.tenant_id = p.tenant_id AND e.payment_id = p.id
+				AND e.type = 'settled' AND e.created_at >= $6 AND e.created_at < $7),
+			EXISTS (SELECT 1 FROM settlement_lines l WHERE l.tenant_id = p.tenant_id AND l.payment_id = p.id
+				AND l.type = 'charge' AND l.status <> 'missing')
+		FROM payments p
+		WHERE p.tenant_id = $1 AND p.provider = $2 AND (p.provider_ref = ANY($3) OR p.id = ANY($4)
+			OR p.currency = $5 AND EXISTS (SELECT 1 FROM payment_events e WHERE e.tenant_id = p.tenant_id AND e.payment_id = p.id
+				AND e.type = 'settled' AND e.created_at >= $6 AND e.created_at < $7))
+		ORDER BY p.id`
+	rows, err := tx.QueryContext(ctx, query, tenantID, batch.Provider, pq.Array(refs), pq.Array(ids), batch.Currency, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []settlement.Payment
+	for rows.Next() {
+		var (
+			p                settlement.Payment
+			amount, refunded float64
+		)
+		if err := rows.Scan(&p.ID, &p.Ref, &amount, &refunded, &p.Currency, &p.Expected, &p.Settled); err != nil {
+			return nil, err
+		}
+		p.Amount, p.Refunded = money.Minor(amount), money.Minor(refunded)
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
+const settlementBatchColumns = `id, provider, reference, settlement_date, currency, lines, matched, unexpected, missing, duplicated,
+	amount_mismatches, fee_deviations, gross, fees, net, expected_fees, tenant_id, created_at`
+
+// GetSettlementBatch returns a batch with its lines: all of them when
+// lines is empty, those with that status, or with "discrepancies" every
+// line that is not matched.
+func (s *PaymentStore) GetSettlementBatch(ctx context.Context, id, lines string) (*models.SettlementBatch, error) {
+	var batch models.SettlementBatch
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT ` + settlementBatchColumns + ` FROM settlement_batches WHERE tenant_id = $1 AND id = $2`
+		err = scanSettlementBatch(tx.QueryRowContext(ctx, query, tenantID, id), &batch)
+		if err == sql.ErrNoRows {
+			return ErrSettlementNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		query = `SELECT line, type, reference, payment_id, currency, gross, fee, net, expected_fee, status
+			FROM settlement_lines WHERE tenant_id = $1 AND batch_id = $2
+			AND ($3 = '' OR ($3 = 'discrepancies' AND status <> 'matched') OR status = $3) ORDER BY line`
+		rows, err := tx.QueryContext(ctx, query, tenantID, id, lines)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var (
+				line      models.SettlementLine
+				paymentID sql.NullInt64
+			)
+			if err := rows.Scan(&line.Line, &line.Type, &line.Reference, &paymentID, &line.Currency, &line.Gross, &line.Fee,
+				&line.Net, &line.ExpectedFee, &line.Status); err != nil {
+				return err
+			}
+			line.PaymentID = paymentID.Int64
+			batch.Items = append(batch.Items, line)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// ListSettlementBatches returns the tenant's batches, optionally of one
+// provider, latest settlement date first.
+func (s *PaymentStore) ListSettlementBatches(ctx context.Context, provider string, page, pageSize int) ([]models.SettlementBatch, error) {
+	var batches []models.SettlementBatch
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT ` + settlementBatchColumns + ` FROM settlement_batches WHERE tenant_id = $1 AND ($2 = '' OR provider = $2)
+			ORDER BY settlement_date DESC, created_at DESC, id LIMIT $3 OFFSET $4`
+		rows, err := tx.QueryContext(ctx, query, tenantID, provider, pageSize, (page-1)*pageSize)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var batch models.SettlementBatch
+			if err := scanSettlementBatch(rows, &batch); err != nil {
+				return err
+			}
+			batches = append(batches, batch)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+func scanSettlementBatch(row scanner, batch *models.SettlementBatch) error {
+	var date time.Time
+	err := row.Scan(&batch.ID, &batch.Provider, &batch.Reference, &date, &batch.Currency, &batch.Lines, &batch.Matched,
+		&batch.Unexpected, &batch.Missing, &batch.Duplicated, &batch.AmountMismatches, &batch.FeeDeviations,
+		&batch.Gross, &batch.Fees, &batch.Net, &batch.ExpectedFees, &batch.TenantID, &batch.CreatedAt)
+	if err != nil {
+		return err
+	}
+	batch.SettlementDate = date.Format("2006-01-02")
+	return nil
+}
@@ -0,0 +1,710 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/money"
+	"go-lang-final/internal/subscriptions"
+	"go-lang-final/internal/tenant"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrSubscriptionPlanNotFound = errors.New("subscription plan not found")
+	ErrSubscriptionNotFound     = errors.New("subscription not found")
+	ErrSubscriptionState        = errors.New("subscription status does not allow this operation")
+	ErrRenewalPending           = errors.New("subscription renewal is being charged")
+	ErrPlanCurrencyMismatch     = errors.New("plan currency differs from the subscription's")
+)
+
+// Prefixes of subscription ids.
+const (
+	subscriptionPlanPrefix   = "sp_"
+	subscriptionPrefix       = "sub_"
+	subscriptionChargePrefix = "sch_"
+)
+
+// SubscriptionChargeKey is the idempotency key the payment of a
+// subscription charge is created with.
+func SubscriptionChargeKey(chargeID string) string {
+	return subscriptionChargeKeyPrefix + chargeID
+}
+
+const subscriptionChargeKeyPrefix = "subscription_charge:"
+
+const (
+	subscriptionPlanColumns   = `id, name, interval, interval_count, amount, currency, trial_days, created_at`
+	subscriptionColumns       = `id, tenant_id, plan_id, payment_method, customer_id, status, current_period_start, current_period_end, trial_end, next_charge_at, attempts, balance, cancel_at_period_end, canceled_at, renewals, anchor, periods, created_at, updated_at`
+	subscriptionChargeColumns = `id, subscription_id, period, attempt, payment_id, amount, currency, status, reason, created_at, updated_at`
+)
+
+// CreateSubscriptionPlan validates and stores a plan of the tenant in scope.
+func (s *PaymentStore) CreateSubscriptionPlan(ctx context.Context, plan models.SubscriptionPlan) (*models.SubscriptionPlan, error) {
+	if err := subscriptions.Validate(plan); err != nil {
+		return nil, err
+	}
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if plan.ID, err = newID(subscriptionPlanPrefix); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO subscription_plans (tenant_id, id, name, interval, interval_count, amount, currency, trial_days) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING created_at`
+		return tx.QueryRowContext(ctx, query, tenantID, plan.ID, plan.Name, plan.Interval, plan.IntervalCount, plan.Amount, plan.Currency, plan.TrialDays).Scan(&plan.CreatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"plan_id":  plan.ID,
+		"interval": plan.Interval,
+		"amount":   plan.Amount,
+		"currency": plan.Currency,
+	}).Info("subscription plan created")
+	return &plan, nil
+}
+
+func (s *PaymentStore) GetSubscriptionPlan(ctx context.Context, id string) (*models.SubscriptionPlan, error) {
+	var plan *models.SubscriptionPlan
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		plan, err = subscriptionPlan(ctx, tx, tenantID, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (s *PaymentStore) ListSubscriptionPlans(ctx context.Context, page, pageSize int) ([]models.SubscriptionPlan, error) {
+	var plans []models.SubscriptionPlan
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT ` + subscriptionPlanColumns + ` FROM subscription_plans WHERE tenant_id = $1 ORDER BY created_at, id LIMIT $2 OFFSET $3`
+		rows, err := tx.QueryContext(ctx, query, tenantID, pageSize, (page-1)*pageSize)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var plan models.SubscriptionPlan
+			if err := scanSubscriptionPlan(rows, &plan); err != nil {
+				return err
+			}
+			plans = append(plans, plan)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+// CreateSubscription subscribes sub.PaymentMethod, on behalf of
+// sub.Customer if set, to sub.PlanID. It starts trialing when the plan has
+// a trial and is otherwise due at once.
+func (s *PaymentStore) CreateSubscription(ctx context.Context, sub models.Subscription, now time.Time) (*models.Subscription, error) {
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		plan, err := subscriptionPlan(ctx, tx, tenantID, sub.PlanID)
+		if err != nil {
+			return err
+		}
+		switch {
+		case strings.HasPrefix(sub.PaymentMethod, models.BankAccountPrefix):
+			if err := checkBankAccount(ctx, tx, tenantID, models.Payment{Currency: plan.Currency, PaymentMethod: sub.PaymentMethod}); err != nil {
+				return err
+			}
+		case sub.PaymentMethod != "":
+			if err := checkPaymentMethod(ctx, tx, tenantID, sub.PaymentMethod); err != nil {
+				return err
+			}
+		}
+		if sub.Customer != "" {
+			if err := checkCustomer(ctx, tx, tenantID, sub.Customer); err != nil {
+				return err
+			}
+		}
+
+		subscriptions.Start(&sub, *plan, now)
+		if sub.ID, err = newID(subscriptionPrefix); err != nil {
+			return err
+		}
+		query := `INSERT INTO subscriptions (tenant_id, id, plan_id, payment_method, customer_id, status, current_period_start, current_period_end, trial_end, next_charge_at, anchor)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10, $11) RETURNING ` + subscriptionColumns
+		row := tx.QueryRowContext(ctx, query, tenantID, sub.ID, sub.PlanID, sub.PaymentMethod, sub.Customer, sub.Status, sub.CurrentPeriodStart, sub.CurrentPeriodEnd, sub.TrialEnd, sub.NextChargeAt, sub.Anchor)
+		return scanSubscription(row, &sub)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"subscription_id": sub.ID,
+		"plan_id":         sub.PlanID,
+		"status":          sub.Status,
+	}).Info("subscription created")
+	return &sub, nil
+}
+
+func (s *PaymentStore) GetSubscription(ctx context.Context, id string) (*models.Subscription, error) {
+	var sub models.Subscription
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE tenant_id = $1 AND id = $2`
+		err = scanSubscription(tx.QueryRowContext(ctx, query, tenantID, id), &sub)
+		if err == sql.ErrNoRows {
+			return ErrSubscriptionNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// ListSubscriptions pages through the tenant's subscriptions, oldest
+// first, only those with status unless it is empty.
+func (s *PaymentStore) ListSubscriptions(ctx context.Context, status string, page, pageSize int) ([]models.Subscription, error) {
+	var subs []models.Subscription
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE tenant_id = $1 AND ($2 = '' OR status = $2) ORDER BY created_at, id LIMIT $3 OFFSET $4`
+		rows, err := tx.QueryContext(ctx, query, tenantID, status, pageSize, (page-1)*pageSize)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var sub models.Subscription
+			if err := scanSubscription(rows, &sub); err != nil {
+				return err
+			}
+			subs = append(subs, sub)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// ChangeSubscriptionPlan moves a trialing or active subscription to another
+// plan in the same currency. The current period is kept and the next one
+// follows the new plan. An active subscription is prorated: the unused part
+// of the old plan is credited, the rest of the period on the new plan owed,
+// and the difference settled with the next renewal.
+func (s *PaymentStore) ChangeSubscriptionPlan(ctx context.Context, id, planID string, now time.Time) (*models.Subscription, error) {
+	var sub *models.Subscription
+	var proration int64
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if sub, err = lockSubscription(ctx, tx, tenantID, id); err != nil {
+			return err
+		}
+		if sub.Status != models.SubscriptionTrialing && sub.Status != models.SubscriptionActive {
+			return ErrSubscriptionState
+		}
+		if err := checkNoPendingCharge(ctx, tx, tenantID, id); err != nil {
+			return err
+		}
+		from, err := subscriptionPlan(ctx, tx, tenantID, sub.PlanID)
+		if err != nil {
+			return err
+		}
+		to, err := subscriptionPlan(ctx, tx, tenantID, planID)
+		if err != nil {
+			return err
+		}
+		if to.Currency != from.Currency {
+			return ErrPlanCurrencyMismatch
+		}
+
+		if sub.Status == models.SubscriptionActive {
+			newEnd := subscriptions.PeriodEnd(sub.CurrentPeriodStart, to.Interval, to.IntervalCount, 1)
+			proration = subscriptions.Prorate(money.Minor(from.Amount), money.Minor(to.Amount), sub.CurrentPeriodStart, sub.CurrentPeriodEnd, newEnd, now)
+		}
+		query := `UPDATE subscriptions SET plan_id = $3, balance = balance + $4, anchor = current_period_end, periods = 0, updated_at = now() WHERE tenant_id = $1 AND id = $2 RETURNING ` + subscriptionColumns
+		return scanSubscription(tx.QueryRowContext(ctx, query, tenantID, id, planID, money.Major(proration)), sub)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"subscription_id": id,
+		"plan_id":         planID,
+		"proration":       money.Major(proration),
+	}).Info("subscription plan changed")
+	return sub, nil
+}
+
+// CancelSubscription cancels a subscription now, or with atPeriodEnd once
+// the period paid for, or the trial, is over. A renewal being charged is
+// still recorded but does not renew a canceled subscription.
+func (s *PaymentStore) CancelSubscription(ctx context.Context, id string, atPeriodEnd bool, now time.Time) (*models.Subscription, error) {
+	var sub *models.Subscription
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		if sub, err = lockSubscription(ctx, tx, tenantID, id); err != nil {
+			return err
+		}
+		if sub.Status == models.SubscriptionCanceled {
+			return ErrSubscriptionState
+		}
+
+		query := `UPDATE subscriptions SET status = 'canceled', canceled_at = $3, next_charge_at = NULL, updated_at = now() WHERE tenant_id = $1 AND id = $2 RETURNING ` + subscriptionColumns
+		args := []interface{}{tenantID, id, now}
+		if atPeriodEnd {
+			query = `UPDATE subscriptions SET cancel_at_period_end = true, updated_at = now() WHERE tenant_id = $1 AND id = $2 RETURNING ` + subscriptionColumns
+			args = args[:2]
+		}
+		return scanSubscription(tx.QueryRowContext(ctx, query, args...), sub)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"subscription_id": id,
+		"at_period_end":   atPeriodEnd,
+	}).Info("subscription canceled")
+	return sub, nil
+}
+
+// ListSubscriptionCharges returns every attempt to collect a renewal of
+// the subscription, oldest first.
+func (s *PaymentStore) ListSubscriptionCharges(ctx context.Context, id string) ([]models.SubscriptionCharge, error) {
+	var charges []models.SubscriptionCharge
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		var found int
+		err = tx.QueryRowContext(ctx, `SELECT 1 FROM subscriptions WHERE tenant_id = $1 AND id = $2`, tenantID, id).Scan(&found)
+		if err == sql.ErrNoRows {
+			return ErrSubscriptionNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT ` + subscriptionChargeColumns + ` FROM subscription_charges WHERE tenant_id = $1 AND subscription_id = $2 ORDER BY period, attempt`
+		rows, err := tx.QueryContext(ctx, query, tenantID, id)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var charge models.SubscriptionCharge
+			if err := scanSubscriptionCharge(rows, &charge); err != nil {
+				return err
+			}
+			charges = append(charges, charge)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return charges, nil
+}
+
+// DueSubscriptions returns up to limit subscriptions due to be charged at
+// now, most overdue first, with only their tenant and id set. A
+// cross-tenant scope sees every tenant.
+func (s *PaymentStore) DueSubscriptions(ctx context.Context, now time.Time, limit int) ([]models.Subscription, error) {
+	var subs []models.Subscription
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		query := `SELECT tenant_id, id FROM subscriptions WHERE ($1 = '' OR tenant_id = $1) AND status <> 'canceled' AND next_charge_at <= $2 ORDER BY next_charge_at LIMIT $3`
+		rows, err := tx.QueryContext(ctx, query, scope.TenantID, now, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var sub models.Subscription
+			if err := rows.Scan(&sub.TenantID, &sub.ID); err != nil {
+				return err
+			}
+			subs = append(subs, sub)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// ClaimSubscriptionCharge takes the renewal of a due subscription and
+// returns the charge to collect it with, nil when the subscription is not
+// due anymore or another replica holds it. The subscription is leased: it
+// is not due again until lease has passed, so a charge whose outcome is
+// never recorded, say because the replica died, is claimed again then and
+// the same charge is returned. Charges of subscriptions due to cancel
+// cancel them instead, and a renewal the subscription's credit covers is
+// recorded as succeeded without a payment.
+func (s *PaymentStore) ClaimSubscriptionCharge(ctx context.Context, id string, now time.Time, lease time.Duration) (*models.SubscriptionCharge, error) {
+	var charge *models.SubscriptionCharge
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+
+		var sub models.Subscription
+		query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE tenant_id = $1 AND id = $2 AND status <> 'canceled' AND next_charge_at <= $3 FOR UPDATE SKIP LOCKED`
+		err = scanSubscription(tx.QueryRowContext(ctx, query, tenantID, id, now), &sub)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if sub.CancelAtPeriodEnd {
+			query = `UPDATE subscriptions SET status = 'canceled', canceled_at = $3, next_charge_at = NULL, updated_at = now() WHERE tenant_id = $1 AND id = $2`
+			_, err = tx.ExecContext(ctx, query, tenantID, id, now)
+			return err
+		}
+
+		period, attempt := sub.Renewals+1, sub.Attempts+1
+		charge = &models.SubscriptionCharge{PaymentMethod: sub.PaymentMethod, Customer: sub.Customer}
+		var paymentID sql.NullInt64
+		query = `SELECT c.id, c.subscription_id, c.period, c.attempt, c.payment_id, c.amount, c.currency, c.status, c.reason, c.created_at, c.updated_at, COALESCE(p.status, '')
+			FROM subscription_charges c
+			LEFT JOIN idempotency_keys k ON k.tenant_id = c.tenant_id AND k.key = $5 || c.id
+			LEFT JOIN payments p ON p.tenant_id = k.tenant_id AND p.id = k.payment_id
+			WHERE c.tenant_id = $1 AND c.subscription_id = $2 AND c.period = $3 AND c.attempt = $4`
+		err = tx.QueryRowContext(ctx, query, tenantID, id, period, attempt, subscriptionChargeKeyPrefix).Scan(&charge.ID, &charge.SubscriptionID, &charge.Period, &charge.Attempt,
+			&paymentID, &charge.Amount, &charge.Currency, &charge.Status, &charge.Reason, &charge.CreatedAt, &charge.UpdatedAt, &charge.PaymentStatus)
+		charge.PaymentID = paymentID.Int64
+		switch {
+		case err == sql.ErrNoRows:
+			if err := newSubscriptionCharge(ctx, tx, tenantID, &sub, charge); err != nil {
+				return err
+			}
+			if charge.Status != models.ChargePending {
+				return nil
+			}
+		case err != nil:
+			return err
+		}
+
+		query = `UPDATE subscriptions SET next_charge_at = $3 WHERE tenant_id = $1 AND id = $2`
+		_, err = tx.ExecContext(ctx, query, tenantID, id, now.Add(lease))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if charge != nil {
+		logging.FromContext(ctx).WithFields(logrus.Fields{
+			"subscription_id": id,
+			"charge_id":       charge.ID,
+			"payment_id":      charge.PaymentID,
+			"status":          charge.Status,
+		}).Debug("subscription charge claimed")
+	}
+	return charge, nil
+}
+
+// newSubscriptionCharge records the next attempt to collect the renewal of
+// sub, with a payment id from nextPaymentID.
+func newSubscriptionCharge(ctx context.Context, tx *sql.Tx, tenantID string, sub *models.Subscription, charge *models.SubscriptionCharge) error {
+	plan, err := subscriptionPlan(ctx, tx, tenantID, sub.PlanID)
+	if err != nil {
+		return err
+	}
+	amount, rest := subscriptions.Due(money.Minor(plan.Amount), money.Minor(sub.Balance))
+
+	if charge.ID, err = newID(subscriptionChargePrefix); err != nil {
+		return err
+	}
+	charge.SubscriptionID, charge.Period, charge.Attempt = sub.ID, sub.Renewals+1, sub.Attempts+1
+	charge.Amount, charge.Currency, charge.Status = money.Major(amount), plan.Currency, models.ChargePending
+
+	var paymentID sql.NullInt64
+	if amount == 0 {
+		charge.Status = models.ChargeSucceeded
+	} else {
+		if paymentID.Int64, err = nextPaymentID(ctx, tx, tenantID); err != nil {
+			return err
+		}
+		paymentID.Valid, charge.PaymentID = true, paymentID.Int64
+	}
+
+	query := `INSERT INTO subscription_charges (tenant_id, id, subscription_id, period, attempt, payment_id, amount, currency, status) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING created_at, updated_at`
+	err = tx.QueryRowContext(ctx, query, tenantID, charge.ID, sub.ID, charge.Period, charge.Attempt, paymentID, charge.Amount, charge.Currency, charge.Status).Scan(&charge.CreatedAt, &charge.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if amount == 0 {
+		return renew(ctx, tx, tenantID, sub, plan, rest)
+	}
+	return nil
+}
+
+// nextPaymentID allocates a payment id past every payment and charge of the
+// tenant, with the tenant row locked as creates lock it. Clients choose the
+// ids of their own payments, so one may still take it before the charge's
+// payment is stored; see ReallocateSubscriptionPayment.
+func nextPaymentID(ctx context.Context, tx *sql.Tx, tenantID string) (int64, error) {
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM tenants WHERE id = $1 FOR UPDATE`, tenantID); err != nil {
+		return 0, err
+	}
+	var id int64
+	query := `SELECT GREATEST((SELECT COALESCE(max(id), 0) FROM payments WHERE tenant_id = $1), (SELECT COALESCE(max(payment_id), 0) FROM subscription_charges WHERE tenant_id = $1)) + 1`
+	err := tx.QueryRowContext(ctx, query, tenantID).Scan(&id)
+	return id, err
+}
+
+// ReallocateSubscriptionPayment gives a pending charge whose payment id was
+// taken by another payment a new one, and returns it. A charge whose
+// payment was stored under its idempotency key keeps its id, so a retry
+// replays that payment instead of charging twice. It returns 0 for a charge
+// that is no longer pending.
+func (s *PaymentStore) ReallocateSubscriptionPayment(ctx context.Context, chargeID string) (int64, error) {
+	var paymentID int64
+	err := s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		var stored bool
+		query := `SELECT payment_id, EXISTS (SELECT 1 FROM idempotency_keys WHERE tenant_id = $1 AND key = $3) FROM subscription_charges WHERE tenant_id = $1 AND id = $2 AND status = 'pending' FOR UPDATE`
+		err = tx.QueryRowContext(ctx, query, tenantID, chargeID, SubscriptionChargeKey(chargeID)).Scan(&paymentID, &stored)
+		if err == sql.ErrNoRows {
+			paymentID = 0
+			return nil
+		}
+		if err != nil || stored {
+			return err
+		}
+
+		if paymentID, err = nextPaymentID(ctx, tx, tenantID); err != nil {
+			return err
+		}
+		query = `UPDATE subscription_charges SET payment_id = $3, updated_at = now() WHERE tenant_id = $1 AND id = $2`
+		_, err = tx.ExecContext(ctx, query, tenantID, chargeID, paymentID)
+		return err
+	})
+	if err != nil || paymentID == 0 {
+		return 0, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"charge_id":  chargeID,
+		"payment_id": paymentID,
+	}).Info("subscription charge payment id reallocated")
+	return paymentID, nil
+}
+
+// CompleteSubscriptionCharge records that the payment of a pending charge
+// went through and renews the subscription for the next period. Charges
+// already recorded are left as they are.
+func (s *PaymentStore) CompleteSubscriptionCharge(ctx context.Context, chargeID string) error {
+	return s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		sub, charge, err := recordCharge(ctx, tx, tenantID, chargeID, models.ChargeSucceeded, "")
+		if err != nil || sub == nil {
+			return err
+		}
+		plan, err := subscriptionPlan(ctx, tx, tenantID, sub.PlanID)
+		if err != nil {
+			return err
+		}
+
+		logging.FromContext(ctx).WithFields(logrus.Fields{
+			"subscription_id": sub.ID,
+			"charge_id":       chargeID,
+			"period":          charge.Period,
+		}).Info("subscription renewed")
+		return renew(ctx, tx, tenantID, sub, plan, 0)
+	})
+}
+
+// FailSubscriptionCharge records that a pending charge failed for reason.
+// The subscription is past due and tried again after the wait retries
+// gives the attempt; once they are used up it is canceled.
+func (s *PaymentStore) FailSubscriptionCharge(ctx context.Context, chargeID, reason string, retries []time.Duration, now time.Time) error {
+	return s.inTenant(ctx, func(tx *sql.Tx, scope tenant.Scope) error {
+		tenantID, err := scope.Single()
+		if err != nil {
+			return err
+		}
+		sub, charge, err := recordCharge(ctx, tx, tenantID, chargeID, models.ChargeFailed, reason)
+		if err != nil || sub == nil {
+			return err
+		}
+
+		fields := logrus.Fields{
+			"subscription_id": sub.ID,
+			"charge_id":       chargeID,
+			"attempt":         charge.Attempt,
+			"reason":          reason,
+		}
+		if wait, ok := subscriptions.Retry(retries, charge.Attempt); ok {
+			query := `UPDATE subscriptions SET status = 'past_due', attempts = $3, next_charge_at = $4, updated_at = now() WHERE tenant_id = $1 AND id = $2`
+			_, err = tx.ExecContext(ctx, query, tenantID, sub.ID, charge.Attempt, now.Add(wait))
+			logging.FromContext(ctx).WithFields(fields).WithField("retry_at", now.Add(wait)).Warn("subscription charge failed")
+			return err
+		}
+		query := `UPDATE subscriptions SET status = 'canceled', attempts = $3, canceled_at = $4, next_charge_at = NULL, updated_at = now() WHERE tenant_id = $1 AND id = $2`
+		_, err = tx.ExecContext(ctx, query, tenantID, sub.ID, charge.Attempt, now)
+		logging.FromContext(ctx).WithFields(fields).Warn("subscription canceled after its retries failed")
+		return err
+	})
+}
+
+// recordCharge settles a pending charge with status and returns it with
+// its subscription, locked. The subscription is nil when the charge was
+// settled already or the subscription moved on: it was canceled, or the
+// renewal was paid otherwise.
+func recordCharge(ctx context.Context, tx *sql.Tx, tenantID, chargeID, status, reason string) (*models.Subscription, *models.SubscriptionCharge, error) {
+	var charge models.SubscriptionCharge
+	query := `UPDATE subscription_charges SET status = $3, reason = $4, updated_at = now() WHERE tenant_id = $1 AND id = $2 AND status = 'pending' RETURNING ` + subscriptionChargeColumns
+	err := scanSubscriptionCharge(tx.QueryRowContext(ctx, query, tenantID, chargeID, status, reason), &charge)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sub, err := lockSubscription(ctx, tx, tenantID, charge.SubscriptionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sub.Status == models.SubscriptionCanceled || sub.Renewals+1 != charge.Period || sub.Attempts+1 != charge.Attempt {
+		return nil, &charge, nil
+	}
+	return sub, &charge, nil
+}
+
+// renew starts the next period of sub, leaving balance, in minor units, to
+// settle later.
+func renew(ctx context.Context, tx *sql.Tx, tenantID string, sub *models.Subscription, plan *models.SubscriptionPlan, balance int64) error {
+	periods := sub.Periods + 1
+	end := subscriptions.PeriodEnd(sub.Anchor, plan.Interval, plan.IntervalCount, periods)
+	query := `UPDATE subscriptions SET status = 'active', renewals = renewals + 1, periods = $3, current_period_start = current_period_end, current_period_end = $4, next_charge_at = $4, attempts = 0, balance = $5, updated_at = now() WHERE tenant_id = $1 AND id = $2`
+	_, err := tx.ExecContext(ctx, query, tenantID, sub.ID, periods, end, money.Major(balance))
+	return err
+}
+
+// checkNoPendingCharge fails while a renewal of the subscription is being
+// charged, whose amount a change would invalidate.
+func checkNoPendingCharge(ctx context.Context, tx *sql.Tx, tenantID, id string) error {
+	var pending bool
+	query := `SELECT EXISTS (SELECT 1 FROM subscription_charges WHERE tenant_id = $1 AND subscription_id = $2 AND status = 'pending')`
+	if err := tx.QueryRowContext(ctx, query, tenantID, id).Scan(&pending); err != nil {
+		return err
+	}
+	if pending {
+		return ErrRenewalPending
+	}
+	return nil
+}
+
+func subscriptionPlan(ctx context.Context, tx *sql.Tx, tenantID, id string) (*models.SubscriptionPlan, error) {
+	var plan models.SubscriptionPlan
+	query := `SELECT ` + subscriptionPlanColumns + ` FROM subscription_plans WHERE tenant_id = $1 AND id = $2`
+	err := scanSubscriptionPlan(tx.QueryRowContext(ctx, query, tenantID, id), &plan)
+	if err == sql.ErrNoRows {
+		return nil, ErrSubscriptionPlanNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func lockSubscription(ctx context.Context, tx *sql.Tx, tenantID, id string) (*models.Subscription, error) {
+	var sub models.Subscription
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE tenant_id = $1 AND id = $2 FOR UPDATE`
+	err := scanSubscription(tx.QueryRowContext(ctx, query, tenantID, id), &sub)
+	if err == sql.ErrNoRows {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func scanSubscriptionPlan(row scanner, plan *models.SubscriptionPlan) error {
+	return row.Scan(&plan.ID, &plan.Name, &plan.Interval, &plan.IntervalCount, &plan.Amount, &plan.Currency, &plan.TrialDays, &plan.CreatedAt)
+}
+
+func scanSubscription(row scanner, sub *models.Subscription) error {
+	var customer sql.NullString
+	var trialEnd, nextChargeAt, canceledAt sql.NullTime
+	err := row.Scan(&sub.ID, &sub.TenantID, &sub.PlanID, &sub.PaymentMethod, &customer, &sub.Status, &sub.CurrentPeriodStart, &sub.CurrentPeriodEnd, &trialEnd, &nextChargeAt,
+		&sub.Attempts, &sub.Balance, &sub.CancelAtPeriodEnd, &canceledAt, &sub.Renewals, &sub.Anchor, &sub.Periods, &sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	sub.Customer = customer.String
+	sub.TrialEnd, sub.NextChargeAt, sub.CanceledAt = nullTime(trialEnd), nullTime(nextChargeAt), nullTime(canceledAt)
+	return nil
+}
+
+func scanSubscriptionCharge(row scanner, charge *models.SubscriptionCharge) error {
+	var paymentID sql.NullInt64
+	err := row.Scan(&charge.ID, &charge.SubscriptionID, &charge.Period, &charge.Attempt, &paymentID, &charge.Amount, &charge.Currency, &charge.Status, &charge.Reason, &charge.CreatedAt, &charge.UpdatedAt)
+	charge.PaymentID = paymentID.Int64
+	return err
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
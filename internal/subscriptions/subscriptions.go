@@ -0,0 +1,152 @@
+// Package subscriptions works out the billing periods of recurring
+// payments: when they end, what a plan change mid-period is worth and when
+// a failed charge is tried again.
+package subscriptions
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"go-lang-final/internal/models"
+)
+
+// Billing intervals.
+const (
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+	IntervalYear  = "year"
+)
+
+// MaxTrialDays bounds the free trial of a plan.
+const MaxTrialDays = 730
+
+// ErrInvalidPlan is returned for a subscription plan that cannot bill.
+var ErrInvalidPlan = errors.New("invalid subscription plan")
+
+// DefaultRetries is the dunning schedule used unless one is configured: a
+// failed charge is tried again after a day, three days and a week.
+var DefaultRetries = []time.Duration{24 * time.Hour, 72 * time.Hour, 168 * time.Hour}
+
+// ValidInterval reports whether interval is one plans can bill at.
+func ValidInterval(interval string) bool {
+	switch interval {
+	case IntervalDay, IntervalWeek, IntervalMonth, IntervalYear:
+		return true
+	}
+	return false
+}
+
+// Validate checks that plan bills a positive amount at a known interval.
+func Validate(plan models.SubscriptionPlan) error {
+	switch {
+	case !ValidInterval(plan.Interval):
+		return fmt.Errorf("%w: unknown interval %q", ErrInvalidPlan, plan.Interval)
+	case plan.IntervalCount < 1:
+		return fmt.Errorf("%w: interval_count must be at least 1", ErrInvalidPlan)
+	case plan.Amount <= 0:
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidPlan)
+	case plan.TrialDays < 0 || plan.TrialDays > MaxTrialDays:
+		return fmt.Errorf("%w: trial_days must be between 0 and %d", ErrInvalidPlan, MaxTrialDays)
+	}
+	return nil
+}
+
+// PeriodEnd returns the end of the nth period of a plan's interval from
+// anchor on. Monthly and yearly periods keep the anchor's day of the month,
+// falling back to the last day of shorter months, so a subscription started
+// on the 31st is billed on the 30th in April and on the 31st again in May.
+func PeriodEnd(anchor time.Time, interval string, count, n int) time.Time {
+	switch interval {
+	case IntervalDay:
+		return anchor.AddDate(0, 0, count*n)
+	case IntervalWeek:
+		return anchor.AddDate(0, 0, 7*count*n)
+	case IntervalYear:
+		count *= 12
+	}
+
+	// time.Date normalises month overflow; the day is clamped first.
+	month := anchor.Month() + time.Month(count*n)
+	day := anchor.Day()
+	if last := time.Date(anchor.Year(), month+1, 0, 0, 0, 0, 0, anchor.Location()).Day(); day > last {
+		day = last
+	}
+	return time.Date(anchor.Year(), month, day, anchor.Hour(), anchor.Minute(), anchor.Second(), anchor.Nanosecond(), anchor.Location())
+}
+
+// Start sets the status and first period of a new subscription to plan.
+// With a trial the subscription is trialing until the trial ends and is
+// charged then; without one it is active and charged right away.
+func Start(sub *models.Subscription, plan models.SubscriptionPlan, now time.Time) {
+	sub.PlanID = plan.ID
+	sub.Status = models.SubscriptionActive
+	sub.Anchor, sub.Periods = now, 0
+	sub.CurrentPeriodStart, sub.CurrentPeriodEnd = now, now
+	sub.TrialEnd = nil
+	if plan.TrialDays > 0 {
+		end := now.AddDate(0, 0, plan.TrialDays)
+		sub.Status = models.SubscriptionTrialing
+		sub.Anchor, sub.CurrentPeriodEnd, sub.TrialEnd = end, end, &end
+	}
+	next := sub.CurrentPeriodEnd
+	sub.NextChargeAt = &next
+}
+
+// Prorate returns what switching plans at now is worth for the rest of the
+// period [start, end): the unused part of the old plan, of amount from, is
+// credited and the new plan, of amount to, is charged for the same time at
+// its own rate, that of a period from start to newEnd. Amounts are in minor
+// units; the result is positive when owed and negative when credited,
+// rounded half away from zero.
+func Prorate(from, to int64, start, end, newEnd, now time.Time) int64 {
+	period, newPeriod := end.Sub(start), newEnd.Sub(start)
+	left := end.Sub(now)
+	if period <= 0 || newPeriod <= 0 || left <= 0 {
+		return 0
+	}
+	if left > period {
+		left = period
+	}
+	unused := new(big.Rat).SetFrac(big.NewInt(from), big.NewInt(int64(period)))
+	owed := new(big.Rat).SetFrac(big.NewInt(to), big.NewInt(int64(newPeriod)))
+	owed.Sub(owed, unused)
+	return round(owed.Mul(owed, new(big.Rat).SetInt64(int64(left))))
+}
+
+// Due splits what a renewal of a plan of amount costs with the
+// subscription's balance: charge is what is collected now and rest the
+// credit left for later renewals, zero or negative.
+func Due(amount, balance int64) (charge, rest int64) {
+	total := amount + balance
+	if total > 0 {
+		return total, 0
+	}
+	return 0, total
+}
+
+// Retry returns how long to wait before trying a renewal again after its
+// attempt-th charge failed, and false when the retries are used up.
+func Retry(retries []time.Duration, attempt int) (time.Duration, bool) {
+	if attempt < 1 || attempt > len(retries) {
+		return 0, false
+	}
+	return retries[attempt-1], true
+}
+
+// round rounds r to an integer, half away from zero.
+func round(r *big.Rat) int64 {
+	num, den := new(big.Int).Set(r.Num()), r.Denom()
+	neg := num.Sign() < 0
+	num.Abs(num)
+	q, m := new(big.Int).QuoRem(num, den, new(big.Int))
+	if m.Lsh(m, 1).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if neg {
+		q.Neg(q)
+	}
+	return q.Int64()
+}
@@ -0,0 +1,94 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+
+	"go-lang-final/internal/auth"
+	"go-lang-final/internal/logging"
+	"go-lang-final/internal/rbac"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Middleware puts the tenant scope of the authenticated principal into the
+// request context. It must run after auth.Middleware.
+func Middleware(policy *rbac.Policy) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := auth.PrincipalFromContext(r.Context())
+			if !ok {
+				http.Error(w, auth.ErrUnauthenticated.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			scope, err := Resolve(p, policy, r.Header.Get(Header))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(withScopeLogger(r.Context(), scope)))
+		})
+	}
+}
+
+func UnaryServerInterceptor(policy *rbac.Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := scopeIncoming(ctx, policy)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func StreamServerInterceptor(policy *rbac.Policy) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := scopeIncoming(ss.Context(), policy)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &scopedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type scopedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *scopedStream) Context() context.Context {
+	return s.ctx
+}
+
+func scopeIncoming(ctx context.Context, policy *rbac.Policy) (context.Context, error) {
+	p, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return ctx, status.Error(codes.Unauthenticated, auth.ErrUnauthenticated.Error())
+	}
+
+	requested := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if vals := md.Get(Metadata); len(vals) > 0 {
+			requested = vals[0]
+		}
+	}
+
+	scope, err := Resolve(p, policy, requested)
+	if err != nil {
+		return ctx, status.Error(codes.PermissionDenied, err.Error())
+	}
+	return withScopeLogger(ctx, scope), nil
+}
+
+func withScopeLogger(ctx context.Context, scope Scope) context.Context {
+	ctx = WithScope(ctx, scope)
+	if scope.TenantID != "" {
+		ctx = logging.WithLogger(ctx, logging.FromContext(ctx).WithField("tenant_id", scope.TenantID))
+	}
+	return ctx
+}
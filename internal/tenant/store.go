@@ -0,0 +1,95 @@
+package tenant
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Tenant holds per-merchant configuration and quotas. Nil limits mean
+// unlimited; an empty currency list allows every currency.
+type Tenant struct {
+	ID                string   `json:"id"`
+	Name              string   `json:"name"`
+	AllowedCurrencies []string `json:"allowed_currencies"`
+	MaxPaymentAmount  *float64 `json:"max_payment_amount,omitempty"`
+	MaxPayments       *int64   `json:"max_payments,omitempty"`
+	// Daily limits on payment creation, reset at midnight UTC.
+	DailyPaymentLimit *int64    `json:"daily_payment_limit,omitempty"`
+	DailyAmountLimit  *float64  `json:"daily_amount_limit,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type Store struct {
+	DB *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{DB: db}
+}
+
+func (s *Store) CreateTenant(ctx context.Context, t Tenant) error {
+	query := `INSERT INTO tenants (id, name, allowed_currencies, max_payment_amount, max_payments, daily_payment_limit, daily_amount_limit) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := s.DB.ExecContext(ctx, query, t.ID, t.Name, pq.Array(t.AllowedCurrencies), t.MaxPaymentAmount, t.MaxPayments, t.DailyPaymentLimit, t.DailyAmountLimit)
+	return err
+}
+
+func (s *Store) UpdateTenant(ctx context.Context, t Tenant) error {
+	query := `UPDATE tenants SET name = $2, allowed_currencies = $3, max_payment_amount = $4, max_payments = $5, daily_payment_limit = $6, daily_amount_limit = $7 WHERE id = $1`
+	res, err := s.DB.ExecContext(ctx, query, t.ID, t.Name, pq.Array(t.AllowedCurrencies), t.MaxPaymentAmount, t.MaxPayments, t.DailyPaymentLimit, t.DailyAmountLimit)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("tenant not found")
+	}
+	return nil
+}
+
+func (s *Store) GetTenant(ctx context.Context, id string) (*Tenant, error) {
+	query := `SELECT id, name, allowed_currencies, max_payment_amount, max_payments, daily_payment_limit, daily_amount_limit, created_at FROM tenants WHERE id = $1`
+	var t Tenant
+	err := s.DB.QueryRowContext(ctx, query, id).Scan(&t.ID, &t.Name, pq.Array(&t.AllowedCurrencies), &t.MaxPaymentAmount, &t.MaxPayments, &t.DailyPaymentLimit, &t.DailyAmountLimit, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("tenant not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (s *Store) ListTenants(ctx context.Context) ([]Tenant, error) {
+	query := `SELECT id, name, allowed_currencies, max_payment_amount, max_payments, daily_payment_limit, daily_amount_limit, created_at FROM tenants ORDER BY id`
+	rows, err := s.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenants []Tenant
+	for rows.Next() {
+		var t Tenant
+		if err := rows.Scan(&t.ID, &t.Name, pq.Array(&t.AllowedCurrencies), &t.MaxPaymentAmount, &t.MaxPayments, &t.DailyPaymentLimit, &t.DailyAmountLimit, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, t)
+	}
+	return tenants, rows.Err()
+}
+
+// AllowsCurrency reports whether the tenant accepts payments in currency.
+func (t *Tenant) AllowsCurrency(currency string) bool {
+	if len(t.AllowedCurrencies) == 0 {
+		return true
+	}
+	for _, c := range t.AllowedCurrencies {
+		if c == currency {
+			return true
+		}
+	}
+	return false
+}
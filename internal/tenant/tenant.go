@@ -0,0 +1,69 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+
+	"go-lang-final/internal/auth"
+	"go-lang-final/internal/rbac"
+)
+
+const (
+	Header   = "X-Tenant-ID"
+	Metadata = "x-tenant-id"
+)
+
+var (
+	ErrNoTenant       = errors.New("no tenant in scope")
+	ErrTenantMismatch = errors.New("principal may not act on this tenant")
+)
+
+// Scope is the set of tenants a request may touch. It is always derived
+// from the authenticated principal, never from the request body.
+type Scope struct {
+	TenantID string
+	// All is set for cross-tenant administrators that did not pick a
+	// tenant. Such a scope may read across tenants but not write.
+	All bool
+}
+
+type contextKey int
+
+const scopeKey contextKey = 0
+
+func WithScope(ctx context.Context, s Scope) context.Context {
+	return context.WithValue(ctx, scopeKey, s)
+}
+
+func FromContext(ctx context.Context) (Scope, bool) {
+	s, ok := ctx.Value(scopeKey).(Scope)
+	return s, ok
+}
+
+// Single returns the tenant for operations that act on exactly one tenant.
+func (s Scope) Single() (string, error) {
+	if s.TenantID == "" {
+		return "", ErrNoTenant
+	}
+	return s.TenantID, nil
+}
+
+// Resolve computes the scope for a principal. A tenant requested through
+// the X-Tenant-ID header is only honoured for cross-tenant administrators
+// or when it matches the principal's own tenant.
+func Resolve(p *auth.Principal, policy *rbac.Policy, requested string) (Scope, error) {
+	crossTenant := policy.Allowed(p.Roles, rbac.TenantsCross)
+
+	switch {
+	case crossTenant && requested != "":
+		return Scope{TenantID: requested}, nil
+	case crossTenant:
+		return Scope{All: true}, nil
+	case p.TenantID == "":
+		return Scope{}, ErrNoTenant
+	case requested != "" && requested != p.TenantID:
+		return Scope{}, ErrTenantMismatch
+	default:
+		return Scope{TenantID: p.TenantID}, nil
+	}
+}
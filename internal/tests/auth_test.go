@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-lang-final/internal/auth"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func writeJWKS(t *testing.T, kid string, key *rsa.PrivateKey) string {
+	set := map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": kid,
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func signToken(t *testing.T, kid string, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func newTestVerifier(t *testing.T) (*auth.JWTVerifier, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	verifier, err := auth.NewJWTVerifier(writeJWKS(t, "k1", key), "https://issuer.test", "payments")
+	require.NoError(t, err)
+	return verifier, key
+}
+
+func TestJWTVerifierAcceptsValidToken(t *testing.T) {
+	verifier, key := newTestVerifier(t)
+
+	token := signToken(t, "k1", key, jwt.MapClaims{
+		"sub":   "user-1",
+		"iss":   "https://issuer.test",
+		"aud":   "payments",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"viewer"},
+	})
+
+	p, err := verifier.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", p.ID)
+	assert.Equal(t, []string{"viewer"}, p.Roles)
+	assert.Equal(t, auth.MethodJWT, p.Method)
+}
+
+func TestJWTVerifierRejectsInvalidTokens(t *testing.T) {
+	verifier, key := newTestVerifier(t)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	valid := jwt.MapClaims{"sub": "user-1", "iss": "https://issuer.test", "aud": "payments", "exp": time.Now().Add(time.Hour).Unix()}
+	expired := jwt.MapClaims{"sub": "user-1", "iss": "https://issuer.test", "aud": "payments", "exp": time.Now().Add(-time.Minute).Unix()}
+	wrongAudience := jwt.MapClaims{"sub": "user-1", "iss": "https://issuer.test", "aud": "other", "exp": time.Now().Add(time.Hour).Unix()}
+
+	for name, token := range map[string]string{
+		"expired":        signToken(t, "k1", key, expired),
+		"wrong audience": signToken(t, "k1", key, wrongAudience),
+		"unknown kid":    signToken(t, "k2", key, valid),
+		"wrong key":      signToken(t, "k1", other, valid),
+		"garbage":        "not-a-token",
+	} {
+		_, err := verifier.Verify(token)
+		assert.ErrorIs(t, err, auth.ErrUnauthenticated, name)
+	}
+}
+
+func TestAPIKeyAuthenticate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	raw := "pk_a1b2c3d4e5f6_c2VjcmV0"
+	sum := sha256.Sum256([]byte(raw))
+
+	rows := sqlmock.NewRows([]string{"id", "name", "key_hash", "roles", "tenant_id", "expires_at", "revoked_at"}).
+		AddRow(7, "checkout", hex.EncodeToString(sum[:]), "{viewer,operator}", "acme", nil, nil)
+	mock.ExpectQuery("SELECT id, name, key_hash, roles, (.+) FROM api_keys WHERE prefix = ?").
+		WithArgs("a1b2c3d4e5f6").
+		WillReturnRows(rows)
+
+	s := auth.NewAPIKeyStore(db)
+	p, err := s.Authenticate(context.Background(), raw)
+	assert.NoError(t, err)
+	assert.Equal(t, &auth.Principal{ID: "7", Name: "checkout", Method: auth.MethodAPIKey, Roles: []string{"viewer", "operator"}, TenantID: "acme"}, p)
+}
+
+func TestAPIKeyAuthenticateRejectsRevokedKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	raw := "pk_a1b2c3d4e5f6_c2VjcmV0"
+	sum := sha256.Sum256([]byte(raw))
+
+	rows := sqlmock.NewRows([]string{"id", "name", "key_hash", "roles", "tenant_id", "expires_at", "revoked_at"}).
+		AddRow(7, "checkout", hex.EncodeToString(sum[:]), "{viewer}", "acme", nil, time.Now())
+	mock.ExpectQuery("SELECT id, name, key_hash, roles, (.+) FROM api_keys WHERE prefix = ?").
+		WithArgs("a1b2c3d4e5f6").
+		WillReturnRows(rows)
+
+	s := auth.NewAPIKeyStore(db)
+	_, err = s.Authenticate(context.Background(), raw)
+	assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+}
+
+func TestAuthMiddlewareRejectsMissingCredentials(t *testing.T) {
+	verifier, key := newTestVerifier(t)
+	a := auth.NewAuthenticator(nil, verifier, nil)
+
+	r := mux.NewRouter()
+	r.Use(auth.Middleware(a))
+	r.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) {
+		p, ok := auth.PrincipalFromContext(r.Context())
+		assert.True(t, ok)
+		w.Write([]byte(p.ID))
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/list", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+
+	token := signToken(t, "k1", key, jwt.MapClaims{
+		"sub": "user-9", "iss": "https://issuer.test", "aud": "payments", "exp": time.Now().Add(time.Hour).Unix(),
+	})
+	req := httptest.NewRequest(http.MethodGet, "/list", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "user-9", rec.Body.String())
+}
+
+func TestAuthUnaryInterceptorReturnsUnauthenticated(t *testing.T) {
+	verifier, _ := newTestVerifier(t)
+	interceptor := auth.UnaryServerInterceptor(auth.NewAuthenticator(nil, verifier, nil))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer nope"))
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/proto.PaymentService/DeletePayment"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			t.Fatal("handler must not run")
+			return nil, nil
+		})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-lang-final/internal/ctl"
+	"go-lang-final/internal/handlers"
+	"go-lang-final/pkg/paymentsclient"
+	"go-lang-final/proto"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ctlConfig = `default: staging
+profiles:
+  staging:
+    grpc_addr: staging.internal:50051
+    api_key: $CTL_TEST_KEY
+  prod:
+    transport: rest
+    rest_url: https://payments.example.com
+    token: static-token
+    tenant: acme
+    tls:
+      enabled: true
+`
+
+func TestCtlProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(ctlConfig), 0o600))
+	t.Setenv("CTL_TEST_KEY", "pk_from_env")
+
+	cfg, err := ctl.LoadConfig(path)
+	require.NoError(t, err)
+
+	staging, err := cfg.Profile("")
+	require.NoError(t, err)
+	assert.Equal(t, ctl.TransportGRPC, staging.Transport)
+	assert.Equal(t, "staging.internal:50051", staging.GRPCAddr)
+	assert.Equal(t, "pk_from_env", staging.APIKey)
+
+	prod, err := cfg.Profile("prod")
+	require.NoError(t, err)
+	assert.Equal(t, ctl.TransportREST, prod.Transport)
+	assert.Equal(t, "acme", prod.Tenant)
+	assert.True(t, prod.TLS.Enabled)
+
+	_, err = cfg.Profile("qa")
+	assert.EqualError(t, err, `unknown profile "qa"`)
+}
+
+func TestCtlMissingConfigUsesDefaults(t *testing.T) {
+	cfg, err := ctl.LoadConfig(filepath.Join(t.TempDir(), "absent.yaml"))
+	require.NoError(t, err)
+	p, err := cfg.Profile("")
+	require.NoError(t, err)
+	assert.Equal(t, ctl.DefaultProfile, p)
+
+	p.Transport = "carrier-pigeon"
+	_, _, err = p.Connect()
+	assert.Error(t, err)
+}
+
+var ctlPayments = []paymentsclient.Payment{
+	{ID: 1, Amount: 10, Currency: "USD", TenantID: "acme", Status: "created"},
+	{ID: 2, Amount: 99.5, Currency: "EUR", TenantID: "acme", Status: "partially_refunded", RefundedAmount: 20},
+}
+
+func TestCtlPrinterFormats(t *testing.T) {
+	var buf bytes.Buffer
+	p, err := ctl.NewPrinter(&buf, ctl.Table)
+	require.NoError(t, err)
+	require.NoError(t, p.Payments(ctlPayments))
+	assert.Equal(t, "ID  TENANT  AMOUNT  CURRENCY  STATUS              REFUNDED\n"+
+		"1   acme    10.00   USD       created             0.00\n"+
+		"2   acme    99.50   EUR       partially_refunded  20.00\n", buf.String())
+
+	buf.Reset()
+	p, _ = ctl.NewPrinter(&buf, ctl.JSON)
+	require.NoError(t, p.Payment(ctlPayments[0]))
+	assert.JSONEq(t, `{"id":1,"amount":10,"currency":"USD","tenant_id":"acme","status":"created"}`, buf.String())
+
+	buf.Reset()
+	p, _ = ctl.NewPrinter(&buf, ctl.YAML)
+	require.NoError(t, p.Payment(ctlPayments[1]))
+	assert.Equal(t, "amount: 99.5\ncurrency: EUR\nid: 2\nrefunded_amount: 20\nstatus: partially_refunded\ntenant_id: acme\n", buf.String())
+
+	_, err = ctl.NewPrinter(&buf, "xml")
+	assert.Error(t, err)
+}
+
+func TestCtlPrinterStreamsEvents(t *testing.T) {
+	var buf bytes.Buffer
+	p, _ := ctl.NewPrinter(&buf, ctl.JSON)
+	at := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, p.Event(paymentsclient.Event{ID: 1, PaymentID: 7, Type: "created", CreatedAt: at}))
+	require.NoError(t, p.Event(paymentsclient.Event{ID: 2, PaymentID: 7, Type: "cancelled", CreatedAt: at}))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+
+	buf.Reset()
+	p, _ = ctl.NewPrinter(&buf, ctl.Table)
+	require.NoError(t, p.Event(paymentsclient.Event{ID: 1, PaymentID: 7, Type: "created", CreatedAt: at}))
+	require.NoError(t, p.Event(paymentsclient.Event{ID: 2, PaymentID: 7, Type: "cancelled", CreatedAt: at}))
+	assert.Equal(t, 1, strings.Count(buf.String(), "EVENT"))
+	assert.Contains(t, buf.String(), "2026-05-01T12:00:00Z")
+}
+
+func TestCtlExport(t *testing.T) {
+	var buf bytes.Buffer
+	e, err := ctl.NewExporter(&buf, ctl.CSV)
+	require.NoError(t, err)
+	for _, p := range ctlPayments {
+		require.NoError(t, e.Write(p))
+	}
+	require.NoError(t, e.Close())
+	assert.Equal(t, "id,tenant_id,amount,currency,status,refunded_amount\n"+
+		"1,acme,10.00,USD,created,0.00\n"+
+		"2,acme,99.50,EUR,partially_refunded,20.00\n", buf.String())
+
+	buf.Reset()
+	e, _ = ctl.NewExporter(&buf, ctl.JSONL)
+	for _, p := range ctlPayments {
+		require.NoError(t, e.Write(p))
+	}
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+}
+
+func TestCtlConfirm(t *testing.T) {
+	var out bytes.Buffer
+	c := &ctl.Confirmer{In: strings.NewReader("y\n"), Out: &out, Interactive: true}
+	assert.NoError(t, c.Confirm("Cancel payment %d?", 7))
+	assert.Equal(t, "Cancel payment 7? [y/N] ", out.String())
+
+	c = &ctl.Confirmer{In: strings.NewReader("\n"), Out: &out, Interactive: true}
+	assert.ErrorIs(t, c.Confirm("Cancel?"), ctl.ErrAborted)
+
+	c = &ctl.Confirmer{In: strings.NewReader("y\n"), Out: &out}
+	assert.ErrorIs(t, c.Confirm("Cancel?"), ctl.ErrNotInteractive)
+
+	c = &ctl.Confirmer{Yes: true}
+	assert.NoError(t, c.Confirm("Cancel?"))
+}
+
+// The CLI talks to the service only through paymentsclient; this checks a
+// profile reaches a REST endpoint end to end.
+func TestCtlProfileConnectsOverREST(t *testing.T) {
+	fake := newFakePayments()
+	fake.payments[3] = &proto.Payment{Id: 3, Amount: 1, Currency: "USD", Status: "created"}
+	logger, _ := newTestLogger()
+	r := mux.NewRouter()
+	handlers.RegisterRESTService(r, fake, logger)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	p := ctl.DefaultProfile
+	p.Transport = ctl.TransportREST
+	p.RESTURL = srv.URL
+	client, conn, err := p.Connect()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	got, err := client.Get(context.Background(), 3)
+	require.NoError(t, err)
+	assert.Equal(t, "created", got.Status)
+}
@@ -0,0 +1,288 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-lang-final/internal/customers"
+	"go-lang-final/internal/handlers"
+	"go-lang-final/internal/metadata"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/tenant"
+	"go-lang-final/internal/validation"
+	"go-lang-final/proto"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testCustomerID = "cus_00000000000000000000000a"
+
+var customerCreated = time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+
+func customerRows(email, name, externalRef, metadata string, erasedAt interface{}) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "tenant_id", "email", "name", "external_ref", "metadata", "erased_at", "created_at", "updated_at"}).
+		AddRow(testCustomerID, "acme", email, name, externalRef, []byte(metadata), erasedAt, customerCreated, customerCreated)
+}
+
+func TestNormalizeCustomer(t *testing.T) {
+	c := models.Customer{Email: " zoe@example.com ", Name: "Zoë Dupont\n"}
+	require.NoError(t, customers.Normalize(&c))
+	assert.Equal(t, "zoe@example.com", c.Email)
+	assert.Equal(t, "Zoë Dupont", c.Name)
+	require.NoError(t, customers.Normalize(&models.Customer{ExternalRef: "crm-42"}))
+
+	tooMany := map[string]string{}
+	for i := 0; i <= metadata.MaxKeys; i++ {
+		tooMany[fmt.Sprintf("key%d", i)] = "v"
+	}
+	for name, c := range map[string]models.Customer{
+		"nothing":       {Metadata: map[string]string{"plan": "pro"}},
+		"display name":  {Email: "Zoë <zoe@example.com>"},
+		"not an email":  {Email: "zoe"},
+		"long name":     {Name: strings.Repeat("n", customers.MaxNameLength+1)},
+		"too many keys": {Name: "Zoë", Metadata: tooMany},
+		"empty key":     {Name: "Zoë", Metadata: map[string]string{"": "v"}},
+		"long value":    {Name: "Zoë", Metadata: map[string]string{"note": strings.Repeat("é", metadata.MaxValueLength+1)}},
+	} {
+		c := c
+		assert.ErrorIs(t, customers.Normalize(&c), customers.ErrInvalidCustomer, name)
+	}
+
+	v := validation.Check(&proto.Customer{Email: "zoe", Metadata: map[string]string{"": "v"}})
+	fields := map[string]bool{}
+	for _, violation := range v {
+		fields[violation.GetField()] = true
+	}
+	assert.Equal(t, map[string]bool{"email": true, "metadata": true}, fields)
+	assert.Len(t, validation.Check(&proto.CreatePaymentRequest{Id: 1, Amount: 1, Currency: "EUR", Customer: "42"}), 1)
+	assert.Len(t, validation.Check(&proto.AttachPaymentMethodRequest{Id: testCustomerID, Token: testCardToken}), 0)
+}
+
+func TestCreateCustomer(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("INSERT INTO customers").
+		WithArgs("acme", sqlmock.AnyArg(), "zoe@example.com", "Zoë Dupont", "crm-42", []byte(`{"plan":"pro"}`)).
+		WillReturnRows(customerRows("zoe@example.com", "Zoë Dupont", "crm-42", `{"plan":"pro"}`, nil))
+	mock.ExpectCommit()
+
+	res, err := svc.CreateCustomer(ctx, &proto.Customer{Email: "zoe@example.com", Name: " Zoë Dupont", ExternalRef: "crm-42", Metadata: map[string]string{"plan": "pro"}})
+	require.NoError(t, err)
+	assert.Equal(t, testCustomerID, res.GetId())
+	assert.Equal(t, map[string]string{"plan": "pro"}, res.GetMetadata())
+	assert.Nil(t, res.GetErasedAt())
+
+	// The external reference of another customer is taken.
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("INSERT INTO customers").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "customers_external_ref_idx"})
+	mock.ExpectRollback()
+	_, err = svc.CreateCustomer(ctx, &proto.Customer{ExternalRef: "crm-42"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = svc.CreateCustomer(ctx, &proto.Customer{Metadata: map[string]string{"plan": "pro"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEraseCustomer(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+	erasedAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM customers WHERE tenant_id = \\$1 AND id = \\$2 FOR UPDATE").
+		WithArgs("acme", testCustomerID).
+		WillReturnRows(customerRows("zoe@example.com", "Zoë Dupont", "crm-42", `{"plan":"pro"}`, nil))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM subscriptions").
+		WithArgs("acme", testCustomerID).
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).AddRow(false))
+	mock.ExpectExec("DELETE FROM payment_methods WHERE tenant_id = \\$1 AND customer_id = \\$2").
+		WithArgs("acme", testCustomerID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE bank_accounts SET holder_name = '', iban = '', bic = ''").
+		WithArgs("acme", testCustomerID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// Only the free text of the payments goes; amounts and statuses stay.
+	mock.ExpectExec("UPDATE payments SET description = '', statement_descriptor = '', metadata = '{}' WHERE tenant_id = \\$1 AND customer_id = \\$2$").
+		WithArgs("acme", testCustomerID).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("UPDATE invoices SET customer_name = '', customer_email = '', customer_address = ''").
+		WithArgs("acme", testCustomerID, "zoe@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	// The pain.001 file of a refund to the customer's bank account loses
+	// the creditor; the other transfer of the batch keeps its own.
+	batch := testBatch()
+	document, err := batch.Pain001()
+	require.NoError(t, err)
+	mock.ExpectQuery("SELECT b.id, b.document, ARRAY\\(SELECT t.id FROM credit_transfers").
+		WithArgs("acme", testCustomerID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "document", "transfers"}).AddRow("ctb_1", document, "{ct-1}"))
+	var redacted captured
+	mock.ExpectExec("UPDATE credit_transfer_batches SET document = \\$3").
+		WithArgs("acme", "ctb_1", &redacted).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("UPDATE customers SET email = '', name = '', external_ref = '', metadata = '{}', erased_at = \\$3").
+		WithArgs("acme", testCustomerID, sqlmock.AnyArg()).
+		WillReturnRows(customerRows("", "", "", `{}`, erasedAt))
+	mock.ExpectCommit()
+
+	res, err := svc.EraseCustomer(ctx, &proto.EraseCustomerRequest{Id: testCustomerID})
+	require.NoError(t, err)
+	assert.Empty(t, res.GetEmail())
+	assert.Empty(t, res.GetName())
+	assert.Empty(t, res.GetExternalRef())
+	assert.Empty(t, res.GetMetadata())
+	assert.Equal(t, erasedAt, res.GetErasedAt().AsTime())
+	file := string(redacted.value.([]byte))
+	assert.NotContains(t, file, "Jane Doe")
+	assert.NotContains(t, file, batch.Transfers[0].Creditor.IBAN)
+	assert.Contains(t, file, "<Nm>Max Mustermann</Nm>")
+	assert.Contains(t, file, "<Ustrd>Refund of payment 7</Ustrd>")
+
+	// Erasing again changes nothing.
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FOR UPDATE").
+		WithArgs("acme", testCustomerID).
+		WillReturnRows(customerRows("", "", "", `{}`, erasedAt))
+	mock.ExpectCommit()
+	res, err = svc.EraseCustomer(ctx, &proto.EraseCustomerRequest{Id: testCustomerID})
+	require.NoError(t, err)
+	assert.Equal(t, erasedAt, res.GetErasedAt().AsTime())
+
+	// A live subscription keeps the customer's data.
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FOR UPDATE").
+		WithArgs("acme", testCustomerID).
+		WillReturnRows(customerRows("zoe@example.com", "", "", `{}`, nil))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM subscriptions").
+		WithArgs("acme", testCustomerID).
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).AddRow(true))
+	mock.ExpectRollback()
+	_, err = svc.EraseCustomer(ctx, &proto.EraseCustomerRequest{Id: testCustomerID})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreatePaymentForCustomer(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+
+	expectTenantLimits := func() {
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("FROM tenants").
+			WithArgs("acme").
+			WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{}", nil, nil, nil, nil))
+		mock.ExpectQuery("INSERT INTO daily_quotas").
+			WithArgs("acme", 20.0).
+			WillReturnRows(sqlmock.NewRows([]string{"payments", "amount"}).AddRow(1, 20.0))
+	}
+
+	expectTenantLimits()
+	mock.ExpectQuery("SELECT erased_at IS NOT NULL FROM customers").
+		WithArgs("acme", testCustomerID).
+		WillReturnRows(sqlmock.NewRows([]string{"erased"}).AddRow(false))
+	mock.ExpectExec("INSERT INTO payments").
+		WithArgs("acme", 1, 20.0, "EUR", "created", "", "", "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE payments SET customer_id = NULLIF\\(\\$3, ''\\), metadata = \\$4").
+		WithArgs("acme", 1, testCustomerID, []byte("{}"), "", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	_, err := svc.CreatePayment(ctx, &proto.CreatePaymentRequest{Id: 1, Amount: 20, Currency: "EUR", Customer: testCustomerID})
+	require.NoError(t, err)
+
+	// Erased customers take no new payments.
+	expectTenantLimits()
+	mock.ExpectQuery("SELECT erased_at IS NOT NULL FROM customers").
+		WithArgs("acme", testCustomerID).
+		WillReturnRows(sqlmock.NewRows([]string{"erased"}).AddRow(true))
+	mock.ExpectRollback()
+	_, err = svc.CreatePayment(ctx, &proto.CreatePaymentRequest{Id: 1, Amount: 20, Currency: "EUR", Customer: testCustomerID})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCustomerLifetimeValue(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+	first, last := customerCreated.Add(time.Hour), customerCreated.Add(48*time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT erased_at IS NOT NULL FROM customers").
+		WithArgs("acme", testCustomerID).
+		WillReturnRows(sqlmock.NewRows([]string{"erased"}).AddRow(true))
+	mock.ExpectQuery("GROUP BY p.currency").
+		WithArgs("acme", testCustomerID).
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "count", "gross", "refunded", "first", "last"}).
+			AddRow("EUR", 3, 30.3, 10.1, first, last).
+			AddRow("USD", 1, 9.99, 0.0, first, first))
+	mock.ExpectCommit()
+
+	// Erased customers keep their history.
+	res, err := svc.GetCustomerLifetimeValue(ctx, &proto.GetCustomerLifetimeValueRequest{Id: testCustomerID})
+	require.NoError(t, err)
+	require.Len(t, res.GetValues(), 2)
+	eur := res.GetValues()[0]
+	assert.Equal(t, int32(3), eur.GetPayments())
+	assert.Equal(t, 20.2, eur.GetNet())
+	assert.Equal(t, first, eur.GetFirstPaymentAt().AsTime())
+	assert.Equal(t, last, eur.GetLastPaymentAt().AsTime())
+	assert.Equal(t, 9.99, res.GetValues()[1].GetNet())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestV2Customers(t *testing.T) {
+	svc, mock, _ := newTestBankService(t)
+	logger, _ := newTestLogger()
+	r := mux.NewRouter()
+	handlers.RegisterRESTService(r, svc, logger)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(tenant.WithScope(r.Context(), tenant.Scope{TenantID: "acme"})))
+		})
+	})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("INSERT INTO customers").
+		WithArgs("acme", sqlmock.AnyArg(), "zoe@example.com", "", "", []byte(`{}`)).
+		WillReturnRows(customerRows("zoe@example.com", "", "", `{}`, nil))
+	mock.ExpectCommit()
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/customers", bytes.NewBufferString(`{"email":"zoe@example.com"}`)))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, "/v2/customers/"+testCustomerID, rec.Header().Get("Location"))
+	assert.JSONEq(t, `{"id":"`+testCustomerID+`","email":"zoe@example.com","created_at":"2026-10-01T09:00:00Z","updated_at":"2026-10-01T09:00:00Z"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/customers", bytes.NewBufferString(`{"email":"not an email"}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT erased_at IS NOT NULL FROM customers").
+		WithArgs("acme", testCustomerID).
+		WillReturnRows(sqlmock.NewRows([]string{"erased"}))
+	mock.ExpectRollback()
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/customers/"+testCustomerID+"/payments", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
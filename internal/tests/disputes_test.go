@@ -0,0 +1,526 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-lang-final/internal/blob"
+	"go-lang-final/internal/disputes"
+	"go-lang-final/internal/handlers"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/provider"
+	"go-lang-final/internal/routing"
+	"go-lang-final/internal/store"
+	"go-lang-final/internal/tenant"
+	"go-lang-final/proto"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testDisputeID  = "dp_00000000000000000000000a"
+	testEvidenceID = "dpe_00000000000000000000000b"
+)
+
+func TestValidateDispute(t *testing.T) {
+	now := vaultNow
+	d := models.Dispute{Reason: disputes.ReasonFraudulent, Amount: 10}
+	require.NoError(t, disputes.Validate(&d, now))
+	assert.Equal(t, now.Add(disputes.DefaultResponseWindow), d.EvidenceDueBy)
+
+	for name, d := range map[string]models.Dispute{
+		"reason":   {Reason: "angry", Amount: 10},
+		"amount":   {Reason: disputes.ReasonDuplicate},
+		"deadline": {Reason: disputes.ReasonDuplicate, Amount: 10, EvidenceDueBy: now.Add(-time.Hour)},
+	} {
+		assert.True(t, errors.Is(disputes.Validate(&d, now), disputes.ErrInvalidDispute), name)
+	}
+
+	e := models.DisputeEvidence{Type: disputes.EvidenceReceipt, Filename: "receipt.pdf", ContentType: "application/pdf", Size: 10}
+	assert.NoError(t, disputes.CheckEvidence(e))
+	e.ContentType = "text/plain; charset=utf-8"
+	assert.NoError(t, disputes.CheckEvidence(e))
+	for name, change := range map[string]func(*models.DisputeEvidence){
+		"type":         func(e *models.DisputeEvidence) { e.Type = "selfie" },
+		"path":         func(e *models.DisputeEvidence) { e.Filename = "../receipt.pdf" },
+		"content type": func(e *models.DisputeEvidence) { e.ContentType = "application/zip" },
+		"size":         func(e *models.DisputeEvidence) { e.Size = disputes.MaxEvidenceSize + 1 },
+	} {
+		e := e
+		change(&e)
+		assert.True(t, errors.Is(disputes.CheckEvidence(e), disputes.ErrInvalidEvidence), name)
+	}
+
+	assert.True(t, disputes.CanMove(models.DisputeNeedsResponse, models.DisputeUnderReview))
+	assert.True(t, disputes.CanMove(models.DisputeUnderReview, models.DisputeWon))
+	assert.False(t, disputes.CanMove(models.DisputeUnderReview, models.DisputeNeedsResponse))
+	assert.False(t, disputes.CanMove(models.DisputeLost, models.DisputeWon))
+}
+
+func TestRemindersDue(t *testing.T) {
+	leads := []time.Duration{24 * time.Hour, 72 * time.Hour}
+	disputes.SortReminders(leads)
+	assert.Equal(t, []time.Duration{72 * time.Hour, 24 * time.Hour}, leads)
+
+	due := vaultNow.Add(100 * time.Hour)
+	assert.Equal(t, 0, disputes.RemindersDue(due, vaultNow, leads))
+	assert.Equal(t, 1, disputes.RemindersDue(due, vaultNow.Add(28*time.Hour), leads))
+	assert.Equal(t, 2, disputes.RemindersDue(due, vaultNow.Add(99*time.Hour), leads))
+	// Past the deadline the dispute is lost instead.
+	assert.Equal(t, 0, disputes.RemindersDue(due, due, leads))
+}
+
+func TestBlobStores(t *testing.T) {
+	disk, err := blob.New(blob.TypeDisk, t.TempDir())
+	require.NoError(t, err)
+	memory, err := blob.New(blob.TypeMemory, "")
+	require.NoError(t, err)
+	_, err = blob.New("s3", "")
+	assert.Error(t, err)
+
+	ctx := context.Background()
+	for name, s := range map[string]blob.Store{"disk": disk, "memory": memory} {
+		require.NoError(t, s.Put(ctx, "dp_1/dpe_1", bytes.NewBufferString("first")), name)
+		require.NoError(t, s.Put(ctx, "dp_1/dpe_1", bytes.NewBufferString("receipt")), name)
+		r, err := s.Get(ctx, "dp_1/dpe_1")
+		require.NoError(t, err, name)
+		b, err := io.ReadAll(r)
+		r.Close()
+		require.NoError(t, err, name)
+		assert.Equal(t, "receipt", string(b), name)
+
+		require.NoError(t, s.Delete(ctx, "dp_1/dpe_1"), name)
+		require.NoError(t, s.Delete(ctx, "dp_1/dpe_1"), name)
+		_, err = s.Get(ctx, "dp_1/dpe_1")
+		assert.ErrorIs(t, err, blob.ErrNotFound, name)
+		assert.ErrorIs(t, s.Put(ctx, "../outside", bytes.NewBufferString("x")), blob.ErrInvalidKey, name)
+	}
+}
+
+func TestSimulatorDisputes(t *testing.T) {
+	sim, clock := newTestSimulator()
+	ctx := context.Background()
+
+	txn, err := sim.Authorize(ctx, provider.Authorization{Amount: 40, Currency: "USD", CardNumber: provider.CardDisputed})
+	require.NoError(t, err)
+	_, err = sim.Capture(ctx, txn.Ref, 40)
+	require.NoError(t, err)
+
+	list, err := sim.Disputes(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	d := list[0]
+	assert.Equal(t, txn.Ref, d.Ref)
+	assert.Equal(t, disputes.ReasonFraudulent, d.Reason)
+	assert.Equal(t, 40.0, d.Amount)
+	assert.Equal(t, provider.DisputeNeedsResponse, d.Status)
+	assert.Equal(t, clock.now.Add(provider.DefaultDisputeWindow), d.EvidenceDueBy)
+
+	_, err = sim.SubmitEvidence(ctx, d.ID, nil)
+	assert.ErrorIs(t, err, provider.ErrNotAllowed)
+	d, err = sim.SubmitEvidence(ctx, d.ID, []provider.Evidence{{Type: disputes.EvidenceReceipt, Filename: "receipt.txt", ContentType: "text/plain", Content: []byte("paid")}})
+	require.NoError(t, err)
+	assert.Equal(t, provider.DisputeUnderReview, d.Status)
+	require.NoError(t, sim.ResolveDispute(d.ID, true))
+	assert.ErrorIs(t, sim.ResolveDispute(d.ID, false), provider.ErrNotAllowed)
+
+	// Disputes can be opened on any captured transaction, up to what is
+	// left of it.
+	txn, err = sim.Authorize(ctx, provider.Authorization{Amount: 10, Currency: "USD"})
+	require.NoError(t, err)
+	_, err = sim.OpenDispute(txn.Ref, disputes.ReasonDuplicate, 10)
+	assert.ErrorIs(t, err, provider.ErrNotAllowed)
+	_, err = sim.Capture(ctx, txn.Ref, 10)
+	require.NoError(t, err)
+	_, err = sim.OpenDispute(txn.Ref, disputes.ReasonDuplicate, 10.01)
+	assert.ErrorIs(t, err, provider.ErrNotAllowed)
+	_, err = sim.OpenDispute(txn.Ref, disputes.ReasonDuplicate, 10)
+	require.NoError(t, err)
+	list, err = sim.Disputes(ctx)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, provider.DisputeWon, list[0].Status)
+}
+
+func disputeRows(status string, dueBy time.Time, provider, ref string, remindersSent int) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "tenant_id", "payment_id", "reason", "amount", "currency", "status", "evidence_due_by", "provider", "provider_ref", "outcome",
+		"reminders_sent", "submitted_at", "closed_at", "created_at", "updated_at"}).
+		AddRow(testDisputeID, "acme", 1, disputes.ReasonFraudulent, 40.0, "USD", status, dueBy, provider, ref, "", remindersSent, nil, nil, vaultNow, vaultNow)
+}
+
+func evidenceRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "dispute_id", "type", "filename", "content_type", "size", "sha256", "blob_key", "created_at"})
+}
+
+// expectDisputeOpened expects a dispute of 40 of payment 1, captured for
+// 100 of which disputed was already disputed, to be opened.
+func expectDisputeOpened(mock sqlmock.Sqlmock, disputed float64, provider, ref string) {
+	mock.ExpectQuery("SELECT amount, currency, status, refunded_amount FROM payments WHERE tenant_id = \\$1 AND id = \\$2 FOR UPDATE").
+		WithArgs("acme", int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"amount", "currency", "status", "refunded_amount"}).AddRow(100.0, "USD", models.StatusCreated, 10.0))
+	mock.ExpectQuery("SELECT COALESCE\\(sum\\(amount\\), 0\\) FROM disputes").
+		WithArgs("acme", int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(disputed))
+	if disputed > 50 {
+		return
+	}
+	mock.ExpectQuery("INSERT INTO disputes").
+		WithArgs("acme", sqlmock.AnyArg(), int64(1), disputes.ReasonFraudulent, 40.0, "USD", models.DisputeNeedsResponse, sqlmock.AnyArg(), provider, ref).
+		WillReturnRows(disputeRows(models.DisputeNeedsResponse, vaultNow.Add(disputes.DefaultResponseWindow), provider, ref, 0))
+	expectDisputePostings(mock, "merchant_balance", "dispute_holds", "hold for dispute of payment 1")
+}
+
+func expectDisputePostings(mock sqlmock.Sqlmock, debit, credit, description string) {
+	mock.ExpectExec("INSERT INTO ledger_postings").
+		WithArgs("acme", sqlmock.AnyArg(), debit, 40.0, "USD", sqlmock.AnyArg(), "dispute", testDisputeID, description).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO ledger_postings").
+		WithArgs("acme", sqlmock.AnyArg(), credit, -40.0, "USD", sqlmock.AnyArg(), "dispute", testDisputeID, description).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+}
+
+func TestCreateDispute(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectDisputeOpened(mock, 0, "", "")
+	mock.ExpectCommit()
+	res, err := svc.CreateDispute(ctx, &proto.CreateDisputeRequest{PaymentId: 1, Reason: disputes.ReasonFraudulent, Amount: 40})
+	require.NoError(t, err)
+	assert.Equal(t, testDisputeID, res.GetId())
+	assert.Equal(t, models.DisputeNeedsResponse, res.GetStatus())
+	assert.Equal(t, "USD", res.GetCurrency())
+
+	// 100 was paid, 10 refunded and 60 disputed, leaving 30.
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectDisputeOpened(mock, 60, "", "")
+	mock.ExpectRollback()
+	_, err = svc.CreateDispute(ctx, &proto.CreateDisputeRequest{PaymentId: 1, Reason: disputes.ReasonFraudulent, Amount: 40})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = svc.CreateDispute(ctx, &proto.CreateDisputeRequest{PaymentId: 1, Reason: "angry", Amount: 40})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCloseDispute(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+	dueBy := vaultNow.Add(time.Hour)
+
+	// Accepting a dispute loses it; the held amount goes to the cardholder.
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM disputes WHERE tenant_id = \\$1 AND id = \\$2").
+		WithArgs("acme", testDisputeID).
+		WillReturnRows(disputeRows(models.DisputeNeedsResponse, dueBy, "", "", 0))
+	mock.ExpectQuery("FROM dispute_evidence").WithArgs("acme", testDisputeID).WillReturnRows(evidenceRows())
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM disputes WHERE tenant_id = \\$1 AND id = \\$2 FOR UPDATE").
+		WithArgs("acme", testDisputeID).
+		WillReturnRows(disputeRows(models.DisputeNeedsResponse, dueBy, "", "", 0))
+	mock.ExpectQuery("UPDATE disputes SET status = \\$3, outcome = \\$4").
+		WithArgs("acme", testDisputeID, models.DisputeLost, "accepted", sqlmock.AnyArg()).
+		WillReturnRows(disputeRows(models.DisputeLost, dueBy, "", "", 0))
+	expectDisputePostings(mock, "dispute_holds", "chargebacks", "reversal of lost dispute of payment 1")
+	mock.ExpectCommit()
+	res, err := svc.AcceptDispute(ctx, &proto.AcceptDisputeRequest{Id: testDisputeID})
+	require.NoError(t, err)
+	assert.Equal(t, models.DisputeLost, res.GetStatus())
+
+	// A won dispute releases the hold back to the balance.
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM disputes WHERE tenant_id = \\$1 AND id = \\$2").
+		WithArgs("acme", testDisputeID).
+		WillReturnRows(disputeRows(models.DisputeUnderReview, dueBy, "", "", 0))
+	mock.ExpectQuery("FROM dispute_evidence").WithArgs("acme", testDisputeID).WillReturnRows(evidenceRows())
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM disputes WHERE tenant_id = \\$1 AND id = \\$2 FOR UPDATE").
+		WithArgs("acme", testDisputeID).
+		WillReturnRows(disputeRows(models.DisputeUnderReview, dueBy, "", "", 0))
+	mock.ExpectQuery("UPDATE disputes SET status = \\$3, outcome = \\$4").
+		WithArgs("acme", testDisputeID, models.DisputeWon, "bank sided with us", sqlmock.AnyArg()).
+		WillReturnRows(disputeRows(models.DisputeWon, dueBy, "", "", 0))
+	expectDisputePostings(mock, "dispute_holds", "merchant_balance", "release of won dispute of payment 1")
+	mock.ExpectCommit()
+	res, err = svc.ResolveDispute(ctx, &proto.ResolveDisputeRequest{Id: testDisputeID, Status: models.DisputeWon, Outcome: "bank sided with us"})
+	require.NoError(t, err)
+	assert.Equal(t, models.DisputeWon, res.GetStatus())
+
+	// Closed disputes stay closed.
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM disputes WHERE tenant_id = \\$1 AND id = \\$2").
+		WithArgs("acme", testDisputeID).
+		WillReturnRows(disputeRows(models.DisputeWon, dueBy, "", "", 0))
+	mock.ExpectQuery("FROM dispute_evidence").WithArgs("acme", testDisputeID).WillReturnRows(evidenceRows())
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM disputes WHERE tenant_id = \\$1 AND id = \\$2 FOR UPDATE").
+		WithArgs("acme", testDisputeID).
+		WillReturnRows(disputeRows(models.DisputeWon, dueBy, "", "", 0))
+	mock.ExpectRollback()
+	_, err = svc.ResolveDispute(ctx, &proto.ResolveDisputeRequest{Id: testDisputeID, Status: models.DisputeLost})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// The provider decides the disputes it reported.
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM disputes WHERE tenant_id = \\$1 AND id = \\$2").
+		WithArgs("acme", testDisputeID).
+		WillReturnRows(disputeRows(models.DisputeUnderReview, dueBy, provider.SimulatorName, "simdp_000001", 0))
+	mock.ExpectQuery("FROM dispute_evidence").WithArgs("acme", testDisputeID).WillReturnRows(evidenceRows())
+	mock.ExpectCommit()
+	_, err = svc.ResolveDispute(ctx, &proto.ResolveDisputeRequest{Id: testDisputeID, Status: models.DisputeWon})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestV2DisputeEvidence(t *testing.T) {
+	svc, mock, _ := newTestBankService(t)
+	evidence := blob.NewMemory()
+	svc.ConfigureDisputes(evidence, nil, nil)
+	logger, _ := newTestLogger()
+	r := mux.NewRouter()
+	handlers.RegisterRESTService(r, svc, logger)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(tenant.WithScope(r.Context(), tenant.Scope{TenantID: "acme"})))
+		})
+	})
+	dueBy := vaultNow.Add(time.Hour)
+	doc := []byte("Order 1 was delivered on 2026-05-02.")
+	sum := sha256.Sum256(doc)
+	digest := hex.EncodeToString(sum[:])
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM disputes WHERE tenant_id = \\$1 AND id = \\$2 FOR UPDATE").
+		WithArgs("acme", testDisputeID).
+		WillReturnRows(disputeRows(models.DisputeNeedsResponse, dueBy, "", "", 0))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM dispute_evidence").
+		WithArgs("acme", testDisputeID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("INSERT INTO dispute_evidence").
+		WithArgs("acme", sqlmock.AnyArg(), testDisputeID, disputes.EvidenceShippingDocumentation, "delivery.txt", "text/plain", int64(len(doc)), digest, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(vaultNow))
+	mock.ExpectCommit()
+	req := httptest.NewRequest(http.MethodPost, "/v2/disputes/"+testDisputeID+"/evidence?type=shipping_documentation&filename=delivery.txt", bytes.NewReader(doc))
+	req.Header.Set("Content-Type", "text/plain")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var e models.DisputeEvidence
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &e))
+	assert.Equal(t, digest, e.SHA256)
+	assert.Equal(t, "/v2/disputes/"+testDisputeID+"/evidence/"+e.ID, rec.Header().Get("Location"))
+	stored, err := evidence.Get(context.Background(), testDisputeID+"/"+e.ID)
+	require.NoError(t, err)
+	content, _ := io.ReadAll(stored)
+	assert.Equal(t, doc, content)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM dispute_evidence WHERE tenant_id = \\$1 AND dispute_id = \\$2 AND id = \\$3").
+		WithArgs("acme", testDisputeID, testEvidenceID).
+		WillReturnRows(evidenceRows().AddRow(testEvidenceID, testDisputeID, disputes.EvidenceShippingDocumentation, "delivery.txt", "text/plain", len(doc), digest,
+			testDisputeID+"/"+e.ID, vaultNow))
+	mock.ExpectCommit()
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/disputes/"+testDisputeID+"/evidence/"+testEvidenceID, nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "text/plain", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=delivery.txt`, rec.Header().Get("Content-Disposition"))
+	assert.Equal(t, doc, rec.Body.Bytes())
+
+	// Files that are not accepted as evidence never reach the store.
+	req = httptest.NewRequest(http.MethodPost, "/v2/disputes/"+testDisputeID+"/evidence?type=shipping_documentation&filename=delivery.zip", bytes.NewReader(doc))
+	req.Header.Set("Content-Type", "application/zip")
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+
+	// Evidence can only be added while the dispute needs a response.
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM disputes WHERE tenant_id = \\$1 AND id = \\$2 FOR UPDATE").
+		WithArgs("acme", testDisputeID).
+		WillReturnRows(disputeRows(models.DisputeUnderReview, dueBy, "", "", 0))
+	mock.ExpectRollback()
+	req = httptest.NewRequest(http.MethodPost, "/v2/disputes/"+testDisputeID+"/evidence?type=receipt&filename=receipt.txt", bytes.NewReader(doc))
+	req.Header.Set("Content-Type", "text/plain")
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), "dispute does not allow this operation")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// recordingNotifier keeps the reminders it is given.
+type recordingNotifier struct{ reminders []disputes.Reminder }
+
+func (n *recordingNotifier) Remind(ctx context.Context, r disputes.Reminder) error {
+	n.reminders = append(n.reminders, r)
+	return nil
+}
+
+func TestCheckDisputeDeadlines(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+	notifier := &recordingNotifier{}
+	svc.ConfigureDisputes(blob.NewMemory(), []time.Duration{24 * time.Hour, 72 * time.Hour}, notifier)
+	ctx = tenant.WithScope(ctx, tenant.Scope{All: true})
+	now := time.Now().UTC()
+	overdue, soon := now.Add(-time.Minute), now.Add(30*time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("", "on").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM disputes WHERE \\(\\$1 = '' OR tenant_id = \\$1\\) AND status = 'needs_response'").
+		WithArgs("", sqlmock.AnyArg(), sqlmock.AnyArg(), 2, 100).
+		WillReturnRows(disputeRows(models.DisputeNeedsResponse, overdue, "", "", 2).
+			AddRow("dp_00000000000000000000000c", "acme", 2, disputes.ReasonDuplicate, 5.0, "USD", models.DisputeNeedsResponse, soon, "", "", "", 0, nil, nil, vaultNow, vaultNow))
+	mock.ExpectCommit()
+
+	// The deadline of the first passed without a response.
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM disputes WHERE tenant_id = \\$1 AND id = \\$2 FOR UPDATE").
+		WithArgs("acme", testDisputeID).
+		WillReturnRows(disputeRows(models.DisputeNeedsResponse, overdue, "", "", 2))
+	mock.ExpectQuery("UPDATE disputes SET status = \\$3, outcome = \\$4").
+		WithArgs("acme", testDisputeID, models.DisputeLost, "evidence deadline passed", sqlmock.AnyArg()).
+		WillReturnRows(disputeRows(models.DisputeLost, overdue, "", "", 2))
+	expectDisputePostings(mock, "dispute_holds", "chargebacks", "reversal of lost dispute of payment 1")
+	mock.ExpectCommit()
+
+	// The second is due within 72 hours but not yet within 24.
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE disputes SET reminders_sent = \\$4").
+		WithArgs("acme", "dp_00000000000000000000000c", 0, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	handled, err := svc.CheckDisputeDeadlines(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, handled)
+	require.Len(t, notifier.reminders, 1)
+	assert.Equal(t, "dp_00000000000000000000000c", notifier.reminders[0].Dispute.ID)
+	assert.InDelta(t, (30 * time.Hour).Seconds(), notifier.reminders[0].Left.Seconds(), 60)
+
+	// Another replica sent the reminder first.
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("", "on").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM disputes").
+		WithArgs("", sqlmock.AnyArg(), sqlmock.AnyArg(), 2, 100).
+		WillReturnRows(disputeRows(models.DisputeNeedsResponse, soon, "", "", 0))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE disputes SET reminders_sent = \\$4").
+		WithArgs("acme", testDisputeID, 0, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	handled, err = svc.CheckDisputeDeadlines(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, handled)
+	assert.Len(t, notifier.reminders, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSyncDisputes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	sim, _ := newTestSimulator()
+	svc := handlers.NewRoutedPaymentService(&store.PaymentStore{DB: db}, routing.Single(sim), nil)
+	evidence := blob.NewMemory()
+	svc.ConfigureDisputes(evidence, nil, nil)
+	ctx := tenant.WithScope(context.Background(), tenant.Scope{All: true})
+
+	txn, err := sim.Authorize(ctx, provider.Authorization{Amount: 100, Currency: "USD"})
+	require.NoError(t, err)
+	_, err = sim.Capture(ctx, txn.Ref, 100)
+	require.NoError(t, err)
+	pd, err := sim.OpenDispute(txn.Ref, disputes.ReasonFraudulent, 40)
+	require.NoError(t, err)
+
+	expectProviderPayment := func() {
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT set_config").WithArgs("", "on").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("FROM payments WHERE \\(\\$1 = '' OR tenant_id = \\$1\\) AND provider = \\$2 AND provider_ref = \\$3").
+			WithArgs("", provider.SimulatorName, txn.Ref).
+			WillReturnRows(paymentRows(models.Payment{TenantID: "acme", ID: 1, Amount: 100, Currency: "USD", Status: models.StatusCreated}))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	// A new dispute is opened against the payment of its transaction.
+	expectProviderPayment()
+	mock.ExpectQuery("FROM disputes WHERE tenant_id = \\$1 AND provider = \\$2 AND provider_ref = \\$3 FOR UPDATE").
+		WithArgs("acme", provider.SimulatorName, pd.ID).
+		WillReturnRows(sqlmock.NewRows(nil))
+	expectDisputeOpened(mock, 0, provider.SimulatorName, pd.ID)
+	mock.ExpectCommit()
+	changed, err := svc.SyncDisputes(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, changed)
+
+	// Submitting it sends the evidence to the provider.
+	require.NoError(t, evidence.Put(ctx, testDisputeID+"/"+testEvidenceID, bytes.NewBufferString("receipt")))
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM disputes WHERE tenant_id = \\$1 AND id = \\$2 FOR UPDATE").
+		WithArgs("acme", testDisputeID).
+		WillReturnRows(disputeRows(models.DisputeNeedsResponse, pd.EvidenceDueBy, provider.SimulatorName, pd.ID, 0))
+	mock.ExpectQuery("FROM dispute_evidence").
+		WithArgs("acme", testDisputeID).
+		WillReturnRows(evidenceRows().AddRow(testEvidenceID, testDisputeID, disputes.EvidenceReceipt, "receipt.txt", "text/plain", 7, "", testDisputeID+"/"+testEvidenceID, vaultNow))
+	mock.ExpectQuery("UPDATE disputes SET status = 'under_review'").
+		WithArgs("acme", testDisputeID, sqlmock.AnyArg()).
+		WillReturnRows(disputeRows(models.DisputeUnderReview, pd.EvidenceDueBy, provider.SimulatorName, pd.ID, 0))
+	mock.ExpectCommit()
+	res, err := svc.SubmitDispute(tenant.WithScope(ctx, tenant.Scope{TenantID: "acme"}), &proto.SubmitDisputeRequest{Id: testDisputeID})
+	require.NoError(t, err)
+	assert.Equal(t, models.DisputeUnderReview, res.GetStatus())
+	list, err := sim.Disputes(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, provider.DisputeUnderReview, list[0].Status)
+
+	// The bank's decision is recorded on the next sync.
+	require.NoError(t, sim.ResolveDispute(pd.ID, false))
+	expectProviderPayment()
+	mock.ExpectQuery("FROM disputes WHERE tenant_id = \\$1 AND provider = \\$2 AND provider_ref = \\$3 FOR UPDATE").
+		WithArgs("acme", provider.SimulatorName, pd.ID).
+		WillReturnRows(disputeRows(models.DisputeUnderReview, pd.EvidenceDueBy, provider.SimulatorName, pd.ID, 0))
+	mock.ExpectQuery("UPDATE disputes SET status = \\$3, outcome = \\$4").
+		WithArgs("acme", testDisputeID, models.DisputeLost, "decided by "+provider.SimulatorName, sqlmock.AnyArg()).
+		WillReturnRows(disputeRows(models.DisputeLost, pd.EvidenceDueBy, provider.SimulatorName, pd.ID, 0))
+	expectDisputePostings(mock, "dispute_holds", "chargebacks", "reversal of lost dispute of payment 1")
+	mock.ExpectCommit()
+	changed, err = svc.SyncDisputes(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, changed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
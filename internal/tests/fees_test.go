@@ -0,0 +1,247 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-lang-final/internal/fees"
+	"go-lang-final/internal/handlers"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/tenant"
+	"go-lang-final/internal/validation"
+	"go-lang-final/proto"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func testPricingPlan() models.PricingPlan {
+	return models.PricingPlan{
+		ID:   "pp_00000000000000000000000a",
+		Name: "standard",
+		Pricing: models.Pricing{
+			Percentage: "2.9",
+			Fixed:      0.30,
+			Min:        0.50,
+			Max:        10,
+			Tiers:      []models.PricingTier{{Volume: 10000, Percentage: "2.5", Fixed: 0.25}},
+		},
+		Overrides: []models.PricingOverride{
+			{Currency: "EUR", Pricing: models.Pricing{Percentage: "1.4", Fixed: 0.25}},
+			{Method: fees.MethodBankTransfer, Pricing: models.Pricing{Percentage: "0.8", Max: 5}},
+			{Currency: "EUR", Method: fees.MethodBankTransfer, Pricing: models.Pricing{Fixed: 0.35}},
+		},
+	}
+}
+
+func TestCalculateFee(t *testing.T) {
+	plan := testPricingPlan()
+	for _, tc := range []struct {
+		name     string
+		amount   int64
+		currency string
+		method   string
+		volume   int64
+		want     int64
+	}{
+		{"percentage plus fixed", 10000, "USD", fees.MethodCard, 0, 320},
+		{"rounded", 1000, "USD", fees.MethodCard, 0, 59},
+		{"minimum", 500, "USD", fees.MethodCard, 0, 50},
+		{"maximum", 100000, "USD", fees.MethodCard, 0, 1000},
+		{"below tier", 10000, "USD", fees.MethodCard, 999999, 320},
+		{"tier", 10000, "USD", fees.MethodCard, 1000000, 275},
+		{"currency override", 10000, "EUR", fees.MethodCard, 0, 165},
+		{"method override", 100000, "USD", fees.MethodBankTransfer, 0, 500},
+		{"currency and method override", 10000, "EUR", fees.MethodBankTransfer, 0, 35},
+	} {
+		fee, err := fees.Calculate(plan, tc.amount, tc.currency, tc.method, tc.volume)
+		require.NoError(t, err, tc.name)
+		assert.Equal(t, tc.want, fee.Amount, tc.name)
+	}
+
+	// 1.5% of 1.00 is 1.5 cents, rounded half away from zero.
+	fee, err := fees.Calculate(models.PricingPlan{Pricing: models.Pricing{Percentage: "1.5"}}, 100, "USD", fees.MethodCard, 0)
+	require.NoError(t, err)
+	assert.Equal(t, fees.Fee{Amount: 2, Percentage: "1.5"}, fee)
+
+	assert.Equal(t, fees.MethodBankTransfer, fees.Method("ba_00000000000000000000000a"))
+	assert.Equal(t, fees.MethodCard, fees.Method("pm_00000000000000000000000a"))
+	assert.Equal(t, fees.MethodCard, fees.Method(""))
+}
+
+func TestValidatePricingPlan(t *testing.T) {
+	plan := testPricingPlan()
+	plan.Pricing.Percentage = "2.9000"
+	require.NoError(t, fees.Validate(&plan))
+	assert.Equal(t, "2.9", plan.Pricing.Percentage)
+
+	for name, change := range map[string]func(*models.PricingPlan){
+		"no name":           func(p *models.PricingPlan) { p.Name = " " },
+		"percentage above":  func(p *models.PricingPlan) { p.Pricing.Percentage = "100.5" },
+		"too many decimals": func(p *models.PricingPlan) { p.Pricing.Percentage = "2.12345" },
+		"negative fixed":    func(p *models.PricingPlan) { p.Pricing.Fixed = -1 },
+		"max below min":     func(p *models.PricingPlan) { p.Pricing.Max = 0.10 },
+		"descending tiers": func(p *models.PricingPlan) {
+			p.Pricing.Tiers = append(p.Pricing.Tiers, models.PricingTier{Volume: 5000})
+		},
+		"empty override":     func(p *models.PricingPlan) { p.Overrides[0].Currency = "" },
+		"unknown method":     func(p *models.PricingPlan) { p.Overrides[1].Method = "wallet" },
+		"duplicate override": func(p *models.PricingPlan) { p.Overrides[1].Currency = "EUR" },
+	} {
+		plan := testPricingPlan()
+		plan.Overrides = append([]models.PricingOverride(nil), plan.Overrides...)
+		change(&plan)
+		assert.ErrorIs(t, fees.Validate(&plan), fees.ErrInvalidPlan, name)
+	}
+
+	v := validation.Check(&proto.PricingPlan{Id: "plan-1", Pricing: &proto.Pricing{Percentage: "x"}, Overrides: []*proto.PricingOverride{{}}})
+	fields := map[string]bool{}
+	for _, violation := range v {
+		fields[violation.GetField()] = true
+	}
+	assert.Equal(t, map[string]bool{
+		"id": true, "name": true, "pricing.percentage": true, "overrides[0]": true, "overrides[0].pricing": true,
+	}, fields)
+}
+
+func expectTenantPlan(t *testing.T, mock sqlmock.Sqlmock, plan *models.PricingPlan) {
+	rows := sqlmock.NewRows([]string{"id", "name", "pricing", "overrides", "created_at", "updated_at"})
+	if plan != nil {
+		pricing, err := json.Marshal(plan.Pricing)
+		require.NoError(t, err)
+		overrides, err := json.Marshal(plan.Overrides)
+		require.NoError(t, err)
+		rows.AddRow(plan.ID, plan.Name, pricing, overrides, vaultNow, vaultNow)
+	}
+	mock.ExpectQuery("FROM tenants t JOIN pricing_plans p").WithArgs("acme").WillReturnRows(rows)
+}
+
+func TestCreatePaymentChargesFee(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+	svc.ConfigureFees(true)
+	plan := testPricingPlan()
+
+	expectPaymentInsert(mock, 100.0, "USD")
+	expectTenantPlan(t, mock, &plan)
+	mock.ExpectQuery("SELECT COALESCE\\(sum\\(payment_amount\\), 0\\) FROM payment_fees").
+		WithArgs("acme", "USD", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(12000.0))
+	mock.ExpectQuery("INSERT INTO payment_fees").
+		WithArgs("acme", int64(1), plan.ID, 2.75, "USD", 100.0, fees.MethodCard, "2.5", 0.25, 12000.0).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(vaultNow))
+	mock.ExpectExec("INSERT INTO ledger_postings").
+		WithArgs("acme", sqlmock.AnyArg(), "merchant_balance", 2.75, "USD", sqlmock.AnyArg(), "fee", plan.ID, "fee on payment 1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO ledger_postings").
+		WithArgs("acme", sqlmock.AnyArg(), "fee_revenue", -2.75, "USD", sqlmock.AnyArg(), "fee", plan.ID, "fee on payment 1").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	res, err := svc.CreatePayment(ctx, &proto.CreatePaymentRequest{Id: 1, Amount: 100, Currency: "USD"})
+	require.NoError(t, err)
+	fee := res.GetFee()
+	require.NotNil(t, fee)
+	assert.Equal(t, 2.75, fee.GetAmount())
+	assert.Equal(t, "2.5", fee.GetPercentage())
+	assert.Equal(t, plan.ID, fee.GetPlanId())
+
+	// A tenant without a plan is not charged.
+	expectPaymentInsert(mock, 100.0, "USD")
+	expectTenantPlan(t, mock, nil)
+	mock.ExpectCommit()
+	res, err = svc.CreatePayment(ctx, &proto.CreatePaymentRequest{Id: 1, Amount: 100, Currency: "USD"})
+	require.NoError(t, err)
+	assert.Nil(t, res.GetFee())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSimulateFee(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+	plan := testPricingPlan()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectTenantPlan(t, mock, &plan)
+	mock.ExpectCommit()
+
+	volume := 0.0
+	fee, err := svc.SimulateFee(ctx, &proto.SimulateFeeRequest{Amount: 100, Currency: "EUR", Volume: &volume})
+	require.NoError(t, err)
+	assert.Equal(t, 1.65, fee.GetAmount())
+	assert.Equal(t, fees.MethodCard, fee.GetMethod())
+	assert.Nil(t, fee.GetCreatedAt())
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectTenantPlan(t, mock, nil)
+	mock.ExpectRollback()
+	_, err = svc.SimulateFee(ctx, &proto.SimulateFeeRequest{Amount: 100, Currency: "EUR"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestV2Fees(t *testing.T) {
+	svc, mock, _ := newTestBankService(t)
+	logger, _ := newTestLogger()
+	r := mux.NewRouter()
+	handlers.RegisterRESTService(r, svc, logger)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(tenant.WithScope(r.Context(), tenant.Scope{TenantID: "acme"})))
+		})
+	})
+
+	mock.ExpectQuery("INSERT INTO pricing_plans").
+		WithArgs(sqlmock.AnyArg(), "standard", []byte(`{"percentage":"2.9","fixed":0.3}`), []byte(`[]`)).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at"}).AddRow(vaultNow, vaultNow))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/pricing_plans",
+		bytes.NewBufferString(`{"name":"standard","pricing":{"percentage":"2.90","fixed":0.3}}`)))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var plan models.PricingPlan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &plan))
+	assert.Regexp(t, "^pp_[0-9a-f]{24}$", plan.ID)
+	assert.Equal(t, "/v2/pricing_plans/"+plan.ID, rec.Header().Get("Location"))
+	assert.Equal(t, "2.9", plan.Pricing.Percentage)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/pricing_plans",
+		bytes.NewBufferString(`{"name":"standard","pricing":{"percentage":"101"}}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM payment_fees").
+		WithArgs("acme", time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)).
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "count", "volume", "fees"}).
+			AddRow("EUR", 3, 300.0, 4.95).
+			AddRow("USD", 1, 100.0, 3.2))
+	mock.ExpectCommit()
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/fee_statements/2026-09", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `{"tenant_id":"acme","month":"2026-09","totals":[
+		{"currency":"EUR","payments":3,"volume":300,"fees":4.95},
+		{"currency":"USD","payments":1,"volume":100,"fees":3.2}]}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/fee_statements/september", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE tenants SET pricing_plan_id").WithArgs("acme", nil).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/v2/pricing_plan", bytes.NewBufferString(`{"plan_id":""}`)))
+	assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
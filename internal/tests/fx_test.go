@@ -0,0 +1,358 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-lang-final/internal/fees"
+	"go-lang-final/internal/fx"
+	"go-lang-final/internal/handlers"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/money"
+	"go-lang-final/internal/store"
+	"go-lang-final/internal/tenant"
+	"go-lang-final/internal/validation"
+	"go-lang-final/proto"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestFXConvert(t *testing.T) {
+	for _, tc := range []struct {
+		amount          int64
+		currency, quote string
+		rate            string
+		want            int64
+	}{
+		{10000, "USD", "EUR", "1.085", 10850},
+		{5, "USD", "EUR", "0.5", 3},
+		{-5, "USD", "EUR", "0.5", -3},
+		{7, "USD", "EUR", "0.5", 4},
+		{2550, "USD", "EUR", "1.1734", 2992},
+		{10000, "USD", "EUR", "0.9216589862", 9217},
+		{123456789, "USD", "EUR", "470.1234567891", 58039932409},
+		// JPY has no decimals: 10.00 EUR are 1626 yen, not 162586.
+		{1000, "EUR", "JPY", "162.586", 1626},
+		{1626, "JPY", "EUR", "0.0061506", 1000},
+		// KWD has three: 10.00 EUR are 3.312 dinars, 3312 fils.
+		{1000, "EUR", "KWD", "0.33118", 3312},
+		{3312, "KWD", "EUR", "3.0195", 1000},
+		{100, "KWD", "JPY", "490.9", 49},
+	} {
+		got, err := fx.Convert(tc.amount, tc.currency, tc.quote, tc.rate)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, got, "%d %s at %s", tc.amount, tc.currency, tc.rate)
+	}
+	assert.Equal(t, 3, money.Exponent("KWD"))
+	assert.Equal(t, 0, money.Exponent("JPY"))
+	assert.Equal(t, 2, money.Exponent("EUR"))
+	assert.Equal(t, int64(1500), money.ToMinor(1.5, "KWD"))
+	assert.Equal(t, 1500.0, money.ToMajor(1500, "JPY"))
+
+	inverted, err := fx.Invert("1.085")
+	require.NoError(t, err)
+	assert.Equal(t, "0.9216589862", inverted)
+	inverted, err = fx.Invert("2.00")
+	require.NoError(t, err)
+	assert.Equal(t, "0.5", inverted)
+	_, err = fx.Invert("100000000000")
+	assert.ErrorIs(t, err, fx.ErrInvalidRate)
+
+	for _, rate := range []string{"", "0", "0.0", "-1.2", "1e3", "1,5", "1.12345678901"} {
+		_, err := fx.Parse(rate)
+		assert.ErrorIs(t, err, fx.ErrInvalidRate, rate)
+	}
+}
+
+func TestParseFXTable(t *testing.T) {
+	table := "\ufeffbase,quote,rate,effective_at\n" +
+		"# published 2026-10-01\n" +
+		"eur,usd,1.0850000000,2026-10-01T00:00:00Z\n" +
+		"EUR,KZT, 512.37,2026-10-01T02:00:00+02:00\n"
+	rates, err := fx.ParseTable(strings.NewReader(table), "file:rates.csv")
+	require.NoError(t, err)
+	require.Len(t, rates, 2)
+	assert.Equal(t, fx.Rate{
+		Base: "EUR", Quote: "USD", Rate: "1.085", EffectiveAt: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), Source: "file:rates.csv",
+	}, rates[0])
+	assert.Equal(t, "512.37", rates[1].Rate)
+	assert.True(t, rates[1].EffectiveAt.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)))
+
+	for name, table := range map[string]string{
+		"empty":          "",
+		"no rates":       "base,quote,rate,effective_at\n",
+		"wrong header":   "from,to,rate,at\nEUR,USD,1.08,2026-10-01T00:00:00Z\n",
+		"date only":      "base,quote,rate,effective_at\nEUR,USD,1.08,2026-10-01\n",
+		"same currency":  "base,quote,rate,effective_at\nEUR,EUR,1,2026-10-01T00:00:00Z\n",
+		"negative rate":  "base,quote,rate,effective_at\nEUR,USD,-1.08,2026-10-01T00:00:00Z\n",
+		"invalid code":   "base,quote,rate,effective_at\nEURO,USD,1.08,2026-10-01T00:00:00Z\n",
+		"missing column": "base,quote,rate,effective_at\nEUR,USD,1.08\n",
+	} {
+		_, err := fx.ParseTable(strings.NewReader(table), "")
+		assert.ErrorIs(t, err, fx.ErrInvalidFile, name)
+	}
+}
+
+func expectPaymentInsert(mock sqlmock.Sqlmock, amount float64, currency string) {
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM tenants").
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{}", nil, nil, nil, nil))
+	mock.ExpectQuery("INSERT INTO daily_quotas").
+		WithArgs("acme", amount).
+		WillReturnRows(sqlmock.NewRows([]string{"payments", "amount"}).AddRow(1, amount))
+	mock.ExpectExec("INSERT INTO payments").
+		WithArgs("acme", 1, amount, currency, "created", "", "", "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func TestCreatePaymentConverted(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+	svc.ConfigureFX("EUR", time.Minute)
+	effective := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	// Only the opposite direction is on record, so its rate is inverted.
+	expectPaymentInsert(mock, 100.0, "USD")
+	mock.ExpectQuery("SELECT base, rate, effective_at FROM fx_rates").
+		WithArgs("USD", "EUR", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"base", "rate", "effective_at"}).AddRow("EUR", "1.0850000000", effective))
+	mock.ExpectQuery("INSERT INTO payment_conversions").
+		WithArgs("acme", int64(1), 100.0, "USD", 92.17, "EUR", "0.9216589862", effective, "").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(vaultNow))
+	mock.ExpectCommit()
+
+	res, err := svc.CreatePayment(ctx, &proto.CreatePaymentRequest{Id: 1, Amount: 100, Currency: "USD"})
+	require.NoError(t, err)
+	c := res.GetConversion()
+	require.NotNil(t, c)
+	assert.Equal(t, 92.17, c.GetSettlementAmount())
+	assert.Equal(t, "EUR", c.GetSettlementCurrency())
+	assert.Equal(t, "0.9216589862", c.GetRate())
+	assert.Equal(t, effective, c.GetRateEffectiveAt().AsTime())
+
+	// A payment in the settlement currency is not converted.
+	expectPaymentInsert(mock, 10.0, "EUR")
+	mock.ExpectCommit()
+	res, err = svc.CreatePayment(ctx, &proto.CreatePaymentRequest{Id: 1, Amount: 10, Currency: "EUR"})
+	require.NoError(t, err)
+	assert.Nil(t, res.GetConversion())
+
+	// Without a rate the payment is refused.
+	expectPaymentInsert(mock, 10.0, "KZT")
+	mock.ExpectQuery("SELECT base, rate, effective_at FROM fx_rates").
+		WithArgs("KZT", "GBP", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"base", "rate", "effective_at"}))
+	mock.ExpectRollback()
+	_, err = svc.CreatePayment(ctx, &proto.CreatePaymentRequest{Id: 1, Amount: 10, Currency: "KZT", SettlementCurrency: "GBP"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Payments, fees and the ledger keep hundredths while fx converts in the
+// minor units of each currency; amounts with no more decimals than both
+// allow convert the same either way.
+func TestPaymentAmountsFollowCurrencyExponent(t *testing.T) {
+	for _, tc := range []struct {
+		amount   float64
+		currency string
+		ok       bool
+	}{
+		{1001, "JPY", true},
+		{1000.5, "JPY", false},
+		{1.23, "KWD", true},
+		{1.234, "KWD", false},
+		{10.01, "USD", true},
+		{10.005, "USD", false},
+	} {
+		v := validation.Check(&proto.CreatePaymentRequest{Id: 1, Amount: tc.amount, Currency: tc.currency})
+		assert.Equal(t, tc.ok, len(v) == 0, "%v %s", tc.amount, tc.currency)
+		assert.Equal(t, tc.ok, money.Exact(tc.amount, tc.currency), "%v %s", tc.amount, tc.currency)
+	}
+
+	svc, mock, ctx := newTestBankService(t)
+	svc.ConfigureFees(true)
+	effective := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	plan := models.PricingPlan{ID: "pp_00000000000000000000000a", Name: "yen", Pricing: models.Pricing{Percentage: "2.9"}}
+
+	// 1001 yen are 2.042 dinars, and the 29.029 yen fee is charged and
+	// posted as 29 yen.
+	expectPaymentInsert(mock, 1001.0, "JPY")
+	mock.ExpectQuery("SELECT base, rate, effective_at FROM fx_rates").
+		WithArgs("JPY", "KWD", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"base", "rate", "effective_at"}).AddRow("JPY", "0.0020400000", effective))
+	mock.ExpectQuery("INSERT INTO payment_conversions").
+		WithArgs("acme", int64(1), 1001.0, "JPY", 2.042, "KWD", "0.00204", effective, "").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(vaultNow))
+	expectTenantPlan(t, mock, &plan)
+	mock.ExpectQuery("SELECT COALESCE\\(sum\\(payment_amount\\), 0\\) FROM payment_fees").
+		WithArgs("acme", "JPY", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0.0))
+	mock.ExpectQuery("INSERT INTO payment_fees").
+		WithArgs("acme", int64(1), plan.ID, 29.0, "JPY", 1001.0, fees.MethodCard, "2.9", 0.0, 0.0).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(vaultNow))
+	mock.ExpectExec("INSERT INTO ledger_postings").
+		WithArgs("acme", sqlmock.AnyArg(), "merchant_balance", 29.0, "JPY", sqlmock.AnyArg(), "fee", plan.ID, "fee on payment 1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO ledger_postings").
+		WithArgs("acme", sqlmock.AnyArg(), "fee_revenue", -29.0, "JPY", sqlmock.AnyArg(), "fee", plan.ID, "fee on payment 1").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	res, err := svc.CreatePayment(ctx, &proto.CreatePaymentRequest{Id: 1, Amount: 1001, Currency: "JPY", SettlementCurrency: "KWD"})
+	require.NoError(t, err)
+	assert.Equal(t, 2.042, res.GetConversion().GetSettlementAmount())
+	assert.Equal(t, 29.0, res.GetFee().GetAmount())
+
+	// The store refuses what validation would have.
+	s := &store.PaymentStore{}
+	assert.ErrorIs(t, s.CreatePayment(ctx, models.Payment{ID: 2, Amount: 1000.5, Currency: "JPY"}), store.ErrAmountPrecision)
+	assert.ErrorIs(t, s.UpdatePayment(ctx, 2, models.Payment{ID: 2, Amount: 1.234, Currency: "KWD"}, store.PaymentDetails{}), store.ErrAmountPrecision)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+const testQuoteID = "fxq_00000000000000000000000a"
+
+func TestCreatePaymentWithQuote(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+	effective := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	quoteColumns := []string{"currency", "settlement_currency", "rate", "rate_effective_at", "expires_at"}
+
+	expectPaymentInsert(mock, 25.5, "GBP")
+	mock.ExpectQuery("SELECT currency, settlement_currency, rate, rate_effective_at, expires_at FROM fx_quotes").
+		WithArgs("acme", testQuoteID).
+		WillReturnRows(sqlmock.NewRows(quoteColumns).AddRow("GBP", "EUR", "1.1734000000", effective, time.Now().Add(time.Minute)))
+	mock.ExpectQuery("INSERT INTO payment_conversions").
+		WithArgs("acme", int64(1), 25.5, "GBP", 29.92, "EUR", "1.1734", effective, testQuoteID).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(vaultNow))
+	mock.ExpectCommit()
+
+	res, err := svc.CreatePayment(ctx, &proto.CreatePaymentRequest{Id: 1, Amount: 25.5, Currency: "GBP", FxQuoteId: testQuoteID})
+	require.NoError(t, err)
+	assert.Equal(t, testQuoteID, res.GetConversion().GetQuoteId())
+	assert.Equal(t, 29.92, res.GetConversion().GetSettlementAmount())
+
+	expectPaymentInsert(mock, 25.5, "GBP")
+	mock.ExpectQuery("FROM fx_quotes").WithArgs("acme", testQuoteID).
+		WillReturnRows(sqlmock.NewRows(quoteColumns).AddRow("GBP", "EUR", "1.1734", effective, time.Now().Add(-time.Second)))
+	mock.ExpectRollback()
+	_, err = svc.CreatePayment(ctx, &proto.CreatePaymentRequest{Id: 1, Amount: 25.5, Currency: "GBP", FxQuoteId: testQuoteID})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	expectPaymentInsert(mock, 25.5, "USD")
+	mock.ExpectQuery("FROM fx_quotes").WithArgs("acme", testQuoteID).
+		WillReturnRows(sqlmock.NewRows(quoteColumns).AddRow("GBP", "EUR", "1.1734", effective, time.Now().Add(time.Minute)))
+	mock.ExpectRollback()
+	_, err = svc.CreatePayment(ctx, &proto.CreatePaymentRequest{Id: 1, Amount: 25.5, Currency: "USD", FxQuoteId: testQuoteID})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateFXQuote(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+	svc.ConfigureFX("", 5*time.Minute)
+	effective := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT base, rate, effective_at FROM fx_rates").
+		WithArgs("GBP", "EUR", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"base", "rate", "effective_at"}).AddRow("GBP", "1.1734000000", effective))
+	mock.ExpectQuery("INSERT INTO fx_quotes").
+		WithArgs("acme", sqlmock.AnyArg(), "GBP", "EUR", "1.1734", effective, 25.5, 29.92, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "created_at"}).AddRow("acme", vaultNow))
+	mock.ExpectCommit()
+
+	before := time.Now()
+	quote, err := svc.CreateFXQuote(ctx, &proto.CreateFXQuoteRequest{Currency: "GBP", SettlementCurrency: "EUR", Amount: 25.5})
+	require.NoError(t, err)
+	assert.Regexp(t, "^fxq_[0-9a-f]{24}$", quote.GetId())
+	assert.Equal(t, "1.1734", quote.GetRate())
+	assert.Equal(t, 29.92, quote.GetSettlementAmount())
+	assert.WithinDuration(t, before.Add(5*time.Minute), quote.GetExpiresAt().AsTime(), time.Second)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportFXRatesValidation(t *testing.T) {
+	at := timestamppb.New(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+	assert.Empty(t, validation.Check(&proto.ImportFXRatesRequest{Rates: []*proto.FXRate{
+		{Base: "EUR", Quote: "USD", Rate: "1.085", EffectiveAt: at},
+	}}))
+
+	fields := map[string]bool{}
+	for _, v := range validation.Check(&proto.ImportFXRatesRequest{Rates: []*proto.FXRate{
+		{Base: "EUR", Quote: "EUR", Rate: "1", EffectiveAt: at},
+		{Base: "EUR", Quote: "USD", Rate: "1.12345678901"},
+	}}) {
+		fields[v.GetField()] = true
+	}
+	assert.Equal(t, map[string]bool{"rates[0].quote": true, "rates[1].rate": true, "rates[1].effective_at": true}, fields)
+
+	v := validation.Check(&proto.CreatePaymentRequest{Id: 1, Amount: 1, Currency: "USD", SettlementCurrency: "eur", FxQuoteId: "q1"})
+	require.Len(t, v, 2)
+	assert.Equal(t, "settlement_currency", v[0].GetField())
+	assert.Equal(t, "fx_quote_id", v[1].GetField())
+}
+
+func TestV2FXRates(t *testing.T) {
+	svc, mock, _ := newTestBankService(t)
+	logger, _ := newTestLogger()
+	r := mux.NewRouter()
+	handlers.RegisterRESTService(r, svc, logger)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(tenant.WithScope(r.Context(), tenant.Scope{TenantID: "acme"})))
+		})
+	})
+
+	effective := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO fx_rates").WithArgs("EUR", "USD", "1.085", effective, "api").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO fx_rates").WithArgs("EUR", "KZT", "512.37", effective, "api").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/fx_rates", bytes.NewBufferString(
+		"base,quote,rate,effective_at\nEUR,USD,1.0850,2026-10-01T00:00:00Z\nEUR,KZT,512.37,2026-10-01T00:00:00Z\n"))
+	req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `{"imported":1}`, rec.Body.String())
+
+	mock.ExpectQuery("SELECT base, quote, rate, effective_at, source FROM fx_rates").WithArgs("EUR", "", 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"base", "quote", "rate", "effective_at", "source"}).
+			AddRow("EUR", "USD", "1.0850000000", effective, "api"))
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/fx_rates?base=EUR", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var envelope handlers.FXRateListEnvelope
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &envelope))
+	require.Len(t, envelope.Data, 1)
+	assert.Equal(t, "1.085", envelope.Data[0].Rate)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/fx_rates", bytes.NewBufferString(`{"rates":[{"base":"EUR","quote":"USD","rate":"-1"}]}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM payment_conversions").WithArgs("acme", int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"payment_id", "amount", "currency", "settlement_amount", "settlement_currency", "rate",
+			"rate_effective_at", "quote_id", "created_at"}))
+	mock.ExpectRollback()
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/payments/7/conversion", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,204 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	"go-lang-final/internal/handlers"
+	"go-lang-final/internal/router"
+	"go-lang-final/internal/store"
+	"go-lang-final/internal/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newGatewayRouter(t *testing.T) (*mux.Router, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	logger, _ := newTestLogger()
+	r := router.NewRouter(&store.PaymentStore{DB: db}, logger)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := tenant.WithScope(r.Context(), tenant.Scope{TenantID: "acme"})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	return r, mock
+}
+
+func expectGet(mock sqlmock.Sqlmock, rows *sqlmock.Rows) {
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT tenant_id, id, amount, currency, status, refunded_amount, provider, provider_ref, provider_status, payment_method, metadata, description, statement_descriptor FROM payments WHERE tenant_id = \\$1 AND id = \\$2").
+		WithArgs("acme", 7).
+		WillReturnRows(rows)
+}
+
+func TestGatewayGetPayment(t *testing.T) {
+	r, mock := newGatewayRouter(t)
+	expectGet(mock, sqlmock.NewRows([]string{"tenant_id", "id", "amount", "currency", "status", "refunded_amount", "provider", "provider_ref", "provider_status", "payment_method", "metadata", "description", "statement_descriptor"}).AddRow("acme", 7, 12.5, "EUR", "created", 0.0, "", "", "", "", []byte("{}"), "", ""))
+	mock.ExpectCommit()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/payments/7", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+	assert.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", rec.Header().Get("Sunset"))
+	assert.Equal(t, `</v2/payments/{id}>; rel="successor-version"`, rec.Header().Get("Link"))
+	assert.JSONEq(t, `{"id":"7","amount":12.5,"currency":"EUR","tenant_id":"acme","status":"created","refunded_amount":0,"provider":"","provider_ref":"","provider_status":"","payment_method":"","metadata":{},"description":"","statement_descriptor":""}`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGatewayLegacyAliasIsDeprecated(t *testing.T) {
+	r, mock := newGatewayRouter(t)
+	expectGet(mock, sqlmock.NewRows([]string{"tenant_id", "id", "amount", "currency", "status", "refunded_amount", "provider", "provider_ref", "provider_status", "payment_method", "metadata", "description", "statement_descriptor"}).AddRow("acme", 7, 12.5, "EUR", "created", 0.0, "", "", "", "", []byte("{}"), "", ""))
+	mock.ExpectCommit()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/get?id=7", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+	assert.Equal(t, `</v2/payments/{id}>; rel="successor-version"`, rec.Header().Get("Link"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGatewayMapsNotFound(t *testing.T) {
+	r, mock := newGatewayRouter(t)
+	expectGet(mock, sqlmock.NewRows([]string{"tenant_id", "id", "amount", "currency", "status", "refunded_amount", "provider", "provider_ref", "provider_status", "payment_method", "metadata", "description", "statement_descriptor"}))
+	mock.ExpectRollback()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/payments/7", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	var body struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, 5, body.Code)
+	assert.Contains(t, body.Message, "payment not found")
+}
+
+func TestGatewayCreatePayment(t *testing.T) {
+	r, mock := newGatewayRouter(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM tenants").
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{}", nil, nil, nil, nil))
+	mock.ExpectQuery("INSERT INTO daily_quotas").
+		WithArgs("acme", 100.0).
+		WillReturnRows(sqlmock.NewRows([]string{"payments", "amount"}).AddRow(1, 100.0))
+	mock.ExpectExec("INSERT INTO payments").
+		WithArgs("acme", 1, 100.0, "USD", "created", "", "", "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	body := bytes.NewBufferString(`{"id":1,"amount":100,"currency":"USD","tenant_id":"ignored"}`)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/payments", body))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, `{"success":true,"status":"created","action_url":"","conversion":null,"fee":null}`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// REST calls the service in process, so it skipped the validation the gRPC
+// interceptors apply and stored whatever it was sent.
+func TestRESTValidatesRequests(t *testing.T) {
+	r, mock := newGatewayRouter(t)
+	for _, target := range []string{"/v1/payments", "/v2/payments"} {
+		body := bytes.NewBufferString(`{"id":42,"amount":-5,"currency":"usd"}`)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, body))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+		assert.Contains(t, rec.Body.String(), "amount", target)
+		assert.Contains(t, rec.Body.String(), "currency", target)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// REST used to reject list requests without amount, page and pageSize while
+// gRPC accepted them; both now share the gRPC defaults.
+func TestGatewayListWithoutFilters(t *testing.T) {
+	r, mock := newGatewayRouter(t)
+	for i := 0; i < 2; i++ {
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT tenant_id, id, amount, currency, status, refunded_amount, provider, provider_ref, provider_status, payment_method, metadata, description, statement_descriptor FROM payments").
+			WithArgs("acme", "", "", []byte("{}"), "", 20, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "id", "amount", "currency", "status", "refunded_amount", "provider", "provider_ref", "provider_status", "payment_method", "metadata", "description", "statement_descriptor"}).AddRow("acme", 1, 10.0, "USD", "created", 0.0, "", "", "", "", []byte("{}"), "", ""))
+		mock.ExpectCommit()
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/payments", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"payments":[{"id":"1","amount":10,"currency":"USD","tenant_id":"acme","status":"created","refunded_amount":0,"provider":"","provider_ref":"","provider_status":"","payment_method":"","customer":"","metadata":{},"description":"","statement_descriptor":""}]}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/list", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"id":"1","amount":10,"currency":"USD","tenant_id":"acme","status":"created","refunded_amount":0,"provider":"","provider_ref":"","provider_status":"","payment_method":"","customer":"","metadata":{},"description":"","statement_descriptor":""}]`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGatewayListAmountFilter(t *testing.T) {
+	r, mock := newGatewayRouter(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT tenant_id, id, amount, currency, status, refunded_amount, provider, provider_ref, provider_status, payment_method, metadata, description, statement_descriptor FROM payments").
+		WithArgs("acme", "USD", "0.00", []byte("{}"), "", 5, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "id", "amount", "currency", "status", "refunded_amount", "provider", "provider_ref", "provider_status", "payment_method", "metadata", "description", "statement_descriptor"}))
+	mock.ExpectCommit()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/payments?currency=USD&amount=0&page=2&pageSize=5", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// The lifecycle RPCs are only served by /v2, so the frozen v1 bindings
+// cover the original five and every RPC has a named REST route.
+func TestGatewayBindingsCoverEveryRPC(t *testing.T) {
+	primary := map[string]bool{}
+	for _, b := range handlers.Bindings() {
+		assert.Contains(t, handlers.MethodPermissions, b.FullMethod)
+		assert.Contains(t, handlers.RoutePermissions, b.RPC)
+		if !b.Deprecated {
+			primary[b.RPC] = true
+		}
+	}
+	assert.Len(t, primary, 5)
+	for method := range handlers.MethodPermissions {
+		assert.Contains(t, handlers.RoutePermissions, path.Base(method))
+	}
+}
+
+func TestGatewayQuotaSetsRetryAfter(t *testing.T) {
+	r, mock := newGatewayRouter(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM tenants").
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{}", nil, nil, 1, nil))
+	mock.ExpectQuery("INSERT INTO daily_quotas").
+		WithArgs("acme", 1.0).
+		WillReturnRows(sqlmock.NewRows([]string{"payments", "amount"}).AddRow(2, 2.0))
+	mock.ExpectRollback()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/create", bytes.NewBufferString(`{"id":1,"amount":1,"currency":"USD"}`)))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
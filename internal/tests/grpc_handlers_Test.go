@@ -106,7 +106,8 @@ func TestGRPCListPayments(t *testing.T) {
 
 	s := &store.PaymentStore{DB: db}
 	h := &PaymentService{store: s}
-	req := &proto.ListPaymentsRequest{Currency: "USD", Amount: 100.0, Page: 1, PageSize: 10}
+	amount := 100.0
+	req := &proto.ListPaymentsRequest{Currency: "USD", Amount: &amount, Page: 1, PageSize: 10}
 
 	res, err := h.ListPayments(context.Background(), req)
 	assert.NoError(t, err)
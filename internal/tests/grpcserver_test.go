@@ -0,0 +1,162 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"go-lang-final/internal/config"
+	"go-lang-final/internal/grpcserver"
+	"go-lang-final/internal/metrics"
+	"go-lang-final/proto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// panickyPayments panics in GetPayment and WatchPayments.
+type panickyPayments struct {
+	*fakePayments
+}
+
+func (p *panickyPayments) GetPayment(context.Context, *proto.GetPaymentRequest) (*proto.GetPaymentResponse, error) {
+	panic("boom")
+}
+
+func (p *panickyPayments) WatchPayments(*proto.WatchPaymentsRequest, proto.PaymentService_WatchPaymentsServer) error {
+	panic("boom")
+}
+
+// serveBuilt builds a server with b, registers svc and returns a client
+// connected to it.
+func serveBuilt(t *testing.T, b *grpcserver.Builder, svc proto.PaymentServiceServer) proto.PaymentServiceClient {
+	srv, err := b.Build()
+	require.NoError(t, err)
+	proto.RegisterPaymentServiceServer(srv, svc)
+
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return proto.NewPaymentServiceClient(conn)
+}
+
+func TestGRPCServerRecoversFromPanics(t *testing.T) {
+	logger, hook := newTestLogger()
+	registry := metrics.NewRegistry()
+	client := serveBuilt(t, &grpcserver.Builder{Logger: logger, Metrics: registry}, &panickyPayments{newFakePayments()})
+	ctx := context.Background()
+
+	_, err := client.GetPayment(ctx, &proto.GetPaymentRequest{Id: 1})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, "internal error", status.Convert(err).Message())
+
+	stream, err := client.WatchPayments(ctx, &proto.WatchPaymentsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	// The server is still up after both panics.
+	_, err = client.GetPayment(ctx, &proto.GetPaymentRequest{Id: 1})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	var logged int
+	for _, e := range hook.AllEntries() {
+		if e.Message == "recovered from panic in gRPC handler" {
+			logged++
+			assert.Equal(t, "boom", e.Data["panic"])
+			assert.Contains(t, e.Data["stack"], "panickyPayments")
+		}
+	}
+	assert.Equal(t, 3, logged)
+	assert.Equal(t, uint64(2), registry.Handled(proto.PaymentService_GetPayment_FullMethodName, "Internal"))
+	assert.Equal(t, uint64(1), registry.Handled(proto.PaymentService_WatchPayments_FullMethodName, "Internal"))
+}
+
+func TestGRPCServerMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	fake := newFakePayments()
+	fake.payments[1] = &proto.Payment{Id: 1, Amount: 5, Currency: "USD"}
+	client := serveBuilt(t, &grpcserver.Builder{Metrics: registry}, fake)
+	ctx := context.Background()
+
+	_, err := client.GetPayment(ctx, &proto.GetPaymentRequest{Id: 1})
+	require.NoError(t, err)
+	_, err = client.GetPayment(ctx, &proto.GetPaymentRequest{Id: 2})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	assert.Equal(t, uint64(1), registry.Handled(proto.PaymentService_GetPayment_FullMethodName, "OK"))
+	assert.Equal(t, uint64(1), registry.Handled(proto.PaymentService_GetPayment_FullMethodName, "NotFound"))
+
+	rec := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	assert.Contains(t, body, `grpc_server_handled_total{grpc_method="/proto.PaymentService/GetPayment",grpc_code="NotFound"} 1`)
+	assert.Contains(t, body, `grpc_server_handling_seconds_count{grpc_method="/proto.PaymentService/GetPayment"} 2`)
+	assert.Contains(t, body, `grpc_server_in_flight{grpc_method="/proto.PaymentService/GetPayment"} 0`)
+	assert.Contains(t, body, "grpc_server_panics_total 0")
+}
+
+func TestMetricsListenOnLoopbackByDefault(t *testing.T) {
+	host, _, err := net.SplitHostPort(config.Default().MetricsAddr)
+	require.NoError(t, err)
+	assert.True(t, net.ParseIP(host).IsLoopback(), "metrics must not be exposed on every interface by default")
+}
+
+func TestGRPCServerValidatesRequests(t *testing.T) {
+	fake := newFakePayments()
+	client := serveBuilt(t, &grpcserver.Builder{}, fake)
+
+	_, err := client.CreatePayment(context.Background(), &proto.CreatePaymentRequest{Id: 0, Amount: -1, Currency: "usd"})
+	st := status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 1)
+	br, ok := st.Details()[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+	var fields []string
+	for _, v := range br.GetFieldViolations() {
+		fields = append(fields, v.GetField())
+	}
+	assert.Equal(t, []string{"id", "amount", "currency"}, fields)
+	assert.Zero(t, fake.calls["create"], "handler must not run for invalid requests")
+
+	_, err = client.ListPayments(context.Background(), &proto.ListPaymentsRequest{Page: 1, PageSize: 5000})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.CreatePayment(context.Background(), &proto.CreatePaymentRequest{Id: 1, Amount: 5, Currency: "USD"})
+	assert.NoError(t, err)
+}
+
+func TestGRPCServerReflection(t *testing.T) {
+	const reflectionService = "grpc.reflection.v1.ServerReflection"
+
+	srv, err := (&grpcserver.Builder{}).Build()
+	require.NoError(t, err)
+	assert.NotContains(t, srv.GetServiceInfo(), reflectionService)
+
+	srv, err = (&grpcserver.Builder{Config: config.GRPCConfig{Reflection: true}}).Build()
+	require.NoError(t, err)
+	assert.Contains(t, srv.GetServiceInfo(), reflectionService)
+	assert.Contains(t, grpcserver.ReflectionPermissions, "/"+reflectionService+"/ServerReflectionInfo")
+}
+
+func TestGRPCServerKeepaliveConfig(t *testing.T) {
+	cfg := config.GRPCConfig{Keepalive: config.KeepaliveConfig{Time: "2h", Timeout: "20s", MinTime: "1m"}}
+	_, err := (&grpcserver.Builder{Config: cfg}).Build()
+	assert.NoError(t, err)
+
+	cfg.Keepalive.MaxConnectionAge = "forever"
+	_, err = (&grpcserver.Builder{Config: cfg}).Build()
+	assert.ErrorContains(t, err, "invalid grpc keepalive max_connection_age")
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"go-lang-final/internal/idempotency"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/store"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func expectKeyLookup(mock sqlmock.Sqlmock, rows *sqlmock.Rows) {
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT request_hash FROM idempotency_keys WHERE tenant_id = \\$1 AND key = \\$2").
+		WithArgs("acme", "key-1").
+		WillReturnRows(rows)
+}
+
+func TestCreatePaymentRecordsIdempotencyKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	expectKeyLookup(mock, sqlmock.NewRows([]string{"request_hash"}))
+	mock.ExpectQuery("FROM tenants").
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{}", nil, nil, nil, nil))
+	mock.ExpectQuery("INSERT INTO daily_quotas").
+		WithArgs("acme", 100.0).
+		WillReturnRows(sqlmock.NewRows([]string{"payments", "amount"}).AddRow(1, 100.0))
+	mock.ExpectExec("INSERT INTO payments").
+		WithArgs("acme", 1, 100.0, "USD", "created", "", "", "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO idempotency_keys").
+		WithArgs("acme", "key-1", sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	s := &store.PaymentStore{DB: db}
+	ctx := idempotency.WithKey(tenantContext(), "key-1")
+	err = s.CreatePayment(ctx, models.Payment{ID: 1, Amount: 100.0, Currency: "USD"})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreatePaymentReplaysIdempotencyKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	s := &store.PaymentStore{DB: db}
+	ctx := idempotency.WithKey(tenantContext(), "key-1")
+	payment := models.Payment{ID: 1, Amount: 100.0, Currency: "USD",
+		Metadata: map[string]string{"order": "42", "sku": "A1"}, Description: "Coffee", StatementDescriptor: "ACME COFFEE"}
+
+	// Capture the hash the store records for the payment.
+	var hash string
+	expectKeyLookup(mock, sqlmock.NewRows([]string{"request_hash"}))
+	mock.ExpectQuery("FROM tenants").
+		WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{}", nil, nil, nil, nil))
+	mock.ExpectQuery("INSERT INTO daily_quotas").
+		WillReturnRows(sqlmock.NewRows([]string{"payments", "amount"}).AddRow(1, 100.0))
+	mock.ExpectExec("INSERT INTO payments").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE payments SET customer_id").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO idempotency_keys").
+		WithArgs("acme", "key-1", hashCapture{&hash}, 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	assert.NoError(t, s.CreatePayment(ctx, payment))
+
+	// The same request is a no-op that neither inserts nor charges quota.
+	expectKeyLookup(mock, sqlmock.NewRows([]string{"request_hash"}).AddRow(hash))
+	mock.ExpectCommit()
+	assert.NoError(t, s.CreatePayment(ctx, payment))
+
+	// A request that differs in any stored field is rejected.
+	for name, change := range map[string]func(p *models.Payment){
+		"amount":               func(p *models.Payment) { p.Amount = 200 },
+		"payment_method":       func(p *models.Payment) { p.PaymentMethod = "pm_00000000000000000000000a" },
+		"customer":             func(p *models.Payment) { p.Customer = "cus_00000000000000000000000a" },
+		"metadata":             func(p *models.Payment) { p.Metadata = map[string]string{"order": "43", "sku": "A1"} },
+		"description":          func(p *models.Payment) { p.Description = "Tea" },
+		"statement_descriptor": func(p *models.Payment) { p.StatementDescriptor = "ACME TEA" },
+	} {
+		other := payment
+		other.Metadata = map[string]string{"sku": "A1", "order": "42"}
+		change(&other)
+		expectKeyLookup(mock, sqlmock.NewRows([]string{"request_hash"}).AddRow(hash))
+		mock.ExpectRollback()
+		assert.ErrorIs(t, s.CreatePayment(ctx, other), idempotency.ErrKeyReused, name)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// hashCapture matches any string argument and records it.
+type hashCapture struct{ dst *string }
+
+func (c hashCapture) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	*c.dst = s
+	return ok && s != ""
+}
+
+func TestIdempotencyKeyFromMetadata(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(idempotency.Metadata, "abc"))
+	ctx, err := idempotency.FromIncoming(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", idempotency.FromContext(ctx))
+
+	ctx, err = idempotency.FromIncoming(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "", idempotency.FromContext(ctx))
+
+	long := make([]byte, idempotency.MaxKeyLength+1)
+	for i := range long {
+		long[i] = 'a'
+	}
+	_, err = idempotency.FromIncoming(metadata.NewIncomingContext(context.Background(), metadata.Pairs(idempotency.Metadata, string(long))))
+	assert.Error(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"go-lang-final/internal/models"
 	"go-lang-final/internal/router"
 	"go-lang-final/internal/store"
+	"go-lang-final/internal/tenant"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -23,14 +24,35 @@ func setupTestServer() *httptest.Server {
 	}
 
 	r := router.NewRouter(paymentStore, logger)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := tenant.WithScope(r.Context(), tenant.Scope{TenantID: testTenant})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
 	return httptest.NewServer(r)
 }
 
+const testTenant = "default"
+
+// gatewayPayment decodes a payment as rendered by the REST gateway, which
+// follows the proto JSON mapping and quotes 64-bit ids.
+type gatewayPayment struct {
+	ID       int64   `json:"id,string"`
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+	TenantID string  `json:"tenant_id"`
+}
+
+func (p gatewayPayment) model() models.Payment {
+	return models.Payment{ID: p.ID, Amount: p.Amount, Currency: p.Currency, TenantID: p.TenantID}
+}
+
 func TestCreateAndGetPayment(t *testing.T) {
 	server := setupTestServer()
 	defer server.Close()
 
-	payment := models.Payment{ID: 1, Amount: 100.0, Currency: "USD"}
+	payment := models.Payment{ID: 1, Amount: 100.0, Currency: "USD", TenantID: testTenant}
 	body, _ := json.Marshal(payment)
 	resp, err := http.Post(server.URL+"/create", "application/json", bytes.NewBuffer(body))
 	assert.NoError(t, err)
@@ -40,9 +62,9 @@ func TestCreateAndGetPayment(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 
-	var gotPayment models.Payment
+	var gotPayment gatewayPayment
 	json.NewDecoder(resp.Body).Decode(&gotPayment)
-	assert.Equal(t, payment, gotPayment)
+	assert.Equal(t, payment, gotPayment.model())
 }
 
 func TestCreateAndUpdatePayment(t *testing.T) {
@@ -55,7 +77,7 @@ func TestCreateAndUpdatePayment(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusCreated, resp.StatusCode)
 
-	updatedPayment := models.Payment{ID: 2, Amount: 200.0, Currency: "EUR"}
+	updatedPayment := models.Payment{ID: 2, Amount: 200.0, Currency: "EUR", TenantID: testTenant}
 	body, _ = json.Marshal(updatedPayment)
 	req, _ := http.NewRequest(http.MethodPut, server.URL+"/update?id=2", bytes.NewBuffer(body))
 	client := &http.Client{}
@@ -67,9 +89,9 @@ func TestCreateAndUpdatePayment(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 
-	var gotPayment models.Payment
+	var gotPayment gatewayPayment
 	json.NewDecoder(resp.Body).Decode(&gotPayment)
-	assert.Equal(t, updatedPayment, gotPayment)
+	assert.Equal(t, updatedPayment, gotPayment.model())
 }
 
 func TestCreateAndDeletePayment(t *testing.T) {
@@ -97,13 +119,13 @@ func TestListPayments(t *testing.T) {
 	server := setupTestServer()
 	defer server.Close()
 
-	payment1 := models.Payment{ID: 4, Amount: 400.0, Currency: "USD"}
+	payment1 := models.Payment{ID: 4, Amount: 400.0, Currency: "USD", TenantID: testTenant}
 	body, _ := json.Marshal(payment1)
 	resp, err := http.Post(server.URL+"/create", "application/json", bytes.NewBuffer(body))
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusCreated, resp.StatusCode)
 
-	payment2 := models.Payment{ID: 5, Amount: 500.0, Currency: "USD"}
+	payment2 := models.Payment{ID: 5, Amount: 500.0, Currency: "USD", TenantID: testTenant}
 	body, _ = json.Marshal(payment2)
 	resp, err = http.Post(server.URL+"/create", "application/json", bytes.NewBuffer(body))
 	assert.NoError(t, err)
@@ -113,17 +135,17 @@ func TestListPayments(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 
-	var payments []models.Payment
+	var payments []gatewayPayment
 	json.NewDecoder(resp.Body).Decode(&payments)
 	assert.Len(t, payments, 2)
-	assert.Equal(t, []models.Payment{payment1, payment2}, payments)
+	assert.Equal(t, []models.Payment{payment1, payment2}, []models.Payment{payments[0].model(), payments[1].model()})
 }
 
 func TestCreateAndListPayments(t *testing.T) {
 	server := setupTestServer()
 	defer server.Close()
 
-	payment := models.Payment{ID: 6, Amount: 600.0, Currency: "JPY"}
+	payment := models.Payment{ID: 6, Amount: 600.0, Currency: "JPY", TenantID: testTenant}
 	body, _ := json.Marshal(payment)
 	resp, err := http.Post(server.URL+"/create", "application/json", bytes.NewBuffer(body))
 	assert.NoError(t, err)
@@ -133,8 +155,8 @@ func TestCreateAndListPayments(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 
-	var payments []models.Payment
+	var payments []gatewayPayment
 	json.NewDecoder(resp.Body).Decode(&payments)
 	assert.Len(t, payments, 1)
-	assert.Equal(t, payment, payments[0])
+	assert.Equal(t, payment, payments[0].model())
 }
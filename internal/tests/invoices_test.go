@@ -0,0 +1,304 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-lang-final/internal/handlers"
+	"go-lang-final/internal/invoices"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/tenant"
+	"go-lang-final/proto"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testInvoiceID = "in_00000000000000000000000a"
+
+var testIssuer = invoices.Issuer{Name: "Acme GmbH", Address: "Hauptstraße 1\n10115 Berlin", TaxID: "DE123456789"}
+
+func testInvoice() models.Invoice {
+	return models.Invoice{
+		Currency:     "EUR",
+		CustomerName: "Zoë Dupont",
+		Lines: []models.InvoiceLine{
+			{Description: " Consulting ", Quantity: 3, UnitAmount: 33.33, DiscountRate: "10.0", TaxRate: "19"},
+			{Description: "Hosting", Quantity: 1, UnitAmount: 0.05, TaxRate: "7"},
+		},
+	}
+}
+
+func TestCalculateInvoice(t *testing.T) {
+	inv := testInvoice()
+	inv.AmountPaid = 50
+	require.NoError(t, invoices.Calculate(&inv))
+
+	// 99.99 less 10% is 89.99 after rounding 9.999 to 10.00; 19% of it
+	// rounds 17.0981 to 17.10.
+	assert.Equal(t, models.InvoiceLine{Description: "Consulting", Quantity: 3, UnitAmount: 33.33, DiscountRate: "10", TaxRate: "19", Amount: 99.99, Discount: 10, Tax: 17.1}, inv.Lines[0])
+	// 7% of five cents is 0.35 cents, rounded to nothing.
+	assert.Equal(t, 0.0, inv.Lines[1].Tax)
+	assert.Equal(t, 100.04, inv.Subtotal)
+	assert.Equal(t, 10.0, inv.Discount)
+	assert.Equal(t, 17.1, inv.Tax)
+	assert.Equal(t, 107.14, inv.Total)
+	assert.Equal(t, 57.14, inv.AmountDue)
+
+	for name, change := range map[string]func(*models.Invoice){
+		"currency":    func(inv *models.Invoice) { inv.Currency = "euro" },
+		"due date":    func(inv *models.Invoice) { inv.DueDate = "31/12/2026" },
+		"no lines":    func(inv *models.Invoice) { inv.Lines = nil },
+		"description": func(inv *models.Invoice) { inv.Lines[0].Description = " " },
+		"quantity":    func(inv *models.Invoice) { inv.Lines[0].Quantity = 0 },
+		"unit amount": func(inv *models.Invoice) { inv.Lines[1].UnitAmount = -1 },
+		"tax rate":    func(inv *models.Invoice) { inv.Lines[1].TaxRate = "101" },
+	} {
+		inv := testInvoice()
+		change(&inv)
+		assert.True(t, errors.Is(invoices.Calculate(&inv), invoices.ErrInvalidInvoice), name)
+	}
+}
+
+func TestRenderInvoice(t *testing.T) {
+	issued := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	inv := testInvoice()
+	require.NoError(t, invoices.Calculate(&inv))
+	inv.Number, inv.Status, inv.IssuedAt, inv.DueDate = invoices.Number("INV-", 42), models.InvoiceOpen, &issued, "2026-10-31"
+	inv.Memo = "<script>alert(1)</script>"
+	assert.Equal(t, "INV-000042", inv.Number)
+
+	doc, err := invoices.RenderHTML(inv, testIssuer)
+	require.NoError(t, err)
+	html := string(doc)
+	assert.Contains(t, html, "Invoice INV-000042")
+	assert.Contains(t, html, "Zoë Dupont")
+	assert.Contains(t, html, "107.14")
+	assert.NotContains(t, html, "<script>")
+
+	pdf := invoices.RenderPDF(inv, testIssuer)
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+	assert.Contains(t, string(pdf), "(Invoice INV-000042) Tj")
+	// Text is encoded in Windows-1252.
+	assert.Contains(t, string(pdf), "(Zo\xeb Dupont) Tj")
+
+	// Lines that do not fit on a page continue on the next.
+	inv.Lines = nil
+	for i := 0; i < invoices.MaxLines; i++ {
+		inv.Lines = append(inv.Lines, models.InvoiceLine{Description: "Item", Quantity: 1, UnitAmount: 1})
+	}
+	require.NoError(t, invoices.Calculate(&inv))
+	assert.Contains(t, string(invoices.RenderPDF(inv, testIssuer)), "/Count 3 >>")
+}
+
+func invoiceRows(inv models.Invoice) *sqlmock.Rows {
+	lines, _ := json.Marshal(inv.Lines)
+	var number, dueDate, issuedAt, paidAt interface{}
+	if inv.Number != "" {
+		number = inv.Number
+	}
+	if inv.DueDate != "" {
+		dueDate, _ = time.Parse(invoices.DateLayout, inv.DueDate)
+	}
+	if inv.IssuedAt != nil {
+		issuedAt = *inv.IssuedAt
+	}
+	if inv.PaidAt != nil {
+		paidAt = *inv.PaidAt
+	}
+	return sqlmock.NewRows([]string{"id", "tenant_id", "number", "status", "currency", "customer_name", "customer_email", "customer_address", "memo", "lines",
+		"subtotal", "discount", "tax", "total", "amount_paid", "due_date", "issued_at", "paid_at", "voided_at", "created_at", "updated_at"}).
+		AddRow(testInvoiceID, "acme", number, inv.Status, inv.Currency, inv.CustomerName, "", "", "", lines,
+			inv.Subtotal, inv.Discount, inv.Tax, inv.Total, inv.AmountPaid, dueDate, issuedAt, paidAt, nil, vaultNow, vaultNow)
+}
+
+func calculatedInvoice(t *testing.T, status string) models.Invoice {
+	inv := testInvoice()
+	require.NoError(t, invoices.Calculate(&inv))
+	inv.Status = status
+	return inv
+}
+
+func TestFinalizeInvoice(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+	svc.ConfigureInvoices("ACME-", testIssuer)
+	draft := calculatedInvoice(t, models.InvoiceDraft)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM invoices WHERE tenant_id = \\$1 AND id = \\$2 FOR UPDATE").
+		WithArgs("acme", testInvoiceID).
+		WillReturnRows(invoiceRows(draft))
+	mock.ExpectQuery("INSERT INTO invoice_sequences").
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"last_number"}).AddRow(7))
+	open := draft
+	issued := time.Now().UTC()
+	open.Number, open.Status, open.IssuedAt, open.DueDate = "ACME-000007", models.InvoiceOpen, &issued, issued.AddDate(0, 0, 30).Format(invoices.DateLayout)
+	mock.ExpectQuery("UPDATE invoices SET number").
+		WithArgs("acme", testInvoiceID, "ACME-000007", models.InvoiceOpen, open.DueDate, sqlmock.AnyArg(), nil).
+		WillReturnRows(invoiceRows(open))
+	mock.ExpectCommit()
+
+	res, err := svc.FinalizeInvoice(ctx, &proto.FinalizeInvoiceRequest{Id: testInvoiceID})
+	require.NoError(t, err)
+	assert.Equal(t, "ACME-000007", res.GetNumber())
+	assert.Equal(t, models.InvoiceOpen, res.GetStatus())
+	assert.Equal(t, 107.14, res.GetAmountDue())
+
+	// A finalized invoice cannot be finalized again.
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM invoices WHERE tenant_id = \\$1 AND id = \\$2 FOR UPDATE").
+		WithArgs("acme", testInvoiceID).
+		WillReturnRows(invoiceRows(open))
+	mock.ExpectRollback()
+	_, err = svc.FinalizeInvoice(ctx, &proto.FinalizeInvoiceRequest{Id: testInvoiceID})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func expectInvoicePayment(mock sqlmock.Sqlmock, inv models.Invoice, paymentAmount float64, currency string, used float64) {
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM invoices WHERE tenant_id = \\$1 AND id = \\$2 FOR UPDATE").
+		WithArgs("acme", testInvoiceID).
+		WillReturnRows(invoiceRows(inv))
+	mock.ExpectQuery("SELECT amount, currency, status, refunded_amount FROM payments").
+		WithArgs("acme", int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"amount", "currency", "status", "refunded_amount"}).AddRow(paymentAmount, currency, models.StatusCreated, 0.0))
+	if currency != inv.Currency {
+		mock.ExpectRollback()
+		return
+	}
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(amount\\), 0\\) FROM invoice_payments").
+		WithArgs("acme", int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(used))
+}
+
+func TestPayInvoice(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+	open := calculatedInvoice(t, models.InvoiceOpen)
+	open.Number = "INV-000001"
+
+	// Part of the invoice is paid.
+	expectInvoicePayment(mock, open, 100, "EUR", 0)
+	mock.ExpectExec("INSERT INTO invoice_payments").
+		WithArgs("acme", testInvoiceID, int64(1), 40.0).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	partial := open
+	partial.AmountPaid = 40
+	mock.ExpectQuery("UPDATE invoices SET amount_paid").
+		WithArgs("acme", testInvoiceID, 40.0, models.InvoiceOpen, nil).
+		WillReturnRows(invoiceRows(partial))
+	mock.ExpectQuery("SELECT payment_id, amount, created_at FROM invoice_payments").
+		WithArgs("acme", testInvoiceID).
+		WillReturnRows(sqlmock.NewRows([]string{"payment_id", "amount", "created_at"}).AddRow(1, 40.0, vaultNow))
+	mock.ExpectCommit()
+	res, err := svc.PayInvoice(ctx, &proto.PayInvoiceRequest{Id: testInvoiceID, PaymentId: 1, Amount: 40})
+	require.NoError(t, err)
+	assert.Equal(t, models.InvoiceOpen, res.GetStatus())
+	assert.Equal(t, 67.14, res.GetAmountDue())
+	require.Len(t, res.GetPayments(), 1)
+
+	// The rest of the payment is more than the invoice still needs.
+	expectInvoicePayment(mock, partial, 100, "EUR", 40)
+	mock.ExpectRollback()
+	_, err = svc.PayInvoice(ctx, &proto.PayInvoiceRequest{Id: testInvoiceID, PaymentId: 1, Amount: 70})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Without an amount the payment settles what is due.
+	expectInvoicePayment(mock, partial, 100, "EUR", 40)
+	mock.ExpectExec("INSERT INTO invoice_payments").
+		WithArgs("acme", testInvoiceID, int64(1), 60.0).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("UPDATE invoices SET amount_paid").
+		WithArgs("acme", testInvoiceID, 60.0, models.InvoiceOpen, nil).
+		WillReturnRows(invoiceRows(partial))
+	mock.ExpectQuery("SELECT payment_id, amount, created_at FROM invoice_payments").
+		WithArgs("acme", testInvoiceID).
+		WillReturnRows(sqlmock.NewRows([]string{"payment_id", "amount", "created_at"}))
+	mock.ExpectCommit()
+	_, err = svc.PayInvoice(ctx, &proto.PayInvoiceRequest{Id: testInvoiceID, PaymentId: 1})
+	require.NoError(t, err)
+
+	expectInvoicePayment(mock, open, 100, "USD", 0)
+	_, err = svc.PayInvoice(ctx, &proto.PayInvoiceRequest{Id: testInvoiceID, PaymentId: 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestV2Invoices(t *testing.T) {
+	svc, mock, _ := newTestBankService(t)
+	svc.ConfigureInvoices("", testIssuer)
+	logger, _ := newTestLogger()
+	r := mux.NewRouter()
+	handlers.RegisterRESTService(r, svc, logger)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(tenant.WithScope(r.Context(), tenant.Scope{TenantID: "acme"})))
+		})
+	})
+	draft := calculatedInvoice(t, models.InvoiceDraft)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("INSERT INTO invoices").
+		WithArgs("acme", sqlmock.AnyArg(), "EUR", "Zoë Dupont", "", "", "", sqlmock.AnyArg(), 100.04, 10.0, 17.1, 107.14, "").
+		WillReturnRows(invoiceRows(draft))
+	mock.ExpectCommit()
+	body, err := json.Marshal(testInvoice())
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/invoices", bytes.NewReader(body)))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var inv models.Invoice
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &inv))
+	assert.Equal(t, "/v2/invoices/"+testInvoiceID, rec.Header().Get("Location"))
+	assert.Equal(t, models.InvoiceDraft, inv.Status)
+	assert.Empty(t, inv.Number)
+	assert.Equal(t, 107.14, inv.Total)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/invoices",
+		bytes.NewBufferString(`{"currency":"EUR","customer_name":"Zoë","lines":[{"description":"x","quantity":0,"unit_amount":1}]}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+
+	for _, format := range []string{"pdf", "html"} {
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("FROM invoices WHERE tenant_id = \\$1 AND id = \\$2").
+			WithArgs("acme", testInvoiceID).
+			WillReturnRows(invoiceRows(draft))
+		mock.ExpectQuery("SELECT payment_id, amount, created_at FROM invoice_payments").
+			WithArgs("acme", testInvoiceID).
+			WillReturnRows(sqlmock.NewRows([]string{"payment_id", "amount", "created_at"}))
+		mock.ExpectCommit()
+		rec = httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/invoices/"+testInvoiceID+"/document?format="+format, nil))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		if format == "pdf" {
+			assert.Equal(t, "application/pdf", rec.Header().Get("Content-Type"))
+			assert.True(t, strings.HasPrefix(rec.Body.String(), "%PDF-"))
+		} else {
+			assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+			assert.Contains(t, rec.Body.String(), "Draft invoice")
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/invoices/"+testInvoiceID+"/document?format=docx", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
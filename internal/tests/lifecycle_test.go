@@ -0,0 +1,421 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/store"
+	"go-lang-final/pkg/paymentsclient"
+	"go-lang-final/proto"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func expectLock(mock sqlmock.Sqlmock, id int64, amount float64, state string, refunded float64) {
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT amount, status, refunded_amount, provider, provider_ref, provider_status FROM payments WHERE tenant_id = \\$1 AND id = \\$2 FOR UPDATE").
+		WithArgs("acme", id).
+		WillReturnRows(sqlmock.NewRows([]string{"amount", "status", "refunded_amount", "provider", "provider_ref", "provider_status"}).AddRow(amount, state, refunded, "", "", ""))
+}
+
+func TestStoreRefundPayment(t *testing.T) {
+	cases := []struct {
+		name     string
+		refunded float64
+		refund   float64
+		status   string
+	}{
+		{"partial", 0, 30, models.StatusPartiallyRefunded},
+		// 0.1 + 0.2 is not 0.3 in floating point; cents are.
+		{"completes in cents", 99.7, 0.3, models.StatusRefunded},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			expectLock(mock, 7, 100, models.StatusCreated, tc.refunded)
+			mock.ExpectExec("SELECT set_config\\('app.event_reason'").WithArgs("customer request").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery("UPDATE payments SET refunded_amount = \\$3, status = \\$4, provider_status = \\$5 WHERE tenant_id = \\$1 AND id = \\$2 RETURNING").
+				WithArgs("acme", 7, tc.refunded+tc.refund, tc.status, "").
+				WillReturnRows(paymentRows(models.Payment{ID: 7, Amount: 100, Currency: "USD", TenantID: "acme", Status: tc.status, RefundedAmount: tc.refunded + tc.refund}))
+			mock.ExpectCommit()
+
+			s := &store.PaymentStore{DB: db}
+			payment, err := s.RefundPayment(tenantContext(), 7, tc.refund, "customer request", nil)
+			require.NoError(t, err)
+			assert.Equal(t, tc.status, payment.Status)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestStoreRefundRejected(t *testing.T) {
+	cases := []struct {
+		name     string
+		status   string
+		refunded float64
+		refund   float64
+		err      error
+	}{
+		{"over the unrefunded amount", models.StatusPartiallyRefunded, 80, 20.01, store.ErrRefundTooLarge},
+		{"zero", models.StatusCreated, 0, 0, store.ErrRefundTooLarge},
+		{"cancelled", models.StatusCancelled, 0, 1, store.ErrInvalidState},
+		{"fully refunded", models.StatusRefunded, 100, 1, store.ErrInvalidState},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			expectLock(mock, 7, 100, tc.status, tc.refunded)
+			mock.ExpectRollback()
+
+			s := &store.PaymentStore{DB: db}
+			_, err = s.RefundPayment(tenantContext(), 7, tc.refund, "", nil)
+			assert.ErrorIs(t, err, tc.err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestStoreCancelOnlyUnrefunded(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	expectLock(mock, 7, 100, models.StatusPartiallyRefunded, 10)
+	mock.ExpectRollback()
+
+	s := &store.PaymentStore{DB: db}
+	_, err = s.CancelPayment(tenantContext(), 7, "duplicate", nil)
+	assert.ErrorIs(t, err, store.ErrInvalidState)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStoreUpdateKeepsAmountOfHeldPayments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM tenants").
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{}", nil, nil, nil, nil))
+	mock.ExpectExec("UPDATE payments SET amount = \\$3, currency = \\$4 WHERE tenant_id = \\$1 AND id = \\$2\\s+AND \\(\\(amount = \\$3 AND currency = \\$4\\) OR \\(status = 'created' AND refunded_amount = 0 AND provider_ref = ''\\)\\)").
+		WithArgs("acme", 7, 1.0, "USD").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM payments WHERE tenant_id = \\$1 AND id = \\$2\\)").
+		WithArgs("acme", 7).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	s := &store.PaymentStore{DB: db}
+	err = s.UpdatePayment(tenantContext(), 7, models.Payment{ID: 7, Amount: 1, Currency: "USD"}, store.PaymentDetails{})
+	assert.ErrorIs(t, err, store.ErrAmountFixed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStoreDeleteRequiresCancelOfHeldPayments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, exists := range []bool{true, false} {
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE FROM payments WHERE tenant_id = \\$1 AND id = \\$2 AND \\(provider_ref = '' OR status = 'cancelled'\\)").
+			WithArgs("acme", 7).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs("acme", 7).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(exists))
+		mock.ExpectRollback()
+	}
+
+	s := &store.PaymentStore{DB: db}
+	assert.ErrorIs(t, s.DeletePayment(tenantContext(), 7), store.ErrPaymentCaptured)
+	assert.ErrorIs(t, s.DeletePayment(tenantContext(), 7), store.ErrPaymentNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestV2DeleteHeldPaymentConflicts(t *testing.T) {
+	r, mock := newGatewayRouter(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM payments").WithArgs("acme", 7).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS").WithArgs("acme", 7).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/v2/payments/7", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), store.ErrPaymentCaptured.Error())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func eventRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "payment_id", "tenant_id", "type", "amount", "currency", "status", "reason", "created_at"})
+}
+
+func TestV2RefundAndHistory(t *testing.T) {
+	r, mock := newGatewayRouter(t)
+	expectLock(mock, 7, 100, models.StatusCreated, 0)
+	mock.ExpectExec("SELECT set_config\\('app.event_reason'").WithArgs("damaged").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("UPDATE payments SET refunded_amount").
+		WithArgs("acme", 7, 25.0, models.StatusPartiallyRefunded, "").
+		WillReturnRows(paymentRows(models.Payment{ID: 7, Amount: 100, Currency: "USD", TenantID: "acme", Status: models.StatusPartiallyRefunded, RefundedAmount: 25}))
+	mock.ExpectCommit()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/payments/7/refund", bytes.NewBufferString(`{"amount":25,"reason":"damaged"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id":7,"amount":100,"currency":"USD","tenant_id":"acme","status":"partially_refunded","refunded_amount":25}`, rec.Body.String())
+
+	created := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM payment_events WHERE tenant_id = \\$1 AND payment_id = \\$2 ORDER BY id").
+		WithArgs("acme", 7).
+		WillReturnRows(eventRows().
+			AddRow(1, 7, "acme", "created", 100.0, "USD", "created", "", created).
+			AddRow(2, 7, "acme", "refunded", 25.0, "USD", "partially_refunded", "damaged", created.Add(time.Hour)))
+	mock.ExpectCommit()
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/payments/7/events", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":[
+		{"id":1,"payment_id":7,"tenant_id":"acme","type":"created","amount":100,"currency":"USD","status":"created","created_at":"2026-05-01T12:00:00Z"},
+		{"id":2,"payment_id":7,"tenant_id":"acme","type":"refunded","amount":25,"currency":"USD","status":"partially_refunded","reason":"damaged","created_at":"2026-05-01T13:00:00Z"}
+	]}`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestV2CancelRefundedPaymentConflicts(t *testing.T) {
+	r, mock := newGatewayRouter(t)
+	expectLock(mock, 7, 100, models.StatusRefunded, 100)
+	mock.ExpectRollback()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/payments/7/cancel", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), store.ErrInvalidState.Error())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestV2EventFeed(t *testing.T) {
+	r, mock := newGatewayRouter(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM payment_events WHERE \\(\\$1 = '' OR tenant_id = \\$1\\) AND id > \\$2 ORDER BY id LIMIT \\$3").
+		WithArgs("acme", 41, 1000).
+		WillReturnRows(eventRows().AddRow(42, 9, "acme", "cancelled", 5.0, "EUR", "cancelled", "", time.Now()))
+	mock.ExpectCommit()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/events?after=41&limit=5000", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"type":"cancelled"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// record appends an event to the fake's feed; f.mu must be held.
+func (f *fakePayments) record(p *proto.Payment, typ string, amount float64, reason string) {
+	f.events = append(f.events, &proto.PaymentEvent{
+		Id:        int64(len(f.events) + 1),
+		PaymentId: p.Id,
+		TenantId:  p.TenantId,
+		Type:      typ,
+		Amount:    amount,
+		Currency:  p.Currency,
+		Status:    p.Status,
+		Reason:    reason,
+		CreatedAt: timestamppb.Now(),
+	})
+}
+
+func (f *fakePayments) RefundPayment(ctx context.Context, req *proto.RefundPaymentRequest) (*proto.RefundPaymentResponse, error) {
+	if err := f.enter(ctx, "refund"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.payments[req.GetId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "payment not found")
+	}
+	if p.RefundedAmount+req.GetAmount() > p.Amount {
+		return nil, status.Error(codes.InvalidArgument, store.ErrRefundTooLarge.Error())
+	}
+	p.RefundedAmount += req.GetAmount()
+	p.Status = models.StatusPartiallyRefunded
+	if p.RefundedAmount == p.Amount {
+		p.Status = models.StatusRefunded
+	}
+	f.record(p, "refunded", req.GetAmount(), req.GetReason())
+	return &proto.RefundPaymentResponse{Payment: p}, nil
+}
+
+func (f *fakePayments) CancelPayment(ctx context.Context, req *proto.CancelPaymentRequest) (*proto.CancelPaymentResponse, error) {
+	if err := f.enter(ctx, "cancel"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.payments[req.GetId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "payment not found")
+	}
+	if p.Status != models.StatusCreated {
+		return nil, status.Error(codes.FailedPrecondition, store.ErrInvalidState.Error())
+	}
+	p.Status = models.StatusCancelled
+	f.record(p, "cancelled", p.Amount, req.GetReason())
+	return &proto.CancelPaymentResponse{Payment: p}, nil
+}
+
+func (f *fakePayments) ListPaymentEvents(ctx context.Context, req *proto.ListPaymentEventsRequest) (*proto.ListPaymentEventsResponse, error) {
+	if err := f.enter(ctx, "history"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	res := &proto.ListPaymentEventsResponse{}
+	for _, e := range f.events {
+		if e.PaymentId == req.GetId() {
+			res.Events = append(res.Events, e)
+		}
+	}
+	if len(res.Events) == 0 {
+		return nil, status.Error(codes.NotFound, "payment not found")
+	}
+	return res, nil
+}
+
+func (f *fakePayments) EventsAfter(ctx context.Context, after int64, limit int) ([]*proto.PaymentEvent, error) {
+	if err := f.enter(ctx, "watch"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var events []*proto.PaymentEvent
+	for _, e := range f.events {
+		if e.Id > after && len(events) < limit {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (f *fakePayments) WatchPayments(req *proto.WatchPaymentsRequest, stream proto.PaymentService_WatchPaymentsServer) error {
+	after := req.GetAfterEventId()
+	for {
+		events, err := f.EventsAfter(stream.Context(), after, 100)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			if err := stream.Send(e); err != nil {
+				return err
+			}
+			after = e.Id
+		}
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestClientRefundCancelAndHistory(t *testing.T) {
+	for _, name := range []string{"grpc", "rest"} {
+		t.Run(name, func(t *testing.T) {
+			c := clientTransports(t, newFakePayments())[name]
+			ctx := context.Background()
+			_, err := c.Create(ctx, paymentsclient.Payment{ID: 1, Amount: 10, Currency: "USD"})
+			require.NoError(t, err)
+			_, err = c.Create(ctx, paymentsclient.Payment{ID: 2, Amount: 5, Currency: "USD"})
+			require.NoError(t, err)
+
+			refunded, err := c.Refund(ctx, 1, 4, "damaged")
+			require.NoError(t, err)
+			assert.Equal(t, models.StatusPartiallyRefunded, refunded.Status)
+			assert.Equal(t, 4.0, refunded.RefundedAmount)
+
+			_, err = c.Cancel(ctx, 1, "")
+			assert.Equal(t, codes.FailedPrecondition, paymentsclient.Code(err))
+			cancelled, err := c.Cancel(ctx, 2, "duplicate")
+			require.NoError(t, err)
+			assert.Equal(t, models.StatusCancelled, cancelled.Status)
+
+			events, err := c.History(ctx, 1)
+			require.NoError(t, err)
+			require.Len(t, events, 2)
+			assert.Equal(t, "refunded", events[1].Type)
+			assert.Equal(t, "damaged", events[1].Reason)
+			assert.False(t, events[1].CreatedAt.IsZero())
+		})
+	}
+}
+
+func TestClientRefundIsNotRetried(t *testing.T) {
+	fake := newFakePayments()
+	fake.payments[1] = &proto.Payment{Id: 1, Amount: 10, Currency: "USD", Status: models.StatusCreated}
+	for name, c := range clientTransports(t, fake) {
+		t.Run(name, func(t *testing.T) {
+			fake.mu.Lock()
+			fake.unavailable, fake.calls["refund"] = 1, 0
+			fake.mu.Unlock()
+
+			_, err := c.Refund(context.Background(), 1, 1, "")
+			assert.Equal(t, codes.Unavailable, paymentsclient.Code(err))
+			assert.Equal(t, 1, fake.calls["refund"])
+		})
+	}
+}
+
+func TestClientWatchFollowsNewEvents(t *testing.T) {
+	for _, name := range []string{"grpc", "rest"} {
+		t.Run(name, func(t *testing.T) {
+			c := clientTransports(t, newFakePayments())[name]
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, err := c.Create(ctx, paymentsclient.Payment{ID: 1, Amount: 10, Currency: "USD"})
+			require.NoError(t, err)
+
+			seen := make(chan paymentsclient.Event, 10)
+			done := make(chan error, 1)
+			watchCtx, stop := context.WithCancel(ctx)
+			go func() {
+				done <- c.Watch(watchCtx, 0, func(e paymentsclient.Event) error {
+					seen <- e
+					return nil
+				})
+			}()
+
+			assert.Equal(t, "created", (<-seen).Type)
+			_, err = c.Cancel(ctx, 1, "")
+			require.NoError(t, err)
+			assert.Equal(t, "cancelled", (<-seen).Type)
+
+			stop()
+			assert.NoError(t, <-done)
+		})
+	}
+}
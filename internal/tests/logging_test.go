@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-lang-final/internal/config"
+	"go-lang-final/internal/logging"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func newTestLogger(redact ...string) (*logrus.Logger, *logtest.Hook) {
+	logger := logrus.New()
+	logger.AddHook(logging.NewRedactionHook(redact))
+	hook := logtest.NewLocal(logger)
+	return logger, hook
+}
+
+func TestHTTPMiddlewarePropagatesRequestID(t *testing.T) {
+	logger, hook := newTestLogger()
+
+	r := mux.NewRouter()
+	r.Use(logging.Middleware(logger))
+	r.HandleFunc("/payments/{id}", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "abc-123", logging.RequestIDFromContext(r.Context()))
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("hello"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/payments/7", nil)
+	req.Header.Set(logging.RequestIDHeader, "abc-123")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, "abc-123", rec.Header().Get(logging.RequestIDHeader))
+	entry := hook.LastEntry()
+	assert.Equal(t, "abc-123", entry.Data["request_id"])
+	assert.Equal(t, "/payments/{id}", entry.Data["route"])
+	assert.Equal(t, http.StatusTeapot, entry.Data["status"])
+	assert.Equal(t, 5, entry.Data["bytes"])
+}
+
+func TestHTTPMiddlewareReplacesInvalidRequestID(t *testing.T) {
+	logger, _ := newTestLogger()
+
+	r := mux.NewRouter()
+	r.Use(logging.Middleware(logger))
+	r.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/list", nil)
+	req.Header.Set(logging.RequestIDHeader, "bad id\nwith newline")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	id := rec.Header().Get(logging.RequestIDHeader)
+	assert.Len(t, id, 32)
+}
+
+func TestRedactionHookMasksConfiguredFields(t *testing.T) {
+	logger, hook := newTestLogger("Amount", "card_number")
+
+	logger.WithFields(logrus.Fields{"amount": 100.0, "card_number": "4242", "currency": "USD"}).Info("payment")
+
+	entry := hook.LastEntry()
+	assert.Equal(t, "[REDACTED]", entry.Data["amount"])
+	assert.Equal(t, "[REDACTED]", entry.Data["card_number"])
+	assert.Equal(t, "USD", entry.Data["currency"])
+}
+
+func TestFromContextFallsBackToRedactingLogger(t *testing.T) {
+	logger := logging.New(config.LoggingConfig{RedactFields: []string{"card_number"}})
+	hook := logtest.NewLocal(logger)
+
+	logging.FromContext(context.Background()).WithField("card_number", "4242").Info("payment")
+
+	entry := hook.LastEntry()
+	if assert.NotNil(t, entry) {
+		assert.Equal(t, "[REDACTED]", entry.Data["card_number"])
+	}
+}
+
+func TestUnaryInterceptorUsesMetadataRequestID(t *testing.T) {
+	logger, hook := newTestLogger()
+	interceptor := logging.UnaryServerInterceptor(logger)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(logging.RequestIDMetadata, "req-42"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.PaymentService/GetPayment"}
+
+	_, err := interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		assert.Equal(t, "req-42", logging.RequestIDFromContext(ctx))
+		logging.FromContext(ctx).Info("inside handler")
+		return nil, nil
+	})
+	assert.NoError(t, err)
+
+	entries := hook.AllEntries()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "req-42", entries[0].Data["request_id"])
+	assert.Equal(t, "/proto.PaymentService/GetPayment", entries[1].Data["method"])
+	assert.Equal(t, "OK", entries[1].Data["code"])
+}
@@ -0,0 +1,38 @@
+package tests
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"go-lang-final/internal/money"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoneyRound(t *testing.T) {
+	for _, tc := range []struct {
+		num, den int64
+		want     int64
+	}{
+		{5, 2, 3},
+		{-5, 2, -3},
+		{7, 3, 2},
+		{-7, 3, -2},
+		{4, 1, 4},
+	} {
+		got, ok := money.Round(big.NewRat(tc.num, tc.den))
+		assert.True(t, ok)
+		assert.Equal(t, tc.want, got, "%d/%d", tc.num, tc.den)
+	}
+
+	huge := new(big.Rat).Mul(big.NewRat(math.MaxInt64, 1), big.NewRat(2, 1))
+	_, ok := money.Round(huge)
+	assert.False(t, ok)
+}
+
+func TestMoneyMinorUnits(t *testing.T) {
+	assert.Equal(t, int64(1999), money.Minor(19.99))
+	assert.Equal(t, int64(30), money.Minor(0.1+0.2))
+	assert.Equal(t, 19.99, money.Major(1999))
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-lang-final/internal/apidocs"
+	"go-lang-final/internal/router"
+	"go-lang-final/internal/store"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPICoversEveryRoute(t *testing.T) {
+	logger, _ := newTestLogger()
+	r := router.NewRouter(&store.PaymentStore{}, logger)
+	spec := apidocs.Spec()
+
+	routes := 0
+	err := r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		require.NoError(t, err)
+		methods, err := route.GetMethods()
+		require.NoError(t, err, path)
+		for _, method := range methods {
+			routes++
+			assert.NotNil(t, spec.Operation(method, path), "%s %s is not in the OpenAPI spec", method, path)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	operations := 0
+	for _, item := range spec.Paths {
+		operations += len(item)
+	}
+	assert.Equal(t, routes, operations, "the spec documents routes that are not registered")
+}
+
+// The /v2 routes are written by hand rather than generated from the proto,
+// so the spec is checked against them: every named route has an operation
+// with its name as operationId at the same method and path.
+func TestOpenAPIMatchesV2Routes(t *testing.T) {
+	logger, _ := newTestLogger()
+	r := router.NewRouter(&store.PaymentStore{}, logger)
+	spec := apidocs.Spec()
+
+	documented := map[string]bool{}
+	for path, item := range spec.Paths {
+		if strings.HasPrefix(path, "/v2/") {
+			for method, op := range item {
+				documented[method+" "+path+" "+op.OperationID] = true
+			}
+		}
+	}
+
+	err := r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		require.NoError(t, err)
+		if !strings.HasPrefix(path, "/v2/") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		require.NoError(t, err, path)
+		for _, method := range methods {
+			key := strings.ToLower(method) + " " + path + " " + route.GetName()
+			assert.True(t, documented[key], "%s %s has no operation %s in the OpenAPI spec", method, path, route.GetName())
+			delete(documented, key)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Empty(t, documented, "the spec documents /v2 operations that are not registered")
+}
+
+func TestOpenAPISchemas(t *testing.T) {
+	spec := apidocs.Spec()
+	assert.Equal(t, "3.1.0", spec.OpenAPI)
+	for _, name := range []string{"Payment", "Error", "ListEnvelope", "Pagination", "Links", "v1.GetPaymentResponse"} {
+		assert.Contains(t, spec.Components.Schemas, name)
+	}
+	assert.Equal(t, []string{"id", "amount", "currency"}, spec.Components.Schemas["Payment"].Required)
+
+	get := spec.Operation(http.MethodGet, "/v1/payments/{id}")
+	require.NotNil(t, get)
+	assert.True(t, get.Deprecated)
+	require.Len(t, get.Parameters, 1)
+	assert.Equal(t, "path", get.Parameters[0].In)
+
+	list := spec.Operation(http.MethodGet, "/list")
+	require.NotNil(t, list)
+	assert.Equal(t, "array", list.Responses["200"].Content["application/json"].Schema.Type)
+
+	create := spec.Operation(http.MethodPost, "/v2/payments")
+	require.NotNil(t, create)
+	assert.False(t, create.Deprecated)
+	assert.Contains(t, create.Responses["201"].Headers, "Location")
+}
+
+func TestOpenAPIServedWithDocs(t *testing.T) {
+	h := apidocs.Handler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var doc map[string]interface{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&doc))
+	assert.Equal(t, "3.1.0", doc["openapi"])
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	page, _ := io.ReadAll(rec.Body)
+	assert.Contains(t, string(page), `fetch("/openapi.json")`)
+	assert.NotContains(t, string(page), "https://", "the docs page must not load external assets")
+}
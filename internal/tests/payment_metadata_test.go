@@ -0,0 +1,215 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-lang-final/internal/handlers"
+	"go-lang-final/internal/metadata"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/store"
+	"go-lang-final/internal/validation"
+	"go-lang-final/proto"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataLimits(t *testing.T) {
+	assert.NoError(t, metadata.Check(nil))
+	assert.NoError(t, metadata.Check(map[string]string{"order": "42"}))
+	assert.Error(t, metadata.Check(map[string]string{strings.Repeat("k", metadata.MaxKeyLength+1): "v"}))
+
+	assert.NoError(t, metadata.CheckDescription(""))
+	assert.Error(t, metadata.CheckDescription(strings.Repeat("d", metadata.MaxDescriptionLength+1)))
+
+	for _, descriptor := range []string{"", "ACME SHOP", "ACME COFFEE 42"} {
+		assert.NoError(t, metadata.CheckDescriptor(descriptor), descriptor)
+	}
+	for _, descriptor := range []string{"ACME", "ACME SHOP THAT IS TOO LONG", "ACME*SHOP", "CAFÉ PARIS", "1234567"} {
+		assert.Error(t, metadata.CheckDescriptor(descriptor), descriptor)
+	}
+}
+
+func TestValidatePaymentDetails(t *testing.T) {
+	tooMany := map[string]string{}
+	for i := 0; i <= metadata.MaxKeys; i++ {
+		tooMany[fmt.Sprintf("key%d", i)] = "v"
+	}
+	descriptor := "<script>"
+	for name, req := range map[string]interface{}{
+		"too many keys":     &proto.CreatePaymentRequest{Id: 1, Amount: 10, Currency: "USD", Metadata: tooMany},
+		"long description":  &proto.CreatePaymentRequest{Id: 1, Amount: 10, Currency: "USD", Description: strings.Repeat("d", metadata.MaxDescriptionLength+1)},
+		"short descriptor":  &proto.CreatePaymentRequest{Id: 1, Amount: 10, Currency: "USD", StatementDescriptor: "ACME"},
+		"update descriptor": &proto.UpdatePaymentRequest{Id: 1, Amount: 10, Currency: "USD", StatementDescriptor: &descriptor},
+		"filter empty key":  &proto.ListPaymentsRequest{Metadata: map[string]string{"": "42"}},
+		"long query":        &proto.ListPaymentsRequest{Query: strings.Repeat("q", metadata.MaxDescriptionLength+1)},
+	} {
+		assert.Len(t, validation.Check(req), 1, name)
+	}
+	assert.Empty(t, validation.Check(&proto.CreatePaymentRequest{
+		Id: 1, Amount: 10, Currency: "USD", Metadata: map[string]string{"order": "42"}, Description: "Two coffees", StatementDescriptor: "ACME COFFEE",
+	}))
+}
+
+func TestCreatePaymentWithDetails(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM tenants").
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{}", nil, nil, nil, nil))
+	mock.ExpectQuery("INSERT INTO daily_quotas").
+		WithArgs("acme", 20.0).
+		WillReturnRows(sqlmock.NewRows([]string{"payments", "amount"}).AddRow(1, 20.0))
+	mock.ExpectExec("INSERT INTO payments").
+		WithArgs("acme", 1, 20.0, "EUR", "created", "", "", "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE payments SET customer_id = NULLIF\\(\\$3, ''\\), metadata = \\$4, description = \\$5, statement_descriptor = \\$6").
+		WithArgs("acme", 1, "", []byte(`{"order":"42"}`), "Two coffees", "ACME COFFEE").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	s := &store.PaymentStore{DB: db}
+	err = s.CreatePayment(tenantContext(), models.Payment{
+		ID: 1, Amount: 20, Currency: "EUR",
+		Metadata:            map[string]string{"order": "42", "coupon": ""},
+		Description:         "Two coffees",
+		StatementDescriptor: "ACME COFFEE",
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	err = s.CreatePayment(tenantContext(), models.Payment{ID: 2, Amount: 20, Currency: "EUR", StatementDescriptor: "ACME*COFFEE"})
+	assert.ErrorIs(t, err, store.ErrInvalidPaymentDetails)
+}
+
+func TestUpdatePaymentMergesMetadata(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	expectUpdate := func() {
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("FROM tenants").
+			WithArgs("acme").
+			WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{}", nil, nil, nil, nil))
+		mock.ExpectExec("UPDATE payments SET amount = \\$3, currency = \\$4").
+			WithArgs("acme", 1, 20.0, "EUR").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	description := "Three coffees"
+
+	expectUpdate()
+	mock.ExpectQuery("UPDATE payments SET metadata = \\(metadata \\|\\| \\$3::jsonb\\) - \\$4::text\\[\\], description = COALESCE\\(\\$5, description\\)").
+		WithArgs("acme", 1, []byte(`{"order":"43"}`), pq.Array([]string{"coupon"}), &description, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"metadata"}).AddRow([]byte(`{"order":"43"}`)))
+	mock.ExpectCommit()
+
+	s := &store.PaymentStore{DB: db}
+	err = s.UpdatePayment(tenantContext(), 1, models.Payment{ID: 1, Amount: 20, Currency: "EUR"}, store.PaymentDetails{
+		Metadata:    map[string]string{"order": "43", "coupon": ""},
+		Description: &description,
+	})
+	require.NoError(t, err)
+
+	merged := map[string]string{}
+	for i := 0; i <= metadata.MaxKeys; i++ {
+		merged[fmt.Sprintf("key%d", i)] = "v"
+	}
+	full, err := json.Marshal(merged)
+	require.NoError(t, err)
+	expectUpdate()
+	mock.ExpectQuery("UPDATE payments SET metadata").
+		WithArgs("acme", 1, []byte(`{"key0":"v"}`), pq.Array([]string{}), nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"metadata"}).AddRow(full))
+	mock.ExpectRollback()
+
+	err = s.UpdatePayment(tenantContext(), 1, models.Payment{ID: 1, Amount: 20, Currency: "EUR"}, store.PaymentDetails{
+		Metadata: map[string]string{"key0": "v"},
+	})
+	assert.ErrorIs(t, err, store.ErrInvalidPaymentDetails)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListPaymentsByMetadataAndSearch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("metadata @> \\$4::jsonb\\) AND \\(\\$5 = '' OR description_search @@ websearch_to_tsquery\\('simple', \\$5\\)\\) ORDER BY CASE WHEN \\$5 = '' THEN 0 ELSE ts_rank").
+		WithArgs("acme", "", "", []byte(`{"order":"42"}`), "coffee", 10, 0).
+		WillReturnRows(paymentRows(models.Payment{
+			ID: 1, Amount: 20, Currency: "EUR", TenantID: "acme", Status: models.StatusCreated,
+			Metadata: map[string]string{"order": "42"}, Description: "Two coffees",
+		}))
+	mock.ExpectCommit()
+
+	s := &store.PaymentStore{DB: db}
+	payments, err := s.ListPayments(tenantContext(), store.PaymentFilter{Metadata: map[string]string{"order": "42"}, Query: "coffee"}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+	assert.Equal(t, map[string]string{"order": "42"}, payments[0].Metadata)
+	assert.Equal(t, "Two coffees", payments[0].Description)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestV2ListFiltersByMetadata(t *testing.T) {
+	r, mock := newGatewayRouter(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM payments").
+		WithArgs("acme", "", "", []byte(`{"order":"42"}`), "two coffees", 20, 0).
+		WillReturnRows(paymentRows(models.Payment{ID: 1, Amount: 20, Currency: "EUR", TenantID: "acme", Metadata: map[string]string{"order": "42"}}))
+	mock.ExpectCommit()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/payments?metadata%5Border%5D=42&query=two+coffees", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"metadata":{"order":"42"}`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestV2PatchPaymentMetadata(t *testing.T) {
+	r, mock := newGatewayRouter(t)
+	current := models.Payment{ID: 7, Amount: 10, Currency: "USD", TenantID: "acme", Metadata: map[string]string{"order": "42", "coupon": "SPRING"}}
+	expectFetch(mock, 7, paymentRows(current))
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM tenants").
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{}", nil, nil, nil, nil))
+	mock.ExpectExec("UPDATE payments SET amount").
+		WithArgs("acme", 7, 10.0, "USD").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	description := "Gift"
+	mock.ExpectQuery("UPDATE payments SET metadata").
+		WithArgs("acme", 7, []byte(`{"gift":"yes"}`), pq.Array([]string{"coupon"}), &description, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"metadata"}).AddRow([]byte(`{"gift":"yes","order":"42"}`)))
+	mock.ExpectCommit()
+	updated := current
+	updated.Metadata = map[string]string{"order": "42", "gift": "yes"}
+	updated.Description = description
+	expectFetch(mock, 7, paymentRows(updated))
+
+	req := httptest.NewRequest(http.MethodPatch, "/v2/payments/7", bytes.NewBufferString(`{"metadata":{"coupon":null,"gift":"yes"},"description":"Gift"}`))
+	req.Header.Set("Content-Type", handlers.MergePatchContentType)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id":7,"amount":10,"currency":"USD","tenant_id":"acme","metadata":{"gift":"yes","order":"42"},"description":"Gift"}`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
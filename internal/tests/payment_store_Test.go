@@ -1,8 +1,10 @@
 package tests
 
 import (
+	"context"
 	"go-lang-final/internal/models"
 	"go-lang-final/internal/store"
+	"go-lang-final/internal/tenant"
 
 	"testing"
 
@@ -10,17 +12,30 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func tenantContext() context.Context {
+	return tenant.WithScope(context.Background(), tenant.Scope{TenantID: "acme"})
+}
+
 func TestCreatePayment(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer db.Close()
 
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT allowed_currencies, max_payment_amount, max_payments, (.+) FROM tenants").
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{}", nil, nil, nil, nil))
+	mock.ExpectQuery("INSERT INTO daily_quotas").
+		WithArgs("acme", 100.0).
+		WillReturnRows(sqlmock.NewRows([]string{"payments", "amount"}).AddRow(1, 100.0))
 	mock.ExpectExec("INSERT INTO payments").
-		WithArgs(1, 100.0, "USD").
+		WithArgs("acme", 1, 100.0, "USD", "created", "", "", "", "").
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	s := &store.PaymentStore{DB: db}
-	err = s.CreatePayment(models.Payment{ID: 1, Amount: 100.0, Currency: "USD"})
+	err = s.CreatePayment(tenantContext(), models.Payment{ID: 1, Amount: 100.0, Currency: "USD"})
 	assert.NoError(t, err)
 }
 
@@ -29,17 +44,20 @@ func TestGetPayment(t *testing.T) {
 	assert.NoError(t, err)
 	defer db.Close()
 
-	rows := sqlmock.NewRows([]string{"id", "amount", "currency"}).
-		AddRow(1, 100.0, "USD")
+	rows := sqlmock.NewRows([]string{"tenant_id", "id", "amount", "currency", "status", "refunded_amount", "provider", "provider_ref", "provider_status", "payment_method", "metadata", "description", "statement_descriptor"}).
+		AddRow("acme", 1, 100.0, "USD", "created", 0.0, "", "", "", "", []byte("{}"), "", "")
 
-	mock.ExpectQuery("SELECT id, amount, currency FROM payments WHERE id = ?").
-		WithArgs(1).
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT tenant_id, id, amount, currency, status, refunded_amount, provider, provider_ref, provider_status, payment_method, metadata, description, statement_descriptor FROM payments WHERE tenant_id = \\$1 AND id = \\$2").
+		WithArgs("acme", 1).
 		WillReturnRows(rows)
+	mock.ExpectCommit()
 
 	s := &store.PaymentStore{DB: db}
-	payment, err := s.GetPayment(1)
+	payment, err := s.GetPayment(tenantContext(), 1)
 	assert.NoError(t, err)
-	assert.Equal(t, &models.Payment{ID: 1, Amount: 100.0, Currency: "USD"}, payment)
+	assert.Equal(t, &models.Payment{ID: 1, Amount: 100.0, Currency: "USD", TenantID: "acme", Status: models.StatusCreated}, payment)
 }
 
 func TestUpdatePayment(t *testing.T) {
@@ -47,12 +65,18 @@ func TestUpdatePayment(t *testing.T) {
 	assert.NoError(t, err)
 	defer db.Close()
 
-	mock.ExpectExec("UPDATE payments SET amount = ?, currency = ? WHERE id = ?").
-		WithArgs(100.0, "USD", 1).
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT allowed_currencies, max_payment_amount, max_payments, (.+) FROM tenants").
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{}", nil, nil, nil, nil))
+	mock.ExpectExec("UPDATE payments SET amount = \\$3, currency = \\$4 WHERE tenant_id = \\$1 AND id = \\$2").
+		WithArgs("acme", 1, 100.0, "USD", "created", "", "", "", "").
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	s := &store.PaymentStore{DB: db}
-	err = s.UpdatePayment(1, models.Payment{ID: 1, Amount: 100.0, Currency: "USD"})
+	err = s.UpdatePayment(tenantContext(), 1, models.Payment{ID: 1, Amount: 100.0, Currency: "USD"}, store.PaymentDetails{})
 	assert.NoError(t, err)
 }
 
@@ -61,12 +85,15 @@ func TestDeletePayment(t *testing.T) {
 	assert.NoError(t, err)
 	defer db.Close()
 
-	mock.ExpectExec("DELETE FROM payments WHERE id = ?").
-		WithArgs(1).
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM payments WHERE tenant_id = \\$1 AND id = \\$2").
+		WithArgs("acme", 1).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	s := &store.PaymentStore{DB: db}
-	err = s.DeletePayment(1)
+	err = s.DeletePayment(tenantContext(), 1)
 	assert.NoError(t, err)
 }
 
@@ -75,20 +102,23 @@ func TestListingPayments(t *testing.T) {
 	assert.NoError(t, err)
 	defer db.Close()
 
-	rows := sqlmock.NewRows([]string{"id", "amount", "currency"}).
-		AddRow(1, 100.0, "USD").
-		AddRow(2, 200.0, "USD")
+	rows := sqlmock.NewRows([]string{"tenant_id", "id", "amount", "currency", "status", "refunded_amount", "provider", "provider_ref", "provider_status", "payment_method", "metadata", "description", "statement_descriptor"}).
+		AddRow("acme", 1, 100.0, "USD", "created", 0.0, "", "", "", "", []byte("{}"), "", "").
+		AddRow("acme", 2, 200.0, "USD", "created", 0.0, "", "", "", "", []byte("{}"), "", "")
 
-	mock.ExpectQuery("SELECT id, amount, currency FROM payments WHERE currency = ? AND amount = ? LIMIT ? OFFSET ?").
-		WithArgs("USD", "100.00", 10, 0).
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT tenant_id, id, amount, currency, status, refunded_amount, provider, provider_ref, provider_status, payment_method, metadata, description, statement_descriptor FROM payments WHERE (.+) AND \\(\\$2 = '' OR currency = \\$2\\) AND \\(\\$3 = '' OR amount = NULLIF\\(\\$3, ''\\)::numeric\\) AND \\(\\$4::jsonb = '\\{\\}' OR metadata @> \\$4::jsonb\\) (.+) LIMIT \\$6 OFFSET \\$7").
+		WithArgs("acme", "USD", "100.00", []byte("{}"), "", 10, 0).
 		WillReturnRows(rows)
+	mock.ExpectCommit()
 
 	s := &store.PaymentStore{DB: db}
-	payments, err := s.ListPayments("USD", "100.00", 1, 10)
+	payments, err := s.ListPayments(tenantContext(), store.PaymentFilter{Currency: "USD", Amount: "100.00"}, 1, 10)
 	assert.NoError(t, err)
 	assert.Len(t, payments, 2)
 	assert.Equal(t, []models.Payment{
-		{ID: 1, Amount: 100.0, Currency: "USD"},
-		{ID: 2, Amount: 200.0, Currency: "USD"},
+		{ID: 1, Amount: 100.0, Currency: "USD", TenantID: "acme", Status: models.StatusCreated},
+		{ID: 2, Amount: 200.0, Currency: "USD", TenantID: "acme", Status: models.StatusCreated},
 	}, payments)
 }
@@ -0,0 +1,344 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"go-lang-final/internal/handlers"
+	"go-lang-final/internal/ratelimit"
+	"go-lang-final/pkg/paymentsclient"
+	"go-lang-final/proto"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakePayments is an in-memory PaymentService with failure injection.
+type fakePayments struct {
+	proto.UnimplementedPaymentServiceServer
+
+	mu       sync.Mutex
+	payments map[int64]*proto.Payment
+	keys     map[string]int64
+	calls    map[string]int
+	apiKeys  []string
+	events   []*proto.PaymentEvent
+
+	// unavailable fails that many upcoming calls with Unavailable.
+	unavailable int
+	// dropCreate makes the next successful create report Unavailable, as if
+	// the response was lost.
+	dropCreate bool
+	// failWith fails every call with this error.
+	failWith error
+	// block makes calls wait for their deadline.
+	block bool
+}
+
+func newFakePayments() *fakePayments {
+	return &fakePayments{payments: map[int64]*proto.Payment{}, keys: map[string]int64{}, calls: map[string]int{}}
+}
+
+func (f *fakePayments) enter(ctx context.Context, method string) error {
+	f.mu.Lock()
+	f.calls[method]++
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("x-api-key")) > 0 {
+		f.apiKeys = append(f.apiKeys, md.Get("x-api-key")[0])
+	}
+	block, failWith := f.block, f.failWith
+	if f.unavailable > 0 {
+		f.unavailable--
+		failWith = status.Error(codes.Unavailable, "try again")
+	}
+	f.mu.Unlock()
+
+	if block {
+		<-ctx.Done()
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	return failWith
+}
+
+func (f *fakePayments) CreatePayment(ctx context.Context, req *proto.CreatePaymentRequest) (*proto.CreatePaymentResponse, error) {
+	if err := f.enter(ctx, "create"); err != nil {
+		return nil, err
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	key := ""
+	if vals := md.Get("idempotency-key"); len(vals) > 0 {
+		key = vals[0]
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if id, ok := f.keys[key]; ok && key != "" && id == req.GetId() {
+		return &proto.CreatePaymentResponse{Success: true}, nil
+	}
+	if _, ok := f.payments[req.GetId()]; ok {
+		return nil, status.Error(codes.Internal, "duplicate payment")
+	}
+	f.payments[req.GetId()] = &proto.Payment{Id: req.GetId(), Amount: req.GetAmount(), Currency: req.GetCurrency(), TenantId: "acme", Status: "created"}
+	f.record(f.payments[req.GetId()], "created", req.GetAmount(), "")
+	f.keys[key] = req.GetId()
+	if f.dropCreate {
+		f.dropCreate = false
+		return nil, status.Error(codes.Unavailable, "connection reset")
+	}
+	return &proto.CreatePaymentResponse{Success: true}, nil
+}
+
+func (f *fakePayments) GetPayment(ctx context.Context, req *proto.GetPaymentRequest) (*proto.GetPaymentResponse, error) {
+	if err := f.enter(ctx, "get"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.payments[req.GetId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "payment not found")
+	}
+	return &proto.GetPaymentResponse{Id: p.Id, Amount: p.Amount, Currency: p.Currency, TenantId: p.TenantId, Status: p.Status, RefundedAmount: p.RefundedAmount}, nil
+}
+
+func (f *fakePayments) UpdatePayment(ctx context.Context, req *proto.UpdatePaymentRequest) (*proto.UpdatePaymentResponse, error) {
+	if err := f.enter(ctx, "update"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.payments[req.GetId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "payment not found")
+	}
+	p.Amount, p.Currency = req.GetAmount(), req.GetCurrency()
+	return &proto.UpdatePaymentResponse{Success: true}, nil
+}
+
+func (f *fakePayments) DeletePayment(ctx context.Context, req *proto.DeletePaymentRequest) (*proto.DeletePaymentResponse, error) {
+	if err := f.enter(ctx, "delete"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.payments[req.GetId()]; !ok {
+		return nil, status.Error(codes.NotFound, "payment not found")
+	}
+	delete(f.payments, req.GetId())
+	return &proto.DeletePaymentResponse{Success: true}, nil
+}
+
+func (f *fakePayments) ListPayments(ctx context.Context, req *proto.ListPaymentsRequest) (*proto.ListPaymentsResponse, error) {
+	if err := f.enter(ctx, "list"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var matched []*proto.Payment
+	for _, p := range f.payments {
+		if req.GetCurrency() == "" || p.Currency == req.GetCurrency() {
+			matched = append(matched, p)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Id < matched[j].Id })
+
+	size := int(req.GetPageSize())
+	start := (int(req.GetPage()) - 1) * size
+	if start > len(matched) {
+		start = len(matched)
+	}
+	end := start + size
+	if end > len(matched) {
+		end = len(matched)
+	}
+	return &proto.ListPaymentsResponse{Payments: matched[start:end]}, nil
+}
+
+var fastRetries = paymentsclient.RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     10 * time.Millisecond,
+	Multiplier:     2,
+}
+
+// clientTransports starts fake behind an in-process gRPC server and REST
+// router and returns a client for each.
+func clientTransports(t *testing.T, fake *fakePayments, opts ...paymentsclient.Option) map[string]*paymentsclient.Client {
+	opts = append([]paymentsclient.Option{paymentsclient.WithAPIKey("pk_test"), paymentsclient.WithRetryPolicy(fastRetries)}, opts...)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	proto.RegisterPaymentServiceServer(srv, fake)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	logger, _ := newTestLogger()
+	r := mux.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fake.mu.Lock()
+			fake.apiKeys = append(fake.apiKeys, r.Header.Get("X-API-Key"))
+			fake.mu.Unlock()
+			next.ServeHTTP(w, r)
+		})
+	})
+	handlers.RegisterRESTService(r, fake, logger)
+	httpSrv := httptest.NewServer(r)
+	t.Cleanup(httpSrv.Close)
+
+	return map[string]*paymentsclient.Client{
+		"grpc": paymentsclient.NewGRPC(conn, opts...),
+		"rest": paymentsclient.NewHTTP(httpSrv.URL, httpSrv.Client(), opts...),
+	}
+}
+
+func TestClientCRUD(t *testing.T) {
+	for name, c := range clientTransports(t, newFakePayments()) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			created, err := c.Create(ctx, paymentsclient.Payment{ID: 1, Amount: 10, Currency: "USD"})
+			require.NoError(t, err)
+			assert.Equal(t, &paymentsclient.Payment{ID: 1, Amount: 10, Currency: "USD", TenantID: "acme", Status: "created"}, created)
+
+			updated, err := c.Update(ctx, paymentsclient.Payment{ID: 1, Amount: 12, Currency: "EUR"})
+			require.NoError(t, err)
+			assert.Equal(t, "EUR", updated.Currency)
+
+			require.NoError(t, c.Delete(ctx, 1))
+			_, err = c.Get(ctx, 1)
+			assert.True(t, paymentsclient.IsNotFound(err), "got %v", err)
+		})
+	}
+}
+
+func TestClientSendsCredentials(t *testing.T) {
+	fake := newFakePayments()
+	for _, c := range clientTransports(t, fake) {
+		_, _ = c.Get(context.Background(), 1)
+	}
+	assert.Equal(t, []string{"pk_test", "pk_test"}, fake.apiKeys)
+}
+
+func TestClientRetriesTransientErrors(t *testing.T) {
+	fake := newFakePayments()
+	fake.payments[1] = &proto.Payment{Id: 1, Amount: 1, Currency: "USD"}
+	for name, c := range clientTransports(t, fake) {
+		t.Run(name, func(t *testing.T) {
+			fake.calls["get"] = 0
+			fake.unavailable = 2
+			_, err := c.Get(context.Background(), 1)
+			require.NoError(t, err)
+			assert.Equal(t, 3, fake.calls["get"])
+
+			fake.calls["get"] = 0
+			fake.unavailable = 10
+			_, err = c.Get(context.Background(), 1)
+			assert.Equal(t, codes.Unavailable, paymentsclient.Code(err))
+			assert.Equal(t, fastRetries.MaxAttempts, fake.calls["get"])
+			fake.unavailable = 0
+
+			fake.calls["get"] = 0
+			_, err = c.Get(context.Background(), 404)
+			assert.True(t, paymentsclient.IsNotFound(err))
+			assert.Equal(t, 1, fake.calls["get"], "NotFound must not be retried")
+		})
+	}
+}
+
+func TestClientCreateRetryIsIdempotent(t *testing.T) {
+	for _, name := range []string{"grpc", "rest"} {
+		t.Run(name, func(t *testing.T) {
+			fake := newFakePayments()
+			c := clientTransports(t, fake)[name]
+			fake.dropCreate = true
+
+			p, err := c.Create(context.Background(), paymentsclient.Payment{ID: 7, Amount: 5, Currency: "USD"})
+			require.NoError(t, err)
+			assert.Equal(t, int64(7), p.ID)
+			assert.Equal(t, 2, fake.calls["create"])
+			assert.Len(t, fake.payments, 1)
+			assert.Len(t, fake.keys, 1, "both attempts must carry the same key")
+		})
+	}
+}
+
+func TestClientDoesNotRetryLongQuotaWaits(t *testing.T) {
+	fake := newFakePayments()
+	fake.failWith = ratelimit.ResourceExhausted("daily quota exceeded", time.Hour)
+	for name, c := range clientTransports(t, fake) {
+		t.Run(name, func(t *testing.T) {
+			fake.calls["create"] = 0
+			_, err := c.Create(context.Background(), paymentsclient.Payment{ID: 1, Amount: 1, Currency: "USD"})
+			var e *paymentsclient.Error
+			require.ErrorAs(t, err, &e)
+			assert.Equal(t, codes.ResourceExhausted, e.Code)
+			assert.Equal(t, time.Hour, e.RetryAfter)
+			assert.Equal(t, 1, fake.calls["create"])
+		})
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	fake := newFakePayments()
+	fake.block = true
+	for name, c := range clientTransports(t, fake, paymentsclient.WithTimeout(50*time.Millisecond)) {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			_, err := c.Get(context.Background(), 1)
+			assert.Equal(t, codes.DeadlineExceeded, paymentsclient.Code(err), "got %v", err)
+			assert.Less(t, time.Since(start), 5*time.Second)
+		})
+	}
+}
+
+func TestClientIterator(t *testing.T) {
+	fake := newFakePayments()
+	for i := int64(1); i <= 45; i++ {
+		currency := "USD"
+		if i%3 == 0 {
+			currency = "EUR"
+		}
+		fake.payments[i] = &proto.Payment{Id: i, Amount: float64(i), Currency: currency}
+	}
+
+	for name, c := range clientTransports(t, fake) {
+		t.Run(name, func(t *testing.T) {
+			fake.calls["list"] = 0
+			var ids []int64
+			it := c.Iterate(context.Background(), paymentsclient.ListOptions{PageSize: 20})
+			for it.Next() {
+				ids = append(ids, it.Payment().ID)
+			}
+			require.NoError(t, it.Err())
+			assert.Len(t, ids, 45)
+			assert.True(t, sort.SliceIsSorted(ids, func(i, j int) bool { return ids[i] < ids[j] }))
+			assert.Equal(t, 3, fake.calls["list"])
+
+			var eur int
+			it = c.Iterate(context.Background(), paymentsclient.ListOptions{Currency: "EUR", PageSize: 5})
+			for it.Next() {
+				assert.Equal(t, "EUR", it.Payment().Currency)
+				eur++
+			}
+			require.NoError(t, it.Err())
+			assert.Equal(t, 15, eur)
+		})
+	}
+}
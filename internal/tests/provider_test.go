@@ -0,0 +1,342 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-lang-final/internal/handlers"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/provider"
+	"go-lang-final/internal/store"
+	"go-lang-final/internal/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a settable clock for the simulator.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestSimulator() (*provider.Simulator, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	return provider.NewSimulator(provider.SimulatorOptions{SettlementDelay: time.Hour, Now: clock.Now}), clock
+}
+
+func TestSimulatorMagicValues(t *testing.T) {
+	cases := []struct {
+		name    string
+		card    string
+		amount  float64
+		decline string
+		err     error
+		status  provider.Status
+	}{
+		{"default card", "", 10, "", nil, provider.StatusAuthorized},
+		{"approved card", provider.CardApproved, 10, "", nil, provider.StatusAuthorized},
+		{"declined card", provider.CardDeclined, 10, "card_declined", nil, ""},
+		{"insufficient funds", provider.CardInsufficientFunds, 10, "insufficient_funds", nil, ""},
+		{"invalid number", "4242424242424241", 10, "invalid_number", nil, ""},
+		{"timeout card", provider.CardTimeout, 10, "", provider.ErrTimeout, ""},
+		{"3DS card", provider.CardRequires3DS, 10, "", nil, provider.StatusRequiresAction},
+		{"delayed settlement card", provider.CardDelayedSettlement, 10, "", nil, provider.StatusAuthorized},
+		{"decline amount", "", 10.91, "do_not_honor", nil, ""},
+		{"timeout amount", provider.CardApproved, 10.92, "", provider.ErrTimeout, ""},
+		{"3DS amount", "", 10.93, "", nil, provider.StatusRequiresAction},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sim, _ := newTestSimulator()
+			txn, err := sim.Authorize(context.Background(), provider.Authorization{Amount: tc.amount, Currency: "USD", CardNumber: tc.card})
+			switch {
+			case tc.decline != "":
+				var decline *provider.DeclineError
+				require.ErrorAs(t, err, &decline)
+				assert.Equal(t, tc.decline, decline.Code)
+			case tc.err != nil:
+				assert.ErrorIs(t, err, tc.err)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, "sim_000001", txn.Ref)
+				assert.Equal(t, tc.status, txn.Status)
+				assert.Equal(t, tc.status == provider.StatusRequiresAction, txn.ActionURL != "")
+			}
+		})
+	}
+}
+
+func TestSimulatorSettlement(t *testing.T) {
+	sim, clock := newTestSimulator()
+	ctx := context.Background()
+
+	immediate, err := sim.Authorize(ctx, provider.Authorization{Amount: 10, Currency: "USD"})
+	require.NoError(t, err)
+	immediate, err = sim.Capture(ctx, immediate.Ref, 10)
+	require.NoError(t, err)
+	assert.Equal(t, provider.StatusSettled, immediate.Status)
+	_, err = sim.Void(ctx, immediate.Ref)
+	assert.ErrorIs(t, err, provider.ErrNotAllowed, "settled funds are refunded, not voided")
+
+	delayed, err := sim.Authorize(ctx, provider.Authorization{Amount: 10.94, Currency: "USD"})
+	require.NoError(t, err)
+	delayed, err = sim.Capture(ctx, delayed.Ref, 10.94)
+	require.NoError(t, err)
+	assert.Equal(t, provider.StatusCaptured, delayed.Status)
+
+	clock.now = clock.now.Add(59 * time.Minute)
+	delayed, err = sim.Status(ctx, delayed.Ref)
+	require.NoError(t, err)
+	assert.Equal(t, provider.StatusCaptured, delayed.Status)
+	clock.now = clock.now.Add(time.Minute)
+	delayed, err = sim.Status(ctx, delayed.Ref)
+	require.NoError(t, err)
+	assert.Equal(t, provider.StatusSettled, delayed.Status)
+
+	_, err = sim.Refund(ctx, delayed.Ref, 10)
+	assert.NoError(t, err)
+	_, err = sim.Refund(ctx, delayed.Ref, 1)
+	assert.ErrorIs(t, err, provider.ErrNotAllowed)
+
+	_, err = sim.Status(ctx, "sim_999999")
+	assert.ErrorIs(t, err, provider.ErrUnknownTransaction)
+}
+
+func TestSimulatorIdempotencyAndChallenge(t *testing.T) {
+	sim, _ := newTestSimulator()
+	ctx := context.Background()
+	auth := provider.Authorization{Key: "k1", Amount: 10, Currency: "USD", CardNumber: provider.CardRequires3DS}
+
+	first, err := sim.Authorize(ctx, auth)
+	require.NoError(t, err)
+	again, err := sim.Authorize(ctx, auth)
+	require.NoError(t, err)
+	assert.Equal(t, first, again)
+
+	_, err = sim.Capture(ctx, first.Ref, 10)
+	assert.ErrorIs(t, err, provider.ErrNotAllowed, "cannot capture before the challenge")
+	require.NoError(t, sim.CompleteChallenge(first.Ref, true))
+	txn, err := sim.Status(ctx, first.Ref)
+	require.NoError(t, err)
+	assert.Equal(t, provider.StatusAuthorized, txn.Status)
+	assert.Empty(t, txn.ActionURL)
+
+	other, err := sim.Authorize(ctx, provider.Authorization{Amount: 5, Currency: "USD", CardNumber: provider.CardRequires3DS})
+	require.NoError(t, err)
+	assert.Equal(t, "sim_000002", other.Ref)
+	require.NoError(t, sim.CompleteChallenge(other.Ref, false))
+	txn, err = sim.Status(ctx, other.Ref)
+	require.NoError(t, err)
+	assert.Equal(t, provider.StatusFailed, txn.Status)
+}
+
+func newProviderRouter(t *testing.T, p provider.Provider) (*mux.Router, sqlmock.Sqlmock, *handlers.PaymentService) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	logger, _ := newTestLogger()
+	svc := handlers.NewPaymentService(&store.PaymentStore{DB: db}, p)
+	r := mux.NewRouter()
+	handlers.RegisterRESTService(r, svc, logger)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := tenant.WithScope(r.Context(), tenant.Scope{TenantID: "acme"})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	return r, mock, svc
+}
+
+// expectCreate expects a create of payment 42 that passes the tenant limits
+// and inserts it with the given status and provider columns, authorized by
+// the only provider with the given outcome.
+func expectCreate(mock sqlmock.Sqlmock, amount float64, status, ref, providerStatus, outcome string) {
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM tenants").
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{}", nil, nil, nil, nil))
+	mock.ExpectQuery("INSERT INTO daily_quotas").
+		WithArgs("acme", amount).
+		WillReturnRows(sqlmock.NewRows([]string{"payments", "amount"}).AddRow(1, amount))
+	args := []driver.Value{"acme", 42, amount, "USD", status, provider.SimulatorName, ref, providerStatus, ""}
+	mock.ExpectExec("INSERT INTO payments \\(tenant_id, id, amount, currency, status, provider, provider_ref, provider_status, payment_method\\)").
+		WithArgs(args...).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO payment_routing_decisions").
+		WithArgs("acme", 42, "", "", provider.SimulatorName, []byte(`[{"provider":"simulator","outcome":"`+outcome+`"}]`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+}
+
+func TestV2CreateChargesProvider(t *testing.T) {
+	sim, _ := newTestSimulator()
+	r, mock, _ := newProviderRouter(t, sim)
+	expectCreate(mock, 10.0, models.StatusCreated, "sim_000001", "settled", "authorized")
+	expectFetch(mock, 42, paymentRows(models.Payment{ID: 42, Amount: 10, Currency: "USD", TenantID: "acme", Status: models.StatusCreated,
+		Provider: provider.SimulatorName, ProviderRef: "sim_000001", ProviderStatus: "settled"}))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/payments", bytes.NewBufferString(`{"id":42,"amount":10,"currency":"USD","card_number":"4242424242424242"}`)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, `{"id":42,"amount":10,"currency":"USD","tenant_id":"acme","status":"created","provider":"simulator","provider_ref":"sim_000001","provider_status":"settled"}`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestV2CreateRequiresAction(t *testing.T) {
+	sim, _ := newTestSimulator()
+	r, mock, _ := newProviderRouter(t, sim)
+	expectCreate(mock, 10.0, models.StatusRequiresAction, "sim_000001", "requires_action", "requires_action")
+	expectFetch(mock, 42, paymentRows(models.Payment{ID: 42, Amount: 10, Currency: "USD", TenantID: "acme", Status: models.StatusRequiresAction,
+		Provider: provider.SimulatorName, ProviderRef: "sim_000001", ProviderStatus: "requires_action"}))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/payments", bytes.NewBufferString(`{"id":42,"amount":10,"currency":"USD","card_number":"`+provider.CardRequires3DS+`"}`)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"requires_action"`)
+	assert.Contains(t, rec.Body.String(), `"action_url":"https://simulator.invalid/3ds/sim_000001"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestV2CreateDeclinedIsNotStored(t *testing.T) {
+	sim, _ := newTestSimulator()
+	r, mock, _ := newProviderRouter(t, sim)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/payments", bytes.NewBufferString(`{"id":42,"amount":10,"currency":"USD","card_number":"`+provider.CardInsufficientFunds+`"}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "payment declined: insufficient_funds")
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/payments", bytes.NewBufferString(`{"id":42,"amount":10.92,"currency":"USD"}`)))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateReleasesChargeTheStoreRejected(t *testing.T) {
+	sim, _ := newTestSimulator()
+	r, mock, _ := newProviderRouter(t, sim)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM tenants").
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{EUR}", nil, nil, nil, nil))
+	mock.ExpectRollback()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/payments", bytes.NewBufferString(`{"id":42,"amount":10.94,"currency":"USD"}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	txn, err := sim.Status(context.Background(), "sim_000001")
+	require.NoError(t, err)
+	assert.Equal(t, provider.StatusVoided, txn.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// expectProviderLock expects the row lock of a refund or cancel of payment 7
+// processed by the simulator as ref.
+func expectProviderLock(mock sqlmock.Sqlmock, state, ref, providerStatus string) {
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FOR UPDATE").
+		WithArgs("acme", 7).
+		WillReturnRows(sqlmock.NewRows([]string{"amount", "status", "refunded_amount", "provider", "provider_ref", "provider_status"}).
+			AddRow(100.0, state, 0.0, provider.SimulatorName, ref, providerStatus))
+}
+
+func TestRefundAndCancelGoThroughProvider(t *testing.T) {
+	sim, _ := newTestSimulator()
+	ctx := context.Background()
+	settled, err := sim.Authorize(ctx, provider.Authorization{Amount: 100, Currency: "USD"})
+	require.NoError(t, err)
+	_, err = sim.Capture(ctx, settled.Ref, 100)
+	require.NoError(t, err)
+	r, mock, _ := newProviderRouter(t, sim)
+
+	// A settled payment cannot be voided, so cancelling it fails and
+	// nothing changes.
+	expectProviderLock(mock, models.StatusCreated, settled.Ref, "settled")
+	mock.ExpectRollback()
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/payments/7/cancel", bytes.NewBufferString(`{}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "does not allow this operation")
+
+	expectProviderLock(mock, models.StatusCreated, settled.Ref, "settled")
+	mock.ExpectExec("SELECT set_config\\('app.event_reason'").WithArgs("").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("UPDATE payments SET refunded_amount").
+		WithArgs("acme", 7, 100.0, models.StatusRefunded, "settled").
+		WillReturnRows(paymentRows(models.Payment{ID: 7, Amount: 100, Currency: "USD", TenantID: "acme", Status: models.StatusRefunded, RefundedAmount: 100}))
+	mock.ExpectCommit()
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/payments/7/refund", bytes.NewBufferString(`{"amount":100}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	_, err = sim.Refund(ctx, settled.Ref, 0.01)
+	assert.ErrorIs(t, err, provider.ErrNotAllowed, "the provider refunded everything")
+
+	pending, err := sim.Authorize(ctx, provider.Authorization{Amount: 100, Currency: "USD", CardNumber: provider.CardRequires3DS})
+	require.NoError(t, err)
+	expectProviderLock(mock, models.StatusRequiresAction, pending.Ref, "requires_action")
+	mock.ExpectExec("SELECT set_config\\('app.event_reason'").WithArgs("abandoned").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("UPDATE payments SET status = \\$3, provider_status = \\$4").
+		WithArgs("acme", 7, models.StatusCancelled, "voided").
+		WillReturnRows(paymentRows(models.Payment{ID: 7, Amount: 100, Currency: "USD", TenantID: "acme", Status: models.StatusCancelled}))
+	mock.ExpectCommit()
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/payments/7/cancel", bytes.NewBufferString(`{"reason":"abandoned"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSyncProvider(t *testing.T) {
+	sim, clock := newTestSimulator()
+	ctx := context.Background()
+	challenged, err := sim.Authorize(ctx, provider.Authorization{Amount: 20, Currency: "USD", CardNumber: provider.CardRequires3DS})
+	require.NoError(t, err)
+	require.NoError(t, sim.CompleteChallenge(challenged.Ref, true))
+	delayed, err := sim.Authorize(ctx, provider.Authorization{Amount: 30, Currency: "USD", CardNumber: provider.CardDelayedSettlement})
+	require.NoError(t, err)
+	_, err = sim.Capture(ctx, delayed.Ref, 30)
+	require.NoError(t, err)
+	unsettled, err := sim.Authorize(ctx, provider.Authorization{Amount: 40, Currency: "USD", CardNumber: provider.CardDelayedSettlement})
+	require.NoError(t, err)
+	clock.now = clock.now.Add(time.Hour)
+	_, err = sim.Capture(ctx, unsettled.Ref, 40)
+	require.NoError(t, err)
+
+	_, mock, svc := newProviderRouter(t, sim)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("", "on").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM payments WHERE \\(\\$1 = '' OR tenant_id = \\$1\\) AND provider_ref <> ''").
+		WithArgs("", 100).
+		WillReturnRows(paymentRows(
+			models.Payment{TenantID: "acme", ID: 1, Amount: 20, Currency: "USD", Status: models.StatusRequiresAction, Provider: provider.SimulatorName, ProviderRef: challenged.Ref, ProviderStatus: "requires_action"},
+			models.Payment{TenantID: "globex", ID: 2, Amount: 30, Currency: "USD", Status: models.StatusCreated, Provider: provider.SimulatorName, ProviderRef: delayed.Ref, ProviderStatus: "captured"},
+			models.Payment{TenantID: "acme", ID: 3, Amount: 40, Currency: "USD", Status: models.StatusCreated, Provider: provider.SimulatorName, ProviderRef: unsettled.Ref, ProviderStatus: "captured"},
+		))
+	mock.ExpectCommit()
+	expectRecord := func(tenantID string, id int64, from, to, providerStatus, reason string) {
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT set_config").WithArgs(tenantID, "off").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SELECT set_config\\('app.event_reason'").WithArgs(reason).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("UPDATE payments SET status = \\$4, provider_status = \\$5 WHERE tenant_id = \\$1 AND id = \\$2 AND status = \\$3").
+			WithArgs(tenantID, id, from, to, providerStatus).
+			WillReturnRows(paymentRows(models.Payment{TenantID: tenantID, ID: id, Status: to, ProviderStatus: providerStatus}))
+		mock.ExpectCommit()
+	}
+	expectRecord("acme", 1, models.StatusRequiresAction, models.StatusCreated, "settled", "challenge completed")
+	expectRecord("globex", 2, models.StatusCreated, models.StatusCreated, "settled", "")
+
+	changed, err := svc.SyncProvider(tenant.WithScope(ctx, tenant.Scope{All: true}))
+	require.NoError(t, err)
+	assert.Equal(t, 2, changed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
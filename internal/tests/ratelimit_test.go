@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-lang-final/internal/auth"
+	"go-lang-final/internal/config"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/ratelimit"
+	"go-lang-final/internal/store"
+	"go-lang-final/internal/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMemoryStoreTokenBucket(t *testing.T) {
+	s := ratelimit.NewMemoryStore()
+	rule := ratelimit.Rule{Rate: 1, Burst: 2}
+	now := time.Unix(1000, 0)
+
+	d, _ := s.Take(context.Background(), "k", rule, now)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 1, d.Remaining)
+	d, _ = s.Take(context.Background(), "k", rule, now)
+	assert.True(t, d.Allowed)
+	d, _ = s.Take(context.Background(), "k", rule, now)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, time.Second, d.RetryAfter)
+
+	d, _ = s.Take(context.Background(), "k", rule, now.Add(1500*time.Millisecond))
+	assert.True(t, d.Allowed)
+
+	d, _ = s.Take(context.Background(), "other", rule, now)
+	assert.True(t, d.Allowed)
+}
+
+func TestCeilSeconds(t *testing.T) {
+	assert.Equal(t, 0, ratelimit.CeilSeconds(0))
+	assert.Equal(t, 1, ratelimit.CeilSeconds(300*time.Millisecond))
+	assert.Equal(t, 1, ratelimit.CeilSeconds(time.Second))
+	assert.Equal(t, 2, ratelimit.CeilSeconds(time.Second+time.Nanosecond))
+}
+
+func TestRateLimitMiddlewareSetsHeaders(t *testing.T) {
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), config.RateLimitConfig{
+		Default: config.RateLimitRule{Rate: 100, Burst: 100, Key: ratelimit.KeyAPIKey},
+		Routes: map[string]config.RateLimitRule{
+			"ListPayments": {Rate: 0.01, Burst: 2, Key: ratelimit.KeyTenant},
+		},
+	})
+
+	r := mux.NewRouter()
+	r.Use(withPrincipal(&auth.Principal{ID: "k1"}), limiter.Middleware())
+	r.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) {}).Name("ListPayments")
+	r.HandleFunc("/get", func(w http.ResponseWriter, r *http.Request) {}).Name("GetPayment")
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/list", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/list", nil))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "100", rec.Header().Get("Retry-After"))
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/get", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "100", rec.Header().Get("RateLimit-Limit"))
+}
+
+func TestRateLimitInterceptorReturnsRetryInfo(t *testing.T) {
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), config.RateLimitConfig{
+		Default: config.RateLimitRule{Rate: 0.5, Burst: 1, Key: ratelimit.KeyTenant},
+	})
+	interceptor := limiter.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.PaymentService/ListPayments"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil }
+
+	acme := tenant.WithScope(context.Background(), tenant.Scope{TenantID: "acme"})
+	_, err := interceptor(acme, nil, info, handler)
+	assert.NoError(t, err)
+
+	_, err = interceptor(acme, nil, info, handler)
+	st := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	require.Len(t, st.Details(), 1)
+	retry := st.Details()[0].(*errdetails.RetryInfo)
+	assert.InDelta(t, 2*time.Second, retry.RetryDelay.AsDuration(), float64(10*time.Millisecond))
+
+	globex := tenant.WithScope(context.Background(), tenant.Scope{TenantID: "globex"})
+	_, err = interceptor(globex, nil, info, handler)
+	assert.NoError(t, err)
+}
+
+func TestPostgresRateLimitStore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Unix(2000, 0)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO rate_limit_buckets").WithArgs("k", 5.0, now).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT tokens, updated_at FROM rate_limit_buckets").
+		WithArgs("k").
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "updated_at"}).AddRow(0.25, now.Add(-time.Second)))
+	mock.ExpectExec("UPDATE rate_limit_buckets").WithArgs("k", 0.5, now).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	s := ratelimit.NewPostgresStore(db)
+	d, err := s.Take(context.Background(), "k", ratelimit.Rule{Rate: 0.25, Burst: 5}, now)
+	assert.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 2*time.Second, d.RetryAfter)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDailyQuotaExceeded(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM tenants").
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).
+			AddRow("{}", nil, nil, 100, nil))
+	mock.ExpectQuery("INSERT INTO daily_quotas").
+		WithArgs("acme", 10.0).
+		WillReturnRows(sqlmock.NewRows([]string{"payments", "amount"}).AddRow(101, 5000.0))
+	mock.ExpectRollback()
+
+	s := &store.PaymentStore{DB: db}
+	ctx := tenant.WithScope(context.Background(), tenant.Scope{TenantID: "acme"})
+	err = s.CreatePayment(ctx, models.Payment{ID: 1, Amount: 10.0, Currency: "USD"})
+	assert.ErrorIs(t, err, store.ErrDailyQuotaExceeded)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	reset := ratelimit.UntilQuotaReset(time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Hour, reset)
+}
@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-lang-final/internal/audit"
+	"go-lang-final/internal/auth"
+	"go-lang-final/internal/handlers"
+	"go-lang-final/internal/rbac"
+	"go-lang-final/internal/router"
+	"go-lang-final/proto"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type recordingAuditor struct {
+	events []audit.Event
+}
+
+func (r *recordingAuditor) Record(ctx context.Context, event audit.Event) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func withPrincipal(p *auth.Principal) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), p)))
+		})
+	}
+}
+
+func TestEveryRESTRouteHasPermission(t *testing.T) {
+	r := router.NewRouter(nil, logrus.New())
+
+	err := r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tpl, _ := route.GetPathTemplate()
+		name := route.GetName()
+		assert.NotEmpty(t, name, "route %s has no name", tpl)
+		_, ok := handlers.RoutePermissions[name]
+		assert.True(t, ok, "route %s (%s) has no permission", tpl, name)
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestEveryGRPCMethodHasPermission(t *testing.T) {
+	desc := proto.PaymentService_ServiceDesc
+	for _, m := range desc.Methods {
+		_, ok := handlers.MethodPermissions["/"+desc.ServiceName+"/"+m.MethodName]
+		assert.True(t, ok, "method %s has no permission", m.MethodName)
+	}
+	for _, s := range desc.Streams {
+		_, ok := handlers.MethodPermissions["/"+desc.ServiceName+"/"+s.StreamName]
+		assert.True(t, ok, "stream %s has no permission", s.StreamName)
+	}
+}
+
+func TestRESTAndGRPCPermissionsMatch(t *testing.T) {
+	service := "/" + proto.PaymentService_ServiceDesc.ServiceName + "/"
+	for name, want := range handlers.RoutePermissions {
+		got, ok := handlers.MethodPermissions[service+name]
+		if !ok {
+			// REST-only routes, such as file downloads, have no gRPC method.
+			continue
+		}
+		assert.Equal(t, want, got, "%s requires different permissions over REST and gRPC", name)
+	}
+}
+
+func TestViewerCannotDeletePayment(t *testing.T) {
+	auditor := &recordingAuditor{}
+	enforcer := rbac.NewEnforcer(rbac.DefaultPolicy(), handlers.RoutePermissions, handlers.MethodPermissions, auditor)
+
+	r := router.NewRouter(nil, logrus.New())
+	r.Use(withPrincipal(&auth.Principal{ID: "support-1", Roles: []string{rbac.RoleViewer}}), enforcer.Middleware())
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/delete?id=1", nil))
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Len(t, auditor.events, 1)
+	assert.Equal(t, "support-1", auditor.events[0].Principal)
+	assert.Equal(t, audit.OutcomeDenied, auditor.events[0].Outcome)
+	assert.Equal(t, string(rbac.PaymentsDelete), auditor.events[0].Resource)
+}
+
+func TestUnannotatedRouteIsDenied(t *testing.T) {
+	auditor := &recordingAuditor{}
+	enforcer := rbac.NewEnforcer(rbac.DefaultPolicy(), handlers.RoutePermissions, handlers.MethodPermissions, auditor)
+
+	r := mux.NewRouter()
+	r.Use(withPrincipal(&auth.Principal{ID: "admin-1", Roles: []string{rbac.RoleAdmin}}), enforcer.Middleware())
+	r.HandleFunc("/debug", func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not run")
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Len(t, auditor.events, 1)
+}
+
+func TestGRPCEnforcer(t *testing.T) {
+	enforcer := rbac.NewEnforcer(rbac.DefaultPolicy(), handlers.RoutePermissions, handlers.MethodPermissions, &recordingAuditor{})
+	interceptor := enforcer.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: proto.PaymentService_DeletePayment_FullMethodName}
+	called := false
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		called = true
+		return nil, nil
+	}
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{ID: "op", Roles: []string{rbac.RoleOperator}})
+	_, err := interceptor(ctx, nil, info, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.False(t, called)
+
+	ctx = auth.WithPrincipal(context.Background(), &auth.Principal{ID: "root", Roles: []string{rbac.RoleAdmin}})
+	_, err = interceptor(ctx, nil, info, handler)
+	assert.NoError(t, err)
+	assert.True(t, called)
+
+	_, err = interceptor(context.Background(), nil, info, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestLoadPolicyDB(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"role", "permission"}).
+		AddRow("viewer", "payments:read").
+		AddRow("support", "payments:read").
+		AddRow("support", "refunds:create")
+	mock.ExpectQuery("SELECT role, permission FROM role_permissions").WillReturnRows(rows)
+
+	policy, err := rbac.LoadPolicyDB(context.Background(), db)
+	assert.NoError(t, err)
+	assert.True(t, policy.Allowed([]string{"support"}, rbac.RefundsCreate))
+	assert.False(t, policy.Allowed([]string{"viewer"}, rbac.PaymentsDelete))
+}
@@ -0,0 +1,401 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-lang-final/internal/auth"
+	"go-lang-final/internal/handlers"
+	"go-lang-final/internal/reconcile"
+	"go-lang-final/internal/store"
+	"go-lang-final/internal/tenant"
+	"go-lang-final/proto"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testCAMT053 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <GrpHdr><MsgId>MSG-1</MsgId><CreDtTm>2026-10-02T06:00:00</CreDtTm></GrpHdr>
+    <Stmt>
+      <Id>STMT-2026-10-01</Id>
+      <Acct><Id><IBAN>DE89370400440532013000</IBAN></Id></Acct>
+      <Ntry>
+        <Amt Ccy="EUR">10.50</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts>BOOK</Sts>
+        <BookgDt><Dt>2026-10-01</Dt></BookgDt><AcctSvcrRef>B1</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <Refs><EndToEndId>NOTPROVIDED</EndToEndId></Refs>
+          <RmtInf><Ustrd>Order 12 PAY-7</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">120.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts>BOOK</Sts>
+        <BookgDt><Dt>2026-10-01</Dt></BookgDt><AcctSvcrRef>B2</AcctSvcrRef>
+        <NtryDtls>
+          <TxDtls><Refs><AcctSvcrRef>B2-1</AcctSvcrRef></Refs><Amt Ccy="EUR">20.00</Amt><RmtInf><Ustrd>thank you</Ustrd></RmtInf></TxDtls>
+          <TxDtls><Refs><AcctSvcrRef>B2-2</AcctSvcrRef><EndToEndId>PAY-9</EndToEndId></Refs><Amt Ccy="EUR">100.00</Amt></TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">5.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><DtTm>2026-10-02T09:30:00+02:00</DtTm></BookgDt><AcctSvcrRef>B3</AcctSvcrRef>
+        <NtryDtls><TxDtls><Refs><EndToEndId>ct-00000000000000000000000a</EndToEndId></Refs></TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">10.50</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts>BOOK</Sts>
+        <BookgDt><Dt>2026-10-01</Dt></BookgDt><AcctSvcrRef>B1</AcctSvcrRef>
+        <AddtlNtryInf>Order 12 PAY-7</AddtlNtryInf>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">1.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts>BOOK</Sts>
+        <BookgDt><Dt>2026-10-02</Dt></BookgDt><AcctSvcrRef>B5</AcctSvcrRef>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">3.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts>PDNG</Sts>
+        <BookgDt><Dt>2026-10-02</Dt></BookgDt>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`
+
+const testMT940 = `{1:F01COBADEFFAXXX0000000000}{2:I940COBADEFFXXXXN}{4:
+:20:STARTUMS
+:25:37040044/0532013000
+:28C:00001/001
+:60F:C261001EUR1000,00
+:61:2610011001C10,50NTRFNONREF//B1
+:86:166?00SEPA-UEBERWEISUNG?20EREF+PAY-7?21SVWZ+Order 12
+:61:2610021002D5,00NTRFct-00000000000000000000000a//B3
+:86:177?00SEPA-UEBERWEISUNG?20Refund
+:61:2612311231RD2,00NTRFNONREF
+:62F:C261002EUR1003,50
+-}`
+
+func TestParseCAMT053(t *testing.T) {
+	st, err := reconcile.Parse([]byte(testCAMT053))
+	require.NoError(t, err)
+	assert.Equal(t, reconcile.FormatCAMT053, st.Format)
+	assert.Equal(t, "STMT-2026-10-01", st.ID)
+	assert.Equal(t, "DE89370400440532013000", st.Account)
+	require.Len(t, st.Entries, 6, "pending entries are left out, batch entries split")
+
+	first := st.Entries[0]
+	assert.True(t, first.Credit)
+	assert.Equal(t, int64(1050), first.Amount)
+	assert.Equal(t, "EUR", first.Currency)
+	assert.Equal(t, "B1", first.BankRef)
+	assert.Empty(t, first.References)
+	assert.Equal(t, "Order 12 PAY-7", first.Text)
+	assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), first.BookingDate)
+
+	assert.Equal(t, int64(2000), st.Entries[1].Amount)
+	assert.Equal(t, "B2-1", st.Entries[1].BankRef)
+	assert.Equal(t, int64(10000), st.Entries[2].Amount)
+	assert.Equal(t, []string{"PAY-9"}, st.Entries[2].References)
+
+	refund := st.Entries[3]
+	assert.False(t, refund.Credit)
+	assert.Equal(t, []string{"ct-00000000000000000000000a"}, refund.References)
+	assert.Equal(t, "2026-10-02", refund.BookingDate.UTC().Format("2006-01-02"))
+
+	_, err = reconcile.Parse([]byte(`<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.002.001.03"/>`))
+	assert.ErrorIs(t, err, reconcile.ErrInvalidStatement)
+}
+
+func TestParseMT940(t *testing.T) {
+	st, err := reconcile.Parse([]byte(testMT940))
+	require.NoError(t, err)
+	assert.Equal(t, reconcile.FormatMT940, st.Format)
+	assert.Equal(t, "STARTUMS", st.ID)
+	assert.Equal(t, "37040044/0532013000", st.Account)
+	require.Len(t, st.Entries, 3)
+
+	assert.True(t, st.Entries[0].Credit)
+	assert.Equal(t, int64(1050), st.Entries[0].Amount)
+	assert.Equal(t, "EUR", st.Entries[0].Currency)
+	assert.Equal(t, "B1", st.Entries[0].BankRef)
+	assert.Equal(t, []string{"PAY-7"}, st.Entries[0].References)
+	assert.Contains(t, st.Entries[0].Text, "SVWZ+Order 12")
+
+	assert.False(t, st.Entries[1].Credit)
+	assert.Equal(t, []string{"ct-00000000000000000000000a"}, st.Entries[1].References)
+	assert.Equal(t, time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC), st.Entries[1].BookingDate)
+
+	// A reversed debit is money coming back.
+	assert.True(t, st.Entries[2].Credit)
+	assert.Equal(t, time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC), st.Entries[2].BookingDate)
+
+	_, err = reconcile.Parse([]byte(":20:X\n:61:2610011001C10,50NTRFNONREF\n"))
+	assert.ErrorIs(t, err, reconcile.ErrInvalidStatement)
+}
+
+func testCandidates() []reconcile.Candidate {
+	day := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	return []reconcile.Candidate{
+		{EventID: 1, PaymentID: 7, Kind: reconcile.KindPayment, References: []string{"PAY-7"}, Amount: 1050, Currency: "EUR", Date: day},
+		{EventID: 2, PaymentID: 8, Kind: reconcile.KindPayment, References: []string{"PAY-8"}, Amount: 2000, Currency: "EUR", Date: day},
+		{EventID: 3, PaymentID: 9, Kind: reconcile.KindPayment, References: []string{"PAY-9"}, Amount: 9000, Currency: "EUR", Date: day},
+		{EventID: 4, PaymentID: 7, Kind: reconcile.KindRefund, References: []string{"PAY-7", "ct-00000000000000000000000a"}, Amount: 500, Currency: "EUR", Date: day},
+	}
+}
+
+func TestMatch(t *testing.T) {
+	st, err := reconcile.Parse([]byte(testCAMT053))
+	require.NoError(t, err)
+
+	results := reconcile.Match(st.Entries, testCandidates(), reconcile.DefaultOptions())
+	require.Len(t, results, 6)
+	want := []struct {
+		status string
+		event  int64
+		diff   int64
+	}{
+		{reconcile.Matched, 1, 0},
+		{reconcile.Matched, 2, 0},
+		{reconcile.AmountMismatch, 3, 1000},
+		{reconcile.Matched, 4, 0},
+		{reconcile.Duplicated, 1, 0},
+		{reconcile.Unmatched, 0, 0},
+	}
+	for i, w := range want {
+		assert.Equal(t, w.status, results[i].Status, "line %d", i+1)
+		if w.event == 0 {
+			assert.Nil(t, results[i].Candidate, "line %d", i+1)
+			continue
+		}
+		require.NotNil(t, results[i].Candidate, "line %d", i+1)
+		assert.Equal(t, w.event, results[i].Candidate.EventID, "line %d", i+1)
+		assert.Equal(t, w.diff, results[i].Difference, "line %d", i+1)
+	}
+
+	// A tolerance of ten covers the mismatch.
+	results = reconcile.Match(st.Entries, testCandidates(), reconcile.Options{Tolerance: 1000, DateWindow: 3})
+	assert.Equal(t, reconcile.Matched, results[2].Status)
+}
+
+func TestMatchOptions(t *testing.T) {
+	entry := reconcile.Entry{Credit: true, Amount: 2000, Currency: "EUR", BookingDate: time.Date(2026, 10, 6, 0, 0, 0, 0, time.UTC)}
+
+	// Outside the date window nothing matches.
+	results := reconcile.Match([]reconcile.Entry{entry}, testCandidates(), reconcile.DefaultOptions())
+	assert.Equal(t, reconcile.Unmatched, results[0].Status)
+	results = reconcile.Match([]reconcile.Entry{entry}, testCandidates(), reconcile.Options{DateWindow: 5})
+	assert.Equal(t, reconcile.Matched, results[0].Status)
+
+	// Two candidates of the same amount are ambiguous without a reference.
+	candidates := append(testCandidates(), reconcile.Candidate{EventID: 5, PaymentID: 10, Kind: reconcile.KindPayment, Amount: 2000, Currency: "EUR", Date: entry.BookingDate})
+	results = reconcile.Match([]reconcile.Entry{entry}, candidates, reconcile.Options{DateWindow: 5})
+	assert.Equal(t, reconcile.Unmatched, results[0].Status)
+
+	// A payment an earlier run settled makes a referencing line a duplicate.
+	candidates = testCandidates()
+	candidates[0].Reconciled = true
+	entry = reconcile.Entry{Credit: true, Amount: 1050, Currency: "EUR", BookingDate: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), Text: "PAY-7"}
+	results = reconcile.Match([]reconcile.Entry{entry}, candidates, reconcile.DefaultOptions())
+	assert.Equal(t, reconcile.Duplicated, results[0].Status)
+
+	// References are whole words: PAY-70 is not PAY-7.
+	entry.Text = "PAY-70"
+	results = reconcile.Match([]reconcile.Entry{entry}, candidates, reconcile.DefaultOptions())
+	assert.Equal(t, reconcile.Unmatched, results[0].Status)
+}
+
+var candidateColumns = []string{"id", "payment_id", "type", "amount", "currency", "created_at", "provider_ref", "transfers", "reconciled"}
+
+func expectCandidates(mock sqlmock.Sqlmock) {
+	day := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT e.id, e.payment_id, e.type, e.amount, e.currency, e.created_at").
+		WithArgs("acme", time.Date(2026, 9, 27, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 7, 0, 0, 0, 0, time.UTC)).
+		WillReturnRows(sqlmock.NewRows(candidateColumns).
+			AddRow(1, 7, "created", 10.5, "EUR", day, "", "{}", false).
+			AddRow(2, 8, "created", 20.0, "EUR", day, "sim_8", "{}", false).
+			AddRow(3, 9, "created", 90.0, "EUR", day, "", "{}", false).
+			AddRow(4, 7, "refunded", 5.0, "EUR", day, "", "{ct-00000000000000000000000a}", false))
+}
+
+func TestImportBankStatement(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+
+	_, err := svc.ImportBankStatement(ctx, &proto.ImportBankStatementRequest{Document: []byte("garbage")})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectCandidates(mock)
+	mock.ExpectQuery("INSERT INTO reconciliation_runs").
+		WithArgs("acme", sqlmock.AnyArg(), "camt.053", "STMT-2026-10-01", "DE89370400440532013000", 0.0, 3, 6, 3, 1, 1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "created_at"}).AddRow("acme", vaultNow))
+	lines := []struct {
+		bankRef, direction, status string
+		amount                     float64
+		payment, event             interface{}
+		difference                 float64
+	}{
+		{"B1", "credit", "matched", 10.5, int64(7), int64(1), 0},
+		{"B2-1", "credit", "matched", 20, int64(8), int64(2), 0},
+		{"B2-2", "credit", "amount_mismatch", 100, int64(9), int64(3), 10},
+		{"B3", "debit", "matched", 5, int64(7), int64(4), 0},
+		{"B1", "credit", "duplicated", 10.5, int64(7), int64(1), 0},
+		{"B5", "credit", "unmatched", 1, nil, nil, 0},
+	}
+	for i, l := range lines {
+		mock.ExpectExec("INSERT INTO reconciliation_lines").
+			WithArgs("acme", sqlmock.AnyArg(), i+1, l.bankRef, sqlmock.AnyArg(), sqlmock.AnyArg(), l.direction,
+				l.amount, "EUR", sqlmock.AnyArg(), l.status, l.payment, l.event, l.difference).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	run, err := svc.ImportBankStatement(ctx, &proto.ImportBankStatementRequest{Document: []byte(testCAMT053)})
+	require.NoError(t, err)
+	assert.Regexp(t, "^rec_[0-9a-f]{24}$", run.GetId())
+	assert.Equal(t, int32(6), run.GetLineCount())
+	assert.Equal(t, int32(3), run.GetMatched())
+	require.Len(t, run.GetLines(), 6)
+	assert.Equal(t, "PAY-9", run.GetLines()[2].GetReference())
+	assert.Equal(t, "2026-10-02", run.GetLines()[3].GetBookingDate())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportBankStatementTolerance(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+	svc.ConfigureReconciliation(reconcile.Options{Tolerance: 1000, DateWindow: 3})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectCandidates(mock)
+	mock.ExpectQuery("INSERT INTO reconciliation_runs").
+		WithArgs("acme", sqlmock.AnyArg(), "camt.053", "STMT-2026-10-01", "DE89370400440532013000", 10.0, 3, 6, 4, 1, 1, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "created_at"}).AddRow("acme", vaultNow))
+	for i := 0; i < 6; i++ {
+		mock.ExpectExec("INSERT INTO reconciliation_lines").WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	run, err := svc.ImportBankStatement(ctx, &proto.ImportBankStatementRequest{Document: []byte(testCAMT053)})
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), run.GetMatched())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+var lineColumns = []string{"line", "bank_ref", "reference", "description", "direction", "amount", "currency", "booking_date", "status",
+	"payment_id", "event_id", "difference", "resolution", "resolution_note", "resolved_by", "resolved_at"}
+
+const testRunID = "rec_00000000000000000000000a"
+
+func TestResolveReconciliationLine(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{ID: "ops@acme"})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT status, resolution FROM reconciliation_lines").
+		WithArgs("acme", testRunID, 6).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "resolution"}).AddRow("unmatched", ""))
+	mock.ExpectQuery("SELECT 1 FROM payments").WithArgs("acme", int64(11)).
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+	mock.ExpectQuery("UPDATE reconciliation_lines SET resolution").
+		WithArgs("acme", testRunID, 6, "accepted", int64(11), "paid in cash", "ops@acme").
+		WillReturnRows(sqlmock.NewRows(lineColumns).AddRow(6, "B5", "", "", "credit", 1.0, "EUR", time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC),
+			"unmatched", 11, nil, 0.0, "accepted", "paid in cash", "ops@acme", vaultNow))
+	mock.ExpectCommit()
+
+	line, err := svc.ResolveReconciliationLine(ctx, &proto.ResolveReconciliationLineRequest{
+		RunId: testRunID, Line: 6, Resolution: "accepted", PaymentId: 11, Note: "paid in cash",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "accepted", line.GetResolution())
+	assert.Equal(t, int64(11), line.GetPaymentId())
+	assert.Equal(t, "ops@acme", line.GetResolvedBy())
+	assert.NotNil(t, line.GetResolvedAt())
+
+	// Matched lines are no exceptions.
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT status, resolution FROM reconciliation_lines").
+		WithArgs("acme", testRunID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "resolution"}).AddRow("matched", ""))
+	mock.ExpectRollback()
+
+	_, err = svc.ResolveReconciliationLine(ctx, &proto.ResolveReconciliationLineRequest{RunId: testRunID, Line: 1, Resolution: "written_off"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = svc.ResolveReconciliationLine(ctx, &proto.ResolveReconciliationLineRequest{RunId: testRunID, Line: 1, Resolution: "ignored"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestV2ReconciliationRuns(t *testing.T) {
+	svc, mock, _ := newTestBankService(t)
+	logger, _ := newTestLogger()
+	r := mux.NewRouter()
+	handlers.RegisterRESTService(r, svc, logger)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(tenant.WithScope(r.Context(), tenant.Scope{TenantID: "acme"})))
+		})
+	})
+
+	runColumns := []string{"id", "format", "statement_id", "account", "tolerance", "date_window_days", "lines", "matched", "unmatched",
+		"duplicated", "amount_mismatches", "tenant_id", "created_at"}
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT id, format, statement_id, account, tolerance, date_window_days, lines, matched, unmatched, duplicated, amount_mismatches, tenant_id, created_at FROM reconciliation_runs").
+		WithArgs("acme", testRunID).
+		WillReturnRows(sqlmock.NewRows(runColumns).AddRow(testRunID, "mt940", "STARTUMS", "", 0.0, 3, 3, 2, 1, 0, 0, "acme", vaultNow))
+	mock.ExpectQuery("SELECT line, bank_ref, reference, description, direction, amount, currency, booking_date, status").
+		WithArgs("acme", testRunID, "exceptions").
+		WillReturnRows(sqlmock.NewRows(lineColumns).AddRow(3, "", "", "", "credit", 2.0, "EUR", time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+			"unmatched", nil, nil, 0.0, "", "", "", nil))
+	mock.ExpectCommit()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/reconciliation_runs/"+testRunID+"?status=exceptions", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var run map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &run))
+	assert.Equal(t, float64(2), run["matched"])
+	require.Len(t, run["items"], 1)
+	assert.Equal(t, "2026-12-31", run["items"].([]interface{})[0].(map[string]interface{})["booking_date"])
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT id, format").WithArgs("acme", "rec_00000000000000000000000b").WillReturnRows(sqlmock.NewRows(runColumns))
+	mock.ExpectRollback()
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/reconciliation_runs/rec_00000000000000000000000b", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/reconciliation_runs?date_window_days=90", bytes.NewBufferString(testMT940)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/reconciliation_runs/"+testRunID+"/lines/x/resolve", bytes.NewBufferString(`{"resolution":"accepted"}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReconciliationRunNotFound(t *testing.T) {
+	s, mock, ctx := newTestBankStore(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT status, resolution FROM reconciliation_lines").WithArgs("acme", testRunID, 99).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "resolution"}))
+	mock.ExpectRollback()
+
+	_, err := s.ResolveReconciliationLine(ctx, testRunID, 99, "accepted", 0, "", "")
+	assert.ErrorIs(t, err, store.ErrLineNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
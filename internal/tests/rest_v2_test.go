@@ -0,0 +1,163 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-lang-final/internal/handlers"
+	"go-lang-final/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func paymentRows(rows ...models.Payment) *sqlmock.Rows {
+	r := sqlmock.NewRows([]string{"tenant_id", "id", "amount", "currency", "status", "refunded_amount", "provider", "provider_ref", "provider_status", "payment_method", "metadata", "description", "statement_descriptor"})
+	for _, p := range rows {
+		metadata := []byte("{}")
+		if p.Metadata != nil {
+			metadata, _ = json.Marshal(p.Metadata)
+		}
+		r.AddRow(p.TenantID, p.ID, p.Amount, p.Currency, p.Status, p.RefundedAmount, p.Provider, p.ProviderRef, p.ProviderStatus, p.PaymentMethod,
+			metadata, p.Description, p.StatementDescriptor)
+	}
+	return r
+}
+
+func expectFetch(mock sqlmock.Sqlmock, id int64, rows *sqlmock.Rows) {
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT tenant_id, id, amount, currency, status, refunded_amount, provider, provider_ref, provider_status, payment_method, metadata, description, statement_descriptor FROM payments WHERE tenant_id = \\$1 AND id = \\$2").
+		WithArgs("acme", id).
+		WillReturnRows(rows)
+	mock.ExpectCommit()
+}
+
+func TestV2CreatePayment(t *testing.T) {
+	r, mock := newGatewayRouter(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM tenants").
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{}", nil, nil, nil, nil))
+	mock.ExpectQuery("INSERT INTO daily_quotas").
+		WithArgs("acme", 10.05).
+		WillReturnRows(sqlmock.NewRows([]string{"payments", "amount"}).AddRow(1, 10.05))
+	mock.ExpectExec("INSERT INTO payments").
+		WithArgs("acme", 42, 10.05, "USD", "created", "", "", "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	expectFetch(mock, 42, paymentRows(models.Payment{ID: 42, Amount: 10.05, Currency: "USD", TenantID: "acme"}))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/payments", bytes.NewBufferString(`{"id":42,"amount":10.05,"currency":"USD"}`)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "/v2/payments/42", rec.Header().Get("Location"))
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+	assert.JSONEq(t, `{"id":42,"amount":10.05,"currency":"USD","tenant_id":"acme"}`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestV2PatchPayment(t *testing.T) {
+	r, mock := newGatewayRouter(t)
+	expectFetch(mock, 7, paymentRows(models.Payment{ID: 7, Amount: 10, Currency: "USD", TenantID: "acme"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM tenants").
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{}", nil, nil, nil, nil))
+	mock.ExpectExec("UPDATE payments").
+		WithArgs("acme", 7, 10.0, "EUR").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectFetch(mock, 7, paymentRows(models.Payment{ID: 7, Amount: 10, Currency: "EUR", TenantID: "acme"}))
+
+	req := httptest.NewRequest(http.MethodPatch, "/v2/payments/7", bytes.NewBufferString(`{"currency":"EUR"}`))
+	req.Header.Set("Content-Type", handlers.MergePatchContentType)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id":7,"amount":10,"currency":"EUR","tenant_id":"acme"}`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestV2PatchRejectsInvalidPatches(t *testing.T) {
+	r, mock := newGatewayRouter(t)
+
+	req := httptest.NewRequest(http.MethodPatch, "/v2/payments/7", bytes.NewBufferString(`{"currency":"EUR"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+
+	for _, patch := range []string{`{"amount":null}`, `{"id":8}`, `{"status":"paid"}`, `[1]`} {
+		expectFetch(mock, 7, paymentRows(models.Payment{ID: 7, Amount: 10, Currency: "USD", TenantID: "acme"}))
+		req := httptest.NewRequest(http.MethodPatch, "/v2/payments/7", bytes.NewBufferString(patch))
+		req.Header.Set("Content-Type", handlers.MergePatchContentType+"; charset=utf-8")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, patch)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestV2DeleteAndNotFound(t *testing.T) {
+	r, mock := newGatewayRouter(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM payments").WithArgs("acme", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT tenant_id, id, amount, currency, status, refunded_amount, provider, provider_ref, provider_status, payment_method, metadata, description, statement_descriptor FROM payments").WithArgs("acme", 7).WillReturnRows(paymentRows())
+	mock.ExpectRollback()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/v2/payments/7", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/payments/7", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/payments/abc", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestV2ListEnvelope(t *testing.T) {
+	r, mock := newGatewayRouter(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT tenant_id, id, amount, currency, status, refunded_amount, provider, provider_ref, provider_status, payment_method, metadata, description, statement_descriptor FROM payments").
+		WithArgs("acme", "USD", "", []byte("{}"), "", 2, 2).
+		WillReturnRows(paymentRows(
+			models.Payment{ID: 3, Amount: 1, Currency: "USD", TenantID: "acme"},
+			models.Payment{ID: 4, Amount: 2, Currency: "USD", TenantID: "acme"},
+		))
+	mock.ExpectCommit()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/payments?currency=USD&page=2&page_size=2", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var body handlers.ListEnvelope
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Len(t, body.Data, 2)
+	assert.Equal(t, handlers.Pagination{Page: 2, PageSize: 2}, body.Pagination)
+	assert.Equal(t, handlers.Links{
+		Self: "/v2/payments?currency=USD&page=2&page_size=2",
+		Next: "/v2/payments?currency=USD&page=3&page_size=2",
+		Prev: "/v2/payments?currency=USD&page=1&page_size=2",
+	}, body.Links)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/payments?page=0", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,285 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-lang-final/internal/config"
+	"go-lang-final/internal/handlers"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/provider"
+	"go-lang-final/internal/routing"
+	"go-lang-final/internal/store"
+	"go-lang-final/internal/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubProvider answers authorizations with err, or authorizes them when err
+// is nil, and counts the calls.
+type stubProvider struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (p *stubProvider) Name() string { return p.name }
+
+func (p *stubProvider) Authorize(_ context.Context, _ provider.Authorization) (provider.Transaction, error) {
+	p.calls++
+	if p.err != nil {
+		return provider.Transaction{}, p.err
+	}
+	return provider.Transaction{Ref: p.name + "_1", Status: provider.StatusAuthorized}, nil
+}
+
+func (p *stubProvider) Capture(_ context.Context, ref string, _ float64) (provider.Transaction, error) {
+	return provider.Transaction{Ref: ref, Status: provider.StatusSettled}, nil
+}
+
+func (p *stubProvider) Void(context.Context, string) (provider.Transaction, error) {
+	return provider.Transaction{}, provider.ErrNotAllowed
+}
+
+func (p *stubProvider) Refund(context.Context, string, float64) (provider.Transaction, error) {
+	return provider.Transaction{}, provider.ErrNotAllowed
+}
+
+func (p *stubProvider) Status(context.Context, string) (provider.Transaction, error) {
+	return provider.Transaction{}, provider.ErrUnknownTransaction
+}
+
+func routeRequest(tenantID string, amount float64, currency, card string) routing.Request {
+	return routing.Request{TenantID: tenantID, Authorization: provider.Authorization{Amount: amount, Currency: currency, CardNumber: card}}
+}
+
+func TestRoutingRules(t *testing.T) {
+	a, b, c := &stubProvider{name: "a"}, &stubProvider{name: "b"}, &stubProvider{name: "c"}
+	router, err := routing.New([]provider.Provider{a, b, c}, routing.Options{
+		Rules: []routing.Rule{
+			{Name: "small-eur", Currencies: []string{"EUR"}, MaxAmount: 100, Targets: []routing.Target{{Provider: "a", Weight: 1}}},
+			{Name: "globex", Tenants: []string{"globex"}, Targets: []routing.Target{{Provider: "b", Weight: 1}}},
+			{Name: "german-cards", Countries: []string{"DE"}, Targets: []routing.Target{{Provider: "c", Weight: 1}}},
+		},
+		BINCountries: map[string]string{"4": "US", "400000": "DE"},
+		Rand:         func() float64 { return 0 },
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name     string
+		req      routing.Request
+		rule     string
+		country  string
+		provider string
+	}{
+		{"currency and amount band", routeRequest("acme", 99.99, "EUR", ""), "small-eur", "", "a"},
+		{"no rule matches", routeRequest("acme", 100, "EUR", provider.CardApproved), "", "US", "a"},
+		{"tenant", routeRequest("globex", 100, "EUR", ""), "globex", "", "b"},
+		{"longest BIN prefix", routeRequest("acme", 100, "USD", provider.CardDelayedSettlement), "german-cards", "DE", "c"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, _, decision, err := router.Authorize(context.Background(), tc.req)
+			require.NoError(t, err)
+			assert.Equal(t, tc.provider, p.Name())
+			assert.Equal(t, tc.rule, decision.Rule)
+			assert.Equal(t, tc.country, decision.Country)
+		})
+	}
+
+	_, err = routing.New([]provider.Provider{a}, routing.Options{Rules: []routing.Rule{{Name: "typo", Targets: []routing.Target{{Provider: "x", Weight: 1}}}}})
+	assert.Error(t, err)
+	_, err = routing.New([]provider.Provider{a, a}, routing.Options{})
+	assert.Error(t, err)
+}
+
+func TestRoutingWeightedSplit(t *testing.T) {
+	a, b := &stubProvider{name: "a"}, &stubProvider{name: "b"}
+	draws := []float64{0.1, 0.74, 0.75, 0.99}
+	router, err := routing.New([]provider.Provider{a, b}, routing.Options{
+		Rules: []routing.Rule{{Name: "ab-test", Targets: []routing.Target{{Provider: "a", Weight: 3}, {Provider: "b", Weight: 1}}}},
+		Rand: func() float64 {
+			n := draws[0]
+			draws = draws[1:]
+			return n
+		},
+	})
+	require.NoError(t, err)
+
+	var picked []string
+	for i := 0; i < 4; i++ {
+		p, _, _, err := router.Authorize(context.Background(), routeRequest("acme", 10, "USD", ""))
+		require.NoError(t, err)
+		picked = append(picked, p.Name())
+	}
+	assert.Equal(t, []string{"a", "a", "b", "b"}, picked)
+
+	// A keyed payment ignores the draw and always goes to the same provider.
+	req := routeRequest("acme", 10, "USD", "")
+	req.Key = "retry-me"
+	first, _, _, err := router.Authorize(context.Background(), req)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		draws = []float64{0.5}
+		p, _, _, err := router.Authorize(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, first.Name(), p.Name())
+	}
+}
+
+func TestRoutingFailover(t *testing.T) {
+	newRouter := func(err error) (*routing.Router, *stubProvider, *stubProvider) {
+		primary, backup := &stubProvider{name: "primary", err: err}, &stubProvider{name: "backup"}
+		router, rerr := routing.New([]provider.Provider{primary, backup}, routing.Options{
+			Rules: []routing.Rule{{Name: "all", Targets: []routing.Target{{Provider: "primary", Weight: 1}, {Provider: "backup"}}}},
+		})
+		require.NoError(t, rerr)
+		return router, primary, backup
+	}
+
+	router, _, backup := newRouter(&provider.DeclineError{Code: "do_not_honor"})
+	p, txn, decision, err := router.Authorize(context.Background(), routeRequest("acme", 10, "USD", ""))
+	require.NoError(t, err)
+	assert.Equal(t, "backup", p.Name())
+	assert.Equal(t, "backup_1", txn.Ref)
+	assert.Equal(t, []models.RoutingAttempt{
+		{Provider: "primary", Outcome: "declined:do_not_honor"},
+		{Provider: "backup", Outcome: "authorized"},
+	}, decision.Attempts)
+	assert.Equal(t, 1, backup.calls)
+
+	router, _, backup = newRouter(&provider.DeclineError{Code: "insufficient_funds"})
+	_, _, decision, err = router.Authorize(context.Background(), routeRequest("acme", 10, "USD", ""))
+	var decline *provider.DeclineError
+	require.ErrorAs(t, err, &decline)
+	assert.Equal(t, "insufficient_funds", decline.Code)
+	assert.Len(t, decision.Attempts, 1)
+	assert.Zero(t, backup.calls, "a hard decline is final")
+
+	router, _, backup = newRouter(provider.ErrTimeout)
+	_, _, decision, err = router.Authorize(context.Background(), routeRequest("acme", 10, "USD", ""))
+	assert.ErrorIs(t, err, provider.ErrTimeout)
+	assert.Equal(t, []models.RoutingAttempt{{Provider: "primary", Outcome: "timeout"}}, decision.Attempts)
+	assert.Zero(t, backup.calls, "the timed out authorization may have gone through")
+}
+
+func TestRoutingCircuitBreaker(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	primary, backup := &stubProvider{name: "primary", err: provider.ErrTimeout}, &stubProvider{name: "backup"}
+	router, err := routing.New([]provider.Provider{primary, backup}, routing.Options{
+		Rules:   []routing.Rule{{Name: "all", Targets: []routing.Target{{Provider: "primary", Weight: 1}, {Provider: "backup"}}}},
+		Breaker: routing.BreakerOptions{Window: 4, MinCalls: 2, ErrorRate: 0.5, Cooldown: time.Minute},
+		Now:     clock.Now,
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, _, _, err = router.Authorize(ctx, routeRequest("acme", 10, "USD", ""))
+		assert.ErrorIs(t, err, provider.ErrTimeout)
+	}
+	assert.Equal(t, routing.StateOpen, router.State("primary"))
+
+	p, _, decision, err := router.Authorize(ctx, routeRequest("acme", 10, "USD", ""))
+	require.NoError(t, err)
+	assert.Equal(t, "backup", p.Name())
+	assert.Equal(t, models.RoutingAttempt{Provider: "primary", Outcome: "circuit_open"}, decision.Attempts[0])
+	assert.Equal(t, 2, primary.calls)
+
+	// After the cooldown one probe goes through; it fails and the breaker
+	// opens again.
+	clock.now = clock.now.Add(time.Minute)
+	assert.Equal(t, routing.StateHalfOpen, router.State("primary"))
+	_, _, _, err = router.Authorize(ctx, routeRequest("acme", 10, "USD", ""))
+	assert.ErrorIs(t, err, provider.ErrTimeout)
+	assert.Equal(t, routing.StateOpen, router.State("primary"))
+
+	// The next probe succeeds and closes it.
+	clock.now = clock.now.Add(time.Minute)
+	primary.err = nil
+	p, _, _, err = router.Authorize(ctx, routeRequest("acme", 10, "USD", ""))
+	require.NoError(t, err)
+	assert.Equal(t, "primary", p.Name())
+	assert.Equal(t, routing.StateClosed, router.State("primary"))
+
+	// Declines are answers from a healthy provider.
+	primary.err = &provider.DeclineError{Code: "card_declined"}
+	for i := 0; i < 4; i++ {
+		router.Authorize(ctx, routeRequest("acme", 10, "USD", ""))
+	}
+	assert.Equal(t, routing.StateClosed, router.State("primary"))
+}
+
+func TestRoutingFromConfig(t *testing.T) {
+	cfg := config.Default().Provider
+	router, err := routing.FromConfig(cfg, nil)
+	require.NoError(t, err)
+	_, ok := router.Provider(provider.SimulatorName)
+	assert.True(t, ok)
+
+	cfg.Providers = []config.ProviderInstance{{Name: "psp-a", Type: "simulator"}, {Name: "psp-b", Type: "simulator", SettlementDelay: "5m"}}
+	cfg.Routing.Rules = []config.RoutingRule{{Name: "eur", Currencies: []string{"EUR"}, Targets: []config.RoutingTarget{{Provider: "psp-b", Weight: 1}}}}
+	router, err = routing.FromConfig(cfg, nil)
+	require.NoError(t, err)
+	p, _, decision, err := router.Authorize(context.Background(), routeRequest("acme", 10, "EUR", ""))
+	require.NoError(t, err)
+	assert.Equal(t, "psp-b", p.Name())
+	assert.Equal(t, "eur", decision.Rule)
+
+	cfg.Providers = append(cfg.Providers, config.ProviderInstance{Name: "psp-c", Type: "acquirer"})
+	_, err = routing.FromConfig(cfg, nil)
+	assert.Error(t, err)
+}
+
+func TestCreateRecordsFailoverDecision(t *testing.T) {
+	primary := &stubProvider{name: "primary", err: &provider.DeclineError{Code: "try_again_later"}}
+	backup := provider.NewSimulator(provider.SimulatorOptions{Name: "backup"})
+	router, err := routing.New([]provider.Provider{primary, backup}, routing.Options{
+		Rules: []routing.Rule{{Name: "usd", Currencies: []string{"USD"}, Targets: []routing.Target{{Provider: "primary", Weight: 1}, {Provider: "backup"}}}},
+	})
+	require.NoError(t, err)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	logger, _ := newTestLogger()
+	r := mux.NewRouter()
+	handlers.RegisterRESTService(r, handlers.NewRoutedPaymentService(&store.PaymentStore{DB: db}, router, nil), logger)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(tenant.WithScope(r.Context(), tenant.Scope{TenantID: "acme"})))
+		})
+	})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM tenants").
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{}", nil, nil, nil, nil))
+	mock.ExpectQuery("INSERT INTO daily_quotas").
+		WithArgs("acme", 10.0).
+		WillReturnRows(sqlmock.NewRows([]string{"payments", "amount"}).AddRow(1, 10.0))
+	mock.ExpectExec("INSERT INTO payments").
+		WithArgs("acme", 42, 10.0, "USD", models.StatusCreated, "backup", "sim_000001", "settled", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO payment_routing_decisions \\(tenant_id, payment_id, rule, country, provider, attempts\\)").
+		WithArgs("acme", 42, "usd", "", "backup", []byte(`[{"provider":"primary","outcome":"declined:try_again_later"},{"provider":"backup","outcome":"authorized"}]`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	expectFetch(mock, 42, paymentRows(models.Payment{ID: 42, Amount: 10, Currency: "USD", TenantID: "acme", Status: models.StatusCreated,
+		Provider: "backup", ProviderRef: "sim_000001", ProviderStatus: "settled"}))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/payments", bytes.NewBufferString(`{"id":42,"amount":10,"currency":"USD"}`)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"provider":"backup"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,363 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-lang-final/internal/handlers"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/sepa"
+	"go-lang-final/internal/store"
+	"go-lang-final/internal/tenant"
+	"go-lang-final/proto"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var testDebtor = sepa.Party{Name: "Acme GmbH", IBAN: "DE89370400440532013000", BIC: "COBADEFFXXX"}
+
+func TestValidateIBAN(t *testing.T) {
+	cases := []struct {
+		name string
+		iban string
+		want string
+		err  error
+	}{
+		{"germany", "DE89370400440532013000", "DE89370400440532013000", nil},
+		{"print format", "de89 3704 0044 0532 0130 00", "DE89370400440532013000", nil},
+		{"united kingdom", "GB82WEST12345698765432", "GB82WEST12345698765432", nil},
+		{"france", "FR1420041010050500013M02606", "FR1420041010050500013M02606", nil},
+		{"bad check digits", "DE88370400440532013000", "", sepa.ErrInvalidIBAN},
+		{"wrong length", "DE8937040044053201300", "", sepa.ErrInvalidIBAN},
+		{"letters in numeric bban", "DE89370400440532O13000", "", sepa.ErrInvalidIBAN},
+		{"outside sepa", "BR1800360305000010009795493C1", "", sepa.ErrUnsupportedCountry},
+		{"empty", "", "", sepa.ErrInvalidIBAN},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			iban, err := sepa.ValidateIBAN(tc.iban)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, iban)
+		})
+	}
+}
+
+func TestValidateBIC(t *testing.T) {
+	for _, code := range []string{"COBADEFF", "COBADEFFXXX", "deutdeff500"} {
+		_, err := sepa.ValidateBIC(code)
+		assert.NoError(t, err, code)
+	}
+	for _, code := range []string{"", "COBADE", "COBA1EFF", "COBADEFFXX", "COBADE1F"} {
+		_, err := sepa.ValidateBIC(code)
+		assert.ErrorIs(t, err, sepa.ErrInvalidBIC, code)
+	}
+}
+
+func testBatch() sepa.Batch {
+	return sepa.Batch{
+		MessageID:     "ctb-000000000000000000000001",
+		CreatedAt:     time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC),
+		ExecutionDate: time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC),
+		Debtor:        testDebtor,
+		Transfers: []sepa.Transfer{
+			{EndToEndID: "ct-1", Amount: 1050, Creditor: sepa.Party{Name: "Jane Doe", IBAN: "FR1420041010050500013M02606"}, Remittance: "Refund of payment 7"},
+			{EndToEndID: "ct-2", Amount: 250000, Creditor: sepa.Party{Name: "Max Mustermann", IBAN: "DE89370400440532013000", BIC: "COBADEFF"}},
+		},
+	}
+}
+
+func TestPain001(t *testing.T) {
+	doc, err := testBatch().Pain001()
+	require.NoError(t, err)
+
+	xml := string(doc)
+	assert.True(t, strings.HasPrefix(xml, "<?xml"))
+	assert.Contains(t, xml, `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.001.001.03">`)
+	assert.Contains(t, xml, "<MsgId>ctb-000000000000000000000001</MsgId>")
+	assert.Contains(t, xml, "<CreDtTm>2026-05-01T12:00:00</CreDtTm>")
+	assert.Equal(t, 2, strings.Count(xml, "<NbOfTxs>2</NbOfTxs>"))
+	assert.Equal(t, 2, strings.Count(xml, "<CtrlSum>2510.50</CtrlSum>"))
+	assert.Contains(t, xml, "<ReqdExctnDt>2026-05-04</ReqdExctnDt>")
+	assert.Contains(t, xml, "<Cd>SEPA</Cd>")
+	assert.Contains(t, xml, "<ChrgBr>SLEV</ChrgBr>")
+	assert.Contains(t, xml, `<InstdAmt Ccy="EUR">10.50</InstdAmt>`)
+	assert.Contains(t, xml, "<EndToEndId>ct-2</EndToEndId>")
+	assert.Contains(t, xml, "<Ustrd>Refund of payment 7</Ustrd>")
+	// The first creditor has no BIC, so its agent is left out.
+	assert.Equal(t, 2, strings.Count(xml, "<BIC>"))
+}
+
+func TestPain001Validation(t *testing.T) {
+	batch := testBatch()
+	batch.MessageID = strings.Repeat("x", 36)
+	batch.Transfers[0].Amount = 0
+	batch.Transfers[1].EndToEndID = "ct-1"
+	batch.Transfers[1].Creditor.Name = "Zoë & Co"
+	batch.Transfers[1].Creditor.IBAN = "DE00370400440532013000"
+
+	_, err := batch.Pain001()
+	require.ErrorIs(t, err, sepa.ErrInvalidBatch)
+	for _, problem := range []string{"message id", "transfer 1 amount", "end-to-end id ct-1 is not unique", "transfer 2 creditor name", "transfer 2 creditor IBAN"} {
+		assert.Contains(t, err.Error(), problem)
+	}
+
+	_, err = sepa.Batch{MessageID: "m", CreatedAt: time.Now(), ExecutionDate: time.Now(), Debtor: testDebtor}.Pain001()
+	assert.ErrorIs(t, err, sepa.ErrInvalidBatch)
+}
+
+func TestRedactCreditors(t *testing.T) {
+	doc, err := testBatch().Pain001()
+	require.NoError(t, err)
+
+	redacted, err := sepa.RedactCreditors(doc, []string{"ct-2"})
+	require.NoError(t, err)
+	xml := string(redacted)
+	assert.Contains(t, xml, `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.001.001.03">`)
+	assert.NotContains(t, xml, "Max Mustermann")
+	assert.NotContains(t, xml, "<BIC>COBADEFF</BIC>")
+	assert.Contains(t, xml, "<Nm>Jane Doe</Nm>")
+	assert.Contains(t, xml, "<IBAN>FR1420041010050500013M02606</IBAN>")
+	// The debtor shares the IBAN but is not a creditor.
+	assert.Equal(t, 1, strings.Count(xml, "<IBAN>DE89370400440532013000</IBAN>"))
+	assert.Contains(t, xml, `<InstdAmt Ccy="EUR">2500.00</InstdAmt>`)
+
+	_, err = sepa.RedactCreditors([]byte("not xml"), nil)
+	assert.ErrorIs(t, err, sepa.ErrInvalidBatch)
+}
+
+func TestSEPAText(t *testing.T) {
+	assert.Equal(t, "Zoe Co", sepa.Text("Zoë & Co", 70))
+	assert.Equal(t, "Muenchen", sepa.Text("München", 70))
+	assert.Equal(t, "abc", sepa.Text("abcdef", 3))
+}
+
+const testPain002 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.002.001.03">
+  <CstmrPmtStsRpt>
+    <GrpHdr><MsgId>BANK-1</MsgId><CreDtTm>2026-05-04T08:00:00</CreDtTm></GrpHdr>
+    <OrgnlGrpInfAndSts>
+      <OrgnlMsgId>ctb-000000000000000000000001</OrgnlMsgId>
+      <OrgnlMsgNmId>pain.001.001.03</OrgnlMsgNmId>
+      <GrpSts>PART</GrpSts>
+    </OrgnlGrpInfAndSts>
+    <OrgnlPmtInfAndSts>
+      <OrgnlPmtInfId>ctb-000000000000000000000001</OrgnlPmtInfId>
+      <TxInfAndSts><OrgnlEndToEndId>ct-1</OrgnlEndToEndId><TxSts>ACSC</TxSts></TxInfAndSts>
+      <TxInfAndSts>
+        <OrgnlEndToEndId>ct-2</OrgnlEndToEndId><TxSts>RJCT</TxSts>
+        <StsRsnInf><Rsn><Cd>AC01</Cd></Rsn></StsRsnInf>
+      </TxInfAndSts>
+    </OrgnlPmtInfAndSts>
+  </CstmrPmtStsRpt>
+</Document>`
+
+func TestParsePain002(t *testing.T) {
+	report, err := sepa.ParsePain002([]byte(testPain002))
+	require.NoError(t, err)
+	assert.Equal(t, "BANK-1", report.MessageID)
+	assert.Equal(t, "ctb-000000000000000000000001", report.OriginalMessageID)
+	assert.Equal(t, sepa.StatusPartiallyAccepted, report.GroupStatus)
+	assert.Equal(t, []sepa.TransactionStatus{
+		{EndToEndID: "ct-1", Status: sepa.StatusAcceptedSettlement},
+		{EndToEndID: "ct-2", Status: sepa.StatusRejected, Reason: "AC01"},
+	}, report.Transactions)
+
+	_, err = sepa.ParsePain002([]byte(strings.ReplaceAll(testPain002, "pain.002.001.03", "pain.001.001.03")))
+	assert.ErrorIs(t, err, sepa.ErrInvalidReport)
+	_, err = sepa.ParsePain002([]byte("not xml"))
+	assert.ErrorIs(t, err, sepa.ErrInvalidReport)
+}
+
+func newTestBankService(t *testing.T) (*handlers.PaymentService, sqlmock.Sqlmock, context.Context) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	svc := handlers.NewPaymentService(&store.PaymentStore{DB: db}, nil)
+	return svc, mock, tenant.WithScope(context.Background(), tenant.Scope{TenantID: "acme"})
+}
+
+func TestCreateCreditTransferBatch(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+
+	_, err := svc.CreateCreditTransferBatch(ctx, &proto.CreateCreditTransferBatchRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	svc.EnableCreditTransfers(testDebtor)
+
+	executionDate := time.Now().UTC().AddDate(0, 0, 1).Truncate(24 * time.Hour)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT t.id, t.amount, t.remittance, a.holder_name, a.iban, a.bic FROM credit_transfers t").
+		WithArgs("acme", 5000).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "amount", "remittance", "holder_name", "iban", "bic"}).
+			AddRow("ct-1", 10.5, "Refund of payment 7", "Zoë Dupont", "FR1420041010050500013M02606", "").
+			AddRow("ct-2", 2500.0, "", "Max Mustermann", "DE89370400440532013000", "COBADEFF"))
+	mock.ExpectQuery("INSERT INTO credit_transfer_batches").
+		WithArgs("acme", sqlmock.AnyArg(), executionDate.Format("2006-01-02"), 2, 2510.5, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "transfers", "total", "execution_date", "bank_status", "tenant_id", "created_at"}).
+			AddRow("ctb-000000000000000000000001", 2, 2510.5, executionDate, "", "acme", vaultNow))
+	mock.ExpectExec("UPDATE credit_transfers SET status = 'submitted', batch_id = \\$2").
+		WithArgs("acme", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	batch, err := svc.CreateCreditTransferBatch(ctx, &proto.CreateCreditTransferBatchRequest{ExecutionDate: executionDate.Format("2006-01-02")})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), batch.GetTransfers())
+	assert.Equal(t, executionDate.Format("2006-01-02"), batch.GetExecutionDate())
+	assert.Contains(t, string(batch.GetDocument()), "<Nm>Zoe Dupont</Nm>")
+	assert.Contains(t, string(batch.GetDocument()), "<CtrlSum>2510.50</CtrlSum>")
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT t.id").WillReturnRows(sqlmock.NewRows([]string{"id", "amount", "remittance", "holder_name", "iban", "bic"}))
+	mock.ExpectRollback()
+
+	_, err = svc.CreateCreditTransferBatch(ctx, &proto.CreateCreditTransferBatchRequest{})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = svc.CreateCreditTransferBatch(ctx, &proto.CreateCreditTransferBatchRequest{ExecutionDate: "2020-01-01"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportPaymentStatusReport(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("UPDATE credit_transfer_batches SET bank_status").
+		WithArgs("acme", "ctb-000000000000000000000001", "PART").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "transfers", "total", "execution_date", "bank_status", "tenant_id", "created_at"}).
+			AddRow("ctb-000000000000000000000001", 2, 2510.5, vaultNow, "PART", "acme", vaultNow))
+	mock.ExpectExec("UPDATE credit_transfers SET status = \\$4, reason = \\$5").
+		WithArgs("acme", "ctb-000000000000000000000001", "ct-1", "settled", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE credit_transfers SET status = \\$4, reason = \\$5").
+		WithArgs("acme", "ctb-000000000000000000000001", "ct-2", "rejected", "AC01").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	res, err := svc.ImportPaymentStatusReport(ctx, &proto.ImportPaymentStatusReportRequest{Document: []byte(testPain002)})
+	require.NoError(t, err)
+	assert.Equal(t, "ctb-000000000000000000000001", res.GetBatchId())
+	assert.Equal(t, "PART", res.GetGroupStatus())
+	assert.Equal(t, int32(2), res.GetUpdated())
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("UPDATE credit_transfer_batches SET bank_status").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "transfers", "total", "execution_date", "bank_status", "tenant_id", "created_at"}))
+	mock.ExpectRollback()
+
+	_, err = svc.ImportPaymentStatusReport(ctx, &proto.ImportPaymentStatusReportRequest{Document: []byte(testPain002)})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = svc.ImportPaymentStatusReport(ctx, &proto.ImportPaymentStatusReportRequest{Document: []byte("<Document/>")})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBankTransferRefundQueuesCreditTransfer(t *testing.T) {
+	s, mock, ctx := newTestBankStore(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT amount, status, refunded_amount, provider, provider_ref, provider_status FROM payments").
+		WithArgs("acme", int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"amount", "status", "refunded_amount", "provider", "provider_ref", "provider_status"}).
+			AddRow(100.0, "created", 0.0, "", "", ""))
+	mock.ExpectExec("SELECT set_config\\('app.event_reason'").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("UPDATE payments SET refunded_amount").
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "id", "amount", "currency", "status", "refunded_amount", "provider", "provider_ref", "provider_status", "payment_method", "metadata", "description", "statement_descriptor"}).
+			AddRow("acme", 7, 100.0, "EUR", "partially_refunded", 40.0, "", "", "", "ba_000000000000000000000001", []byte("{}"), "", ""))
+	mock.ExpectQuery("INSERT INTO credit_transfers").
+		WithArgs("acme", sqlmock.AnyArg(), "ba_000000000000000000000001", sqlmock.AnyArg(), "refund", 40.0, "EUR", "Refund of payment 7 damaged").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "tenant_id", "created_at"}).AddRow("ct-1", "pending", "acme", vaultNow))
+	mock.ExpectCommit()
+
+	payment, err := s.RefundPayment(ctx, 7, 40, "damaged", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "partially_refunded", payment.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func newTestBankStore(t *testing.T) (*store.PaymentStore, sqlmock.Sqlmock, context.Context) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return &store.PaymentStore{DB: db}, mock, tenant.WithScope(context.Background(), tenant.Scope{TenantID: "acme"})
+}
+
+func TestBankTransferPaymentMustBeEUR(t *testing.T) {
+	s, mock, ctx := newTestBankStore(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT allowed_currencies, max_payment_amount, max_payments, daily_payment_limit, daily_amount_limit FROM tenants").
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).
+			AddRow("{}", nil, nil, nil, nil))
+	mock.ExpectQuery("INSERT INTO daily_quotas").
+		WillReturnRows(sqlmock.NewRows([]string{"payments", "amount"}).AddRow(1, 10.0))
+	mock.ExpectRollback()
+
+	err := s.CreatePayment(ctx, models.Payment{ID: 7, Amount: 10, Currency: "USD", PaymentMethod: "ba_000000000000000000000001"})
+	assert.ErrorIs(t, err, store.ErrCurrencyNotSEPA)
+	assert.True(t, store.Rejected(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestV2BankAccounts(t *testing.T) {
+	svc, mock, _ := newTestBankService(t)
+	logger, _ := newTestLogger()
+	r := mux.NewRouter()
+	handlers.RegisterRESTService(r, svc, logger)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(tenant.WithScope(r.Context(), tenant.Scope{TenantID: "acme"})))
+		})
+	})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("INSERT INTO bank_accounts \\(tenant_id, token, holder_name, iban, bic\\)").
+		WithArgs("acme", sqlmock.AnyArg(), "Jane Doe", "DE89370400440532013000", "COBADEFFXXX").
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "created_at"}).AddRow("acme", vaultNow))
+	mock.ExpectCommit()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/bank_accounts", bytes.NewBufferString(`{"holder_name":"Jane Doe","iban":"DE89 3704 0044 0532 0130 00","bic":"cobadeffxxx"}`)))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "0532013000")
+
+	var account map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &account))
+	assert.Regexp(t, "^ba_[0-9a-f]{24}$", account["token"])
+	assert.Equal(t, "/v2/bank_accounts/"+account["token"].(string), rec.Header().Get("Location"))
+	assert.Equal(t, "DE", account["country"])
+	assert.Equal(t, "3000", account["last4"])
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/bank_accounts", bytes.NewBufferString(`{"holder_name":"Jane Doe","iban":"DE88370400440532013000"}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/payouts", bytes.NewBufferString(`{"bank_account":"ba_000000000000000000000001","amount":10,"currency":"USD"}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,286 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-lang-final/internal/handlers"
+	"go-lang-final/internal/settlement"
+	"go-lang-final/internal/tenant"
+	"go-lang-final/proto"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testSettlementFile = "\ufeffTransaction;Kind;Amount;Fee;Net;Ccy;Date\n" +
+	"sim_1;Charge;100.00;2.90;97.10;eur;01.10.2026\n" +
+	"sim_2;Sale;50.00;1.00;49.00;EUR;01.10.2026\n" +
+	"sim_3;Charge;20.00;0.58;19.42;EUR;01.10.2026\n" +
+	"sim_1;Refund;10.00;0.00;-10.00;EUR;02.10.2026\n" +
+	"Payout;Transfer;-200.00;0.00;-200.00;EUR;02.10.2026\n" +
+	"sim_9;Charge;5.00;0.15;4.85;EUR;02.10.2026\n"
+
+func testSettlementParser(t *testing.T) settlement.Parser {
+	p, err := settlement.NewParser("csv", settlement.Options{
+		Delimiter:  ";",
+		DateFormat: "02.01.2006",
+		Columns: map[string]string{
+			settlement.FieldReference: "Transaction",
+			settlement.FieldType:      "Kind",
+			settlement.FieldGross:     "Amount",
+			settlement.FieldCurrency:  "Ccy",
+		},
+		Types: map[string]string{"charge": settlement.TypeCharge, "sale": settlement.TypeCharge, "refund": settlement.TypeRefund},
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func TestParseSettlementCSV(t *testing.T) {
+	lines, err := testSettlementParser(t).Parse([]byte(testSettlementFile))
+	require.NoError(t, err)
+	require.Len(t, lines, 5)
+	assert.Equal(t, settlement.Line{
+		Number: 1, Type: settlement.TypeCharge, Reference: "sim_1", Gross: 10000, Fee: 290, Net: 9710, Currency: "EUR",
+		Date: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+	}, lines[0])
+	assert.Equal(t, settlement.TypeRefund, lines[3].Type)
+	assert.Equal(t, int64(-1000), lines[3].Gross)
+	assert.Equal(t, int64(-1000), lines[3].Net)
+	assert.Equal(t, 5, lines[4].Number)
+	assert.Equal(t, "sim_9", lines[4].Reference)
+
+	// Without a type column negative amounts are refunds.
+	p, err := settlement.NewParser("", settlement.Options{})
+	require.NoError(t, err)
+	lines, err = p.Parse([]byte("payment_id,gross,fee,currency\n7,-5.5,-0.10,USD\n"))
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.Equal(t, settlement.Line{Number: 1, Type: settlement.TypeRefund, PaymentID: 7, Gross: -550, Fee: 10, Net: -560, Currency: "USD"}, lines[0])
+
+	for name, file := range map[string]string{
+		"empty":            "",
+		"no fee column":    "reference,gross,currency\nsim_1,1.00,EUR\n",
+		"no reference":     "gross,fee,currency\n1.00,0.10,EUR\n",
+		"net mismatch":     "reference,gross,fee,net,currency\nsim_1,1.00,0.10,0.95,EUR\n",
+		"three decimals":   "reference,gross,fee,currency\nsim_1,1.005,0.10,EUR\n",
+		"invalid currency": "reference,gross,fee,currency\nsim_1,1.00,0.10,EURO\n",
+		"invalid id":       "payment_id,gross,fee,currency\nabc,1.00,0.10,EUR\n",
+	} {
+		_, err := p.Parse([]byte(file))
+		assert.ErrorIs(t, err, settlement.ErrInvalidReport, name)
+	}
+
+	_, err = settlement.NewParser("xlsx", settlement.Options{})
+	assert.ErrorIs(t, err, settlement.ErrUnknownFormat)
+	_, err = settlement.NewParser("csv", settlement.Options{Columns: map[string]string{"amount": "Amount"}})
+	assert.Error(t, err)
+	_, err = settlement.NewParser("csv", settlement.Options{Types: map[string]string{"payout": "transfer"}})
+	assert.Error(t, err)
+}
+
+func TestCheckSettlement(t *testing.T) {
+	payments := []settlement.Payment{
+		{ID: 1, Ref: "sim_1", Amount: 10000, Refunded: 1000, Currency: "EUR", Expected: true},
+		{ID: 2, Ref: "sim_2", Amount: 5000, Currency: "EUR", Expected: true, Settled: true},
+		{ID: 3, Ref: "sim_3", Amount: 2000, Currency: "EUR", Expected: true},
+		{ID: 4, Ref: "sim_4", Amount: 3000, Currency: "EUR", Expected: true},
+		{ID: 5, Ref: "sim_5", Amount: 3000, Currency: "EUR", Expected: true, Settled: true},
+	}
+	lines := []settlement.Line{
+		{Type: settlement.TypeCharge, Reference: "sim_1", Gross: 10000, Fee: 291, Currency: "EUR"},
+		{Type: settlement.TypeCharge, Reference: "sim_2", Gross: 5000, Fee: 145, Currency: "EUR"},
+		{Type: settlement.TypeCharge, PaymentID: 3, Gross: 2000, Fee: 100, Currency: "EUR"},
+		{Type: settlement.TypeRefund, Reference: "sim_1", Gross: -2000, Currency: "EUR"},
+		{Type: settlement.TypeRefund, Reference: "sim_1", Gross: -1000, Currency: "EUR"},
+		{Type: settlement.TypeRefund, Reference: "sim_1", Gross: -1000, Currency: "EUR"},
+		{Type: settlement.TypeCharge, Reference: "sim_1", Gross: 10000, Fee: 290, Currency: "EUR"},
+		{Type: settlement.TypeCharge, Reference: "sim_8", Gross: 100, Fee: 3, Currency: "EUR"},
+	}
+
+	results, missing := settlement.Check(lines, payments, settlement.Rate{Percent: 2.9}, 1)
+	statuses := make([]string, len(results))
+	for i, r := range results {
+		statuses[i] = r.Status
+	}
+	assert.Equal(t, []string{
+		settlement.Matched, settlement.Duplicated, settlement.FeeDeviation, settlement.AmountMismatch,
+		settlement.Matched, settlement.Duplicated, settlement.Duplicated, settlement.Unexpected,
+	}, statuses)
+	assert.Equal(t, int64(290), results[0].ExpectedFee)
+	assert.Equal(t, int64(58), results[2].ExpectedFee)
+	assert.Nil(t, results[7].Payment)
+	require.Len(t, missing, 1)
+	assert.Equal(t, int64(4), missing[0].ID)
+
+	assert.Equal(t, int64(55), settlement.Rate{Percent: 1.5, Fixed: 25}.Fee(2000))
+}
+
+var settlementPaymentColumns = []string{"id", "provider_ref", "amount", "refunded_amount", "currency", "expected", "settled"}
+
+func newTestSettlementService(t *testing.T) (*handlers.PaymentService, sqlmock.Sqlmock, context.Context) {
+	svc, mock, ctx := newTestBankService(t)
+	svc.ConfigureSettlements(map[string]handlers.SettlementFormat{
+		"simulator": {Parser: testSettlementParser(t), Rate: settlement.Rate{Percent: 2.9}, Tolerance: 1},
+	}, 3)
+	return svc, mock, ctx
+}
+
+func TestImportSettlementReport(t *testing.T) {
+	svc, mock, ctx := newTestSettlementService(t)
+
+	_, err := svc.ImportSettlementReport(ctx, &proto.ImportSettlementReportRequest{Provider: "acquirer", Reference: "S1"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = svc.ImportSettlementReport(ctx, &proto.ImportSettlementReportRequest{Provider: "simulator", Reference: "S1", Document: []byte("x")})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT p.id, p.provider_ref, p.amount, p.refunded_amount, p.currency").
+		WithArgs("acme", "simulator", sqlmock.AnyArg(), sqlmock.AnyArg(), "EUR",
+			time.Date(2026, 9, 29, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 3, 0, 0, 0, 0, time.UTC)).
+		WillReturnRows(sqlmock.NewRows(settlementPaymentColumns).
+			AddRow(1, "sim_1", 100.0, 10.0, "EUR", true, false).
+			AddRow(2, "sim_2", 50.0, 0.0, "EUR", true, false).
+			AddRow(3, "sim_3", 25.0, 0.0, "EUR", true, false).
+			AddRow(4, "sim_4", 30.0, 0.0, "EUR", true, false))
+	mock.ExpectQuery("INSERT INTO settlement_batches").
+		WithArgs("acme", sqlmock.AnyArg(), "simulator", "S-2026-10-02", "2026-10-02", "EUR", 5, 2, 1, 1, 0, 1, 1,
+			165.0, 4.63, 160.37, 5.08).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "created_at"}).AddRow("acme", vaultNow))
+	fees := []struct {
+		payment interface{}
+		fee     float64
+	}{{int64(1), 2.9}, {int64(2), 1}, {int64(3), 0.58}, {nil, 0.15}}
+	for _, f := range fees {
+		mock.ExpectExec("INSERT INTO ledger_postings").
+			WithArgs("acme", sqlmock.AnyArg(), "provider_fees:simulator", f.fee, "EUR", f.payment, "settlement", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO ledger_postings").
+			WithArgs("acme", sqlmock.AnyArg(), "provider_balance:simulator", -f.fee, "EUR", f.payment, "settlement", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	items := []struct {
+		typ, ref, status string
+		payment          interface{}
+		gross            float64
+	}{
+		{"charge", "sim_1", "matched", int64(1), 100},
+		{"charge", "sim_2", "fee_deviation", int64(2), 50},
+		{"charge", "sim_3", "amount_mismatch", int64(3), 20},
+		{"refund", "sim_1", "matched", int64(1), -10},
+		{"charge", "sim_9", "unexpected", nil, 5},
+		{"charge", "sim_4", "missing", int64(4), 30},
+	}
+	for i, item := range items {
+		mock.ExpectExec("INSERT INTO settlement_lines").
+			WithArgs("acme", sqlmock.AnyArg(), i+1, item.typ, item.ref, item.payment, "EUR", item.gross,
+				sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), item.status).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	batch, err := svc.ImportSettlementReport(ctx, &proto.ImportSettlementReportRequest{
+		Provider: "simulator", Reference: "S-2026-10-02", Document: []byte(testSettlementFile),
+	})
+	require.NoError(t, err)
+	assert.Regexp(t, "^stb_[0-9a-f]{24}$", batch.GetId())
+	assert.Equal(t, "2026-10-02", batch.GetSettlementDate())
+	assert.Equal(t, int32(1), batch.GetMissing())
+	require.Len(t, batch.GetLines(), 6)
+	assert.Equal(t, 1.45, batch.GetLines()[1].GetExpectedFee())
+	assert.Equal(t, 0.87, batch.GetLines()[5].GetExpectedFee())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportSettlementReportTwice(t *testing.T) {
+	svc, mock, ctx := newTestSettlementService(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT p.id, p.provider_ref").
+		WithArgs("acme", "simulator", sqlmock.AnyArg(), sqlmock.AnyArg(), "EUR",
+			time.Date(2026, 9, 28, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC)).
+		WillReturnRows(sqlmock.NewRows(settlementPaymentColumns))
+	mock.ExpectQuery("INSERT INTO settlement_batches").WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "created_at"}))
+	mock.ExpectRollback()
+
+	_, err := svc.ImportSettlementReport(ctx, &proto.ImportSettlementReportRequest{
+		Provider: "simulator", Reference: "S-2026-10-01", SettlementDate: "2026-10-01", Document: []byte(testSettlementFile),
+	})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+const testBatchID = "stb_00000000000000000000000a"
+
+func TestV2SettlementBatches(t *testing.T) {
+	svc, mock, _ := newTestSettlementService(t)
+	logger, _ := newTestLogger()
+	r := mux.NewRouter()
+	handlers.RegisterRESTService(r, svc, logger)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(tenant.WithScope(r.Context(), tenant.Scope{TenantID: "acme"})))
+		})
+	})
+
+	batchColumns := []string{"id", "provider", "reference", "settlement_date", "currency", "lines", "matched", "unexpected", "missing",
+		"duplicated", "amount_mismatches", "fee_deviations", "gross", "fees", "net", "expected_fees", "tenant_id", "created_at"}
+	settlementLineColumns := []string{"line", "type", "reference", "payment_id", "currency", "gross", "fee", "net", "expected_fee", "status"}
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT id, provider, reference, settlement_date").WithArgs("acme", testBatchID).
+		WillReturnRows(sqlmock.NewRows(batchColumns).AddRow(testBatchID, "simulator", "S-2026-10-02", time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC),
+			"EUR", 2, 1, 0, 1, 0, 0, 0, 100.0, 2.9, 97.1, 2.9, "acme", vaultNow))
+	mock.ExpectQuery("SELECT line, type, reference, payment_id").WithArgs("acme", testBatchID, "discrepancies").
+		WillReturnRows(sqlmock.NewRows(settlementLineColumns).AddRow(2, "charge", "sim_4", 4, "EUR", 30.0, 0.0, 0.0, 0.87, "missing"))
+	mock.ExpectCommit()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/settlement_batches/"+testBatchID+"/report", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+	reader := csv.NewReader(rec.Body)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, "2026-10-02", records[1][2])
+	assert.Equal(t, []string{"2", "missing", "charge", "sim_4", "4", "30.00", "0.00", "0.87", "0.00"}, records[3])
+
+	postingColumns := []string{"id", "entry_id", "account", "amount", "currency", "payment_id", "source", "source_id", "description",
+		"tenant_id", "created_at"}
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT id, entry_id, account, amount").WithArgs("acme", int64(1), "", 2, 0).
+		WillReturnRows(sqlmock.NewRows(postingColumns).
+			AddRow(1, "le_1", "provider_fees:simulator", 2.9, "EUR", 1, "settlement", testBatchID, "fee", "acme", vaultNow).
+			AddRow(2, "le_1", "provider_balance:simulator", -2.9, "EUR", 1, "settlement", testBatchID, "fee", "acme", vaultNow))
+	mock.ExpectCommit()
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/ledger_postings?payment_id=1&page_size=2", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var envelope handlers.LedgerListEnvelope
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &envelope))
+	require.Len(t, envelope.Data, 2)
+	assert.Equal(t, -2.9, envelope.Data[1].Amount)
+	assert.NotEmpty(t, envelope.Links.Next)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/settlement_batches?provider=simulator", bytes.NewBufferString(testSettlementFile)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
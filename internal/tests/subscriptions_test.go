@@ -0,0 +1,412 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-lang-final/internal/handlers"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/subscriptions"
+	"go-lang-final/internal/tenant"
+	"go-lang-final/internal/validation"
+	"go-lang-final/proto"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPlanID         = "sp_00000000000000000000000a"
+	testSubscriptionID = "sub_00000000000000000000000a"
+	testChargeID       = "sch_00000000000000000000000a"
+	testCardToken      = "pm_00000000000000000000000a"
+)
+
+var subscriptionAnchor = time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC)
+
+func TestSubscriptionPeriods(t *testing.T) {
+	// Monthly periods keep the anchor's day where the month has it.
+	for n, want := range []time.Time{
+		subscriptionAnchor,
+		time.Date(2026, 2, 28, 9, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 31, 9, 0, 0, 0, time.UTC),
+		time.Date(2026, 4, 30, 9, 0, 0, 0, time.UTC),
+	} {
+		assert.Equal(t, want, subscriptions.PeriodEnd(subscriptionAnchor, subscriptions.IntervalMonth, 1, n))
+	}
+	leap := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC), subscriptions.PeriodEnd(leap, subscriptions.IntervalYear, 1, 1))
+	assert.Equal(t, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC), subscriptions.PeriodEnd(leap, subscriptions.IntervalYear, 2, 2))
+	assert.Equal(t, leap.AddDate(0, 0, 28), subscriptions.PeriodEnd(leap, subscriptions.IntervalWeek, 2, 2))
+	assert.Equal(t, leap.AddDate(0, 0, 3), subscriptions.PeriodEnd(leap, subscriptions.IntervalDay, 3, 1))
+
+	var sub models.Subscription
+	subscriptions.Start(&sub, models.SubscriptionPlan{ID: testPlanID, TrialDays: 14}, subscriptionAnchor)
+	trialEnd := subscriptionAnchor.AddDate(0, 0, 14)
+	assert.Equal(t, models.SubscriptionTrialing, sub.Status)
+	assert.Equal(t, &trialEnd, sub.TrialEnd)
+	assert.Equal(t, &trialEnd, sub.NextChargeAt)
+	assert.Equal(t, trialEnd, sub.Anchor)
+
+	subscriptions.Start(&sub, models.SubscriptionPlan{ID: testPlanID}, subscriptionAnchor)
+	assert.Equal(t, models.SubscriptionActive, sub.Status)
+	assert.Nil(t, sub.TrialEnd)
+	assert.Equal(t, &subscriptionAnchor, sub.NextChargeAt)
+}
+
+func TestProrate(t *testing.T) {
+	start := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 30)
+	half := start.AddDate(0, 0, 15)
+
+	assert.Equal(t, int64(500), subscriptions.Prorate(1000, 2000, start, end, end, half))
+	assert.Equal(t, int64(-500), subscriptions.Prorate(2000, 1000, start, end, end, half))
+	assert.Equal(t, int64(0), subscriptions.Prorate(1000, 2000, start, end, end, end))
+	// A yearly plan is charged at its own rate for the rest of the month.
+	assert.Equal(t, int64(500), subscriptions.Prorate(1000, 24000, start, end, start.AddDate(0, 0, 360), half))
+	// Half a cent rounds away from zero either way.
+	assert.Equal(t, int64(1), subscriptions.Prorate(0, 1, start, start.AddDate(0, 0, 2), start.AddDate(0, 0, 2), start.AddDate(0, 0, 1)))
+	assert.Equal(t, int64(-1), subscriptions.Prorate(1, 0, start, start.AddDate(0, 0, 2), start.AddDate(0, 0, 2), start.AddDate(0, 0, 1)))
+
+	for _, tc := range []struct{ balance, charge, rest int64 }{
+		{250, 1250, 0},
+		{-300, 700, 0},
+		{-1500, 0, -500},
+	} {
+		charge, rest := subscriptions.Due(1000, tc.balance)
+		assert.Equal(t, tc.charge, charge)
+		assert.Equal(t, tc.rest, rest)
+	}
+
+	wait, ok := subscriptions.Retry(subscriptions.DefaultRetries, 1)
+	assert.True(t, ok)
+	assert.Equal(t, 24*time.Hour, wait)
+	_, ok = subscriptions.Retry(subscriptions.DefaultRetries, len(subscriptions.DefaultRetries)+1)
+	assert.False(t, ok)
+}
+
+func TestValidateSubscriptionPlan(t *testing.T) {
+	plan := models.SubscriptionPlan{Name: "pro", Interval: subscriptions.IntervalMonth, IntervalCount: 1, Amount: 9.99, Currency: "USD"}
+	require.NoError(t, subscriptions.Validate(plan))
+
+	for name, change := range map[string]func(*models.SubscriptionPlan){
+		"unknown interval": func(p *models.SubscriptionPlan) { p.Interval = "fortnight" },
+		"no count":         func(p *models.SubscriptionPlan) { p.IntervalCount = 0 },
+		"free":             func(p *models.SubscriptionPlan) { p.Amount = 0 },
+		"long trial":       func(p *models.SubscriptionPlan) { p.TrialDays = subscriptions.MaxTrialDays + 1 },
+	} {
+		plan := plan
+		change(&plan)
+		assert.ErrorIs(t, subscriptions.Validate(plan), subscriptions.ErrInvalidPlan, name)
+	}
+
+	v := validation.Check(&proto.CreateSubscriptionRequest{PlanId: "pro", PaymentMethod: "4242"})
+	fields := map[string]bool{}
+	for _, violation := range v {
+		fields[violation.GetField()] = true
+	}
+	assert.Equal(t, map[string]bool{"plan_id": true, "payment_method": true}, fields)
+}
+
+func subscriptionRows(status string, attempts int, next time.Time) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "tenant_id", "plan_id", "payment_method", "customer_id", "status", "current_period_start", "current_period_end", "trial_end",
+		"next_charge_at", "attempts", "balance", "cancel_at_period_end", "canceled_at", "renewals", "anchor", "periods", "created_at", "updated_at"}).
+		AddRow(testSubscriptionID, "acme", testPlanID, testCardToken, nil, status, subscriptionAnchor, subscriptionAnchor, nil,
+			next, attempts, 0.0, false, nil, 0, subscriptionAnchor, 0, subscriptionAnchor, subscriptionAnchor)
+}
+
+func subscriptionPlanRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "name", "interval", "interval_count", "amount", "currency", "trial_days", "created_at"}).
+		AddRow(testPlanID, "pro", "month", 1, 9.99, "USD", 0, subscriptionAnchor)
+}
+
+func subscriptionChargeRows(attempt int, status string) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "subscription_id", "period", "attempt", "payment_id", "amount", "currency", "status", "reason", "created_at", "updated_at"}).
+		AddRow(testChargeID, testSubscriptionID, 1, attempt, 7, 9.99, "USD", status, "", subscriptionAnchor, subscriptionAnchor)
+}
+
+// expectDueSubscription expects a pass to find the test subscription due
+// and claim its renewal, with the charge of attempt either on record with
+// a payment of paymentStatus or, when paymentStatus is empty, recorded now.
+func expectDueSubscription(mock sqlmock.Sqlmock, status string, attempts int, paymentStatus string) {
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("", "on").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT tenant_id, id FROM subscriptions").
+		WithArgs("", sqlmock.AnyArg(), 100).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "id"}).AddRow("acme", testSubscriptionID))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM subscriptions WHERE .* FOR UPDATE SKIP LOCKED").
+		WithArgs("acme", testSubscriptionID, sqlmock.AnyArg()).
+		WillReturnRows(subscriptionRows(status, attempts, subscriptionAnchor))
+	existing := mock.ExpectQuery("FROM subscription_charges c").WithArgs("acme", testSubscriptionID, 1, attempts+1, "subscription_charge:")
+	if paymentStatus != "" {
+		existing.WillReturnRows(sqlmock.NewRows([]string{"id", "subscription_id", "period", "attempt", "payment_id", "amount", "currency", "status", "reason", "created_at", "updated_at", "payment_status"}).
+			AddRow(testChargeID, testSubscriptionID, 1, attempts+1, 7, 9.99, "USD", "pending", "", subscriptionAnchor, subscriptionAnchor, paymentStatus))
+	} else {
+		existing.WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectQuery("FROM subscription_plans").WithArgs("acme", testPlanID).WillReturnRows(subscriptionPlanRows())
+		mock.ExpectExec("SELECT id FROM tenants WHERE id = \\$1 FOR UPDATE").WithArgs("acme").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT GREATEST").WithArgs("acme").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectQuery("INSERT INTO subscription_charges").
+			WithArgs("acme", sqlmock.AnyArg(), testSubscriptionID, 1, attempts+1, int64(7), 9.99, "USD", "pending").
+			WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at"}).AddRow(subscriptionAnchor, subscriptionAnchor))
+	}
+	mock.ExpectExec("UPDATE subscriptions SET next_charge_at").
+		WithArgs("acme", testSubscriptionID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+// expectChargePayment expects the payment of the renewal to be created
+// under the charge's idempotency key.
+func expectChargePayment(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT request_hash FROM idempotency_keys").
+		WithArgs("acme", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(nil))
+	mock.ExpectQuery("FROM tenants").
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{}", nil, nil, nil, nil))
+	mock.ExpectQuery("INSERT INTO daily_quotas").
+		WithArgs("acme", 9.99).
+		WillReturnRows(sqlmock.NewRows([]string{"payments", "amount"}).AddRow(1, 9.99))
+}
+
+func TestChargeSubscriptions(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+	ctx = tenant.WithScope(ctx, tenant.Scope{All: true})
+
+	expectDueSubscription(mock, models.SubscriptionActive, 0, "")
+	expectChargePayment(mock)
+	mock.ExpectQuery("SELECT 1 FROM payment_methods").WithArgs("acme", testCardToken).WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO payments").
+		WithArgs("acme", 7, 9.99, "USD", "created", "", "", "", testCardToken).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO idempotency_keys").
+		WithArgs("acme", sqlmock.AnyArg(), sqlmock.AnyArg(), 7).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	// The payment went through, so the next period ends a month after the
+	// anchor, on the last day of February.
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("UPDATE subscription_charges SET status").
+		WithArgs("acme", sqlmock.AnyArg(), "succeeded", "").
+		WillReturnRows(subscriptionChargeRows(1, "succeeded"))
+	mock.ExpectQuery("FROM subscriptions WHERE tenant_id = \\$1 AND id = \\$2 FOR UPDATE").
+		WithArgs("acme", testSubscriptionID).
+		WillReturnRows(subscriptionRows(models.SubscriptionActive, 0, subscriptionAnchor))
+	mock.ExpectQuery("FROM subscription_plans").WithArgs("acme", testPlanID).WillReturnRows(subscriptionPlanRows())
+	mock.ExpectExec("UPDATE subscriptions SET status = 'active'").
+		WithArgs("acme", testSubscriptionID, 1, time.Date(2026, 2, 28, 9, 0, 0, 0, time.UTC), 0.0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	settled, err := svc.ChargeSubscriptions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, settled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChargeSubscriptionsAfterRestart(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+	ctx = tenant.WithScope(ctx, tenant.Scope{All: true})
+
+	// A pass died after creating the payment; the next one records it
+	// instead of charging again.
+	expectDueSubscription(mock, models.SubscriptionActive, 0, models.StatusCreated)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("UPDATE subscription_charges SET status").
+		WithArgs("acme", testChargeID, "succeeded", "").
+		WillReturnRows(subscriptionChargeRows(1, "succeeded"))
+	mock.ExpectQuery("FROM subscriptions WHERE tenant_id = \\$1 AND id = \\$2 FOR UPDATE").
+		WithArgs("acme", testSubscriptionID).
+		WillReturnRows(subscriptionRows(models.SubscriptionActive, 0, subscriptionAnchor))
+	mock.ExpectQuery("FROM subscription_plans").WithArgs("acme", testPlanID).WillReturnRows(subscriptionPlanRows())
+	mock.ExpectExec("UPDATE subscriptions SET status = 'active'").
+		WithArgs("acme", testSubscriptionID, 1, time.Date(2026, 2, 28, 9, 0, 0, 0, time.UTC), 0.0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	settled, err := svc.ChargeSubscriptions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, settled)
+
+	// A payment still waiting for its challenge leaves the charge pending.
+	expectDueSubscription(mock, models.SubscriptionActive, 0, models.StatusRequiresAction)
+	settled, err = svc.ChargeSubscriptions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, settled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChargeSubscriptionsPaymentIDTaken(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+	ctx = tenant.WithScope(ctx, tenant.Scope{All: true})
+
+	expectTakenPayment := func(id int64) {
+		expectChargePayment(mock)
+		mock.ExpectQuery("SELECT 1 FROM payment_methods").WithArgs("acme", testCardToken).WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+		mock.ExpectExec("INSERT INTO payments").
+			WithArgs("acme", id, 9.99, "USD", "created", "", "", "", testCardToken).
+			WillReturnError(&pq.Error{Code: "23505", Constraint: "payments_pkey"})
+		mock.ExpectRollback()
+	}
+	expectReallocation := func(from, to int64) {
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT payment_id, EXISTS \\(SELECT 1 FROM idempotency_keys").
+			WithArgs("acme", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"payment_id", "stored"}).AddRow(from, false))
+		mock.ExpectExec("SELECT id FROM tenants WHERE id = \\$1 FOR UPDATE").WithArgs("acme").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT GREATEST").WithArgs("acme").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(to))
+		mock.ExpectExec("UPDATE subscription_charges SET payment_id = \\$3").
+			WithArgs("acme", sqlmock.AnyArg(), to).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+
+	// A client created payment 7 before the renewal's payment was stored;
+	// the charge moves to payment 8 and goes through.
+	expectDueSubscription(mock, models.SubscriptionActive, 0, "")
+	expectTakenPayment(7)
+	expectReallocation(7, 8)
+	expectChargePayment(mock)
+	mock.ExpectQuery("SELECT 1 FROM payment_methods").WithArgs("acme", testCardToken).WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO payments").
+		WithArgs("acme", 8, 9.99, "USD", "created", "", "", "", testCardToken).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO idempotency_keys").
+		WithArgs("acme", sqlmock.AnyArg(), sqlmock.AnyArg(), 8).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("UPDATE subscription_charges SET status").
+		WithArgs("acme", sqlmock.AnyArg(), "succeeded", "").
+		WillReturnRows(subscriptionChargeRows(1, "succeeded"))
+	mock.ExpectQuery("FROM subscriptions WHERE tenant_id = \\$1 AND id = \\$2 FOR UPDATE").
+		WithArgs("acme", testSubscriptionID).
+		WillReturnRows(subscriptionRows(models.SubscriptionActive, 0, subscriptionAnchor))
+	mock.ExpectQuery("FROM subscription_plans").WithArgs("acme", testPlanID).WillReturnRows(subscriptionPlanRows())
+	mock.ExpectExec("UPDATE subscriptions SET status = 'active'").
+		WithArgs("acme", testSubscriptionID, 1, time.Date(2026, 2, 28, 9, 0, 0, 0, time.UTC), 0.0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	settled, err := svc.ChargeSubscriptions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, settled)
+
+	// A conflict that persists is no decline: the charge stays pending for
+	// the next pass instead of sending the subscription to dunning.
+	expectDueSubscription(mock, models.SubscriptionActive, 0, "")
+	expectTakenPayment(7)
+	expectReallocation(7, 8)
+	expectTakenPayment(8)
+	settled, err = svc.ChargeSubscriptions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, settled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChargeSubscriptionsDunning(t *testing.T) {
+	svc, mock, ctx := newTestBankService(t)
+	ctx = tenant.WithScope(ctx, tenant.Scope{All: true})
+	svc.ConfigureSubscriptions(time.Minute, []time.Duration{time.Hour})
+
+	expectDeclined := func(attempts int) {
+		expectDueSubscription(mock, models.SubscriptionPastDue, attempts, "")
+		expectChargePayment(mock)
+		mock.ExpectQuery("SELECT 1 FROM payment_methods").WithArgs("acme", testCardToken).WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectRollback()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("UPDATE subscription_charges SET status").
+			WithArgs("acme", sqlmock.AnyArg(), "failed", "payment method not found").
+			WillReturnRows(subscriptionChargeRows(attempts+1, "failed"))
+		mock.ExpectQuery("FROM subscriptions WHERE tenant_id = \\$1 AND id = \\$2 FOR UPDATE").
+			WithArgs("acme", testSubscriptionID).
+			WillReturnRows(subscriptionRows(models.SubscriptionPastDue, attempts, subscriptionAnchor))
+	}
+
+	// The first failure is retried an hour later.
+	expectDeclined(0)
+	mock.ExpectExec("UPDATE subscriptions SET status = 'past_due'").
+		WithArgs("acme", testSubscriptionID, 1, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	settled, err := svc.ChargeSubscriptions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, settled)
+
+	// The second has no retry left and cancels the subscription.
+	expectDeclined(1)
+	mock.ExpectExec("UPDATE subscriptions SET status = 'canceled'").
+		WithArgs("acme", testSubscriptionID, 2, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	settled, err = svc.ChargeSubscriptions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, settled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestV2SubscriptionPlans(t *testing.T) {
+	svc, mock, _ := newTestBankService(t)
+	logger, _ := newTestLogger()
+	r := mux.NewRouter()
+	handlers.RegisterRESTService(r, svc, logger)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(tenant.WithScope(r.Context(), tenant.Scope{TenantID: "acme"})))
+		})
+	})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("INSERT INTO subscription_plans").
+		WithArgs("acme", sqlmock.AnyArg(), "pro", "month", 1, 9.99, "USD", 14).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(vaultNow))
+	mock.ExpectCommit()
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/subscription_plans",
+		bytes.NewBufferString(`{"name":" pro ","interval":"month","amount":9.99,"currency":"USD","trial_days":14}`)))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var plan models.SubscriptionPlan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &plan))
+	assert.Regexp(t, "^sp_[0-9a-f]{24}$", plan.ID)
+	assert.Equal(t, "/v2/subscription_plans/"+plan.ID, rec.Header().Get("Location"))
+	assert.Equal(t, 1, plan.IntervalCount)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/subscription_plans",
+		bytes.NewBufferString(`{"name":"pro","interval":"fortnight","amount":9.99,"currency":"USD"}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM subscriptions WHERE tenant_id = \\$1 AND id = \\$2 FOR UPDATE").
+		WithArgs("acme", testSubscriptionID).
+		WillReturnRows(sqlmock.NewRows(nil))
+	mock.ExpectRollback()
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/subscriptions/"+testSubscriptionID+"/cancel", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
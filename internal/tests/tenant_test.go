@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-lang-final/internal/auth"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/rbac"
+	"go-lang-final/internal/store"
+	"go-lang-final/internal/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveTenantScope(t *testing.T) {
+	policy := rbac.DefaultPolicy()
+	merchant := &auth.Principal{ID: "k1", Roles: []string{rbac.RoleOperator}, TenantID: "acme"}
+	platform := &auth.Principal{ID: "k2", Roles: []string{rbac.RolePlatformAdmin}}
+
+	scope, err := tenant.Resolve(merchant, policy, "")
+	assert.NoError(t, err)
+	assert.Equal(t, tenant.Scope{TenantID: "acme"}, scope)
+
+	_, err = tenant.Resolve(merchant, policy, "globex")
+	assert.ErrorIs(t, err, tenant.ErrTenantMismatch)
+
+	_, err = tenant.Resolve(&auth.Principal{ID: "k3", Roles: []string{rbac.RoleViewer}}, policy, "")
+	assert.ErrorIs(t, err, tenant.ErrNoTenant)
+
+	scope, err = tenant.Resolve(platform, policy, "")
+	assert.NoError(t, err)
+	assert.Equal(t, tenant.Scope{All: true}, scope)
+
+	scope, err = tenant.Resolve(platform, policy, "globex")
+	assert.NoError(t, err)
+	assert.Equal(t, tenant.Scope{TenantID: "globex"}, scope)
+}
+
+func TestTenantMiddlewareRejectsForeignTenantHeader(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(withPrincipal(&auth.Principal{ID: "k1", Roles: []string{rbac.RoleOperator}, TenantID: "acme"}), tenant.Middleware(rbac.DefaultPolicy()))
+	r.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) {
+		scope, _ := tenant.FromContext(r.Context())
+		w.Write([]byte(scope.TenantID))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/list", nil)
+	req.Header.Set(tenant.Header, "globex")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/list", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "acme", rec.Body.String())
+}
+
+func TestCreatePaymentIgnoresBodyTenant(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT allowed_currencies, max_payment_amount, max_payments, (.+) FROM tenants").
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{}", nil, nil, nil, nil))
+	mock.ExpectQuery("INSERT INTO daily_quotas").
+		WithArgs("acme", 100.0).
+		WillReturnRows(sqlmock.NewRows([]string{"payments", "amount"}).AddRow(1, 100.0))
+	mock.ExpectExec("INSERT INTO payments").
+		WithArgs("acme", 1, 100.0, "USD", "created", "", "", "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	s := &store.PaymentStore{DB: db}
+	ctx := tenant.WithScope(context.Background(), tenant.Scope{TenantID: "acme"})
+	err = s.CreatePayment(ctx, models.Payment{ID: 1, Amount: 100.0, Currency: "USD", TenantID: "globex"})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreatePaymentEnforcesTenantLimits(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	s := &store.PaymentStore{DB: db}
+	ctx := tenant.WithScope(context.Background(), tenant.Scope{TenantID: "acme"})
+	limits := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"allowed_currencies", "max_payment_amount", "max_payments", "daily_payment_limit", "daily_amount_limit"}).AddRow("{USD,EUR}", 500.0, 10, nil, nil)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM tenants").WithArgs("acme").WillReturnRows(limits())
+	mock.ExpectRollback()
+	err = s.CreatePayment(ctx, models.Payment{ID: 1, Amount: 100.0, Currency: "KZT"})
+	assert.ErrorIs(t, err, store.ErrCurrencyNotAllowed)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM tenants").WithArgs("acme").WillReturnRows(limits())
+	mock.ExpectRollback()
+	err = s.CreatePayment(ctx, models.Payment{ID: 1, Amount: 900.0, Currency: "USD"})
+	assert.ErrorIs(t, err, store.ErrAmountTooLarge)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM tenants").WithArgs("acme").WillReturnRows(limits())
+	mock.ExpectQuery("SELECT count").WithArgs("acme").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(10))
+	mock.ExpectRollback()
+	err = s.CreatePayment(ctx, models.Payment{ID: 1, Amount: 100.0, Currency: "USD"})
+	assert.ErrorIs(t, err, store.ErrQuotaExceeded)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCrossTenantListAndMissingScope(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	s := &store.PaymentStore{DB: db}
+
+	_, err = s.ListPayments(context.Background(), store.PaymentFilter{Currency: "USD", Amount: "100.00"}, 1, 10)
+	assert.ErrorIs(t, err, tenant.ErrNoTenant)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("", "on").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT tenant_id, id, amount, currency, status, refunded_amount, provider, provider_ref, provider_status, payment_method, metadata, description, statement_descriptor FROM payments").
+		WithArgs("", "USD", "100.00", []byte("{}"), "", 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "id", "amount", "currency", "status", "refunded_amount", "provider", "provider_ref", "provider_status", "payment_method", "metadata", "description", "statement_descriptor"}).
+			AddRow("acme", 1, 100.0, "USD", "created", 0.0, "", "", "", "", []byte("{}"), "", "").
+			AddRow("globex", 1, 100.0, "USD", "created", 0.0, "", "", "", "", []byte("{}"), "", ""))
+	mock.ExpectCommit()
+
+	ctx := tenant.WithScope(context.Background(), tenant.Scope{All: true})
+	payments, err := s.ListPayments(ctx, store.PaymentFilter{Currency: "USD", Amount: "100.00"}, 1, 10)
+	assert.NoError(t, err)
+	assert.Len(t, payments, 2)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("", "on").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	err = s.DeletePayment(ctx, 1)
+	assert.ErrorIs(t, err, tenant.ErrNoTenant)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
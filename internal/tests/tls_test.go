@@ -0,0 +1,256 @@
+package tests
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-lang-final/internal/auth"
+	"go-lang-final/internal/config"
+	"go-lang-final/internal/tlsconfig"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns PEM encoded certificate and key for a leaf signed by the CA.
+func (ca *testCA) issue(t *testing.T, serial int64, subject pkix.Name, server bool) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if server {
+		tpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		tpl.DNSNames = []string{"localhost"}
+		tpl.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeTLSFiles(t *testing.T, dir string, ca *testCA, serial int64) config.TLSConfig {
+	certPEM, keyPEM := ca.issue(t, serial, pkix.Name{CommonName: "localhost"}, true)
+	cfg := config.TLSConfig{
+		Enabled:      true,
+		CertFile:     filepath.Join(dir, "server.crt"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+		ClientAuth:   "require",
+		MinVersion:   "1.2",
+	}
+	require.NoError(t, os.WriteFile(cfg.CertFile, certPEM, 0o600))
+	require.NoError(t, os.WriteFile(cfg.KeyFile, keyPEM, 0o600))
+	require.NoError(t, os.WriteFile(cfg.ClientCAFile, ca.pem, 0o600))
+	return cfg
+}
+
+func clientTLS(t *testing.T, ca *testCA, subject *pkix.Name) *tls.Config {
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.pem)
+	c := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+	if subject != nil {
+		certPEM, keyPEM := ca.issue(t, 99, *subject, false)
+		pair, err := tls.X509KeyPair(certPEM, keyPEM)
+		require.NoError(t, err)
+		c.Certificates = []tls.Certificate{pair}
+	}
+	return c
+}
+
+func clientCertMapper() *auth.CertMapper {
+	return auth.NewCertMapper([]config.ClientCertificate{
+		{Subject: "CN=billing,O=Acme", ID: "billing-service", Roles: []string{"operator"}, TenantID: "acme"},
+	})
+}
+
+func TestMutualTLSMapsClientCertificateToPrincipal(t *testing.T) {
+	ca := newTestCA(t)
+	serverTLS, _, err := tlsconfig.New(writeTLSFiles(t, t.TempDir(), ca, 2))
+	require.NoError(t, err)
+
+	r := mux.NewRouter()
+	r.Use(auth.Middleware(auth.NewAuthenticator(nil, nil, clientCertMapper())))
+	r.HandleFunc("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		p, _ := auth.PrincipalFromContext(r.Context())
+		w.Write([]byte(p.ID + "/" + p.TenantID))
+	})
+
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", serverTLS)
+	require.NoError(t, err)
+	srv := &http.Server{Handler: r}
+	go srv.Serve(lis)
+	defer srv.Close()
+
+	known := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS(t, ca, &pkix.Name{CommonName: "billing", Organization: []string{"Acme"}})}}
+	resp, err := known.Get("https://" + lis.Addr().String() + "/whoami")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "billing-service/acme", string(body))
+
+	unknown := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS(t, ca, &pkix.Name{CommonName: "intruder"})}}
+	resp, err = unknown.Get("https://" + lis.Addr().String() + "/whoami")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS(t, ca, nil)}}
+	_, err = anonymous.Get("https://" + lis.Addr().String() + "/whoami")
+	assert.Error(t, err)
+}
+
+func TestMutualTLSOverGRPC(t *testing.T) {
+	ca := newTestCA(t)
+	serverTLS, _, err := tlsconfig.New(writeTLSFiles(t, t.TempDir(), ca, 2))
+	require.NoError(t, err)
+
+	var seen *auth.Principal
+	capture := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		seen, _ = auth.PrincipalFromContext(ctx)
+		return handler(ctx, req)
+	}
+	srv := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(serverTLS)),
+		grpc.ChainUnaryInterceptor(auth.UnaryServerInterceptor(auth.NewAuthenticator(nil, nil, clientCertMapper())), capture),
+	)
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	creds := credentials.NewTLS(clientTLS(t, ca, &pkix.Name{CommonName: "billing", Organization: []string{"Acme"}}))
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(creds))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.NotNil(t, seen)
+	assert.Equal(t, "billing-service", seen.ID)
+	assert.Equal(t, auth.MethodMTLS, seen.Method)
+}
+
+func TestReloaderPicksUpRotatedCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	cfg := writeTLSFiles(t, dir, ca, 2)
+
+	reloader, err := tlsconfig.NewReloader(cfg.CertFile, cfg.KeyFile, cfg.ClientCAFile)
+	require.NoError(t, err)
+
+	serial := func() int64 {
+		cert, err := reloader.GetCertificate(nil)
+		require.NoError(t, err)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		require.NoError(t, err)
+		return leaf.SerialNumber.Int64()
+	}
+	assert.Equal(t, int64(2), serial())
+
+	writeTLSFiles(t, dir, ca, 3)
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(cfg.CertFile, future, future))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger, _ := newTestLogger()
+	go reloader.Watch(ctx, 10*time.Millisecond, logger)
+
+	assert.Eventually(t, func() bool { return serial() == 3 }, time.Second, 10*time.Millisecond)
+}
+
+func TestTLSPolicyIsEnforced(t *testing.T) {
+	ca := newTestCA(t)
+	cfg := writeTLSFiles(t, t.TempDir(), ca, 2)
+	cfg.ClientAuth = "none"
+	cfg.MinVersion = "1.3"
+
+	serverTLS, _, err := tlsconfig.New(cfg)
+	require.NoError(t, err)
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", serverTLS)
+	require.NoError(t, err)
+	defer lis.Close()
+	go func() {
+		for {
+			c, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go c.(*tls.Conn).Handshake()
+		}
+	}()
+
+	old := clientTLS(t, ca, nil)
+	old.MaxVersion = tls.VersionTLS12
+	_, err = tls.Dial("tcp", lis.Addr().String(), old)
+	assert.Error(t, err)
+
+	conn, err := tls.Dial("tcp", lis.Addr().String(), clientTLS(t, ca, nil))
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), conn.ConnectionState().Version)
+	conn.Close()
+
+	cfg.CipherSuites = []string{"TLS_RSA_WITH_RC4_128_SHA"}
+	_, _, err = tlsconfig.New(cfg)
+	assert.Error(t, err)
+}
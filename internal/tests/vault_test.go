@@ -0,0 +1,278 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-lang-final/internal/handlers"
+	"go-lang-final/internal/provider"
+	"go-lang-final/internal/routing"
+	"go-lang-final/internal/store"
+	"go-lang-final/internal/tenant"
+	"go-lang-final/internal/vault"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var vaultNow = time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// captured matches any argument and keeps it, to hand sealed cards written
+// by one query back from the next.
+type captured struct{ value driver.Value }
+
+func (c *captured) Match(v driver.Value) bool {
+	c.value = v
+	return true
+}
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func newTestVault(t *testing.T) (*vault.Vault, *vault.Detokenizer, sqlmock.Sqlmock, *recordingAuditor) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	keys, err := vault.NewKeyring(testKey(1))
+	require.NoError(t, err)
+	auditor := &recordingAuditor{}
+	v, d := vault.New(&store.PaymentStore{DB: db}, keys, auditor)
+	v.Now = func() time.Time { return vaultNow }
+	return v, d, mock, auditor
+}
+
+// expectTokenize expects a visa card ending in 4242 to be stored and
+// captures the token and the sealed card.
+func expectTokenize(mock sqlmock.Sqlmock) (token, keyID, wrapped, ciphertext *captured) {
+	token, keyID, wrapped, ciphertext = &captured{}, &captured{}, &captured{}, &captured{}
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("INSERT INTO payment_methods \\(tenant_id, token, brand, last4, exp_month, exp_year, key_id, wrapped_key, ciphertext\\)").
+		WithArgs("acme", token, vault.BrandVisa, "4242", 12, 2030, keyID, wrapped, ciphertext).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "created_at"}).AddRow("acme", vaultNow))
+	mock.ExpectCommit()
+	return token, keyID, wrapped, ciphertext
+}
+
+func TestCardValidate(t *testing.T) {
+	cases := []struct {
+		name  string
+		card  vault.Card
+		brand string
+		err   error
+	}{
+		{"visa", vault.Card{Number: "4242424242424242", ExpMonth: 12, ExpYear: 2030}, vault.BrandVisa, nil},
+		{"mastercard", vault.Card{Number: "5555555555554444", ExpMonth: 1, ExpYear: 2027}, vault.BrandMastercard, nil},
+		{"mastercard 2-series", vault.Card{Number: "2223003122003222", ExpMonth: 1, ExpYear: 2027}, vault.BrandMastercard, nil},
+		{"amex", vault.Card{Number: "378282246310005", ExpMonth: 1, ExpYear: 2027}, vault.BrandAmex, nil},
+		{"discover", vault.Card{Number: "6011111111111117", ExpMonth: 1, ExpYear: 2027}, vault.BrandDiscover, nil},
+		{"bad check digit", vault.Card{Number: "4242424242424241", ExpMonth: 12, ExpYear: 2030}, "", vault.ErrInvalidNumber},
+		{"unknown range", vault.Card{Number: "9999999999999995", ExpMonth: 12, ExpYear: 2030}, "", vault.ErrUnsupportedBrand},
+		{"bad month", vault.Card{Number: "4242424242424242", ExpMonth: 13, ExpYear: 2030}, "", vault.ErrInvalidExpiry},
+		{"too far ahead", vault.Card{Number: "4242424242424242", ExpMonth: 1, ExpYear: 2099}, "", vault.ErrInvalidExpiry},
+		{"expired", vault.Card{Number: "4242424242424242", ExpMonth: 4, ExpYear: 2026}, "", vault.ErrExpired},
+		{"expires this month", vault.Card{Number: "4242424242424242", ExpMonth: 5, ExpYear: 2026}, vault.BrandVisa, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			brand, err := vault.Validate(tc.card, vaultNow)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.brand, brand)
+		})
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	old, err := vault.NewKeyring(testKey(1))
+	require.NoError(t, err)
+	sealed, err := old.Seal([]byte("card"), []byte("acme/pm_1"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(sealed.Ciphertext), "card")
+
+	rotated, err := vault.NewKeyring(testKey(2), testKey(1))
+	require.NoError(t, err)
+	plaintext, err := rotated.Open(sealed, []byte("acme/pm_1"))
+	require.NoError(t, err)
+	assert.Equal(t, "card", string(plaintext))
+
+	resealed, err := rotated.Seal([]byte("card"), []byte("acme/pm_1"))
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed.KeyID, resealed.KeyID)
+
+	// A card cannot be moved to another tenant or token.
+	_, err = rotated.Open(sealed, []byte("globex/pm_1"))
+	assert.Error(t, err)
+
+	retired, err := vault.NewKeyring(testKey(2))
+	require.NoError(t, err)
+	_, err = retired.Open(sealed, []byte("acme/pm_1"))
+	assert.ErrorIs(t, err, vault.ErrUnknownKey)
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vault.keys")
+	content := "# active key\n" + strings.Repeat("02", 32) + "\n\n" + strings.Repeat("01", 32) + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	keys, err := vault.LoadKeyFile(path)
+	require.NoError(t, err)
+	old, err := vault.NewKeyring(testKey(1))
+	require.NoError(t, err)
+	sealed, err := old.Seal([]byte("card"), nil)
+	require.NoError(t, err)
+	_, err = keys.Open(sealed, nil)
+	assert.NoError(t, err)
+
+	secret := strings.Repeat("ab", 31)
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("01", 32)+"\n"+secret+"\n"), 0o600))
+	_, err = vault.LoadKeyFile(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+	assert.NotContains(t, err.Error(), secret)
+}
+
+func TestVaultTokenizeAndDetokenize(t *testing.T) {
+	v, d, mock, auditor := newTestVault(t)
+	ctx := tenant.WithScope(context.Background(), tenant.Scope{TenantID: "acme"})
+	token, keyID, wrapped, ciphertext := expectTokenize(mock)
+
+	method, err := v.Tokenize(ctx, vault.Card{Number: "4242424242424242", ExpMonth: 12, ExpYear: 2030})
+	require.NoError(t, err)
+	assert.Regexp(t, "^pm_[0-9a-f]{24}$", method.Token)
+	assert.Equal(t, token.value, method.Token)
+	assert.Equal(t, vault.BrandVisa, method.Brand)
+	assert.Equal(t, "4242", method.Last4)
+	assert.Equal(t, "acme", method.TenantID)
+	assert.NotContains(t, string(ciphertext.value.([]byte)), "4242424242424242")
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT key_id, wrapped_key, ciphertext FROM payment_methods WHERE tenant_id = \\$1 AND token = \\$2").
+		WithArgs("acme", method.Token).
+		WillReturnRows(sqlmock.NewRows([]string{"key_id", "wrapped_key", "ciphertext"}).AddRow(keyID.value, wrapped.value, ciphertext.value))
+	mock.ExpectCommit()
+
+	card, err := d.Card(ctx, method.Token, "authorize payment")
+	require.NoError(t, err)
+	assert.Equal(t, vault.Card{Number: "4242424242424242", ExpMonth: 12, ExpYear: 2030}, card)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT key_id, wrapped_key, ciphertext FROM payment_methods").
+		WithArgs("acme", "pm_000000000000000000000000").
+		WillReturnRows(sqlmock.NewRows([]string{"key_id", "wrapped_key", "ciphertext"}))
+	mock.ExpectRollback()
+
+	_, err = d.Card(ctx, "pm_000000000000000000000000", "authorize payment")
+	assert.ErrorIs(t, err, store.ErrPaymentMethodNotFound)
+
+	require.Len(t, auditor.events, 2)
+	assert.Equal(t, vault.ActionDetokenize, auditor.events[0].Action)
+	assert.Equal(t, "system", auditor.events[0].Principal)
+	assert.Equal(t, method.Token, auditor.events[0].Resource)
+	assert.Equal(t, "allowed", auditor.events[0].Outcome)
+	assert.Equal(t, "denied", auditor.events[1].Outcome)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestV2PaymentMethods(t *testing.T) {
+	v, _, mock, _ := newTestVault(t)
+	sim, _ := newTestSimulator()
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	logger, _ := newTestLogger()
+	svc := handlers.NewRoutedPaymentService(&store.PaymentStore{DB: db}, routing.Single(sim), v)
+	r := mux.NewRouter()
+	handlers.RegisterRESTService(r, svc, logger)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(tenant.WithScope(r.Context(), tenant.Scope{TenantID: "acme"})))
+		})
+	})
+
+	expectTokenize(mock)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/payment_methods", bytes.NewBufferString(`{"number":"4242424242424242","exp_month":12,"exp_year":2030}`)))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "4242424242424242")
+
+	var method map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &method))
+	assert.Equal(t, "/v2/payment_methods/"+method["token"].(string), rec.Header().Get("Location"))
+	assert.Equal(t, "visa", method["brand"])
+	assert.Equal(t, "4242", method["last4"])
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/payment_methods", bytes.NewBufferString(`{"number":"4242424242424241","exp_month":12,"exp_year":2030}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("acme", "off").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT token, brand, last4, exp_month, exp_year, tenant_id, created_at FROM payment_methods").
+		WithArgs("acme", "pm_000000000000000000000000").
+		WillReturnRows(sqlmock.NewRows([]string{"token", "brand", "last4", "exp_month", "exp_year", "tenant_id", "created_at"}))
+	mock.ExpectRollback()
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/payment_methods/pm_000000000000000000000000", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// stubCards is a CardSource over a fixed set of cards.
+type stubCards map[string]vault.Card
+
+func (c stubCards) Card(_ context.Context, token, _ string) (vault.Card, error) {
+	card, ok := c[token]
+	if !ok {
+		return vault.Card{}, store.ErrPaymentMethodNotFound
+	}
+	return card, nil
+}
+
+func TestRoutingOpensPaymentMethods(t *testing.T) {
+	sim, _ := newTestSimulator()
+	cards := stubCards{
+		"pm_approved": {Number: provider.CardApproved, ExpMonth: 12, ExpYear: 2030},
+		"pm_declined": {Number: provider.CardDeclined, ExpMonth: 12, ExpYear: 2030},
+	}
+	router, err := routing.New([]provider.Provider{sim}, routing.Options{Cards: cards})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, txn, _, err := router.Authorize(ctx, routing.Request{TenantID: "acme", PaymentMethod: "pm_approved", Authorization: provider.Authorization{Amount: 10, Currency: "USD"}})
+	require.NoError(t, err)
+	assert.Equal(t, provider.StatusAuthorized, txn.Status)
+
+	_, _, _, err = router.Authorize(ctx, routing.Request{TenantID: "acme", PaymentMethod: "pm_declined", Authorization: provider.Authorization{Amount: 10, Currency: "USD"}})
+	var decline *provider.DeclineError
+	require.ErrorAs(t, err, &decline)
+	assert.Equal(t, "card_declined", decline.Code)
+
+	_, _, _, err = router.Authorize(ctx, routing.Request{TenantID: "acme", PaymentMethod: "pm_missing", Authorization: provider.Authorization{Amount: 10, Currency: "USD"}})
+	assert.ErrorIs(t, err, store.ErrPaymentMethodNotFound)
+
+	_, _, _, err = routing.Single(sim).Authorize(ctx, routing.Request{TenantID: "acme", PaymentMethod: "pm_approved"})
+	assert.ErrorIs(t, err, routing.ErrNoVault)
+}
@@ -0,0 +1,759 @@
+// Package validation rejects malformed gRPC requests before they reach the
+// handlers. Violations are reported as InvalidArgument with a
+// google.rpc.BadRequest detail listing every offending field.
+package validation
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go-lang-final/internal/customers"
+	"go-lang-final/internal/disputes"
+	"go-lang-final/internal/fees"
+	"go-lang-final/internal/fx"
+	"go-lang-final/internal/invoices"
+	"go-lang-final/internal/metadata"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/money"
+	"go-lang-final/internal/sepa"
+	"go-lang-final/internal/subscriptions"
+	"go-lang-final/proto"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// MaxPageSize bounds list requests.
+	MaxPageSize = 1000
+	// MaxReasonLength bounds the free-text reasons of refunds and
+	// cancellations.
+	MaxReasonLength = 500
+)
+
+var (
+	currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+	cardNumber   = regexp.MustCompile(`^[0-9]{12,19}$`)
+	// paymentMethodToken matches the tokens issued by the vault.
+	paymentMethodToken = regexp.MustCompile(`^pm_[0-9a-f]{24}$`)
+	// bankAccountToken, creditTransferID and batchID match the ids of the
+	// bank transfer resources.
+	bankAccountToken = regexp.MustCompile(`^ba_[0-9a-f]{24}$`)
+	creditTransferID = regexp.MustCompile(`^ct-[0-9a-f]{24}$`)
+	batchID          = regexp.MustCompile(`^ctb-[0-9a-f]{24}$`)
+	// reconciliationRunID matches the ids of reconciliation runs.
+	reconciliationRunID = regexp.MustCompile(`^rec_[0-9a-f]{24}$`)
+	// settlementBatchID matches the ids of settlement batches.
+	settlementBatchID = regexp.MustCompile(`^stb_[0-9a-f]{24}$`)
+	// fxQuoteID matches the ids of fx quotes.
+	fxQuoteID = regexp.MustCompile(`^fxq_[0-9a-f]{24}$`)
+	// pricingPlanID matches the ids of pricing plans.
+	pricingPlanID = regexp.MustCompile(`^pp_[0-9a-f]{24}$`)
+	// subscriptionPlanID and subscriptionID match the ids of subscription
+	// plans and subscriptions.
+	subscriptionPlanID = regexp.MustCompile(`^sp_[0-9a-f]{24}$`)
+	subscriptionID     = regexp.MustCompile(`^sub_[0-9a-f]{24}$`)
+	// invoiceID matches the ids of invoices.
+	invoiceID = regexp.MustCompile(`^in_[0-9a-f]{24}$`)
+	// customerID matches the ids of customers.
+	customerID = regexp.MustCompile(`^cus_[0-9a-f]{24}$`)
+	// disputeID and disputeEvidenceID match the ids of disputes and their
+	// evidence.
+	disputeID         = regexp.MustCompile(`^dp_[0-9a-f]{24}$`)
+	disputeEvidenceID = regexp.MustCompile(`^dpe_[0-9a-f]{24}$`)
+)
+
+// MaxStatusReportSize bounds the pain.002 documents ImportPaymentStatusReport
+// accepts.
+const MaxStatusReportSize = 10 << 20
+
+// MaxStatementSize bounds the bank statements ImportBankStatement accepts.
+const MaxStatementSize = 20 << 20
+
+// MaxDateWindow bounds the days a statement line may lie from the payment
+// it settles.
+const MaxDateWindow = 31
+
+// MaxSettlementSize bounds the settlement files ImportSettlementReport
+// accepts.
+const MaxSettlementSize = 50 << 20
+
+// MaxFXRates bounds the rates one ImportFXRates request adds.
+const MaxFXRates = 10000
+
+// MaxPricingOverrides and MaxPricingTiers bound the size of a pricing plan.
+const (
+	MaxPricingOverrides = 100
+	MaxPricingTiers     = 20
+)
+
+// MaxPlanNameLength bounds the names of pricing and subscription plans.
+const MaxPlanNameLength = 200
+
+// MaxIntervalCount bounds the intervals between the charges of a
+// subscription plan.
+const MaxIntervalCount = 365
+
+// settlementStatuses are the line filters of GetSettlementBatch.
+var settlementStatuses = map[string]bool{
+	"": true, "discrepancies": true, "matched": true, "unexpected": true, "missing": true, "duplicated": true,
+	"amount_mismatch": true, "fee_deviation": true,
+}
+
+// subscriptionStatuses are the filters of ListSubscriptions.
+var subscriptionStatuses = map[string]bool{
+	"": true, models.SubscriptionTrialing: true, models.SubscriptionActive: true, models.SubscriptionPastDue: true, models.SubscriptionCanceled: true,
+}
+
+// invoiceStatuses are the filters of ListInvoices.
+var invoiceStatuses = map[string]bool{
+	"": true, models.InvoiceDraft: true, models.InvoiceOpen: true, models.InvoicePaid: true, models.InvoiceVoid: true,
+}
+
+// disputeStatuses are the filters of ListDisputes.
+var disputeStatuses = map[string]bool{
+	"": true, models.DisputeNeedsResponse: true, models.DisputeUnderReview: true, models.DisputeWon: true, models.DisputeLost: true,
+}
+
+// MaxOutcomeLength bounds the explanation of how a dispute was decided.
+const MaxOutcomeLength = 500
+
+// MaxCustomerFieldLength and MaxMemoLength bound the free text of
+// invoices.
+const (
+	MaxCustomerFieldLength = 500
+	MaxMemoLength          = 2000
+)
+
+// lineStatuses are the line filters of GetReconciliationRun.
+var lineStatuses = map[string]bool{
+	"": true, "exceptions": true, "matched": true, "unmatched": true, "duplicated": true, "amount_mismatch": true,
+}
+
+// Check returns the field violations of a request message; messages without
+// rules have none.
+func Check(req interface{}) []*errdetails.BadRequest_FieldViolation {
+	var v violations
+	switch r := req.(type) {
+	case *proto.CreatePaymentRequest:
+		v.id(r.GetId())
+		v.amount("amount", r.GetAmount())
+		v.currency(r.GetCurrency(), true)
+		v.decimals("amount", r.GetAmount(), r.GetCurrency())
+		if r.GetCardNumber() != "" && !cardNumber.MatchString(r.GetCardNumber()) {
+			v.add("card_number", "must be 12 to 19 digits")
+		}
+		if r.GetPaymentMethod() != "" {
+			if r.GetCardNumber() != "" {
+				v.add("payment_method", "must not be set together with card_number")
+			}
+			if !bankAccountToken.MatchString(r.GetPaymentMethod()) {
+				v.token("payment_method", r.GetPaymentMethod())
+			}
+		}
+		if r.GetSettlementCurrency() != "" {
+			v.match("settlement_currency", r.GetSettlementCurrency(), currencyCode, "must be an ISO 4217 code such as EUR")
+		}
+		if r.GetFxQuoteId() != "" {
+			v.match("fx_quote_id", r.GetFxQuoteId(), fxQuoteID, "must be an fx quote id")
+		}
+		if r.GetCustomer() != "" {
+			v.match("customer", r.GetCustomer(), customerID, "must be a customer id")
+		}
+		v.paymentDetails(r.GetMetadata(), r.GetDescription(), r.GetStatementDescriptor())
+	case *proto.UpdatePaymentRequest:
+		v.id(r.GetId())
+		v.amount("amount", r.GetAmount())
+		v.currency(r.GetCurrency(), true)
+		v.decimals("amount", r.GetAmount(), r.GetCurrency())
+		v.paymentDetails(r.GetMetadata(), r.GetDescription(), r.GetStatementDescriptor())
+	case *proto.GetPaymentRequest:
+		v.id(r.GetId())
+	case *proto.DeletePaymentRequest:
+		v.id(r.GetId())
+	case *proto.ListPaymentsRequest:
+		v.currency(r.GetCurrency(), false)
+		if r.Amount != nil && r.GetAmount() < 0 {
+			v.add("amount", "must not be negative")
+		}
+		if err := metadata.Check(r.GetMetadata()); err != nil {
+			v.add("metadata", err.Error())
+		}
+		if utf8.RuneCountInString(r.GetQuery()) > metadata.MaxDescriptionLength {
+			v.add("query", fmt.Sprintf("must be at most %d characters", metadata.MaxDescriptionLength))
+		}
+		if r.GetPage() < 0 {
+			v.add("page", "must not be negative")
+		}
+		if r.GetPageSize() < 0 || r.GetPageSize() > MaxPageSize {
+			v.add("page_size", fmt.Sprintf("must be between 0 and %d", MaxPageSize))
+		}
+	case *proto.RefundPaymentRequest:
+		v.id(r.GetId())
+		v.amount("amount", r.GetAmount())
+		v.reason(r.GetReason())
+	case *proto.CancelPaymentRequest:
+		v.id(r.GetId())
+		v.reason(r.GetReason())
+	case *proto.ListPaymentEventsRequest:
+		v.id(r.GetId())
+	case *proto.CreatePaymentMethodRequest:
+		if !cardNumber.MatchString(r.GetNumber()) {
+			v.add("number", "must be 12 to 19 digits")
+		}
+		if r.GetExpMonth() < 1 || r.GetExpMonth() > 12 {
+			v.add("exp_month", "must be between 1 and 12")
+		}
+		if r.GetExpYear() < 2000 {
+			v.add("exp_year", "must be a four-digit year")
+		}
+	case *proto.GetPaymentMethodRequest:
+		v.token("token", r.GetToken())
+	case *proto.CreateBankAccountRequest:
+		if r.GetHolderName() == "" || len(r.GetHolderName()) > 70 {
+			v.add("holder_name", "must be 1 to 70 bytes")
+		}
+		if _, err := sepa.ValidateIBAN(r.GetIban()); err != nil {
+			v.add("iban", err.Error())
+		}
+		if r.GetBic() != "" {
+			if _, err := sepa.ValidateBIC(r.GetBic()); err != nil {
+				v.add("bic", err.Error())
+			}
+		}
+	case *proto.GetBankAccountRequest:
+		v.match("token", r.GetToken(), bankAccountToken, "must be a bank account token")
+	case *proto.CreatePayoutRequest:
+		v.match("bank_account", r.GetBankAccount(), bankAccountToken, "must be a bank account token")
+		v.amount("amount", r.GetAmount())
+		if r.GetCurrency() != "EUR" {
+			v.add("currency", "must be EUR")
+		}
+		if len(r.GetRemittance()) > 140 {
+			v.add("remittance", "must be at most 140 bytes")
+		}
+	case *proto.GetCreditTransferRequest:
+		v.match("id", r.GetId(), creditTransferID, "must be a credit transfer id")
+	case *proto.CreateCreditTransferBatchRequest:
+		if r.GetExecutionDate() != "" {
+			if _, err := time.Parse("2006-01-02", r.GetExecutionDate()); err != nil {
+				v.add("execution_date", "must be a YYYY-MM-DD date")
+			}
+		}
+	case *proto.GetCreditTransferBatchRequest:
+		v.match("id", r.GetId(), batchID, "must be a credit transfer batch id")
+	case *proto.ImportPaymentStatusReportRequest:
+		if len(r.GetDocument()) == 0 || len(r.GetDocument()) > MaxStatusReportSize {
+			v.add("document", fmt.Sprintf("must be 1 to %d bytes", MaxStatusReportSize))
+		}
+	case *proto.ImportBankStatementRequest:
+		if len(r.GetDocument()) == 0 || len(r.GetDocument()) > MaxStatementSize {
+			v.add("document", fmt.Sprintf("must be 1 to %d bytes", MaxStatementSize))
+		}
+		if r.GetTolerance() < 0 {
+			v.add("tolerance", "must not be negative")
+		}
+		if r.GetDateWindowDays() < 0 || r.GetDateWindowDays() > MaxDateWindow {
+			v.add("date_window_days", fmt.Sprintf("must be between 0 and %d", MaxDateWindow))
+		}
+	case *proto.GetReconciliationRunRequest:
+		v.match("id", r.GetId(), reconciliationRunID, "must be a reconciliation run id")
+		if !lineStatuses[r.GetStatus()] {
+			v.add("status", "must be exceptions, matched, unmatched, duplicated or amount_mismatch")
+		}
+	case *proto.ListReconciliationRunsRequest:
+		v.page(r.GetPage(), r.GetPageSize())
+	case *proto.ResolveReconciliationLineRequest:
+		v.match("run_id", r.GetRunId(), reconciliationRunID, "must be a reconciliation run id")
+		if r.GetLine() < 1 {
+			v.add("line", "must be positive")
+		}
+		switch r.GetResolution() {
+		case "accepted":
+		case "written_off":
+			if r.GetPaymentId() != 0 {
+				v.add("payment_id", "must not be set when writing off")
+			}
+		default:
+			v.add("resolution", "must be accepted or written_off")
+		}
+		if r.GetPaymentId() < 0 {
+			v.add("payment_id", "must not be negative")
+		}
+		if len(r.GetNote()) > MaxReasonLength {
+			v.add("note", fmt.Sprintf("must be at most %d bytes", MaxReasonLength))
+		}
+	case *proto.ImportSettlementReportRequest:
+		if r.GetProvider() == "" || len(r.GetProvider()) > 64 {
+			v.add("provider", "must be 1 to 64 bytes")
+		}
+		if r.GetReference() == "" || len(r.GetReference()) > 128 {
+			v.add("reference", "must be 1 to 128 bytes")
+		}
+		if r.GetSettlementDate() != "" {
+			if _, err := time.Parse("2006-01-02", r.GetSettlementDate()); err != nil {
+				v.add("settlement_date", "must be a YYYY-MM-DD date")
+			}
+		}
+		if len(r.GetDocument()) == 0 || len(r.GetDocument()) > MaxSettlementSize {
+			v.add("document", fmt.Sprintf("must be 1 to %d bytes", MaxSettlementSize))
+		}
+	case *proto.GetSettlementBatchRequest:
+		v.match("id", r.GetId(), settlementBatchID, "must be a settlement batch id")
+		if !settlementStatuses[r.GetStatus()] {
+			v.add("status", "must be discrepancies, matched, unexpected, missing, duplicated, amount_mismatch or fee_deviation")
+		}
+	case *proto.ListSettlementBatchesRequest:
+		v.page(r.GetPage(), r.GetPageSize())
+	case *proto.ListLedgerPostingsRequest:
+		if r.GetPaymentId() < 0 {
+			v.add("payment_id", "must not be negative")
+		}
+		v.page(r.GetPage(), r.GetPageSize())
+	case *proto.ImportFXRatesRequest:
+		if len(r.GetRates()) == 0 || len(r.GetRates()) > MaxFXRates {
+			v.add("rates", fmt.Sprintf("must hold 1 to %d rates", MaxFXRates))
+		}
+		for i, rate := range r.GetRates() {
+			field := fmt.Sprintf("rates[%d]", i)
+			v.match(field+".base", rate.GetBase(), currencyCode, "must be an ISO 4217 code such as USD")
+			v.match(field+".quote", rate.GetQuote(), currencyCode, "must be an ISO 4217 code such as EUR")
+			if rate.GetBase() == rate.GetQuote() {
+				v.add(field+".quote", "must differ from base")
+			}
+			if _, err := fx.Parse(rate.GetRate()); err != nil {
+				v.add(field+".rate", fmt.Sprintf("must be a positive decimal of at most %d decimals", fx.Scale))
+			}
+			if rate.GetEffectiveAt() == nil {
+				v.add(field+".effective_at", "is required")
+			}
+		}
+	case *proto.ListFXRatesRequest:
+		if r.GetBase() != "" {
+			v.match("base", r.GetBase(), currencyCode, "must be an ISO 4217 code such as USD")
+		}
+		if r.GetQuote() != "" {
+			v.match("quote", r.GetQuote(), currencyCode, "must be an ISO 4217 code such as EUR")
+		}
+		v.page(r.GetPage(), r.GetPageSize())
+	case *proto.CreateFXQuoteRequest:
+		v.currency(r.GetCurrency(), true)
+		v.match("settlement_currency", r.GetSettlementCurrency(), currencyCode, "must be an ISO 4217 code such as EUR")
+		if r.GetSettlementCurrency() == r.GetCurrency() {
+			v.add("settlement_currency", "must differ from currency")
+		}
+		if r.GetAmount() < 0 {
+			v.add("amount", "must not be negative")
+		}
+		v.decimals("amount", r.GetAmount(), r.GetCurrency())
+	case *proto.GetPaymentConversionRequest:
+		v.id(r.GetId())
+	case *proto.PricingPlan:
+		if r.GetId() != "" {
+			v.match("id", r.GetId(), pricingPlanID, "must be a pricing plan id")
+		}
+		if name := strings.TrimSpace(r.GetName()); name == "" || len(name) > MaxPlanNameLength {
+			v.add("name", fmt.Sprintf("must be 1 to %d bytes", MaxPlanNameLength))
+		}
+		v.pricing("pricing", r.GetPricing())
+		if len(r.GetOverrides()) > MaxPricingOverrides {
+			v.add("overrides", fmt.Sprintf("must hold at most %d overrides", MaxPricingOverrides))
+		}
+		for i, o := range r.GetOverrides() {
+			field := fmt.Sprintf("overrides[%d]", i)
+			if o.GetCurrency() == "" && o.GetMethod() == "" {
+				v.add(field, "must name a currency, a method or both")
+			}
+			if o.GetCurrency() != "" {
+				v.match(field+".currency", o.GetCurrency(), currencyCode, "must be an ISO 4217 code such as USD")
+			}
+			if o.GetMethod() != "" {
+				v.method(field+".method", o.GetMethod())
+			}
+			v.pricing(field+".pricing", o.GetPricing())
+		}
+	case *proto.GetPricingPlanRequest:
+		v.match("id", r.GetId(), pricingPlanID, "must be a pricing plan id")
+	case *proto.ListPricingPlansRequest:
+		v.page(r.GetPage(), r.GetPageSize())
+	case *proto.AssignPricingPlanRequest:
+		if r.GetPlanId() != "" {
+			v.match("plan_id", r.GetPlanId(), pricingPlanID, "must be a pricing plan id")
+		}
+	case *proto.SimulateFeeRequest:
+		v.amount("amount", r.GetAmount())
+		v.currency(r.GetCurrency(), true)
+		if r.GetMethod() != "" {
+			v.method("method", r.GetMethod())
+		}
+		if r.Volume != nil && r.GetVolume() < 0 {
+			v.add("volume", "must not be negative")
+		}
+	case *proto.GetPaymentFeeRequest:
+		v.id(r.GetId())
+	case *proto.GetFeeStatementRequest:
+		if _, err := time.Parse("2006-01", r.GetMonth()); r.GetMonth() != "" && err != nil {
+			v.add("month", "must be formatted YYYY-MM")
+		}
+	case *proto.SubscriptionPlan:
+		if r.GetId() != "" {
+			v.match("id", r.GetId(), subscriptionPlanID, "must be a subscription plan id")
+		}
+		if name := strings.TrimSpace(r.GetName()); name == "" || len(name) > MaxPlanNameLength {
+			v.add("name", fmt.Sprintf("must be 1 to %d bytes", MaxPlanNameLength))
+		}
+		if !subscriptions.ValidInterval(r.GetInterval()) {
+			v.add("interval", "must be day, week, month or year")
+		}
+		if r.GetIntervalCount() < 0 || r.GetIntervalCount() > MaxIntervalCount {
+			v.add("interval_count", fmt.Sprintf("must be between 0 and %d", MaxIntervalCount))
+		}
+		v.amount("amount", r.GetAmount())
+		v.currency(r.GetCurrency(), true)
+		if r.GetTrialDays() < 0 || r.GetTrialDays() > subscriptions.MaxTrialDays {
+			v.add("trial_days", fmt.Sprintf("must be between 0 and %d", subscriptions.MaxTrialDays))
+		}
+	case *proto.GetSubscriptionPlanRequest:
+		v.match("id", r.GetId(), subscriptionPlanID, "must be a subscription plan id")
+	case *proto.ListSubscriptionPlansRequest:
+		v.page(r.GetPage(), r.GetPageSize())
+	case *proto.CreateSubscriptionRequest:
+		v.match("plan_id", r.GetPlanId(), subscriptionPlanID, "must be a subscription plan id")
+		if pm := r.GetPaymentMethod(); pm != "" && !paymentMethodToken.MatchString(pm) && !bankAccountToken.MatchString(pm) {
+			v.add("payment_method", "must be a payment method or bank account token")
+		}
+		if r.GetCustomer() != "" {
+			v.match("customer", r.GetCustomer(), customerID, "must be a customer id")
+		}
+	case *proto.GetSubscriptionRequest:
+		v.match("id", r.GetId(), subscriptionID, "must be a subscription id")
+	case *proto.ListSubscriptionsRequest:
+		v.page(r.GetPage(), r.GetPageSize())
+		if !subscriptionStatuses[r.GetStatus()] {
+			v.add("status", "must be trialing, active, past_due or canceled")
+		}
+	case *proto.ChangeSubscriptionPlanRequest:
+		v.match("id", r.GetId(), subscriptionID, "must be a subscription id")
+		v.match("plan_id", r.GetPlanId(), subscriptionPlanID, "must be a subscription plan id")
+	case *proto.CancelSubscriptionRequest:
+		v.match("id", r.GetId(), subscriptionID, "must be a subscription id")
+	case *proto.ListSubscriptionChargesRequest:
+		v.match("id", r.GetId(), subscriptionID, "must be a subscription id")
+	case *proto.Invoice:
+		if r.GetId() != "" {
+			v.match("id", r.GetId(), invoiceID, "must be an invoice id")
+		}
+		v.currency(r.GetCurrency(), true)
+		for _, f := range [][2]string{
+			{"customer_name", r.GetCustomerName()}, {"customer_email", r.GetCustomerEmail()}, {"customer_address", r.GetCustomerAddress()},
+		} {
+			if len(f[1]) > MaxCustomerFieldLength {
+				v.add(f[0], fmt.Sprintf("must be at most %d bytes", MaxCustomerFieldLength))
+			}
+		}
+		if len(r.GetMemo()) > MaxMemoLength {
+			v.add("memo", fmt.Sprintf("must be at most %d bytes", MaxMemoLength))
+		}
+		if r.GetDueDate() != "" {
+			if _, err := time.Parse(invoices.DateLayout, r.GetDueDate()); err != nil {
+				v.add("due_date", "must be a date formatted YYYY-MM-DD")
+			}
+		}
+		if n := len(r.GetLines()); n == 0 || n > invoices.MaxLines {
+			v.add("lines", fmt.Sprintf("must hold 1 to %d lines", invoices.MaxLines))
+		}
+		for i, l := range r.GetLines() {
+			line := fmt.Sprintf("lines[%d]", i)
+			if d := strings.TrimSpace(l.GetDescription()); d == "" || len(d) > invoices.MaxDescriptionLength {
+				v.add(line+".description", fmt.Sprintf("must be 1 to %d bytes", invoices.MaxDescriptionLength))
+			}
+			if l.GetQuantity() < 1 || l.GetQuantity() > invoices.MaxQuantity {
+				v.add(line+".quantity", fmt.Sprintf("must be between 1 and %d", invoices.MaxQuantity))
+			}
+			if l.GetUnitAmount() < 0 || l.GetUnitAmount() > invoices.MaxUnitAmount {
+				v.add(line+".unit_amount", fmt.Sprintf("must be between 0 and %d", invoices.MaxUnitAmount))
+			}
+			if _, err := fees.NormalizePercentage(l.GetDiscountRate()); err != nil {
+				v.add(line+".discount_rate", "must be a percentage between 0 and 100")
+			}
+			if _, err := fees.NormalizePercentage(l.GetTaxRate()); err != nil {
+				v.add(line+".tax_rate", "must be a percentage between 0 and 100")
+			}
+		}
+	case *proto.GetInvoiceRequest:
+		v.match("id", r.GetId(), invoiceID, "must be an invoice id")
+	case *proto.ListInvoicesRequest:
+		v.page(r.GetPage(), r.GetPageSize())
+		if !invoiceStatuses[r.GetStatus()] {
+			v.add("status", "must be draft, open, paid or void")
+		}
+	case *proto.FinalizeInvoiceRequest:
+		v.match("id", r.GetId(), invoiceID, "must be an invoice id")
+	case *proto.VoidInvoiceRequest:
+		v.match("id", r.GetId(), invoiceID, "must be an invoice id")
+	case *proto.PayInvoiceRequest:
+		v.match("id", r.GetId(), invoiceID, "must be an invoice id")
+		if r.GetPaymentId() <= 0 {
+			v.add("payment_id", "must be positive")
+		}
+		if r.GetAmount() < 0 {
+			v.add("amount", "must not be negative")
+		}
+	case *proto.RenderInvoiceRequest:
+		v.match("id", r.GetId(), invoiceID, "must be an invoice id")
+		if f := r.GetFormat(); f != "" && f != "html" && f != "pdf" {
+			v.add("format", "must be html or pdf")
+		}
+	case *proto.Customer:
+		if r.GetId() != "" {
+			v.match("id", r.GetId(), customerID, "must be a customer id")
+		}
+		if err := customers.CheckEmail(strings.TrimSpace(r.GetEmail())); err != nil {
+			v.add("email", err.Error())
+		}
+		if len(r.GetName()) > customers.MaxNameLength {
+			v.add("name", fmt.Sprintf("must be at most %d bytes", customers.MaxNameLength))
+		}
+		if len(r.GetExternalRef()) > customers.MaxExternalRefLength {
+			v.add("external_ref", fmt.Sprintf("must be at most %d bytes", customers.MaxExternalRefLength))
+		}
+		if strings.TrimSpace(r.GetEmail()) == "" && strings.TrimSpace(r.GetName()) == "" && strings.TrimSpace(r.GetExternalRef()) == "" {
+			v.add("email", "email, name or external_ref is required")
+		}
+		if err := metadata.Check(r.GetMetadata()); err != nil {
+			v.add("metadata", err.Error())
+		}
+	case *proto.GetCustomerRequest:
+		v.match("id", r.GetId(), customerID, "must be a customer id")
+	case *proto.ListCustomersRequest:
+		v.page(r.GetPage(), r.GetPageSize())
+	case *proto.DeleteCustomerRequest:
+		v.match("id", r.GetId(), customerID, "must be a customer id")
+	case *proto.EraseCustomerRequest:
+		v.match("id", r.GetId(), customerID, "must be a customer id")
+	case *proto.AttachPaymentMethodRequest:
+		v.match("id", r.GetId(), customerID, "must be a customer id")
+		if !paymentMethodToken.MatchString(r.GetToken()) && !bankAccountToken.MatchString(r.GetToken()) {
+			v.add("token", "must be a payment method or bank account token")
+		}
+	case *proto.ListCustomerPaymentMethodsRequest:
+		v.match("id", r.GetId(), customerID, "must be a customer id")
+	case *proto.ListCustomerPaymentsRequest:
+		v.match("id", r.GetId(), customerID, "must be a customer id")
+		v.page(r.GetPage(), r.GetPageSize())
+	case *proto.GetCustomerLifetimeValueRequest:
+		v.match("id", r.GetId(), customerID, "must be a customer id")
+	case *proto.CreateDisputeRequest:
+		if r.GetPaymentId() <= 0 {
+			v.add("payment_id", "must be positive")
+		}
+		if !disputes.ValidReason(r.GetReason()) {
+			v.add("reason", "must be a dispute reason")
+		}
+		v.amount("amount", r.GetAmount())
+		if r.GetEvidenceDueBy() != nil && !r.GetEvidenceDueBy().AsTime().After(time.Now()) {
+			v.add("evidence_due_by", "must be in the future")
+		}
+	case *proto.GetDisputeRequest:
+		v.match("id", r.GetId(), disputeID, "must be a dispute id")
+	case *proto.ListDisputesRequest:
+		v.page(r.GetPage(), r.GetPageSize())
+		if !disputeStatuses[r.GetStatus()] {
+			v.add("status", "must be needs_response, under_review, won or lost")
+		}
+		if r.GetPaymentId() < 0 {
+			v.add("payment_id", "must not be negative")
+		}
+	case *proto.UploadDisputeEvidenceRequest:
+		v.match("dispute_id", r.GetDisputeId(), disputeID, "must be a dispute id")
+		if !disputes.ValidEvidenceType(r.GetType()) {
+			v.add("type", "must be an evidence type")
+		}
+		if f := r.GetFilename(); f == "" || len(f) > disputes.MaxFilenameLength || strings.ContainsAny(f, `/\`) || !utf8.ValidString(f) {
+			v.add("filename", fmt.Sprintf("must be a file name of 1 to %d bytes", disputes.MaxFilenameLength))
+		}
+		if !disputes.ValidContentType(r.GetContentType()) {
+			v.add("content_type", "must be application/pdf, image/png, image/jpeg or text/plain")
+		}
+		if len(r.GetDocument()) == 0 || len(r.GetDocument()) > disputes.MaxEvidenceSize {
+			v.add("document", fmt.Sprintf("must be 1 to %d bytes", disputes.MaxEvidenceSize))
+		}
+	case *proto.GetDisputeEvidenceRequest:
+		v.match("dispute_id", r.GetDisputeId(), disputeID, "must be a dispute id")
+		v.match("id", r.GetId(), disputeEvidenceID, "must be a dispute evidence id")
+	case *proto.SubmitDisputeRequest:
+		v.match("id", r.GetId(), disputeID, "must be a dispute id")
+	case *proto.AcceptDisputeRequest:
+		v.match("id", r.GetId(), disputeID, "must be a dispute id")
+	case *proto.ResolveDisputeRequest:
+		v.match("id", r.GetId(), disputeID, "must be a dispute id")
+		if r.GetStatus() != models.DisputeWon && r.GetStatus() != models.DisputeLost {
+			v.add("status", "must be won or lost")
+		}
+		if len(r.GetOutcome()) > MaxOutcomeLength {
+			v.add("outcome", fmt.Sprintf("must be at most %d bytes", MaxOutcomeLength))
+		}
+	case *proto.WatchPaymentsRequest:
+		if r.GetAfterEventId() < 0 {
+			v.add("after_event_id", "must not be negative")
+		}
+	}
+	return v
+}
+
+// Error returns the InvalidArgument status for the violations of req, or
+// nil when it is valid.
+func Error(req interface{}) error {
+	v := Check(req)
+	if len(v) == 0 {
+		return nil
+	}
+	st := status.Newf(codes.InvalidArgument, "invalid request: %s %s", v[0].GetField(), v[0].GetDescription())
+	if detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: v}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := Error(req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor validates every message the client sends.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &validatedStream{ServerStream: ss})
+	}
+}
+
+type validatedStream struct {
+	grpc.ServerStream
+}
+
+func (s *validatedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return Error(m)
+}
+
+type violations []*errdetails.BadRequest_FieldViolation
+
+func (v *violations) add(field, description string) {
+	*v = append(*v, &errdetails.BadRequest_FieldViolation{Field: field, Description: description})
+}
+
+func (v *violations) id(id int64) {
+	if id <= 0 {
+		v.add("id", "must be positive")
+	}
+}
+
+func (v *violations) amount(field string, amount float64) {
+	if amount <= 0 {
+		v.add(field, "must be positive")
+	}
+}
+
+// decimals checks amount has no more decimals than currency allows; see
+// money.Places.
+func (v *violations) decimals(field string, amount float64, currency string) {
+	if !money.Exact(amount, currency) {
+		v.add(field, fmt.Sprintf("must have at most %d decimals in %s", money.Places(currency), currency))
+	}
+}
+
+func (v *violations) currency(code string, required bool) {
+	if code == "" && !required {
+		return
+	}
+	if !currencyCode.MatchString(code) {
+		v.add("currency", "must be an ISO 4217 code such as USD")
+	}
+}
+
+func (v *violations) token(field, token string) {
+	v.match(field, token, paymentMethodToken, "must be a payment method token")
+}
+
+func (v *violations) match(field, value string, pattern *regexp.Regexp, description string) {
+	if !pattern.MatchString(value) {
+		v.add(field, description)
+	}
+}
+
+func (v *violations) page(page, pageSize int32) {
+	if page < 0 {
+		v.add("page", "must not be negative")
+	}
+	if pageSize < 0 || pageSize > MaxPageSize {
+		v.add("page_size", fmt.Sprintf("must be between 0 and %d", MaxPageSize))
+	}
+}
+
+func (v *violations) method(field, method string) {
+	if method != fees.MethodCard && method != fees.MethodBankTransfer {
+		v.add(field, fmt.Sprintf("must be %s or %s", fees.MethodCard, fees.MethodBankTransfer))
+	}
+}
+
+func (v *violations) pricing(field string, p *proto.Pricing) {
+	if p == nil {
+		v.add(field, "is required")
+		return
+	}
+	const percentage = "must be a percentage between 0 and 100"
+	if _, err := fees.NormalizePercentage(p.GetPercentage()); err != nil {
+		v.add(field+".percentage", percentage)
+	}
+	if p.GetFixed() < 0 || p.GetMin() < 0 || p.GetMax() < 0 {
+		v.add(field, "fixed, min and max must not be negative")
+	}
+	if p.GetMax() > 0 && p.GetMax() < p.GetMin() {
+		v.add(field+".max", "must not be below min")
+	}
+	if len(p.GetTiers()) > MaxPricingTiers {
+		v.add(field+".tiers", fmt.Sprintf("must hold at most %d tiers", MaxPricingTiers))
+	}
+	for i, t := range p.GetTiers() {
+		tier := fmt.Sprintf("%s.tiers[%d]", field, i)
+		if _, err := fees.NormalizePercentage(t.GetPercentage()); err != nil {
+			v.add(tier+".percentage", percentage)
+		}
+		if t.GetVolume() < 0 || t.GetFixed() < 0 {
+			v.add(tier, "volume and fixed must not be negative")
+		}
+		if i > 0 && t.GetVolume() <= p.GetTiers()[i-1].GetVolume() {
+			v.add(tier+".volume", "must be above the volume of the tier before")
+		}
+	}
+}
+
+func (v *violations) reason(reason string) {
+	if len(reason) > MaxReasonLength {
+		v.add("reason", fmt.Sprintf("must be at most %d bytes", MaxReasonLength))
+	}
+}
+
+func (v *violations) paymentDetails(m map[string]string, description, descriptor string) {
+	if err := metadata.Check(m); err != nil {
+		v.add("metadata", err.Error())
+	}
+	if err := metadata.CheckDescription(description); err != nil {
+		v.add("description", err.Error())
+	}
+	if err := metadata.CheckDescriptor(descriptor); err != nil {
+		v.add("statement_descriptor", err.Error())
+	}
+}
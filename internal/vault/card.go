@@ -0,0 +1,118 @@
+package vault
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// Card brands recognised from the card number's BIN range.
+const (
+	BrandVisa       = "visa"
+	BrandMastercard = "mastercard"
+	BrandAmex       = "amex"
+	BrandDiscover   = "discover"
+)
+
+// maxExpiryYears is how far in the future an expiry date may lie.
+const maxExpiryYears = 20
+
+var (
+	ErrInvalidNumber    = errors.New("invalid card number")
+	ErrUnsupportedBrand = errors.New("card brand not supported")
+	ErrInvalidExpiry    = errors.New("invalid card expiry")
+	ErrExpired          = errors.New("card expired")
+)
+
+// Card is card data as accepted from the client. The security code is not
+// part of it: it may be passed to a provider once but never stored.
+type Card struct {
+	Number   string
+	ExpMonth int
+	ExpYear  int
+}
+
+// Last4 returns the last four digits of the card number.
+func (c Card) Last4() string {
+	if len(c.Number) < 4 {
+		return c.Number
+	}
+	return c.Number[len(c.Number)-4:]
+}
+
+// Validate checks the card number's Luhn digit, that its BIN range and
+// length belong to a supported brand, and that the card has not expired at
+// now. It returns the brand.
+func Validate(card Card, now time.Time) (string, error) {
+	if !Luhn(card.Number) {
+		return "", ErrInvalidNumber
+	}
+	brand := Brand(card.Number)
+	if brand == "" {
+		return "", ErrUnsupportedBrand
+	}
+
+	if card.ExpMonth < 1 || card.ExpMonth > 12 || card.ExpYear < 2000 {
+		return "", ErrInvalidExpiry
+	}
+	now = now.UTC()
+	if card.ExpYear > now.Year()+maxExpiryYears {
+		return "", ErrInvalidExpiry
+	}
+	// A card is valid through the last day of its expiry month.
+	if !now.Before(time.Date(card.ExpYear, time.Month(card.ExpMonth)+1, 1, 0, 0, 0, 0, time.UTC)) {
+		return "", ErrExpired
+	}
+	return brand, nil
+}
+
+// Brand returns the brand whose BIN range and number length match number,
+// or "" if none does.
+func Brand(number string) string {
+	n := len(number)
+	switch {
+	case prefixIn(number, 1, 4, 4) && (n == 13 || n == 16 || n == 19):
+		return BrandVisa
+	case (prefixIn(number, 2, 51, 55) || prefixIn(number, 4, 2221, 2720)) && n == 16:
+		return BrandMastercard
+	case (prefixIn(number, 2, 34, 34) || prefixIn(number, 2, 37, 37)) && n == 15:
+		return BrandAmex
+	case (prefixIn(number, 4, 6011, 6011) || prefixIn(number, 3, 644, 649) || prefixIn(number, 2, 65, 65)) && n >= 16 && n <= 19:
+		return BrandDiscover
+	default:
+		return ""
+	}
+}
+
+// prefixIn reports whether the first digits of number, read as a number,
+// lie between lo and hi.
+func prefixIn(number string, digits, lo, hi int) bool {
+	if len(number) < digits {
+		return false
+	}
+	prefix, err := strconv.Atoi(number[:digits])
+	return err == nil && prefix >= lo && prefix <= hi
+}
+
+// Luhn reports whether number is 12 to 19 digits long and passes the Luhn
+// check.
+func Luhn(number string) bool {
+	if len(number) < 12 || len(number) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range number {
+		d := int(number[len(number)-1-i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,151 @@
+package vault
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go-lang-final/internal/store"
+)
+
+// keySize is the size of vault keys and data keys: AES-256.
+const keySize = 32
+
+// ErrUnknownKey is returned for a card sealed under a key the keyring no
+// longer holds.
+var ErrUnknownKey = errors.New("vault key not found")
+
+// Keyring holds the vault keys that wrap the per-card data keys. The first
+// key seals new cards; the others only open cards sealed before a rotation.
+type Keyring struct {
+	active string
+	keys   map[string][]byte
+}
+
+// LoadKeyFile reads a key file holding one hex-encoded 32-byte key per line,
+// the active key first. Blank lines and lines starting with # are ignored.
+func LoadKeyFile(path string) (*Keyring, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault key file: %v", err)
+	}
+	defer f.Close()
+
+	var keys [][]byte
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, err := hex.DecodeString(line)
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("vault key file holds an invalid key on line %d", n)
+		}
+		keys = append(keys, key)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read vault key file: %v", err)
+	}
+	return NewKeyring(keys...)
+}
+
+// NewKeyring returns a keyring sealing with the first of keys.
+func NewKeyring(keys ...[]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("vault needs at least one key")
+	}
+	k := &Keyring{keys: make(map[string][]byte)}
+	for i, key := range keys {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("vault keys must be %d bytes", keySize)
+		}
+		id := keyID(key)
+		if i == 0 {
+			k.active = id
+		}
+		k.keys[id] = key
+	}
+	return k, nil
+}
+
+// keyID names a key without revealing it.
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Seal encrypts plaintext with envelope encryption: under a fresh data key,
+// which is then wrapped by the active vault key. aad binds the ciphertext to
+// its owner, so a sealed card copied to another token does not open.
+func (k *Keyring) Seal(plaintext, aad []byte) (store.SealedCard, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return store.SealedCard{}, err
+	}
+	ciphertext, err := seal(dataKey, plaintext, aad)
+	if err != nil {
+		return store.SealedCard{}, err
+	}
+	wrapped, err := seal(k.keys[k.active], dataKey, []byte(k.active))
+	if err != nil {
+		return store.SealedCard{}, err
+	}
+	return store.SealedCard{KeyID: k.active, WrappedKey: wrapped, Ciphertext: ciphertext}, nil
+}
+
+// Open decrypts what Seal sealed with the same aad.
+func (k *Keyring) Open(sealed store.SealedCard, aad []byte) ([]byte, error) {
+	key, ok := k.keys[sealed.KeyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	dataKey, err := open(key, sealed.WrappedKey, []byte(sealed.KeyID))
+	if err != nil {
+		return nil, err
+	}
+	return open(dataKey, sealed.Ciphertext, aad)
+}
+
+// seal encrypts with AES-256-GCM and prepends the nonce.
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("vault ciphertext is truncated")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], aad)
+	if err != nil {
+		return nil, errors.New("vault ciphertext does not decrypt")
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,150 @@
+// Package vault tokenizes cards. Card data is validated, sealed with
+// envelope encryption under the keys of a local key file and stored in
+// payment_methods; callers get back an opaque token with the brand, last four
+// digits and expiry. Only the Detokenizer opens a sealed card, and every use
+// of it is audited.
+package vault
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go-lang-final/internal/audit"
+	"go-lang-final/internal/auth"
+	"go-lang-final/internal/models"
+	"go-lang-final/internal/store"
+	"go-lang-final/internal/tenant"
+)
+
+// TokenPrefix starts every payment method token.
+const TokenPrefix = "pm_"
+
+// ActionDetokenize is the audit action recorded for every detokenization.
+const ActionDetokenize = "payment_methods:detokenize"
+
+// Vault stores cards and describes them by token. It never returns card
+// numbers; see Detokenizer.
+type Vault struct {
+	store *store.PaymentStore
+	keys  *Keyring
+	// Now replaces the clock expiry dates are checked against.
+	Now func() time.Time
+}
+
+// Detokenizer opens vaulted cards for the payment provider layer. It is
+// kept apart from Vault so that only the code given one can see card
+// numbers.
+type Detokenizer struct {
+	vault    *Vault
+	recorder audit.Recorder
+}
+
+// New returns the vault and its detokenizer, which records every card it
+// opens with recorder.
+func New(store *store.PaymentStore, keys *Keyring, recorder audit.Recorder) (*Vault, *Detokenizer) {
+	v := &Vault{store: store, keys: keys, Now: time.Now}
+	return v, &Detokenizer{vault: v, recorder: recorder}
+}
+
+// Tokenize validates card and stores it for the tenant in ctx.
+func (v *Vault) Tokenize(ctx context.Context, card Card) (*models.PaymentMethod, error) {
+	brand, err := Validate(card, v.Now())
+	if err != nil {
+		return nil, err
+	}
+	scope, _ := tenant.FromContext(ctx)
+	tenantID, err := scope.Single()
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	token := TokenPrefix + hex.EncodeToString(b)
+	plaintext, err := json.Marshal(sealedCard{Number: card.Number, ExpMonth: card.ExpMonth, ExpYear: card.ExpYear})
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := v.keys.Seal(plaintext, additionalData(tenantID, token))
+	if err != nil {
+		return nil, err
+	}
+
+	return v.store.CreatePaymentMethod(ctx, models.PaymentMethod{
+		Token:    token,
+		Brand:    brand,
+		Last4:    card.Last4(),
+		ExpMonth: card.ExpMonth,
+		ExpYear:  card.ExpYear,
+	}, sealed)
+}
+
+// PaymentMethod describes the card behind token.
+func (v *Vault) PaymentMethod(ctx context.Context, token string) (*models.PaymentMethod, error) {
+	return v.store.GetPaymentMethod(ctx, token)
+}
+
+// Card opens the card behind token of the tenant in ctx. purpose says why,
+// such as "authorize with simulator", and goes to the audit log with the
+// principal in ctx. A detokenization that cannot be audited fails.
+func (d *Detokenizer) Card(ctx context.Context, token, purpose string) (Card, error) {
+	card, err := d.open(ctx, token)
+
+	event := audit.Event{
+		Principal: "system",
+		Action:    ActionDetokenize,
+		Resource:  token,
+		Outcome:   audit.OutcomeAllowed,
+		Reason:    purpose,
+	}
+	if p, ok := auth.PrincipalFromContext(ctx); ok {
+		event.Principal = p.ID
+	}
+	if err != nil {
+		event.Outcome, event.Reason = audit.OutcomeDenied, purpose+": "+err.Error()
+	}
+	if auditErr := d.recorder.Record(ctx, event); auditErr != nil {
+		return Card{}, errors.Join(errors.New("failed to audit detokenization"), auditErr)
+	}
+	return card, err
+}
+
+func (d *Detokenizer) open(ctx context.Context, token string) (Card, error) {
+	scope, _ := tenant.FromContext(ctx)
+	tenantID, err := scope.Single()
+	if err != nil {
+		return Card{}, err
+	}
+	sealed, err := d.vault.store.SealedPaymentMethod(ctx, token)
+	if err != nil {
+		return Card{}, err
+	}
+	plaintext, err := d.vault.keys.Open(*sealed, additionalData(tenantID, token))
+	if err != nil {
+		return Card{}, err
+	}
+
+	var c sealedCard
+	if err := json.Unmarshal(plaintext, &c); err != nil {
+		return Card{}, err
+	}
+	return Card{Number: c.Number, ExpMonth: c.ExpMonth, ExpYear: c.ExpYear}, nil
+}
+
+// sealedCard is the plaintext sealed for a card.
+type sealedCard struct {
+	Number   string `json:"number"`
+	ExpMonth int    `json:"exp_month"`
+	ExpYear  int    `json:"exp_year"`
+}
+
+// additionalData binds a sealed card to its tenant and token.
+func additionalData(tenantID, token string) []byte {
+	return []byte(tenantID + "/" + token)
+}
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS payments (
+    id BIGINT PRIMARY KEY,
+    amount NUMERIC(18, 2) NOT NULL,
+    currency TEXT NOT NULL
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS role_permissions;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS roles;
@@ -0,0 +1,33 @@
+ALTER TABLE api_keys ADD COLUMN roles TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE role_permissions (
+    role TEXT NOT NULL,
+    permission TEXT NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('viewer', 'payments:read'),
+    ('operator', 'payments:read'),
+    ('operator', 'payments:write'),
+    ('operator', 'refunds:create'),
+    ('admin', 'payments:read'),
+    ('admin', 'payments:write'),
+    ('admin', 'payments:delete'),
+    ('admin', 'refunds:create'),
+    ('admin', 'audit:read'),
+    ('auditor', 'payments:read'),
+    ('auditor', 'audit:read');
+
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    time TIMESTAMPTZ NOT NULL,
+    request_id TEXT NOT NULL DEFAULT '',
+    principal TEXT NOT NULL,
+    action TEXT NOT NULL,
+    resource TEXT NOT NULL,
+    outcome TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX audit_log_time_idx ON audit_log (time);
@@ -0,0 +1,9 @@
+DELETE FROM role_permissions WHERE role = 'platform_admin';
+DROP POLICY IF EXISTS payments_tenant_isolation ON payments;
+ALTER TABLE payments NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE payments DISABLE ROW LEVEL SECURITY;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE payments DROP CONSTRAINT payments_pkey;
+ALTER TABLE payments DROP COLUMN tenant_id;
+ALTER TABLE payments ADD PRIMARY KEY (id);
+DROP TABLE IF EXISTS tenants;
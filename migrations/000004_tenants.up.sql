@@ -0,0 +1,40 @@
+CREATE TABLE tenants (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    allowed_currencies TEXT[] NOT NULL DEFAULT '{}',
+    max_payment_amount NUMERIC(18, 2),
+    max_payments BIGINT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Rows that existed before multi-tenancy are assigned to a default tenant.
+INSERT INTO tenants (id, name) VALUES ('default', 'Default tenant');
+
+ALTER TABLE payments ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE payments ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE payments DROP CONSTRAINT payments_pkey;
+ALTER TABLE payments ADD PRIMARY KEY (tenant_id, id);
+
+ALTER TABLE api_keys ADD COLUMN tenant_id TEXT REFERENCES tenants (id);
+
+-- Second line of defence behind the tenant_id filters in PaymentStore. The
+-- store sets app.tenant_id and app.cross_tenant per transaction. FORCE makes
+-- the policy apply to the table owner too; the service must not connect as
+-- a superuser, which bypasses RLS.
+ALTER TABLE payments ENABLE ROW LEVEL SECURITY;
+ALTER TABLE payments FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY payments_tenant_isolation ON payments
+    USING (
+        tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.cross_tenant', true) = 'on'
+    )
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('platform_admin', 'payments:read'),
+    ('platform_admin', 'payments:write'),
+    ('platform_admin', 'payments:delete'),
+    ('platform_admin', 'refunds:create'),
+    ('platform_admin', 'audit:read'),
+    ('platform_admin', 'tenants:cross');
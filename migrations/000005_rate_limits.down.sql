@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS daily_quotas;
+ALTER TABLE tenants DROP COLUMN IF EXISTS daily_amount_limit;
+ALTER TABLE tenants DROP COLUMN IF EXISTS daily_payment_limit;
+DROP TABLE IF EXISTS rate_limit_buckets;
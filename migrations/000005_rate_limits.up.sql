@@ -0,0 +1,16 @@
+CREATE TABLE rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+ALTER TABLE tenants ADD COLUMN daily_payment_limit BIGINT;
+ALTER TABLE tenants ADD COLUMN daily_amount_limit NUMERIC(18, 2);
+
+CREATE TABLE daily_quotas (
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    day DATE NOT NULL,
+    payments BIGINT NOT NULL,
+    amount NUMERIC(18, 2) NOT NULL,
+    PRIMARY KEY (tenant_id, day)
+);
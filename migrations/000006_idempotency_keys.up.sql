@@ -0,0 +1,17 @@
+-- One row per idempotency key that created a payment. request_hash detects a
+-- key being replayed with a different request.
+CREATE TABLE idempotency_keys (
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    key TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    payment_id BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, key)
+);
+
+ALTER TABLE idempotency_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE idempotency_keys FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY idempotency_keys_tenant_isolation ON idempotency_keys
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS payments_record_event ON payments;
+DROP FUNCTION IF EXISTS record_payment_event();
+DROP TABLE IF EXISTS payment_events;
+ALTER TABLE payments DROP COLUMN IF EXISTS refunded_amount;
+ALTER TABLE payments DROP COLUMN IF EXISTS status;
@@ -0,0 +1,66 @@
+ALTER TABLE payments ADD COLUMN status TEXT NOT NULL DEFAULT 'created'
+    CHECK (status IN ('created', 'partially_refunded', 'refunded', 'cancelled'));
+ALTER TABLE payments ADD COLUMN refunded_amount NUMERIC(18, 2) NOT NULL DEFAULT 0
+    CHECK (refunded_amount >= 0 AND refunded_amount <= amount);
+
+-- The history of every payment, written by a trigger so no code path that
+-- changes payments can skip it. PaymentStore passes the reason for a change
+-- in app.event_reason.
+CREATE TABLE payment_events (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    payment_id BIGINT NOT NULL,
+    type TEXT NOT NULL,
+    amount NUMERIC(18, 2) NOT NULL,
+    currency TEXT NOT NULL,
+    status TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX payment_events_payment_idx ON payment_events (tenant_id, payment_id, id);
+
+ALTER TABLE payment_events ENABLE ROW LEVEL SECURITY;
+ALTER TABLE payment_events FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY payment_events_tenant_isolation ON payment_events
+    USING (
+        tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.cross_tenant', true) = 'on'
+    )
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+CREATE FUNCTION record_payment_event() RETURNS trigger AS $$
+DECLARE
+    reason TEXT := COALESCE(current_setting('app.event_reason', true), '');
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO payment_events (tenant_id, payment_id, type, amount, currency, status, reason)
+        VALUES (NEW.tenant_id, NEW.id, 'created', NEW.amount, NEW.currency, NEW.status, reason);
+    ELSIF TG_OP = 'UPDATE' THEN
+        INSERT INTO payment_events (tenant_id, payment_id, type, amount, currency, status, reason)
+        VALUES (
+            NEW.tenant_id, NEW.id,
+            CASE
+                WHEN NEW.refunded_amount <> OLD.refunded_amount THEN 'refunded'
+                WHEN NEW.status = 'cancelled' AND OLD.status <> 'cancelled' THEN 'cancelled'
+                ELSE 'updated'
+            END,
+            CASE
+                WHEN NEW.refunded_amount <> OLD.refunded_amount THEN NEW.refunded_amount - OLD.refunded_amount
+                ELSE NEW.amount
+            END,
+            NEW.currency, NEW.status, reason
+        );
+    ELSE
+        INSERT INTO payment_events (tenant_id, payment_id, type, amount, currency, status, reason)
+        VALUES (OLD.tenant_id, OLD.id, 'deleted', OLD.amount, OLD.currency, OLD.status, reason);
+        RETURN OLD;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER payments_record_event
+    AFTER INSERT OR UPDATE OR DELETE ON payments
+    FOR EACH ROW EXECUTE FUNCTION record_payment_event();
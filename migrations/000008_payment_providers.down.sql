@@ -0,0 +1,41 @@
+CREATE OR REPLACE FUNCTION record_payment_event() RETURNS trigger AS $$
+DECLARE
+    reason TEXT := COALESCE(current_setting('app.event_reason', true), '');
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO payment_events (tenant_id, payment_id, type, amount, currency, status, reason)
+        VALUES (NEW.tenant_id, NEW.id, 'created', NEW.amount, NEW.currency, NEW.status, reason);
+    ELSIF TG_OP = 'UPDATE' THEN
+        INSERT INTO payment_events (tenant_id, payment_id, type, amount, currency, status, reason)
+        VALUES (
+            NEW.tenant_id, NEW.id,
+            CASE
+                WHEN NEW.refunded_amount <> OLD.refunded_amount THEN 'refunded'
+                WHEN NEW.status = 'cancelled' AND OLD.status <> 'cancelled' THEN 'cancelled'
+                ELSE 'updated'
+            END,
+            CASE
+                WHEN NEW.refunded_amount <> OLD.refunded_amount THEN NEW.refunded_amount - OLD.refunded_amount
+                ELSE NEW.amount
+            END,
+            NEW.currency, NEW.status, reason
+        );
+    ELSE
+        INSERT INTO payment_events (tenant_id, payment_id, type, amount, currency, status, reason)
+        VALUES (OLD.tenant_id, OLD.id, 'deleted', OLD.amount, OLD.currency, OLD.status, reason);
+        RETURN OLD;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS payments_provider_pending_idx;
+DROP INDEX IF EXISTS payments_provider_ref_idx;
+ALTER TABLE payments DROP COLUMN IF EXISTS provider_status;
+ALTER TABLE payments DROP COLUMN IF EXISTS provider_ref;
+ALTER TABLE payments DROP COLUMN IF EXISTS provider;
+
+UPDATE payments SET status = 'cancelled' WHERE status = 'requires_action';
+ALTER TABLE payments DROP CONSTRAINT payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check
+    CHECK (status IN ('created', 'partially_refunded', 'refunded', 'cancelled'));
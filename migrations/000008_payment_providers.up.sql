@@ -0,0 +1,50 @@
+-- A payment waits in requires_action while the customer completes a 3-D
+-- Secure challenge with the provider.
+ALTER TABLE payments DROP CONSTRAINT payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check
+    CHECK (status IN ('requires_action', 'created', 'partially_refunded', 'refunded', 'cancelled'));
+
+-- The provider that processed the payment, its reference for the
+-- transaction and the provider's last known status of it. Payments recorded
+-- without a provider leave them empty.
+ALTER TABLE payments ADD COLUMN provider TEXT NOT NULL DEFAULT '';
+ALTER TABLE payments ADD COLUMN provider_ref TEXT NOT NULL DEFAULT '';
+ALTER TABLE payments ADD COLUMN provider_status TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX payments_provider_ref_idx ON payments (provider, provider_ref) WHERE provider_ref <> '';
+CREATE INDEX payments_provider_pending_idx ON payments (id)
+    WHERE status = 'requires_action' OR provider_status IN ('authorized', 'captured');
+
+-- Record completed challenges and settlement as their own event types.
+CREATE OR REPLACE FUNCTION record_payment_event() RETURNS trigger AS $$
+DECLARE
+    reason TEXT := COALESCE(current_setting('app.event_reason', true), '');
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO payment_events (tenant_id, payment_id, type, amount, currency, status, reason)
+        VALUES (NEW.tenant_id, NEW.id, 'created', NEW.amount, NEW.currency, NEW.status, reason);
+    ELSIF TG_OP = 'UPDATE' THEN
+        INSERT INTO payment_events (tenant_id, payment_id, type, amount, currency, status, reason)
+        VALUES (
+            NEW.tenant_id, NEW.id,
+            CASE
+                WHEN NEW.refunded_amount <> OLD.refunded_amount THEN 'refunded'
+                WHEN NEW.status = 'cancelled' AND OLD.status <> 'cancelled' THEN 'cancelled'
+                WHEN OLD.status = 'requires_action' AND NEW.status = 'created' THEN 'authorized'
+                WHEN NEW.provider_status = 'settled' AND OLD.provider_status <> 'settled' THEN 'settled'
+                ELSE 'updated'
+            END,
+            CASE
+                WHEN NEW.refunded_amount <> OLD.refunded_amount THEN NEW.refunded_amount - OLD.refunded_amount
+                ELSE NEW.amount
+            END,
+            NEW.currency, NEW.status, reason
+        );
+    ELSE
+        INSERT INTO payment_events (tenant_id, payment_id, type, amount, currency, status, reason)
+        VALUES (OLD.tenant_id, OLD.id, 'deleted', OLD.amount, OLD.currency, OLD.status, reason);
+        RETURN OLD;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
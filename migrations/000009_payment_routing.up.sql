@@ -0,0 +1,27 @@
+-- How the provider of each payment was chosen: the routing rule that matched,
+-- the card's BIN country and every provider tried, in order, with its
+-- outcome. Kept for analysis of routing and failover, so it outlives the
+-- payment like payment_events do.
+CREATE TABLE payment_routing_decisions (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    payment_id BIGINT NOT NULL,
+    rule TEXT NOT NULL DEFAULT '',
+    country TEXT NOT NULL DEFAULT '',
+    provider TEXT NOT NULL,
+    attempts JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX payment_routing_decisions_payment_idx ON payment_routing_decisions (tenant_id, payment_id);
+CREATE INDEX payment_routing_decisions_rule_idx ON payment_routing_decisions (rule, created_at);
+
+ALTER TABLE payment_routing_decisions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE payment_routing_decisions FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY payment_routing_decisions_tenant_isolation ON payment_routing_decisions
+    USING (
+        tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.cross_tenant', true) = 'on'
+    )
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
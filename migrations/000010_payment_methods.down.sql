@@ -0,0 +1,3 @@
+DELETE FROM role_permissions WHERE permission = 'payment_methods:create';
+ALTER TABLE payments DROP COLUMN IF EXISTS payment_method;
+DROP TABLE IF EXISTS payment_methods;
@@ -0,0 +1,31 @@
+-- Cards tokenized by the vault. The card number is only stored encrypted:
+-- ciphertext is sealed with a per-card data key, which is itself wrapped by
+-- the vault key key_id. Raw card numbers never reach the payments table.
+CREATE TABLE payment_methods (
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    token TEXT NOT NULL,
+    brand TEXT NOT NULL,
+    last4 TEXT NOT NULL,
+    exp_month INTEGER NOT NULL CHECK (exp_month BETWEEN 1 AND 12),
+    exp_year INTEGER NOT NULL,
+    key_id TEXT NOT NULL,
+    wrapped_key BYTEA NOT NULL,
+    ciphertext BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, token)
+);
+
+ALTER TABLE payment_methods ENABLE ROW LEVEL SECURITY;
+ALTER TABLE payment_methods FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY payment_methods_tenant_isolation ON payment_methods
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+-- The token of the vaulted card a payment charged, if any.
+ALTER TABLE payments ADD COLUMN payment_method TEXT NOT NULL DEFAULT '';
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('operator', 'payment_methods:create'),
+    ('admin', 'payment_methods:create'),
+    ('platform_admin', 'payment_methods:create');
@@ -0,0 +1,4 @@
+DELETE FROM role_permissions WHERE permission = 'payouts:write';
+DROP TABLE IF EXISTS credit_transfers;
+DROP TABLE IF EXISTS credit_transfer_batches;
+DROP TABLE IF EXISTS bank_accounts;
@@ -0,0 +1,73 @@
+-- SEPA bank accounts. Payments made from one are recorded with its token in
+-- payments.payment_method; refunds of those payments and payouts are sent
+-- back to it as credit transfers. The IBAN is kept in full because the
+-- pain.001 files sent to the bank need it.
+CREATE TABLE bank_accounts (
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    token TEXT NOT NULL,
+    holder_name TEXT NOT NULL,
+    iban TEXT NOT NULL,
+    bic TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, token)
+);
+
+-- A pain.001 file of credit transfers as it was handed to the bank.
+-- bank_status is the group status of the last pain.002 report on it.
+CREATE TABLE credit_transfer_batches (
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    id TEXT NOT NULL,
+    execution_date DATE NOT NULL,
+    transfers INTEGER NOT NULL,
+    total NUMERIC(18, 2) NOT NULL,
+    bank_status TEXT NOT NULL DEFAULT '',
+    document BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, id)
+);
+
+-- Outgoing SEPA credit transfers. They wait as pending until a batch picks
+-- them up and follow the bank's status reports from there. id is the
+-- end-to-end id the bank reports them by.
+CREATE TABLE credit_transfers (
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    id TEXT NOT NULL,
+    bank_account TEXT NOT NULL,
+    payment_id BIGINT,
+    kind TEXT NOT NULL CHECK (kind IN ('refund', 'payout')),
+    amount NUMERIC(18, 2) NOT NULL CHECK (amount > 0),
+    currency TEXT NOT NULL CHECK (currency = 'EUR'),
+    remittance TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'submitted', 'accepted', 'settled', 'rejected')),
+    reason TEXT NOT NULL DEFAULT '',
+    batch_id TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, id),
+    FOREIGN KEY (tenant_id, bank_account) REFERENCES bank_accounts (tenant_id, token),
+    FOREIGN KEY (tenant_id, batch_id) REFERENCES credit_transfer_batches (tenant_id, id)
+);
+
+CREATE INDEX credit_transfers_pending_idx ON credit_transfers (tenant_id, created_at) WHERE status = 'pending';
+CREATE INDEX credit_transfers_batch_idx ON credit_transfers (tenant_id, batch_id);
+
+ALTER TABLE bank_accounts ENABLE ROW LEVEL SECURITY;
+ALTER TABLE bank_accounts FORCE ROW LEVEL SECURITY;
+ALTER TABLE credit_transfer_batches ENABLE ROW LEVEL SECURITY;
+ALTER TABLE credit_transfer_batches FORCE ROW LEVEL SECURITY;
+ALTER TABLE credit_transfers ENABLE ROW LEVEL SECURITY;
+ALTER TABLE credit_transfers FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY bank_accounts_tenant_isolation ON bank_accounts
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+CREATE POLICY credit_transfer_batches_tenant_isolation ON credit_transfer_batches
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+CREATE POLICY credit_transfers_tenant_isolation ON credit_transfers
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'payouts:write'),
+    ('platform_admin', 'payouts:write');
@@ -0,0 +1,4 @@
+DELETE FROM role_permissions WHERE permission = 'reconciliation:write';
+DROP INDEX IF EXISTS payment_events_settlement_idx;
+DROP TABLE IF EXISTS reconciliation_lines;
+DROP TABLE IF EXISTS reconciliation_runs;
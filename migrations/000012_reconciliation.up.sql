@@ -0,0 +1,63 @@
+-- One import of a bank statement file and the options it was matched with.
+-- The counts summarize the status of its lines at import time.
+CREATE TABLE reconciliation_runs (
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    id TEXT NOT NULL,
+    format TEXT NOT NULL CHECK (format IN ('camt.053', 'mt940')),
+    statement_id TEXT NOT NULL,
+    account TEXT NOT NULL DEFAULT '',
+    tolerance NUMERIC(18, 2) NOT NULL,
+    date_window_days INTEGER NOT NULL,
+    lines INTEGER NOT NULL,
+    matched INTEGER NOT NULL,
+    unmatched INTEGER NOT NULL,
+    duplicated INTEGER NOT NULL,
+    amount_mismatches INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, id)
+);
+
+-- The statement lines of a run with what they matched. event_id is the
+-- payment event (created or refunded) the line settles; every line that is
+-- not matched is an exception until someone resolves it.
+CREATE TABLE reconciliation_lines (
+    tenant_id TEXT NOT NULL,
+    run_id TEXT NOT NULL,
+    line INTEGER NOT NULL,
+    bank_ref TEXT NOT NULL DEFAULT '',
+    reference TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    direction TEXT NOT NULL CHECK (direction IN ('credit', 'debit')),
+    amount NUMERIC(18, 2) NOT NULL,
+    currency TEXT NOT NULL,
+    booking_date DATE NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('matched', 'unmatched', 'duplicated', 'amount_mismatch')),
+    payment_id BIGINT,
+    event_id BIGINT,
+    difference NUMERIC(18, 2) NOT NULL DEFAULT 0,
+    resolution TEXT NOT NULL DEFAULT '' CHECK (resolution IN ('', 'accepted', 'written_off')),
+    resolution_note TEXT NOT NULL DEFAULT '',
+    resolved_by TEXT NOT NULL DEFAULT '',
+    resolved_at TIMESTAMPTZ,
+    PRIMARY KEY (tenant_id, run_id, line),
+    FOREIGN KEY (tenant_id, run_id) REFERENCES reconciliation_runs (tenant_id, id)
+);
+
+CREATE INDEX reconciliation_lines_event_idx ON reconciliation_lines (tenant_id, event_id) WHERE event_id IS NOT NULL;
+CREATE INDEX payment_events_settlement_idx ON payment_events (tenant_id, created_at) WHERE type IN ('created', 'refunded');
+
+ALTER TABLE reconciliation_runs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE reconciliation_runs FORCE ROW LEVEL SECURITY;
+ALTER TABLE reconciliation_lines ENABLE ROW LEVEL SECURITY;
+ALTER TABLE reconciliation_lines FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY reconciliation_runs_tenant_isolation ON reconciliation_runs
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+CREATE POLICY reconciliation_lines_tenant_isolation ON reconciliation_lines
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'reconciliation:write'),
+    ('platform_admin', 'reconciliation:write');
@@ -0,0 +1,5 @@
+DELETE FROM role_permissions WHERE permission = 'ledger:read';
+DROP INDEX IF EXISTS payment_events_settled_idx;
+DROP TABLE IF EXISTS settlement_lines;
+DROP TABLE IF EXISTS settlement_batches;
+DROP TABLE IF EXISTS ledger_postings;
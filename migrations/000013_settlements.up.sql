@@ -0,0 +1,92 @@
+-- The ledger records money movements as balanced entries: every posting of
+-- an entry shares its entry_id, debits are positive, credits negative, and
+-- the postings of an entry sum to zero. source and source_id name what
+-- produced the entry, such as a settlement batch.
+CREATE TABLE ledger_postings (
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    id BIGSERIAL,
+    entry_id TEXT NOT NULL,
+    account TEXT NOT NULL,
+    amount NUMERIC(18, 2) NOT NULL,
+    currency TEXT NOT NULL,
+    payment_id BIGINT,
+    source TEXT NOT NULL,
+    source_id TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, id)
+);
+
+CREATE INDEX ledger_postings_payment_idx ON ledger_postings (tenant_id, payment_id) WHERE payment_id IS NOT NULL;
+CREATE INDEX ledger_postings_account_idx ON ledger_postings (tenant_id, account, id);
+
+-- One settlement file of a provider. reference is the provider's name for
+-- the file and makes importing it twice fail. The counts and totals
+-- summarize its lines; expected_fees is what the contracted rate charges.
+CREATE TABLE settlement_batches (
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    id TEXT NOT NULL,
+    provider TEXT NOT NULL,
+    reference TEXT NOT NULL,
+    settlement_date DATE NOT NULL,
+    currency TEXT NOT NULL,
+    lines INTEGER NOT NULL,
+    matched INTEGER NOT NULL,
+    unexpected INTEGER NOT NULL,
+    missing INTEGER NOT NULL,
+    duplicated INTEGER NOT NULL,
+    amount_mismatches INTEGER NOT NULL,
+    fee_deviations INTEGER NOT NULL,
+    gross NUMERIC(18, 2) NOT NULL,
+    fees NUMERIC(18, 2) NOT NULL,
+    net NUMERIC(18, 2) NOT NULL,
+    expected_fees NUMERIC(18, 2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, id),
+    UNIQUE (tenant_id, provider, reference)
+);
+
+-- The transactions of a batch plus one missing line per payment the
+-- provider settled that the file left out.
+CREATE TABLE settlement_lines (
+    tenant_id TEXT NOT NULL,
+    batch_id TEXT NOT NULL,
+    line INTEGER NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('charge', 'refund')),
+    reference TEXT NOT NULL DEFAULT '',
+    payment_id BIGINT,
+    currency TEXT NOT NULL,
+    gross NUMERIC(18, 2) NOT NULL,
+    fee NUMERIC(18, 2) NOT NULL,
+    net NUMERIC(18, 2) NOT NULL,
+    expected_fee NUMERIC(18, 2) NOT NULL,
+    status TEXT NOT NULL
+        CHECK (status IN ('matched', 'unexpected', 'missing', 'duplicated', 'amount_mismatch', 'fee_deviation')),
+    PRIMARY KEY (tenant_id, batch_id, line),
+    FOREIGN KEY (tenant_id, batch_id) REFERENCES settlement_batches (tenant_id, id)
+);
+
+CREATE INDEX settlement_lines_payment_idx ON settlement_lines (tenant_id, payment_id) WHERE payment_id IS NOT NULL;
+CREATE INDEX payment_events_settled_idx ON payment_events (tenant_id, created_at) WHERE type = 'settled';
+
+ALTER TABLE ledger_postings ENABLE ROW LEVEL SECURITY;
+ALTER TABLE ledger_postings FORCE ROW LEVEL SECURITY;
+ALTER TABLE settlement_batches ENABLE ROW LEVEL SECURITY;
+ALTER TABLE settlement_batches FORCE ROW LEVEL SECURITY;
+ALTER TABLE settlement_lines ENABLE ROW LEVEL SECURITY;
+ALTER TABLE settlement_lines FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY ledger_postings_tenant_isolation ON ledger_postings
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+CREATE POLICY settlement_batches_tenant_isolation ON settlement_batches
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+CREATE POLICY settlement_lines_tenant_isolation ON settlement_lines
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'ledger:read'),
+    ('auditor', 'ledger:read'),
+    ('platform_admin', 'ledger:read');
@@ -0,0 +1,4 @@
+DELETE FROM role_permissions WHERE permission = 'fx_rates:write';
+DROP TABLE IF EXISTS payment_conversions;
+DROP TABLE IF EXISTS fx_quotes;
+DROP TABLE IF EXISTS fx_rates;
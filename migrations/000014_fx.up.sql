@@ -0,0 +1,65 @@
+-- Exchange rates: one unit of base is worth rate units of quote from
+-- effective_at on, until a later rate of the pair takes effect. Rates are
+-- shared by every tenant and only ever added, so the rate any conversion
+-- used stays on record.
+CREATE TABLE fx_rates (
+    base TEXT NOT NULL,
+    quote TEXT NOT NULL,
+    rate NUMERIC(28, 10) NOT NULL CHECK (rate > 0),
+    effective_at TIMESTAMPTZ NOT NULL,
+    source TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (base, quote, effective_at),
+    CHECK (base <> quote)
+);
+
+-- A rate locked for a tenant until expires_at. amount and settlement_amount
+-- are only set when the quote was asked for an amount.
+CREATE TABLE fx_quotes (
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    id TEXT NOT NULL,
+    currency TEXT NOT NULL,
+    settlement_currency TEXT NOT NULL,
+    rate NUMERIC(28, 10) NOT NULL,
+    rate_effective_at TIMESTAMPTZ NOT NULL,
+    amount NUMERIC(18, 2),
+    settlement_amount NUMERIC(18, 2),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, id)
+);
+
+-- The conversion of a payment from the currency the customer paid in (the
+-- presentment currency) to the one the merchant settles in, with the rate
+-- used. Like payment_events it outlives the payment.
+CREATE TABLE payment_conversions (
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    payment_id BIGINT NOT NULL,
+    amount NUMERIC(18, 2) NOT NULL,
+    currency TEXT NOT NULL,
+    settlement_amount NUMERIC(18, 2) NOT NULL,
+    settlement_currency TEXT NOT NULL,
+    rate NUMERIC(28, 10) NOT NULL,
+    rate_effective_at TIMESTAMPTZ NOT NULL,
+    quote_id TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, payment_id)
+);
+
+ALTER TABLE fx_quotes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE fx_quotes FORCE ROW LEVEL SECURITY;
+ALTER TABLE payment_conversions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE payment_conversions FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY fx_quotes_tenant_isolation ON fx_quotes
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+CREATE POLICY payment_conversions_tenant_isolation ON payment_conversions
+    USING (
+        tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.cross_tenant', true) = 'on'
+    )
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('platform_admin', 'fx_rates:write');
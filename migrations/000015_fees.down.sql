@@ -0,0 +1,4 @@
+DELETE FROM role_permissions WHERE permission = 'pricing_plans:write';
+DROP TABLE IF EXISTS payment_fees;
+ALTER TABLE tenants DROP COLUMN IF EXISTS pricing_plan_id;
+DROP TABLE IF EXISTS pricing_plans;
@@ -0,0 +1,45 @@
+-- Pricing plans, shared by every tenant. pricing and overrides hold the
+-- rules as the API shows them; see models.PricingPlan.
+CREATE TABLE pricing_plans (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    pricing JSONB NOT NULL,
+    overrides JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE tenants ADD COLUMN pricing_plan_id TEXT REFERENCES pricing_plans (id);
+
+-- The fee a payment was charged when it was captured, with the pricing it
+-- came from, so later changes to the plan leave it as it was. volume is
+-- the tenant's volume of the month in currency before the payment.
+CREATE TABLE payment_fees (
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    payment_id BIGINT NOT NULL,
+    plan_id TEXT NOT NULL REFERENCES pricing_plans (id),
+    amount NUMERIC(18, 2) NOT NULL,
+    currency TEXT NOT NULL,
+    payment_amount NUMERIC(18, 2) NOT NULL,
+    method TEXT NOT NULL,
+    percentage NUMERIC(7, 4),
+    fixed NUMERIC(18, 2) NOT NULL DEFAULT 0,
+    volume NUMERIC(18, 2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, payment_id)
+);
+
+CREATE INDEX payment_fees_month_idx ON payment_fees (tenant_id, currency, created_at);
+
+ALTER TABLE payment_fees ENABLE ROW LEVEL SECURITY;
+ALTER TABLE payment_fees FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY payment_fees_tenant_isolation ON payment_fees
+    USING (
+        tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.cross_tenant', true) = 'on'
+    )
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('platform_admin', 'pricing_plans:write');
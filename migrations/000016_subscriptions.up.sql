@@ -0,0 +1,95 @@
+-- Recurring payments. A plan says what is billed and how often; a
+-- subscription charges its payment method for the plan every period.
+CREATE TABLE subscription_plans (
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    interval TEXT NOT NULL CHECK (interval IN ('day', 'week', 'month', 'year')),
+    interval_count INTEGER NOT NULL DEFAULT 1 CHECK (interval_count > 0),
+    amount NUMERIC(18, 2) NOT NULL CHECK (amount > 0),
+    currency TEXT NOT NULL,
+    trial_days INTEGER NOT NULL DEFAULT 0 CHECK (trial_days >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, id)
+);
+
+-- next_charge_at is when the scheduler charges the next renewal. While a
+-- replica charges one it is pushed out by a lease, so other replicas leave
+-- the subscription alone until the lease runs out. The period ends are
+-- periods plan intervals after anchor; renewals counts every period paid.
+CREATE TABLE subscriptions (
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    id TEXT NOT NULL,
+    plan_id TEXT NOT NULL,
+    payment_method TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL CHECK (status IN ('trialing', 'active', 'past_due', 'canceled')),
+    current_period_start TIMESTAMPTZ NOT NULL,
+    current_period_end TIMESTAMPTZ NOT NULL,
+    trial_end TIMESTAMPTZ,
+    next_charge_at TIMESTAMPTZ,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    balance NUMERIC(18, 2) NOT NULL DEFAULT 0,
+    cancel_at_period_end BOOLEAN NOT NULL DEFAULT false,
+    canceled_at TIMESTAMPTZ,
+    renewals INTEGER NOT NULL DEFAULT 0,
+    anchor TIMESTAMPTZ NOT NULL,
+    periods INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, id),
+    FOREIGN KEY (tenant_id, plan_id) REFERENCES subscription_plans (tenant_id, id)
+);
+
+CREATE INDEX subscriptions_due_idx ON subscriptions (next_charge_at) WHERE status <> 'canceled';
+
+-- Every attempt to collect a renewal. The payment id is allocated when the
+-- attempt is first claimed and the payment is created with an idempotency
+-- key derived from the attempt, so an attempt retried after a crash or by
+-- another replica reaches the same payment and provider transaction.
+CREATE TABLE subscription_charges (
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    id TEXT NOT NULL,
+    subscription_id TEXT NOT NULL,
+    period INTEGER NOT NULL,
+    attempt INTEGER NOT NULL,
+    payment_id BIGINT,
+    amount NUMERIC(18, 2) NOT NULL,
+    currency TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, id),
+    UNIQUE (tenant_id, subscription_id, period, attempt),
+    FOREIGN KEY (tenant_id, subscription_id) REFERENCES subscriptions (tenant_id, id)
+);
+
+CREATE UNIQUE INDEX subscription_charges_payment_idx ON subscription_charges (tenant_id, payment_id) WHERE payment_id IS NOT NULL;
+
+ALTER TABLE subscription_plans ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscription_plans FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_charges ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscription_charges FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY subscription_plans_tenant_isolation ON subscription_plans
+    USING (
+        tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.cross_tenant', true) = 'on'
+    )
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+CREATE POLICY subscriptions_tenant_isolation ON subscriptions
+    USING (
+        tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.cross_tenant', true) = 'on'
+    )
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+CREATE POLICY subscription_charges_tenant_isolation ON subscription_charges
+    USING (
+        tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.cross_tenant', true) = 'on'
+    )
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
@@ -0,0 +1,81 @@
+-- The last invoice number given out to each tenant. Numbers are taken in
+-- the transaction that finalizes an invoice, with the row locked, so a
+-- rolled back finalization gives its number back and the sequence has no
+-- gaps.
+CREATE TABLE invoice_sequences (
+    tenant_id TEXT PRIMARY KEY REFERENCES tenants (id),
+    last_number BIGINT NOT NULL
+);
+
+-- lines holds the line items as the API shows them; see
+-- models.InvoiceLine. The totals are worked out from them when the
+-- invoice is saved. number is set when the invoice is finalized.
+CREATE TABLE invoices (
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    id TEXT NOT NULL,
+    number TEXT,
+    status TEXT NOT NULL CHECK (status IN ('draft', 'open', 'paid', 'void')),
+    currency TEXT NOT NULL,
+    customer_name TEXT NOT NULL DEFAULT '',
+    customer_email TEXT NOT NULL DEFAULT '',
+    customer_address TEXT NOT NULL DEFAULT '',
+    memo TEXT NOT NULL DEFAULT '',
+    lines JSONB NOT NULL,
+    subtotal NUMERIC(18, 2) NOT NULL,
+    discount NUMERIC(18, 2) NOT NULL,
+    tax NUMERIC(18, 2) NOT NULL,
+    total NUMERIC(18, 2) NOT NULL,
+    amount_paid NUMERIC(18, 2) NOT NULL DEFAULT 0 CHECK (amount_paid <= total),
+    due_date DATE,
+    issued_at TIMESTAMPTZ,
+    paid_at TIMESTAMPTZ,
+    voided_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, id),
+    UNIQUE (tenant_id, number),
+    CHECK ((status = 'draft') = (number IS NULL) OR status = 'void')
+);
+
+CREATE INDEX invoices_status_idx ON invoices (tenant_id, status, created_at);
+
+-- The parts of payments applied to invoices.
+CREATE TABLE invoice_payments (
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    invoice_id TEXT NOT NULL,
+    payment_id BIGINT NOT NULL,
+    amount NUMERIC(18, 2) NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, invoice_id, payment_id),
+    FOREIGN KEY (tenant_id, invoice_id) REFERENCES invoices (tenant_id, id)
+);
+
+CREATE INDEX invoice_payments_payment_idx ON invoice_payments (tenant_id, payment_id);
+
+ALTER TABLE invoice_sequences ENABLE ROW LEVEL SECURITY;
+ALTER TABLE invoice_sequences FORCE ROW LEVEL SECURITY;
+ALTER TABLE invoices ENABLE ROW LEVEL SECURITY;
+ALTER TABLE invoices FORCE ROW LEVEL SECURITY;
+ALTER TABLE invoice_payments ENABLE ROW LEVEL SECURITY;
+ALTER TABLE invoice_payments FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY invoice_sequences_tenant_isolation ON invoice_sequences
+    USING (
+        tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.cross_tenant', true) = 'on'
+    )
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+CREATE POLICY invoices_tenant_isolation ON invoices
+    USING (
+        tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.cross_tenant', true) = 'on'
+    )
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+CREATE POLICY invoice_payments_tenant_isolation ON invoice_payments
+    USING (
+        tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.cross_tenant', true) = 'on'
+    )
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
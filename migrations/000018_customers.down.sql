@@ -0,0 +1,6 @@
+DELETE FROM role_permissions WHERE permission = 'customers:erase';
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS customer_id;
+ALTER TABLE bank_accounts DROP COLUMN IF EXISTS customer_id;
+ALTER TABLE payment_methods DROP COLUMN IF EXISTS customer_id;
+ALTER TABLE payments DROP COLUMN IF EXISTS customer_id;
+DROP TABLE IF EXISTS customers;
@@ -0,0 +1,50 @@
+-- Customers of a tenant. Erasing a customer blanks its personal data and
+-- sets erased_at; the row stays so the payments linked to it keep their
+-- customer. external_ref is the tenant's own id for the customer.
+CREATE TABLE customers (
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    id TEXT NOT NULL,
+    email TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL DEFAULT '',
+    external_ref TEXT NOT NULL DEFAULT '',
+    metadata JSONB NOT NULL DEFAULT '{}',
+    erased_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, id)
+);
+
+CREATE UNIQUE INDEX customers_external_ref_idx ON customers (tenant_id, external_ref) WHERE external_ref <> '';
+CREATE INDEX customers_email_idx ON customers (tenant_id, email) WHERE email <> '';
+
+ALTER TABLE customers ENABLE ROW LEVEL SECURITY;
+ALTER TABLE customers FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY customers_tenant_isolation ON customers
+    USING (
+        tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.cross_tenant', true) = 'on'
+    )
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+-- Payments, saved cards and bank accounts, and subscriptions may belong to
+-- a customer.
+ALTER TABLE payments ADD COLUMN customer_id TEXT;
+ALTER TABLE payments ADD FOREIGN KEY (tenant_id, customer_id) REFERENCES customers (tenant_id, id);
+CREATE INDEX payments_customer_idx ON payments (tenant_id, customer_id) WHERE customer_id IS NOT NULL;
+
+ALTER TABLE payment_methods ADD COLUMN customer_id TEXT;
+ALTER TABLE payment_methods ADD FOREIGN KEY (tenant_id, customer_id) REFERENCES customers (tenant_id, id);
+CREATE INDEX payment_methods_customer_idx ON payment_methods (tenant_id, customer_id) WHERE customer_id IS NOT NULL;
+
+ALTER TABLE bank_accounts ADD COLUMN customer_id TEXT;
+ALTER TABLE bank_accounts ADD FOREIGN KEY (tenant_id, customer_id) REFERENCES customers (tenant_id, id);
+CREATE INDEX bank_accounts_customer_idx ON bank_accounts (tenant_id, customer_id) WHERE customer_id IS NOT NULL;
+
+ALTER TABLE subscriptions ADD COLUMN customer_id TEXT;
+ALTER TABLE subscriptions ADD FOREIGN KEY (tenant_id, customer_id) REFERENCES customers (tenant_id, id);
+CREATE INDEX subscriptions_customer_idx ON subscriptions (tenant_id, customer_id) WHERE customer_id IS NOT NULL;
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'customers:erase'),
+    ('platform_admin', 'customers:erase');
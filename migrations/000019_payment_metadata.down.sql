@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS payments_description_search_idx;
+DROP INDEX IF EXISTS payments_metadata_idx;
+ALTER TABLE payments DROP COLUMN IF EXISTS description_search;
+ALTER TABLE payments DROP COLUMN IF EXISTS statement_descriptor;
+ALTER TABLE payments DROP COLUMN IF EXISTS description;
+ALTER TABLE payments DROP COLUMN IF EXISTS metadata;
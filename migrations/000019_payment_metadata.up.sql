@@ -0,0 +1,12 @@
+-- What integrators attach to payments. metadata is a flat object of string
+-- values, searched by containment through its GIN index. description_search
+-- indexes the words of the description for full-text search; the 'simple'
+-- configuration does not stem, so it suits descriptions in any language.
+ALTER TABLE payments ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE payments ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE payments ADD COLUMN statement_descriptor TEXT NOT NULL DEFAULT '';
+ALTER TABLE payments ADD COLUMN description_search TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('simple', description)) STORED;
+
+CREATE INDEX payments_metadata_idx ON payments USING GIN (metadata jsonb_path_ops);
+CREATE INDEX payments_description_search_idx ON payments USING GIN (description_search);
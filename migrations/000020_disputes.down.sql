@@ -0,0 +1,3 @@
+DELETE FROM role_permissions WHERE permission = 'disputes:write';
+DROP TABLE IF EXISTS dispute_evidence;
+DROP TABLE IF EXISTS disputes;
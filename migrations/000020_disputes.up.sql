@@ -0,0 +1,71 @@
+-- A cardholder contesting a payment with their bank. Disputes reported by a
+-- payment provider carry its name and the provider's id of the dispute,
+-- which is unique per provider. reminders_sent counts the reminders of the
+-- evidence deadline sent so far.
+CREATE TABLE disputes (
+    tenant_id TEXT NOT NULL REFERENCES tenants (id),
+    id TEXT NOT NULL,
+    payment_id BIGINT NOT NULL,
+    reason TEXT NOT NULL,
+    amount NUMERIC(18, 2) NOT NULL CHECK (amount > 0),
+    currency TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'needs_response'
+        CHECK (status IN ('needs_response', 'under_review', 'won', 'lost')),
+    evidence_due_by TIMESTAMPTZ NOT NULL,
+    provider TEXT NOT NULL DEFAULT '',
+    provider_ref TEXT NOT NULL DEFAULT '',
+    outcome TEXT NOT NULL DEFAULT '',
+    reminders_sent INT NOT NULL DEFAULT 0,
+    submitted_at TIMESTAMPTZ,
+    closed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, id),
+    FOREIGN KEY (tenant_id, payment_id) REFERENCES payments (tenant_id, id)
+);
+
+CREATE UNIQUE INDEX disputes_provider_ref_idx ON disputes (provider, provider_ref) WHERE provider_ref <> '';
+CREATE INDEX disputes_payment_idx ON disputes (tenant_id, payment_id);
+CREATE INDEX disputes_due_idx ON disputes (evidence_due_by) WHERE status = 'needs_response';
+
+-- The files backing the tenant's side of a dispute. The content is in the
+-- blob store under blob_key; sha256 is its hex digest.
+CREATE TABLE dispute_evidence (
+    tenant_id TEXT NOT NULL,
+    id TEXT NOT NULL,
+    dispute_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL CHECK (size > 0),
+    sha256 TEXT NOT NULL,
+    blob_key TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, id),
+    FOREIGN KEY (tenant_id, dispute_id) REFERENCES disputes (tenant_id, id)
+);
+
+CREATE INDEX dispute_evidence_dispute_idx ON dispute_evidence (tenant_id, dispute_id);
+
+ALTER TABLE disputes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE disputes FORCE ROW LEVEL SECURITY;
+ALTER TABLE dispute_evidence ENABLE ROW LEVEL SECURITY;
+ALTER TABLE dispute_evidence FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY disputes_tenant_isolation ON disputes
+    USING (
+        tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.cross_tenant', true) = 'on'
+    )
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+CREATE POLICY dispute_evidence_tenant_isolation ON dispute_evidence
+    USING (
+        tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.cross_tenant', true) = 'on'
+    )
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'disputes:write'),
+    ('platform_admin', 'disputes:write');
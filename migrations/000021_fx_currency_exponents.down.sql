@@ -0,0 +1,4 @@
+ALTER TABLE payment_conversions ALTER COLUMN settlement_amount TYPE NUMERIC(18, 2);
+ALTER TABLE payment_conversions ALTER COLUMN amount TYPE NUMERIC(18, 2);
+ALTER TABLE fx_quotes ALTER COLUMN settlement_amount TYPE NUMERIC(18, 2);
+ALTER TABLE fx_quotes ALTER COLUMN amount TYPE NUMERIC(18, 2);
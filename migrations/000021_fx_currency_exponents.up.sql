@@ -0,0 +1,7 @@
+-- Conversions follow the ISO 4217 exponent of each currency, and some have
+-- three or four decimals (KWD, CLF), so the amounts of quotes and
+-- conversions keep four.
+ALTER TABLE fx_quotes ALTER COLUMN amount TYPE NUMERIC(18, 4);
+ALTER TABLE fx_quotes ALTER COLUMN settlement_amount TYPE NUMERIC(18, 4);
+ALTER TABLE payment_conversions ALTER COLUMN amount TYPE NUMERIC(18, 4);
+ALTER TABLE payment_conversions ALTER COLUMN settlement_amount TYPE NUMERIC(18, 4);
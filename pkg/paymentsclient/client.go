@@ -0,0 +1,260 @@
+// Package paymentsclient is the Go client for the payments service. A Client
+// talks to the service over gRPC or over the v2 REST API with the same typed
+// interface, and takes care of credentials, idempotency keys for creation,
+// retries of transient failures, per-call deadlines and pagination.
+//
+//	conn, _ := grpc.NewClient("payments:50051", grpc.WithTransportCredentials(creds))
+//	c := paymentsclient.NewGRPC(conn, paymentsclient.WithAPIKey(key))
+//	p, err := c.Create(ctx, paymentsclient.Payment{ID: 1, Amount: 10, Currency: "USD"})
+//
+// Mutual TLS is configured on the grpc.ClientConn or http.Client passed in.
+package paymentsclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Payment is a payment as returned by the service.
+type Payment struct {
+	ID             int64   `json:"id"`
+	Amount         float64 `json:"amount"`
+	Currency       string  `json:"currency"`
+	TenantID       string  `json:"tenant_id,omitempty"`
+	Status         string  `json:"status,omitempty"`
+	RefundedAmount float64 `json:"refunded_amount,omitempty"`
+	Provider       string  `json:"provider,omitempty"`
+	ProviderRef    string  `json:"provider_ref,omitempty"`
+	ProviderStatus string  `json:"provider_status,omitempty"`
+	// CardNumber is charged by Create and never returned.
+	CardNumber string `json:"card_number,omitempty"`
+	// ActionURL is set by Create when Status is requires_action: the customer
+	// has to complete a challenge there before the payment goes through.
+	ActionURL string `json:"action_url,omitempty"`
+}
+
+// Event is one entry of a payment's history. Type is one of created,
+// authorized, settled, updated, refunded, cancelled and deleted; for refunds Amount is the amount
+// refunded by that event.
+type Event struct {
+	ID        int64     `json:"id"`
+	PaymentID int64     `json:"payment_id"`
+	TenantID  string    `json:"tenant_id"`
+	Type      string    `json:"type"`
+	Amount    float64   `json:"amount"`
+	Currency  string    `json:"currency"`
+	Status    string    `json:"status"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListOptions filters ListPayments. Zero values match every payment; PageSize
+// defaults to DefaultPageSize.
+type ListOptions struct {
+	Currency string
+	Amount   *float64
+	PageSize int
+}
+
+// Page is one page of ListPayments results.
+type Page struct {
+	Payments []Payment
+	Page     int
+	// More is set when a following page may hold further payments.
+	More bool
+}
+
+const DefaultPageSize = 20
+
+// DefaultTimeout bounds each call, retries included, unless overridden with
+// WithTimeout or by an earlier context deadline.
+const DefaultTimeout = 30 * time.Second
+
+// Client is safe for concurrent use.
+type Client struct {
+	transport transport
+	creds     credentials
+	retry     RetryPolicy
+	timeout   time.Duration
+}
+
+type credentials struct {
+	apiKey string
+	token  string
+	tenant string
+}
+
+// transport is implemented for gRPC and REST. Calls are single attempts;
+// the Client retries them.
+type transport interface {
+	// create returns the challenge URL of a payment that requires action.
+	create(ctx context.Context, c credentials, p Payment, key string) (string, error)
+	get(ctx context.Context, c credentials, id int64) (*Payment, error)
+	update(ctx context.Context, c credentials, p Payment) error
+	delete(ctx context.Context, c credentials, id int64) error
+	list(ctx context.Context, c credentials, opts ListOptions, page int) (*Page, error)
+	refund(ctx context.Context, c credentials, id int64, amount float64, reason string) (*Payment, error)
+	cancel(ctx context.Context, c credentials, id int64, reason string) (*Payment, error)
+	history(ctx context.Context, c credentials, id int64) ([]Event, error)
+	// watch calls fn for every event after the given id until ctx is done
+	// or fn fails.
+	watch(ctx context.Context, c credentials, after int64, fn func(Event) error) error
+}
+
+type Option func(*Client)
+
+// WithAPIKey authenticates with an API key.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.creds.apiKey = key }
+}
+
+// WithBearerToken authenticates with a JWT.
+func WithBearerToken(token string) Option {
+	return func(c *Client) { c.creds.token = token }
+}
+
+// WithTenant selects the tenant for cross-tenant administrators.
+func WithTenant(id string) Option {
+	return func(c *Client) { c.creds.tenant = id }
+}
+
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) { c.retry = p }
+}
+
+// WithTimeout sets the deadline of each call; zero disables it.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.timeout = d }
+}
+
+// NewGRPC returns a client using the gRPC API over conn.
+func NewGRPC(conn grpc.ClientConnInterface, opts ...Option) *Client {
+	return newClient(newGRPCTransport(conn), opts)
+}
+
+// NewHTTP returns a client using the v2 REST API at baseURL, such as
+// "https://payments.example.com". httpClient may be nil.
+func NewHTTP(baseURL string, httpClient *http.Client, opts ...Option) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return newClient(&httpTransport{baseURL: baseURL, client: httpClient}, opts)
+}
+
+func newClient(t transport, opts []Option) *Client {
+	c := &Client{transport: t, retry: DefaultRetryPolicy, timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey makes Create use key instead of a generated one, for
+// callers that need to retry across process restarts.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// Create creates a payment and returns it as stored. Every attempt carries
+// the same idempotency key, so retries never create a payment twice. Callers
+// that retry Create themselves should pin the key with WithIdempotencyKey.
+func (c *Client) Create(ctx context.Context, p Payment) (*Payment, error) {
+	key, _ := ctx.Value(idempotencyKey{}).(string)
+	if key == "" {
+		key = newIdempotencyKey()
+	}
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	var actionURL string
+	err := c.do(ctx, func(ctx context.Context) (err error) {
+		actionURL, err = c.transport.create(ctx, c.creds, p, key)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	created, err := c.get(ctx, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	created.ActionURL = actionURL
+	return created, nil
+}
+
+func (c *Client) Get(ctx context.Context, id int64) (*Payment, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.get(ctx, id)
+}
+
+// Update replaces the amount and currency of payment p.ID and returns it as
+// stored.
+func (c *Client) Update(ctx context.Context, p Payment) (*Payment, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	err := c.do(ctx, func(ctx context.Context) error {
+		return c.transport.update(ctx, c.creds, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.get(ctx, p.ID)
+}
+
+// Delete deletes a payment. A retried delete whose first attempt succeeded
+// reports codes.NotFound.
+func (c *Client) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.do(ctx, func(ctx context.Context) error {
+		return c.transport.delete(ctx, c.creds, id)
+	})
+}
+
+// List returns one page of payments; pages start at 1.
+func (c *Client) List(ctx context.Context, opts ListOptions, page int) (*Page, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultPageSize
+	}
+
+	var result *Page
+	err := c.do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = c.transport.list(ctx, c.creds, opts, page)
+		return err
+	})
+	return result, err
+}
+
+func (c *Client) get(ctx context.Context, id int64) (*Payment, error) {
+	var p *Payment
+	err := c.do(ctx, func(ctx context.Context) error {
+		var err error
+		p, err = c.transport.get(ctx, c.creds, id)
+		return err
+	})
+	return p, err
+}
+
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,77 @@
+package paymentsclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Error is returned for every failed call, whatever the transport. REST
+// failures are reported with the gRPC code the service mapped them from.
+type Error struct {
+	Code    codes.Code
+	Message string
+	// RetryAfter is the wait the server asked for, if any.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("payments: %s: %s", e.Code, e.Message)
+}
+
+// Code returns the code of an error returned by the client, codes.OK for
+// nil and codes.Unknown for anything else.
+func Code(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return codes.Unknown
+}
+
+func IsNotFound(err error) bool {
+	return Code(err) == codes.NotFound
+}
+
+func fromStatus(s *status.Status) *Error {
+	e := &Error{Code: s.Code(), Message: s.Message()}
+	for _, d := range s.Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok {
+			e.RetryAfter = info.GetRetryDelay().AsDuration()
+		}
+	}
+	return e
+}
+
+// codeForHTTP maps statuses of responses without a status body, such as
+// those written by the auth and rate limit middleware or a proxy.
+func codeForHTTP(status int) codes.Code {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnsupportedMediaType:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return codes.Unavailable
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	default:
+		return codes.Unknown
+	}
+}
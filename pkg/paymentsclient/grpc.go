@@ -0,0 +1,169 @@
+package paymentsclient
+
+import (
+	"context"
+
+	"go-lang-final/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type grpcTransport struct {
+	client proto.PaymentServiceClient
+}
+
+func newGRPCTransport(conn grpc.ClientConnInterface) *grpcTransport {
+	return &grpcTransport{client: proto.NewPaymentServiceClient(conn)}
+}
+
+func (t *grpcTransport) outgoing(ctx context.Context, c credentials) context.Context {
+	var kv []string
+	switch {
+	case c.token != "":
+		kv = append(kv, "authorization", "Bearer "+c.token)
+	case c.apiKey != "":
+		kv = append(kv, "x-api-key", c.apiKey)
+	}
+	if c.tenant != "" {
+		kv = append(kv, "x-tenant-id", c.tenant)
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+func (t *grpcTransport) create(ctx context.Context, c credentials, p Payment, key string) (string, error) {
+	ctx = metadata.AppendToOutgoingContext(t.outgoing(ctx, c), "idempotency-key", key)
+	res, err := t.client.CreatePayment(ctx, &proto.CreatePaymentRequest{Id: p.ID, Amount: p.Amount, Currency: p.Currency, CardNumber: p.CardNumber})
+	if err != nil {
+		return "", grpcError(err)
+	}
+	return res.GetActionUrl(), nil
+}
+
+func (t *grpcTransport) get(ctx context.Context, c credentials, id int64) (*Payment, error) {
+	res, err := t.client.GetPayment(t.outgoing(ctx, c), &proto.GetPaymentRequest{Id: id})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &Payment{
+		ID:             res.GetId(),
+		Amount:         res.GetAmount(),
+		Currency:       res.GetCurrency(),
+		TenantID:       res.GetTenantId(),
+		Status:         res.GetStatus(),
+		RefundedAmount: res.GetRefundedAmount(),
+		Provider:       res.GetProvider(),
+		ProviderRef:    res.GetProviderRef(),
+		ProviderStatus: res.GetProviderStatus(),
+	}, nil
+}
+
+func (t *grpcTransport) update(ctx context.Context, c credentials, p Payment) error {
+	_, err := t.client.UpdatePayment(t.outgoing(ctx, c), &proto.UpdatePaymentRequest{Id: p.ID, Amount: p.Amount, Currency: p.Currency})
+	return grpcError(err)
+}
+
+func (t *grpcTransport) delete(ctx context.Context, c credentials, id int64) error {
+	_, err := t.client.DeletePayment(t.outgoing(ctx, c), &proto.DeletePaymentRequest{Id: id})
+	return grpcError(err)
+}
+
+func (t *grpcTransport) list(ctx context.Context, c credentials, opts ListOptions, page int) (*Page, error) {
+	res, err := t.client.ListPayments(t.outgoing(ctx, c), &proto.ListPaymentsRequest{
+		Currency: opts.Currency,
+		Amount:   opts.Amount,
+		Page:     int32(page),
+		PageSize: int32(opts.PageSize),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	result := &Page{Page: page}
+	for _, p := range res.GetPayments() {
+		result.Payments = append(result.Payments, *fromProto(p))
+	}
+	result.More = len(result.Payments) == opts.PageSize
+	return result, nil
+}
+
+func (t *grpcTransport) refund(ctx context.Context, c credentials, id int64, amount float64, reason string) (*Payment, error) {
+	res, err := t.client.RefundPayment(t.outgoing(ctx, c), &proto.RefundPaymentRequest{Id: id, Amount: amount, Reason: reason})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return fromProto(res.GetPayment()), nil
+}
+
+func (t *grpcTransport) cancel(ctx context.Context, c credentials, id int64, reason string) (*Payment, error) {
+	res, err := t.client.CancelPayment(t.outgoing(ctx, c), &proto.CancelPaymentRequest{Id: id, Reason: reason})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return fromProto(res.GetPayment()), nil
+}
+
+func (t *grpcTransport) history(ctx context.Context, c credentials, id int64) ([]Event, error) {
+	res, err := t.client.ListPaymentEvents(t.outgoing(ctx, c), &proto.ListPaymentEventsRequest{Id: id})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	events := make([]Event, 0, len(res.GetEvents()))
+	for _, e := range res.GetEvents() {
+		events = append(events, eventFromProto(e))
+	}
+	return events, nil
+}
+
+func (t *grpcTransport) watch(ctx context.Context, c credentials, after int64, fn func(Event) error) error {
+	stream, err := t.client.WatchPayments(t.outgoing(ctx, c), &proto.WatchPaymentsRequest{AfterEventId: after})
+	if err != nil {
+		return grpcError(err)
+	}
+	for {
+		e, err := stream.Recv()
+		if err != nil {
+			return grpcError(err)
+		}
+		if err := fn(eventFromProto(e)); err != nil {
+			return err
+		}
+	}
+}
+
+func fromProto(p *proto.Payment) *Payment {
+	return &Payment{
+		ID:             p.GetId(),
+		Amount:         p.GetAmount(),
+		Currency:       p.GetCurrency(),
+		TenantID:       p.GetTenantId(),
+		Status:         p.GetStatus(),
+		RefundedAmount: p.GetRefundedAmount(),
+		Provider:       p.GetProvider(),
+		ProviderRef:    p.GetProviderRef(),
+		ProviderStatus: p.GetProviderStatus(),
+	}
+}
+
+func eventFromProto(e *proto.PaymentEvent) Event {
+	return Event{
+		ID:        e.GetId(),
+		PaymentID: e.GetPaymentId(),
+		TenantID:  e.GetTenantId(),
+		Type:      e.GetType(),
+		Amount:    e.GetAmount(),
+		Currency:  e.GetCurrency(),
+		Status:    e.GetStatus(),
+		Reason:    e.GetReason(),
+		CreatedAt: e.GetCreatedAt().AsTime(),
+	}
+}
+
+func grpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fromStatus(status.Convert(err))
+}
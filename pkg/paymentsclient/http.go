@@ -0,0 +1,210 @@
+package paymentsclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// httpTransport speaks the v2 REST API.
+type httpTransport struct {
+	baseURL string
+	client  *http.Client
+}
+
+type listEnvelope struct {
+	Data       []Payment `json:"data"`
+	Pagination struct {
+		Page int `json:"page"`
+	} `json:"pagination"`
+	Links struct {
+		Next string `json:"next"`
+	} `json:"links"`
+}
+
+func (t *httpTransport) create(ctx context.Context, c credentials, p Payment, key string) (string, error) {
+	var created Payment
+	if err := t.do(ctx, c, http.MethodPost, "/v2/payments", "application/json", p, map[string]string{"Idempotency-Key": key}, &created); err != nil {
+		return "", err
+	}
+	return created.ActionURL, nil
+}
+
+func (t *httpTransport) get(ctx context.Context, c credentials, id int64) (*Payment, error) {
+	var p Payment
+	if err := t.do(ctx, c, http.MethodGet, paymentPath(id), "", nil, nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (t *httpTransport) update(ctx context.Context, c credentials, p Payment) error {
+	patch := map[string]interface{}{"amount": p.Amount, "currency": p.Currency}
+	return t.do(ctx, c, http.MethodPatch, paymentPath(p.ID), "application/merge-patch+json", patch, nil, nil)
+}
+
+func (t *httpTransport) delete(ctx context.Context, c credentials, id int64) error {
+	return t.do(ctx, c, http.MethodDelete, paymentPath(id), "", nil, nil, nil)
+}
+
+func (t *httpTransport) list(ctx context.Context, c credentials, opts ListOptions, page int) (*Page, error) {
+	query := url.Values{}
+	if opts.Currency != "" {
+		query.Set("currency", opts.Currency)
+	}
+	if opts.Amount != nil {
+		query.Set("amount", strconv.FormatFloat(*opts.Amount, 'f', -1, 64))
+	}
+	query.Set("page", strconv.Itoa(page))
+	query.Set("page_size", strconv.Itoa(opts.PageSize))
+
+	var env listEnvelope
+	if err := t.do(ctx, c, http.MethodGet, "/v2/payments?"+query.Encode(), "", nil, nil, &env); err != nil {
+		return nil, err
+	}
+	return &Page{Payments: env.Data, Page: env.Pagination.Page, More: env.Links.Next != ""}, nil
+}
+
+func (t *httpTransport) refund(ctx context.Context, c credentials, id int64, amount float64, reason string) (*Payment, error) {
+	var p Payment
+	body := map[string]interface{}{"amount": amount, "reason": reason}
+	if err := t.do(ctx, c, http.MethodPost, paymentPath(id)+"/refund", "application/json", body, nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (t *httpTransport) cancel(ctx context.Context, c credentials, id int64, reason string) (*Payment, error) {
+	var p Payment
+	body := map[string]interface{}{"reason": reason}
+	if err := t.do(ctx, c, http.MethodPost, paymentPath(id)+"/cancel", "application/json", body, nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+type eventList struct {
+	Data []Event `json:"data"`
+}
+
+func (t *httpTransport) history(ctx context.Context, c credentials, id int64) ([]Event, error) {
+	var list eventList
+	if err := t.do(ctx, c, http.MethodGet, paymentPath(id)+"/events", "", nil, nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Data, nil
+}
+
+// watch polls the event feed, draining it before waiting WatchPollInterval.
+func (t *httpTransport) watch(ctx context.Context, c credentials, after int64, fn func(Event) error) error {
+	const limit = 100
+	for {
+		var list eventList
+		path := "/v2/events?after=" + strconv.FormatInt(after, 10) + "&limit=" + strconv.Itoa(limit)
+		if err := t.do(ctx, c, http.MethodGet, path, "", nil, nil, &list); err != nil {
+			return err
+		}
+		for _, e := range list.Data {
+			if err := fn(e); err != nil {
+				return err
+			}
+			after = e.ID
+		}
+		if len(list.Data) == limit {
+			continue
+		}
+
+		timer := time.NewTimer(WatchPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &Error{Code: codes.Canceled, Message: ctx.Err().Error()}
+		case <-timer.C:
+		}
+	}
+}
+
+func (t *httpTransport) do(ctx context.Context, c credentials, method, path, contentType string, body interface{}, headers map[string]string, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, t.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	switch {
+	case c.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	case c.apiKey != "":
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.tenant != "" {
+		req.Header.Set("X-Tenant-ID", c.tenant)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return &Error{Code: codes.DeadlineExceeded, Message: ctxErr.Error()}
+		}
+		return &Error{Code: codes.Unavailable, Message: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return httpError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("payments: decoding %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// httpError decodes the gRPC status body the service writes for errors,
+// falling back to the HTTP status for plain-text responses.
+func httpError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	e := &Error{Code: codeForHTTP(resp.StatusCode), Message: string(bytes.TrimSpace(raw))}
+
+	var body struct {
+		Code    *int   `json:"code"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(raw, &body) == nil && body.Code != nil {
+		e.Code, e.Message = codes.Code(*body.Code), body.Message
+	}
+	if e.Message == "" {
+		e.Message = resp.Status
+	}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(secs) * time.Second
+	}
+	return e
+}
+
+func paymentPath(id int64) string {
+	return "/v2/payments/" + strconv.FormatInt(id, 10)
+}
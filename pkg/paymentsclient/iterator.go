@@ -0,0 +1,57 @@
+package paymentsclient
+
+import "context"
+
+// Iterator walks every payment matching a ListOptions, fetching pages as
+// needed:
+//
+//	it := c.Iterate(ctx, paymentsclient.ListOptions{Currency: "USD"})
+//	for it.Next() {
+//		p := it.Payment()
+//	}
+//	if err := it.Err(); err != nil {
+//
+// Payments created or deleted while iterating may be skipped or repeated.
+type Iterator struct {
+	client *Client
+	ctx    context.Context
+	opts   ListOptions
+
+	page    int
+	buf     []Payment
+	current Payment
+	more    bool
+	err     error
+}
+
+func (c *Client) Iterate(ctx context.Context, opts ListOptions) *Iterator {
+	return &Iterator{client: c, ctx: ctx, opts: opts, more: true}
+}
+
+// Next advances to the next payment and reports whether there is one.
+func (it *Iterator) Next() bool {
+	for len(it.buf) == 0 {
+		if !it.more || it.err != nil {
+			return false
+		}
+		it.page++
+		page, err := it.client.List(it.ctx, it.opts, it.page)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.buf, it.more = page.Payments, page.More
+	}
+	it.current, it.buf = it.buf[0], it.buf[1:]
+	return true
+}
+
+// Payment returns the payment Next advanced to.
+func (it *Iterator) Payment() Payment {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,75 @@
+package paymentsclient
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// WatchPollInterval is how often the REST transport polls for new events in
+// Watch; gRPC clients receive them as a stream.
+const WatchPollInterval = time.Second
+
+// Refund refunds amount of a payment and returns it as stored. Refunds are
+// not idempotent, so unlike other calls a failed refund is never retried.
+func (c *Client) Refund(ctx context.Context, id int64, amount float64, reason string) (*Payment, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.transport.refund(ctx, c.creds, id, amount, reason)
+}
+
+// Cancel cancels a payment that has not been refunded. A retried cancel
+// whose first attempt succeeded reports codes.FailedPrecondition.
+func (c *Client) Cancel(ctx context.Context, id int64, reason string) (*Payment, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var p *Payment
+	err := c.do(ctx, func(ctx context.Context) error {
+		var err error
+		p, err = c.transport.cancel(ctx, c.creds, id, reason)
+		return err
+	})
+	return p, err
+}
+
+// History returns the events of a payment, oldest first.
+func (c *Client) History(ctx context.Context, id int64) ([]Event, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var events []Event
+	err := c.do(ctx, func(ctx context.Context) error {
+		var err error
+		events, err = c.transport.history(ctx, c.creds, id)
+		return err
+	})
+	return events, err
+}
+
+// Watch calls fn for every event after the event id after, in order, and
+// keeps following new events until ctx is done or fn returns an error. The
+// call timeout does not apply. Lost connections are resumed from the last
+// event delivered, with the retry policy's backoff.
+func (c *Client) Watch(ctx context.Context, after int64, fn func(Event) error) error {
+	var fnErr error
+	deliver := func(e Event) error {
+		if err := fn(e); err != nil {
+			fnErr = err
+			return err
+		}
+		after = e.ID
+		return nil
+	}
+	err := c.do(ctx, func(ctx context.Context) error {
+		return c.transport.watch(ctx, c.creds, after, deliver)
+	})
+	switch {
+	case fnErr != nil:
+		return fnErr
+	case errors.Is(ctx.Err(), context.Canceled):
+		return nil
+	default:
+		return err
+	}
+}
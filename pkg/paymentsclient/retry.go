@@ -0,0 +1,76 @@
+package paymentsclient
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// RetryPolicy controls how transient failures are retried. Only Unavailable,
+// Aborted and ResourceExhausted are retried; the latter only when the server
+// asks to wait no longer than MaxBackoff, so daily quotas fail fast.
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt; 1 disables retries.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Multiplier:     2,
+}
+
+func (c *Client) do(ctx context.Context, call func(context.Context) error) error {
+	backoff := c.retry.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := call(ctx)
+		if err == nil {
+			return nil
+		}
+
+		wait, ok := c.retry.retryable(err)
+		if !ok || attempt >= c.retry.MaxAttempts {
+			return err
+		}
+		// Full jitter, but never sooner than the server asked for.
+		if jittered := time.Duration(rand.Int63n(int64(backoff) + 1)); jittered > wait {
+			wait = jittered
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+
+		backoff = time.Duration(float64(backoff) * c.retry.Multiplier)
+		if backoff > c.retry.MaxBackoff {
+			backoff = c.retry.MaxBackoff
+		}
+	}
+}
+
+// retryable reports whether err may be retried and the minimum wait the
+// server asked for.
+func (p RetryPolicy) retryable(err error) (time.Duration, bool) {
+	e, ok := err.(*Error)
+	if !ok {
+		return 0, false
+	}
+	switch e.Code {
+	case codes.Unavailable, codes.Aborted:
+		return e.RetryAfter, true
+	case codes.ResourceExhausted:
+		return e.RetryAfter, e.RetryAfter <= p.MaxBackoff
+	default:
+		return 0, false
+	}
+}
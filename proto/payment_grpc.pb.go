@@ -19,11 +19,80 @@ import (
 const _ = grpc.SupportPackageIsVersion8
 
 const (
-	PaymentService_CreatePayment_FullMethodName = "/proto.PaymentService/CreatePayment"
-	PaymentService_GetPayment_FullMethodName    = "/proto.PaymentService/GetPayment"
-	PaymentService_UpdatePayment_FullMethodName = "/proto.PaymentService/UpdatePayment"
-	PaymentService_DeletePayment_FullMethodName = "/proto.PaymentService/DeletePayment"
-	PaymentService_ListPayments_FullMethodName  = "/proto.PaymentService/ListPayments"
+	PaymentService_CreatePayment_FullMethodName              = "/proto.PaymentService/CreatePayment"
+	PaymentService_GetPayment_FullMethodName                 = "/proto.PaymentService/GetPayment"
+	PaymentService_UpdatePayment_FullMethodName              = "/proto.PaymentService/UpdatePayment"
+	PaymentService_DeletePayment_FullMethodName              = "/proto.PaymentService/DeletePayment"
+	PaymentService_ListPayments_FullMethodName               = "/proto.PaymentService/ListPayments"
+	PaymentService_RefundPayment_FullMethodName              = "/proto.PaymentService/RefundPayment"
+	PaymentService_CancelPayment_FullMethodName              = "/proto.PaymentService/CancelPayment"
+	PaymentService_ListPaymentEvents_FullMethodName          = "/proto.PaymentService/ListPaymentEvents"
+	PaymentService_WatchPayments_FullMethodName              = "/proto.PaymentService/WatchPayments"
+	PaymentService_CreatePaymentMethod_FullMethodName        = "/proto.PaymentService/CreatePaymentMethod"
+	PaymentService_GetPaymentMethod_FullMethodName           = "/proto.PaymentService/GetPaymentMethod"
+	PaymentService_CreateBankAccount_FullMethodName          = "/proto.PaymentService/CreateBankAccount"
+	PaymentService_GetBankAccount_FullMethodName             = "/proto.PaymentService/GetBankAccount"
+	PaymentService_CreatePayout_FullMethodName               = "/proto.PaymentService/CreatePayout"
+	PaymentService_GetCreditTransfer_FullMethodName          = "/proto.PaymentService/GetCreditTransfer"
+	PaymentService_CreateCreditTransferBatch_FullMethodName  = "/proto.PaymentService/CreateCreditTransferBatch"
+	PaymentService_GetCreditTransferBatch_FullMethodName     = "/proto.PaymentService/GetCreditTransferBatch"
+	PaymentService_ImportPaymentStatusReport_FullMethodName  = "/proto.PaymentService/ImportPaymentStatusReport"
+	PaymentService_ImportBankStatement_FullMethodName        = "/proto.PaymentService/ImportBankStatement"
+	PaymentService_GetReconciliationRun_FullMethodName       = "/proto.PaymentService/GetReconciliationRun"
+	PaymentService_ListReconciliationRuns_FullMethodName     = "/proto.PaymentService/ListReconciliationRuns"
+	PaymentService_ResolveReconciliationLine_FullMethodName  = "/proto.PaymentService/ResolveReconciliationLine"
+	PaymentService_ImportSettlementReport_FullMethodName     = "/proto.PaymentService/ImportSettlementReport"
+	PaymentService_GetSettlementBatch_FullMethodName         = "/proto.PaymentService/GetSettlementBatch"
+	PaymentService_ListSettlementBatches_FullMethodName      = "/proto.PaymentService/ListSettlementBatches"
+	PaymentService_ListLedgerPostings_FullMethodName         = "/proto.PaymentService/ListLedgerPostings"
+	PaymentService_ImportFXRates_FullMethodName              = "/proto.PaymentService/ImportFXRates"
+	PaymentService_ListFXRates_FullMethodName                = "/proto.PaymentService/ListFXRates"
+	PaymentService_CreateFXQuote_FullMethodName              = "/proto.PaymentService/CreateFXQuote"
+	PaymentService_GetPaymentConversion_FullMethodName       = "/proto.PaymentService/GetPaymentConversion"
+	PaymentService_CreatePricingPlan_FullMethodName          = "/proto.PaymentService/CreatePricingPlan"
+	PaymentService_UpdatePricingPlan_FullMethodName          = "/proto.PaymentService/UpdatePricingPlan"
+	PaymentService_GetPricingPlan_FullMethodName             = "/proto.PaymentService/GetPricingPlan"
+	PaymentService_ListPricingPlans_FullMethodName           = "/proto.PaymentService/ListPricingPlans"
+	PaymentService_AssignPricingPlan_FullMethodName          = "/proto.PaymentService/AssignPricingPlan"
+	PaymentService_GetTenantPricingPlan_FullMethodName       = "/proto.PaymentService/GetTenantPricingPlan"
+	PaymentService_SimulateFee_FullMethodName                = "/proto.PaymentService/SimulateFee"
+	PaymentService_GetPaymentFee_FullMethodName              = "/proto.PaymentService/GetPaymentFee"
+	PaymentService_GetFeeStatement_FullMethodName            = "/proto.PaymentService/GetFeeStatement"
+	PaymentService_CreateSubscriptionPlan_FullMethodName     = "/proto.PaymentService/CreateSubscriptionPlan"
+	PaymentService_GetSubscriptionPlan_FullMethodName        = "/proto.PaymentService/GetSubscriptionPlan"
+	PaymentService_ListSubscriptionPlans_FullMethodName      = "/proto.PaymentService/ListSubscriptionPlans"
+	PaymentService_CreateSubscription_FullMethodName         = "/proto.PaymentService/CreateSubscription"
+	PaymentService_GetSubscription_FullMethodName            = "/proto.PaymentService/GetSubscription"
+	PaymentService_ListSubscriptions_FullMethodName          = "/proto.PaymentService/ListSubscriptions"
+	PaymentService_ChangeSubscriptionPlan_FullMethodName     = "/proto.PaymentService/ChangeSubscriptionPlan"
+	PaymentService_CancelSubscription_FullMethodName         = "/proto.PaymentService/CancelSubscription"
+	PaymentService_ListSubscriptionCharges_FullMethodName    = "/proto.PaymentService/ListSubscriptionCharges"
+	PaymentService_CreateInvoice_FullMethodName              = "/proto.PaymentService/CreateInvoice"
+	PaymentService_GetInvoice_FullMethodName                 = "/proto.PaymentService/GetInvoice"
+	PaymentService_ListInvoices_FullMethodName               = "/proto.PaymentService/ListInvoices"
+	PaymentService_UpdateInvoice_FullMethodName              = "/proto.PaymentService/UpdateInvoice"
+	PaymentService_FinalizeInvoice_FullMethodName            = "/proto.PaymentService/FinalizeInvoice"
+	PaymentService_VoidInvoice_FullMethodName                = "/proto.PaymentService/VoidInvoice"
+	PaymentService_PayInvoice_FullMethodName                 = "/proto.PaymentService/PayInvoice"
+	PaymentService_RenderInvoice_FullMethodName              = "/proto.PaymentService/RenderInvoice"
+	PaymentService_CreateCustomer_FullMethodName             = "/proto.PaymentService/CreateCustomer"
+	PaymentService_GetCustomer_FullMethodName                = "/proto.PaymentService/GetCustomer"
+	PaymentService_ListCustomers_FullMethodName              = "/proto.PaymentService/ListCustomers"
+	PaymentService_UpdateCustomer_FullMethodName             = "/proto.PaymentService/UpdateCustomer"
+	PaymentService_DeleteCustomer_FullMethodName             = "/proto.PaymentService/DeleteCustomer"
+	PaymentService_EraseCustomer_FullMethodName              = "/proto.PaymentService/EraseCustomer"
+	PaymentService_AttachPaymentMethod_FullMethodName        = "/proto.PaymentService/AttachPaymentMethod"
+	PaymentService_ListCustomerPaymentMethods_FullMethodName = "/proto.PaymentService/ListCustomerPaymentMethods"
+	PaymentService_ListCustomerPayments_FullMethodName       = "/proto.PaymentService/ListCustomerPayments"
+	PaymentService_GetCustomerLifetimeValue_FullMethodName   = "/proto.PaymentService/GetCustomerLifetimeValue"
+	PaymentService_CreateDispute_FullMethodName              = "/proto.PaymentService/CreateDispute"
+	PaymentService_GetDispute_FullMethodName                 = "/proto.PaymentService/GetDispute"
+	PaymentService_ListDisputes_FullMethodName               = "/proto.PaymentService/ListDisputes"
+	PaymentService_UploadDisputeEvidence_FullMethodName      = "/proto.PaymentService/UploadDisputeEvidence"
+	PaymentService_GetDisputeEvidence_FullMethodName         = "/proto.PaymentService/GetDisputeEvidence"
+	PaymentService_SubmitDispute_FullMethodName              = "/proto.PaymentService/SubmitDispute"
+	PaymentService_AcceptDispute_FullMethodName              = "/proto.PaymentService/AcceptDispute"
+	PaymentService_ResolveDispute_FullMethodName             = "/proto.PaymentService/ResolveDispute"
 )
 
 // PaymentServiceClient is the client API for PaymentService service.
@@ -35,6 +104,115 @@ type PaymentServiceClient interface {
 	UpdatePayment(ctx context.Context, in *UpdatePaymentRequest, opts ...grpc.CallOption) (*UpdatePaymentResponse, error)
 	DeletePayment(ctx context.Context, in *DeletePaymentRequest, opts ...grpc.CallOption) (*DeletePaymentResponse, error)
 	ListPayments(ctx context.Context, in *ListPaymentsRequest, opts ...grpc.CallOption) (*ListPaymentsResponse, error)
+	// The payment lifecycle RPCs below have no v1 bindings; REST exposes
+	// them under /v2 only.
+	RefundPayment(ctx context.Context, in *RefundPaymentRequest, opts ...grpc.CallOption) (*RefundPaymentResponse, error)
+	CancelPayment(ctx context.Context, in *CancelPaymentRequest, opts ...grpc.CallOption) (*CancelPaymentResponse, error)
+	ListPaymentEvents(ctx context.Context, in *ListPaymentEventsRequest, opts ...grpc.CallOption) (*ListPaymentEventsResponse, error)
+	// WatchPayments streams payment events with an id greater than
+	// after_event_id, then follows new events until the client cancels.
+	WatchPayments(ctx context.Context, in *WatchPaymentsRequest, opts ...grpc.CallOption) (PaymentService_WatchPaymentsClient, error)
+	// CreatePaymentMethod stores a card in the vault and returns its token;
+	// the card number is never returned again.
+	CreatePaymentMethod(ctx context.Context, in *CreatePaymentMethodRequest, opts ...grpc.CallOption) (*PaymentMethod, error)
+	GetPaymentMethod(ctx context.Context, in *GetPaymentMethodRequest, opts ...grpc.CallOption) (*PaymentMethod, error)
+	// CreateBankAccount validates a SEPA account and returns its token,
+	// which pays by bank transfer in place of a vaulted card.
+	CreateBankAccount(ctx context.Context, in *CreateBankAccountRequest, opts ...grpc.CallOption) (*BankAccount, error)
+	GetBankAccount(ctx context.Context, in *GetBankAccountRequest, opts ...grpc.CallOption) (*BankAccount, error)
+	// CreatePayout queues a credit transfer to a bank account for the next
+	// batch. Refunds of bank transfer payments are queued the same way.
+	CreatePayout(ctx context.Context, in *CreatePayoutRequest, opts ...grpc.CallOption) (*CreditTransfer, error)
+	GetCreditTransfer(ctx context.Context, in *GetCreditTransferRequest, opts ...grpc.CallOption) (*CreditTransfer, error)
+	// CreateCreditTransferBatch collects the pending credit transfers into a
+	// pain.001 file for the bank.
+	CreateCreditTransferBatch(ctx context.Context, in *CreateCreditTransferBatchRequest, opts ...grpc.CallOption) (*CreditTransferBatch, error)
+	GetCreditTransferBatch(ctx context.Context, in *GetCreditTransferBatchRequest, opts ...grpc.CallOption) (*CreditTransferBatch, error)
+	// ImportPaymentStatusReport applies a pain.002 report from the bank to
+	// the batch and transfers it refers to.
+	ImportPaymentStatusReport(ctx context.Context, in *ImportPaymentStatusReportRequest, opts ...grpc.CallOption) (*ImportPaymentStatusReportResponse, error)
+	// ImportBankStatement matches the lines of a camt.053 or MT940 statement
+	// against payments and refunds and records the outcome as a run.
+	ImportBankStatement(ctx context.Context, in *ImportBankStatementRequest, opts ...grpc.CallOption) (*ReconciliationRun, error)
+	GetReconciliationRun(ctx context.Context, in *GetReconciliationRunRequest, opts ...grpc.CallOption) (*ReconciliationRun, error)
+	ListReconciliationRuns(ctx context.Context, in *ListReconciliationRunsRequest, opts ...grpc.CallOption) (*ListReconciliationRunsResponse, error)
+	// ResolveReconciliationLine settles an exception of a run by hand.
+	ResolveReconciliationLine(ctx context.Context, in *ResolveReconciliationLineRequest, opts ...grpc.CallOption) (*ReconciliationLine, error)
+	// ImportSettlementReport checks a provider's settlement file against its
+	// payments and books the provider's fees to the ledger.
+	ImportSettlementReport(ctx context.Context, in *ImportSettlementReportRequest, opts ...grpc.CallOption) (*SettlementBatch, error)
+	GetSettlementBatch(ctx context.Context, in *GetSettlementBatchRequest, opts ...grpc.CallOption) (*SettlementBatch, error)
+	ListSettlementBatches(ctx context.Context, in *ListSettlementBatchesRequest, opts ...grpc.CallOption) (*ListSettlementBatchesResponse, error)
+	ListLedgerPostings(ctx context.Context, in *ListLedgerPostingsRequest, opts ...grpc.CallOption) (*ListLedgerPostingsResponse, error)
+	// ImportFXRates adds exchange rates to the table shared by all tenants.
+	ImportFXRates(ctx context.Context, in *ImportFXRatesRequest, opts ...grpc.CallOption) (*ImportFXRatesResponse, error)
+	ListFXRates(ctx context.Context, in *ListFXRatesRequest, opts ...grpc.CallOption) (*ListFXRatesResponse, error)
+	// CreateFXQuote locks the current rate of a currency pair for a while;
+	// payments created with the quote are converted at its rate.
+	CreateFXQuote(ctx context.Context, in *CreateFXQuoteRequest, opts ...grpc.CallOption) (*FXQuote, error)
+	GetPaymentConversion(ctx context.Context, in *GetPaymentConversionRequest, opts ...grpc.CallOption) (*PaymentConversion, error)
+	// Pricing plans are shared by all tenants; AssignPricingPlan puts the
+	// tenant in scope on one. Payments are charged the plan's fee when they
+	// are captured.
+	CreatePricingPlan(ctx context.Context, in *PricingPlan, opts ...grpc.CallOption) (*PricingPlan, error)
+	UpdatePricingPlan(ctx context.Context, in *PricingPlan, opts ...grpc.CallOption) (*PricingPlan, error)
+	GetPricingPlan(ctx context.Context, in *GetPricingPlanRequest, opts ...grpc.CallOption) (*PricingPlan, error)
+	ListPricingPlans(ctx context.Context, in *ListPricingPlansRequest, opts ...grpc.CallOption) (*ListPricingPlansResponse, error)
+	AssignPricingPlan(ctx context.Context, in *AssignPricingPlanRequest, opts ...grpc.CallOption) (*PricingPlan, error)
+	GetTenantPricingPlan(ctx context.Context, in *GetTenantPricingPlanRequest, opts ...grpc.CallOption) (*PricingPlan, error)
+	// SimulateFee prices a hypothetical payment by the tenant's plan
+	// without recording anything.
+	SimulateFee(ctx context.Context, in *SimulateFeeRequest, opts ...grpc.CallOption) (*PaymentFee, error)
+	GetPaymentFee(ctx context.Context, in *GetPaymentFeeRequest, opts ...grpc.CallOption) (*PaymentFee, error)
+	GetFeeStatement(ctx context.Context, in *GetFeeStatementRequest, opts ...grpc.CallOption) (*FeeStatement, error)
+	// Subscriptions charge their payment method for a plan every period; a
+	// scheduler creates the payments when they are due and retries failed
+	// ones. Plan changes are prorated into the next renewal.
+	CreateSubscriptionPlan(ctx context.Context, in *SubscriptionPlan, opts ...grpc.CallOption) (*SubscriptionPlan, error)
+	GetSubscriptionPlan(ctx context.Context, in *GetSubscriptionPlanRequest, opts ...grpc.CallOption) (*SubscriptionPlan, error)
+	ListSubscriptionPlans(ctx context.Context, in *ListSubscriptionPlansRequest, opts ...grpc.CallOption) (*ListSubscriptionPlansResponse, error)
+	CreateSubscription(ctx context.Context, in *CreateSubscriptionRequest, opts ...grpc.CallOption) (*Subscription, error)
+	GetSubscription(ctx context.Context, in *GetSubscriptionRequest, opts ...grpc.CallOption) (*Subscription, error)
+	ListSubscriptions(ctx context.Context, in *ListSubscriptionsRequest, opts ...grpc.CallOption) (*ListSubscriptionsResponse, error)
+	ChangeSubscriptionPlan(ctx context.Context, in *ChangeSubscriptionPlanRequest, opts ...grpc.CallOption) (*Subscription, error)
+	CancelSubscription(ctx context.Context, in *CancelSubscriptionRequest, opts ...grpc.CallOption) (*Subscription, error)
+	ListSubscriptionCharges(ctx context.Context, in *ListSubscriptionChargesRequest, opts ...grpc.CallOption) (*ListSubscriptionChargesResponse, error)
+	// Invoices are drafted, finalized into the tenant's gapless number
+	// sequence and paid by one or more payments. RenderInvoice returns the
+	// invoice as an HTML or PDF document.
+	CreateInvoice(ctx context.Context, in *Invoice, opts ...grpc.CallOption) (*Invoice, error)
+	GetInvoice(ctx context.Context, in *GetInvoiceRequest, opts ...grpc.CallOption) (*Invoice, error)
+	ListInvoices(ctx context.Context, in *ListInvoicesRequest, opts ...grpc.CallOption) (*ListInvoicesResponse, error)
+	UpdateInvoice(ctx context.Context, in *Invoice, opts ...grpc.CallOption) (*Invoice, error)
+	FinalizeInvoice(ctx context.Context, in *FinalizeInvoiceRequest, opts ...grpc.CallOption) (*Invoice, error)
+	VoidInvoice(ctx context.Context, in *VoidInvoiceRequest, opts ...grpc.CallOption) (*Invoice, error)
+	PayInvoice(ctx context.Context, in *PayInvoiceRequest, opts ...grpc.CallOption) (*Invoice, error)
+	RenderInvoice(ctx context.Context, in *RenderInvoiceRequest, opts ...grpc.CallOption) (*RenderInvoiceResponse, error)
+	// Customers are who payments, saved payment methods and subscriptions
+	// belong to. EraseCustomer anonymizes a customer and removes the
+	// personal data of its payment methods; its payments stay on record.
+	CreateCustomer(ctx context.Context, in *Customer, opts ...grpc.CallOption) (*Customer, error)
+	GetCustomer(ctx context.Context, in *GetCustomerRequest, opts ...grpc.CallOption) (*Customer, error)
+	ListCustomers(ctx context.Context, in *ListCustomersRequest, opts ...grpc.CallOption) (*ListCustomersResponse, error)
+	UpdateCustomer(ctx context.Context, in *Customer, opts ...grpc.CallOption) (*Customer, error)
+	DeleteCustomer(ctx context.Context, in *DeleteCustomerRequest, opts ...grpc.CallOption) (*DeleteCustomerResponse, error)
+	EraseCustomer(ctx context.Context, in *EraseCustomerRequest, opts ...grpc.CallOption) (*Customer, error)
+	AttachPaymentMethod(ctx context.Context, in *AttachPaymentMethodRequest, opts ...grpc.CallOption) (*CustomerPaymentMethods, error)
+	ListCustomerPaymentMethods(ctx context.Context, in *ListCustomerPaymentMethodsRequest, opts ...grpc.CallOption) (*CustomerPaymentMethods, error)
+	ListCustomerPayments(ctx context.Context, in *ListCustomerPaymentsRequest, opts ...grpc.CallOption) (*ListPaymentsResponse, error)
+	GetCustomerLifetimeValue(ctx context.Context, in *GetCustomerLifetimeValueRequest, opts ...grpc.CallOption) (*CustomerLifetimeValue, error)
+	// Disputes are cardholders contesting payments with their bank. Each
+	// holds its amount back from the tenant's balance until it is won or
+	// lost. Evidence is uploaded while a dispute needs a response and sent
+	// to the bank by SubmitDispute; AcceptDispute gives the dispute up.
+	CreateDispute(ctx context.Context, in *CreateDisputeRequest, opts ...grpc.CallOption) (*Dispute, error)
+	GetDispute(ctx context.Context, in *GetDisputeRequest, opts ...grpc.CallOption) (*Dispute, error)
+	ListDisputes(ctx context.Context, in *ListDisputesRequest, opts ...grpc.CallOption) (*ListDisputesResponse, error)
+	UploadDisputeEvidence(ctx context.Context, in *UploadDisputeEvidenceRequest, opts ...grpc.CallOption) (*DisputeEvidence, error)
+	GetDisputeEvidence(ctx context.Context, in *GetDisputeEvidenceRequest, opts ...grpc.CallOption) (*GetDisputeEvidenceResponse, error)
+	SubmitDispute(ctx context.Context, in *SubmitDisputeRequest, opts ...grpc.CallOption) (*Dispute, error)
+	AcceptDispute(ctx context.Context, in *AcceptDisputeRequest, opts ...grpc.CallOption) (*Dispute, error)
+	ResolveDispute(ctx context.Context, in *ResolveDisputeRequest, opts ...grpc.CallOption) (*Dispute, error)
 }
 
 type paymentServiceClient struct {
@@ -95,6 +273,719 @@ func (c *paymentServiceClient) ListPayments(ctx context.Context, in *ListPayment
 	return out, nil
 }
 
+func (c *paymentServiceClient) RefundPayment(ctx context.Context, in *RefundPaymentRequest, opts ...grpc.CallOption) (*RefundPaymentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RefundPaymentResponse)
+	err := c.cc.Invoke(ctx, PaymentService_RefundPayment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CancelPayment(ctx context.Context, in *CancelPaymentRequest, opts ...grpc.CallOption) (*CancelPaymentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelPaymentResponse)
+	err := c.cc.Invoke(ctx, PaymentService_CancelPayment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ListPaymentEvents(ctx context.Context, in *ListPaymentEventsRequest, opts ...grpc.CallOption) (*ListPaymentEventsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPaymentEventsResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ListPaymentEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) WatchPayments(ctx context.Context, in *WatchPaymentsRequest, opts ...grpc.CallOption) (PaymentService_WatchPaymentsClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PaymentService_ServiceDesc.Streams[0], PaymentService_WatchPayments_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &paymentServiceWatchPaymentsClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type PaymentService_WatchPaymentsClient interface {
+	Recv() (*PaymentEvent, error)
+	grpc.ClientStream
+}
+
+type paymentServiceWatchPaymentsClient struct {
+	grpc.ClientStream
+}
+
+func (x *paymentServiceWatchPaymentsClient) Recv() (*PaymentEvent, error) {
+	m := new(PaymentEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *paymentServiceClient) CreatePaymentMethod(ctx context.Context, in *CreatePaymentMethodRequest, opts ...grpc.CallOption) (*PaymentMethod, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PaymentMethod)
+	err := c.cc.Invoke(ctx, PaymentService_CreatePaymentMethod_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetPaymentMethod(ctx context.Context, in *GetPaymentMethodRequest, opts ...grpc.CallOption) (*PaymentMethod, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PaymentMethod)
+	err := c.cc.Invoke(ctx, PaymentService_GetPaymentMethod_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CreateBankAccount(ctx context.Context, in *CreateBankAccountRequest, opts ...grpc.CallOption) (*BankAccount, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BankAccount)
+	err := c.cc.Invoke(ctx, PaymentService_CreateBankAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetBankAccount(ctx context.Context, in *GetBankAccountRequest, opts ...grpc.CallOption) (*BankAccount, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BankAccount)
+	err := c.cc.Invoke(ctx, PaymentService_GetBankAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CreatePayout(ctx context.Context, in *CreatePayoutRequest, opts ...grpc.CallOption) (*CreditTransfer, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreditTransfer)
+	err := c.cc.Invoke(ctx, PaymentService_CreatePayout_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetCreditTransfer(ctx context.Context, in *GetCreditTransferRequest, opts ...grpc.CallOption) (*CreditTransfer, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreditTransfer)
+	err := c.cc.Invoke(ctx, PaymentService_GetCreditTransfer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CreateCreditTransferBatch(ctx context.Context, in *CreateCreditTransferBatchRequest, opts ...grpc.CallOption) (*CreditTransferBatch, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreditTransferBatch)
+	err := c.cc.Invoke(ctx, PaymentService_CreateCreditTransferBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetCreditTransferBatch(ctx context.Context, in *GetCreditTransferBatchRequest, opts ...grpc.CallOption) (*CreditTransferBatch, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreditTransferBatch)
+	err := c.cc.Invoke(ctx, PaymentService_GetCreditTransferBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ImportPaymentStatusReport(ctx context.Context, in *ImportPaymentStatusReportRequest, opts ...grpc.CallOption) (*ImportPaymentStatusReportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ImportPaymentStatusReportResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ImportPaymentStatusReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ImportBankStatement(ctx context.Context, in *ImportBankStatementRequest, opts ...grpc.CallOption) (*ReconciliationRun, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReconciliationRun)
+	err := c.cc.Invoke(ctx, PaymentService_ImportBankStatement_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetReconciliationRun(ctx context.Context, in *GetReconciliationRunRequest, opts ...grpc.CallOption) (*ReconciliationRun, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReconciliationRun)
+	err := c.cc.Invoke(ctx, PaymentService_GetReconciliationRun_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ListReconciliationRuns(ctx context.Context, in *ListReconciliationRunsRequest, opts ...grpc.CallOption) (*ListReconciliationRunsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListReconciliationRunsResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ListReconciliationRuns_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ResolveReconciliationLine(ctx context.Context, in *ResolveReconciliationLineRequest, opts ...grpc.CallOption) (*ReconciliationLine, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReconciliationLine)
+	err := c.cc.Invoke(ctx, PaymentService_ResolveReconciliationLine_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ImportSettlementReport(ctx context.Context, in *ImportSettlementReportRequest, opts ...grpc.CallOption) (*SettlementBatch, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SettlementBatch)
+	err := c.cc.Invoke(ctx, PaymentService_ImportSettlementReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetSettlementBatch(ctx context.Context, in *GetSettlementBatchRequest, opts ...grpc.CallOption) (*SettlementBatch, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SettlementBatch)
+	err := c.cc.Invoke(ctx, PaymentService_GetSettlementBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ListSettlementBatches(ctx context.Context, in *ListSettlementBatchesRequest, opts ...grpc.CallOption) (*ListSettlementBatchesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSettlementBatchesResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ListSettlementBatches_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ListLedgerPostings(ctx context.Context, in *ListLedgerPostingsRequest, opts ...grpc.CallOption) (*ListLedgerPostingsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListLedgerPostingsResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ListLedgerPostings_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ImportFXRates(ctx context.Context, in *ImportFXRatesRequest, opts ...grpc.CallOption) (*ImportFXRatesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ImportFXRatesResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ImportFXRates_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ListFXRates(ctx context.Context, in *ListFXRatesRequest, opts ...grpc.CallOption) (*ListFXRatesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListFXRatesResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ListFXRates_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CreateFXQuote(ctx context.Context, in *CreateFXQuoteRequest, opts ...grpc.CallOption) (*FXQuote, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FXQuote)
+	err := c.cc.Invoke(ctx, PaymentService_CreateFXQuote_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetPaymentConversion(ctx context.Context, in *GetPaymentConversionRequest, opts ...grpc.CallOption) (*PaymentConversion, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PaymentConversion)
+	err := c.cc.Invoke(ctx, PaymentService_GetPaymentConversion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CreatePricingPlan(ctx context.Context, in *PricingPlan, opts ...grpc.CallOption) (*PricingPlan, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PricingPlan)
+	err := c.cc.Invoke(ctx, PaymentService_CreatePricingPlan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) UpdatePricingPlan(ctx context.Context, in *PricingPlan, opts ...grpc.CallOption) (*PricingPlan, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PricingPlan)
+	err := c.cc.Invoke(ctx, PaymentService_UpdatePricingPlan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetPricingPlan(ctx context.Context, in *GetPricingPlanRequest, opts ...grpc.CallOption) (*PricingPlan, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PricingPlan)
+	err := c.cc.Invoke(ctx, PaymentService_GetPricingPlan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ListPricingPlans(ctx context.Context, in *ListPricingPlansRequest, opts ...grpc.CallOption) (*ListPricingPlansResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPricingPlansResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ListPricingPlans_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) AssignPricingPlan(ctx context.Context, in *AssignPricingPlanRequest, opts ...grpc.CallOption) (*PricingPlan, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PricingPlan)
+	err := c.cc.Invoke(ctx, PaymentService_AssignPricingPlan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetTenantPricingPlan(ctx context.Context, in *GetTenantPricingPlanRequest, opts ...grpc.CallOption) (*PricingPlan, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PricingPlan)
+	err := c.cc.Invoke(ctx, PaymentService_GetTenantPricingPlan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) SimulateFee(ctx context.Context, in *SimulateFeeRequest, opts ...grpc.CallOption) (*PaymentFee, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PaymentFee)
+	err := c.cc.Invoke(ctx, PaymentService_SimulateFee_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetPaymentFee(ctx context.Context, in *GetPaymentFeeRequest, opts ...grpc.CallOption) (*PaymentFee, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PaymentFee)
+	err := c.cc.Invoke(ctx, PaymentService_GetPaymentFee_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetFeeStatement(ctx context.Context, in *GetFeeStatementRequest, opts ...grpc.CallOption) (*FeeStatement, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FeeStatement)
+	err := c.cc.Invoke(ctx, PaymentService_GetFeeStatement_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CreateSubscriptionPlan(ctx context.Context, in *SubscriptionPlan, opts ...grpc.CallOption) (*SubscriptionPlan, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SubscriptionPlan)
+	err := c.cc.Invoke(ctx, PaymentService_CreateSubscriptionPlan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetSubscriptionPlan(ctx context.Context, in *GetSubscriptionPlanRequest, opts ...grpc.CallOption) (*SubscriptionPlan, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SubscriptionPlan)
+	err := c.cc.Invoke(ctx, PaymentService_GetSubscriptionPlan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ListSubscriptionPlans(ctx context.Context, in *ListSubscriptionPlansRequest, opts ...grpc.CallOption) (*ListSubscriptionPlansResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSubscriptionPlansResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ListSubscriptionPlans_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CreateSubscription(ctx context.Context, in *CreateSubscriptionRequest, opts ...grpc.CallOption) (*Subscription, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Subscription)
+	err := c.cc.Invoke(ctx, PaymentService_CreateSubscription_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetSubscription(ctx context.Context, in *GetSubscriptionRequest, opts ...grpc.CallOption) (*Subscription, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Subscription)
+	err := c.cc.Invoke(ctx, PaymentService_GetSubscription_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ListSubscriptions(ctx context.Context, in *ListSubscriptionsRequest, opts ...grpc.CallOption) (*ListSubscriptionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSubscriptionsResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ListSubscriptions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ChangeSubscriptionPlan(ctx context.Context, in *ChangeSubscriptionPlanRequest, opts ...grpc.CallOption) (*Subscription, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Subscription)
+	err := c.cc.Invoke(ctx, PaymentService_ChangeSubscriptionPlan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CancelSubscription(ctx context.Context, in *CancelSubscriptionRequest, opts ...grpc.CallOption) (*Subscription, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Subscription)
+	err := c.cc.Invoke(ctx, PaymentService_CancelSubscription_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ListSubscriptionCharges(ctx context.Context, in *ListSubscriptionChargesRequest, opts ...grpc.CallOption) (*ListSubscriptionChargesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSubscriptionChargesResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ListSubscriptionCharges_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CreateInvoice(ctx context.Context, in *Invoice, opts ...grpc.CallOption) (*Invoice, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Invoice)
+	err := c.cc.Invoke(ctx, PaymentService_CreateInvoice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetInvoice(ctx context.Context, in *GetInvoiceRequest, opts ...grpc.CallOption) (*Invoice, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Invoice)
+	err := c.cc.Invoke(ctx, PaymentService_GetInvoice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ListInvoices(ctx context.Context, in *ListInvoicesRequest, opts ...grpc.CallOption) (*ListInvoicesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListInvoicesResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ListInvoices_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) UpdateInvoice(ctx context.Context, in *Invoice, opts ...grpc.CallOption) (*Invoice, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Invoice)
+	err := c.cc.Invoke(ctx, PaymentService_UpdateInvoice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) FinalizeInvoice(ctx context.Context, in *FinalizeInvoiceRequest, opts ...grpc.CallOption) (*Invoice, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Invoice)
+	err := c.cc.Invoke(ctx, PaymentService_FinalizeInvoice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) VoidInvoice(ctx context.Context, in *VoidInvoiceRequest, opts ...grpc.CallOption) (*Invoice, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Invoice)
+	err := c.cc.Invoke(ctx, PaymentService_VoidInvoice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) PayInvoice(ctx context.Context, in *PayInvoiceRequest, opts ...grpc.CallOption) (*Invoice, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Invoice)
+	err := c.cc.Invoke(ctx, PaymentService_PayInvoice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) RenderInvoice(ctx context.Context, in *RenderInvoiceRequest, opts ...grpc.CallOption) (*RenderInvoiceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RenderInvoiceResponse)
+	err := c.cc.Invoke(ctx, PaymentService_RenderInvoice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CreateCustomer(ctx context.Context, in *Customer, opts ...grpc.CallOption) (*Customer, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Customer)
+	err := c.cc.Invoke(ctx, PaymentService_CreateCustomer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetCustomer(ctx context.Context, in *GetCustomerRequest, opts ...grpc.CallOption) (*Customer, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Customer)
+	err := c.cc.Invoke(ctx, PaymentService_GetCustomer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ListCustomers(ctx context.Context, in *ListCustomersRequest, opts ...grpc.CallOption) (*ListCustomersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListCustomersResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ListCustomers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) UpdateCustomer(ctx context.Context, in *Customer, opts ...grpc.CallOption) (*Customer, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Customer)
+	err := c.cc.Invoke(ctx, PaymentService_UpdateCustomer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) DeleteCustomer(ctx context.Context, in *DeleteCustomerRequest, opts ...grpc.CallOption) (*DeleteCustomerResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteCustomerResponse)
+	err := c.cc.Invoke(ctx, PaymentService_DeleteCustomer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) EraseCustomer(ctx context.Context, in *EraseCustomerRequest, opts ...grpc.CallOption) (*Customer, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Customer)
+	err := c.cc.Invoke(ctx, PaymentService_EraseCustomer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) AttachPaymentMethod(ctx context.Context, in *AttachPaymentMethodRequest, opts ...grpc.CallOption) (*CustomerPaymentMethods, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CustomerPaymentMethods)
+	err := c.cc.Invoke(ctx, PaymentService_AttachPaymentMethod_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ListCustomerPaymentMethods(ctx context.Context, in *ListCustomerPaymentMethodsRequest, opts ...grpc.CallOption) (*CustomerPaymentMethods, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CustomerPaymentMethods)
+	err := c.cc.Invoke(ctx, PaymentService_ListCustomerPaymentMethods_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ListCustomerPayments(ctx context.Context, in *ListCustomerPaymentsRequest, opts ...grpc.CallOption) (*ListPaymentsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPaymentsResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ListCustomerPayments_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetCustomerLifetimeValue(ctx context.Context, in *GetCustomerLifetimeValueRequest, opts ...grpc.CallOption) (*CustomerLifetimeValue, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CustomerLifetimeValue)
+	err := c.cc.Invoke(ctx, PaymentService_GetCustomerLifetimeValue_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CreateDispute(ctx context.Context, in *CreateDisputeRequest, opts ...grpc.CallOption) (*Dispute, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Dispute)
+	err := c.cc.Invoke(ctx, PaymentService_CreateDispute_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetDispute(ctx context.Context, in *GetDisputeRequest, opts ...grpc.CallOption) (*Dispute, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Dispute)
+	err := c.cc.Invoke(ctx, PaymentService_GetDispute_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ListDisputes(ctx context.Context, in *ListDisputesRequest, opts ...grpc.CallOption) (*ListDisputesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDisputesResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ListDisputes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) UploadDisputeEvidence(ctx context.Context, in *UploadDisputeEvidenceRequest, opts ...grpc.CallOption) (*DisputeEvidence, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DisputeEvidence)
+	err := c.cc.Invoke(ctx, PaymentService_UploadDisputeEvidence_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetDisputeEvidence(ctx context.Context, in *GetDisputeEvidenceRequest, opts ...grpc.CallOption) (*GetDisputeEvidenceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetDisputeEvidenceResponse)
+	err := c.cc.Invoke(ctx, PaymentService_GetDisputeEvidence_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) SubmitDispute(ctx context.Context, in *SubmitDisputeRequest, opts ...grpc.CallOption) (*Dispute, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Dispute)
+	err := c.cc.Invoke(ctx, PaymentService_SubmitDispute_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) AcceptDispute(ctx context.Context, in *AcceptDisputeRequest, opts ...grpc.CallOption) (*Dispute, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Dispute)
+	err := c.cc.Invoke(ctx, PaymentService_AcceptDispute_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ResolveDispute(ctx context.Context, in *ResolveDisputeRequest, opts ...grpc.CallOption) (*Dispute, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Dispute)
+	err := c.cc.Invoke(ctx, PaymentService_ResolveDispute_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PaymentServiceServer is the server API for PaymentService service.
 // All implementations must embed UnimplementedPaymentServiceServer
 // for forward compatibility
@@ -104,127 +995,1688 @@ type PaymentServiceServer interface {
 	UpdatePayment(context.Context, *UpdatePaymentRequest) (*UpdatePaymentResponse, error)
 	DeletePayment(context.Context, *DeletePaymentRequest) (*DeletePaymentResponse, error)
 	ListPayments(context.Context, *ListPaymentsRequest) (*ListPaymentsResponse, error)
+	// The payment lifecycle RPCs below have no v1 bindings; REST exposes
+	// them under /v2 only.
+	RefundPayment(context.Context, *RefundPaymentRequest) (*RefundPaymentResponse, error)
+	CancelPayment(context.Context, *CancelPaymentRequest) (*CancelPaymentResponse, error)
+	ListPaymentEvents(context.Context, *ListPaymentEventsRequest) (*ListPaymentEventsResponse, error)
+	// WatchPayments streams payment events with an id greater than
+	// after_event_id, then follows new events until the client cancels.
+	WatchPayments(*WatchPaymentsRequest, PaymentService_WatchPaymentsServer) error
+	// CreatePaymentMethod stores a card in the vault and returns its token;
+	// the card number is never returned again.
+	CreatePaymentMethod(context.Context, *CreatePaymentMethodRequest) (*PaymentMethod, error)
+	GetPaymentMethod(context.Context, *GetPaymentMethodRequest) (*PaymentMethod, error)
+	// CreateBankAccount validates a SEPA account and returns its token,
+	// which pays by bank transfer in place of a vaulted card.
+	CreateBankAccount(context.Context, *CreateBankAccountRequest) (*BankAccount, error)
+	GetBankAccount(context.Context, *GetBankAccountRequest) (*BankAccount, error)
+	// CreatePayout queues a credit transfer to a bank account for the next
+	// batch. Refunds of bank transfer payments are queued the same way.
+	CreatePayout(context.Context, *CreatePayoutRequest) (*CreditTransfer, error)
+	GetCreditTransfer(context.Context, *GetCreditTransferRequest) (*CreditTransfer, error)
+	// CreateCreditTransferBatch collects the pending credit transfers into a
+	// pain.001 file for the bank.
+	CreateCreditTransferBatch(context.Context, *CreateCreditTransferBatchRequest) (*CreditTransferBatch, error)
+	GetCreditTransferBatch(context.Context, *GetCreditTransferBatchRequest) (*CreditTransferBatch, error)
+	// ImportPaymentStatusReport applies a pain.002 report from the bank to
+	// the batch and transfers it refers to.
+	ImportPaymentStatusReport(context.Context, *ImportPaymentStatusReportRequest) (*ImportPaymentStatusReportResponse, error)
+	// ImportBankStatement matches the lines of a camt.053 or MT940 statement
+	// against payments and refunds and records the outcome as a run.
+	ImportBankStatement(context.Context, *ImportBankStatementRequest) (*ReconciliationRun, error)
+	GetReconciliationRun(context.Context, *GetReconciliationRunRequest) (*ReconciliationRun, error)
+	ListReconciliationRuns(context.Context, *ListReconciliationRunsRequest) (*ListReconciliationRunsResponse, error)
+	// ResolveReconciliationLine settles an exception of a run by hand.
+	ResolveReconciliationLine(context.Context, *ResolveReconciliationLineRequest) (*ReconciliationLine, error)
+	// ImportSettlementReport checks a provider's settlement file against its
+	// payments and books the provider's fees to the ledger.
+	ImportSettlementReport(context.Context, *ImportSettlementReportRequest) (*SettlementBatch, error)
+	GetSettlementBatch(context.Context, *GetSettlementBatchRequest) (*SettlementBatch, error)
+	ListSettlementBatches(context.Context, *ListSettlementBatchesRequest) (*ListSettlementBatchesResponse, error)
+	ListLedgerPostings(context.Context, *ListLedgerPostingsRequest) (*ListLedgerPostingsResponse, error)
+	// ImportFXRates adds exchange rates to the table shared by all tenants.
+	ImportFXRates(context.Context, *ImportFXRatesRequest) (*ImportFXRatesResponse, error)
+	ListFXRates(context.Context, *ListFXRatesRequest) (*ListFXRatesResponse, error)
+	// CreateFXQuote locks the current rate of a currency pair for a while;
+	// payments created with the quote are converted at its rate.
+	CreateFXQuote(context.Context, *CreateFXQuoteRequest) (*FXQuote, error)
+	GetPaymentConversion(context.Context, *GetPaymentConversionRequest) (*PaymentConversion, error)
+	// Pricing plans are shared by all tenants; AssignPricingPlan puts the
+	// tenant in scope on one. Payments are charged the plan's fee when they
+	// are captured.
+	CreatePricingPlan(context.Context, *PricingPlan) (*PricingPlan, error)
+	UpdatePricingPlan(context.Context, *PricingPlan) (*PricingPlan, error)
+	GetPricingPlan(context.Context, *GetPricingPlanRequest) (*PricingPlan, error)
+	ListPricingPlans(context.Context, *ListPricingPlansRequest) (*ListPricingPlansResponse, error)
+	AssignPricingPlan(context.Context, *AssignPricingPlanRequest) (*PricingPlan, error)
+	GetTenantPricingPlan(context.Context, *GetTenantPricingPlanRequest) (*PricingPlan, error)
+	// SimulateFee prices a hypothetical payment by the tenant's plan
+	// without recording anything.
+	SimulateFee(context.Context, *SimulateFeeRequest) (*PaymentFee, error)
+	GetPaymentFee(context.Context, *GetPaymentFeeRequest) (*PaymentFee, error)
+	GetFeeStatement(context.Context, *GetFeeStatementRequest) (*FeeStatement, error)
+	// Subscriptions charge their payment method for a plan every period; a
+	// scheduler creates the payments when they are due and retries failed
+	// ones. Plan changes are prorated into the next renewal.
+	CreateSubscriptionPlan(context.Context, *SubscriptionPlan) (*SubscriptionPlan, error)
+	GetSubscriptionPlan(context.Context, *GetSubscriptionPlanRequest) (*SubscriptionPlan, error)
+	ListSubscriptionPlans(context.Context, *ListSubscriptionPlansRequest) (*ListSubscriptionPlansResponse, error)
+	CreateSubscription(context.Context, *CreateSubscriptionRequest) (*Subscription, error)
+	GetSubscription(context.Context, *GetSubscriptionRequest) (*Subscription, error)
+	ListSubscriptions(context.Context, *ListSubscriptionsRequest) (*ListSubscriptionsResponse, error)
+	ChangeSubscriptionPlan(context.Context, *ChangeSubscriptionPlanRequest) (*Subscription, error)
+	CancelSubscription(context.Context, *CancelSubscriptionRequest) (*Subscription, error)
+	ListSubscriptionCharges(context.Context, *ListSubscriptionChargesRequest) (*ListSubscriptionChargesResponse, error)
+	// Invoices are drafted, finalized into the tenant's gapless number
+	// sequence and paid by one or more payments. RenderInvoice returns the
+	// invoice as an HTML or PDF document.
+	CreateInvoice(context.Context, *Invoice) (*Invoice, error)
+	GetInvoice(context.Context, *GetInvoiceRequest) (*Invoice, error)
+	ListInvoices(context.Context, *ListInvoicesRequest) (*ListInvoicesResponse, error)
+	UpdateInvoice(context.Context, *Invoice) (*Invoice, error)
+	FinalizeInvoice(context.Context, *FinalizeInvoiceRequest) (*Invoice, error)
+	VoidInvoice(context.Context, *VoidInvoiceRequest) (*Invoice, error)
+	PayInvoice(context.Context, *PayInvoiceRequest) (*Invoice, error)
+	RenderInvoice(context.Context, *RenderInvoiceRequest) (*RenderInvoiceResponse, error)
+	// Customers are who payments, saved payment methods and subscriptions
+	// belong to. EraseCustomer anonymizes a customer and removes the
+	// personal data of its payment methods; its payments stay on record.
+	CreateCustomer(context.Context, *Customer) (*Customer, error)
+	GetCustomer(context.Context, *GetCustomerRequest) (*Customer, error)
+	ListCustomers(context.Context, *ListCustomersRequest) (*ListCustomersResponse, error)
+	UpdateCustomer(context.Context, *Customer) (*Customer, error)
+	DeleteCustomer(context.Context, *DeleteCustomerRequest) (*DeleteCustomerResponse, error)
+	EraseCustomer(context.Context, *EraseCustomerRequest) (*Customer, error)
+	AttachPaymentMethod(context.Context, *AttachPaymentMethodRequest) (*CustomerPaymentMethods, error)
+	ListCustomerPaymentMethods(context.Context, *ListCustomerPaymentMethodsRequest) (*CustomerPaymentMethods, error)
+	ListCustomerPayments(context.Context, *ListCustomerPaymentsRequest) (*ListPaymentsResponse, error)
+	GetCustomerLifetimeValue(context.Context, *GetCustomerLifetimeValueRequest) (*CustomerLifetimeValue, error)
+	// Disputes are cardholders contesting payments with their bank. Each
+	// holds its amount back from the tenant's balance until it is won or
+	// lost. Evidence is uploaded while a dispute needs a response and sent
+	// to the bank by SubmitDispute; AcceptDispute gives the dispute up.
+	CreateDispute(context.Context, *CreateDisputeRequest) (*Dispute, error)
+	GetDispute(context.Context, *GetDisputeRequest) (*Dispute, error)
+	ListDisputes(context.Context, *ListDisputesRequest) (*ListDisputesResponse, error)
+	UploadDisputeEvidence(context.Context, *UploadDisputeEvidenceRequest) (*DisputeEvidence, error)
+	GetDisputeEvidence(context.Context, *GetDisputeEvidenceRequest) (*GetDisputeEvidenceResponse, error)
+	SubmitDispute(context.Context, *SubmitDisputeRequest) (*Dispute, error)
+	AcceptDispute(context.Context, *AcceptDisputeRequest) (*Dispute, error)
+	ResolveDispute(context.Context, *ResolveDisputeRequest) (*Dispute, error)
+	mustEmbedUnimplementedPaymentServiceServer()
+}
+
+// UnimplementedPaymentServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedPaymentServiceServer struct {
+}
+
+func (UnimplementedPaymentServiceServer) CreatePayment(context.Context, *CreatePaymentRequest) (*CreatePaymentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePayment not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetPayment(context.Context, *GetPaymentRequest) (*GetPaymentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPayment not implemented")
+}
+func (UnimplementedPaymentServiceServer) UpdatePayment(context.Context, *UpdatePaymentRequest) (*UpdatePaymentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePayment not implemented")
+}
+func (UnimplementedPaymentServiceServer) DeletePayment(context.Context, *DeletePaymentRequest) (*DeletePaymentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeletePayment not implemented")
+}
+func (UnimplementedPaymentServiceServer) ListPayments(context.Context, *ListPaymentsRequest) (*ListPaymentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPayments not implemented")
+}
+func (UnimplementedPaymentServiceServer) RefundPayment(context.Context, *RefundPaymentRequest) (*RefundPaymentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefundPayment not implemented")
+}
+func (UnimplementedPaymentServiceServer) CancelPayment(context.Context, *CancelPaymentRequest) (*CancelPaymentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelPayment not implemented")
+}
+func (UnimplementedPaymentServiceServer) ListPaymentEvents(context.Context, *ListPaymentEventsRequest) (*ListPaymentEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPaymentEvents not implemented")
+}
+func (UnimplementedPaymentServiceServer) WatchPayments(*WatchPaymentsRequest, PaymentService_WatchPaymentsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchPayments not implemented")
+}
+func (UnimplementedPaymentServiceServer) CreatePaymentMethod(context.Context, *CreatePaymentMethodRequest) (*PaymentMethod, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePaymentMethod not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetPaymentMethod(context.Context, *GetPaymentMethodRequest) (*PaymentMethod, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPaymentMethod not implemented")
+}
+func (UnimplementedPaymentServiceServer) CreateBankAccount(context.Context, *CreateBankAccountRequest) (*BankAccount, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateBankAccount not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetBankAccount(context.Context, *GetBankAccountRequest) (*BankAccount, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBankAccount not implemented")
+}
+func (UnimplementedPaymentServiceServer) CreatePayout(context.Context, *CreatePayoutRequest) (*CreditTransfer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePayout not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetCreditTransfer(context.Context, *GetCreditTransferRequest) (*CreditTransfer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCreditTransfer not implemented")
+}
+func (UnimplementedPaymentServiceServer) CreateCreditTransferBatch(context.Context, *CreateCreditTransferBatchRequest) (*CreditTransferBatch, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCreditTransferBatch not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetCreditTransferBatch(context.Context, *GetCreditTransferBatchRequest) (*CreditTransferBatch, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCreditTransferBatch not implemented")
+}
+func (UnimplementedPaymentServiceServer) ImportPaymentStatusReport(context.Context, *ImportPaymentStatusReportRequest) (*ImportPaymentStatusReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportPaymentStatusReport not implemented")
+}
+func (UnimplementedPaymentServiceServer) ImportBankStatement(context.Context, *ImportBankStatementRequest) (*ReconciliationRun, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportBankStatement not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetReconciliationRun(context.Context, *GetReconciliationRunRequest) (*ReconciliationRun, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetReconciliationRun not implemented")
+}
+func (UnimplementedPaymentServiceServer) ListReconciliationRuns(context.Context, *ListReconciliationRunsRequest) (*ListReconciliationRunsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListReconciliationRuns not implemented")
+}
+func (UnimplementedPaymentServiceServer) ResolveReconciliationLine(context.Context, *ResolveReconciliationLineRequest) (*ReconciliationLine, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResolveReconciliationLine not implemented")
+}
+func (UnimplementedPaymentServiceServer) ImportSettlementReport(context.Context, *ImportSettlementReportRequest) (*SettlementBatch, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportSettlementReport not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetSettlementBatch(context.Context, *GetSettlementBatchRequest) (*SettlementBatch, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSettlementBatch not implemented")
+}
+func (UnimplementedPaymentServiceServer) ListSettlementBatches(context.Context, *ListSettlementBatchesRequest) (*ListSettlementBatchesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSettlementBatches not implemented")
+}
+func (UnimplementedPaymentServiceServer) ListLedgerPostings(context.Context, *ListLedgerPostingsRequest) (*ListLedgerPostingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListLedgerPostings not implemented")
+}
+func (UnimplementedPaymentServiceServer) ImportFXRates(context.Context, *ImportFXRatesRequest) (*ImportFXRatesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportFXRates not implemented")
+}
+func (UnimplementedPaymentServiceServer) ListFXRates(context.Context, *ListFXRatesRequest) (*ListFXRatesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFXRates not implemented")
+}
+func (UnimplementedPaymentServiceServer) CreateFXQuote(context.Context, *CreateFXQuoteRequest) (*FXQuote, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateFXQuote not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetPaymentConversion(context.Context, *GetPaymentConversionRequest) (*PaymentConversion, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPaymentConversion not implemented")
+}
+func (UnimplementedPaymentServiceServer) CreatePricingPlan(context.Context, *PricingPlan) (*PricingPlan, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePricingPlan not implemented")
+}
+func (UnimplementedPaymentServiceServer) UpdatePricingPlan(context.Context, *PricingPlan) (*PricingPlan, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePricingPlan not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetPricingPlan(context.Context, *GetPricingPlanRequest) (*PricingPlan, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPricingPlan not implemented")
+}
+func (UnimplementedPaymentServiceServer) ListPricingPlans(context.Context, *ListPricingPlansRequest) (*ListPricingPlansResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPricingPlans not implemented")
+}
+func (UnimplementedPaymentServiceServer) AssignPricingPlan(context.Context, *AssignPricingPlanRequest) (*PricingPlan, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AssignPricingPlan not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetTenantPricingPlan(context.Context, *GetTenantPricingPlanRequest) (*PricingPlan, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTenantPricingPlan not implemented")
+}
+func (UnimplementedPaymentServiceServer) SimulateFee(context.Context, *SimulateFeeRequest) (*PaymentFee, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SimulateFee not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetPaymentFee(context.Context, *GetPaymentFeeRequest) (*PaymentFee, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPaymentFee not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetFeeStatement(context.Context, *GetFeeStatementRequest) (*FeeStatement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFeeStatement not implemented")
+}
+func (UnimplementedPaymentServiceServer) CreateSubscriptionPlan(context.Context, *SubscriptionPlan) (*SubscriptionPlan, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSubscriptionPlan not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetSubscriptionPlan(context.Context, *GetSubscriptionPlanRequest) (*SubscriptionPlan, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSubscriptionPlan not implemented")
+}
+func (UnimplementedPaymentServiceServer) ListSubscriptionPlans(context.Context, *ListSubscriptionPlansRequest) (*ListSubscriptionPlansResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSubscriptionPlans not implemented")
+}
+func (UnimplementedPaymentServiceServer) CreateSubscription(context.Context, *CreateSubscriptionRequest) (*Subscription, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSubscription not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetSubscription(context.Context, *GetSubscriptionRequest) (*Subscription, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSubscription not implemented")
+}
+func (UnimplementedPaymentServiceServer) ListSubscriptions(context.Context, *ListSubscriptionsRequest) (*ListSubscriptionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSubscriptions not implemented")
+}
+func (UnimplementedPaymentServiceServer) ChangeSubscriptionPlan(context.Context, *ChangeSubscriptionPlanRequest) (*Subscription, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangeSubscriptionPlan not implemented")
+}
+func (UnimplementedPaymentServiceServer) CancelSubscription(context.Context, *CancelSubscriptionRequest) (*Subscription, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelSubscription not implemented")
+}
+func (UnimplementedPaymentServiceServer) ListSubscriptionCharges(context.Context, *ListSubscriptionChargesRequest) (*ListSubscriptionChargesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSubscriptionCharges not implemented")
+}
+func (UnimplementedPaymentServiceServer) CreateInvoice(context.Context, *Invoice) (*Invoice, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateInvoice not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetInvoice(context.Context, *GetInvoiceRequest) (*Invoice, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetInvoice not implemented")
+}
+func (UnimplementedPaymentServiceServer) ListInvoices(context.Context, *ListInvoicesRequest) (*ListInvoicesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListInvoices not implemented")
+}
+func (UnimplementedPaymentServiceServer) UpdateInvoice(context.Context, *Invoice) (*Invoice, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateInvoice not implemented")
+}
+func (UnimplementedPaymentServiceServer) FinalizeInvoice(context.Context, *FinalizeInvoiceRequest) (*Invoice, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FinalizeInvoice not implemented")
+}
+func (UnimplementedPaymentServiceServer) VoidInvoice(context.Context, *VoidInvoiceRequest) (*Invoice, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VoidInvoice not implemented")
+}
+func (UnimplementedPaymentServiceServer) PayInvoice(context.Context, *PayInvoiceRequest) (*Invoice, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PayInvoice not implemented")
+}
+func (UnimplementedPaymentServiceServer) RenderInvoice(context.Context, *RenderInvoiceRequest) (*RenderInvoiceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenderInvoice not implemented")
+}
+func (UnimplementedPaymentServiceServer) CreateCustomer(context.Context, *Customer) (*Customer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCustomer not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetCustomer(context.Context, *GetCustomerRequest) (*Customer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCustomer not implemented")
+}
+func (UnimplementedPaymentServiceServer) ListCustomers(context.Context, *ListCustomersRequest) (*ListCustomersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCustomers not implemented")
+}
+func (UnimplementedPaymentServiceServer) UpdateCustomer(context.Context, *Customer) (*Customer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateCustomer not implemented")
+}
+func (UnimplementedPaymentServiceServer) DeleteCustomer(context.Context, *DeleteCustomerRequest) (*DeleteCustomerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteCustomer not implemented")
+}
+func (UnimplementedPaymentServiceServer) EraseCustomer(context.Context, *EraseCustomerRequest) (*Customer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EraseCustomer not implemented")
+}
+func (UnimplementedPaymentServiceServer) AttachPaymentMethod(context.Context, *AttachPaymentMethodRequest) (*CustomerPaymentMethods, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AttachPaymentMethod not implemented")
+}
+func (UnimplementedPaymentServiceServer) ListCustomerPaymentMethods(context.Context, *ListCustomerPaymentMethodsRequest) (*CustomerPaymentMethods, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCustomerPaymentMethods not implemented")
+}
+func (UnimplementedPaymentServiceServer) ListCustomerPayments(context.Context, *ListCustomerPaymentsRequest) (*ListPaymentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCustomerPayments not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetCustomerLifetimeValue(context.Context, *GetCustomerLifetimeValueRequest) (*CustomerLifetimeValue, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCustomerLifetimeValue not implemented")
+}
+func (UnimplementedPaymentServiceServer) CreateDispute(context.Context, *CreateDisputeRequest) (*Dispute, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateDispute not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetDispute(context.Context, *GetDisputeRequest) (*Dispute, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDispute not implemented")
+}
+func (UnimplementedPaymentServiceServer) ListDisputes(context.Context, *ListDisputesRequest) (*ListDisputesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDisputes not implemented")
+}
+func (UnimplementedPaymentServiceServer) UploadDisputeEvidence(context.Context, *UploadDisputeEvidenceRequest) (*DisputeEvidence, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UploadDisputeEvidence not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetDisputeEvidence(context.Context, *GetDisputeEvidenceRequest) (*GetDisputeEvidenceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDisputeEvidence not implemented")
+}
+func (UnimplementedPaymentServiceServer) SubmitDispute(context.Context, *SubmitDisputeRequest) (*Dispute, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitDispute not implemented")
+}
+func (UnimplementedPaymentServiceServer) AcceptDispute(context.Context, *AcceptDisputeRequest) (*Dispute, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AcceptDispute not implemented")
+}
+func (UnimplementedPaymentServiceServer) ResolveDispute(context.Context, *ResolveDisputeRequest) (*Dispute, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResolveDispute not implemented")
+}
+func (UnimplementedPaymentServiceServer) mustEmbedUnimplementedPaymentServiceServer() {}
+
+// UnsafePaymentServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PaymentServiceServer will
+// result in compilation errors.
+type UnsafePaymentServiceServer interface {
 	mustEmbedUnimplementedPaymentServiceServer()
 }
 
-// UnimplementedPaymentServiceServer must be embedded to have forward compatible implementations.
-type UnimplementedPaymentServiceServer struct {
+func RegisterPaymentServiceServer(s grpc.ServiceRegistrar, srv PaymentServiceServer) {
+	s.RegisterService(&PaymentService_ServiceDesc, srv)
+}
+
+func _PaymentService_CreatePayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CreatePayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_CreatePayment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CreatePayment(ctx, req.(*CreatePaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetPayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetPayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetPayment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetPayment(ctx, req.(*GetPaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_UpdatePayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).UpdatePayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_UpdatePayment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).UpdatePayment(ctx, req.(*UpdatePaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_DeletePayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletePaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).DeletePayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_DeletePayment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).DeletePayment(ctx, req.(*DeletePaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ListPayments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPaymentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ListPayments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ListPayments_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ListPayments(ctx, req.(*ListPaymentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_RefundPayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefundPaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).RefundPayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_RefundPayment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).RefundPayment(ctx, req.(*RefundPaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CancelPayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelPaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CancelPayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_CancelPayment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CancelPayment(ctx, req.(*CancelPaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ListPaymentEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPaymentEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ListPaymentEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ListPaymentEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ListPaymentEvents(ctx, req.(*ListPaymentEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_WatchPayments_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchPaymentsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PaymentServiceServer).WatchPayments(m, &paymentServiceWatchPaymentsServer{ServerStream: stream})
+}
+
+type PaymentService_WatchPaymentsServer interface {
+	Send(*PaymentEvent) error
+	grpc.ServerStream
+}
+
+type paymentServiceWatchPaymentsServer struct {
+	grpc.ServerStream
+}
+
+func (x *paymentServiceWatchPaymentsServer) Send(m *PaymentEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _PaymentService_CreatePaymentMethod_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePaymentMethodRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CreatePaymentMethod(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_CreatePaymentMethod_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CreatePaymentMethod(ctx, req.(*CreatePaymentMethodRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetPaymentMethod_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPaymentMethodRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetPaymentMethod(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetPaymentMethod_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetPaymentMethod(ctx, req.(*GetPaymentMethodRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CreateBankAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateBankAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CreateBankAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_CreateBankAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CreateBankAccount(ctx, req.(*CreateBankAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetBankAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBankAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetBankAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetBankAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetBankAccount(ctx, req.(*GetBankAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CreatePayout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePayoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CreatePayout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_CreatePayout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CreatePayout(ctx, req.(*CreatePayoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetCreditTransfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCreditTransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetCreditTransfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetCreditTransfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetCreditTransfer(ctx, req.(*GetCreditTransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CreateCreditTransferBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCreditTransferBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CreateCreditTransferBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_CreateCreditTransferBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CreateCreditTransferBatch(ctx, req.(*CreateCreditTransferBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetCreditTransferBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCreditTransferBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetCreditTransferBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetCreditTransferBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetCreditTransferBatch(ctx, req.(*GetCreditTransferBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ImportPaymentStatusReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportPaymentStatusReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ImportPaymentStatusReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ImportPaymentStatusReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ImportPaymentStatusReport(ctx, req.(*ImportPaymentStatusReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ImportBankStatement_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportBankStatementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ImportBankStatement(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ImportBankStatement_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ImportBankStatement(ctx, req.(*ImportBankStatementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetReconciliationRun_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetReconciliationRunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetReconciliationRun(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetReconciliationRun_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetReconciliationRun(ctx, req.(*GetReconciliationRunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ListReconciliationRuns_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListReconciliationRunsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ListReconciliationRuns(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ListReconciliationRuns_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ListReconciliationRuns(ctx, req.(*ListReconciliationRunsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ResolveReconciliationLine_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveReconciliationLineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ResolveReconciliationLine(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ResolveReconciliationLine_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ResolveReconciliationLine(ctx, req.(*ResolveReconciliationLineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ImportSettlementReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportSettlementReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ImportSettlementReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ImportSettlementReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ImportSettlementReport(ctx, req.(*ImportSettlementReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetSettlementBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSettlementBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetSettlementBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetSettlementBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetSettlementBatch(ctx, req.(*GetSettlementBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ListSettlementBatches_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSettlementBatchesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ListSettlementBatches(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ListSettlementBatches_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ListSettlementBatches(ctx, req.(*ListSettlementBatchesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ListLedgerPostings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListLedgerPostingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ListLedgerPostings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ListLedgerPostings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ListLedgerPostings(ctx, req.(*ListLedgerPostingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ImportFXRates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportFXRatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ImportFXRates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ImportFXRates_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ImportFXRates(ctx, req.(*ImportFXRatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ListFXRates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFXRatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ListFXRates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ListFXRates_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ListFXRates(ctx, req.(*ListFXRatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CreateFXQuote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateFXQuoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CreateFXQuote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_CreateFXQuote_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CreateFXQuote(ctx, req.(*CreateFXQuoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetPaymentConversion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPaymentConversionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetPaymentConversion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetPaymentConversion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetPaymentConversion(ctx, req.(*GetPaymentConversionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CreatePricingPlan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PricingPlan)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CreatePricingPlan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_CreatePricingPlan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CreatePricingPlan(ctx, req.(*PricingPlan))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_UpdatePricingPlan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PricingPlan)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).UpdatePricingPlan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_UpdatePricingPlan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).UpdatePricingPlan(ctx, req.(*PricingPlan))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetPricingPlan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPricingPlanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetPricingPlan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetPricingPlan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetPricingPlan(ctx, req.(*GetPricingPlanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ListPricingPlans_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPricingPlansRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ListPricingPlans(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ListPricingPlans_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ListPricingPlans(ctx, req.(*ListPricingPlansRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_AssignPricingPlan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AssignPricingPlanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).AssignPricingPlan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_AssignPricingPlan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).AssignPricingPlan(ctx, req.(*AssignPricingPlanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetTenantPricingPlan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTenantPricingPlanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetTenantPricingPlan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetTenantPricingPlan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetTenantPricingPlan(ctx, req.(*GetTenantPricingPlanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_SimulateFee_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SimulateFeeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).SimulateFee(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_SimulateFee_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).SimulateFee(ctx, req.(*SimulateFeeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetPaymentFee_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPaymentFeeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetPaymentFee(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetPaymentFee_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetPaymentFee(ctx, req.(*GetPaymentFeeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetFeeStatement_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFeeStatementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetFeeStatement(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetFeeStatement_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetFeeStatement(ctx, req.(*GetFeeStatementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CreateSubscriptionPlan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubscriptionPlan)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CreateSubscriptionPlan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_CreateSubscriptionPlan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CreateSubscriptionPlan(ctx, req.(*SubscriptionPlan))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetSubscriptionPlan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSubscriptionPlanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetSubscriptionPlan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetSubscriptionPlan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetSubscriptionPlan(ctx, req.(*GetSubscriptionPlanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ListSubscriptionPlans_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSubscriptionPlansRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ListSubscriptionPlans(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ListSubscriptionPlans_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ListSubscriptionPlans(ctx, req.(*ListSubscriptionPlansRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CreateSubscription_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSubscriptionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CreateSubscription(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_CreateSubscription_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CreateSubscription(ctx, req.(*CreateSubscriptionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetSubscription_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSubscriptionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetSubscription(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetSubscription_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetSubscription(ctx, req.(*GetSubscriptionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ListSubscriptions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSubscriptionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ListSubscriptions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ListSubscriptions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ListSubscriptions(ctx, req.(*ListSubscriptionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ChangeSubscriptionPlan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeSubscriptionPlanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ChangeSubscriptionPlan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ChangeSubscriptionPlan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ChangeSubscriptionPlan(ctx, req.(*ChangeSubscriptionPlanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CancelSubscription_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelSubscriptionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CancelSubscription(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_CancelSubscription_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CancelSubscription(ctx, req.(*CancelSubscriptionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ListSubscriptionCharges_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSubscriptionChargesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ListSubscriptionCharges(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ListSubscriptionCharges_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ListSubscriptionCharges(ctx, req.(*ListSubscriptionChargesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CreateInvoice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Invoice)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CreateInvoice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_CreateInvoice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CreateInvoice(ctx, req.(*Invoice))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetInvoice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetInvoiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetInvoice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetInvoice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetInvoice(ctx, req.(*GetInvoiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ListInvoices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListInvoicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ListInvoices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ListInvoices_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ListInvoices(ctx, req.(*ListInvoicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_UpdateInvoice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Invoice)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).UpdateInvoice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_UpdateInvoice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).UpdateInvoice(ctx, req.(*Invoice))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_FinalizeInvoice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FinalizeInvoiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).FinalizeInvoice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_FinalizeInvoice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).FinalizeInvoice(ctx, req.(*FinalizeInvoiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_VoidInvoice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VoidInvoiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).VoidInvoice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_VoidInvoice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).VoidInvoice(ctx, req.(*VoidInvoiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_PayInvoice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PayInvoiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).PayInvoice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_PayInvoice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).PayInvoice(ctx, req.(*PayInvoiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_RenderInvoice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenderInvoiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).RenderInvoice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_RenderInvoice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).RenderInvoice(ctx, req.(*RenderInvoiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CreateCustomer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Customer)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CreateCustomer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_CreateCustomer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CreateCustomer(ctx, req.(*Customer))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetCustomer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCustomerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetCustomer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetCustomer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetCustomer(ctx, req.(*GetCustomerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ListCustomers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCustomersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ListCustomers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ListCustomers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ListCustomers(ctx, req.(*ListCustomersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_UpdateCustomer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Customer)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).UpdateCustomer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_UpdateCustomer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).UpdateCustomer(ctx, req.(*Customer))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_DeleteCustomer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteCustomerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).DeleteCustomer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_DeleteCustomer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).DeleteCustomer(ctx, req.(*DeleteCustomerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_EraseCustomer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EraseCustomerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).EraseCustomer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_EraseCustomer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).EraseCustomer(ctx, req.(*EraseCustomerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_AttachPaymentMethod_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AttachPaymentMethodRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).AttachPaymentMethod(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_AttachPaymentMethod_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).AttachPaymentMethod(ctx, req.(*AttachPaymentMethodRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ListCustomerPaymentMethods_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCustomerPaymentMethodsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ListCustomerPaymentMethods(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ListCustomerPaymentMethods_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ListCustomerPaymentMethods(ctx, req.(*ListCustomerPaymentMethodsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (UnimplementedPaymentServiceServer) CreatePayment(context.Context, *CreatePaymentRequest) (*CreatePaymentResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreatePayment not implemented")
-}
-func (UnimplementedPaymentServiceServer) GetPayment(context.Context, *GetPaymentRequest) (*GetPaymentResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetPayment not implemented")
-}
-func (UnimplementedPaymentServiceServer) UpdatePayment(context.Context, *UpdatePaymentRequest) (*UpdatePaymentResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UpdatePayment not implemented")
+func _PaymentService_ListCustomerPayments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCustomerPaymentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ListCustomerPayments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ListCustomerPayments_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ListCustomerPayments(ctx, req.(*ListCustomerPaymentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedPaymentServiceServer) DeletePayment(context.Context, *DeletePaymentRequest) (*DeletePaymentResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method DeletePayment not implemented")
+
+func _PaymentService_GetCustomerLifetimeValue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCustomerLifetimeValueRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetCustomerLifetimeValue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetCustomerLifetimeValue_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetCustomerLifetimeValue(ctx, req.(*GetCustomerLifetimeValueRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedPaymentServiceServer) ListPayments(context.Context, *ListPaymentsRequest) (*ListPaymentsResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ListPayments not implemented")
+
+func _PaymentService_CreateDispute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateDisputeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CreateDispute(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_CreateDispute_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CreateDispute(ctx, req.(*CreateDisputeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedPaymentServiceServer) mustEmbedUnimplementedPaymentServiceServer() {}
 
-// UnsafePaymentServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to PaymentServiceServer will
-// result in compilation errors.
-type UnsafePaymentServiceServer interface {
-	mustEmbedUnimplementedPaymentServiceServer()
+func _PaymentService_GetDispute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDisputeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetDispute(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetDispute_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetDispute(ctx, req.(*GetDisputeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterPaymentServiceServer(s grpc.ServiceRegistrar, srv PaymentServiceServer) {
-	s.RegisterService(&PaymentService_ServiceDesc, srv)
+func _PaymentService_ListDisputes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDisputesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ListDisputes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ListDisputes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ListDisputes(ctx, req.(*ListDisputesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _PaymentService_CreatePayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreatePaymentRequest)
+func _PaymentService_UploadDisputeEvidence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UploadDisputeEvidenceRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(PaymentServiceServer).CreatePayment(ctx, in)
+		return srv.(PaymentServiceServer).UploadDisputeEvidence(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: PaymentService_CreatePayment_FullMethodName,
+		FullMethod: PaymentService_UploadDisputeEvidence_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(PaymentServiceServer).CreatePayment(ctx, req.(*CreatePaymentRequest))
+		return srv.(PaymentServiceServer).UploadDisputeEvidence(ctx, req.(*UploadDisputeEvidenceRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _PaymentService_GetPayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetPaymentRequest)
+func _PaymentService_GetDisputeEvidence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDisputeEvidenceRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(PaymentServiceServer).GetPayment(ctx, in)
+		return srv.(PaymentServiceServer).GetDisputeEvidence(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: PaymentService_GetPayment_FullMethodName,
+		FullMethod: PaymentService_GetDisputeEvidence_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(PaymentServiceServer).GetPayment(ctx, req.(*GetPaymentRequest))
+		return srv.(PaymentServiceServer).GetDisputeEvidence(ctx, req.(*GetDisputeEvidenceRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _PaymentService_UpdatePayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UpdatePaymentRequest)
+func _PaymentService_SubmitDispute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitDisputeRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(PaymentServiceServer).UpdatePayment(ctx, in)
+		return srv.(PaymentServiceServer).SubmitDispute(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: PaymentService_UpdatePayment_FullMethodName,
+		FullMethod: PaymentService_SubmitDispute_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(PaymentServiceServer).UpdatePayment(ctx, req.(*UpdatePaymentRequest))
+		return srv.(PaymentServiceServer).SubmitDispute(ctx, req.(*SubmitDisputeRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _PaymentService_DeletePayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DeletePaymentRequest)
+func _PaymentService_AcceptDispute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AcceptDisputeRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(PaymentServiceServer).DeletePayment(ctx, in)
+		return srv.(PaymentServiceServer).AcceptDispute(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: PaymentService_DeletePayment_FullMethodName,
+		FullMethod: PaymentService_AcceptDispute_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(PaymentServiceServer).DeletePayment(ctx, req.(*DeletePaymentRequest))
+		return srv.(PaymentServiceServer).AcceptDispute(ctx, req.(*AcceptDisputeRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _PaymentService_ListPayments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ListPaymentsRequest)
+func _PaymentService_ResolveDispute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveDisputeRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(PaymentServiceServer).ListPayments(ctx, in)
+		return srv.(PaymentServiceServer).ResolveDispute(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: PaymentService_ListPayments_FullMethodName,
+		FullMethod: PaymentService_ResolveDispute_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(PaymentServiceServer).ListPayments(ctx, req.(*ListPaymentsRequest))
+		return srv.(PaymentServiceServer).ResolveDispute(ctx, req.(*ResolveDisputeRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -256,7 +2708,285 @@ var PaymentService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListPayments",
 			Handler:    _PaymentService_ListPayments_Handler,
 		},
+		{
+			MethodName: "RefundPayment",
+			Handler:    _PaymentService_RefundPayment_Handler,
+		},
+		{
+			MethodName: "CancelPayment",
+			Handler:    _PaymentService_CancelPayment_Handler,
+		},
+		{
+			MethodName: "ListPaymentEvents",
+			Handler:    _PaymentService_ListPaymentEvents_Handler,
+		},
+		{
+			MethodName: "CreatePaymentMethod",
+			Handler:    _PaymentService_CreatePaymentMethod_Handler,
+		},
+		{
+			MethodName: "GetPaymentMethod",
+			Handler:    _PaymentService_GetPaymentMethod_Handler,
+		},
+		{
+			MethodName: "CreateBankAccount",
+			Handler:    _PaymentService_CreateBankAccount_Handler,
+		},
+		{
+			MethodName: "GetBankAccount",
+			Handler:    _PaymentService_GetBankAccount_Handler,
+		},
+		{
+			MethodName: "CreatePayout",
+			Handler:    _PaymentService_CreatePayout_Handler,
+		},
+		{
+			MethodName: "GetCreditTransfer",
+			Handler:    _PaymentService_GetCreditTransfer_Handler,
+		},
+		{
+			MethodName: "CreateCreditTransferBatch",
+			Handler:    _PaymentService_CreateCreditTransferBatch_Handler,
+		},
+		{
+			MethodName: "GetCreditTransferBatch",
+			Handler:    _PaymentService_GetCreditTransferBatch_Handler,
+		},
+		{
+			MethodName: "ImportPaymentStatusReport",
+			Handler:    _PaymentService_ImportPaymentStatusReport_Handler,
+		},
+		{
+			MethodName: "ImportBankStatement",
+			Handler:    _PaymentService_ImportBankStatement_Handler,
+		},
+		{
+			MethodName: "GetReconciliationRun",
+			Handler:    _PaymentService_GetReconciliationRun_Handler,
+		},
+		{
+			MethodName: "ListReconciliationRuns",
+			Handler:    _PaymentService_ListReconciliationRuns_Handler,
+		},
+		{
+			MethodName: "ResolveReconciliationLine",
+			Handler:    _PaymentService_ResolveReconciliationLine_Handler,
+		},
+		{
+			MethodName: "ImportSettlementReport",
+			Handler:    _PaymentService_ImportSettlementReport_Handler,
+		},
+		{
+			MethodName: "GetSettlementBatch",
+			Handler:    _PaymentService_GetSettlementBatch_Handler,
+		},
+		{
+			MethodName: "ListSettlementBatches",
+			Handler:    _PaymentService_ListSettlementBatches_Handler,
+		},
+		{
+			MethodName: "ListLedgerPostings",
+			Handler:    _PaymentService_ListLedgerPostings_Handler,
+		},
+		{
+			MethodName: "ImportFXRates",
+			Handler:    _PaymentService_ImportFXRates_Handler,
+		},
+		{
+			MethodName: "ListFXRates",
+			Handler:    _PaymentService_ListFXRates_Handler,
+		},
+		{
+			MethodName: "CreateFXQuote",
+			Handler:    _PaymentService_CreateFXQuote_Handler,
+		},
+		{
+			MethodName: "GetPaymentConversion",
+			Handler:    _PaymentService_GetPaymentConversion_Handler,
+		},
+		{
+			MethodName: "CreatePricingPlan",
+			Handler:    _PaymentService_CreatePricingPlan_Handler,
+		},
+		{
+			MethodName: "UpdatePricingPlan",
+			Handler:    _PaymentService_UpdatePricingPlan_Handler,
+		},
+		{
+			MethodName: "GetPricingPlan",
+			Handler:    _PaymentService_GetPricingPlan_Handler,
+		},
+		{
+			MethodName: "ListPricingPlans",
+			Handler:    _PaymentService_ListPricingPlans_Handler,
+		},
+		{
+			MethodName: "AssignPricingPlan",
+			Handler:    _PaymentService_AssignPricingPlan_Handler,
+		},
+		{
+			MethodName: "GetTenantPricingPlan",
+			Handler:    _PaymentService_GetTenantPricingPlan_Handler,
+		},
+		{
+			MethodName: "SimulateFee",
+			Handler:    _PaymentService_SimulateFee_Handler,
+		},
+		{
+			MethodName: "GetPaymentFee",
+			Handler:    _PaymentService_GetPaymentFee_Handler,
+		},
+		{
+			MethodName: "GetFeeStatement",
+			Handler:    _PaymentService_GetFeeStatement_Handler,
+		},
+		{
+			MethodName: "CreateSubscriptionPlan",
+			Handler:    _PaymentService_CreateSubscriptionPlan_Handler,
+		},
+		{
+			MethodName: "GetSubscriptionPlan",
+			Handler:    _PaymentService_GetSubscriptionPlan_Handler,
+		},
+		{
+			MethodName: "ListSubscriptionPlans",
+			Handler:    _PaymentService_ListSubscriptionPlans_Handler,
+		},
+		{
+			MethodName: "CreateSubscription",
+			Handler:    _PaymentService_CreateSubscription_Handler,
+		},
+		{
+			MethodName: "GetSubscription",
+			Handler:    _PaymentService_GetSubscription_Handler,
+		},
+		{
+			MethodName: "ListSubscriptions",
+			Handler:    _PaymentService_ListSubscriptions_Handler,
+		},
+		{
+			MethodName: "ChangeSubscriptionPlan",
+			Handler:    _PaymentService_ChangeSubscriptionPlan_Handler,
+		},
+		{
+			MethodName: "CancelSubscription",
+			Handler:    _PaymentService_CancelSubscription_Handler,
+		},
+		{
+			MethodName: "ListSubscriptionCharges",
+			Handler:    _PaymentService_ListSubscriptionCharges_Handler,
+		},
+		{
+			MethodName: "CreateInvoice",
+			Handler:    _PaymentService_CreateInvoice_Handler,
+		},
+		{
+			MethodName: "GetInvoice",
+			Handler:    _PaymentService_GetInvoice_Handler,
+		},
+		{
+			MethodName: "ListInvoices",
+			Handler:    _PaymentService_ListInvoices_Handler,
+		},
+		{
+			MethodName: "UpdateInvoice",
+			Handler:    _PaymentService_UpdateInvoice_Handler,
+		},
+		{
+			MethodName: "FinalizeInvoice",
+			Handler:    _PaymentService_FinalizeInvoice_Handler,
+		},
+		{
+			MethodName: "VoidInvoice",
+			Handler:    _PaymentService_VoidInvoice_Handler,
+		},
+		{
+			MethodName: "PayInvoice",
+			Handler:    _PaymentService_PayInvoice_Handler,
+		},
+		{
+			MethodName: "RenderInvoice",
+			Handler:    _PaymentService_RenderInvoice_Handler,
+		},
+		{
+			MethodName: "CreateCustomer",
+			Handler:    _PaymentService_CreateCustomer_Handler,
+		},
+		{
+			MethodName: "GetCustomer",
+			Handler:    _PaymentService_GetCustomer_Handler,
+		},
+		{
+			MethodName: "ListCustomers",
+			Handler:    _PaymentService_ListCustomers_Handler,
+		},
+		{
+			MethodName: "UpdateCustomer",
+			Handler:    _PaymentService_UpdateCustomer_Handler,
+		},
+		{
+			MethodName: "DeleteCustomer",
+			Handler:    _PaymentService_DeleteCustomer_Handler,
+		},
+		{
+			MethodName: "EraseCustomer",
+			Handler:    _PaymentService_EraseCustomer_Handler,
+		},
+		{
+			MethodName: "AttachPaymentMethod",
+			Handler:    _PaymentService_AttachPaymentMethod_Handler,
+		},
+		{
+			MethodName: "ListCustomerPaymentMethods",
+			Handler:    _PaymentService_ListCustomerPaymentMethods_Handler,
+		},
+		{
+			MethodName: "ListCustomerPayments",
+			Handler:    _PaymentService_ListCustomerPayments_Handler,
+		},
+		{
+			MethodName: "GetCustomerLifetimeValue",
+			Handler:    _PaymentService_GetCustomerLifetimeValue_Handler,
+		},
+		{
+			MethodName: "CreateDispute",
+			Handler:    _PaymentService_CreateDispute_Handler,
+		},
+		{
+			MethodName: "GetDispute",
+			Handler:    _PaymentService_GetDispute_Handler,
+		},
+		{
+			MethodName: "ListDisputes",
+			Handler:    _PaymentService_ListDisputes_Handler,
+		},
+		{
+			MethodName: "UploadDisputeEvidence",
+			Handler:    _PaymentService_UploadDisputeEvidence_Handler,
+		},
+		{
+			MethodName: "GetDisputeEvidence",
+			Handler:    _PaymentService_GetDisputeEvidence_Handler,
+		},
+		{
+			MethodName: "SubmitDispute",
+			Handler:    _PaymentService_SubmitDispute_Handler,
+		},
+		{
+			MethodName: "AcceptDispute",
+			Handler:    _PaymentService_AcceptDispute_Handler,
+		},
+		{
+			MethodName: "ResolveDispute",
+			Handler:    _PaymentService_ResolveDispute_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchPayments",
+			Handler:       _PaymentService_WatchPayments_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/payment.proto",
 }